@@ -93,6 +93,30 @@ func main() {
 		}
 	}()
 
+	// 守护进程 mTLS 监听器
+	var tlsSrv *http.Server
+	if c.Services.ClientCA.Enabled() {
+		tlsConfig, err := c.Services.ClientCA.TLSConfig()
+		if err != nil {
+			logger.Errorf("守护进程 mTLS 监听器未启动: %v", err)
+		} else {
+			// mTLS 端口只开放守护进程 WebSocket 接口
+			daemonMux := http.NewServeMux()
+			daemonMux.Handle("/api/clients/daemon/ws", r)
+			tlsSrv = &http.Server{
+				Addr:      fmt.Sprintf(":%d", result.Config.DaemonTLS.Port),
+				Handler:   daemonMux,
+				TLSConfig: tlsConfig,
+			}
+			logger.Infof("守护进程 mTLS 监听器启动于 %s", tlsSrv.Addr)
+			go func() {
+				if err := tlsSrv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+					logger.Errorf("守护进程 mTLS 监听器异常退出: %v", err)
+				}
+			}()
+		}
+	}
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -105,6 +129,9 @@ func main() {
 	// 关闭 HTTP 服务器
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if tlsSrv != nil {
+		tlsSrv.Shutdown(ctx)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("Server forced to shutdown")
 	}
@@ -26,6 +26,43 @@ func RegisterScheduledTasks(c *container.Container) {
 	// 注册告警检测定时任务
 	c.Services.TaskManager.RegisterPeriodicTask("alert-check", 5*time.Minute, c.Services.Alert.CheckAlerts)
 	c.Services.TaskManager.RegisterPeriodicTask("offline-alert-check", 1*time.Minute, c.Services.Alert.CheckOfflineAlerts)
+	c.Services.TaskManager.RegisterPeriodicTask("metric-alert-check", 1*time.Minute, c.Services.Alert.CheckMetricAlerts)
+
+	// 流量配额检查
+	c.Services.TaskManager.RegisterPeriodicTask("traffic-quota-check", 1*time.Minute, c.Services.TrafficQuota.Check)
+
+	// 月度流量报表邮件
+	c.Services.TaskManager.RegisterPeriodicTask("traffic-report-monthly", 1*time.Hour, c.Services.TrafficReport.CheckMonthlyReport)
+
+	// 代理访客及访问拦截记录写入与清理
+	c.Services.TaskManager.RegisterPeriodicTask("proxy-visitor-flush", 30*time.Second, c.Services.ProxyVisitor.Flush)
+	c.Services.TaskManager.RegisterPeriodicTask("proxy-visitor-cleanup", 1*time.Hour, c.Services.ProxyVisitor.Cleanup)
+	c.Services.TaskManager.RegisterPeriodicTask("proxy-access-flush", 30*time.Second, c.Services.ProxyAccess.Flush)
+	c.Services.TaskManager.RegisterPeriodicTask("proxy-access-cleanup", 1*time.Hour, c.Services.ProxyAccess.Cleanup)
+
+	// 代理外部拨测及结果清理
+	c.Services.TaskManager.RegisterPeriodicTask("proxy-probe-run", 10*time.Second, c.Services.ProxyProbe.RunDue)
+	c.Services.TaskManager.RegisterPeriodicTask("proxy-probe-cleanup", 1*time.Hour, c.Services.ProxyProbe.Cleanup)
+	c.Services.TaskManager.RegisterPeriodicTask("webhook-delivery-retry", 15*time.Second, c.Services.WebhookDelivery.ProcessDue)
+	c.Services.TaskManager.RegisterPeriodicTask("webhook-delivery-cleanup", 1*time.Hour, c.Services.WebhookDelivery.Cleanup)
+
+	// 告警通知汇总发送及每日告警汇总邮件
+	c.Services.TaskManager.RegisterPeriodicTask("notification-digest-flush", 10*time.Second, c.Services.NotificationDigest.FlushDue)
+	c.Services.TaskManager.RegisterPeriodicTask("alert-summary-daily", 10*time.Minute, c.Services.AlertSummary.CheckDailySummary)
+
+	// 告警、操作日志及系统事件转发到 syslog/Loki
+	c.Services.TaskManager.RegisterPeriodicTask("log-stream-flush", 5*time.Second, c.Services.LogStream.Flush)
+
+	// 未确认告警逐级升级
+	c.Services.TaskManager.RegisterPeriodicTask("alert-escalation", 30*time.Second, c.Services.AlertEscalation.ProcessDue)
+
+	// 指标降采样汇总
+	c.Services.TaskManager.RegisterPeriodicTask("metrics-rollup", 5*time.Minute, c.Services.MetricsRollup.Rollup)
+
+	// 守护进程 mTLS 证书续期
+	if c.Services.ClientCA.Enabled() {
+		c.Services.TaskManager.RegisterPeriodicTask("client-cert-renewal", 12*time.Hour, c.Services.ClientCA.RenewExpiringCerts)
+	}
 }
 
 // RegisterCallbacks 注册所有回调函数
@@ -65,6 +102,9 @@ func StartServices(c *container.Container) {
 		logger.Warnf("重置客户端状态失败: %v", err)
 	}
 
+	// 订阅需转发到日志平台的事件，先于其他任务启动以免遗漏启动期间的事件
+	c.Services.LogStream.Start()
+
 	// 启动任务管理器管理的任务
 	c.Services.TaskManager.Start()
 
@@ -83,6 +123,7 @@ func StopServices(c *container.Container, shutdownTimeout time.Duration) {
 	c.Services.Realtime.Stop()
 	c.Services.FrpSync.Stop()
 	c.Services.ClientStatusChecker.Stop()
+	c.Services.LogStream.Stop()
 
 	if err := c.Services.TaskManager.Shutdown(shutdownTimeout); err != nil {
 		logger.Errorf("任务管理器关闭错误: %v", err)
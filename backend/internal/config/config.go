@@ -21,12 +21,15 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Log      LogConfig      `mapstructure:"log"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Security SecurityConfig `mapstructure:"security"`
-	Frps     FrpsConfig     `mapstructure:"frps"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Log        LogConfig        `mapstructure:"log"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	Security   SecurityConfig   `mapstructure:"security"`
+	Frps       FrpsConfig       `mapstructure:"frps"`
+	DaemonTLS  DaemonTLSConfig  `mapstructure:"daemon_tls"`
+	DaemonAuth DaemonAuthConfig `mapstructure:"daemon_auth"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
 }
 
 type LogConfig struct {
@@ -73,6 +76,29 @@ type FrpsConfig struct {
 	GithubAPI      string `mapstructure:"github_api"`
 }
 
+// DaemonTLSConfig 守护进程 mTLS 配置
+type DaemonTLSConfig struct {
+	Enabled           bool   `mapstructure:"enabled"`
+	Port              int    `mapstructure:"port"`
+	CertFile          string `mapstructure:"cert_file"`
+	KeyFile           string `mapstructure:"key_file"`
+	CADir             string `mapstructure:"ca_dir"`
+	ClientCertDays    int    `mapstructure:"client_cert_days"`
+	RequireClientCert bool   `mapstructure:"require_client_cert"`
+}
+
+// DaemonAuthConfig 守护进程认证配置
+type DaemonAuthConfig struct {
+	// DisableLegacyToken 停用旧版守护进程通过 ?token= 查询参数的认证，所有守护进程升级后开启
+	DisableLegacyToken bool `mapstructure:"disable_legacy_token"`
+}
+
+// MetricsConfig Prometheus 指标导出配置
+type MetricsConfig struct {
+	// Token 供 Prometheus 抓取使用的 Bearer 令牌，为空时只接受登录用户的 JWT
+	Token string `mapstructure:"token"`
+}
+
 var GlobalConfig *Config
 
 func LoadConfig(path string) error {
@@ -147,6 +173,24 @@ func (c *Config) Validate() error {
 		errs = append(errs, "security.encryption_key must be exactly 32 characters for AES-256")
 	}
 
+	// 验证守护进程 mTLS 配置
+	if c.DaemonTLS.Enabled {
+		if c.DaemonTLS.Port < 1 || c.DaemonTLS.Port > 65535 {
+			errs = append(errs, fmt.Sprintf("daemon_tls.port must be between 1 and 65535, got %d", c.DaemonTLS.Port))
+		} else if c.DaemonTLS.Port == c.Server.Port {
+			errs = append(errs, "daemon_tls.port must differ from server.port")
+		}
+		if c.DaemonTLS.CertFile == "" || c.DaemonTLS.KeyFile == "" {
+			errs = append(errs, "daemon_tls.cert_file and daemon_tls.key_file are required when daemon_tls is enabled")
+		}
+		if c.DaemonTLS.CADir == "" {
+			c.DaemonTLS.CADir = "./data/ca"
+		}
+		if c.DaemonTLS.ClientCertDays <= 0 {
+			c.DaemonTLS.ClientCertDays = 365
+		}
+	}
+
 	// 敏感配置默认值警告
 	c.warnDefaultSensitiveValues()
 
@@ -21,21 +21,34 @@ import (
 type Handlers struct {
 	Alert          *handler.AlertHandler
 	AlertRecipient *handler.AlertRecipientHandler
+	AlertSilence   *handler.AlertSilenceHandler
+	AlertSummary   *handler.AlertSummaryHandler
+	Escalation     *handler.AlertEscalationHandler
 	Auth           *handler.AuthHandler
 	Certificate    *handler.CertificateHandler
 	Client         *handler.ClientHandler
+	ClientCred     *handler.ClientCredentialHandler
 	ClientDaemonWS *handler.ClientDaemonWSHandler
 	ClientLog      *handler.ClientLogHandler
 	DaemonDownload *handler.DaemonDownloadHandler
 	DNS            *handler.DNSHandler
 	FrpServer      *handler.FrpServerHandler
+	FrpsPlugin     *handler.FrpsPluginHandler
 	GithubMirror   *handler.GithubMirrorHandler
 	Log            *handler.LogHandler
+	LogSink        *handler.LogSinkHandler
 	LogWS          *handler.LogWSHandler
+	MessageTpl     *handler.MessageTemplateHandler
+	Metrics        *handler.MetricsHandler
 	Monitor        *handler.MonitorHandler
+	Notification   *handler.NotificationChannelHandler
 	Proxy          *handler.ProxyHandler
+	ProxyProbe     *handler.ProxyProbeHandler
+	ProxyVisitor   *handler.ProxyVisitorHandler
 	Setting        *handler.SettingHandler
 	Traffic        *handler.TrafficHandler
+	TrafficQuota   *handler.TrafficQuotaHandler
+	Webhook        *handler.WebhookDeliveryHandler
 	WebSocket      *handler.WebSocketHandler
 }
 
@@ -44,21 +57,34 @@ func NewHandlers(services *Services, repos *Repositories, hub *websocket.Hub) *H
 	return &Handlers{
 		Alert:          handler.NewAlertHandler(services.Alert),
 		AlertRecipient: handler.NewAlertRecipientHandler(),
+		AlertSilence:   handler.NewAlertSilenceHandler(services.AlertSilence),
+		AlertSummary:   handler.NewAlertSummaryHandler(services.AlertSummary),
+		Escalation:     handler.NewAlertEscalationHandler(services.AlertEscalation),
 		Auth:           handler.NewAuthHandler(),
 		Certificate:    handler.NewCertificateHandler(repos.Certificate, services.ACME),
-		Client:         handler.NewClientHandler(services.Client, services.ClientRegister, services.ClientUpdate, services.Log),
-		ClientDaemonWS: handler.NewClientDaemonWSHandler(),
+		Client:         handler.NewClientHandler(services.Client, services.ClientRegister, services.ClientUpdate, services.Log, repos.ClientMetrics),
+		ClientCred:     handler.NewClientCredentialHandler(services.ClientCredential, services.Log),
+		ClientDaemonWS: handler.NewClientDaemonWSHandler(services.ClientCA),
 		ClientLog:      handler.NewClientLogHandler(),
 		DaemonDownload: handler.NewDaemonDownloadHandler(),
 		DNS:            handler.NewDNSHandler(),
-		FrpServer:      handler.NewFrpServerHandler(services.FrpServer, services.Log, repos.ServerMetrics),
+		FrpServer:      handler.NewFrpServerHandler(services.FrpServer, services.Log, services.MetricsRollup),
+		FrpsPlugin:     handler.NewFrpsPluginHandler(services.FrpsPlugin),
 		GithubMirror:   handler.NewGithubMirrorHandler(),
 		Log:            handler.NewLogHandler(),
+		LogSink:        handler.NewLogSinkHandler(services.LogStream),
 		LogWS:          handler.NewLogWSHandler(),
+		MessageTpl:     handler.NewMessageTemplateHandler(services.MessageTemplate),
+		Metrics:        handler.NewMetricsHandler(services.PrometheusExporter),
 		Monitor:        handler.NewMonitorHandler(),
+		Notification:   handler.NewNotificationChannelHandler(services.Notification),
 		Proxy:          handler.NewProxyHandler(),
+		ProxyProbe:     handler.NewProxyProbeHandler(services.ProxyProbe),
+		ProxyVisitor:   handler.NewProxyVisitorHandler(services.ProxyVisitor, services.ProxyAccess),
 		Setting:        handler.NewSettingHandlerWithService(services.Realtime, services.MetricsCollector),
-		Traffic:        handler.NewTrafficHandler(),
+		Traffic:        handler.NewTrafficHandler(services.MetricsRollup, services.TrafficReport),
+		TrafficQuota:   handler.NewTrafficQuotaHandler(services.TrafficQuota),
+		Webhook:        handler.NewWebhookDeliveryHandler(services.WebhookDelivery),
 		WebSocket:      handler.NewWebSocketHandler(hub),
 	}
 }
@@ -24,6 +24,7 @@ type Repositories struct {
 	AlertRecipient *repository.AlertRecipientRepo
 	Certificate    *repository.CertificateRepository
 	Client         *repository.ClientRepository
+	ClientMetrics  *repository.ClientMetricsRepository
 	ClientRegToken *repository.ClientRegisterTokenRepository
 	DNSProvider    *repository.DNSProviderRepository
 	DNSRecord      *repository.DNSRecordRepository
@@ -32,6 +33,7 @@ type Repositories struct {
 	Log            *repository.LogRepository
 	Proxy          *repository.ProxyRepository
 	ProxyMetrics   *repository.ProxyMetricsRepository
+	ProxyProbe     *repository.ProxyProbeRepository
 	ServerMetrics  *repository.ServerMetricsRepository
 	Setting        *repository.SettingRepository
 	Traffic        *repository.TrafficRepository
@@ -45,6 +47,7 @@ func NewRepositories(db *gorm.DB) *Repositories {
 		AlertRecipient: repository.NewAlertRecipientRepo(),
 		Certificate:    repository.NewCertificateRepository(),
 		Client:         repository.NewClientRepository(),
+		ClientMetrics:  repository.NewClientMetricsRepository(),
 		ClientRegToken: repository.NewClientRegisterTokenRepository(),
 		DNSProvider:    repository.NewDNSProviderRepository(),
 		DNSRecord:      repository.NewDNSRecordRepository(),
@@ -53,6 +56,7 @@ func NewRepositories(db *gorm.DB) *Repositories {
 		Log:            repository.NewLogRepository(),
 		Proxy:          repository.NewProxyRepository(),
 		ProxyMetrics:   repository.NewProxyMetricsRepository(),
+		ProxyProbe:     repository.NewProxyProbeRepository(),
 		ServerMetrics:  repository.NewServerMetricsRepository(),
 		Setting:        repository.NewSettingRepository(),
 		Traffic:        repository.NewTrafficRepository(),
@@ -5,6 +5,7 @@ package container
 
 import (
 	"frp-web-panel/internal/config"
+	"frp-web-panel/internal/logger"
 	"frp-web-panel/internal/service"
 	"frp-web-panel/internal/websocket"
 )
@@ -14,9 +15,15 @@ type Services struct {
 	ACME                *service.ACMEService
 	Alert               *service.AlertService
 	AlertRecipient      *service.AlertRecipientService
+	AlertSilence        *service.AlertSilenceService
+	AlertEscalation     *service.AlertEscalationService
+	AlertSummary        *service.AlertSummaryService
+	NotificationDigest  *service.NotificationDigest
 	Auth                *service.AuthService
 	CertRenewal         *service.CertRenewalScheduler
 	Client              *service.ClientService
+	ClientCA            *service.ClientCAService
+	ClientCredential    *service.ClientCredentialService
 	ClientRegister      *service.ClientRegisterService
 	ClientStatusChecker *service.ClientStatusChecker
 	ClientUpdate        *service.ClientUpdateService
@@ -25,15 +32,27 @@ type Services struct {
 	Email               *service.EmailService
 	FrpServer           *service.FrpServerService
 	FrpSync             *service.FrpSyncService
+	FrpsPlugin          *service.FrpsPluginService
 	GithubMirror        *service.GithubMirrorService
 	Log                 *service.LogService
+	LogStream           *service.LogStreamService
+	MessageTemplate     *service.MessageTemplateService
 	MetricsCollector    *service.MetricsCollector
+	MetricsRollup       *service.MetricsRollupService
 	Monitor             *service.MonitorService
+	Notification        *service.NotificationService
+	PrometheusExporter  *service.PrometheusExporter
 	Proxy               *service.ProxyService
+	ProxyAccess         *service.ProxyAccessService
+	ProxyProbe          *service.ProxyProbeService
+	ProxyVisitor        *service.ProxyVisitorService
 	Realtime            *service.RealtimeService
 	Setting             *service.SettingService
 	TaskManager         *service.TaskManager
 	Traffic             *service.TrafficService
+	TrafficQuota        *service.TrafficQuotaService
+	TrafficReport       *service.TrafficReportService
+	WebhookDelivery     *service.WebhookDeliveryService
 }
 
 // NewServices 创建所有 Service 实例
@@ -73,17 +92,29 @@ func NewServices(repos *Repositories, hub *websocket.Hub, cfg *config.Config, cl
 	})
 
 	// 创建指标采集服务
-	metricsCollector := service.NewMetricsCollector(repos.FrpServer)
+	metricsRollup := service.NewMetricsRollupService()
+	metricsCollector := service.NewMetricsCollector(repos.FrpServer, metricsRollup)
 
 	// 创建告警服务
 	alertService := service.NewAlertService(repos.Alert, repos.Traffic, repos.Proxy)
 	alertService.SetClientRepo(repos.Client)
 	alertService.SetFrpServerRepo(repos.FrpServer)
+	alertService.SetProbeRepo(repos.ProxyProbe)
+	alertService.SetMetricsRollup(metricsRollup)
 	alertRecipientService := service.NewAlertRecipientService()
 
 	// 创建客户端相关服务
 	clientService := service.NewClientService()
 	clientRegisterService := service.NewClientRegisterService()
+	clientCredentialService := service.NewClientCredentialService(clientDaemonHub)
+
+	// 创建守护进程 mTLS CA（未启用时各方法为空操作）
+	clientCAService := service.NewClientCAService(cfg.DaemonTLS, clientDaemonHub)
+	if err := clientCAService.Init(); err != nil {
+		logger.Errorf("初始化客户端CA失败，mTLS 已禁用: %v", err)
+	}
+	clientService.SetClientCA(clientCAService)
+	clientRegisterService.SetClientCA(clientCAService)
 	clientStatusChecker := service.NewClientStatusChecker(clientService)
 	clientUpdateService := service.NewClientUpdateService(realtimeService)
 
@@ -96,17 +127,33 @@ func NewServices(repos *Repositories, hub *websocket.Hub, cfg *config.Config, cl
 	dnsService := service.NewDNSService()
 	githubMirrorService := service.NewGithubMirrorService()
 	monitorService := service.NewMonitorService()
+	notificationService := service.NewNotificationService()
+	prometheusExporter := service.NewPrometheusExporter(repos.FrpServer, repos.Alert, clientDaemonHub, hub, taskManager)
 	proxyService := service.NewProxyService()
+	proxyVisitorService := service.NewProxyVisitorService()
+	proxyAccessService := service.NewProxyAccessService()
+	proxyAccessService.SetEventNotifier(service.NewSystemEventNotifier(repos.Alert))
+	proxyProbeService := service.NewProxyProbeService()
+	frpsPluginService := service.NewFrpsPluginService(proxyVisitorService, proxyAccessService)
 	trafficService := service.NewTrafficService()
+	trafficQuotaService := service.NewTrafficQuotaService(clientDaemonHub)
+	trafficQuotaService.SetEventNotifier(service.NewSystemEventNotifier(repos.Alert))
+	trafficReportService := service.NewTrafficReportService(metricsRollup)
 	authService := service.NewAuthService()
+	webhookDeliveryService := service.NewWebhookDeliveryService(repos.Alert)
 
 	return &Services{
 		ACME:                acmeService,
 		Alert:               alertService,
 		AlertRecipient:      alertRecipientService,
+		AlertSilence:        service.NewAlertSilenceService(),
+		AlertEscalation:     service.NewAlertEscalationService(repos.Alert),
+		AlertSummary:        service.NewAlertSummaryService(repos.Alert),
 		Auth:                authService,
 		CertRenewal:         certRenewalScheduler,
 		Client:              clientService,
+		ClientCA:            clientCAService,
+		ClientCredential:    clientCredentialService,
 		ClientRegister:      clientRegisterService,
 		ClientStatusChecker: clientStatusChecker,
 		ClientUpdate:        clientUpdateService,
@@ -115,14 +162,27 @@ func NewServices(repos *Repositories, hub *websocket.Hub, cfg *config.Config, cl
 		Email:               emailService,
 		FrpServer:           frpServerService,
 		FrpSync:             frpSyncService,
+		FrpsPlugin:          frpsPluginService,
 		GithubMirror:        githubMirrorService,
 		Log:                 logService,
+		LogStream:           service.GetLogStreamService(),
+		MessageTemplate:     service.NewMessageTemplateService(),
 		MetricsCollector:    metricsCollector,
+		MetricsRollup:       metricsRollup,
 		Monitor:             monitorService,
+		Notification:        notificationService,
+		NotificationDigest:  service.GetNotificationDigest(),
+		PrometheusExporter:  prometheusExporter,
 		Proxy:               proxyService,
+		ProxyAccess:         proxyAccessService,
+		ProxyProbe:          proxyProbeService,
+		ProxyVisitor:        proxyVisitorService,
 		Realtime:            realtimeService,
 		Setting:             settingService,
 		TaskManager:         taskManager,
 		Traffic:             trafficService,
+		TrafficQuota:        trafficQuotaService,
+		TrafficReport:       trafficReportService,
+		WebhookDelivery:     webhookDeliveryService,
 	}
 }
@@ -18,6 +18,9 @@ const (
 	EventTrafficUpdate  EventType = "traffic_update"
 	EventUpdateProgress EventType = "update_progress"
 	EventUpdateResult   EventType = "update_result"
+	EventAlertLog       EventType = "alert_log"
+	EventOperationLog   EventType = "operation_log"
+	EventSystem         EventType = "system_event"
 )
 
 // Event 事件接口
@@ -87,6 +90,49 @@ type UpdateResultEvent struct {
 
 func (e UpdateResultEvent) Type() EventType { return EventUpdateResult }
 
+// AlertLogEvent 告警触发、恢复及状态变化事件
+type AlertLogEvent struct {
+	AlertID         uint // 恢复事件为0
+	RuleID          uint
+	TargetType      string
+	TargetID        uint
+	AlertType       string // 告警类型，恢复为 recovery
+	Action          string // 状态变化：acknowledged、unacknowledged、resolved、escalated、released，触发和恢复为空
+	Status          string
+	Message         string
+	Silenced        bool
+	SuppressedBy    uint
+	EscalationLevel int
+	Timestamp       time.Time
+}
+
+func (e AlertLogEvent) Type() EventType { return EventAlertLog }
+
+// OperationLogEvent 操作日志（审计）事件
+type OperationLogEvent struct {
+	UserID        uint
+	OperationType string
+	ResourceType  string
+	ResourceID    uint
+	Description   string
+	IPAddress     string
+	IPLocation    string
+	Timestamp     time.Time
+}
+
+func (e OperationLogEvent) Type() EventType { return EventOperationLog }
+
+// SystemEvent 系统事件，如证书申请、DNS 同步、登录失败、配置变更，无论是否配置告警规则都会发布
+type SystemEvent struct {
+	EventType string
+	Level     string // info, warning
+	Message   string
+	Data      string // 事件详情JSON
+	Timestamp time.Time
+}
+
+func (e SystemEvent) Type() EventType { return EventSystem }
+
 // EventHandler 事件处理函数
 type EventHandler func(Event)
 
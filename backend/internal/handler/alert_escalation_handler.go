@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertEscalationHandler struct {
+	escalationService *service.AlertEscalationService
+	logService        *service.LogService
+}
+
+func NewAlertEscalationHandler(escalationService *service.AlertEscalationService) *AlertEscalationHandler {
+	return &AlertEscalationHandler{
+		escalationService: escalationService,
+		logService:        service.NewLogService(),
+	}
+}
+
+// GetPolicies godoc
+// @Summary 获取告警升级策略列表
+// @Description 获取全部告警升级策略及各级通知配置
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.AlertEscalationPolicy} "获取成功"
+// @Failure 500 {object} util.Response "获取失败"
+// @Router /api/alerts/escalation-policies [get]
+func (h *AlertEscalationHandler) GetPolicies(c *gin.Context) {
+	policies, err := h.escalationService.GetPolicies()
+	if err != nil {
+		util.Error(c, 500, "获取升级策略失败")
+		return
+	}
+	util.Success(c, policies)
+}
+
+// CreatePolicy godoc
+// @Summary 创建告警升级策略
+// @Description 告警触发时通知第1级，每级超过等待时间仍未确认则通知下一级；每级可通知接收人分组及值班表的当前值班人
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param policy body model.AlertEscalationPolicy true "升级策略，steps 按级别顺序排列"
+// @Success 200 {object} util.Response{data=model.AlertEscalationPolicy} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/escalation-policies [post]
+func (h *AlertEscalationHandler) CreatePolicy(c *gin.Context) {
+	var policy model.AlertEscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	if err := h.escalationService.CreatePolicy(&policy); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "alert_escalation_policy", policy.ID,
+		fmt.Sprintf("创建告警升级策略: %s (%d级)", policy.Name, len(policy.Steps)), c.ClientIP())
+	util.Success(c, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary 更新告警升级策略
+// @Description 更新策略信息，级别按提交顺序整体替换；已在升级中的告警按新配置继续升级
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "策略ID"
+// @Param policy body model.AlertEscalationPolicy true "升级策略"
+// @Success 200 {object} util.Response{data=model.AlertEscalationPolicy} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/escalation-policies/{id} [put]
+func (h *AlertEscalationHandler) UpdatePolicy(c *gin.Context) {
+	var policy model.AlertEscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	policy.ID = uint(id)
+
+	updated, err := h.escalationService.UpdatePolicy(&policy)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "alert_escalation_policy", updated.ID,
+		fmt.Sprintf("更新告警升级策略: %s (%d级)", updated.Name, len(updated.Steps)), c.ClientIP())
+	util.Success(c, updated)
+}
+
+// DeletePolicy godoc
+// @Summary 删除告警升级策略
+// @Description 删除升级策略，仍被告警规则引用时不允许删除
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "策略ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/alerts/escalation-policies/{id} [delete]
+func (h *AlertEscalationHandler) DeletePolicy(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.escalationService.DeletePolicy(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "alert_escalation_policy", uint(id),
+		fmt.Sprintf("删除告警升级策略: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// GetSchedules godoc
+// @Summary 获取值班表列表
+// @Description 获取全部按周轮换的值班表，current 为当前值班人
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.OnCallSchedule} "获取成功"
+// @Failure 500 {object} util.Response "获取失败"
+// @Router /api/alerts/oncall-schedules [get]
+func (h *AlertEscalationHandler) GetSchedules(c *gin.Context) {
+	schedules, err := h.escalationService.GetSchedules()
+	if err != nil {
+		util.Error(c, 500, "获取值班表失败")
+		return
+	}
+	util.Success(c, schedules)
+}
+
+// CreateSchedule godoc
+// @Summary 创建值班表
+// @Description 从交接时间开始，每隔 rotation_weeks 周在相同星期和时刻按 recipient_ids 顺序轮换值班人
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param schedule body model.OnCallSchedule true "值班表"
+// @Success 200 {object} util.Response{data=model.OnCallSchedule} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/oncall-schedules [post]
+func (h *AlertEscalationHandler) CreateSchedule(c *gin.Context) {
+	var schedule model.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	if err := h.escalationService.CreateSchedule(&schedule); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "oncall_schedule", schedule.ID,
+		"创建值班表: "+schedule.Name, c.ClientIP())
+	util.Success(c, schedule)
+}
+
+// UpdateSchedule godoc
+// @Summary 更新值班表
+// @Description 更新值班表的轮换配置，修改交接时间或值班人顺序会立即影响当前值班人
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "值班表ID"
+// @Param schedule body model.OnCallSchedule true "值班表"
+// @Success 200 {object} util.Response{data=model.OnCallSchedule} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/oncall-schedules/{id} [put]
+func (h *AlertEscalationHandler) UpdateSchedule(c *gin.Context) {
+	var schedule model.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	schedule.ID = uint(id)
+
+	updated, err := h.escalationService.UpdateSchedule(&schedule)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "oncall_schedule", updated.ID,
+		"更新值班表: "+updated.Name, c.ClientIP())
+	util.Success(c, updated)
+}
+
+// DeleteSchedule godoc
+// @Summary 删除值班表
+// @Description 删除值班表，仍被升级策略引用时不允许删除
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "值班表ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/alerts/oncall-schedules/{id} [delete]
+func (h *AlertEscalationHandler) DeleteSchedule(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.escalationService.DeleteSchedule(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "oncall_schedule", uint(id),
+		fmt.Sprintf("删除值班表: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// GetShifts godoc
+// @Summary 获取值班表轮班安排
+// @Description 获取从当前轮班开始的后续轮班及值班人
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "值班表ID"
+// @Param count query int false "轮班数量，最多52" default(8)
+// @Success 200 {object} util.Response{data=[]model.OnCallShift} "获取成功"
+// @Failure 400 {object} util.Response "值班表不存在"
+// @Router /api/alerts/oncall-schedules/{id}/shifts [get]
+func (h *AlertEscalationHandler) GetShifts(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "8"))
+	shifts, err := h.escalationService.GetShifts(uint(id), count)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+	util.Success(c, shifts)
+}
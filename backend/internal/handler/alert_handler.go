@@ -51,7 +51,7 @@ func (h *AlertHandler) CreateRule(c *gin.Context) {
 	}
 
 	if err := h.alertService.CreateRule(&rule); err != nil {
-		util.Error(c, 4002, "创建告警规则失败")
+		util.Error(c, 4002, "创建告警规则失败: "+err.Error())
 		return
 	}
 
@@ -105,7 +105,7 @@ func (h *AlertHandler) UpdateRule(c *gin.Context) {
 	}
 
 	if err := h.alertService.UpdateRule(&rule); err != nil {
-		util.Error(c, 4004, "更新告警规则失败")
+		util.Error(c, 4004, "更新告警规则失败: "+err.Error())
 		return
 	}
 
@@ -171,6 +171,7 @@ func (h *AlertHandler) GetAllRules(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param limit query int false "返回记录数量限制" default(100)
+// @Param status query string false "告警状态: firing, acknowledged, resolved, open(触发中和已确认)"
 // @Success 200 {object} util.Response{data=[]object} "获取成功"
 // @Failure 500 {object} util.Response "获取失败"
 // @Router /api/alerts/logs [get]
@@ -182,6 +183,16 @@ func (h *AlertHandler) GetAlertLogs(c *gin.Context) {
 		}
 	}
 
+	if status := c.Query("status"); status != "" {
+		logs, err := h.alertService.GetAlertLogsByStatus(status, limit)
+		if err != nil {
+			util.Error(c, 4006, "获取告警日志失败: "+err.Error())
+			return
+		}
+		util.Success(c, logs)
+		return
+	}
+
 	logs, err := h.alertService.GetAlertLogs(limit)
 	if err != nil {
 		util.Error(c, 4006, "获取告警日志失败")
@@ -190,3 +201,102 @@ func (h *AlertHandler) GetAlertLogs(c *gin.Context) {
 
 	util.Success(c, logs)
 }
+
+// AcknowledgeAlert godoc
+// @Summary 确认告警
+// @Description 确认触发中的告警，记录确认人和确认时间
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "告警日志ID"
+// @Success 200 {object} util.Response{data=model.AlertLog} "确认成功"
+// @Failure 400 {object} util.Response "告警不存在或状态不允许确认"
+// @Router /api/alerts/logs/{id}/ack [post]
+func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	name, _ := username.(string)
+
+	alert, err := h.alertService.AcknowledgeAlert(uint(id), userID.(uint), name)
+	if err != nil {
+		util.Error(c, 4008, err.Error())
+		return
+	}
+
+	h.logService.CreateLogAsync(userID.(uint), "update", "alert_log", alert.ID,
+		fmt.Sprintf("确认告警: %s", alert.Message), c.ClientIP())
+	util.Success(c, alert)
+}
+
+// UnacknowledgeAlert godoc
+// @Summary 取消确认告警
+// @Description 取消已确认告警的确认状态，告警恢复为触发中
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "告警日志ID"
+// @Success 200 {object} util.Response{data=model.AlertLog} "取消成功"
+// @Failure 400 {object} util.Response "告警不存在或未确认"
+// @Router /api/alerts/logs/{id}/unack [post]
+func (h *AlertHandler) UnacknowledgeAlert(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	alert, err := h.alertService.UnacknowledgeAlert(uint(id))
+	if err != nil {
+		util.Error(c, 4008, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "alert_log", alert.ID,
+		fmt.Sprintf("取消确认告警: %s", alert.Message), c.ClientIP())
+	util.Success(c, alert)
+}
+
+// ResolveAlert godoc
+// @Summary 手动结束告警
+// @Description 将触发中或已确认的告警标记为已恢复，用于不会自动恢复的系统告警
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "告警日志ID"
+// @Success 200 {object} util.Response{data=model.AlertLog} "操作成功"
+// @Failure 400 {object} util.Response "告警不存在或已结束"
+// @Router /api/alerts/logs/{id}/resolve [post]
+func (h *AlertHandler) ResolveAlert(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	alert, err := h.alertService.ResolveAlert(uint(id))
+	if err != nil {
+		util.Error(c, 4008, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "alert_log", alert.ID,
+		fmt.Sprintf("手动结束告警: %s", alert.Message), c.ClientIP())
+	util.Success(c, alert)
+}
+
+// GetSuppressedAlerts godoc
+// @Summary 获取被抑制的下级告警
+// @Description 获取上级 frps/frpc 离线期间被该告警抑制、归并到该告警的下级告警。下级告警只记录不通知，上级告警的通知中不包含下级数量
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "告警日志ID"
+// @Success 200 {object} util.Response{data=[]model.AlertLog} "获取成功"
+// @Failure 400 {object} util.Response "告警不存在"
+// @Router /api/alerts/logs/{id}/suppressed [get]
+func (h *AlertHandler) GetSuppressedAlerts(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	alerts, err := h.alertService.GetSuppressedAlerts(uint(id))
+	if err != nil {
+		util.Error(c, 4008, err.Error())
+		return
+	}
+	util.Success(c, alerts)
+}
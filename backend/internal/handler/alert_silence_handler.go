@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertSilenceHandler struct {
+	silenceService *service.AlertSilenceService
+	logService     *service.LogService
+}
+
+func NewAlertSilenceHandler(silenceService *service.AlertSilenceService) *AlertSilenceHandler {
+	return &AlertSilenceHandler{
+		silenceService: silenceService,
+		logService:     service.NewLogService(),
+	}
+}
+
+// describeSilence 操作日志中使用的静默匹配范围描述
+func describeSilence(silence *model.AlertSilence) string {
+	scope := "全部告警"
+	if silence.TargetType != "" {
+		scope = string(silence.TargetType)
+		if silence.TargetID != 0 {
+			scope += fmt.Sprintf(":%d", silence.TargetID)
+		}
+	}
+	if silence.RuleID != 0 {
+		scope += fmt.Sprintf(" 规则%d", silence.RuleID)
+	}
+	return fmt.Sprintf("%s (%s ~ %s)", scope,
+		silence.StartsAt.Format("2006-01-02 15:04"), silence.EndsAt.Format("2006-01-02 15:04"))
+}
+
+// GetSilences godoc
+// @Summary 获取告警静默列表
+// @Description 获取全部告警静默及计划维护窗口，state 为 pending(未开始)、active(生效中)、expired(已结束)
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.AlertSilence} "获取成功"
+// @Failure 500 {object} util.Response "获取失败"
+// @Router /api/alerts/silences [get]
+func (h *AlertSilenceHandler) GetSilences(c *gin.Context) {
+	silences, err := h.silenceService.GetSilences()
+	if err != nil {
+		util.Error(c, 500, "获取告警静默失败")
+		return
+	}
+	util.Success(c, silences)
+}
+
+// CreateSilence godoc
+// @Summary 创建告警静默
+// @Description 按目标类型、目标ID或规则创建静默，未设置的条件匹配全部；开始时间为空时立即生效，设为将来即为计划维护窗口
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param silence body model.AlertSilence true "静默配置"
+// @Success 200 {object} util.Response{data=model.AlertSilence} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/silences [post]
+func (h *AlertSilenceHandler) CreateSilence(c *gin.Context) {
+	var silence model.AlertSilence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	silence.CreatedBy = userID.(uint)
+	silence.CreatedByName, _ = username.(string)
+
+	if err := h.silenceService.CreateSilence(&silence); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	h.logService.CreateLogAsync(userID.(uint), "create", "alert_silence", silence.ID,
+		"创建告警静默: "+describeSilence(&silence), c.ClientIP())
+	util.Success(c, silence)
+}
+
+// UpdateSilence godoc
+// @Summary 更新告警静默
+// @Description 更新静默的匹配条件、起止时间和备注
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "静默ID"
+// @Param silence body model.AlertSilence true "静默配置"
+// @Success 200 {object} util.Response{data=model.AlertSilence} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/silences/{id} [put]
+func (h *AlertSilenceHandler) UpdateSilence(c *gin.Context) {
+	var silence model.AlertSilence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	silence.ID = uint(id)
+
+	updated, err := h.silenceService.UpdateSilence(&silence)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "alert_silence", updated.ID,
+		"更新告警静默: "+describeSilence(updated), c.ClientIP())
+	util.Success(c, updated)
+}
+
+// ExpireSilence godoc
+// @Summary 立即结束告警静默
+// @Description 将静默的结束时间设为当前时间，保留记录
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "静默ID"
+// @Success 200 {object} util.Response{data=model.AlertSilence} "操作成功"
+// @Failure 400 {object} util.Response "静默不存在或已结束"
+// @Router /api/alerts/silences/{id}/expire [post]
+func (h *AlertSilenceHandler) ExpireSilence(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	silence, err := h.silenceService.ExpireSilence(uint(id))
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "alert_silence", silence.ID,
+		"结束告警静默: "+describeSilence(silence), c.ClientIP())
+	util.Success(c, silence)
+}
+
+// DeleteSilence godoc
+// @Summary 删除告警静默
+// @Description 删除静默记录，删除后立即失效
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "静默ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/alerts/silences/{id} [delete]
+func (h *AlertSilenceHandler) DeleteSilence(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.silenceService.DeleteSilence(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "alert_silence", uint(id),
+		fmt.Sprintf("删除告警静默: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
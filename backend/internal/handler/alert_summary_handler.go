@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertSummaryHandler struct {
+	summaryService *service.AlertSummaryService
+	logService     *service.LogService
+}
+
+func NewAlertSummaryHandler(summaryService *service.AlertSummaryService) *AlertSummaryHandler {
+	return &AlertSummaryHandler{
+		summaryService: summaryService,
+		logService:     service.NewLogService(),
+	}
+}
+
+// GetSummary godoc
+// @Summary 获取每日告警汇总
+// @Description 统计指定日期的告警数量、各目标离线时长及证书事件，与每日汇总邮件内容一致
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param date query string false "日期 YYYY-MM-DD，默认昨天"
+// @Param tz query string false "时区(IANA 名称)，默认服务器时区"
+// @Success 200 {object} util.Response{data=model.AlertDailySummary} "获取成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/summary [get]
+func (h *AlertSummaryHandler) GetSummary(c *gin.Context) {
+	start, end, err := service.ParseAlertSummaryDate(c.Query("date"), c.Query("tz"), time.Now())
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+	summary, err := h.summaryService.BuildSummary(start, end)
+	if err != nil {
+		util.Error(c, 500, err.Error())
+		return
+	}
+	util.Success(c, summary)
+}
+
+// SendSummary godoc
+// @Summary 立即发送告警汇总邮件
+// @Description 生成指定日期的告警汇总并发送给设置中配置的汇总接收人，不影响每日定时发送
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param date query string false "日期 YYYY-MM-DD，默认昨天"
+// @Param tz query string false "时区(IANA 名称)，默认服务器时区"
+// @Success 200 {object} util.Response "发送成功"
+// @Failure 400 {object} util.Response "发送失败"
+// @Router /api/alerts/summary/send [post]
+func (h *AlertSummaryHandler) SendSummary(c *gin.Context) {
+	start, end, err := service.ParseAlertSummaryDate(c.Query("date"), c.Query("tz"), time.Now())
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+	if err := h.summaryService.SendSummary(start, end); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "send", "alert_summary", 0,
+		"发送告警汇总: "+start.Format("2006-01-02"), c.ClientIP())
+	util.Success(c, nil)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/errors"
+	"frp-web-panel/internal/middleware"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClientCredentialHandler struct {
+	credentialService *service.ClientCredentialService
+	logService        *service.LogService
+}
+
+func NewClientCredentialHandler(credentialSvc *service.ClientCredentialService, logSvc *service.LogService) *ClientCredentialHandler {
+	return &ClientCredentialHandler{
+		credentialService: credentialSvc,
+		logService:        logSvc,
+	}
+}
+
+// RotateDaemonToken godoc
+// @Summary 轮换守护进程凭证
+// @Description 生成新的守护进程凭证并推送给在线客户端，旧凭证在宽限期内仍可用于认证；客户端离线时需手动更新 daemon.yaml
+// @Tags 客户端管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "客户端ID"
+// @Success 200 {object} util.Response{data=service.DaemonTokenRotateResult}
+// @Failure 400 {object} util.Response
+// @Failure 500 {object} util.Response
+// @Router /api/clients/{id}/daemon-token/rotate [post]
+func (h *ClientCredentialHandler) RotateDaemonToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.AbortWithAppError(c, errors.NewBadRequest("无效的客户端ID"))
+		return
+	}
+
+	result, err := h.credentialService.RotateDaemonToken(uint(id))
+	if err != nil {
+		middleware.AbortWithAppError(c, errors.NewInternal("轮换凭证失败", err))
+		return
+	}
+
+	if userID, ok := c.Get("user_id"); ok {
+		h.logService.CreateLogAsync(userID.(uint), "rotate_token", "client", uint(id),
+			fmt.Sprintf("轮换守护进程凭证 (ID: %d, 已推送: %v)", id, result.Pushed), c.ClientIP())
+	}
+
+	util.Success(c, result)
+}
+
+// RevokeDaemonToken godoc
+// @Summary 吊销守护进程凭证
+// @Description 立即吊销客户端的守护进程凭证并断开其连接，需重新轮换凭证后才能再次连接
+// @Tags 客户端管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "客户端ID"
+// @Success 200 {object} util.Response
+// @Failure 400 {object} util.Response
+// @Failure 500 {object} util.Response
+// @Router /api/clients/{id}/daemon-token/revoke [post]
+func (h *ClientCredentialHandler) RevokeDaemonToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.AbortWithAppError(c, errors.NewBadRequest("无效的客户端ID"))
+		return
+	}
+
+	if err := h.credentialService.RevokeDaemonToken(uint(id)); err != nil {
+		middleware.AbortWithAppError(c, errors.NewInternal("吊销凭证失败", err))
+		return
+	}
+
+	if userID, ok := c.Get("user_id"); ok {
+		h.logService.CreateLogAsync(userID.(uint), "revoke_token", "client", uint(id),
+			fmt.Sprintf("吊销守护进程凭证 (ID: %d)", id), c.ClientIP())
+	}
+
+	util.Success(c, nil)
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
 	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
 	"frp-web-panel/internal/service"
 	"frp-web-panel/internal/websocket"
 	"net/http"
@@ -16,39 +20,177 @@ var daemonUpgrader = ws.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
+// daemonAuthTimeout 守护进程完成认证握手的超时时间
+const daemonAuthTimeout = 10 * time.Second
+
 type ClientDaemonWSHandler struct {
-	clientService *service.ClientService
+	clientService     *service.ClientService
+	credentialService *service.ClientCredentialService
+	clientCA          *service.ClientCAService
+	stateService      *service.ClientStateService
+	metricsRepo       *repository.ClientMetricsRepository
 }
 
-func NewClientDaemonWSHandler() *ClientDaemonWSHandler {
+func NewClientDaemonWSHandler(clientCA *service.ClientCAService) *ClientDaemonWSHandler {
 	return &ClientDaemonWSHandler{
-		clientService: service.NewClientService(),
+		clientService:     service.NewClientService(),
+		credentialService: service.NewClientCredentialService(websocket.ClientDaemonHubInstance),
+		clientCA:          clientCA,
+		stateService:      service.NewClientStateService(websocket.ClientDaemonHubInstance),
+		metricsRepo:       repository.NewClientMetricsRepository(),
 	}
 }
 
 // HandleConnection godoc
 // @Summary Daemon WebSocket 连接
-// @Description 客户端守护进程 WebSocket 连接接口，用于实时通信（无需认证，使用 Token 验证）
+// @Description 客户端守护进程 WebSocket 连接接口，用于实时通信。连接建立后服务端下发 auth_challenge，守护进程以 HMAC-SHA256(token, nonce:client_id) 签名回复 auth_response 完成认证。旧版守护进程通过查询参数传递 token 的方式已弃用，可通过 daemon_auth.disable_legacy_token 停用
 // @Tags 客户端管理
 // @Accept json
 // @Produce json
-// @Param client_id query int true "客户端ID"
-// @Param token query string true "客户端Token"
+// @Param client_id query int false "客户端ID（仅旧版认证）"
+// @Param token query string false "客户端Token（仅旧版认证）"
 // @Success 101 {string} string "WebSocket 连接成功"
 // @Failure 400 {object} object{error=string}
 // @Failure 401 {object} object{error=string}
 // @Router /api/clients/daemon/ws [get]
 func (h *ClientDaemonWSHandler) HandleConnection(c *gin.Context) {
-	logger.Debug("[守护进程WS] 收到WebSocket连接请求")
-	logger.Debugf("[守护进程WS] 请求路径: %s", c.Request.URL.Path)
-	logger.Debugf("[守护进程WS] 查询参数: %s", c.Request.URL.RawQuery)
-	logger.Debugf("[守护进程WS] 客户端IP: %s", c.ClientIP())
+	logger.Debugf("[守护进程WS] 收到WebSocket连接请求: ip=%s", c.ClientIP())
 
-	clientIDStr := c.Query("client_id")
-	token := c.Query("token")
+	certClientID, err := h.peerCertClientID(c)
+	if err != nil {
+		logger.Warnf("[守护进程WS] 客户端证书校验失败: ip=%s, 错误=%v", c.ClientIP(), err)
+		c.JSON(401, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("token") != "" {
+		h.handleLegacyConnection(c, certClientID)
+		return
+	}
+
+	conn, err := daemonUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Errorf("[守护进程WS] 错误: WebSocket升级失败: %v", err)
+		return
+	}
+
+	auth, err := h.authenticate(conn)
+	if err == nil && certClientID != 0 && certClientID != auth.clientID {
+		err = fmt.Errorf("客户端证书属于客户端 %d，与认证的客户端 %d 不一致", certClientID, auth.clientID)
+	}
+	if err != nil {
+		logger.Warnf("[守护进程WS] 认证失败: ip=%s, 错误=%v", c.ClientIP(), err)
+		conn.WriteJSON(websocket.Message{
+			Type:      websocket.MessageTypeAuthResult,
+			Data:      map[string]interface{}{"success": false, "message": err.Error()},
+			Timestamp: time.Now().Unix(),
+		})
+		conn.Close()
+		return
+	}
+
+	clientID := auth.clientID
+	if err := conn.WriteJSON(websocket.Message{
+		Type:      websocket.MessageTypeAuthResult,
+		ClientID:  clientID,
+		Data:      map[string]interface{}{"success": true},
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		logger.Warnf("[守护进程WS] 发送认证结果失败: %v", err)
+		conn.Close()
+		return
+	}
+
+	logger.Debugf("[守护进程WS] 客户端 %d 认证成功", clientID)
+	h.startSession(clientID, conn, auth.protocol)
+
+	if h.clientCA.NeedsRenewal(clientID, auth.certSerial) {
+		go h.renewClientCert(clientID)
+	}
+}
+
+// peerCertClientID 解析 TLS 连接中已验证的客户端证书对应的客户端ID，未出示证书时返回 0
+func (h *ClientDaemonWSHandler) peerCertClientID(c *gin.Context) (uint, error) {
+	if !h.clientCA.Enabled() {
+		return 0, nil
+	}
+
+	tlsState := c.Request.TLS
+	if tlsState == nil || len(tlsState.VerifiedChains) == 0 || len(tlsState.VerifiedChains[0]) == 0 {
+		if h.clientCA.RequireClientCert() {
+			return 0, fmt.Errorf("缺少客户端证书")
+		}
+		return 0, nil
+	}
+
+	return h.clientCA.ClientIDFromCert(tlsState.VerifiedChains[0][0])
+}
+
+// renewClientCert 为守护进程签发并推送新的 mTLS 证书
+func (h *ClientDaemonWSHandler) renewClientCert(clientID uint) {
+	// 等待连接在 Hub 中完成注册
+	time.Sleep(2 * time.Second)
+	if err := h.clientCA.RenewClientCert(clientID); err != nil {
+		logger.Warnf("[守护进程WS] 客户端 %d 证书签发失败: %v", clientID, err)
+	}
+}
+
+// daemonAuthResult 认证握手结果
+type daemonAuthResult struct {
+	clientID   uint
+	certSerial string // 守护进程当前持有的 mTLS 证书序列号
+	protocol   int    // 守护进程协议版本
+}
+
+// authenticate 执行挑战-响应认证握手
+func (h *ClientDaemonWSHandler) authenticate(conn *ws.Conn) (*daemonAuthResult, error) {
+	nonce, err := service.NewDaemonChallengeNonce()
+	if err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(daemonAuthTimeout))
+	if err := conn.WriteJSON(websocket.Message{
+		Type:      websocket.MessageTypeAuthChallenge,
+		Data:      map[string]interface{}{"nonce": nonce},
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		return nil, fmt.Errorf("发送认证挑战失败: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(daemonAuthTimeout))
+	var resp websocket.Message
+	if err := conn.ReadJSON(&resp); err != nil {
+		return nil, fmt.Errorf("读取认证响应失败: %v", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Time{})
 
-	logger.Debugf("[守护进程WS] 提取参数: client_id=%s, token=%s", clientIDStr, token)
+	if resp.Type != websocket.MessageTypeAuthResponse {
+		return nil, fmt.Errorf("期望 %s 消息，收到 %s", websocket.MessageTypeAuthResponse, resp.Type)
+	}
+	rawID, _ := resp.Data["client_id"].(float64)
+	signature, _ := resp.Data["signature"].(string)
+	if rawID <= 0 || signature == "" {
+		return nil, fmt.Errorf("认证响应缺少 client_id 或 signature")
+	}
 
+	clientID := uint(rawID)
+	if _, err := h.credentialService.VerifyDaemonSignature(clientID, nonce, signature); err != nil {
+		return nil, fmt.Errorf("客户端 %d: %v", clientID, err)
+	}
+
+	result := &daemonAuthResult{clientID: clientID}
+	result.certSerial, _ = resp.Data["cert_serial"].(string)
+	if protocol, ok := resp.Data["protocol"].(float64); ok {
+		result.protocol = int(protocol)
+	}
+	return result, nil
+}
+
+// handleLegacyConnection 兼容旧版守护进程的查询参数认证（已弃用），待其自更新后切换为握手认证
+func (h *ClientDaemonWSHandler) handleLegacyConnection(c *gin.Context, certClientID uint) {
+	clientIDStr := c.Query("client_id")
 	if clientIDStr == "" {
 		logger.Warn("[守护进程WS] 错误: 缺少client_id参数")
 		c.JSON(400, gin.H{"error": "缺少client_id参数"})
@@ -62,52 +204,45 @@ func (h *ClientDaemonWSHandler) HandleConnection(c *gin.Context) {
 		return
 	}
 
-	logger.Debugf("[守护进程WS] 解析client_id: %d", clientID)
-
-	client, err := h.clientService.GetClient(uint(clientID))
-	if err != nil {
-		logger.Warnf("[守护进程WS] 错误: 客户端不存在: ID=%d, 错误=%v", clientID, err)
-		c.JSON(401, gin.H{"error": "客户端不存在"})
+	if certClientID != 0 && certClientID != uint(clientID) {
+		logger.Warnf("[守护进程WS] 客户端证书属于客户端 %d，与请求的客户端 %d 不一致", certClientID, clientID)
+		c.JSON(401, gin.H{"error": "客户端证书与客户端ID不一致"})
 		return
 	}
 
-	logger.Debugf("[守护进程WS] 找到客户端: ID=%d, Name=%s", client.ID, client.Name)
-	logger.Debugf("[守护进程WS] 客户端Token: %s", client.Token)
-	logger.Debugf("[守护进程WS] 请求Token: %s", token)
-
-	if client.Token != token {
-		logger.Warn("[守护进程WS] 错误: Token验证失败")
-		c.JSON(401, gin.H{"error": "Token验证失败"})
+	if _, err := h.credentialService.VerifyLegacyToken(uint(clientID), c.Query("token")); err != nil {
+		logger.Warnf("[守护进程WS] 客户端 %d 旧版认证失败: %v", clientID, err)
+		c.JSON(401, gin.H{"error": err.Error()})
 		return
 	}
 
-	logger.Debug("[守护进程WS] Token验证成功")
+	logger.Warnf("[守护进程WS] 客户端 %d 使用已弃用的查询参数认证，请升级守护进程；全部升级后可配置 daemon_auth.disable_legacy_token 停用该方式", clientID)
 
-	logger.Debug("[守护进程WS] 开始升级为WebSocket连接...")
 	conn, err := daemonUpgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		logger.Errorf("[守护进程WS] 错误: WebSocket升级失败: %v", err)
 		return
 	}
-	logger.Debug("[守护进程WS] WebSocket升级成功")
+	h.startSession(uint(clientID), conn, 0)
+}
 
+// startSession 注册已认证的连接并启动读写协程，旧版查询参数认证的连接协议版本以版本上报为准
+func (h *ClientDaemonWSHandler) startSession(clientID uint, conn *ws.Conn, protocol int) {
 	daemonConn := &websocket.DaemonConnection{
-		ClientID: uint(clientID),
+		ClientID: clientID,
 		Conn:     conn,
 		Send:     make(chan []byte, 256),
 		Hub:      websocket.ClientDaemonHubInstance,
+		Protocol: protocol,
 	}
 
 	websocket.ClientDaemonHubInstance.Register <- daemonConn
 
 	logger.Debugf("[守护进程WS] 更新客户端 %d 的WS连接状态...", clientID)
-	if err := h.clientService.UpdateWSStatus(uint(clientID), true); err != nil {
+	if err := h.clientService.UpdateWSStatus(clientID, true); err != nil {
 		logger.Warnf("[守护进程WS] 警告: 更新WS状态失败: %v", err)
-	} else {
-		logger.Debug("[守护进程WS] 客户端 WS 连接状态已更新为 true")
 	}
 
-	logger.Debug("[守护进程WS] 启动读写协程...")
 	go daemonConn.WritePump()
 	go daemonConn.ReadPump(h)
 }
@@ -133,6 +268,12 @@ func (h *ClientDaemonWSHandler) HandleMessage(clientID uint, msg *websocket.Mess
 		h.handleFrpcControlResult(clientID, msg)
 	case "config_sync_result":
 		h.handleConfigSyncResult(clientID, msg)
+	case websocket.MessageTypeClientMetrics:
+		h.handleClientMetrics(clientID, msg)
+	case websocket.MessageTypeStateReport:
+		h.handleStateReport(clientID, msg)
+	case websocket.MessageTypeAck:
+		websocket.ClientDaemonHubInstance.HandleAck(clientID, msg)
 	}
 }
 
@@ -204,3 +345,43 @@ func (h *ClientDaemonWSHandler) handleConfigSyncResult(clientID uint, msg *webso
 	logger.Debugf("[客户端 %d] 收到配置同步结果消息", clientID)
 	websocket.ClientDaemonHubInstance.HandleConfigSyncResult(clientID, msg.Data)
 }
+
+func (h *ClientDaemonWSHandler) handleStateReport(clientID uint, msg *websocket.Message) {
+	report, err := service.ParseDaemonStateReport(msg.Data)
+	if err != nil {
+		logger.Warnf("[客户端 %d] %v", clientID, err)
+		return
+	}
+	logger.Debugf("[客户端 %d] 收到状态上报: config_version=%d, certs=%d", clientID, report.ConfigVersion, len(report.Certs))
+
+	// 对账过程需要等待守护进程确认，不能阻塞读协程
+	go func() {
+		result, err := h.stateService.Reconcile(clientID, report)
+		if err != nil {
+			logger.Errorf("[客户端 %d] 状态对账失败: %v", clientID, err)
+			return
+		}
+		if result.ConfigPushed || len(result.CertsPushed) > 0 {
+			logger.Infof("[客户端 %d] 状态对账完成: 重新推送配置=%v, 补推证书=%v", clientID, result.ConfigPushed, result.CertsPushed)
+		}
+	}()
+}
+
+func (h *ClientDaemonWSHandler) handleClientMetrics(clientID uint, msg *websocket.Message) {
+	raw, err := json.Marshal(msg.Data)
+	if err != nil {
+		return
+	}
+	var record model.ClientMetricsHistory
+	if err := json.Unmarshal(raw, &record); err != nil {
+		logger.Warnf("[客户端 %d] 主机指标消息格式错误: %v", clientID, err)
+		return
+	}
+	record.ID = 0
+	record.ClientID = clientID
+	record.RecordTime = time.Now()
+
+	if err := h.metricsRepo.Create(&record); err != nil {
+		logger.Errorf("[客户端 %d] 保存主机指标失败: %v", clientID, err)
+	}
+}
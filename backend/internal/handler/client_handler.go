@@ -5,6 +5,7 @@ import (
 	"frp-web-panel/internal/errors"
 	"frp-web-panel/internal/middleware"
 	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
 	"frp-web-panel/internal/service"
 	"frp-web-panel/internal/util"
 	"strconv"
@@ -17,14 +18,16 @@ type ClientHandler struct {
 	clientRegisterService *service.ClientRegisterService
 	clientUpdateService   *service.ClientUpdateService
 	logService            *service.LogService
+	metricsRepo           *repository.ClientMetricsRepository
 }
 
-func NewClientHandler(clientSvc *service.ClientService, registerSvc *service.ClientRegisterService, updateSvc *service.ClientUpdateService, logSvc *service.LogService) *ClientHandler {
+func NewClientHandler(clientSvc *service.ClientService, registerSvc *service.ClientRegisterService, updateSvc *service.ClientUpdateService, logSvc *service.LogService, metricsRepo *repository.ClientMetricsRepository) *ClientHandler {
 	return &ClientHandler{
 		clientService:         clientSvc,
 		clientRegisterService: registerSvc,
 		clientUpdateService:   updateSvc,
 		logService:            logSvc,
+		metricsRepo:           metricsRepo,
 	}
 }
 
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	apperrors "frp-web-panel/internal/errors"
+	"frp-web-panel/internal/middleware"
+	"frp-web-panel/internal/util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMetricsHistory godoc
+// @Summary 获取客户端历史指标
+// @Description 获取指定客户端守护进程上报的主机与 frpc 进程历史指标数据
+// @Tags 客户端管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "客户端ID"
+// @Param days query int false "查询天数(1-90)" default(1)
+// @Success 200 {object} util.Response{data=[]object}
+// @Failure 400 {object} util.Response
+// @Failure 500 {object} util.Response
+// @Router /api/clients/{id}/metrics-history [get]
+func (h *ClientHandler) GetMetricsHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.AbortWithAppError(c, apperrors.NewBadRequest("无效的ID参数"))
+		return
+	}
+
+	days := 1
+	if d := c.Query("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed >= 1 && parsed <= 90 {
+			days = parsed
+		}
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -days)
+
+	records, err := h.metricsRepo.GetHistory(uint(id), start, end)
+	if err != nil {
+		middleware.AbortWithAppError(c, apperrors.NewInternal("获取历史指标失败: "+err.Error(), err))
+		return
+	}
+	util.SuccessResponse(c, records)
+}
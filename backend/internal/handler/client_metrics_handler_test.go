@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupClientMetricsTestDB 创建内存数据库
+func setupClientMetricsTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, db.AutoMigrate(&model.ClientMetricsHistory{}))
+
+	database.DB = db
+	return db
+}
+
+// setupClientMetricsTestRouter 创建测试路由
+func setupClientMetricsTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewClientHandler(nil, nil, nil, nil, repository.NewClientMetricsRepository())
+	r.GET("/api/clients/:id/metrics-history", handler.GetMetricsHistory)
+	return r
+}
+
+func getClientMetricsHistory(t *testing.T, router *gin.Engine, url string) (int, []model.ClientMetricsHistory) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Code int                          `json:"code"`
+		Data []model.ClientMetricsHistory `json:"data"`
+	}
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp.Data
+}
+
+// TestGetClientMetricsHistory 测试按天数查询客户端历史指标
+func TestGetClientMetricsHistory(t *testing.T) {
+	db := setupClientMetricsTestDB(t)
+	router := setupClientMetricsTestRouter()
+
+	now := time.Now()
+	for _, r := range []model.ClientMetricsHistory{
+		{ClientID: 1, CpuPercent: 10, RecordTime: now.Add(-time.Hour)},
+		{ClientID: 1, CpuPercent: 20, RecordTime: now.AddDate(0, 0, -3)},
+		{ClientID: 1, CpuPercent: 30, RecordTime: now.AddDate(0, 0, -30)},
+		{ClientID: 2, CpuPercent: 40, RecordTime: now.Add(-time.Hour)},
+	} {
+		r := r
+		require.NoError(t, db.Create(&r).Error)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+	}{
+		{"默认查询1天", "", 1},
+		{"查询7天", "?days=7", 2},
+		{"查询30天以上", "?days=31", 3},
+		{"超出上限使用默认值", "?days=91", 1},
+		{"非法参数使用默认值", "?days=abc", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, records := getClientMetricsHistory(t, router, "/api/clients/1/metrics-history"+tt.query)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Len(t, records, tt.wantCount)
+			for i := 1; i < len(records); i++ {
+				assert.False(t, records[i].RecordTime.Before(records[i-1].RecordTime), "记录应按时间升序")
+			}
+		})
+	}
+}
+
+// TestGetClientMetricsHistory_InvalidID 测试无效客户端ID
+func TestGetClientMetricsHistory_InvalidID(t *testing.T) {
+	setupClientMetricsTestDB(t)
+	router := setupClientMetricsTestRouter()
+
+	code, _ := getClientMetricsHistory(t, router, "/api/clients/abc/metrics-history")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -5,7 +5,6 @@ import (
 	apperrors "frp-web-panel/internal/errors"
 	"frp-web-panel/internal/middleware"
 	"frp-web-panel/internal/model"
-	"frp-web-panel/internal/repository"
 	"frp-web-panel/internal/service"
 	"frp-web-panel/internal/util"
 	"strconv"
@@ -14,16 +13,16 @@ import (
 )
 
 type FrpServerHandler struct {
-	service     *service.FrpServerService
-	logService  *service.LogService
-	metricsRepo *repository.ServerMetricsRepository
+	service       *service.FrpServerService
+	logService    *service.LogService
+	metricsRollup *service.MetricsRollupService
 }
 
-func NewFrpServerHandler(svc *service.FrpServerService, logSvc *service.LogService, metricsRepo *repository.ServerMetricsRepository) *FrpServerHandler {
+func NewFrpServerHandler(svc *service.FrpServerService, logSvc *service.LogService, metricsRollup *service.MetricsRollupService) *FrpServerHandler {
 	return &FrpServerHandler{
-		service:     svc,
-		logService:  logSvc,
-		metricsRepo: metricsRepo,
+		service:       svc,
+		logService:    logSvc,
+		metricsRollup: metricsRollup,
 	}
 }
 
@@ -55,13 +55,13 @@ func (h *FrpServerHandler) GetMetrics(c *gin.Context) {
 
 // GetMetricsHistory godoc
 // @Summary 获取服务器历史指�?
-// @Description 获取指定 FRP 服务器的历史运行指标数据
+// @Description 获取指定 FRP 服务器的历史运行指标数据，根据查询天数自动选择原始或汇总粒度
 // @Tags FRP服务�?
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "服务器ID"
-// @Param days query int false "查询天数(1-7)" default(1)
+// @Param days query int false "查询天数(1-90)" default(1)
 // @Success 200 {object} util.Response{data=[]object}
 // @Failure 400 {object} util.Response
 // @Failure 500 {object} util.Response
@@ -75,7 +75,7 @@ func (h *FrpServerHandler) GetMetricsHistory(c *gin.Context) {
 
 	days := 1
 	if d := c.Query("days"); d != "" {
-		if parsed, err := strconv.Atoi(d); err == nil && parsed >= 1 && parsed <= 7 {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed >= 1 && parsed <= 90 {
 			days = parsed
 		}
 	}
@@ -83,10 +83,11 @@ func (h *FrpServerHandler) GetMetricsHistory(c *gin.Context) {
 	end := time.Now()
 	start := end.AddDate(0, 0, -days)
 
-	records, err := h.metricsRepo.GetHistory(uint(id), start, end)
+	records, resolution, err := h.metricsRollup.GetServerHistory(uint(id), start, end)
 	if err != nil {
 		middleware.AbortWithAppError(c, apperrors.NewInternal("获取历史指标失败: "+err.Error(), err))
 		return
 	}
+	c.Header("X-Metrics-Resolution", resolution)
 	util.SuccessResponse(c, records)
 }
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FrpsPluginHandler struct {
+	pluginService *service.FrpsPluginService
+}
+
+func NewFrpsPluginHandler(pluginService *service.FrpsPluginService) *FrpsPluginHandler {
+	return &FrpsPluginHandler{
+		pluginService: pluginService,
+	}
+}
+
+// Handle godoc
+// @Summary frps 插件回调
+// @Description frps HTTP 插件回调入口，由托管的 frps 在客户端登录、注册代理、建立工作连接和用户连接时调用，拒绝未在面板登记的客户端和代理，通过路径中的服务器令牌鉴权；响应格式遵循 frps 插件协议
+// @Tags frps插件
+// @Accept json
+// @Produce json
+// @Param server_id path int true "服务器ID"
+// @Param token path string true "插件回调令牌"
+// @Param request body model.FrpsPluginRequest true "插件请求"
+// @Success 200 {object} model.FrpsPluginResponse "插件响应"
+// @Failure 400 {object} map[string]string "请求格式错误"
+// @Failure 403 {object} map[string]string "令牌无效"
+// @Router /api/frps-plugin/{server_id}/{token} [post]
+func (h *FrpsPluginHandler) Handle(c *gin.Context) {
+	serverID, err := strconv.ParseUint(c.Param("server_id"), 10, 32)
+	if err != nil || !h.pluginService.VerifyToken(uint(serverID), c.Param("token")) {
+		c.JSON(403, gin.H{"error": "令牌无效"})
+		return
+	}
+
+	var req model.FrpsPluginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "请求格式错误"})
+		return
+	}
+	c.JSON(200, h.pluginService.Handle(uint(serverID), &req))
+}
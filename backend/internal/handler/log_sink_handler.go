@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LogSinkHandler struct {
+	logStreamService *service.LogStreamService
+	logService       *service.LogService
+}
+
+func NewLogSinkHandler(logStreamService *service.LogStreamService) *LogSinkHandler {
+	return &LogSinkHandler{
+		logStreamService: logStreamService,
+		logService:       service.NewLogService(),
+	}
+}
+
+// LogSinkRequest 日志转发目标请求，密码只写不读
+type LogSinkRequest struct {
+	model.LogSink
+	Password      string `json:"password"`       // Loki Basic 认证密码或 Bearer 令牌，更新时留空保留原值
+	ClearPassword bool   `json:"clear_password"` // 更新时清空密码
+}
+
+func bindLogSinkRequest(c *gin.Context) (*LogSinkRequest, bool) {
+	req := LogSinkRequest{LogSink: model.LogSink{Enabled: true}}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.Error(c, 400, "参数错误")
+		return nil, false
+	}
+	req.LogSink.Password = req.Password
+	return &req, true
+}
+
+// GetAllSinks godoc
+// @Summary 获取日志转发目标列表
+// @Description 获取全部 syslog/Loki 转发目标及推送状态，密码不返回，通过 has_password 标识是否已配置
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.LogSink} "获取成功"
+// @Failure 500 {object} util.Response "获取转发目标失败"
+// @Router /api/log-sinks [get]
+func (h *LogSinkHandler) GetAllSinks(c *gin.Context) {
+	sinks, err := h.logStreamService.GetAllSinks()
+	if err != nil {
+		util.Error(c, 500, "获取转发目标失败")
+		return
+	}
+	util.Success(c, sinks)
+}
+
+// CreateSink godoc
+// @Summary 创建日志转发目标
+// @Description 创建 syslog(RFC5424, UDP/TCP/TLS) 或 Loki 转发目标，告警事件、操作日志和系统事件按所选事件流转发
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param sink body LogSinkRequest true "转发目标"
+// @Success 200 {object} util.Response{data=model.LogSink} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/log-sinks [post]
+func (h *LogSinkHandler) CreateSink(c *gin.Context) {
+	req, ok := bindLogSinkRequest(c)
+	if !ok {
+		return
+	}
+	sink := &req.LogSink
+	if err := h.logStreamService.CreateSink(sink); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "log_sink", sink.ID,
+		fmt.Sprintf("创建日志转发目标: %s (%s)", sink.Name, sink.Type), c.ClientIP())
+	util.Success(c, sink)
+}
+
+// UpdateSink godoc
+// @Summary 更新日志转发目标
+// @Description 更新转发目标配置，密码留空时保留原值
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转发目标ID"
+// @Param sink body LogSinkRequest true "转发目标"
+// @Success 200 {object} util.Response{data=model.LogSink} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/log-sinks/{id} [put]
+func (h *LogSinkHandler) UpdateSink(c *gin.Context) {
+	req, ok := bindLogSinkRequest(c)
+	if !ok {
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	req.LogSink.ID = uint(id)
+
+	sink, err := h.logStreamService.UpdateSink(&req.LogSink, req.ClearPassword)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "log_sink", sink.ID,
+		fmt.Sprintf("更新日志转发目标: %s (%s)", sink.Name, sink.Type), c.ClientIP())
+	util.Success(c, sink)
+}
+
+// DeleteSink godoc
+// @Summary 删除日志转发目标
+// @Description 删除转发目标，尚未推送的缓冲事件一并丢弃
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转发目标ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/log-sinks/{id} [delete]
+func (h *LogSinkHandler) DeleteSink(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.logStreamService.DeleteSink(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "log_sink", uint(id),
+		fmt.Sprintf("删除日志转发目标: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// TestSink godoc
+// @Summary 测试日志转发目标
+// @Description 向已保存的转发目标发送一条测试事件并返回发送结果
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转发目标ID"
+// @Success 200 {object} util.Response "发送成功"
+// @Failure 400 {object} util.Response "发送失败"
+// @Router /api/log-sinks/{id}/test [post]
+func (h *LogSinkHandler) TestSink(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.logStreamService.TestSink(uint(id)); err != nil {
+		util.Error(c, 400, "发送测试事件失败: "+err.Error())
+		return
+	}
+	util.Success(c, nil)
+}
+
+// TestSinkConfig godoc
+// @Summary 测试未保存的日志转发配置
+// @Description 使用表单中的配置发送测试事件；传入 id 且密码留空时沿用已保存的值
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param sink body LogSinkRequest true "转发目标"
+// @Success 200 {object} util.Response "发送成功"
+// @Failure 400 {object} util.Response "发送失败"
+// @Router /api/log-sinks/test [post]
+func (h *LogSinkHandler) TestSinkConfig(c *gin.Context) {
+	req, ok := bindLogSinkRequest(c)
+	if !ok {
+		return
+	}
+	if err := h.logStreamService.TestSinkConfig(&req.LogSink); err != nil {
+		util.Error(c, 400, "发送测试事件失败: "+err.Error())
+		return
+	}
+	util.Success(c, nil)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageTemplateHandler struct {
+	templateService *service.MessageTemplateService
+	logService      *service.LogService
+}
+
+func NewMessageTemplateHandler(templateService *service.MessageTemplateService) *MessageTemplateHandler {
+	return &MessageTemplateHandler{
+		templateService: templateService,
+		logService:      service.NewLogService(),
+	}
+}
+
+// GetTemplates godoc
+// @Summary 获取消息模板列表
+// @Description 获取用户自定义的告警消息模板，未配置的事件类型使用内置模板
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.MessageTemplate} "获取成功"
+// @Failure 500 {object} util.Response "获取消息模板失败"
+// @Router /api/alerts/message-templates [get]
+func (h *MessageTemplateHandler) GetTemplates(c *gin.Context) {
+	templates, err := h.templateService.GetAll()
+	if err != nil {
+		util.Error(c, 500, "获取消息模板失败")
+		return
+	}
+	util.Success(c, templates)
+}
+
+// GetDefaults godoc
+// @Summary 获取内置消息模板
+// @Description 获取指定语言的内置模板、可单独配置模板的系统事件类型及模板变量说明
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param locale query string false "语言 zh-CN/en-US，默认使用通知语言设置"
+// @Success 200 {object} util.Response{data=service.MessageTemplateDefaults} "获取成功"
+// @Router /api/alerts/message-templates/defaults [get]
+func (h *MessageTemplateHandler) GetDefaults(c *gin.Context) {
+	util.Success(c, h.templateService.GetDefaults(c.Query("locale")))
+}
+
+// CreateTemplate godoc
+// @Summary 创建消息模板
+// @Description 为事件类型和发送方式(email/im)创建模板，标题和正文使用 Go text/template 语法
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param template body model.MessageTemplate true "消息模板"
+// @Success 200 {object} util.Response{data=model.MessageTemplate} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/message-templates [post]
+func (h *MessageTemplateHandler) CreateTemplate(c *gin.Context) {
+	tpl := model.MessageTemplate{Enabled: true}
+	if err := c.ShouldBindJSON(&tpl); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	tpl.ID = 0
+	if err := h.templateService.Create(&tpl); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "message_template", tpl.ID,
+		fmt.Sprintf("创建消息模板: %s (%s)", tpl.EventType, tpl.Channel), c.ClientIP())
+	util.Success(c, tpl)
+}
+
+// UpdateTemplate godoc
+// @Summary 更新消息模板
+// @Description 更新消息模板，停用后恢复使用内置模板
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "模板ID"
+// @Param template body model.MessageTemplate true "消息模板"
+// @Success 200 {object} util.Response{data=model.MessageTemplate} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/message-templates/{id} [put]
+func (h *MessageTemplateHandler) UpdateTemplate(c *gin.Context) {
+	var tpl model.MessageTemplate
+	if err := c.ShouldBindJSON(&tpl); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	tpl.ID = uint(id)
+
+	updated, err := h.templateService.Update(&tpl)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "message_template", updated.ID,
+		fmt.Sprintf("更新消息模板: %s (%s)", updated.EventType, updated.Channel), c.ClientIP())
+	util.Success(c, updated)
+}
+
+// DeleteTemplate godoc
+// @Summary 删除消息模板
+// @Description 删除消息模板，该事件类型恢复使用内置模板
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "模板ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/alerts/message-templates/{id} [delete]
+func (h *MessageTemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.templateService.Delete(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "message_template", uint(id),
+		fmt.Sprintf("删除消息模板: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// PreviewTemplate godoc
+// @Summary 预览消息模板
+// @Description 使用示例告警日志渲染模板，标题和正文都为空时预览当前生效的模板；发送方式为 email 时同时返回邮件 HTML
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preview body service.MessageTemplatePreviewRequest true "预览请求"
+// @Success 200 {object} util.Response{data=service.MessageTemplatePreview} "渲染成功"
+// @Failure 400 {object} util.Response "模板错误"
+// @Router /api/alerts/message-templates/preview [post]
+func (h *MessageTemplateHandler) PreviewTemplate(c *gin.Context) {
+	var req service.MessageTemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	preview, err := h.templateService.Preview(&req)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+	util.Success(c, preview)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/expfmt"
+)
+
+type MetricsHandler struct {
+	exporter *service.PrometheusExporter
+}
+
+func NewMetricsHandler(exporter *service.PrometheusExporter) *MetricsHandler {
+	return &MetricsHandler{exporter: exporter}
+}
+
+// Export godoc
+// @Summary Prometheus 指标
+// @Description 以 Prometheus 文本格式导出面板自身的指标：代理流量与速率、客户端在线及配置同步状态、证书剩余天数、DNS 记录状态、告警统计、守护进程连接数与后台任务健康状态。支持配置的抓取令牌或登录用户的 JWT
+// @Tags 监控
+// @Produce plain
+// @Security BearerAuth
+// @Success 200 {string} string "Prometheus 指标"
+// @Failure 401 {object} util.Response
+// @Router /metrics [get]
+func (h *MetricsHandler) Export(c *gin.Context) {
+	format := expfmt.Negotiate(c.Request.Header)
+	c.Header("Content-Type", string(format))
+	c.Status(200)
+	if err := h.exporter.Write(c.Writer, format); err != nil {
+		logger.Errorf("[Prometheus] 输出指标失败: %v", err)
+	}
+}
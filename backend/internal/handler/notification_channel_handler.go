@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationChannelHandler struct {
+	notificationService *service.NotificationService
+	logService          *service.LogService
+}
+
+func NewNotificationChannelHandler(notificationService *service.NotificationService) *NotificationChannelHandler {
+	return &NotificationChannelHandler{
+		notificationService: notificationService,
+		logService:          service.NewLogService(),
+	}
+}
+
+// NotificationChannelRequest 通知渠道请求，令牌和密钥只写不读
+type NotificationChannelRequest struct {
+	model.NotificationChannel
+	Token       string `json:"token"`        // 创建时按渠道类型必填，更新时留空保留原值
+	Secret      string `json:"secret"`       // 钉钉/飞书加签密钥，更新时留空保留原值
+	ClearToken  bool   `json:"clear_token"`  // 更新时清空令牌
+	ClearSecret bool   `json:"clear_secret"` // 更新时清空加签密钥
+}
+
+func bindChannelRequest(c *gin.Context) (*NotificationChannelRequest, bool) {
+	req := NotificationChannelRequest{NotificationChannel: model.NotificationChannel{Enabled: true}}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.Error(c, 400, "参数错误")
+		return nil, false
+	}
+	req.NotificationChannel.Token = req.Token
+	req.NotificationChannel.Secret = req.Secret
+	return &req, true
+}
+
+// GetAllChannels godoc
+// @Summary 获取通知渠道列表
+// @Description 获取全部通知渠道，令牌和加签密钥不返回，通过 has_token/has_secret 标识是否已配置
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.NotificationChannel} "获取成功"
+// @Failure 500 {object} util.Response "获取通知渠道失败"
+// @Router /api/alerts/channels [get]
+func (h *NotificationChannelHandler) GetAllChannels(c *gin.Context) {
+	channels, err := h.notificationService.GetAllChannels()
+	if err != nil {
+		util.Error(c, 500, "获取通知渠道失败")
+		return
+	}
+	util.Success(c, channels)
+}
+
+// CreateChannel godoc
+// @Summary 创建通知渠道
+// @Description 创建钉钉、企业微信、飞书、Telegram、Slack、ntfy、Gotify 或通用 Webhook 通知渠道，告警规则和接收人分组可引用
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param channel body NotificationChannelRequest true "通知渠道"
+// @Success 200 {object} util.Response{data=model.NotificationChannel} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/channels [post]
+func (h *NotificationChannelHandler) CreateChannel(c *gin.Context) {
+	req, ok := bindChannelRequest(c)
+	if !ok {
+		return
+	}
+	channel := &req.NotificationChannel
+	if err := h.notificationService.CreateChannel(channel); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "notification_channel", channel.ID,
+		fmt.Sprintf("创建通知渠道: %s (%s)", channel.Name, channel.Type), c.ClientIP())
+	util.Success(c, channel)
+}
+
+// UpdateChannel godoc
+// @Summary 更新通知渠道
+// @Description 更新通知渠道配置，令牌和加签密钥留空时保留原值
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "渠道ID"
+// @Param channel body NotificationChannelRequest true "通知渠道"
+// @Success 200 {object} util.Response{data=model.NotificationChannel} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/alerts/channels/{id} [put]
+func (h *NotificationChannelHandler) UpdateChannel(c *gin.Context) {
+	req, ok := bindChannelRequest(c)
+	if !ok {
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	req.NotificationChannel.ID = uint(id)
+
+	channel, err := h.notificationService.UpdateChannel(&req.NotificationChannel, req.ClearToken, req.ClearSecret)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "notification_channel", channel.ID,
+		fmt.Sprintf("更新通知渠道: %s (%s)", channel.Name, channel.Type), c.ClientIP())
+	util.Success(c, channel)
+}
+
+// DeleteChannel godoc
+// @Summary 删除通知渠道
+// @Description 删除通知渠道，引用该渠道的规则和分组不再向其发送
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "渠道ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/alerts/channels/{id} [delete]
+func (h *NotificationChannelHandler) DeleteChannel(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.notificationService.DeleteChannel(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "notification_channel", uint(id),
+		fmt.Sprintf("删除通知渠道: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// TestChannel godoc
+// @Summary 测试通知渠道
+// @Description 向已保存的通知渠道发送一条测试消息并返回发送结果
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "渠道ID"
+// @Success 200 {object} util.Response "发送成功"
+// @Failure 400 {object} util.Response "发送失败"
+// @Router /api/alerts/channels/{id}/test [post]
+func (h *NotificationChannelHandler) TestChannel(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.notificationService.TestChannel(uint(id)); err != nil {
+		util.Error(c, 400, "发送测试消息失败: "+err.Error())
+		return
+	}
+	util.Success(c, nil)
+}
+
+// TestChannelConfig godoc
+// @Summary 测试未保存的通知渠道配置
+// @Description 使用表单中的配置发送测试消息；传入 id、渠道类型和地址未改动且令牌或密钥留空时沿用已保存的值
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param channel body NotificationChannelRequest true "通知渠道"
+// @Success 200 {object} util.Response "发送成功"
+// @Failure 400 {object} util.Response "发送失败"
+// @Router /api/alerts/channels/test [post]
+func (h *NotificationChannelHandler) TestChannelConfig(c *gin.Context) {
+	req, ok := bindChannelRequest(c)
+	if !ok {
+		return
+	}
+	if err := h.notificationService.TestChannelConfig(&req.NotificationChannel); err != nil {
+		util.Error(c, 400, "发送测试消息失败: "+err.Error())
+		return
+	}
+	util.Success(c, nil)
+}
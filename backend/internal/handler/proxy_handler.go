@@ -18,6 +18,7 @@ import (
 	"frp-web-panel/internal/util"
 	"frp-web-panel/internal/websocket"
 	"strconv"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,8 +41,23 @@ func NewProxyHandler() *ProxyHandler {
 	}
 }
 
-// pushConfigUpdate 推送配置更新到客户端
+// configPushLocks key: 客户端ID，value: *sync.Mutex，同一客户端的配置推送按顺序执行，避免并发推送使用相同的版本号
+var configPushLocks sync.Map
+
+// pushConfigUpdate 在后台推送配置更新到客户端。推送需要等待守护进程确认，不能阻塞请求，
+// 推送结果通过客户端的配置同步状态反映
 func (h *ProxyHandler) pushConfigUpdate(clientID uint) {
+	go func() {
+		value, _ := configPushLocks.LoadOrStore(clientID, &sync.Mutex{})
+		lock := value.(*sync.Mutex)
+		lock.Lock()
+		defer lock.Unlock()
+		h.pushConfigUpdateSync(clientID)
+	}()
+}
+
+// pushConfigUpdateSync 推送配置更新到客户端，等待守护进程确认
+func (h *ProxyHandler) pushConfigUpdateSync(clientID uint) {
 	logger.Debugf("[配置推送] 开始推送配置到客户端 ID=%d", clientID)
 
 	// 检查客户端是否在线
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProxyProbeHandler struct {
+	probeService *service.ProxyProbeService
+	logService   *service.LogService
+}
+
+func NewProxyProbeHandler(probeService *service.ProxyProbeService) *ProxyProbeHandler {
+	return &ProxyProbeHandler{
+		probeService: probeService,
+		logService:   service.NewLogService(),
+	}
+}
+
+// GetProbes godoc
+// @Summary 获取代理拨测列表
+// @Description 获取代理配置的外部拨测及最近一次拨测状态
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Success 200 {object} util.Response{data=[]model.ProxyProbe} "拨测列表"
+// @Failure 500 {object} util.Response "获取拨测列表失败"
+// @Router /api/proxies/{id}/probes [get]
+func (h *ProxyProbeHandler) GetProbes(c *gin.Context) {
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	probes, err := h.probeService.GetProbes(uint(proxyID))
+	if err != nil {
+		util.Error(c, 500, "获取拨测列表失败")
+		return
+	}
+	util.Success(c, probes)
+}
+
+// CreateProbe godoc
+// @Summary 创建代理拨测
+// @Description 为代理创建外部拨测：tcp 连接服务器远程端口，http 请求自定义域名并校验状态码和关键字，tls 检查证书有效期；目标留空时按代理配置推导
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Param probe body model.ProxyProbe true "拨测配置"
+// @Success 200 {object} util.Response{data=model.ProxyProbe} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/proxies/{id}/probes [post]
+func (h *ProxyProbeHandler) CreateProbe(c *gin.Context) {
+	probe := model.ProxyProbe{Enabled: true}
+	if err := c.ShouldBindJSON(&probe); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	probe.ProxyID = uint(proxyID)
+
+	if err := h.probeService.CreateProbe(&probe); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "proxy_probe", probe.ID,
+		fmt.Sprintf("创建代理拨测: %s (%s)", probe.ProxyName, probe.Type), c.ClientIP())
+	util.Success(c, probe)
+}
+
+// UpdateProbe godoc
+// @Summary 更新代理拨测
+// @Description 更新拨测类型、目标、校验条件和间隔，所属代理不可修改
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "拨测ID"
+// @Param probe body model.ProxyProbe true "拨测配置"
+// @Success 200 {object} util.Response{data=model.ProxyProbe} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/proxy-probes/{id} [put]
+func (h *ProxyProbeHandler) UpdateProbe(c *gin.Context) {
+	probe := model.ProxyProbe{Enabled: true}
+	if err := c.ShouldBindJSON(&probe); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	probe.ID = uint(id)
+
+	updated, err := h.probeService.UpdateProbe(&probe)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "proxy_probe", updated.ID,
+		fmt.Sprintf("更新代理拨测: %s (%s)", updated.ProxyName, updated.Type), c.ClientIP())
+	util.Success(c, updated)
+}
+
+// DeleteProbe godoc
+// @Summary 删除代理拨测
+// @Description 删除拨测配置及其历史结果
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "拨测ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/proxy-probes/{id} [delete]
+func (h *ProxyProbeHandler) DeleteProbe(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.probeService.DeleteProbe(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "proxy_probe", uint(id),
+		fmt.Sprintf("删除代理拨测: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// RunProbe godoc
+// @Summary 立即执行拨测
+// @Description 立即执行一次拨测并返回结果，结果计入可用率统计
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "拨测ID"
+// @Success 200 {object} util.Response{data=model.ProxyProbeResult} "拨测结果"
+// @Failure 404 {object} util.Response "拨测不存在"
+// @Router /api/proxy-probes/{id}/run [post]
+func (h *ProxyProbeHandler) RunProbe(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	result, err := h.probeService.RunNow(uint(id))
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+	util.Success(c, result)
+}
+
+// GetProbeResults godoc
+// @Summary 获取拨测历史
+// @Description 获取拨测最近的成功状态和耗时记录，按时间升序，最多返回1000条
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "拨测ID"
+// @Param hours query int false "时间范围(小时)，默认24，最大720"
+// @Success 200 {object} util.Response{data=[]model.ProxyProbeResult} "拨测历史"
+// @Failure 404 {object} util.Response "拨测不存在"
+// @Router /api/proxy-probes/{id}/results [get]
+func (h *ProxyProbeHandler) GetProbeResults(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours <= 0 || hours > 720 {
+		hours = 24
+	}
+
+	results, err := h.probeService.GetResults(uint(id), hours)
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+	util.Success(c, results)
+}
+
+// GetProbeUptime godoc
+// @Summary 获取拨测可用率
+// @Description 获取拨测最近24小时、7天、30天的可用率和平均耗时，无拨测数据时可用率为 -1
+// @Tags 代理拨测
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "拨测ID"
+// @Success 200 {object} util.Response{data=[]model.ProxyProbeUptime} "可用率"
+// @Failure 404 {object} util.Response "拨测不存在"
+// @Router /api/proxy-probes/{id}/uptime [get]
+func (h *ProxyProbeHandler) GetProbeUptime(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	uptime, err := h.probeService.GetUptime(uint(id))
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+	util.Success(c, uptime)
+}
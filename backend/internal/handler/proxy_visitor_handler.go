@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProxyVisitorHandler struct {
+	visitorService *service.ProxyVisitorService
+	accessService  *service.ProxyAccessService
+	logService     *service.LogService
+}
+
+func NewProxyVisitorHandler(visitorService *service.ProxyVisitorService, accessService *service.ProxyAccessService) *ProxyVisitorHandler {
+	return &ProxyVisitorHandler{
+		visitorService: visitorService,
+		accessService:  accessService,
+		logService:     service.NewLogService(),
+	}
+}
+
+// GetRecentVisitors godoc
+// @Summary 获取代理最近访客
+// @Description 获取代理最近的来源 IP、归属地、连接次数及首次/最后访问时间，需在系统设置中启用 frps 插件后才会记录
+// @Tags 代理管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Param hours query int false "时间范围(小时)，默认24，最大720"
+// @Param limit query int false "返回数量，默认100，最大500"
+// @Success 200 {object} util.Response{data=[]model.ProxyVisitor} "访客列表"
+// @Failure 404 {object} util.Response "代理不存在"
+// @Router /api/proxies/{id}/visitors [get]
+func (h *ProxyVisitorHandler) GetRecentVisitors(c *gin.Context) {
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours <= 0 || hours > 720 {
+		hours = 24
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	visitors, err := h.visitorService.GetRecentVisitors(uint(proxyID), hours, limit)
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+	util.Success(c, visitors)
+}
+
+// GetAccessPolicy godoc
+// @Summary 获取代理访问策略
+// @Description 获取代理的来源 IP/CIDR 黑白名单和地区规则，未配置时返回空
+// @Tags 代理管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Success 200 {object} util.Response{data=model.ProxyAccessPolicy} "访问策略"
+// @Failure 500 {object} util.Response "获取访问策略失败"
+// @Router /api/proxies/{id}/access-policy [get]
+func (h *ProxyVisitorHandler) GetAccessPolicy(c *gin.Context) {
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	policy, err := h.accessService.GetPolicy(uint(proxyID))
+	if err != nil {
+		util.Error(c, 500, "获取访问策略失败")
+		return
+	}
+	util.Success(c, policy)
+}
+
+// SaveAccessPolicy godoc
+// @Summary 保存代理访问策略
+// @Description 创建或更新代理的访问策略，通过 frps NewUserConn 插件在服务端拦截来源连接；需在系统设置中启用 frps 插件。列表字段以逗号或换行分隔，配置了任一白名单时未命中白名单的来源被拒绝，黑名单优先
+// @Tags 代理管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Param policy body model.ProxyAccessPolicy true "访问策略"
+// @Success 200 {object} util.Response{data=model.ProxyAccessPolicy} "保存成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/proxies/{id}/access-policy [put]
+func (h *ProxyVisitorHandler) SaveAccessPolicy(c *gin.Context) {
+	policy := model.ProxyAccessPolicy{Enabled: true}
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	policy.ProxyID = uint(proxyID)
+
+	saved, err := h.accessService.SavePolicy(&policy)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "proxy_access_policy", saved.ProxyID,
+		fmt.Sprintf("保存代理访问策略: %s", saved.ProxyName), c.ClientIP())
+	util.Success(c, saved)
+}
+
+// DeleteAccessPolicy godoc
+// @Summary 删除代理访问策略
+// @Description 删除代理的访问策略，删除后不再拦截任何来源
+// @Tags 代理管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 500 {object} util.Response "删除访问策略失败"
+// @Router /api/proxies/{id}/access-policy [delete]
+func (h *ProxyVisitorHandler) DeleteAccessPolicy(c *gin.Context) {
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.accessService.DeletePolicy(uint(proxyID)); err != nil {
+		util.Error(c, 500, "删除访问策略失败")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "proxy_access_policy", uint(proxyID),
+		fmt.Sprintf("删除代理访问策略: 代理ID=%d", proxyID), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// GetAccessBlocks godoc
+// @Summary 获取代理访问拦截记录
+// @Description 获取被访问策略拦截的来源 IP、归属地、拦截原因和次数，按最后拦截时间倒序
+// @Tags 代理管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "代理ID"
+// @Param limit query int false "返回数量，默认100，最大500"
+// @Success 200 {object} util.Response{data=[]model.ProxyAccessBlock} "拦截记录"
+// @Failure 404 {object} util.Response "代理不存在"
+// @Router /api/proxies/{id}/access-blocks [get]
+func (h *ProxyVisitorHandler) GetAccessBlocks(c *gin.Context) {
+	proxyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	blocks, err := h.accessService.GetRecentBlocks(uint(proxyID), limit)
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+	util.Success(c, blocks)
+}
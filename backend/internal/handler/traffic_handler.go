@@ -10,7 +10,10 @@
 package handler
 
 import (
+	"bytes"
+	"fmt"
 	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
 	"frp-web-panel/internal/repository"
 	"frp-web-panel/internal/service"
 	"frp-web-panel/internal/util"
@@ -23,16 +26,20 @@ import (
 type TrafficHandler struct {
 	trafficService   *service.TrafficService
 	proxyMetricsRepo *repository.ProxyMetricsRepository
+	metricsRollup    *service.MetricsRollupService
 	proxyRepo        *repository.ProxyRepository
 	clientRepo       *repository.ClientRepository
+	reportService    *service.TrafficReportService
 }
 
-func NewTrafficHandler() *TrafficHandler {
+func NewTrafficHandler(metricsRollup *service.MetricsRollupService, reportService *service.TrafficReportService) *TrafficHandler {
 	return &TrafficHandler{
 		trafficService:   service.NewTrafficService(),
 		proxyMetricsRepo: repository.NewProxyMetricsRepository(),
+		metricsRollup:    metricsRollup,
 		proxyRepo:        repository.NewProxyRepository(),
 		clientRepo:       repository.NewClientRepository(),
+		reportService:    reportService,
 	}
 }
 
@@ -57,7 +64,7 @@ func (h *TrafficHandler) GetTrafficSummary(c *gin.Context) {
 
 // GetTrafficHistory godoc
 // @Summary 获取代理流量历史
-// @Description 获取指定代理的流量历史记录
+// @Description 获取指定代理的流量历史记录，根据时间范围自动选择原始或汇总粒度，实际粒度通过 X-Metrics-Resolution 响应头返回
 // @Tags 流量统计
 // @Accept json
 // @Produce json
@@ -109,26 +116,26 @@ func (h *TrafficHandler) GetTrafficHistory(c *gin.Context) {
 	fullProxyName := client.Name + "." + proxy.Name
 	logger.Debugf("[GetTrafficHistory] 查询 proxy_metrics_history: serverID=%d, fullProxyName=%s", serverID, fullProxyName)
 
-	// 5. 从 proxy_metrics_history 表查询流量历史
-	history, err := h.proxyMetricsRepo.GetHistory(serverID, fullProxyName, start, end)
+	// 5. 查询流量历史，长时间范围使用汇总数据
+	history, resolution, err := h.metricsRollup.GetProxyHistory(serverID, fullProxyName, start, end)
 	if err != nil {
 		logger.Debugf("[GetTrafficHistory] 查询失败: %v", err)
 		util.Error(c, 500, "获取流量历史失败")
 		return
 	}
 
-	logger.Debugf("[GetTrafficHistory] 查询 proxy_metrics_history 表结果: %d 条记录", len(history))
+	logger.Debugf("[GetTrafficHistory] 查询结果: %d 条记录，粒度: %s", len(history), resolution)
 
 	// 如果使用完整名称没有找到数据，尝试只用代理名称（兼容旧数据）
 	if len(history) == 0 {
 		logger.Debugf("[GetTrafficHistory] 完整名称无结果，尝试使用短名称: %s", proxy.Name)
-		history, err = h.proxyMetricsRepo.GetHistory(serverID, proxy.Name, start, end)
+		history, resolution, err = h.metricsRollup.GetProxyHistory(serverID, proxy.Name, start, end)
 		if err == nil && len(history) > 0 {
 			logger.Debugf("[GetTrafficHistory] 使用短名称 %s 查询到 %d 条记录", proxy.Name, len(history))
 		}
 	}
 
-	// 5. 转换为前端期望的格式
+	// 6. 转换为前端期望的格式
 	var result []map[string]interface{}
 	for _, h := range history {
 		result = append(result, map[string]interface{}{
@@ -136,6 +143,8 @@ func (h *TrafficHandler) GetTrafficHistory(c *gin.Context) {
 			"proxy_id":         proxyID,
 			"bytes_in":         h.TrafficIn,
 			"bytes_out":        h.TrafficOut,
+			"delta_in":         h.DeltaIn,
+			"delta_out":        h.DeltaOut,
 			"current_rate_in":  h.RateIn,
 			"current_rate_out": h.RateOut,
 			"record_time":      h.RecordTime,
@@ -143,6 +152,7 @@ func (h *TrafficHandler) GetTrafficHistory(c *gin.Context) {
 		})
 	}
 
+	c.Header("X-Metrics-Resolution", resolution)
 	util.Success(c, result)
 }
 
@@ -175,7 +185,7 @@ func (h *TrafficHandler) GetProxyRates(c *gin.Context) {
 
 // GetProxyRateHistory godoc
 // @Summary 获取单个隧道的速率历史
-// @Description 获取指定隧道在时间范围内的速率历史记录
+// @Description 获取指定隧道在时间范围内的速率历史记录，根据时间范围自动选择原始或汇总粒度，实际粒度通过 X-Metrics-Resolution 响应头返回
 // @Tags 流量统计
 // @Accept json
 // @Produce json
@@ -207,11 +217,12 @@ func (h *TrafficHandler) GetProxyRateHistory(c *gin.Context) {
 	start, _ := time.Parse(time.RFC3339, startStr)
 	end, _ := time.Parse(time.RFC3339, endStr)
 
-	history, err := h.proxyMetricsRepo.GetHistory(uint(serverID), proxyName, start, end)
+	history, resolution, err := h.metricsRollup.GetProxyHistory(uint(serverID), proxyName, start, end)
 	if err != nil {
 		util.Error(c, 500, "获取隧道速率历史失败")
 		return
 	}
+	c.Header("X-Metrics-Resolution", resolution)
 	util.Success(c, history)
 }
 
@@ -299,3 +310,104 @@ func (h *TrafficHandler) GetProxiesTrafficSummary(c *gin.Context) {
 
 	util.Success(c, result)
 }
+
+// GetUsageReport godoc
+// @Summary 获取流量用量报表
+// @Description 按客户端、代理或服务器汇总任意日期范围的增量流量，日期按指定时区的自然日计算；首尾所需的细粒度数据超出保留期时 approximate 为 true
+// @Tags 流量统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份(YYYY-MM)，指定后忽略 start/end"
+// @Param start query string false "开始日期(YYYY-MM-DD)"
+// @Param end query string false "结束日期(YYYY-MM-DD，包含当天)"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param group_by query string false "分组方式: client/proxy/server，默认 client"
+// @Param client_id query int false "仅统计指定客户端"
+// @Param server_id query int false "仅统计指定服务器"
+// @Success 200 {object} util.Response{data=model.TrafficReport} "流量报表"
+// @Failure 400 {object} util.Response "参数错误"
+// @Failure 500 {object} util.Response "生成报表失败"
+// @Router /api/traffic/report [get]
+func (h *TrafficHandler) GetUsageReport(c *gin.Context) {
+	report, ok := h.buildUsageReport(c)
+	if !ok {
+		return
+	}
+	util.Success(c, report)
+}
+
+// ExportUsageReport godoc
+// @Summary 导出流量用量报表
+// @Description 以 CSV 或 XLSX 格式导出流量用量报表，查询参数同获取流量用量报表
+// @Tags 流量统计
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param format query string false "导出格式: csv/xlsx，默认 csv"
+// @Param month query string false "月份(YYYY-MM)，指定后忽略 start/end"
+// @Param start query string false "开始日期(YYYY-MM-DD)"
+// @Param end query string false "结束日期(YYYY-MM-DD，包含当天)"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param group_by query string false "分组方式: client/proxy/server，默认 client"
+// @Param client_id query int false "仅统计指定客户端"
+// @Param server_id query int false "仅统计指定服务器"
+// @Success 200 {file} file "报表文件"
+// @Failure 400 {object} util.Response "参数错误"
+// @Failure 500 {object} util.Response "生成报表失败"
+// @Router /api/traffic/report/export [get]
+func (h *TrafficHandler) ExportUsageReport(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		util.Error(c, 400, "不支持的导出格式")
+		return
+	}
+	report, ok := h.buildUsageReport(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType := "text/csv; charset=utf-8"
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		err = h.reportService.WriteXLSX(&buf, report)
+	} else {
+		err = h.reportService.WriteCSV(&buf, report)
+	}
+	if err != nil {
+		logger.Errorf("导出流量报表失败: %v", err)
+		util.Error(c, 500, "导出报表失败")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, service.ReportFilename(report, format)))
+	c.Data(200, contentType, buf.Bytes())
+}
+
+// buildUsageReport 解析查询参数并生成报表，失败时已写入错误响应
+func (h *TrafficHandler) buildUsageReport(c *gin.Context) (*model.TrafficReport, bool) {
+	start, end, loc, err := service.ParseTrafficReportRange(c.Query("start"), c.Query("end"), c.Query("month"), c.Query("tz"))
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return nil, false
+	}
+	clientID, _ := strconv.ParseUint(c.Query("client_id"), 10, 32)
+	serverID, _ := strconv.ParseUint(c.Query("server_id"), 10, 32)
+
+	report, err := h.reportService.BuildReport(service.TrafficReportQuery{
+		Start:    start,
+		End:      end,
+		Location: loc,
+		GroupBy:  c.Query("group_by"),
+		ClientID: uint(clientID),
+		ServerID: uint(serverID),
+	})
+	if err != nil {
+		logger.Errorf("生成流量报表失败: %v", err)
+		util.Error(c, 500, "生成报表失败")
+		return nil, false
+	}
+	return report, true
+}
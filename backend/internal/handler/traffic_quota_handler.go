@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrafficQuotaHandler struct {
+	quotaService *service.TrafficQuotaService
+	logService   *service.LogService
+}
+
+func NewTrafficQuotaHandler(quotaService *service.TrafficQuotaService) *TrafficQuotaHandler {
+	return &TrafficQuotaHandler{
+		quotaService: quotaService,
+		logService:   service.NewLogService(),
+	}
+}
+
+// GetAllQuotas godoc
+// @Summary 获取流量配额列表
+// @Description 获取所有代理和客户端的流量配额及当前周期用量
+// @Tags 流量配额
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} util.Response{data=[]model.TrafficQuota} "获取成功"
+// @Failure 500 {object} util.Response "获取流量配额失败"
+// @Router /api/traffic-quotas [get]
+func (h *TrafficQuotaHandler) GetAllQuotas(c *gin.Context) {
+	quotas, err := h.quotaService.GetAllQuotas()
+	if err != nil {
+		util.Error(c, 500, "获取流量配额失败")
+		return
+	}
+	util.Success(c, quotas)
+}
+
+// CreateQuota godoc
+// @Summary 创建流量配额
+// @Description 为代理或客户端创建日/月流量配额，超出后自动禁用或限速代理，周期重置时自动恢复
+// @Tags 流量配额
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param quota body model.TrafficQuota true "流量配额"
+// @Success 200 {object} util.Response{data=model.TrafficQuota} "创建成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/traffic-quotas [post]
+func (h *TrafficQuotaHandler) CreateQuota(c *gin.Context) {
+	quota := model.TrafficQuota{Enabled: true}
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	if err := h.quotaService.CreateQuota(&quota); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "create", "traffic_quota", quota.ID,
+		fmt.Sprintf("创建流量配额: %s (%s, %d 字节)", quota.TargetName, quota.Period, quota.LimitBytes), c.ClientIP())
+	util.Success(c, quota)
+}
+
+// UpdateQuota godoc
+// @Summary 更新流量配额
+// @Description 更新配额周期、额度和处理方式，配额目标不可修改；已超出的配额会先恢复代理再按新设置判断
+// @Tags 流量配额
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "配额ID"
+// @Param quota body model.TrafficQuota true "流量配额"
+// @Success 200 {object} util.Response{data=model.TrafficQuota} "更新成功"
+// @Failure 400 {object} util.Response "参数错误"
+// @Router /api/traffic-quotas/{id} [put]
+func (h *TrafficQuotaHandler) UpdateQuota(c *gin.Context) {
+	quota := model.TrafficQuota{Enabled: true}
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		util.Error(c, 400, "参数错误")
+		return
+	}
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	quota.ID = uint(id)
+
+	updated, err := h.quotaService.UpdateQuota(&quota)
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "traffic_quota", updated.ID,
+		fmt.Sprintf("更新流量配额: %s (%s, %d 字节)", updated.TargetName, updated.Period, updated.LimitBytes), c.ClientIP())
+	util.Success(c, updated)
+}
+
+// DeleteQuota godoc
+// @Summary 删除流量配额
+// @Description 删除流量配额，并恢复被该配额禁用或限速的代理
+// @Tags 流量配额
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "配额ID"
+// @Success 200 {object} util.Response "删除成功"
+// @Failure 400 {object} util.Response "删除失败"
+// @Router /api/traffic-quotas/{id} [delete]
+func (h *TrafficQuotaHandler) DeleteQuota(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.quotaService.DeleteQuota(uint(id)); err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "delete", "traffic_quota", uint(id),
+		fmt.Sprintf("删除流量配额: ID=%d", id), c.ClientIP())
+	util.Success(c, nil)
+}
+
+// ResetQuota godoc
+// @Summary 清零流量配额用量
+// @Description 手动清零当前周期已用流量，并恢复被该配额禁用或限速的代理
+// @Tags 流量配额
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "配额ID"
+// @Success 200 {object} util.Response{data=model.TrafficQuota} "清零成功"
+// @Failure 400 {object} util.Response "清零失败"
+// @Router /api/traffic-quotas/{id}/reset [post]
+func (h *TrafficQuotaHandler) ResetQuota(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	quota, err := h.quotaService.ResetQuota(uint(id))
+	if err != nil {
+		util.Error(c, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "traffic_quota", quota.ID,
+		fmt.Sprintf("清零流量配额用量: %s", quota.TargetName), c.ClientIP())
+	util.Success(c, quota)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"frp-web-panel/internal/service"
+	"frp-web-panel/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookDeliveryHandler struct {
+	webhookService *service.WebhookDeliveryService
+	logService     *service.LogService
+}
+
+func NewWebhookDeliveryHandler(webhookService *service.WebhookDeliveryService) *WebhookDeliveryHandler {
+	return &WebhookDeliveryHandler{
+		webhookService: webhookService,
+		logService:     service.NewLogService(),
+	}
+}
+
+// GetDeliveries godoc
+// @Summary 获取 Webhook 投递记录
+// @Description 按创建时间倒序获取告警 Webhook 投递记录，可按状态和告警日志过滤
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "投递状态: pending, success, failed"
+// @Param alert_log_id query int false "告警日志ID"
+// @Param limit query int false "返回数量，默认100，最大1000"
+// @Success 200 {object} util.Response{data=[]model.WebhookDelivery} "获取成功"
+// @Failure 500 {object} util.Response "获取投递记录失败"
+// @Router /api/alerts/webhook-deliveries [get]
+func (h *WebhookDeliveryHandler) GetDeliveries(c *gin.Context) {
+	alertLogID, _ := strconv.ParseUint(c.Query("alert_log_id"), 10, 32)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	deliveries, err := h.webhookService.GetDeliveries(c.Query("status"), uint(alertLogID), limit)
+	if err != nil {
+		util.Error(c, 500, "获取投递记录失败")
+		return
+	}
+	util.Success(c, deliveries)
+}
+
+// GetAttempts godoc
+// @Summary 获取 Webhook 投递尝试记录
+// @Description 获取单条投递记录的每次请求结果，包括状态码、耗时和响应内容
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "投递记录ID"
+// @Success 200 {object} util.Response{data=[]model.WebhookDeliveryAttempt} "获取成功"
+// @Failure 404 {object} util.Response "投递记录不存在"
+// @Router /api/alerts/webhook-deliveries/{id}/attempts [get]
+func (h *WebhookDeliveryHandler) GetAttempts(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	attempts, err := h.webhookService.GetAttempts(uint(id))
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+	util.Success(c, attempts)
+}
+
+// Redeliver godoc
+// @Summary 重新投递 Webhook
+// @Description 立即重新发送投递记录中保存的请求体，重置重试次数，失败后继续按退避策略重试
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "投递记录ID"
+// @Success 200 {object} util.Response{data=model.WebhookDelivery} "已重新投递，结果见返回的投递状态"
+// @Failure 404 {object} util.Response "投递记录不存在"
+// @Router /api/alerts/webhook-deliveries/{id}/redeliver [post]
+func (h *WebhookDeliveryHandler) Redeliver(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	delivery, err := h.webhookService.Redeliver(uint(id))
+	if err != nil {
+		util.Error(c, 404, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logService.CreateLogAsync(userID.(uint), "update", "webhook_delivery", delivery.ID,
+		fmt.Sprintf("重新投递 Webhook: %s (结果: %s)", delivery.URL, delivery.Status), c.ClientIP())
+	util.Success(c, delivery)
+}
@@ -10,6 +10,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"frp-web-panel/internal/config"
 	"frp-web-panel/internal/util"
 	"strings"
@@ -53,3 +54,20 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// MetricsAuthMiddleware 指标接口认证：接受配置的抓取令牌，否则回退到 JWT 认证
+func MetricsAuthMiddleware() gin.HandlerFunc {
+	jwtAuth := AuthMiddleware()
+	return func(c *gin.Context) {
+		scrapeToken := config.GlobalConfig.Metrics.Token
+		if scrapeToken != "" {
+			parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+			if len(parts) == 2 && parts[0] == "Bearer" &&
+				subtle.ConstantTimeCompare([]byte(parts[1]), []byte(scrapeToken)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		jwtAuth(c)
+	}
+}
@@ -19,6 +19,7 @@ const (
 	AlertTargetFrpc   AlertTargetType = "frpc"   // frpc 离线告警
 	AlertTargetFrps   AlertTargetType = "frps"   // frps 离线告警
 	AlertTargetSystem AlertTargetType = "system" // 系统级告警
+	AlertTargetProbe  AlertTargetType = "probe"  // 代理拨测失败告警
 )
 
 // 系统级告警规则类型常量
@@ -37,23 +38,43 @@ const (
 	RuleTypeLoginFailed = "login_failed" // 登录失败
 	// 配置相关
 	RuleTypeConfigChanged = "config_changed" // 配置变更
+	// 流量配额相关
+	RuleTypeQuotaExceeded = "quota_exceeded" // 流量配额超出
+	RuleTypeQuotaRestored = "quota_restored" // 流量配额重置恢复
+	// 访问控制相关
+	RuleTypeAccessBlocked = "access_blocked" // 代理来源访问被频繁拦截
+)
+
+// RuleTypeMetric 指标表达式规则，按 AlertRule.Expression 对采集的指标序列求值
+const RuleTypeMetric = "metric"
+
+// 告警状态，历史记录为空，视为已结束
+const (
+	AlertStatusFiring       = "firing"       // 触发中
+	AlertStatusAcknowledged = "acknowledged" // 已确认，处理中
+	AlertStatusResolved     = "resolved"     // 已恢复或手动关闭
 )
 
 type AlertRule struct {
 	ID                  uint            `json:"id" gorm:"primaryKey"`
 	TargetType          AlertTargetType `json:"target_type" gorm:"type:varchar(20);not null;default:'proxy'"` // proxy, frpc, frps
-	TargetID            uint            `json:"target_id" gorm:"not null"`                                    // 对应 proxy_id, client_id, frp_server_id
+	TargetID            uint            `json:"target_id" gorm:"not null"`                                    // 对应 proxy_id, client_id, frp_server_id, probe_id；metric 规则为0时匹配该类型全部目标
 	ProxyID             uint            `json:"proxy_id" gorm:"not null"`                                     // 保留兼容旧数据
-	RuleType            string          `json:"rule_type" gorm:"type:varchar(20);not null"`                   // daily, monthly, rate, offline
-	ThresholdValue      int64           `json:"threshold_value" gorm:"default:0"`                             // 流量告警阈值，离线告警不需要
-	ThresholdUnit       string          `json:"threshold_unit" gorm:"type:varchar(10);default:bytes"`         // bytes, MB, GB
+	RuleType            string          `json:"rule_type" gorm:"type:varchar(20);not null"`                   // daily, monthly, rate, quota, offline, metric
+	Expression          string          `json:"expression" gorm:"type:varchar(500)"`                          // metric 规则的指标表达式，如 avg(frps_cpu_percent[5m]) > 80 for 10m
+	ThresholdValue      int64           `json:"threshold_value" gorm:"default:0"`                             // 流量告警阈值，quota 规则为配额使用百分比，离线告警不需要
+	ThresholdUnit       string          `json:"threshold_unit" gorm:"type:varchar(10);default:bytes"`         // bytes, MB, GB, TB, percent
+	Timezone            string          `json:"timezone" gorm:"type:varchar(50)"`                             // 日/月流量统计按此时区的自然日、自然月对齐，为空使用服务器时区
 	CooldownMinutes     int             `json:"cooldown_minutes" gorm:"default:60"`                           // 告警冷却时间（分钟）
 	OfflineDelaySeconds int             `json:"offline_delay_seconds" gorm:"default:60"`                      // 离线延迟确认时间（秒），防止网络波动误报
 	NotifyOnRecovery    bool            `json:"notify_on_recovery" gorm:"default:true"`                       // 恢复在线时是否发送通知
 	Enabled             bool            `json:"enabled" gorm:"default:true"`
 	NotifyRecipientIDs  string          `json:"notify_recipient_ids" gorm:"type:varchar(500)"` // 接收人ID列表，逗号分隔
 	NotifyGroupIDs      string          `json:"notify_group_ids" gorm:"type:varchar(500)"`     // 分组ID列表，逗号分隔
-	NotifyWebhook       string          `json:"notify_webhook" gorm:"type:varchar(500)"`
+	NotifyChannelIDs    string          `json:"notify_channel_ids" gorm:"type:varchar(500)"`   // 通知渠道ID列表，逗号分隔
+	NotifyWebhook       string          `json:"notify_webhook" gorm:"type:varchar(500)"`       // 兼容旧配置的单个 Webhook 地址，建议改用通知渠道
+	WebhookTemplate     string          `json:"webhook_template" gorm:"type:text"`             // Webhook 请求体模板（Go template），为空时使用默认 JSON
+	EscalationPolicyID  uint            `json:"escalation_policy_id" gorm:"default:0;index"`   // 告警升级策略ID，为0不升级
 	CreatedAt           time.Time       `json:"created_at"`
 	UpdatedAt           time.Time       `json:"updated_at"`
 }
@@ -70,5 +91,77 @@ type AlertLog struct {
 	Message        string          `json:"message" gorm:"type:text"`
 	EventData      string          `json:"event_data" gorm:"type:text"` // 事件详情JSON，用于系统告警
 	Notified       bool            `json:"notified" gorm:"default:false"`
-	CreatedAt      time.Time       `json:"created_at"`
+	Status         string          `json:"status" gorm:"type:varchar(20);index"` // firing, acknowledged, resolved
+	Silenced       bool            `json:"silenced" gorm:"default:false"`        // 触发时处于静默期，未发送通知
+	SuppressedBy   uint            `json:"suppressed_by" gorm:"default:0;index"` // 触发时上级 frps/frpc 处于离线告警中，为抑制它的上级告警ID，未发送通知
+	AckedBy        uint            `json:"acked_by"`                             // 确认人用户ID
+	AckedByName    string          `json:"acked_by_name" gorm:"type:varchar(50)"`
+	AckedAt        *time.Time      `json:"acked_at"`
+	ResolvedAt     *time.Time      `json:"resolved_at"`
+
+	EscalationLevel  int        `json:"escalation_level"`                // 已通知的升级级别，0 表示未使用升级策略
+	NextEscalationAt *time.Time `json:"next_escalation_at" gorm:"index"` // 下一级升级时间，为空表示已到最高级
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// AlertSilence 告警静默，生效期间匹配的告警照常记录但不发送通知，开始时间设为将来即为计划维护窗口
+type AlertSilence struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	TargetType    AlertTargetType `json:"target_type" gorm:"type:varchar(20)"` // 为空匹配全部目标类型
+	TargetID      uint            `json:"target_id"`                           // 为0匹配该类型的全部目标
+	RuleID        uint            `json:"rule_id"`                             // 为0匹配全部规则
+	StartsAt      time.Time       `json:"starts_at" gorm:"index"`
+	EndsAt        time.Time       `json:"ends_at" gorm:"index"`
+	Comment       string          `json:"comment" gorm:"type:varchar(500)"`
+	CreatedBy     uint            `json:"created_by"`
+	CreatedByName string          `json:"created_by_name" gorm:"type:varchar(50)"`
+	State         string          `json:"state" gorm:"-"` // pending, active, expired，非数据库字段
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// 静默状态
+const (
+	SilenceStatePending = "pending" // 未开始（计划维护）
+	SilenceStateActive  = "active"  // 生效中
+	SilenceStateExpired = "expired" // 已结束
+)
+
+// AlertTypeCount 按告警类型统计的告警数量
+type AlertTypeCount struct {
+	AlertType string `json:"alert_type"`
+	Name      string `json:"name"`
+	Count     int    `json:"count"`
+}
+
+// OfflineSummary 目标在统计范围内的离线统计，离线时长从告警触发开始计算
+type OfflineSummary struct {
+	TargetType     AlertTargetType `json:"target_type"`
+	TargetID       uint            `json:"target_id"`
+	TargetName     string          `json:"target_name"`
+	Count          int             `json:"count"`         // 统计范围内的离线次数，含开始前已离线的
+	TotalSeconds   int64           `json:"total_seconds"` // 统计范围内的离线时长
+	LongestSeconds int64           `json:"longest_seconds"`
+	Ongoing        bool            `json:"ongoing"` // 统计结束时仍未恢复
+}
+
+// CertEventSummary 证书事件
+type CertEventSummary struct {
+	Time      time.Time `json:"time"`
+	AlertType string    `json:"alert_type"`
+	Domain    string    `json:"domain"`
+	Message   string    `json:"message"`
+}
+
+// AlertDailySummary 每日告警汇总，时间范围左闭右开
+type AlertDailySummary struct {
+	Start      time.Time          `json:"start"`
+	End        time.Time          `json:"end"`
+	Timezone   string             `json:"timezone"`
+	Total      int                `json:"total"`
+	Silenced   int                `json:"silenced"`
+	Open       int                `json:"open"` // 统计范围内触发且仍未结束的告警
+	Types      []AlertTypeCount   `json:"types"`
+	Offline    []OfflineSummary   `json:"offline"`
+	CertEvents []CertEventSummary `json:"cert_events"`
 }
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// AlertEscalationPolicy 告警升级策略，告警触发时通知第1级，超时仍未确认则依次通知下一级
+type AlertEscalationPolicy struct {
+	ID          uint                  `json:"id" gorm:"primaryKey"`
+	Name        string                `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string                `json:"description" gorm:"type:varchar(500)"`
+	Enabled     bool                  `json:"enabled" gorm:"default:true"`
+	Steps       []AlertEscalationStep `json:"steps" gorm:"-"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// AlertEscalationStep 升级策略中的一级通知
+type AlertEscalationStep struct {
+	ID                   uint   `json:"id" gorm:"primaryKey"`
+	PolicyID             uint   `json:"policy_id" gorm:"not null;index"`
+	Level                int    `json:"level" gorm:"not null"`                    // 级别，从1开始
+	GroupIDs             string `json:"group_ids" gorm:"type:varchar(500)"`       // 通知的接收人分组ID列表，逗号分隔
+	ScheduleIDs          string `json:"schedule_ids" gorm:"type:varchar(500)"`    // 通知当前值班人的值班表ID列表，逗号分隔
+	EscalateAfterMinutes int    `json:"escalate_after_minutes" gorm:"default:30"` // 本级通知后多少分钟未确认升级到下一级，最后一级忽略
+}
+
+// OnCallSchedule 按周轮换的值班表，从交接时间起每隔若干周按接收人顺序轮换
+type OnCallSchedule struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	Name          string          `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description   string          `json:"description" gorm:"type:varchar(500)"`
+	Timezone      string          `json:"timezone" gorm:"type:varchar(50)"`       // 交接时刻按此时区计算，为空使用服务器时区
+	HandoffAt     time.Time       `json:"handoff_at"`                             // 首个轮班开始时间，之后在相同星期和时刻交接
+	RotationWeeks int             `json:"rotation_weeks" gorm:"default:1"`        // 每个轮班持续的周数
+	RecipientIDs  string          `json:"recipient_ids" gorm:"type:varchar(500)"` // 按轮换顺序排列的接收人ID，逗号分隔
+	Enabled       bool            `json:"enabled" gorm:"default:true"`
+	Current       *AlertRecipient `json:"current,omitempty" gorm:"-"` // 当前值班人，非数据库字段
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// OnCallShift 值班表的一个轮班
+type OnCallShift struct {
+	RecipientID   uint      `json:"recipient_id"`
+	RecipientName string    `json:"recipient_name"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+func (AlertEscalationPolicy) TableName() string {
+	return "alert_escalation_policies"
+}
+
+func (AlertEscalationStep) TableName() string {
+	return "alert_escalation_steps"
+}
+
+func (OnCallSchedule) TableName() string {
+	return "oncall_schedules"
+}
@@ -27,6 +27,7 @@ type AlertRecipientGroup struct {
 	Name        string           `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
 	Description string           `json:"description" gorm:"type:varchar(500)"`
 	Enabled     bool             `json:"enabled" gorm:"default:true"`
+	ChannelIDs  string           `json:"channel_ids" gorm:"type:varchar(500)"` // 分组关联的通知渠道ID列表，逗号分隔
 	Recipients  []AlertRecipient `json:"recipients" gorm:"-"`
 	CreatedAt   time.Time        `json:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at"`
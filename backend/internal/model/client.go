@@ -35,6 +35,21 @@ type Client struct {
 	ConfigSyncStatus string     `json:"config_sync_status" gorm:"size:20;default:pending"` // synced/failed/pending/rolled_back
 	ConfigSyncError  string     `json:"config_sync_error" gorm:"type:text"`
 	ConfigSyncTime   *time.Time `json:"config_sync_time"`
+	// 守护进程凭证字段（DaemonToken 为空时回退使用 Token）
+	DaemonToken           string     `json:"-" gorm:"size:128"`
+	DaemonTokenPrev       string     `json:"-" gorm:"size:128"`
+	DaemonTokenPrevExpire *time.Time `json:"-"`
+	DaemonTokenRotatedAt  *time.Time `json:"daemon_token_rotated_at"`
+	DaemonTokenRevoked    bool       `json:"daemon_token_revoked" gorm:"default:false"`
+	// 守护进程 mTLS 客户端证书字段
+	TLSCertSerial   string     `json:"tls_cert_serial" gorm:"size:64"`
+	TLSCertExpireAt *time.Time `json:"tls_cert_expire_at"`
+	// 守护进程上报的本地状态（重连后通过 state_report 上报）
+	AppliedConfigVersion int        `json:"applied_config_version"`
+	AppliedConfigHash    string     `json:"applied_config_hash" gorm:"size:64"`
+	StateReportedAt      *time.Time `json:"state_reported_at"`
+	// 注册时签发的证书（仅在注册响应中返回）
+	TLSCert *ClientTLSCert `json:"tls_cert,omitempty" gorm:"-"`
 	// 版本信息字段
 	FrpcVersion   string    `json:"frpc_version" gorm:"size:50"`
 	DaemonVersion string    `json:"daemon_version" gorm:"size:50"`
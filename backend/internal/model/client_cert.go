@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// RevokedClientCert 已吊销的守护进程客户端证书
+type RevokedClientCert struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Serial    string    `json:"serial" gorm:"uniqueIndex;size:64;not null"`
+	ClientID  uint      `json:"client_id" gorm:"index"`
+	Reason    string    `json:"reason" gorm:"size:100"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// ClientTLSCert 签发给守护进程的 mTLS 客户端证书，私钥只在签发时返回一次，不落库
+type ClientTLSCert struct {
+	CertPEM  string    `json:"cert_pem"`
+	KeyPEM   string    `json:"key_pem"`
+	CAPEM    string    `json:"ca_pem"`
+	Serial   string    `json:"serial"`
+	ExpireAt time.Time `json:"expire_at"`
+	// 注册时附带的连接信息：mTLS 监听器地址，以及监听器使用自签名证书时守护进程需要信任的服务端证书
+	DaemonURL   string `json:"daemon_url,omitempty"`
+	ServerCAPEM string `json:"server_ca_pem,omitempty"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// ClientMetricsHistory 客户端主机与 frpc 进程指标历史记录（由守护进程上报）
+type ClientMetricsHistory struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	ClientID         uint      `json:"client_id" gorm:"index:idx_client_time;not null"`
+	CpuPercent       float64   `json:"cpu_percent"`
+	MemTotalBytes    int64     `json:"mem_total_bytes"`
+	MemUsedBytes     int64     `json:"mem_used_bytes"`
+	NetRxRate        int64     `json:"net_rx_rate"`
+	NetTxRate        int64     `json:"net_tx_rate"`
+	FrpcRunning      bool      `json:"frpc_running"`
+	FrpcCpuPercent   float64   `json:"frpc_cpu_percent"`
+	FrpcRssBytes     int64     `json:"frpc_rss_bytes"`
+	FrpcConnections  int       `json:"frpc_connections"`
+	FrpcUptime       int64     `json:"frpc_uptime"`
+	FrpcRestartCount int       `json:"frpc_restart_count"`
+	RecordTime       time.Time `json:"record_time" gorm:"index:idx_client_time;not null"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (ClientMetricsHistory) TableName() string {
+	return "client_metrics_history"
+}
@@ -0,0 +1,61 @@
+package model
+
+import "encoding/json"
+
+// FrpsPluginRequest frps HTTP 插件请求
+type FrpsPluginRequest struct {
+	Version string          `json:"version"`
+	Op      string          `json:"op"`
+	Content json.RawMessage `json:"content"`
+}
+
+// FrpsPluginResponse frps HTTP 插件响应
+type FrpsPluginResponse struct {
+	Reject       bool        `json:"reject"`
+	RejectReason string      `json:"reject_reason,omitempty"`
+	Unchange     bool        `json:"unchange"`
+	Content      interface{} `json:"content,omitempty"`
+}
+
+// FrpsPluginUser 插件请求中的 frpc 用户信息
+type FrpsPluginUser struct {
+	User  string            `json:"user"`
+	Metas map[string]string `json:"metas"`
+	RunID string            `json:"run_id"`
+}
+
+// FrpsLoginContent Login 操作内容
+type FrpsLoginContent struct {
+	Version       string            `json:"version"`
+	Hostname      string            `json:"hostname"`
+	Os            string            `json:"os"`
+	Arch          string            `json:"arch"`
+	User          string            `json:"user"`
+	RunID         string            `json:"run_id"`
+	Metas         map[string]string `json:"metas"`
+	ClientAddress string            `json:"client_address"`
+}
+
+// FrpsNewProxyContent NewProxy 操作内容，只包含面板校验所需字段
+type FrpsNewProxyContent struct {
+	User          FrpsPluginUser `json:"user"`
+	ProxyName     string         `json:"proxy_name"`
+	ProxyType     string         `json:"proxy_type"`
+	RemotePort    int            `json:"remote_port"`
+	CustomDomains []string       `json:"custom_domains"`
+	Subdomain     string         `json:"subdomain"`
+}
+
+// FrpsNewWorkConnContent NewWorkConn 操作内容
+type FrpsNewWorkConnContent struct {
+	User  FrpsPluginUser `json:"user"`
+	RunID string         `json:"run_id"`
+}
+
+// FrpsNewUserConnContent NewUserConn 操作内容
+type FrpsNewUserConnContent struct {
+	User       FrpsPluginUser `json:"user"`
+	ProxyName  string         `json:"proxy_name"`
+	ProxyType  string         `json:"proxy_type"`
+	RemoteAddr string         `json:"remote_addr"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// LogSinkType 日志转发目标类型
+type LogSinkType string
+
+const (
+	LogSinkSyslog LogSinkType = "syslog" // RFC5424 syslog，支持 UDP/TCP/TLS
+	LogSinkLoki   LogSinkType = "loki"   // Grafana Loki 推送 API
+)
+
+// 转发的事件流
+const (
+	LogStreamAlert  = "alert"  // 告警触发及恢复
+	LogStreamAudit  = "audit"  // 操作日志
+	LogStreamSystem = "system" // 系统事件
+)
+
+// LogSink 日志转发目标，告警事件、操作日志和系统事件在内存中缓冲后批量推送，失败时保留到下次重试
+type LogSink struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	Name          string      `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Type          LogSinkType `json:"type" gorm:"type:varchar(20);not null"`
+	Address       string      `json:"address" gorm:"type:varchar(500);not null"` // syslog 为 host:port，Loki 为推送地址，只填写域名时使用 /loki/api/v1/push
+	Protocol      string      `json:"protocol" gorm:"type:varchar(10)"`          // syslog 传输协议 udp、tcp、tls，默认 udp
+	Facility      int         `json:"facility"`                                  // syslog facility(0-23)，0 使用 local0(16)
+	AppName       string      `json:"app_name" gorm:"type:varchar(50)"`          // syslog APP-NAME，默认 frp-panel
+	TLSSkipVerify bool        `json:"tls_skip_verify"`                           // TLS 不校验服务端证书
+	Streams       string      `json:"streams" gorm:"type:varchar(100)"`          // 转发的事件流 alert、audit、system，逗号分隔，为空转发全部
+	Labels        string      `json:"labels" gorm:"type:varchar(500)"`           // 附加标签，如 env=prod,region=cn
+	Username      string      `json:"username" gorm:"type:varchar(100)"`         // Loki Basic 认证用户名
+	Password      string      `json:"-" gorm:"type:varchar(500)"`                // Loki Basic 认证密码或 Bearer 令牌，不返回给前端
+	TenantID      string      `json:"tenant_id" gorm:"type:varchar(100)"`        // Loki 多租户 X-Scope-OrgID
+	Enabled       bool        `json:"enabled" gorm:"not null"`
+	LastError     string      `json:"last_error" gorm:"type:varchar(500)"` // 最近一次推送失败的原因，成功后清空
+	LastSentAt    *time.Time  `json:"last_sent_at"`                        // 最近一次推送成功的时间
+	HasPassword   bool        `json:"has_password" gorm:"-"`               // 非数据库字段，是否已配置密码
+	Pending       int         `json:"pending" gorm:"-"`                    // 非数据库字段，等待推送的事件数
+	Dropped       int64       `json:"dropped" gorm:"-"`                    // 非数据库字段，启动以来因缓冲区满或被目标拒绝丢弃的事件数
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
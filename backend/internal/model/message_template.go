@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// 消息模板适用的发送方式
+const (
+	MessageChannelEmail = "email" // 邮件
+	MessageChannelIM    = "im"    // 钉钉、企业微信、Telegram 等通知渠道
+)
+
+// 消息模板事件类型，系统告警还可以按具体规则类型(如 cert_renew_failed)单独配置，未配置时使用 system 模板
+const (
+	MessageEventTraffic        = "traffic"         // 代理流量告警(每日/每月/速率/配额)
+	MessageEventOffline        = "offline"         // frpc/frps/拨测离线告警
+	MessageEventRecovery       = "recovery"        // 离线恢复
+	MessageEventMetric         = "metric"          // 指标表达式告警
+	MessageEventMetricRecovery = "metric_recovery" // 指标恢复
+	MessageEventEscalation     = "escalation"      // 告警升级
+	MessageEventSystem         = "system"          // 系统事件
+)
+
+// 通知语言
+const (
+	LocaleZhCN = "zh-CN"
+	LocaleEnUS = "en-US"
+)
+
+// MessageTemplate 用户自定义的告警消息模板，标题和正文使用 Go text/template 语法，
+// 未配置或未启用时使用通知语言对应的内置模板
+type MessageTemplate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EventType string    `json:"event_type" gorm:"type:varchar(50);not null;uniqueIndex:idx_message_template_event_channel"`
+	Channel   string    `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_message_template_event_channel"` // email/im
+	Subject   string    `json:"subject" gorm:"type:varchar(500);not null"`                                               // 邮件主题或通知标题
+	Body      string    `json:"body" gorm:"type:text;not null"`                                                          // 邮件正文按行拆分为段落
+	Enabled   bool      `json:"enabled" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// 指标数据粒度
+const (
+	MetricsResolutionRaw = "raw" // 原始采样
+	MetricsResolution5m  = "5m"
+	MetricsResolution1h  = "1h"
+	MetricsResolution1d  = "1d"
+)
+
+// ProxyMetricsRollup 隧道指标降采样汇总，每个粒度一个时间桶一条记录
+type ProxyMetricsRollup struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Resolution string    `json:"resolution" gorm:"uniqueIndex:idx_proxy_rollup;size:8;not null"`
+	ServerID   uint      `json:"server_id" gorm:"uniqueIndex:idx_proxy_rollup;not null"`
+	ProxyName  string    `json:"proxy_name" gorm:"uniqueIndex:idx_proxy_rollup;size:100;not null"`
+	ProxyType  string    `json:"proxy_type" gorm:"size:20"`
+	TrafficIn  int64     `json:"traffic_in"`   // 时间桶结束时的累计入站流量
+	TrafficOut int64     `json:"traffic_out"`  // 时间桶结束时的累计出站流量
+	DeltaIn    int64     `json:"delta_in"`     // 时间桶内入站流量
+	DeltaOut   int64     `json:"delta_out"`    // 时间桶内出站流量
+	RateIn     int64     `json:"rate_in"`      // 平均入站速率 bytes/s
+	RateOut    int64     `json:"rate_out"`     // 平均出站速率 bytes/s
+	MaxRateIn  int64     `json:"max_rate_in"`  // 峰值入站速率 bytes/s
+	MaxRateOut int64     `json:"max_rate_out"` // 峰值出站速率 bytes/s
+	Samples    int       `json:"samples"`      // 汇总的原始采样数
+	BucketTime time.Time `json:"bucket_time" gorm:"uniqueIndex:idx_proxy_rollup;not null"`
+}
+
+func (ProxyMetricsRollup) TableName() string {
+	return "proxy_metrics_rollup"
+}
+
+// ServerMetricsRollup 服务器指标降采样汇总
+type ServerMetricsRollup struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Resolution     string    `json:"resolution" gorm:"uniqueIndex:idx_server_rollup;size:8;not null"`
+	ServerID       uint      `json:"server_id" gorm:"uniqueIndex:idx_server_rollup;not null"`
+	CpuPercent     float64   `json:"cpu_percent"` // 平均值
+	MaxCpuPercent  float64   `json:"max_cpu_percent"`
+	MemoryBytes    int64     `json:"memory_bytes"` // 平均值
+	MaxMemoryBytes int64     `json:"max_memory_bytes"`
+	TrafficIn      int64     `json:"traffic_in"`  // 时间桶结束时的累计入站流量
+	TrafficOut     int64     `json:"traffic_out"` // 时间桶结束时的累计出站流量
+	Samples        int       `json:"samples"`
+	BucketTime     time.Time `json:"bucket_time" gorm:"uniqueIndex:idx_server_rollup;not null"`
+}
+
+func (ServerMetricsRollup) TableName() string {
+	return "server_metrics_rollup"
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// NotificationChannelType 通知渠道类型
+type NotificationChannelType string
+
+const (
+	ChannelTypeDingTalk NotificationChannelType = "dingtalk" // 钉钉群机器人
+	ChannelTypeWeCom    NotificationChannelType = "wecom"    // 企业微信群机器人
+	ChannelTypeFeishu   NotificationChannelType = "feishu"   // 飞书群机器人
+	ChannelTypeTelegram NotificationChannelType = "telegram" // Telegram Bot
+	ChannelTypeSlack    NotificationChannelType = "slack"    // Slack Incoming Webhook
+	ChannelTypeNtfy     NotificationChannelType = "ntfy"     // ntfy 推送
+	ChannelTypeGotify   NotificationChannelType = "gotify"   // Gotify 推送
+	ChannelTypeWebhook  NotificationChannelType = "webhook"  // 通用 JSON Webhook
+)
+
+// NotificationChannel 告警通知渠道，告警规则和接收人分组可引用多个渠道
+type NotificationChannel struct {
+	ID        uint                    `json:"id" gorm:"primaryKey"`
+	Name      string                  `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Type      NotificationChannelType `json:"type" gorm:"type:varchar(20);not null"`
+	URL       string                  `json:"url" gorm:"type:varchar(500)"`     // 机器人 Webhook 地址，ntfy/Gotify 服务地址，Telegram API 地址（可选）
+	Token     string                  `json:"-" gorm:"type:varchar(500)"`       // Telegram Bot Token、ntfy 访问令牌、Gotify 应用令牌，不返回给前端
+	Secret    string                  `json:"-" gorm:"type:varchar(200)"`       // 钉钉/飞书加签密钥，不返回给前端
+	ChatID    string                  `json:"chat_id" gorm:"type:varchar(100)"` // Telegram 会话ID
+	Topic     string                  `json:"topic" gorm:"type:varchar(100)"`   // ntfy 主题
+	Priority  int                     `json:"priority"`                         // ntfy(1-5)/Gotify(0-10) 消息优先级，0 使用默认
+	RateLimit int                     `json:"rate_limit"`                       // 每小时最多发送的消息数，超出的消息丢弃并在下一条消息中注明，0 使用全局设置
+	Enabled   bool                    `json:"enabled" gorm:"not null"`
+	HasToken  bool                    `json:"has_token" gorm:"-"`  // 非数据库字段，是否已配置令牌
+	HasSecret bool                    `json:"has_secret" gorm:"-"` // 非数据库字段，是否已配置加签密钥
+	CreatedAt time.Time               `json:"created_at"`
+	UpdatedAt time.Time               `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// ProxyAccessPolicy 代理来源访问策略，通过 frps NewUserConn 插件在服务端执行
+// 列表字段均为逗号或换行分隔；配置了任一白名单时，未命中白名单的来源被拒绝
+type ProxyAccessPolicy struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ProxyID      uint      `json:"proxy_id" gorm:"not null;uniqueIndex"`
+	ProxyName    string    `json:"proxy_name" gorm:"-"`            // 非数据库字段，clientName.proxyName
+	AllowCIDRs   string    `json:"allow_cidrs" gorm:"type:text"`   // 允许的 IP 或 CIDR
+	DenyCIDRs    string    `json:"deny_cidrs" gorm:"type:text"`    // 拒绝的 IP 或 CIDR
+	AllowRegions string    `json:"allow_regions" gorm:"type:text"` // 允许的地区，如 中国、广东省、内网IP
+	DenyRegions  string    `json:"deny_regions" gorm:"type:text"`  // 拒绝的地区
+	Enabled      bool      `json:"enabled" gorm:"not null"`        // 是否启用
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ProxyAccessBlock 被访问策略拦截的连接记录，按服务器、代理和来源 IP 聚合
+type ProxyAccessBlock struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ServerID     uint      `json:"server_id" gorm:"not null;uniqueIndex:idx_access_block_proxy_ip"`
+	ProxyName    string    `json:"proxy_name" gorm:"type:varchar(200);not null;uniqueIndex:idx_access_block_proxy_ip"`
+	IP           string    `json:"ip" gorm:"type:varchar(64);not null;uniqueIndex:idx_access_block_proxy_ip"`
+	Location     string    `json:"location" gorm:"type:varchar(200)"`
+	Reason       string    `json:"reason" gorm:"type:varchar(200)"`
+	BlockCount   int64     `json:"block_count" gorm:"not null;default:0"`
+	FirstBlocked time.Time `json:"first_blocked"`
+	LastBlocked  time.Time `json:"last_blocked" gorm:"index"`
+}
@@ -19,6 +19,8 @@ type ProxyMetricsHistory struct {
 	ProxyType  string    `json:"proxy_type" gorm:"size:20"`
 	TrafficIn  int64     `json:"traffic_in"`  // 累计入站流量
 	TrafficOut int64     `json:"traffic_out"` // 累计出站流量
+	DeltaIn    int64     `json:"delta_in"`    // 本采样周期入站流量
+	DeltaOut   int64     `json:"delta_out"`   // 本采样周期出站流量
 	RateIn     int64     `json:"rate_in"`     // 入站速率 bytes/s
 	RateOut    int64     `json:"rate_out"`    // 出站速率 bytes/s
 	RecordTime time.Time `json:"record_time" gorm:"index:idx_proxy_metrics;not null"`
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// 拨测类型
+const (
+	ProbeTypeTCP  = "tcp"  // TCP 连接服务器远程端口
+	ProbeTypeHTTP = "http" // HTTP(S) GET 自定义域名
+	ProbeTypeTLS  = "tls"  // TLS 证书有效期检查
+)
+
+// ProxyProbe 代理外部拨测配置，由面板定时从外部访问代理暴露的服务
+type ProxyProbe struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	ProxyID         uint       `json:"proxy_id" gorm:"not null;index"`
+	ProxyName       string     `json:"proxy_name" gorm:"-"`                         // 非数据库字段，用于返回代理名称
+	Type            string     `json:"type" gorm:"type:varchar(10);not null"`       // tcp, http, tls
+	Target          string     `json:"target" gorm:"size:500"`                      // 自定义目标，留空时按代理配置推导（host:port 或 URL）
+	ExpectedStatus  int        `json:"expected_status"`                             // HTTP 期望状态码，0 表示 2xx/3xx 均视为成功
+	Keyword         string     `json:"keyword" gorm:"size:200"`                     // HTTP 响应中必须包含的关键字
+	CertExpiryDays  int        `json:"cert_expiry_days"`                            // TLS 证书剩余天数低于该值视为失败
+	IntervalSeconds int        `json:"interval_seconds" gorm:"not null;default:60"` // 拨测间隔（秒）
+	TimeoutSeconds  int        `json:"timeout_seconds" gorm:"not null;default:10"`  // 单次拨测超时（秒）
+	Enabled         bool       `json:"enabled" gorm:"not null"`                     // 是否启用
+	LastCheckedAt   *time.Time `json:"last_checked_at"`                             // 最近一次拨测时间
+	LastSuccess     bool       `json:"last_success"`                                // 最近一次拨测是否成功
+	LastLatencyMs   int64      `json:"last_latency_ms"`                             // 最近一次拨测耗时（毫秒）
+	LastError       string     `json:"last_error" gorm:"type:text"`                 // 最近一次拨测失败原因
+	CertExpiresAt   *time.Time `json:"cert_expires_at"`                             // 最近一次获取的证书过期时间
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ProxyProbeResult 单次拨测结果，用于计算可用率和延迟趋势
+type ProxyProbeResult struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ProbeID    uint      `json:"probe_id" gorm:"not null;index:idx_probe_result_time"`
+	Success    bool      `json:"success"`
+	LatencyMs  int64     `json:"latency_ms"`
+	StatusCode int       `json:"status_code"` // HTTP 状态码，其他类型为0
+	Error      string    `json:"error" gorm:"type:text"`
+	CheckedAt  time.Time `json:"checked_at" gorm:"not null;index:idx_probe_result_time;index"`
+}
+
+// ProxyProbeUptime 拨测可用率统计
+type ProxyProbeUptime struct {
+	Hours        int     `json:"hours"`          // 统计时间范围（小时）
+	Total        int64   `json:"total"`          // 拨测次数
+	Success      int64   `json:"success"`        // 成功次数
+	UptimePct    float64 `json:"uptime_pct"`     // 可用率百分比，无数据时为 -1
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // 成功拨测的平均耗时
+}
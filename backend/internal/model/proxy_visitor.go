@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ProxyVisitor 代理访客记录，按服务器、代理和来源 IP 聚合连接次数
+type ProxyVisitor struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ServerID  uint      `json:"server_id" gorm:"not null;uniqueIndex:idx_visitor_proxy_ip"`
+	ProxyName string    `json:"proxy_name" gorm:"type:varchar(200);not null;uniqueIndex:idx_visitor_proxy_ip"` // FRP 中的完整名称 clientName.proxyName
+	IP        string    `json:"ip" gorm:"type:varchar(64);not null;uniqueIndex:idx_visitor_proxy_ip"`
+	Location  string    `json:"location" gorm:"type:varchar(200)"`
+	ConnCount int64     `json:"conn_count" gorm:"not null;default:0"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen" gorm:"index"`
+}
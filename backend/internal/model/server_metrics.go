@@ -19,6 +19,7 @@ type ServerMetricsHistory struct {
 	MemoryBytes int64     `json:"memory_bytes"`
 	TrafficIn   int64     `json:"traffic_in"`
 	TrafficOut  int64     `json:"traffic_out"`
+	ClientCount int64     `json:"client_count"` // frps 在线客户端数
 	RecordTime  time.Time `json:"record_time" gorm:"index:idx_server_time;not null"`
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -9,6 +9,8 @@
  */
 package model
 
+import "time"
+
 // TrafficSummary 流量汇总统计
 type TrafficSummary struct {
 	TotalBytesIn   int64 `json:"total_bytes_in"`
@@ -18,3 +20,45 @@ type TrafficSummary struct {
 	ActiveProxies  int   `json:"active_proxies"`
 	TotalProxies   int   `json:"total_proxies"`
 }
+
+// 流量报表分组方式
+const (
+	TrafficReportByClient = "client"
+	TrafficReportByProxy  = "proxy"
+	TrafficReportByServer = "server"
+)
+
+// ProxyTrafficUsage 单个隧道在时间范围内的流量增量合计
+type ProxyTrafficUsage struct {
+	ServerID  uint   `json:"server_id"`
+	ProxyName string `json:"proxy_name"`
+	DeltaIn   int64  `json:"delta_in"`
+	DeltaOut  int64  `json:"delta_out"`
+}
+
+// TrafficReportRow 流量报表行，按分组方式填充对应的名称字段
+type TrafficReportRow struct {
+	ServerID   uint   `json:"server_id"`
+	ServerName string `json:"server_name"`
+	ClientID   uint   `json:"client_id,omitempty"`
+	ClientName string `json:"client_name,omitempty"`
+	ProxyID    uint   `json:"proxy_id,omitempty"`
+	ProxyName  string `json:"proxy_name,omitempty"`
+	TrafficIn  int64  `json:"traffic_in"`
+	TrafficOut int64  `json:"traffic_out"`
+	Total      int64  `json:"total"`
+}
+
+// TrafficReport 流量用量报表，时间范围左闭右开
+type TrafficReport struct {
+	GroupBy    string             `json:"group_by"`
+	Timezone   string             `json:"timezone"`
+	Start      time.Time          `json:"start"`
+	End        time.Time          `json:"end"`
+	Rows       []TrafficReportRow `json:"rows"`
+	TrafficIn  int64              `json:"traffic_in"`
+	TrafficOut int64              `json:"traffic_out"`
+	Total      int64              `json:"total"`
+	// Approximate 部分时间段的明细数据已超出保留期（如非服务器时区的首尾几小时），合计可能偏小
+	Approximate bool `json:"approximate"`
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// 流量配额目标类型
+const (
+	QuotaTargetProxy  = "proxy"  // 单个代理
+	QuotaTargetClient = "client" // 客户端下全部代理
+)
+
+// 流量配额周期
+const (
+	QuotaPeriodDaily   = "daily"
+	QuotaPeriodMonthly = "monthly"
+)
+
+// 超出配额后的处理方式
+const (
+	QuotaActionSuspend  = "suspend"  // 禁用代理
+	QuotaActionThrottle = "throttle" // 限制代理带宽
+)
+
+// TrafficQuota 流量配额，超出后自动禁用或限速目标代理，周期重置时自动恢复
+type TrafficQuota struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	TargetType    string     `json:"target_type" gorm:"type:varchar(20);not null;index:idx_quota_target"` // proxy, client
+	TargetID      uint       `json:"target_id" gorm:"not null;index:idx_quota_target"`                    // 对应 proxy_id 或 client_id
+	TargetName    string     `json:"target_name" gorm:"-"`                                                // 非数据库字段，用于返回目标名称
+	Period        string     `json:"period" gorm:"type:varchar(10);not null;default:'monthly'"`           // daily, monthly
+	LimitBytes    int64      `json:"limit_bytes" gorm:"not null"`                                         // 周期内允许的入站+出站流量
+	ResetDay      int        `json:"reset_day" gorm:"default:1"`                                          // 月度配额的重置日（1-28）
+	Action        string     `json:"action" gorm:"type:varchar(10);not null;default:'suspend'"`           // suspend, throttle
+	ThrottleLimit string     `json:"throttle_limit" gorm:"size:20"`                                       // 限速时使用的带宽限制，如 1MB
+	Enabled       bool       `json:"enabled" gorm:"not null"`
+	PeriodStart   time.Time  `json:"period_start"`                  // 当前统计周期开始时间
+	UsedBytes     int64      `json:"used_bytes" gorm:"default:0"`   // 当前周期已用流量
+	LastCheckedAt *time.Time `json:"last_checked_at"`               // 已统计到的时间点
+	Exceeded      bool       `json:"exceeded" gorm:"default:false"` // 当前周期是否已超出配额
+	ExceededAt    *time.Time `json:"exceeded_at"`                   // 超出配额的时间
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TrafficQuotaSuspension 因配额被禁用或限速的代理，记录原始状态用于周期重置时恢复
+type TrafficQuotaSuspension struct {
+	ID                     uint      `json:"id" gorm:"primaryKey"`
+	QuotaID                uint      `json:"quota_id" gorm:"not null;index"`
+	ProxyID                uint      `json:"proxy_id" gorm:"not null;index"`
+	Action                 string    `json:"action" gorm:"type:varchar(10);not null"`
+	PrevBandwidthLimit     string    `json:"prev_bandwidth_limit" gorm:"size:20"`
+	PrevBandwidthLimitMode string    `json:"prev_bandwidth_limit_mode" gorm:"size:10"`
+	AppliedBandwidthLimit  string    `json:"applied_bandwidth_limit" gorm:"size:20"` // 限速时写入的带宽限制
+	CreatedAt              time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending = "pending" // 等待投递或等待重试
+	WebhookDeliverySuccess = "success" // 投递成功
+	WebhookDeliveryFailed  = "failed"  // 重试次数用尽
+)
+
+// WebhookDelivery 告警 Webhook 及通用 Webhook 通知渠道的出站投递记录
+// 请求体在入队时渲染并保存，重试和手动重新投递使用相同内容
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	AlertLogID     uint       `json:"alert_log_id" gorm:"index"` // 关联告警日志，恢复通知和通用 Webhook 渠道的投递为0
+	RuleID         uint       `json:"rule_id" gorm:"index"`
+	EventType      string     `json:"event_type" gorm:"type:varchar(30)"`
+	URL            string     `json:"url" gorm:"type:varchar(500);not null"`
+	ContentType    string     `json:"content_type" gorm:"type:varchar(100)"`
+	Payload        string     `json:"payload" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"type:varchar(20);index;default:'pending'"`
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	MaxAttempts    int        `json:"max_attempts" gorm:"default:6"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error" gorm:"type:varchar(500)"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookDeliveryAttempt 单次投递尝试记录
+type WebhookDeliveryAttempt struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	DeliveryID   uint      `json:"delivery_id" gorm:"index;not null"`
+	Attempt      int       `json:"attempt"`
+	Manual       bool      `json:"manual"` // 是否为手动重新投递
+	Success      bool      `json:"success"`
+	StatusCode   int       `json:"status_code"`
+	Error        string    `json:"error" gorm:"type:varchar(500)"`
+	ResponseBody string    `json:"response_body" gorm:"type:text"` // 截断后的响应内容
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// AlertEscalationRepository 告警升级策略及值班表数据访问
+type AlertEscalationRepository struct{}
+
+func NewAlertEscalationRepository() *AlertEscalationRepository {
+	return &AlertEscalationRepository{}
+}
+
+// Policy CRUD
+func (r *AlertEscalationRepository) CreatePolicy(policy *model.AlertEscalationPolicy) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(policy).Error; err != nil {
+			return err
+		}
+		return replaceSteps(tx, policy)
+	})
+}
+
+// UpdatePolicy 更新策略并整体替换其全部级别
+func (r *AlertEscalationRepository) UpdatePolicy(policy *model.AlertEscalationPolicy) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(policy).Error; err != nil {
+			return err
+		}
+		return replaceSteps(tx, policy)
+	})
+}
+
+func replaceSteps(tx *gorm.DB, policy *model.AlertEscalationPolicy) error {
+	if err := tx.Where("policy_id = ?", policy.ID).Delete(&model.AlertEscalationStep{}).Error; err != nil {
+		return err
+	}
+	for i := range policy.Steps {
+		policy.Steps[i].ID = 0
+		policy.Steps[i].PolicyID = policy.ID
+		if err := tx.Create(&policy.Steps[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AlertEscalationRepository) DeletePolicy(id uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", id).Delete(&model.AlertEscalationStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.AlertEscalationPolicy{}, id).Error
+	})
+}
+
+// FindPolicyByID 获取策略及按级别排序的全部级别
+func (r *AlertEscalationRepository) FindPolicyByID(id uint) (*model.AlertEscalationPolicy, error) {
+	var policy model.AlertEscalationPolicy
+	if err := database.DB.First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+	err := database.DB.Where("policy_id = ?", id).Order("level").Find(&policy.Steps).Error
+	return &policy, err
+}
+
+func (r *AlertEscalationRepository) FindAllPolicies() ([]model.AlertEscalationPolicy, error) {
+	var policies []model.AlertEscalationPolicy
+	if err := database.DB.Order("id desc").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	var steps []model.AlertEscalationStep
+	if err := database.DB.Order("level").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+	byPolicy := make(map[uint][]model.AlertEscalationStep)
+	for _, step := range steps {
+		byPolicy[step.PolicyID] = append(byPolicy[step.PolicyID], step)
+	}
+	for i := range policies {
+		policies[i].Steps = byPolicy[policies[i].ID]
+	}
+	return policies, nil
+}
+
+// FindAllSteps 获取全部策略的级别，用于检查值班表引用
+func (r *AlertEscalationRepository) FindAllSteps() ([]model.AlertEscalationStep, error) {
+	var steps []model.AlertEscalationStep
+	err := database.DB.Find(&steps).Error
+	return steps, err
+}
+
+// CountRulesByPolicy 统计引用指定策略的告警规则数
+func (r *AlertEscalationRepository) CountRulesByPolicy(policyID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.AlertRule{}).Where("escalation_policy_id = ?", policyID).Count(&count).Error
+	return count, err
+}
+
+// Schedule CRUD
+func (r *AlertEscalationRepository) CreateSchedule(schedule *model.OnCallSchedule) error {
+	return database.DB.Create(schedule).Error
+}
+
+func (r *AlertEscalationRepository) UpdateSchedule(schedule *model.OnCallSchedule) error {
+	return database.DB.Save(schedule).Error
+}
+
+func (r *AlertEscalationRepository) DeleteSchedule(id uint) error {
+	return database.DB.Delete(&model.OnCallSchedule{}, id).Error
+}
+
+func (r *AlertEscalationRepository) FindScheduleByID(id uint) (*model.OnCallSchedule, error) {
+	var schedule model.OnCallSchedule
+	err := database.DB.First(&schedule, id).Error
+	return &schedule, err
+}
+
+func (r *AlertEscalationRepository) FindAllSchedules() ([]model.OnCallSchedule, error) {
+	var schedules []model.OnCallSchedule
+	err := database.DB.Order("id desc").Find(&schedules).Error
+	return schedules, err
+}
+
+// FindEnabledSchedulesByIDs 获取指定ID中已启用的值班表
+func (r *AlertEscalationRepository) FindEnabledSchedulesByIDs(ids []uint) ([]model.OnCallSchedule, error) {
+	var schedules []model.OnCallSchedule
+	if len(ids) == 0 {
+		return schedules, nil
+	}
+	err := database.DB.Where("id IN ? AND enabled = ?", ids, true).Find(&schedules).Error
+	return schedules, err
+}
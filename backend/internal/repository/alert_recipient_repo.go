@@ -120,3 +120,13 @@ func (r *AlertRecipientRepo) GetRecipientsByGroupIDs(groupIDs []uint) ([]model.A
 	`, groupIDs, true, true).Scan(&recipients).Error
 	return recipients, err
 }
+
+// GetEnabledGroupsByIDs 获取指定ID中已启用的分组
+func (r *AlertRecipientRepo) GetEnabledGroupsByIDs(ids []uint) ([]model.AlertRecipientGroup, error) {
+	var groups []model.AlertRecipientGroup
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	err := database.DB.Where("id IN ? AND enabled = ?", ids, true).Find(&groups).Error
+	return groups, err
+}
@@ -28,6 +28,12 @@ func (r *AlertRepo) CreateRule(rule *model.AlertRule) error {
 	return r.db.Create(rule).Error
 }
 
+func (r *AlertRepo) GetRuleByID(id uint) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	err := r.db.First(&rule, id).Error
+	return &rule, err
+}
+
 func (r *AlertRepo) GetEnabledRules() ([]model.AlertRule, error) {
 	var rules []model.AlertRule
 	err := r.db.Where("enabled = ?", true).Find(&rules).Error
@@ -40,6 +46,13 @@ func (r *AlertRepo) GetEnabledRulesByTargetType(targetType model.AlertTargetType
 	return rules, err
 }
 
+// GetEnabledRulesByRuleType 获取指定规则类型的已启用规则
+func (r *AlertRepo) GetEnabledRulesByRuleType(ruleType string) ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	err := r.db.Where("enabled = ? AND rule_type = ?", true, ruleType).Find(&rules).Error
+	return rules, err
+}
+
 func (r *AlertRepo) GetAllRules() ([]model.AlertRule, error) {
 	var rules []model.AlertRule
 	err := r.db.Find(&rules).Error
@@ -67,15 +80,194 @@ func (r *AlertRepo) DeleteRule(id uint) error {
 }
 
 func (r *AlertRepo) CreateAlert(alert *model.AlertLog) error {
+	if alert.Status == "" {
+		alert.Status = model.AlertStatusFiring
+	}
 	return r.db.Create(alert).Error
 }
 
+func (r *AlertRepo) FindAlertByID(id uint) (*model.AlertLog, error) {
+	var log model.AlertLog
+	err := r.db.First(&log, id).Error
+	return &log, err
+}
+
+// GetAlertLogsByStatus 按状态获取告警日志，open 表示触发中和已确认的告警
+func (r *AlertRepo) GetAlertLogsByStatus(status string, limit int) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if status == "open" {
+		query = query.Where("status IN ?", []string{model.AlertStatusFiring, model.AlertStatusAcknowledged})
+	} else {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&logs).Error
+	return logs, err
+}
+
+// GetOpenAlertsByRule 获取规则下未结束的告警
+func (r *AlertRepo) GetOpenAlertsByRule(ruleID uint) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	err := r.db.Where("rule_id = ? AND status IN ?", ruleID, []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).
+		Find(&logs).Error
+	return logs, err
+}
+
+// GetOpenAlertsByRuleTarget 获取规则下指定目标未结束的告警
+func (r *AlertRepo) GetOpenAlertsByRuleTarget(ruleID, targetID uint) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	err := r.db.Where("rule_id = ? AND target_id = ? AND status IN ?", ruleID, targetID,
+		[]string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).Find(&logs).Error
+	return logs, err
+}
+
+// AcknowledgeAlert 确认触发中的告警，返回是否更新成功
+func (r *AlertRepo) AcknowledgeAlert(id, userID uint, username string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.AlertLog{}).Where("id = ? AND status = ?", id, model.AlertStatusFiring).
+		Updates(map[string]interface{}{
+			"status":        model.AlertStatusAcknowledged,
+			"acked_by":      userID,
+			"acked_by_name": username,
+			"acked_at":      at,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// UnacknowledgeAlert 取消确认，告警恢复为触发中
+func (r *AlertRepo) UnacknowledgeAlert(id uint) (bool, error) {
+	result := r.db.Model(&model.AlertLog{}).Where("id = ? AND status = ?", id, model.AlertStatusAcknowledged).
+		Updates(map[string]interface{}{
+			"status":        model.AlertStatusFiring,
+			"acked_by":      0,
+			"acked_by_name": "",
+			"acked_at":      nil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ResolveAlert 结束单条未结束的告警
+func (r *AlertRepo) ResolveAlert(id uint, at time.Time) (bool, error) {
+	result := r.db.Model(&model.AlertLog{}).
+		Where("id = ? AND status IN ?", id, []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).
+		Updates(map[string]interface{}{"status": model.AlertStatusResolved, "resolved_at": at, "next_escalation_at": nil})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ResolveOpenAlerts 结束规则下全部未结束的告警
+func (r *AlertRepo) ResolveOpenAlerts(ruleID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&model.AlertLog{}).
+		Where("rule_id = ? AND status IN ?", ruleID, []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).
+		Updates(map[string]interface{}{"status": model.AlertStatusResolved, "resolved_at": at, "next_escalation_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// GetDueEscalations 获取仍在触发中且已到升级时间的告警
+func (r *AlertRepo) GetDueEscalations(now time.Time) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	err := r.db.Where("status = ? AND next_escalation_at IS NOT NULL AND next_escalation_at <= ?", model.AlertStatusFiring, now).
+		Order("next_escalation_at").Find(&logs).Error
+	return logs, err
+}
+
+// AdvanceEscalation 将告警从 fromLevel 推进到 level，级别已被其他流程推进时返回 false
+func (r *AlertRepo) AdvanceEscalation(id uint, fromLevel, level int, next *time.Time) (bool, error) {
+	result := r.db.Model(&model.AlertLog{}).Where("id = ? AND escalation_level = ?", id, fromLevel).
+		Updates(map[string]interface{}{"escalation_level": level, "next_escalation_at": next})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ResolveOpenTargetAlerts 结束规则下指定目标全部未结束的告警
+func (r *AlertRepo) ResolveOpenTargetAlerts(ruleID, targetID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&model.AlertLog{}).
+		Where("rule_id = ? AND target_id = ? AND status IN ?", ruleID, targetID, []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).
+		Updates(map[string]interface{}{"status": model.AlertStatusResolved, "resolved_at": at, "next_escalation_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// GetDependencyAlert 获取目标未结束或在 resolvedSince 之后结束的最近一条离线告警，用于判断下级告警是否被抑制
+func (r *AlertRepo) GetDependencyAlert(targetType model.AlertTargetType, targetID uint, resolvedSince time.Time) (*model.AlertLog, error) {
+	var log model.AlertLog
+	err := r.db.Where("target_type = ? AND target_id = ? AND alert_type = ?", targetType, targetID, "offline").
+		Where("status IN ? OR resolved_at >= ?", []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}, resolvedSince).
+		Order("id DESC").First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetSuppressedAlerts 获取被指定告警抑制的下级告警
+func (r *AlertRepo) GetSuppressedAlerts(parentID uint) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	err := r.db.Where("suppressed_by = ?", parentID).Order("created_at ASC, id ASC").Find(&logs).Error
+	return logs, err
+}
+
+// GetOpenSuppressedAlert 获取规则下指定目标未结束且被上级告警抑制的最近一条告警
+func (r *AlertRepo) GetOpenSuppressedAlert(ruleID, targetID uint) (*model.AlertLog, error) {
+	var log model.AlertLog
+	err := r.db.Where("rule_id = ? AND target_id = ? AND suppressed_by > 0 AND status IN ?",
+		ruleID, targetID, []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).
+		Order("created_at DESC, id DESC").First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// ReleaseSuppressedAlert 上级告警结束后解除未结束告警的抑制，返回是否更新成功
+func (r *AlertRepo) ReleaseSuppressedAlert(id uint) (bool, error) {
+	result := r.db.Model(&model.AlertLog{}).
+		Where("id = ? AND suppressed_by > 0 AND status IN ?", id, []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}).
+		Update("suppressed_by", 0)
+	return result.RowsAffected == 1, result.Error
+}
+
 func (r *AlertRepo) GetAlertLogs(limit int) ([]model.AlertLog, error) {
 	var logs []model.AlertLog
 	err := r.db.Order("created_at DESC").Limit(limit).Find(&logs).Error
 	return logs, err
 }
 
+// GetAlertsBetween 获取时间范围内触发的告警，时间范围左闭右开，按触发时间升序
+func (r *AlertRepo) GetAlertsBetween(start, end time.Time) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	err := r.db.Where("created_at >= ? AND created_at < ?", start, end).
+		Order("created_at ASC, id ASC").Find(&logs).Error
+	return logs, err
+}
+
+// GetOfflineAlertsOpenAt 获取在指定时间之前触发、该时间仍未恢复的离线告警，按触发时间升序
+func (r *AlertRepo) GetOfflineAlertsOpenAt(at time.Time) ([]model.AlertLog, error) {
+	var logs []model.AlertLog
+	err := r.db.Where("alert_type = ? AND created_at < ?", "offline", at).
+		Where("status IN ? OR resolved_at > ?", []string{model.AlertStatusFiring, model.AlertStatusAcknowledged}, at).
+		Order("created_at ASC, id ASC").Find(&logs).Error
+	return logs, err
+}
+
+// GetRuleAlertSince 获取规则在指定时间之后的最近一条告警
+func (r *AlertRepo) GetRuleAlertSince(ruleID uint, since time.Time) (*model.AlertLog, error) {
+	var log model.AlertLog
+	err := r.db.Where("rule_id = ? AND created_at >= ?", ruleID, since).
+		Order("created_at DESC").First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetRuleTargetAlertSince 获取规则下指定目标在指定时间之后最近的一条告警
+func (r *AlertRepo) GetRuleTargetAlertSince(ruleID, targetID uint, since time.Time) (*model.AlertLog, error) {
+	var log model.AlertLog
+	err := r.db.Where("rule_id = ? AND target_id = ? AND created_at >= ?", ruleID, targetID, since).
+		Order("created_at DESC").First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
 func (r *AlertRepo) GetRecentAlert(ruleID uint, duration time.Duration) (*model.AlertLog, error) {
 	var log model.AlertLog
 	cutoff := time.Now().Add(-duration)
@@ -120,3 +312,20 @@ func (r *AlertRepo) GetRecentSystemAlert(ruleType string, duration time.Duration
 	}
 	return &log, nil
 }
+
+// AlertLogCount 按目标类型和告警类型统计的告警数量
+type AlertLogCount struct {
+	TargetType string
+	AlertType  string
+	Count      int64
+}
+
+// CountAlertLogsByType 按目标类型和告警类型统计告警记录数量
+func (r *AlertRepo) CountAlertLogsByType() ([]AlertLogCount, error) {
+	var results []AlertLogCount
+	err := r.db.Model(&model.AlertLog{}).
+		Select("target_type, alert_type, COUNT(*) as count").
+		Group("target_type, alert_type").
+		Scan(&results).Error
+	return results, err
+}
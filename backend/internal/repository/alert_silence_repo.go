@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+)
+
+// AlertSilenceRepository 告警静默数据访问
+type AlertSilenceRepository struct{}
+
+func NewAlertSilenceRepository() *AlertSilenceRepository {
+	return &AlertSilenceRepository{}
+}
+
+func (r *AlertSilenceRepository) Create(silence *model.AlertSilence) error {
+	return database.DB.Create(silence).Error
+}
+
+func (r *AlertSilenceRepository) Update(silence *model.AlertSilence) error {
+	return database.DB.Save(silence).Error
+}
+
+func (r *AlertSilenceRepository) Delete(id uint) error {
+	return database.DB.Delete(&model.AlertSilence{}, id).Error
+}
+
+func (r *AlertSilenceRepository) FindByID(id uint) (*model.AlertSilence, error) {
+	var silence model.AlertSilence
+	err := database.DB.First(&silence, id).Error
+	return &silence, err
+}
+
+// FindAll 获取全部静默，按结束时间倒序
+func (r *AlertSilenceRepository) FindAll() ([]model.AlertSilence, error) {
+	var silences []model.AlertSilence
+	err := database.DB.Order("ends_at DESC").Find(&silences).Error
+	return silences, err
+}
+
+// FindActive 获取指定时间生效的静默
+func (r *AlertSilenceRepository) FindActive(at time.Time) ([]model.AlertSilence, error) {
+	var silences []model.AlertSilence
+	err := database.DB.Where("starts_at <= ? AND ends_at > ?", at, at).Find(&silences).Error
+	return silences, err
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+)
+
+type RevokedClientCertRepository struct{}
+
+func NewRevokedClientCertRepository() *RevokedClientCertRepository {
+	return &RevokedClientCertRepository{}
+}
+
+// Revoke 记录吊销的证书序列号，重复吊销时忽略
+func (r *RevokedClientCertRepository) Revoke(clientID uint, serial string, reason string) error {
+	record := &model.RevokedClientCert{
+		Serial:    serial,
+		ClientID:  clientID,
+		Reason:    reason,
+		RevokedAt: time.Now(),
+	}
+	return database.DB.Where("serial = ?", serial).FirstOrCreate(record).Error
+}
+
+// IsRevoked 检查证书序列号是否已吊销
+func (r *RevokedClientCertRepository) IsRevoked(serial string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&model.RevokedClientCert{}).Where("serial = ?", serial).Count(&count).Error
+	return count > 0, err
+}
+
+// FindAllSerials 获取所有已吊销的证书序列号
+func (r *RevokedClientCertRepository) FindAllSerials() ([]string, error) {
+	var serials []string
+	err := database.DB.Model(&model.RevokedClientCert{}).Pluck("serial", &serials).Error
+	return serials, err
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+)
+
+type ClientMetricsRepository struct{}
+
+func NewClientMetricsRepository() *ClientMetricsRepository {
+	return &ClientMetricsRepository{}
+}
+
+// Create 创建指标记录
+func (r *ClientMetricsRepository) Create(metrics *model.ClientMetricsHistory) error {
+	return database.DB.Create(metrics).Error
+}
+
+// GetHistory 获取指定客户端的历史指标
+func (r *ClientMetricsRepository) GetHistory(clientID uint, start, end time.Time) ([]model.ClientMetricsHistory, error) {
+	var records []model.ClientMetricsHistory
+	err := database.DB.Where("client_id = ? AND record_time BETWEEN ? AND ?", clientID, start, end).
+		Order("record_time ASC").Find(&records).Error
+	return records, err
+}
+
+// DeleteOlderThan 删除指定时间之前的记录
+func (r *ClientMetricsRepository) DeleteOlderThan(before time.Time) (int64, error) {
+	result := database.DB.Where("record_time < ?", before).Delete(&model.ClientMetricsHistory{})
+	return result.RowsAffected, result.Error
+}
@@ -39,12 +39,26 @@ func (r *ClientRepository) FindAll(page, pageSize int, keyword string) ([]model.
 	return clients, total, err
 }
 
+// ListAll 获取全部客户端（不分页）
+func (r *ClientRepository) ListAll() ([]model.Client, error) {
+	var clients []model.Client
+	err := database.DB.Order("id ASC").Find(&clients).Error
+	return clients, err
+}
+
 func (r *ClientRepository) FindByID(id uint) (*model.Client, error) {
 	var client model.Client
 	err := database.DB.Preload("Proxies").First(&client, id).Error
 	return &client, err
 }
 
+// FindByName 按名称查找客户端，名称即 frpc 配置中的 user
+func (r *ClientRepository) FindByName(name string) (*model.Client, error) {
+	var client model.Client
+	err := database.DB.Where("name = ?", name).First(&client).Error
+	return &client, err
+}
+
 func (r *ClientRepository) Create(client *model.Client) error {
 	return database.DB.Create(client).Error
 }
@@ -96,6 +110,35 @@ func (r *ClientRepository) UpdateConfigSync(id uint, version int, syncTime *time
 	}).Error
 }
 
+// UpdateDaemonCredential 更新客户端守护进程凭证
+func (r *ClientRepository) UpdateDaemonCredential(id uint, token, prevToken string, prevExpire *time.Time, revoked bool) error {
+	now := time.Now()
+	return database.DB.Model(&model.Client{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"daemon_token":             token,
+		"daemon_token_prev":        prevToken,
+		"daemon_token_prev_expire": prevExpire,
+		"daemon_token_rotated_at":  &now,
+		"daemon_token_revoked":     revoked,
+	}).Error
+}
+
+// UpdateTLSCert 更新客户端当前的 mTLS 证书信息
+func (r *ClientRepository) UpdateTLSCert(id uint, serial string, expireAt *time.Time) error {
+	return database.DB.Model(&model.Client{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"tls_cert_serial":    serial,
+		"tls_cert_expire_at": expireAt,
+	}).Error
+}
+
+// UpdateDaemonState 更新守护进程上报的已应用配置状态
+func (r *ClientRepository) UpdateDaemonState(id uint, version int, hash string, reportedAt time.Time) error {
+	return database.DB.Model(&model.Client{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"applied_config_version": version,
+		"applied_config_hash":    hash,
+		"state_reported_at":      reportedAt,
+	}).Error
+}
+
 // UpdateVersionInfo 更新客户端版本信息
 func (r *ClientRepository) UpdateVersionInfo(id uint, frpcVersion, daemonVersion, os, arch string) error {
 	updates := map[string]interface{}{}
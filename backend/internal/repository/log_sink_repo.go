@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+)
+
+// LogSinkRepository 日志转发目标数据访问
+type LogSinkRepository struct{}
+
+func NewLogSinkRepository() *LogSinkRepository {
+	return &LogSinkRepository{}
+}
+
+func (r *LogSinkRepository) Create(sink *model.LogSink) error {
+	return database.DB.Create(sink).Error
+}
+
+// Update 更新配置，不覆盖推送状态
+func (r *LogSinkRepository) Update(sink *model.LogSink) error {
+	return database.DB.Model(sink).Select("*").Omit("last_error", "last_sent_at", "created_at").Updates(sink).Error
+}
+
+func (r *LogSinkRepository) Delete(id uint) error {
+	return database.DB.Delete(&model.LogSink{}, id).Error
+}
+
+func (r *LogSinkRepository) FindByID(id uint) (*model.LogSink, error) {
+	var sink model.LogSink
+	err := database.DB.First(&sink, id).Error
+	return &sink, err
+}
+
+func (r *LogSinkRepository) FindAll() ([]model.LogSink, error) {
+	var sinks []model.LogSink
+	err := database.DB.Order("id ASC").Find(&sinks).Error
+	return sinks, err
+}
+
+func (r *LogSinkRepository) FindEnabled() ([]model.LogSink, error) {
+	var sinks []model.LogSink
+	err := database.DB.Where("enabled = ?", true).Order("id ASC").Find(&sinks).Error
+	return sinks, err
+}
+
+// UpdateStatus 记录推送结果，成功时 sentAt 不为空
+func (r *LogSinkRepository) UpdateStatus(id uint, lastError string, sentAt *time.Time) error {
+	updates := map[string]interface{}{"last_error": lastError}
+	if sentAt != nil {
+		updates["last_sent_at"] = sentAt
+	}
+	return database.DB.Model(&model.LogSink{}).Where("id = ?", id).Updates(updates).Error
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+)
+
+// MessageTemplateRepository 告警消息模板数据访问
+type MessageTemplateRepository struct{}
+
+func NewMessageTemplateRepository() *MessageTemplateRepository {
+	return &MessageTemplateRepository{}
+}
+
+func (r *MessageTemplateRepository) Create(tpl *model.MessageTemplate) error {
+	return database.DB.Create(tpl).Error
+}
+
+func (r *MessageTemplateRepository) Update(tpl *model.MessageTemplate) error {
+	return database.DB.Save(tpl).Error
+}
+
+func (r *MessageTemplateRepository) Delete(id uint) error {
+	return database.DB.Delete(&model.MessageTemplate{}, id).Error
+}
+
+func (r *MessageTemplateRepository) FindByID(id uint) (*model.MessageTemplate, error) {
+	var tpl model.MessageTemplate
+	err := database.DB.First(&tpl, id).Error
+	return &tpl, err
+}
+
+func (r *MessageTemplateRepository) FindAll() ([]model.MessageTemplate, error) {
+	var templates []model.MessageTemplate
+	err := database.DB.Order("event_type ASC, channel ASC").Find(&templates).Error
+	return templates, err
+}
+
+// FindByEventChannel 获取事件类型和发送方式对应的模板
+func (r *MessageTemplateRepository) FindByEventChannel(eventType, channel string) (*model.MessageTemplate, error) {
+	var tpl model.MessageTemplate
+	err := database.DB.Where("event_type = ? AND channel = ?", eventType, channel).First(&tpl).Error
+	return &tpl, err
+}
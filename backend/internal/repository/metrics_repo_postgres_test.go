@@ -0,0 +1,37 @@
+//go:build postgres
+
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// 使用 -tags postgres 运行时，指标仓储测试同时在 PostgreSQL 上执行，连接串由 TEST_POSTGRES_DSN 指定，例如:
+//
+//	TEST_POSTGRES_DSN="host=127.0.0.1 user=postgres password=postgres dbname=frp_test sslmode=disable" \
+//		go test -tags postgres ./internal/repository/...
+func init() {
+	testDialects = append(testDialects, testDialect{name: "postgres", open: openPostgresTestDB})
+}
+
+func openPostgresTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	require.NotEmpty(t, dsn, "使用 postgres 构建标签时必须设置 TEST_POSTGRES_DSN")
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Migrator().DropTable(metricsTestModels...))
+	require.NoError(t, db.AutoMigrate(metricsTestModels...))
+	t.Cleanup(func() {
+		db.Migrator().DropTable(metricsTestModels...)
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var metricsTestModels = []interface{}{
+	&model.ProxyMetricsHistory{},
+	&model.ServerMetricsHistory{},
+	&model.ProxyMetricsRollup{},
+	&model.ServerMetricsRollup{},
+}
+
+// testDialect 仓储测试使用的数据库，open 返回已迁移好 metricsTestModels 的连接
+type testDialect struct {
+	name string
+	open func(t *testing.T) *gorm.DB
+}
+
+// testDialects 默认只在 SQLite 上运行；使用 -tags postgres 构建时追加 PostgreSQL，
+// 见 metrics_repo_postgres_test.go
+var testDialects = []testDialect{{name: "sqlite", open: openSQLiteTestDB}}
+
+func openSQLiteTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(metricsTestModels...))
+	return db
+}
+
+// runOnTestDialects 在每个测试数据库上运行同一组断言
+func runOnTestDialects(t *testing.T, fn func(t *testing.T)) {
+	for _, dialect := range testDialects {
+		t.Run(dialect.name, func(t *testing.T) {
+			database.DB = dialect.open(t)
+			fn(t)
+		})
+	}
+}
+
+func TestTimeBucketExpr(t *testing.T) {
+	sqliteDB := &gorm.DB{Config: &gorm.Config{Dialector: sqlite.Dialector{}}}
+	postgresDB := &gorm.DB{Config: &gorm.Config{Dialector: postgres.Dialector{}}}
+
+	assert.Equal(t, "(CAST(strftime('%s', record_time) AS INTEGER) + 19800) / 3600", timeBucketExpr(sqliteDB, "record_time", 3600, 19800))
+	assert.Equal(t, "CAST(FLOOR((EXTRACT(EPOCH FROM record_time) + 19800) / 3600) AS BIGINT)", timeBucketExpr(postgresDB, "record_time", 3600, 19800))
+
+	// UTC+5:30 的本地整点
+	loc := time.FixedZone("IST", 19800)
+	assert.Equal(t, timeBucketKey(time.Date(2026, 1, 1, 10, 0, 0, 0, loc), 3600, 19800), timeBucketKey(time.Date(2026, 1, 1, 10, 59, 59, 0, loc), 3600, 19800))
+	assert.NotEqual(t, timeBucketKey(time.Date(2026, 1, 1, 10, 0, 0, 0, loc), 3600, 19800), timeBucketKey(time.Date(2026, 1, 1, 9, 59, 59, 0, loc), 3600, 19800))
+}
+
+func TestProxyMetricsRepository_GetHourlyTrafficTrend(t *testing.T) {
+	runOnTestDialects(t, func(t *testing.T) {
+		repo := NewProxyMetricsRepository()
+		now := time.Now()
+		hour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, time.Local)
+
+		require.NoError(t, repo.BatchCreate([]model.ProxyMetricsHistory{
+			{ServerID: 1, ProxyName: "a", TrafficIn: 5000, DeltaIn: 100, DeltaOut: 10, RecordTime: hour},
+			{ServerID: 1, ProxyName: "a", TrafficIn: 4900, DeltaIn: 200, DeltaOut: 20, RecordTime: hour.Add(-50 * time.Minute)},
+			{ServerID: 1, ProxyName: "b", TrafficIn: 4000, DeltaIn: 300, DeltaOut: 30, RecordTime: hour.Add(-40 * time.Minute)},
+			{ServerID: 1, ProxyName: "a", TrafficIn: 4700, DeltaIn: 999, DeltaOut: 999, RecordTime: hour.Add(-5 * time.Hour)},
+		}))
+
+		trend, err := repo.GetHourlyTrafficTrend(3)
+		require.NoError(t, err)
+		require.Len(t, trend, 3)
+
+		assert.Equal(t, hour.Format("15:00"), trend[2].Time)
+		assert.Equal(t, int64(100), trend[2].Inbound)
+		assert.Equal(t, int64(10), trend[2].Outbound)
+		assert.Equal(t, hour.Add(-time.Hour).Format("15:00"), trend[1].Time)
+		assert.Equal(t, int64(500), trend[1].Inbound)
+		assert.Equal(t, int64(50), trend[1].Outbound)
+		assert.Equal(t, int64(0), trend[0].Inbound, "超出时间范围的数据不应计入")
+	})
+}
+
+func TestProxyMetricsRepository_Queries(t *testing.T) {
+	runOnTestDialects(t, func(t *testing.T) {
+		repo := NewProxyMetricsRepository()
+		now := time.Now()
+
+		require.NoError(t, repo.BatchCreate([]model.ProxyMetricsHistory{
+			{ServerID: 1, ProxyName: "c.web", TrafficIn: 100, TrafficOut: 50, DeltaIn: 100, DeltaOut: 50, RateIn: 1, RecordTime: now.Add(-2 * time.Minute)},
+			{ServerID: 1, ProxyName: "c.web", TrafficIn: 300, TrafficOut: 150, DeltaIn: 200, DeltaOut: 100, RateIn: 2, RecordTime: now.Add(-time.Minute)},
+			{ServerID: 1, ProxyName: "c.ssh", TrafficIn: 10, TrafficOut: 5, DeltaIn: 10, DeltaOut: 5, RateIn: 3, RecordTime: now.Add(-time.Minute)},
+			{ServerID: 2, ProxyName: "d.web", TrafficIn: 7, TrafficOut: 7, DeltaIn: 7, DeltaOut: 7, RecordTime: now.Add(-time.Minute)},
+		}))
+
+		history, err := repo.GetHistory(1, "c.web", now.Add(-time.Hour), now)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, int64(100), history[0].TrafficIn)
+
+		latest, err := repo.GetLatestByServer(1)
+		require.NoError(t, err)
+		require.Len(t, latest, 2)
+		for _, m := range latest {
+			if m.ProxyName == "c.web" {
+				assert.Equal(t, int64(2), m.RateIn)
+			}
+		}
+
+		names, err := repo.GetDistinctProxyNames(1)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"c.web", "c.ssh"}, names)
+
+		summary, err := repo.GetTrafficSummaryByProxyNames([]string{"c.web", "d.web"}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(300), summary["c.web"]["total_in"], "按增量汇总，而不是累计值求和")
+		assert.Equal(t, int64(7), summary["d.web"]["total_out"])
+
+		first, err := repo.FirstRecordTimeSince(now.Add(-90 * time.Second))
+		require.NoError(t, err)
+		require.NotNil(t, first)
+		assert.WithinDuration(t, now.Add(-time.Minute), *first, time.Second)
+
+		deleted, err := repo.DeleteOlderThan(now.Add(-90 * time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+}
+
+func TestServerMetricsRepository_Queries(t *testing.T) {
+	runOnTestDialects(t, func(t *testing.T) {
+		repo := NewServerMetricsRepository()
+		now := time.Now()
+
+		for i := 3; i >= 1; i-- {
+			require.NoError(t, repo.Create(&model.ServerMetricsHistory{
+				ServerID: 1, CpuPercent: float64(i), MemoryBytes: int64(i), RecordTime: now.Add(-time.Duration(i) * time.Hour),
+			}))
+		}
+
+		history, err := repo.GetHistory(1, now.Add(-150*time.Minute), now)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, 2.0, history[0].CpuPercent)
+
+		inRange, err := repo.FindInRange(now.Add(-3*time.Hour), now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Len(t, inRange, 2, "范围左闭右开")
+
+		deleted, err := repo.DeleteOlderThan(now.Add(-150 * time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+}
+
+func TestMetricsRollupRepository_SaveIgnoresDuplicates(t *testing.T) {
+	runOnTestDialects(t, func(t *testing.T) {
+		repo := NewMetricsRollupRepository()
+		bucket := time.Now().Truncate(time.Hour)
+
+		rollup := model.ProxyMetricsRollup{Resolution: model.MetricsResolution1h, ServerID: 1, ProxyName: "a", RateIn: 10, Samples: 1, BucketTime: bucket}
+		require.NoError(t, repo.SaveProxyRollups([]model.ProxyMetricsRollup{rollup}))
+		rollup.RateIn = 20
+		require.NoError(t, repo.SaveProxyRollups([]model.ProxyMetricsRollup{rollup}))
+
+		records, err := repo.GetProxyRollups(model.MetricsResolution1h, 1, "a", bucket.Add(-time.Hour), bucket)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, int64(10), records[0].RateIn, "已存在的时间桶保持不变")
+
+		latest, err := repo.LatestProxyBucket(model.MetricsResolution1h)
+		require.NoError(t, err)
+		require.NotNil(t, latest)
+		assert.True(t, latest.Equal(bucket))
+	})
+}
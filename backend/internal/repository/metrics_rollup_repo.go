@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// MetricsRollupRepository 指标降采样汇总数据访问
+type MetricsRollupRepository struct{}
+
+func NewMetricsRollupRepository() *MetricsRollupRepository {
+	return &MetricsRollupRepository{}
+}
+
+// SaveProxyRollups 批量保存隧道汇总记录，已存在的时间桶保持不变
+func (r *MetricsRollupRepository) SaveProxyRollups(rollups []model.ProxyMetricsRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rollups, 200).Error
+}
+
+// SaveServerRollups 批量保存服务器汇总记录，已存在的时间桶保持不变
+func (r *MetricsRollupRepository) SaveServerRollups(rollups []model.ServerMetricsRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rollups, 200).Error
+}
+
+// GetProxyRollups 获取指定隧道在时间范围内的汇总记录
+func (r *MetricsRollupRepository) GetProxyRollups(resolution string, serverID uint, proxyName string, start, end time.Time) ([]model.ProxyMetricsRollup, error) {
+	var records []model.ProxyMetricsRollup
+	err := database.DB.Where("resolution = ? AND server_id = ? AND proxy_name = ? AND bucket_time BETWEEN ? AND ?", resolution, serverID, proxyName, start, end).
+		Order("bucket_time ASC").Find(&records).Error
+	return records, err
+}
+
+// GetServerRollups 获取指定服务器在时间范围内的汇总记录
+func (r *MetricsRollupRepository) GetServerRollups(resolution string, serverID uint, start, end time.Time) ([]model.ServerMetricsRollup, error) {
+	var records []model.ServerMetricsRollup
+	err := database.DB.Where("resolution = ? AND server_id = ? AND bucket_time BETWEEN ? AND ?", resolution, serverID, start, end).
+		Order("bucket_time ASC").Find(&records).Error
+	return records, err
+}
+
+// FindProxyRollupsInRange 获取某一粒度在时间范围内的全部隧道汇总记录（左闭右开）
+func (r *MetricsRollupRepository) FindProxyRollupsInRange(resolution string, start, end time.Time) ([]model.ProxyMetricsRollup, error) {
+	var records []model.ProxyMetricsRollup
+	err := database.DB.Where("resolution = ? AND bucket_time >= ? AND bucket_time < ?", resolution, start, end).
+		Order("bucket_time ASC").Find(&records).Error
+	return records, err
+}
+
+// FindServerRollupsInRange 获取某一粒度在时间范围内的全部服务器汇总记录（左闭右开）
+func (r *MetricsRollupRepository) FindServerRollupsInRange(resolution string, start, end time.Time) ([]model.ServerMetricsRollup, error) {
+	var records []model.ServerMetricsRollup
+	err := database.DB.Where("resolution = ? AND bucket_time >= ? AND bucket_time < ?", resolution, start, end).
+		Order("bucket_time ASC").Find(&records).Error
+	return records, err
+}
+
+// LatestProxyBucket 获取某一粒度最新的隧道时间桶，没有记录时返回 nil
+func (r *MetricsRollupRepository) LatestProxyBucket(resolution string) (*time.Time, error) {
+	var records []model.ProxyMetricsRollup
+	err := database.DB.Where("resolution = ?", resolution).Order("bucket_time DESC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0].BucketTime, nil
+}
+
+// LatestServerBucket 获取某一粒度最新的服务器时间桶，没有记录时返回 nil
+func (r *MetricsRollupRepository) LatestServerBucket(resolution string) (*time.Time, error) {
+	var records []model.ServerMetricsRollup
+	err := database.DB.Where("resolution = ?", resolution).Order("bucket_time DESC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0].BucketTime, nil
+}
+
+// FirstProxyBucketSince 获取某一粒度指定时间及之后最早的隧道时间桶，没有记录时返回 nil
+func (r *MetricsRollupRepository) FirstProxyBucketSince(resolution string, since time.Time) (*time.Time, error) {
+	var records []model.ProxyMetricsRollup
+	err := database.DB.Where("resolution = ? AND bucket_time >= ?", resolution, since).Order("bucket_time ASC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0].BucketTime, nil
+}
+
+// FirstServerBucketSince 获取某一粒度指定时间及之后最早的服务器时间桶，没有记录时返回 nil
+func (r *MetricsRollupRepository) FirstServerBucketSince(resolution string, since time.Time) (*time.Time, error) {
+	var records []model.ServerMetricsRollup
+	err := database.DB.Where("resolution = ? AND bucket_time >= ?", resolution, since).Order("bucket_time ASC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0].BucketTime, nil
+}
+
+// DeleteProxyRollupsOlderThan 删除某一粒度指定时间之前的隧道汇总记录
+func (r *MetricsRollupRepository) DeleteProxyRollupsOlderThan(resolution string, before time.Time) (int64, error) {
+	result := database.DB.Where("resolution = ? AND bucket_time < ?", resolution, before).Delete(&model.ProxyMetricsRollup{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteServerRollupsOlderThan 删除某一粒度指定时间之前的服务器汇总记录
+func (r *MetricsRollupRepository) DeleteServerRollupsOlderThan(resolution string, before time.Time) (int64, error) {
+	result := database.DB.Where("resolution = ? AND bucket_time < ?", resolution, before).Delete(&model.ServerMetricsRollup{})
+	return result.RowsAffected, result.Error
+}
+
+// SumProxyDeltaByProxy 按隧道汇总某一粒度在时间范围内的流量增量（左闭右开）
+func (r *MetricsRollupRepository) SumProxyDeltaByProxy(resolution string, start, end time.Time) ([]model.ProxyTrafficUsage, error) {
+	var usages []model.ProxyTrafficUsage
+	err := database.DB.Model(&model.ProxyMetricsRollup{}).
+		Select("server_id, proxy_name, COALESCE(SUM(delta_in), 0) as delta_in, COALESCE(SUM(delta_out), 0) as delta_out").
+		Where("resolution = ? AND bucket_time >= ? AND bucket_time < ?", resolution, start, end).
+		Group("server_id, proxy_name").
+		Scan(&usages).Error
+	return usages, err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+)
+
+// NotificationChannelRepository 通知渠道数据访问
+type NotificationChannelRepository struct{}
+
+func NewNotificationChannelRepository() *NotificationChannelRepository {
+	return &NotificationChannelRepository{}
+}
+
+func (r *NotificationChannelRepository) Create(channel *model.NotificationChannel) error {
+	return database.DB.Create(channel).Error
+}
+
+func (r *NotificationChannelRepository) Update(channel *model.NotificationChannel) error {
+	return database.DB.Save(channel).Error
+}
+
+func (r *NotificationChannelRepository) Delete(id uint) error {
+	return database.DB.Delete(&model.NotificationChannel{}, id).Error
+}
+
+func (r *NotificationChannelRepository) FindByID(id uint) (*model.NotificationChannel, error) {
+	var channel model.NotificationChannel
+	err := database.DB.First(&channel, id).Error
+	return &channel, err
+}
+
+func (r *NotificationChannelRepository) FindAll() ([]model.NotificationChannel, error) {
+	var channels []model.NotificationChannel
+	err := database.DB.Order("id ASC").Find(&channels).Error
+	return channels, err
+}
+
+// FindEnabledByIDs 获取指定ID中已启用的渠道
+func (r *NotificationChannelRepository) FindEnabledByIDs(ids []uint) ([]model.NotificationChannel, error) {
+	var channels []model.NotificationChannel
+	if len(ids) == 0 {
+		return channels, nil
+	}
+	err := database.DB.Where("id IN ? AND enabled = ?", ids, true).Order("id ASC").Find(&channels).Error
+	return channels, err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProxyAccessRepository 代理访问策略及拦截记录数据访问
+type ProxyAccessRepository struct{}
+
+func NewProxyAccessRepository() *ProxyAccessRepository {
+	return &ProxyAccessRepository{}
+}
+
+func (r *ProxyAccessRepository) FindPolicyByProxyID(proxyID uint) (*model.ProxyAccessPolicy, error) {
+	var policy model.ProxyAccessPolicy
+	err := database.DB.Where("proxy_id = ?", proxyID).First(&policy).Error
+	return &policy, err
+}
+
+func (r *ProxyAccessRepository) FindEnabledPolicies() ([]model.ProxyAccessPolicy, error) {
+	var policies []model.ProxyAccessPolicy
+	err := database.DB.Where("enabled = ?", true).Find(&policies).Error
+	return policies, err
+}
+
+func (r *ProxyAccessRepository) SavePolicy(policy *model.ProxyAccessPolicy) error {
+	return database.DB.Save(policy).Error
+}
+
+func (r *ProxyAccessRepository) DeletePolicyByProxyID(proxyID uint) error {
+	return database.DB.Where("proxy_id = ?", proxyID).Delete(&model.ProxyAccessPolicy{}).Error
+}
+
+// UpsertBlocks 批量写入拦截记录，已存在的记录累加拦截次数
+func (r *ProxyAccessRepository) UpsertBlocks(blocks []model.ProxyAccessBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "server_id"}, {Name: "proxy_name"}, {Name: "ip"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"block_count":  gorm.Expr("proxy_access_blocks.block_count + excluded.block_count"),
+			"last_blocked": gorm.Expr("excluded.last_blocked"),
+			"reason":       gorm.Expr("excluded.reason"),
+			"location":     gorm.Expr("excluded.location"),
+		}),
+	}).CreateInBatches(&blocks, 200).Error
+}
+
+// FindRecentBlocks 查询代理最近的拦截记录，按最后拦截时间倒序
+func (r *ProxyAccessRepository) FindRecentBlocks(serverID uint, proxyNames []string, limit int) ([]model.ProxyAccessBlock, error) {
+	var blocks []model.ProxyAccessBlock
+	err := database.DB.Where("server_id = ? AND proxy_name IN ?", serverID, proxyNames).
+		Order("last_blocked DESC").Limit(limit).Find(&blocks).Error
+	return blocks, err
+}
+
+func (r *ProxyAccessRepository) DeleteBlocksBefore(before time.Time) (int64, error) {
+	result := database.DB.Where("last_blocked < ?", before).Delete(&model.ProxyAccessBlock{})
+	return result.RowsAffected, result.Error
+}
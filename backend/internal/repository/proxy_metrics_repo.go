@@ -76,61 +76,54 @@ type TrafficTrendPoint struct {
 	Outbound int64  `json:"outbound"`
 }
 
-// GetHourlyTrafficTrend 获取按小时聚合的流量趋势
+// GetHourlyTrafficTrend 获取按小时聚合的流量趋势（各采样周期增量之和）
 func (r *ProxyMetricsRepository) GetHourlyTrafficTrend(hours int) ([]TrafficTrendPoint, error) {
 	startTime := time.Now().Add(-time.Duration(hours) * time.Hour)
 
 	type Result struct {
-		HourKey  string
-		Hour     string
+		Bucket   int64
 		TotalIn  int64
 		TotalOut int64
 	}
 
+	// 按本地时区的整点分桶
+	_, offset := time.Now().Zone()
 	var results []Result
+	bucket := timeBucketExpr(database.DB, "record_time", 3600, offset)
 	err := database.DB.Model(&model.ProxyMetricsHistory{}).
-		Select("strftime('%Y-%m-%d %H', record_time) as hour_key, strftime('%H:00', record_time) as hour, SUM(traffic_in) as total_in, SUM(traffic_out) as total_out").
+		Select(bucket+" as bucket, SUM(delta_in) as total_in, SUM(delta_out) as total_out").
 		Where("record_time >= ?", startTime).
-		Group("hour_key").
-		Order("hour_key ASC").
+		Group("bucket").
+		Order("bucket ASC").
 		Scan(&results).Error
 
 	if err != nil {
 		return nil, err
 	}
 
-	// 创建数据映射
-	dataMap := make(map[string]TrafficTrendPoint)
+	// 创建数据映射，键为小时桶序号
+	dataMap := make(map[int64]Result)
 	for _, r := range results {
-		dataMap[r.HourKey] = TrafficTrendPoint{
-			Time:     r.Hour,
-			Inbound:  r.TotalIn,
-			Outbound: r.TotalOut,
-		}
+		dataMap[r.Bucket] = r
 	}
 
-	// 生成完整的24小时时间轴
+	// 生成完整的时间轴
 	trend := make([]TrafficTrendPoint, 0, hours)
 	now := time.Now()
 	for i := hours - 1; i >= 0; i-- {
 		t := now.Add(-time.Duration(i) * time.Hour)
-		hourKey := t.Format("2006-01-02 15")
-		hourLabel := t.Format("15:00")
-		if data, ok := dataMap[hourKey]; ok {
-			trend = append(trend, data)
-		} else {
-			trend = append(trend, TrafficTrendPoint{
-				Time:     hourLabel,
-				Inbound:  0,
-				Outbound: 0,
-			})
+		point := TrafficTrendPoint{Time: t.Format("15:00")}
+		if data, ok := dataMap[timeBucketKey(t, 3600, offset)]; ok {
+			point.Inbound = data.TotalIn
+			point.Outbound = data.TotalOut
 		}
+		trend = append(trend, point)
 	}
 
 	return trend, nil
 }
 
-// GetTrafficSummaryByProxyNames 获取指定代理名称列表在时间范围内的流量汇总
+// GetTrafficSummaryByProxyNames 获取指定代理名称列表在时间范围内的流量汇总（各采样周期增量之和）
 func (r *ProxyMetricsRepository) GetTrafficSummaryByProxyNames(proxyNames []string, days int) (map[string]map[string]int64, error) {
 	if len(proxyNames) == 0 {
 		return make(map[string]map[string]int64), nil
@@ -146,7 +139,7 @@ func (r *ProxyMetricsRepository) GetTrafficSummaryByProxyNames(proxyNames []stri
 
 	var results []Result
 	err := database.DB.Model(&model.ProxyMetricsHistory{}).
-		Select("proxy_name, COALESCE(SUM(traffic_in), 0) as total_in, COALESCE(SUM(traffic_out), 0) as total_out").
+		Select("proxy_name, COALESCE(SUM(delta_in), 0) as total_in, COALESCE(SUM(delta_out), 0) as total_out").
 		Where("proxy_name IN ? AND record_time >= ?", proxyNames, startTime).
 		Group("proxy_name").
 		Scan(&results).Error
@@ -165,3 +158,45 @@ func (r *ProxyMetricsRepository) GetTrafficSummaryByProxyNames(proxyNames []stri
 
 	return summary, nil
 }
+
+// FindInRange 获取时间范围内的所有隧道指标（左闭右开），用于降采样汇总
+func (r *ProxyMetricsRepository) FindInRange(start, end time.Time) ([]model.ProxyMetricsHistory, error) {
+	var records []model.ProxyMetricsHistory
+	err := database.DB.Where("record_time >= ? AND record_time < ?", start, end).
+		Order("record_time ASC").Find(&records).Error
+	return records, err
+}
+
+// FirstRecordTimeSince 获取指定时间及之后最早一条记录的时间，没有记录时返回 nil
+func (r *ProxyMetricsRepository) FirstRecordTimeSince(since time.Time) (*time.Time, error) {
+	var records []model.ProxyMetricsHistory
+	err := database.DB.Where("record_time >= ?", since).Order("record_time ASC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0].RecordTime, nil
+}
+
+// SumTrafficDelta 统计指定服务器上一组隧道在时间范围内的入站+出站流量（左闭右开）
+func (r *ProxyMetricsRepository) SumTrafficDelta(serverID uint, proxyNames []string, start, end time.Time) (int64, error) {
+	if len(proxyNames) == 0 {
+		return 0, nil
+	}
+	var total int64
+	err := database.DB.Model(&model.ProxyMetricsHistory{}).
+		Select("COALESCE(SUM(delta_in + delta_out), 0)").
+		Where("server_id = ? AND proxy_name IN ? AND record_time >= ? AND record_time < ?", serverID, proxyNames, start, end).
+		Scan(&total).Error
+	return total, err
+}
+
+// SumDeltaByProxy 按隧道汇总时间范围内的流量增量（左闭右开）
+func (r *ProxyMetricsRepository) SumDeltaByProxy(start, end time.Time) ([]model.ProxyTrafficUsage, error) {
+	var usages []model.ProxyTrafficUsage
+	err := database.DB.Model(&model.ProxyMetricsHistory{}).
+		Select("server_id, proxy_name, COALESCE(SUM(delta_in), 0) as delta_in, COALESCE(SUM(delta_out), 0) as delta_out").
+		Where("record_time >= ? AND record_time < ?", start, end).
+		Group("server_id, proxy_name").
+		Scan(&usages).Error
+	return usages, err
+}
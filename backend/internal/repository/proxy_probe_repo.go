@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+)
+
+// ProxyProbeRepository 代理拨测配置及结果数据访问
+type ProxyProbeRepository struct{}
+
+func NewProxyProbeRepository() *ProxyProbeRepository {
+	return &ProxyProbeRepository{}
+}
+
+func (r *ProxyProbeRepository) Create(probe *model.ProxyProbe) error {
+	return database.DB.Create(probe).Error
+}
+
+func (r *ProxyProbeRepository) Update(probe *model.ProxyProbe) error {
+	return database.DB.Save(probe).Error
+}
+
+// Delete 删除拨测配置及其历史结果
+func (r *ProxyProbeRepository) Delete(id uint) error {
+	if err := database.DB.Where("probe_id = ?", id).Delete(&model.ProxyProbeResult{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Delete(&model.ProxyProbe{}, id).Error
+}
+
+func (r *ProxyProbeRepository) FindByID(id uint) (*model.ProxyProbe, error) {
+	var probe model.ProxyProbe
+	err := database.DB.First(&probe, id).Error
+	return &probe, err
+}
+
+func (r *ProxyProbeRepository) FindByProxyID(proxyID uint) ([]model.ProxyProbe, error) {
+	var probes []model.ProxyProbe
+	err := database.DB.Where("proxy_id = ?", proxyID).Order("id ASC").Find(&probes).Error
+	return probes, err
+}
+
+func (r *ProxyProbeRepository) FindEnabled() ([]model.ProxyProbe, error) {
+	var probes []model.ProxyProbe
+	err := database.DB.Where("enabled = ?", true).Find(&probes).Error
+	return probes, err
+}
+
+// SaveResult 写入单次拨测结果并更新拨测配置上的最近状态
+func (r *ProxyProbeRepository) SaveResult(probe *model.ProxyProbe, result *model.ProxyProbeResult) error {
+	if err := database.DB.Create(result).Error; err != nil {
+		return err
+	}
+	return database.DB.Model(&model.ProxyProbe{}).Where("id = ?", probe.ID).Updates(map[string]interface{}{
+		"last_checked_at": probe.LastCheckedAt,
+		"last_success":    probe.LastSuccess,
+		"last_latency_ms": probe.LastLatencyMs,
+		"last_error":      probe.LastError,
+		"cert_expires_at": probe.CertExpiresAt,
+	}).Error
+}
+
+// FindResults 获取指定时间之后的拨测结果，按时间升序
+func (r *ProxyProbeRepository) FindResults(probeID uint, since time.Time, limit int) ([]model.ProxyProbeResult, error) {
+	var results []model.ProxyProbeResult
+	err := database.DB.Where("probe_id = ? AND checked_at >= ?", probeID, since).
+		Order("checked_at DESC").Limit(limit).Find(&results).Error
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+	return results, err
+}
+
+// GetUptime 统计指定时间之后的拨测次数、成功次数及成功拨测平均耗时
+func (r *ProxyProbeRepository) GetUptime(probeID uint, since time.Time) (total, success int64, avgLatency float64, err error) {
+	var row struct {
+		Total      int64
+		Success    int64
+		AvgLatency float64
+	}
+	err = database.DB.Model(&model.ProxyProbeResult{}).
+		Select("COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN success THEN 1 ELSE 0 END), 0) AS success, "+
+			"COALESCE(AVG(CASE WHEN success THEN latency_ms END), 0) AS avg_latency").
+		Where("probe_id = ? AND checked_at >= ?", probeID, since).
+		Scan(&row).Error
+	return row.Total, row.Success, row.AvgLatency, err
+}
+
+// DeleteResultsBefore 删除指定时间之前的拨测结果
+func (r *ProxyProbeRepository) DeleteResultsBefore(before time.Time) (int64, error) {
+	result := database.DB.Where("checked_at < ?", before).Delete(&model.ProxyProbeResult{})
+	return result.RowsAffected, result.Error
+}
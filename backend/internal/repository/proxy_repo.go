@@ -32,6 +32,13 @@ func (r *ProxyRepository) FindByID(id uint) (*model.Proxy, error) {
 	return &proxy, err
 }
 
+// FindByClientIDAndName 按客户端和代理名称查找代理
+func (r *ProxyRepository) FindByClientIDAndName(clientID uint, name string) (*model.Proxy, error) {
+	var proxy model.Proxy
+	err := database.DB.Where("client_id = ? AND name = ?", clientID, name).First(&proxy).Error
+	return &proxy, err
+}
+
 func (r *ProxyRepository) Create(proxy *model.Proxy) error {
 	return database.DB.Create(proxy).Error
 }
@@ -101,6 +108,12 @@ func (r *ProxyRepository) ToggleEnabled(id uint) (*model.Proxy, error) {
 	return &proxy, nil
 }
 
+// UpdateBandwidthLimit 仅更新代理的带宽限制，避免覆盖同时写入的流量统计字段
+func (r *ProxyRepository) UpdateBandwidthLimit(id uint, limit, mode string) error {
+	return database.DB.Model(&model.Proxy{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"bandwidth_limit": limit, "bandwidth_limit_mode": mode}).Error
+}
+
 // FindEnabledByClientID 获取指定客户端的所有启用的代理
 func (r *ProxyRepository) FindEnabledByClientID(clientID uint) ([]model.Proxy, error) {
 	var proxies []model.Proxy
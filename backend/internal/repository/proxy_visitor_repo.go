@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProxyVisitorRepository 代理访客数据访问
+type ProxyVisitorRepository struct{}
+
+func NewProxyVisitorRepository() *ProxyVisitorRepository {
+	return &ProxyVisitorRepository{}
+}
+
+// Upsert 批量写入访客记录，已存在的记录累加连接次数并更新最后访问时间
+func (r *ProxyVisitorRepository) Upsert(visitors []model.ProxyVisitor) error {
+	if len(visitors) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "server_id"}, {Name: "proxy_name"}, {Name: "ip"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"conn_count": gorm.Expr("proxy_visitors.conn_count + excluded.conn_count"),
+			"last_seen":  gorm.Expr("excluded.last_seen"),
+			"location":   gorm.Expr("excluded.location"),
+		}),
+	}).CreateInBatches(&visitors, 200).Error
+}
+
+// FindRecent 查询代理最近的访客，按最后访问时间倒序
+func (r *ProxyVisitorRepository) FindRecent(serverID uint, proxyNames []string, since time.Time, limit int) ([]model.ProxyVisitor, error) {
+	var visitors []model.ProxyVisitor
+	err := database.DB.Where("server_id = ? AND proxy_name IN ? AND last_seen >= ?", serverID, proxyNames, since).
+		Order("last_seen DESC").Limit(limit).Find(&visitors).Error
+	return visitors, err
+}
+
+// DeleteBefore 删除最后访问时间早于指定时间的记录
+func (r *ProxyVisitorRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := database.DB.Where("last_seen < ?", before).Delete(&model.ProxyVisitor{})
+	return result.RowsAffected, result.Error
+}
+
+// TrimPerProxy 每个代理只保留最近访问的 keep 条记录
+func (r *ProxyVisitorRepository) TrimPerProxy(keep int) (int64, error) {
+	type proxyKey struct {
+		ServerID  uint
+		ProxyName string
+	}
+	var keys []proxyKey
+	if err := database.DB.Model(&model.ProxyVisitor{}).Select("server_id, proxy_name").
+		Group("server_id, proxy_name").Having("COUNT(*) > ?", keep).Scan(&keys).Error; err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, key := range keys {
+		var ids []uint
+		if err := database.DB.Model(&model.ProxyVisitor{}).
+			Where("server_id = ? AND proxy_name = ?", key.ServerID, key.ProxyName).
+			Order("last_seen DESC").Offset(keep).Limit(-1).Pluck("id", &ids).Error; err != nil {
+			return deleted, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		result := database.DB.Delete(&model.ProxyVisitor{}, ids)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
@@ -39,3 +39,21 @@ func (r *ServerMetricsRepository) DeleteOlderThan(before time.Time) (int64, erro
 	result := database.DB.Where("record_time < ?", before).Delete(&model.ServerMetricsHistory{})
 	return result.RowsAffected, result.Error
 }
+
+// FindInRange 获取时间范围内的所有服务器指标（左闭右开），用于降采样汇总
+func (r *ServerMetricsRepository) FindInRange(start, end time.Time) ([]model.ServerMetricsHistory, error) {
+	var records []model.ServerMetricsHistory
+	err := database.DB.Where("record_time >= ? AND record_time < ?", start, end).
+		Order("record_time ASC").Find(&records).Error
+	return records, err
+}
+
+// FirstRecordTimeSince 获取指定时间及之后最早一条记录的时间，没有记录时返回 nil
+func (r *ServerMetricsRepository) FirstRecordTimeSince(since time.Time) (*time.Time, error) {
+	var records []model.ServerMetricsHistory
+	err := database.DB.Where("record_time >= ?", since).Order("record_time ASC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0].RecordTime, nil
+}
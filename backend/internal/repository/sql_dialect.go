@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timeBucketExpr 返回按固定秒数对时间列分桶的 SQL 表达式
+// 结果为 (Unix 时间戳 + 时区偏移) 整除桶宽后的整数，与 timeBucketKey 对应，不依赖数据库会话时区
+func timeBucketExpr(db *gorm.DB, column string, seconds int, offset int) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return fmt.Sprintf("CAST(FLOOR((EXTRACT(EPOCH FROM %s) + %d) / %d) AS BIGINT)", column, offset, seconds)
+	default:
+		return fmt.Sprintf("(CAST(strftime('%%s', %s) AS INTEGER) + %d) / %d", column, offset, seconds)
+	}
+}
+
+// timeBucketKey 计算时间所在的桶序号，与 timeBucketExpr 的结果一致
+func timeBucketKey(t time.Time, seconds int, offset int) int64 {
+	return (t.Unix() + int64(offset)) / int64(seconds)
+}
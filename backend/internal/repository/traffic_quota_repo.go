@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+)
+
+// TrafficQuotaRepository 流量配额数据访问
+type TrafficQuotaRepository struct{}
+
+func NewTrafficQuotaRepository() *TrafficQuotaRepository {
+	return &TrafficQuotaRepository{}
+}
+
+func (r *TrafficQuotaRepository) Create(quota *model.TrafficQuota) error {
+	return database.DB.Create(quota).Error
+}
+
+func (r *TrafficQuotaRepository) Update(quota *model.TrafficQuota) error {
+	return database.DB.Save(quota).Error
+}
+
+func (r *TrafficQuotaRepository) Delete(id uint) error {
+	return database.DB.Delete(&model.TrafficQuota{}, id).Error
+}
+
+func (r *TrafficQuotaRepository) FindByID(id uint) (*model.TrafficQuota, error) {
+	var quota model.TrafficQuota
+	err := database.DB.First(&quota, id).Error
+	return &quota, err
+}
+
+func (r *TrafficQuotaRepository) FindAll() ([]model.TrafficQuota, error) {
+	var quotas []model.TrafficQuota
+	err := database.DB.Order("id desc").Find(&quotas).Error
+	return quotas, err
+}
+
+// FindEnabledByTarget 获取目标上已启用的配额
+func (r *TrafficQuotaRepository) FindEnabledByTarget(targetType string, targetID uint) (*model.TrafficQuota, error) {
+	var quota model.TrafficQuota
+	err := database.DB.Where("target_type = ? AND target_id = ? AND enabled = ?", targetType, targetID, true).
+		Order("id ASC").First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// CreateSuspension 记录因配额被禁用或限速的代理
+func (r *TrafficQuotaRepository) CreateSuspension(suspension *model.TrafficQuotaSuspension) error {
+	return database.DB.Create(suspension).Error
+}
+
+// FindSuspensionsByQuota 获取指定配额处置过的代理记录
+func (r *TrafficQuotaRepository) FindSuspensionsByQuota(quotaID uint) ([]model.TrafficQuotaSuspension, error) {
+	var suspensions []model.TrafficQuotaSuspension
+	err := database.DB.Where("quota_id = ?", quotaID).Find(&suspensions).Error
+	return suspensions, err
+}
+
+// FindSuspensionsByProxy 获取指定代理的全部处置记录（可能来自多个配额）
+func (r *TrafficQuotaRepository) FindSuspensionsByProxy(proxyID uint) ([]model.TrafficQuotaSuspension, error) {
+	var suspensions []model.TrafficQuotaSuspension
+	err := database.DB.Where("proxy_id = ?", proxyID).Order("id ASC").Find(&suspensions).Error
+	return suspensions, err
+}
+
+func (r *TrafficQuotaRepository) DeleteSuspension(id uint) error {
+	return database.DB.Delete(&model.TrafficQuotaSuspension{}, id).Error
+}
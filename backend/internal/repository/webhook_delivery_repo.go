@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"time"
+)
+
+// WebhookDeliveryRepository Webhook 投递记录及投递尝试数据访问
+type WebhookDeliveryRepository struct{}
+
+func NewWebhookDeliveryRepository() *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{}
+}
+
+func (r *WebhookDeliveryRepository) Create(delivery *model.WebhookDelivery) error {
+	return database.DB.Create(delivery).Error
+}
+
+func (r *WebhookDeliveryRepository) Update(delivery *model.WebhookDelivery) error {
+	return database.DB.Save(delivery).Error
+}
+
+func (r *WebhookDeliveryRepository) FindByID(id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := database.DB.First(&delivery, id).Error
+	return &delivery, err
+}
+
+// FindAll 按创建时间倒序获取投递记录，status 和 alertLogID 为空时不过滤
+func (r *WebhookDeliveryRepository) FindAll(status string, alertLogID uint, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	query := database.DB.Model(&model.WebhookDelivery{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if alertLogID > 0 {
+		query = query.Where("alert_log_id = ?", alertLogID)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindDue 获取到期待投递的记录
+func (r *WebhookDeliveryRepository) FindDue(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := database.DB.Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Claim 将到期记录的下次投递时间推迟到 leaseUntil，返回是否抢占成功
+// 用于避免定时重试与即时投递同时处理同一条记录，进程中断后租约到期会被重新投递
+func (r *WebhookDeliveryRepository) Claim(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := database.DB.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, model.WebhookDeliveryPending, now).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *WebhookDeliveryRepository) CreateAttempt(attempt *model.WebhookDeliveryAttempt) error {
+	return database.DB.Create(attempt).Error
+}
+
+// FindAttempts 获取投递记录的全部尝试，按时间升序
+func (r *WebhookDeliveryRepository) FindAttempts(deliveryID uint) ([]model.WebhookDeliveryAttempt, error) {
+	var attempts []model.WebhookDeliveryAttempt
+	err := database.DB.Where("delivery_id = ?", deliveryID).Order("id ASC").Find(&attempts).Error
+	return attempts, err
+}
+
+// DeleteFinishedBefore 删除指定时间之前创建且已结束的投递记录及其尝试记录
+func (r *WebhookDeliveryRepository) DeleteFinishedBefore(before time.Time) (int64, error) {
+	finished := database.DB.Model(&model.WebhookDelivery{}).Select("id").
+		Where("created_at < ? AND status <> ?", before, model.WebhookDeliveryPending)
+	if err := database.DB.Where("delivery_id IN (?)", finished).Delete(&model.WebhookDeliveryAttempt{}).Error; err != nil {
+		return 0, err
+	}
+	result := database.DB.Where("created_at < ? AND status <> ?", before, model.WebhookDeliveryPending).Delete(&model.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -20,6 +20,9 @@ import (
 func SetupRoutes(r *gin.Engine, c *container.Container) {
 	h := c.Handlers
 
+	// Prometheus 指标导出
+	r.GET("/metrics", middleware.MetricsAuthMiddleware(), h.Metrics.Export)
+
 	api := r.Group("/api")
 	{
 		api.GET("/health", func(ctx *gin.Context) {
@@ -47,17 +50,23 @@ func SetupRoutes(r *gin.Engine, c *container.Container) {
 			clients.POST("/parse-config", h.Client.ParseConfig)
 			clients.POST("/:id/update", h.Client.UpdateClientSoftware)
 			clients.GET("/:id/versions", h.Client.GetClientVersions)
+			clients.GET("/:id/metrics-history", h.Client.GetMetricsHistory)
 			clients.POST("/batch-update", h.Client.BatchUpdateClients)
 			clients.GET("/online", h.Client.GetOnlineClients)
 			clients.POST("/:id/logs/start", h.ClientLog.StartLogStream)
 			clients.POST("/:id/logs/stop", h.ClientLog.StopLogStream)
 			clients.POST("/:id/frpc/control", h.ClientLog.ControlFrpc)
+			clients.POST("/:id/daemon-token/rotate", h.ClientCred.RotateDaemonToken)
+			clients.POST("/:id/daemon-token/revoke", h.ClientCred.RevokeDaemonToken)
 		}
 
 		api.POST("/clients/register", h.Client.RegisterClient)
 		api.POST("/clients/heartbeat", middleware.RateLimitMiddleware(60), h.Client.Heartbeat)
 		api.GET("/clients/daemon/ws", h.ClientDaemonWS.HandleConnection)
 
+		// frps HTTP 插件回调，通过路径中的令牌鉴权
+		api.POST("/frps-plugin/:server_id/:token", h.FrpsPlugin.Handle)
+
 		r.GET("/install/:token", h.Client.GetInstallScript)
 		r.GET("/download/daemon/:os/:arch", h.DaemonDownload.Download)
 
@@ -68,6 +77,22 @@ func SetupRoutes(r *gin.Engine, c *container.Container) {
 			proxies.PUT("/:id", h.Proxy.UpdateProxy)
 			proxies.DELETE("/:id", h.Proxy.DeleteProxy)
 			proxies.PUT("/:id/toggle", h.Proxy.ToggleProxy)
+			proxies.GET("/:id/visitors", h.ProxyVisitor.GetRecentVisitors)
+			proxies.GET("/:id/access-policy", h.ProxyVisitor.GetAccessPolicy)
+			proxies.PUT("/:id/access-policy", h.ProxyVisitor.SaveAccessPolicy)
+			proxies.DELETE("/:id/access-policy", h.ProxyVisitor.DeleteAccessPolicy)
+			proxies.GET("/:id/access-blocks", h.ProxyVisitor.GetAccessBlocks)
+			proxies.GET("/:id/probes", h.ProxyProbe.GetProbes)
+			proxies.POST("/:id/probes", h.ProxyProbe.CreateProbe)
+		}
+
+		proxyProbes := api.Group("/proxy-probes", middleware.AuthMiddleware())
+		{
+			proxyProbes.PUT("/:id", h.ProxyProbe.UpdateProbe)
+			proxyProbes.DELETE("/:id", h.ProxyProbe.DeleteProbe)
+			proxyProbes.POST("/:id/run", h.ProxyProbe.RunProbe)
+			proxyProbes.GET("/:id/results", h.ProxyProbe.GetProbeResults)
+			proxyProbes.GET("/:id/uptime", h.ProxyProbe.GetProbeUptime)
 		}
 
 		traffic := api.Group("/traffic", middleware.AuthMiddleware())
@@ -78,6 +103,17 @@ func SetupRoutes(r *gin.Engine, c *container.Container) {
 			traffic.GET("/rates/:server_id", h.Traffic.GetProxyRates)
 			traffic.GET("/rates/:server_id/:proxy_name", h.Traffic.GetProxyRateHistory)
 			traffic.GET("/proxies/summary", h.Traffic.GetProxiesTrafficSummary)
+			traffic.GET("/report", h.Traffic.GetUsageReport)
+			traffic.GET("/report/export", h.Traffic.ExportUsageReport)
+		}
+
+		trafficQuotas := api.Group("/traffic-quotas", middleware.AuthMiddleware())
+		{
+			trafficQuotas.GET("", h.TrafficQuota.GetAllQuotas)
+			trafficQuotas.POST("", h.TrafficQuota.CreateQuota)
+			trafficQuotas.PUT("/:id", h.TrafficQuota.UpdateQuota)
+			trafficQuotas.DELETE("/:id", h.TrafficQuota.DeleteQuota)
+			trafficQuotas.POST("/:id/reset", h.TrafficQuota.ResetQuota)
 		}
 
 		logs := api.Group("/logs", middleware.AuthMiddleware())
@@ -86,6 +122,16 @@ func SetupRoutes(r *gin.Engine, c *container.Container) {
 			logs.POST("", h.Log.CreateLog)
 		}
 
+		logSinks := api.Group("/log-sinks", middleware.AuthMiddleware())
+		{
+			logSinks.GET("", h.LogSink.GetAllSinks)
+			logSinks.POST("", h.LogSink.CreateSink)
+			logSinks.POST("/test", h.LogSink.TestSinkConfig)
+			logSinks.PUT("/:id", h.LogSink.UpdateSink)
+			logSinks.DELETE("/:id", h.LogSink.DeleteSink)
+			logSinks.POST("/:id/test", h.LogSink.TestSink)
+		}
+
 		settings := api.Group("/settings", middleware.AuthMiddleware())
 		{
 			settings.GET("", h.Setting.GetSettings)
@@ -113,6 +159,26 @@ func SetupRoutes(r *gin.Engine, c *container.Container) {
 			alerts.PUT("/rules", h.Alert.UpdateRule)
 			alerts.DELETE("/rules/:id", h.Alert.DeleteRule)
 			alerts.GET("/logs", h.Alert.GetAlertLogs)
+			alerts.POST("/logs/:id/ack", h.Alert.AcknowledgeAlert)
+			alerts.POST("/logs/:id/unack", h.Alert.UnacknowledgeAlert)
+			alerts.POST("/logs/:id/resolve", h.Alert.ResolveAlert)
+			alerts.GET("/logs/:id/suppressed", h.Alert.GetSuppressedAlerts)
+			alerts.GET("/summary", h.AlertSummary.GetSummary)
+			alerts.POST("/summary/send", h.AlertSummary.SendSummary)
+			alerts.GET("/silences", h.AlertSilence.GetSilences)
+			alerts.POST("/silences", h.AlertSilence.CreateSilence)
+			alerts.PUT("/silences/:id", h.AlertSilence.UpdateSilence)
+			alerts.DELETE("/silences/:id", h.AlertSilence.DeleteSilence)
+			alerts.POST("/silences/:id/expire", h.AlertSilence.ExpireSilence)
+			alerts.GET("/escalation-policies", h.Escalation.GetPolicies)
+			alerts.POST("/escalation-policies", h.Escalation.CreatePolicy)
+			alerts.PUT("/escalation-policies/:id", h.Escalation.UpdatePolicy)
+			alerts.DELETE("/escalation-policies/:id", h.Escalation.DeletePolicy)
+			alerts.GET("/oncall-schedules", h.Escalation.GetSchedules)
+			alerts.POST("/oncall-schedules", h.Escalation.CreateSchedule)
+			alerts.PUT("/oncall-schedules/:id", h.Escalation.UpdateSchedule)
+			alerts.DELETE("/oncall-schedules/:id", h.Escalation.DeleteSchedule)
+			alerts.GET("/oncall-schedules/:id/shifts", h.Escalation.GetShifts)
 			alerts.GET("/recipients", h.AlertRecipient.GetAllRecipients)
 			alerts.POST("/recipients", h.AlertRecipient.CreateRecipient)
 			alerts.PUT("/recipients/:id", h.AlertRecipient.UpdateRecipient)
@@ -122,6 +188,21 @@ func SetupRoutes(r *gin.Engine, c *container.Container) {
 			alerts.PUT("/groups/:id", h.AlertRecipient.UpdateGroup)
 			alerts.DELETE("/groups/:id", h.AlertRecipient.DeleteGroup)
 			alerts.PUT("/groups/:id/recipients", h.AlertRecipient.SetGroupRecipients)
+			alerts.GET("/channels", h.Notification.GetAllChannels)
+			alerts.POST("/channels", h.Notification.CreateChannel)
+			alerts.POST("/channels/test", h.Notification.TestChannelConfig)
+			alerts.PUT("/channels/:id", h.Notification.UpdateChannel)
+			alerts.DELETE("/channels/:id", h.Notification.DeleteChannel)
+			alerts.POST("/channels/:id/test", h.Notification.TestChannel)
+			alerts.GET("/message-templates", h.MessageTpl.GetTemplates)
+			alerts.GET("/message-templates/defaults", h.MessageTpl.GetDefaults)
+			alerts.POST("/message-templates", h.MessageTpl.CreateTemplate)
+			alerts.POST("/message-templates/preview", h.MessageTpl.PreviewTemplate)
+			alerts.PUT("/message-templates/:id", h.MessageTpl.UpdateTemplate)
+			alerts.DELETE("/message-templates/:id", h.MessageTpl.DeleteTemplate)
+			alerts.GET("/webhook-deliveries", h.Webhook.GetDeliveries)
+			alerts.GET("/webhook-deliveries/:id/attempts", h.Webhook.GetAttempts)
+			alerts.POST("/webhook-deliveries/:id/redeliver", h.Webhook.Redeliver)
 		}
 
 		frpServers := api.Group("/frp-servers", middleware.AuthMiddleware())
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"time"
+)
+
+// 告警目标的依赖关系为 frps → frpc → 代理 → 拨测。上级 frps/frpc 处于离线告警中时，
+// 下级的离线、拨测和指标告警只记录并关联到上级告警（SuppressedBy），不发送通知也不升级；
+// 上级恢复后下级仍未恢复的，等待一个确认周期后补发告警，恢复的则不再发送恢复通知，
+// 使同一次故障只收到上级的告警和恢复通知。上级的告警通知在下级告警之前发送，不包含被抑制的下级数量，
+// 被归并的下级告警通过 GetSuppressedAlerts 查看。
+
+// metricDependencyGrace 上级告警结束后指标告警解除抑制前的等待时间，等待下级重新连接
+const metricDependencyGrace = 60 * time.Second
+
+// alertTargetRef 告警目标
+type alertTargetRef struct {
+	targetType model.AlertTargetType
+	id         uint
+}
+
+// key 与离线状态追踪使用的键一致，如 "frps:1"
+func (r alertTargetRef) key() string {
+	return fmt.Sprintf("%s:%d", r.targetType, r.id)
+}
+
+// alertAncestors 获取目标所依赖的上级 frps、frpc，按 frps 在前的顺序返回
+func (s *AlertService) alertAncestors(targetType model.AlertTargetType, targetID uint) []alertTargetRef {
+	var clientID uint
+	switch targetType {
+	case model.AlertTargetFrpc:
+		clientID = targetID
+	case model.AlertTargetProxy:
+		if s.proxyRepo == nil {
+			return nil
+		}
+		proxy, err := s.proxyRepo.FindByID(targetID)
+		if err != nil {
+			return nil
+		}
+		clientID = proxy.ClientID
+	case model.AlertTargetProbe:
+		if s.probeRepo == nil || s.proxyRepo == nil {
+			return nil
+		}
+		probe, err := s.probeRepo.FindByID(targetID)
+		if err != nil {
+			return nil
+		}
+		proxy, err := s.proxyRepo.FindByID(probe.ProxyID)
+		if err != nil {
+			return nil
+		}
+		clientID = proxy.ClientID
+	default:
+		return nil
+	}
+	if clientID == 0 {
+		return nil
+	}
+
+	var ancestors []alertTargetRef
+	if serverID := s.clientServerID(clientID); serverID != 0 {
+		ancestors = append(ancestors, alertTargetRef{model.AlertTargetFrps, serverID})
+	}
+	if targetType != model.AlertTargetFrpc {
+		ancestors = append(ancestors, alertTargetRef{model.AlertTargetFrpc, clientID})
+	}
+	return ancestors
+}
+
+// suppressingAlert 获取抑制目标告警的上级告警：上级离线告警未结束，或结束不足 grace 时间。
+// 多级上级同时告警时返回最上级的告警，使整条依赖链的告警都归并到根告警
+func (s *AlertService) suppressingAlert(ancestors []alertTargetRef, grace time.Duration, now time.Time) *model.AlertLog {
+	if s.alertRepo == nil {
+		return nil
+	}
+	for _, ref := range ancestors {
+		if alert, err := s.alertRepo.GetDependencyAlert(ref.targetType, ref.id, now.Add(-grace)); err == nil {
+			return alert
+		}
+	}
+	return nil
+}
+
+// ancestorPendingUnlocked 上级已检测到离线但仍在延迟确认期内（不加锁版本），此时下级等待上级的确认结果
+func (s *AlertService) ancestorPendingUnlocked(ancestors []alertTargetRef) bool {
+	for _, ref := range ancestors {
+		if _, pending := s.pendingOffline[ref.key()]; pending && !s.alertingState[ref.key()] {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseSuppressed 上级告警已结束且下级仍在告警时解除抑制，返回是否需要补发通知
+func (s *AlertService) releaseSuppressed(alert *model.AlertLog) bool {
+	released, err := s.alertRepo.ReleaseSuppressedAlert(alert.ID)
+	if err != nil {
+		logger.Errorf("[告警] 解除告警 %d 的抑制失败: %v", alert.ID, err)
+		return false
+	}
+	if !released {
+		return false
+	}
+	logger.Infof("[告警] 上级告警 #%d 已结束，%s 仍未恢复，补发告警通知", alert.SuppressedBy, alert.Message)
+	publishAlertTransition(alert, "released", time.Now())
+	alert.SuppressedBy = 0
+	return !alert.Silenced
+}
+
+// GetSuppressedAlerts 获取被指定告警抑制的下级告警
+func (s *AlertService) GetSuppressedAlerts(parentID uint) ([]model.AlertLog, error) {
+	if _, err := s.alertRepo.FindAlertByID(parentID); err != nil {
+		return nil, fmt.Errorf("告警不存在")
+	}
+	return s.alertRepo.GetSuppressedAlerts(parentID)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAlertService_DependencySuppression 测试 frps 离线时其下 frpc 的离线告警被抑制，frps 恢复后 frpc 仍离线时补发告警
+func TestAlertService_DependencySuppression(t *testing.T) {
+	setup := func(t *testing.T) (*AlertService, *model.FrpServer, *model.Client) {
+		setupLifecycleTestDB(t)
+		require.NoError(t, database.DB.AutoMigrate(&model.FrpServer{}, &model.Client{}, &model.Proxy{}))
+		server := &model.FrpServer{Name: "edge", Status: model.StatusError}
+		require.NoError(t, database.DB.Create(server).Error)
+		client := &model.Client{Name: "home", FrpServerID: &server.ID, OnlineStatus: "offline"}
+		require.NoError(t, database.DB.Create(client).Error)
+		for _, rule := range []*model.AlertRule{
+			{TargetType: model.AlertTargetFrps, TargetID: server.ID, RuleType: "offline", Enabled: true},
+			{TargetType: model.AlertTargetFrpc, TargetID: client.ID, RuleType: "offline", Enabled: true},
+		} {
+			require.NoError(t, database.DB.Create(rule).Error)
+		}
+
+		svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+		svc.SetClientRepo(repository.NewClientRepository())
+		svc.SetFrpServerRepo(repository.NewFrpServerRepository(database.DB))
+		return svc, server, client
+	}
+	targetAlert := func(t *testing.T, targetType model.AlertTargetType, id uint) model.AlertLog {
+		var alert model.AlertLog
+		require.NoError(t, database.DB.Where("target_type = ? AND target_id = ?", targetType, id).Last(&alert).Error)
+		return alert
+	}
+
+	t.Run("上级告警时下级告警归并到上级", func(t *testing.T) {
+		svc, server, client := setup(t)
+		stream := newLogStreamService()
+		stream.Start()
+		svc.pendingOffline["frps:1"] = time.Now().Add(-10 * time.Minute)
+		svc.pendingOffline["frpc:1"] = time.Now().Add(-10 * time.Minute)
+		svc.CheckOfflineAlerts()
+
+		parent := targetAlert(t, model.AlertTargetFrps, server.ID)
+		assert.Zero(t, parent.SuppressedBy)
+		assert.True(t, parent.Notified)
+		child := targetAlert(t, model.AlertTargetFrpc, client.ID)
+		assert.Equal(t, parent.ID, child.SuppressedBy)
+		assert.False(t, child.Notified, "被抑制的告警不发送通知")
+
+		suppressed, err := svc.GetSuppressedAlerts(parent.ID)
+		require.NoError(t, err)
+		require.Len(t, suppressed, 1)
+		assert.Equal(t, child.ID, suppressed[0].ID)
+
+		// frps 恢复后等待 frpc 重连，确认期内不补发
+		require.NoError(t, database.DB.Model(server).Update("status", model.StatusRunning).Error)
+		svc.CheckOfflineAlerts()
+		assert.Equal(t, model.AlertStatusResolved, targetAlert(t, model.AlertTargetFrps, server.ID).Status)
+		assert.Equal(t, parent.ID, targetAlert(t, model.AlertTargetFrpc, client.ID).SuppressedBy)
+
+		// 确认期过后 frpc 仍离线，解除抑制并补发告警
+		require.NoError(t, database.DB.Model(&model.AlertLog{}).Where("id = ?", parent.ID).
+			Update("resolved_at", time.Now().Add(-10*time.Minute)).Error)
+		svc.CheckOfflineAlerts()
+		child = targetAlert(t, model.AlertTargetFrpc, client.ID)
+		assert.Zero(t, child.SuppressedBy)
+		assert.True(t, child.Notified)
+		assert.Equal(t, model.AlertStatusFiring, child.Status)
+		last := stream.pending[len(stream.pending)-1]
+		assert.Equal(t, "released", last.Fields["action"])
+		assert.Equal(t, strconv.FormatUint(uint64(parent.ID), 10), last.Fields["suppressed_by"])
+	})
+
+	t.Run("被抑制的告警恢复时直接结束", func(t *testing.T) {
+		svc, server, client := setup(t)
+		svc.pendingOffline["frps:1"] = time.Now().Add(-10 * time.Minute)
+		svc.pendingOffline["frpc:1"] = time.Now().Add(-10 * time.Minute)
+		svc.CheckOfflineAlerts()
+
+		require.NoError(t, database.DB.Model(server).Update("status", model.StatusRunning).Error)
+		require.NoError(t, database.DB.Model(client).Update("online_status", "online").Error)
+		svc.CheckOfflineAlerts()
+
+		child := targetAlert(t, model.AlertTargetFrpc, client.ID)
+		assert.Equal(t, model.AlertStatusResolved, child.Status)
+		assert.False(t, child.Notified)
+		assert.False(t, svc.alertingState["frpc:1"])
+	})
+
+	t.Run("服务重启后仍在上级结束后补发", func(t *testing.T) {
+		svc, server, client := setup(t)
+		svc.pendingOffline["frps:1"] = time.Now().Add(-10 * time.Minute)
+		svc.pendingOffline["frpc:1"] = time.Now().Add(-10 * time.Minute)
+		svc.CheckOfflineAlerts()
+		parent := targetAlert(t, model.AlertTargetFrps, server.ID)
+		require.Equal(t, parent.ID, targetAlert(t, model.AlertTargetFrpc, client.ID).SuppressedBy)
+
+		// 重启后内存状态丢失，上级已结束且超过确认期
+		require.NoError(t, database.DB.Model(server).Update("status", model.StatusRunning).Error)
+		require.NoError(t, database.DB.Model(&model.AlertLog{}).Where("id = ?", parent.ID).Updates(map[string]interface{}{
+			"status": model.AlertStatusResolved, "resolved_at": time.Now().Add(-10 * time.Minute)}).Error)
+		restarted := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+		restarted.SetClientRepo(repository.NewClientRepository())
+		restarted.SetFrpServerRepo(repository.NewFrpServerRepository(database.DB))
+		restarted.pendingOffline["frpc:1"] = time.Now().Add(-10 * time.Minute)
+		restarted.CheckOfflineAlerts()
+
+		child := targetAlert(t, model.AlertTargetFrpc, client.ID)
+		assert.Zero(t, child.SuppressedBy)
+		assert.True(t, child.Notified)
+		assert.True(t, restarted.alertingState["frpc:1"])
+		var count int64
+		database.DB.Model(&model.AlertLog{}).Where("target_type = ? AND target_id = ?", model.AlertTargetFrpc, client.ID).Count(&count)
+		assert.Equal(t, int64(1), count, "沿用原告警，不重复创建")
+	})
+
+	t.Run("上级在延迟确认期内时下级等待", func(t *testing.T) {
+		svc, _, client := setup(t)
+		svc.pendingOffline["frps:1"] = time.Now()
+		svc.pendingOffline["frpc:1"] = time.Now().Add(-10 * time.Minute)
+		svc.CheckOfflineAlerts()
+
+		var count int64
+		database.DB.Model(&model.AlertLog{}).Where("target_type = ? AND target_id = ?", model.AlertTargetFrpc, client.ID).Count(&count)
+		assert.Zero(t, count)
+		assert.False(t, svc.alertingState["frpc:1"])
+	})
+
+	t.Run("上级未离线时下级正常告警", func(t *testing.T) {
+		svc, server, client := setup(t)
+		require.NoError(t, database.DB.Model(server).Update("status", model.StatusRunning).Error)
+		svc.pendingOffline["frpc:1"] = time.Now().Add(-10 * time.Minute)
+		svc.CheckOfflineAlerts()
+
+		child := targetAlert(t, model.AlertTargetFrpc, client.ID)
+		assert.Zero(t, child.SuppressedBy)
+		assert.True(t, child.Notified)
+	})
+}
+
+// TestAllUnnotified 测试静默或被抑制的告警视为未发送通知
+func TestAllUnnotified(t *testing.T) {
+	assert.False(t, allUnnotified(nil))
+	assert.True(t, allUnnotified([]model.AlertLog{{Silenced: true}, {SuppressedBy: 3}}))
+	assert.False(t, allUnnotified([]model.AlertLog{{Silenced: true}, {}}))
+}
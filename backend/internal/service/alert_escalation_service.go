@@ -0,0 +1,467 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEscalationLevels 升级策略最多级别数
+const maxEscalationLevels = 10
+
+// AlertEscalationService 告警升级策略及值班表管理，负责将未确认的告警逐级通知
+type AlertEscalationService struct {
+	repo                *repository.AlertEscalationRepository
+	alertRepo           *repository.AlertRepo
+	recipientRepo       *repository.AlertRecipientRepo
+	emailService        *EmailService
+	notificationService *NotificationService
+	templates           *MessageTemplateService
+	silenceService      *AlertSilenceService
+	digest              *NotificationDigest
+	groups              digestGrouper
+
+	now func() time.Time // 便于测试替换时钟
+}
+
+func NewAlertEscalationService(alertRepo *repository.AlertRepo) *AlertEscalationService {
+	return &AlertEscalationService{
+		repo:                repository.NewAlertEscalationRepository(),
+		alertRepo:           alertRepo,
+		recipientRepo:       repository.NewAlertRecipientRepo(),
+		emailService:        NewEmailService(),
+		notificationService: NewNotificationService(),
+		templates:           NewMessageTemplateService(),
+		silenceService:      NewAlertSilenceService(),
+		digest:              GetNotificationDigest(),
+		groups: digestGrouper{
+			clientRepo:    repository.NewClientRepository(),
+			proxyRepo:     repository.NewProxyRepository(),
+			probeRepo:     repository.NewProxyProbeRepository(),
+			frpServerRepo: repository.NewFrpServerRepository(database.DB),
+		},
+		now: time.Now,
+	}
+}
+
+// ==================== 升级策略 ====================
+
+func (s *AlertEscalationService) GetPolicies() ([]model.AlertEscalationPolicy, error) {
+	return s.repo.FindAllPolicies()
+}
+
+func (s *AlertEscalationService) CreatePolicy(policy *model.AlertEscalationPolicy) error {
+	policy.ID = 0
+	if err := s.validatePolicy(policy); err != nil {
+		return err
+	}
+	return s.repo.CreatePolicy(policy)
+}
+
+// UpdatePolicy 更新策略，级别按提交顺序整体替换
+func (s *AlertEscalationService) UpdatePolicy(policy *model.AlertEscalationPolicy) (*model.AlertEscalationPolicy, error) {
+	existing, err := s.repo.FindPolicyByID(policy.ID)
+	if err != nil {
+		return nil, fmt.Errorf("升级策略不存在")
+	}
+	if err := s.validatePolicy(policy); err != nil {
+		return nil, err
+	}
+	policy.CreatedAt = existing.CreatedAt
+	if err := s.repo.UpdatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeletePolicy 删除策略，仍被告警规则引用时不允许删除
+func (s *AlertEscalationService) DeletePolicy(id uint) error {
+	if _, err := s.repo.FindPolicyByID(id); err != nil {
+		return fmt.Errorf("升级策略不存在")
+	}
+	count, err := s.repo.CountRulesByPolicy(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("升级策略仍被 %d 条告警规则使用", count)
+	}
+	return s.repo.DeletePolicy(id)
+}
+
+func (s *AlertEscalationService) validatePolicy(policy *model.AlertEscalationPolicy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" {
+		return fmt.Errorf("策略名称不能为空")
+	}
+	if len(policy.Steps) == 0 {
+		return fmt.Errorf("至少需要一个通知级别")
+	}
+	if len(policy.Steps) > maxEscalationLevels {
+		return fmt.Errorf("通知级别不能超过 %d 个", maxEscalationLevels)
+	}
+	for i := range policy.Steps {
+		step := &policy.Steps[i]
+		step.Level = i + 1
+		step.GroupIDs = joinIDList(parseIDList(step.GroupIDs))
+		step.ScheduleIDs = joinIDList(parseIDList(step.ScheduleIDs))
+		if step.GroupIDs == "" && step.ScheduleIDs == "" {
+			return fmt.Errorf("第%d级未设置通知分组或值班表", step.Level)
+		}
+		for _, id := range parseIDList(step.ScheduleIDs) {
+			if _, err := s.repo.FindScheduleByID(id); err != nil {
+				return fmt.Errorf("第%d级引用的值班表 %d 不存在", step.Level, id)
+			}
+		}
+		if i < len(policy.Steps)-1 && step.EscalateAfterMinutes < 1 {
+			return fmt.Errorf("第%d级升级等待时间至少为1分钟", step.Level)
+		}
+	}
+	return nil
+}
+
+// ValidatePolicyRef 校验告警规则引用的升级策略存在
+func (s *AlertEscalationService) ValidatePolicyRef(policyID uint) error {
+	if policyID == 0 {
+		return nil
+	}
+	if _, err := s.repo.FindPolicyByID(policyID); err != nil {
+		return fmt.Errorf("升级策略 %d 不存在", policyID)
+	}
+	return nil
+}
+
+// ==================== 值班表 ====================
+
+// GetSchedules 获取全部值班表并填充当前值班人
+func (s *AlertEscalationService) GetSchedules() ([]model.OnCallSchedule, error) {
+	schedules, err := s.repo.FindAllSchedules()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	for i := range schedules {
+		schedules[i].Current = s.CurrentOnCall(&schedules[i], now)
+	}
+	return schedules, nil
+}
+
+func (s *AlertEscalationService) CreateSchedule(schedule *model.OnCallSchedule) error {
+	schedule.ID = 0
+	if err := s.validateSchedule(schedule); err != nil {
+		return err
+	}
+	if err := s.repo.CreateSchedule(schedule); err != nil {
+		return err
+	}
+	schedule.Current = s.CurrentOnCall(schedule, s.now())
+	return nil
+}
+
+func (s *AlertEscalationService) UpdateSchedule(schedule *model.OnCallSchedule) (*model.OnCallSchedule, error) {
+	existing, err := s.repo.FindScheduleByID(schedule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("值班表不存在")
+	}
+	if err := s.validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	schedule.CreatedAt = existing.CreatedAt
+	if err := s.repo.UpdateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	schedule.Current = s.CurrentOnCall(schedule, s.now())
+	return schedule, nil
+}
+
+// DeleteSchedule 删除值班表，仍被升级策略引用时不允许删除
+func (s *AlertEscalationService) DeleteSchedule(id uint) error {
+	if _, err := s.repo.FindScheduleByID(id); err != nil {
+		return fmt.Errorf("值班表不存在")
+	}
+	steps, err := s.repo.FindAllSteps()
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		for _, sid := range parseIDList(step.ScheduleIDs) {
+			if sid == id {
+				return fmt.Errorf("值班表仍被升级策略使用")
+			}
+		}
+	}
+	return s.repo.DeleteSchedule(id)
+}
+
+// GetShifts 获取值班表从当前轮班开始的 count 个轮班
+func (s *AlertEscalationService) GetShifts(id uint, count int) ([]model.OnCallShift, error) {
+	schedule, err := s.repo.FindScheduleByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("值班表不存在")
+	}
+	if count <= 0 || count > 52 {
+		count = 8
+	}
+	loc, err := scheduleLocation(schedule)
+	if err != nil {
+		return nil, err
+	}
+	ids := parseIDList(schedule.RecipientIDs)
+	if len(ids) == 0 {
+		return []model.OnCallShift{}, nil
+	}
+	names := make(map[uint]string, len(ids))
+	recipients, _ := s.recipientRepo.GetRecipientsByIDs(ids)
+	for _, r := range recipients {
+		names[r.ID] = r.Name
+	}
+
+	rotation, start := onCallRotation(schedule, s.now(), loc)
+	shifts := make([]model.OnCallShift, 0, count)
+	for i := 0; i < count; i++ {
+		end := start.AddDate(0, 0, 7*rotationWeeks(schedule))
+		rid := ids[(rotation+i)%len(ids)]
+		shifts = append(shifts, model.OnCallShift{RecipientID: rid, RecipientName: names[rid], StartsAt: start, EndsAt: end})
+		start = end
+	}
+	return shifts, nil
+}
+
+// CurrentOnCall 获取值班表在指定时间的值班人，值班人已禁用或删除时返回 nil
+func (s *AlertEscalationService) CurrentOnCall(schedule *model.OnCallSchedule, at time.Time) *model.AlertRecipient {
+	ids := parseIDList(schedule.RecipientIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+	loc, err := scheduleLocation(schedule)
+	if err != nil {
+		return nil
+	}
+	rotation, _ := onCallRotation(schedule, at, loc)
+	recipients, err := s.recipientRepo.GetRecipientsByIDs([]uint{ids[rotation%len(ids)]})
+	if err != nil || len(recipients) == 0 {
+		return nil
+	}
+	return &recipients[0]
+}
+
+func (s *AlertEscalationService) validateSchedule(schedule *model.OnCallSchedule) error {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" {
+		return fmt.Errorf("值班表名称不能为空")
+	}
+	if _, err := scheduleLocation(schedule); err != nil {
+		return fmt.Errorf("无效的时区: %s", schedule.Timezone)
+	}
+	if schedule.HandoffAt.IsZero() {
+		return fmt.Errorf("交接时间不能为空")
+	}
+	if schedule.RotationWeeks <= 0 {
+		schedule.RotationWeeks = 1
+	}
+	ids := parseIDList(schedule.RecipientIDs)
+	if len(ids) == 0 {
+		return fmt.Errorf("至少需要一名值班人")
+	}
+	schedule.RecipientIDs = joinIDList(ids)
+	for _, id := range ids {
+		if _, err := s.recipientRepo.GetRecipientByID(id); err != nil {
+			return fmt.Errorf("接收人 %d 不存在", id)
+		}
+	}
+	return nil
+}
+
+func scheduleLocation(schedule *model.OnCallSchedule) (*time.Location, error) {
+	if schedule.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(schedule.Timezone)
+}
+
+func rotationWeeks(schedule *model.OnCallSchedule) int {
+	if schedule.RotationWeeks <= 0 {
+		return 1
+	}
+	return schedule.RotationWeeks
+}
+
+// onCallRotation 计算指定时间所在的轮班序号及其开始时间，交接时刻按值班表时区的日历日计算，
+// 不受夏令时切换影响；交接时间之前视为第一个轮班
+func onCallRotation(schedule *model.OnCallSchedule, at time.Time, loc *time.Location) (int, time.Time) {
+	start := schedule.HandoffAt.In(loc)
+	local := at.In(loc)
+	if !local.After(start) {
+		return 0, start
+	}
+	days := civilDays(local) - civilDays(start)
+	if clockOf(local) < clockOf(start) {
+		days--
+	}
+	period := 7 * rotationWeeks(schedule)
+	rotation := days / period
+	return rotation, start.AddDate(0, 0, rotation*period)
+}
+
+// civilDays 日期距 1970-01-01 的天数
+func civilDays(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// clockOf 当天的时刻（纳秒）
+func clockOf(t time.Time) int64 {
+	return int64(t.Hour())*int64(time.Hour) + int64(t.Minute())*int64(time.Minute) +
+		int64(t.Second())*int64(time.Second) + int64(t.Nanosecond())
+}
+
+func joinIDList(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ==================== 告警升级 ====================
+
+// Start 告警触发后通知升级策略第1级，并安排下一级升级时间；静默的告警不应调用
+func (s *AlertEscalationService) Start(alert *model.AlertLog, rule *model.AlertRule) {
+	if rule.EscalationPolicyID == 0 || alert.ID == 0 {
+		return
+	}
+	policy := s.activePolicy(rule.EscalationPolicyID)
+	if policy == nil {
+		return
+	}
+	at := alert.CreatedAt
+	if at.IsZero() {
+		at = s.now()
+	}
+	s.advance(alert, policy, 0, at)
+}
+
+// ProcessDue 将超过等待时间仍未确认的告警升级到下一级，已确认或已恢复的告警不再升级，匹配静默的告警暂停升级
+func (s *AlertEscalationService) ProcessDue() {
+	now := s.now()
+	alerts, err := s.alertRepo.GetDueEscalations(now.Local())
+	if err != nil {
+		logger.Errorf("[告警升级] 获取待升级告警失败: %v", err)
+		return
+	}
+	for i := range alerts {
+		alert := &alerts[i]
+		var policy *model.AlertEscalationPolicy
+		if rule, err := s.alertRepo.GetRuleByID(alert.RuleID); err == nil && rule.EscalationPolicyID != 0 {
+			policy = s.activePolicy(rule.EscalationPolicyID)
+		}
+		if policy == nil || alert.EscalationLevel >= len(policy.Steps) {
+			// 规则或策略已删除、禁用，或已到最高级，停止升级
+			s.alertRepo.AdvanceEscalation(alert.ID, alert.EscalationLevel, alert.EscalationLevel, nil)
+			continue
+		}
+		if s.silenceService.MatchSilence(alert.TargetType, alert.TargetID, alert.RuleID, now) != nil {
+			// 静默期间暂停升级，保持当前级别，静默结束或删除后的下一轮再升级
+			logger.Debugf("[告警升级] 告警 %d 处于静默期，暂停升级", alert.ID)
+			continue
+		}
+		s.advance(alert, policy, alert.EscalationLevel, now)
+	}
+}
+
+// advance 在 now 将告警从 fromLevel 推进一级并通知该级，推进失败（已被处理）时不通知
+func (s *AlertEscalationService) advance(alert *model.AlertLog, policy *model.AlertEscalationPolicy, fromLevel int, now time.Time) {
+	step := &policy.Steps[fromLevel]
+	var next *time.Time
+	if fromLevel+1 < len(policy.Steps) {
+		t := now.Add(time.Duration(step.EscalateAfterMinutes) * time.Minute).Local()
+		next = &t
+	}
+	ok, err := s.alertRepo.AdvanceEscalation(alert.ID, fromLevel, step.Level, next)
+	if err != nil {
+		logger.Errorf("[告警升级] 更新告警 %d 升级级别失败: %v", alert.ID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	alert.EscalationLevel = step.Level
+	alert.NextEscalationAt = next
+	publishAlertTransition(alert, "escalated", now)
+	s.notifyStep(alert, step, now)
+}
+
+// activePolicy 获取已启用且包含级别的策略，否则返回 nil
+func (s *AlertEscalationService) activePolicy(id uint) *model.AlertEscalationPolicy {
+	policy, err := s.repo.FindPolicyByID(id)
+	if err != nil || !policy.Enabled || len(policy.Steps) == 0 {
+		return nil
+	}
+	return policy
+}
+
+// stepTargets 解析一级需通知的邮箱（分组成员及当前值班人）和分组关联的通知渠道
+func (s *AlertEscalationService) stepTargets(step *model.AlertEscalationStep, at time.Time) ([]string, []uint) {
+	seen := make(map[string]bool)
+	var emails []string
+	addEmail := func(email string) {
+		if email != "" && !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+
+	groupIDs := parseIDList(step.GroupIDs)
+	recipients, _ := s.recipientRepo.GetRecipientsByGroupIDs(groupIDs)
+	for _, r := range recipients {
+		addEmail(r.Email)
+	}
+	schedules, _ := s.repo.FindEnabledSchedulesByIDs(parseIDList(step.ScheduleIDs))
+	for i := range schedules {
+		if r := s.CurrentOnCall(&schedules[i], at); r != nil {
+			addEmail(r.Email)
+		} else {
+			logger.Warnf("[告警升级] 值班表 %s 当前无可用值班人", schedules[i].Name)
+		}
+	}
+
+	var channelIDs []uint
+	groups, _ := s.recipientRepo.GetEnabledGroupsByIDs(groupIDs)
+	for _, g := range groups {
+		channelIDs = append(channelIDs, parseIDList(g.ChannelIDs)...)
+	}
+	return emails, channelIDs
+}
+
+// notifyStep 通知一级的接收方，与告警通知使用相同的汇总分组，受汇总窗口和发送上限约束
+func (s *AlertEscalationService) notifyStep(alert *model.AlertLog, step *model.AlertEscalationStep, at time.Time) {
+	emails, channelIDs := s.stepTargets(step, at)
+	data := alertTemplateData(model.MessageEventEscalation, alert, lookupAlertTargetName(alert.TargetType, alert.TargetID))
+	data.EscalationLevel = step.Level
+	data.Duration = at.Sub(alert.CreatedAt).Truncate(time.Minute).String()
+	msg := s.templates.Compose(model.MessageEventEscalation, alert.CreatedAt, data)
+	msg.Message.EventType = "escalation"
+
+	if s.digest != nil {
+		s.digest.Submit(&DigestNotification{
+			Group:      s.digestGroup(alert, data.EventName),
+			Message:    msg.Message,
+			Emails:     emails,
+			ChannelIDs: channelIDs,
+			SendEmail:  msg.EmailSender(s.emailService),
+		})
+	}
+	logger.Infof("[告警升级] 告警 %d 已通知第 %d 级（邮箱 %d 个，渠道 %d 个）", alert.ID, step.Level, len(emails), len(channelIDs))
+}
+
+// digestGroup 告警的汇总分组，系统告警按事件类型分组，与 SystemEventNotifier 一致
+func (s *AlertEscalationService) digestGroup(alert *model.AlertLog, eventName string) DigestGroup {
+	if alert.TargetType == model.AlertTargetSystem {
+		return DigestGroup{Key: "system:" + alert.AlertType, Name: eventName}
+	}
+	return s.groups.group(alert.TargetType, alert.TargetID)
+}
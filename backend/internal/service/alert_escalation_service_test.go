@@ -0,0 +1,293 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEscalationTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.AlertRule{}, &model.AlertLog{},
+		&model.AlertRecipient{}, &model.AlertRecipientGroup{}, &model.AlertGroupRecipient{},
+		&model.NotificationChannel{}, &model.AlertEscalationPolicy{}, &model.AlertEscalationStep{},
+		&model.OnCallSchedule{}, &model.AlertSilence{}, &model.WebhookDelivery{}, &model.WebhookDeliveryAttempt{})
+}
+
+func createEscalationRecipients(t *testing.T, names ...string) []model.AlertRecipient {
+	recipients := make([]model.AlertRecipient, len(names))
+	for i, name := range names {
+		recipients[i] = model.AlertRecipient{Name: name, Email: name + "@example.com", Enabled: true}
+	}
+	require.NoError(t, database.DB.Create(&recipients).Error)
+	return recipients
+}
+
+// TestOnCallRotation 测试按周轮换、多周轮换及夏令时切换前后的交接时刻
+func TestOnCallRotation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	// 2026-03-02 为周一，柏林 2026-03-29 切换夏令时
+	handoff := time.Date(2026, 3, 2, 9, 0, 0, 0, berlin)
+
+	tests := []struct {
+		name     string
+		weeks    int
+		at       time.Time
+		rotation int
+		start    time.Time
+	}{
+		{"交接前视为第一个轮班", 1, handoff.Add(-time.Hour), 0, handoff},
+		{"第一个轮班内", 1, time.Date(2026, 3, 9, 8, 59, 0, 0, berlin), 0, handoff},
+		{"到交接时刻轮换", 1, time.Date(2026, 3, 9, 9, 0, 0, 0, berlin), 1, time.Date(2026, 3, 9, 9, 0, 0, 0, berlin)},
+		{"夏令时后仍按当地时刻交接", 1, time.Date(2026, 3, 30, 8, 30, 0, 0, berlin), 3, time.Date(2026, 3, 23, 9, 0, 0, 0, berlin)},
+		{"夏令时后交接", 1, time.Date(2026, 3, 30, 9, 0, 0, 0, berlin), 4, time.Date(2026, 3, 30, 9, 0, 0, 0, berlin)},
+		{"两周轮换", 2, time.Date(2026, 3, 20, 12, 0, 0, 0, berlin), 1, time.Date(2026, 3, 16, 9, 0, 0, 0, berlin)},
+		{"其他时区的时间按值班表时区换算", 1, time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC), 1, time.Date(2026, 3, 9, 9, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := &model.OnCallSchedule{HandoffAt: handoff, RotationWeeks: tt.weeks}
+			rotation, start := onCallRotation(schedule, tt.at, berlin)
+			assert.Equal(t, tt.rotation, rotation)
+			assert.True(t, tt.start.Equal(start), "start = %s, want %s", start, tt.start)
+		})
+	}
+}
+
+// TestAlertEscalationService_Schedules 测试值班表校验、当前值班人及轮班安排
+func TestAlertEscalationService_Schedules(t *testing.T) {
+	setupEscalationTestDB(t)
+	recipients := createEscalationRecipients(t, "alice", "bob", "carol")
+	svc := NewAlertEscalationService(repository.NewAlertRepo(database.DB))
+	handoff := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return handoff.AddDate(0, 0, 15) }
+
+	invalid := []model.OnCallSchedule{
+		{Name: " ", HandoffAt: handoff, RecipientIDs: "1"},
+		{Name: "ops", HandoffAt: handoff, RecipientIDs: "1", Timezone: "Mars/Base"},
+		{Name: "ops", RecipientIDs: "1"},
+		{Name: "ops", HandoffAt: handoff},
+		{Name: "ops", HandoffAt: handoff, RecipientIDs: "1,99"},
+	}
+	for _, s := range invalid {
+		assert.Error(t, svc.CreateSchedule(&s), "%+v", s)
+	}
+
+	schedule := &model.OnCallSchedule{Name: "ops", Timezone: "UTC", HandoffAt: handoff,
+		RecipientIDs: " 3, 1 ,2", Enabled: true}
+	require.NoError(t, svc.CreateSchedule(schedule))
+	assert.Equal(t, "3,1,2", schedule.RecipientIDs)
+	assert.Equal(t, 1, schedule.RotationWeeks, "轮换周数默认1周")
+	require.NotNil(t, schedule.Current)
+	assert.Equal(t, "bob", schedule.Current.Name, "第3周轮到第3位")
+
+	shifts, err := svc.GetShifts(schedule.ID, 3)
+	require.NoError(t, err)
+	require.Len(t, shifts, 3)
+	assert.Equal(t, []string{"bob", "carol", "alice"},
+		[]string{shifts[0].RecipientName, shifts[1].RecipientName, shifts[2].RecipientName})
+	assert.True(t, shifts[0].StartsAt.Equal(handoff.AddDate(0, 0, 14)))
+	assert.True(t, shifts[0].EndsAt.Equal(shifts[1].StartsAt))
+
+	// 当前值班人被禁用时无人值班
+	require.NoError(t, database.DB.Model(&recipients[1]).Update("enabled", false).Error)
+	assert.Nil(t, svc.CurrentOnCall(schedule, svc.now()))
+}
+
+// TestAlertEscalationService_Policies 测试策略校验及删除引用检查
+func TestAlertEscalationService_Policies(t *testing.T) {
+	setupEscalationTestDB(t)
+	createEscalationRecipients(t, "alice")
+	svc := NewAlertEscalationService(repository.NewAlertRepo(database.DB))
+	schedule := &model.OnCallSchedule{Name: "ops", HandoffAt: time.Now(), RecipientIDs: "1", Enabled: true}
+	require.NoError(t, svc.CreateSchedule(schedule))
+
+	invalid := []model.AlertEscalationPolicy{
+		{Name: "", Steps: []model.AlertEscalationStep{{GroupIDs: "1"}}},
+		{Name: "p"},
+		{Name: "p", Steps: []model.AlertEscalationStep{{}}},
+		{Name: "p", Steps: []model.AlertEscalationStep{{ScheduleIDs: "99"}}},
+		{Name: "p", Steps: []model.AlertEscalationStep{{GroupIDs: "1", EscalateAfterMinutes: 0}, {GroupIDs: "2"}}},
+	}
+	for _, p := range invalid {
+		assert.Error(t, svc.CreatePolicy(&p), "%+v", p)
+	}
+
+	policy := &model.AlertEscalationPolicy{Name: "p", Enabled: true, Steps: []model.AlertEscalationStep{
+		{GroupIDs: "1", EscalateAfterMinutes: 10},
+		{ScheduleIDs: "1"},
+	}}
+	require.NoError(t, svc.CreatePolicy(policy))
+	assert.Equal(t, []int{1, 2}, []int{policy.Steps[0].Level, policy.Steps[1].Level})
+
+	policy.Steps = []model.AlertEscalationStep{{ScheduleIDs: "1"}}
+	updated, err := svc.UpdatePolicy(policy)
+	require.NoError(t, err)
+	stored, err := repository.NewAlertEscalationRepository().FindPolicyByID(updated.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Steps, 1, "更新时整体替换级别")
+
+	assert.Error(t, svc.DeleteSchedule(schedule.ID), "被策略引用的值班表不能删除")
+
+	alertService := NewAlertService(repository.NewAlertRepo(database.DB), nil, nil)
+	assert.Error(t, alertService.CreateRule(&model.AlertRule{TargetType: model.AlertTargetFrps, RuleType: "offline",
+		EscalationPolicyID: 99}), "引用不存在的策略")
+	rule := &model.AlertRule{TargetType: model.AlertTargetFrps, RuleType: "offline", EscalationPolicyID: policy.ID}
+	require.NoError(t, alertService.CreateRule(rule))
+	assert.Error(t, svc.DeletePolicy(policy.ID), "被规则引用的策略不能删除")
+
+	require.NoError(t, alertService.DeleteRule(rule.ID))
+	require.NoError(t, svc.DeletePolicy(policy.ID))
+	require.NoError(t, svc.DeleteSchedule(schedule.ID))
+}
+
+// TestAlertEscalationService_Escalate 测试逐级通知、确认后停止升级及到最高级后停止
+func TestAlertEscalationService_Escalate(t *testing.T) {
+	setupEscalationTestDB(t)
+	srv, recv := newWebhookReceiver(t)
+	recipients := createEscalationRecipients(t, "alice", "bob", "carol")
+	channel := &model.NotificationChannel{Name: "hook", Type: model.ChannelTypeWebhook, URL: srv.URL, Enabled: true}
+	require.NoError(t, database.DB.Create(channel).Error)
+	group := &model.AlertRecipientGroup{Name: "ops", Enabled: true, ChannelIDs: "1"}
+	require.NoError(t, database.DB.Create(group).Error)
+	require.NoError(t, repository.NewAlertRecipientRepo().SetGroupRecipients(group.ID, []uint{recipients[0].ID}))
+
+	require.NoError(t, repository.NewSettingRepository().UpdateSetting("alert_digest_window_seconds", "0"))
+
+	now := time.Now().Truncate(time.Second)
+	svc := NewAlertEscalationService(repository.NewAlertRepo(database.DB))
+	svc.now = func() time.Time { return now }
+	svc.digest = newNotificationDigest()
+	stream := newLogStreamService()
+	stream.Start()
+	schedule := &model.OnCallSchedule{Name: "oncall", HandoffAt: now.Add(-time.Hour),
+		RecipientIDs: joinIDList([]uint{recipients[2].ID, recipients[1].ID}), Enabled: true}
+	require.NoError(t, svc.CreateSchedule(schedule))
+	policy := &model.AlertEscalationPolicy{Name: "p", Enabled: true, Steps: []model.AlertEscalationStep{
+		{GroupIDs: "1", EscalateAfterMinutes: 10},
+		{ScheduleIDs: "1"},
+	}}
+	require.NoError(t, svc.CreatePolicy(policy))
+
+	emails, channelIDs := svc.stepTargets(&policy.Steps[0], now)
+	assert.Equal(t, []string{"alice@example.com"}, emails)
+	assert.Equal(t, []uint{channel.ID}, channelIDs)
+	emails, channelIDs = svc.stepTargets(&policy.Steps[1], now)
+	assert.Equal(t, []string{"carol@example.com"}, emails, "通知当前值班人")
+	assert.Empty(t, channelIDs)
+
+	rule := &model.AlertRule{TargetType: model.AlertTargetFrps, TargetID: 1, RuleType: "offline",
+		EscalationPolicyID: policy.ID, Enabled: true}
+	require.NoError(t, database.DB.Create(rule).Error)
+	alertRepo := repository.NewAlertRepo(database.DB)
+	newAlert := func() *model.AlertLog {
+		alert := &model.AlertLog{RuleID: rule.ID, TargetType: model.AlertTargetFrps, TargetID: 1, AlertType: "offline",
+			Message: "frps main 已离线", CreatedAt: now}
+		require.NoError(t, alertRepo.CreateAlert(alert))
+		svc.Start(alert, rule)
+		return alert
+	}
+	escalated := newAlert()
+	acked := newAlert()
+	assert.Equal(t, 1, escalated.EscalationLevel)
+	require.NotNil(t, escalated.NextEscalationAt)
+	assert.True(t, escalated.NextEscalationAt.Equal(now.Add(10*time.Minute)))
+	assert.Eventually(t, func() bool {
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		return len(recv.requests) == 2
+	}, 3*time.Second, 20*time.Millisecond, "第1级通过分组渠道通知")
+
+	// 未到升级时间不升级
+	svc.ProcessDue()
+	stored, err := alertRepo.FindAlertByID(escalated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.EscalationLevel)
+
+	_, err = alertRepo.AcknowledgeAlert(acked.ID, 1, "admin", now)
+	require.NoError(t, err)
+	now = now.Add(11 * time.Minute)
+
+	// 静默期间暂停升级，静默删除后继续
+	silence := &model.AlertSilence{TargetType: model.AlertTargetFrps, TargetID: 1,
+		StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)}
+	require.NoError(t, database.DB.Create(silence).Error)
+	svc.ProcessDue()
+	stored, err = alertRepo.FindAlertByID(escalated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.EscalationLevel, "静默期间不升级")
+	require.NoError(t, database.DB.Delete(silence).Error)
+	svc.ProcessDue()
+
+	stored, err = alertRepo.FindAlertByID(escalated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.EscalationLevel)
+	assert.Nil(t, stored.NextEscalationAt, "已到最高级")
+	var levels []string
+	for _, entry := range stream.pending {
+		if entry.Fields["alert_id"] == strconv.FormatUint(uint64(escalated.ID), 10) && entry.Fields["action"] == "escalated" {
+			levels = append(levels, entry.Fields["escalation_level"])
+		}
+	}
+	assert.Equal(t, []string{"1", "2"}, levels, "每一级升级都发布事件")
+	stored, err = alertRepo.FindAlertByID(acked.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.EscalationLevel, "已确认的告警不升级")
+
+	// 取消确认后继续升级
+	_, err = alertRepo.UnacknowledgeAlert(acked.ID)
+	require.NoError(t, err)
+	svc.ProcessDue()
+	stored, err = alertRepo.FindAlertByID(acked.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.EscalationLevel)
+
+	// 未配置策略或策略已禁用时不升级
+	other := &model.AlertLog{RuleID: rule.ID, AlertType: "offline", CreatedAt: now}
+	require.NoError(t, alertRepo.CreateAlert(other))
+	svc.Start(other, &model.AlertRule{})
+	require.NoError(t, database.DB.Model(policy).Update("enabled", false).Error)
+	svc.Start(other, rule)
+	stored, err = alertRepo.FindAlertByID(other.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.EscalationLevel)
+}
+
+// TestAlertEscalationService_NotifyDigest 测试升级通知与告警通知一样按所属 frps 汇总，并受渠道发送上限约束
+func TestAlertEscalationService_NotifyDigest(t *testing.T) {
+	setupEscalationTestDB(t)
+	settingRepo := repository.NewSettingRepository()
+	require.NoError(t, settingRepo.UpdateSetting("alert_digest_window_seconds", "60"))
+	srv, recv := newWebhookReceiver(t)
+	channel := &model.NotificationChannel{Name: "hook", Type: model.ChannelTypeWebhook, URL: srv.URL, Enabled: true, RateLimit: 1}
+	require.NoError(t, database.DB.Create(channel).Error)
+	group := &model.AlertRecipientGroup{Name: "ops", Enabled: true, ChannelIDs: "1"}
+	require.NoError(t, database.DB.Create(group).Error)
+
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.Local)
+	svc := NewAlertEscalationService(repository.NewAlertRepo(database.DB))
+	svc.digest = newNotificationDigest()
+	svc.digest.now = func() time.Time { return now }
+	step := &model.AlertEscalationStep{Level: 1, GroupIDs: "1"}
+	for i := uint(1); i <= 2; i++ {
+		svc.notifyStep(&model.AlertLog{ID: i, TargetType: model.AlertTargetFrps, TargetID: 1, AlertType: "offline",
+			Message: "frps main 已离线", CreatedAt: now.Add(-time.Hour)}, step, now)
+	}
+	now = now.Add(time.Minute)
+	svc.digest.FlushDue()
+	assert.Eventually(t, func() bool { return len(receivedPayloads(t, recv)) == 1 }, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "FRP告警汇总 - frps #1（2条）", receivedPayloads(t, recv)[0].Title, "同一 frps 的升级通知合并发送")
+
+	// 达到渠道发送上限后不再发送
+	require.NoError(t, settingRepo.UpdateSetting("alert_digest_window_seconds", "0"))
+	svc.notifyStep(&model.AlertLog{ID: 3, TargetType: model.AlertTargetFrps, TargetID: 1, AlertType: "offline",
+		CreatedAt: now.Add(-time.Hour)}, step, now)
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, receivedPayloads(t, recv), 1, "超过渠道发送上限")
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/model"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 指标单位
+const (
+	metricUnitNone      = ""
+	metricUnitPercent   = "percent"
+	metricUnitBytes     = "bytes"
+	metricUnitBytesRate = "bytes/s"
+)
+
+// alertMetric 可用于表达式规则的指标
+type alertMetric struct {
+	target model.AlertTargetType
+	label  string
+	unit   string
+	series bool // 是否有历史序列，没有历史序列的指标只能取当前值
+}
+
+// alertMetrics 表达式规则支持的指标
+var alertMetrics = map[string]alertMetric{
+	"frps_cpu_percent":  {model.AlertTargetFrps, "CPU使用率", metricUnitPercent, true},
+	"frps_memory_bytes": {model.AlertTargetFrps, "内存占用", metricUnitBytes, true},
+	"frps_client_count": {model.AlertTargetFrps, "客户端数", metricUnitNone, true},
+	"proxy_rate_in":     {model.AlertTargetProxy, "入站速率", metricUnitBytesRate, true},
+	"proxy_rate_out":    {model.AlertTargetProxy, "出站速率", metricUnitBytesRate, true},
+	"proxy_cur_conns":   {model.AlertTargetProxy, "当前连接数", metricUnitNone, false},
+}
+
+const (
+	maxExpressionWindow = 24 * time.Hour
+	// defaultLastWindow 未指定窗口时取最近一个采样值的回溯范围，超过则视为无数据
+	defaultLastWindow = 5 * time.Minute
+)
+
+// AlertExpression 解析后的指标告警表达式，格式为
+//
+//	[avg|max|min|last(]metric[[窗口]][)] 比较运算符 阈值[单位] [for 持续时间]
+//
+// 例如 avg(frps_cpu_percent[5m]) > 80 for 10m、max(proxy_rate_in[1m]) >= 10MB、proxy_cur_conns > 100
+type AlertExpression struct {
+	Aggregation string        // avg, max, min, last
+	Metric      string        // 指标名
+	Window      time.Duration // 聚合窗口，未指定时为0
+	Operator    string        // >, >=, <, <=, ==, !=
+	Threshold   float64       // 已换算为指标基本单位的阈值
+	For         time.Duration // 条件需持续满足的时间，0 表示立即触发
+}
+
+var alertExpressionPattern = regexp.MustCompile(`^(?:([a-z]+)\s*\(\s*([a-z_]+)\s*(?:\[\s*([0-9a-z]+)\s*\])?\s*\)|([a-z_]+)\s*(?:\[\s*([0-9a-z]+)\s*\])?)` +
+	`\s*(>=|<=|==|!=|>|<)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*([a-z%/]*)(?:\s+for\s+([0-9a-z]+))?$`)
+
+// thresholdUnits 阈值单位换算为字节
+var thresholdUnits = map[string]float64{
+	"b":  1,
+	"kb": 1024,
+	"mb": 1024 * 1024,
+	"gb": 1024 * 1024 * 1024,
+	"tb": 1024 * 1024 * 1024 * 1024,
+}
+
+// ParseAlertExpression 解析指标告警表达式
+func ParseAlertExpression(expr string) (*AlertExpression, error) {
+	m := alertExpressionPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(expr)))
+	if m == nil {
+		return nil, fmt.Errorf("表达式格式错误，示例: avg(frps_cpu_percent[5m]) > 80 for 10m")
+	}
+
+	e := &AlertExpression{Aggregation: m[1], Metric: m[2], Operator: m[6]}
+	window := m[3]
+	if e.Aggregation == "" {
+		e.Aggregation, e.Metric, window = "last", m[4], m[5]
+	}
+	metric, ok := alertMetrics[e.Metric]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指标: %s", e.Metric)
+	}
+	switch e.Aggregation {
+	case "avg", "max", "min", "last":
+	default:
+		return nil, fmt.Errorf("不支持的聚合函数: %s", e.Aggregation)
+	}
+
+	var err error
+	if window != "" {
+		if e.Window, err = parseExpressionDuration(window); err != nil {
+			return nil, fmt.Errorf("无效的窗口: %s", window)
+		}
+		if e.Window < time.Minute || e.Window > maxExpressionWindow {
+			return nil, fmt.Errorf("窗口应在 1m 到 24h 之间")
+		}
+	}
+	if e.Aggregation != "last" && e.Window == 0 {
+		return nil, fmt.Errorf("%s 聚合需要指定窗口，如 %s(%s[5m])", e.Aggregation, e.Aggregation, e.Metric)
+	}
+	if !metric.series && (e.Aggregation != "last" || e.Window != 0) {
+		return nil, fmt.Errorf("指标 %s 没有历史数据，只能取当前值", e.Metric)
+	}
+
+	if e.Threshold, err = strconv.ParseFloat(m[7], 64); err != nil {
+		return nil, fmt.Errorf("无效的阈值: %s", m[7])
+	}
+	if e.Threshold, err = convertThreshold(e.Threshold, m[8], metric.unit); err != nil {
+		return nil, err
+	}
+
+	if m[9] != "" {
+		if e.For, err = parseExpressionDuration(m[9]); err != nil || e.For > maxExpressionWindow {
+			return nil, fmt.Errorf("无效的持续时间: %s", m[9])
+		}
+	}
+	return e, nil
+}
+
+// parseExpressionDuration 解析 30s、5m、1h、1h30m 格式的时长，不允许负数
+func parseExpressionDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("无效的时长: %s", s)
+	}
+	return d, nil
+}
+
+// convertThreshold 校验阈值单位并换算为指标的基本单位
+func convertThreshold(value float64, unit, metricUnit string) (float64, error) {
+	switch {
+	case unit == "":
+		return value, nil
+	case unit == "%" && metricUnit == metricUnitPercent:
+		return value, nil
+	case metricUnit == metricUnitBytes || metricUnit == metricUnitBytesRate:
+		name := strings.TrimSuffix(unit, "/s")
+		if factor, ok := thresholdUnits[name]; ok && (name == unit || metricUnit == metricUnitBytesRate) {
+			return value * factor, nil
+		}
+	}
+	return 0, fmt.Errorf("阈值单位 %s 不适用于该指标", unit)
+}
+
+// Target 表达式指标对应的告警目标类型
+func (e *AlertExpression) Target() model.AlertTargetType {
+	return alertMetrics[e.Metric].target
+}
+
+// Series 指标是否从历史序列取值
+func (e *AlertExpression) Series() bool {
+	return alertMetrics[e.Metric].series
+}
+
+// LookBack 取样的时间范围
+func (e *AlertExpression) LookBack() time.Duration {
+	if e.Window > 0 {
+		return e.Window
+	}
+	return defaultLastWindow
+}
+
+// Aggregate 按聚合函数计算采样值，samples 按时间升序，无采样时返回 false
+func (e *AlertExpression) Aggregate(samples []float64) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	switch e.Aggregation {
+	case "avg":
+		sum := 0.0
+		for _, v := range samples {
+			sum += v
+		}
+		return sum / float64(len(samples)), true
+	case "max":
+		result := math.Inf(-1)
+		for _, v := range samples {
+			result = math.Max(result, v)
+		}
+		return result, true
+	case "min":
+		result := math.Inf(1)
+		for _, v := range samples {
+			result = math.Min(result, v)
+		}
+		return result, true
+	default:
+		return samples[len(samples)-1], true
+	}
+}
+
+// Compare 判断值是否满足告警条件
+func (e *AlertExpression) Compare(value float64) bool {
+	switch e.Operator {
+	case ">":
+		return value > e.Threshold
+	case ">=":
+		return value >= e.Threshold
+	case "<":
+		return value < e.Threshold
+	case "<=":
+		return value <= e.Threshold
+	case "==":
+		return value == e.Threshold
+	case "!=":
+		return value != e.Threshold
+	}
+	return false
+}
+
+// FormatValue 按指标单位格式化数值
+func (e *AlertExpression) FormatValue(value float64) string {
+	switch alertMetrics[e.Metric].unit {
+	case metricUnitPercent:
+		return fmt.Sprintf("%.1f%%", value)
+	case metricUnitBytes:
+		return formatBytes(int64(value))
+	case metricUnitBytesRate:
+		return formatBytes(int64(value)) + "/s"
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
+
+// Describe 表达式的中文描述，用于告警消息
+func (e *AlertExpression) Describe(value float64) string {
+	label := alertMetrics[e.Metric].label
+	if e.Window > 0 {
+		aggNames := map[string]string{"avg": "平均", "max": "最大", "min": "最小", "last": "最新"}
+		label = fmt.Sprintf("%s内%s%s", formatExpressionDuration(e.Window), aggNames[e.Aggregation], label)
+	}
+	desc := fmt.Sprintf("%s %s，%s %s", label, e.FormatValue(value), e.Operator, e.FormatValue(e.Threshold))
+	if e.For > 0 {
+		desc += fmt.Sprintf("，已持续 %s", formatExpressionDuration(e.For))
+	}
+	return desc
+}
+
+// formatExpressionDuration 将时长格式化为 90s、5m、1h30m 形式
+func formatExpressionDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
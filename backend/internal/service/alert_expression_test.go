@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAlertExpression 测试表达式解析、单位换算及校验
+func TestParseAlertExpression(t *testing.T) {
+	tests := []struct {
+		expr string
+		want AlertExpression
+	}{
+		{"avg(frps_cpu_percent[5m]) > 80 for 10m",
+			AlertExpression{Aggregation: "avg", Metric: "frps_cpu_percent", Window: 5 * time.Minute, Operator: ">", Threshold: 80, For: 10 * time.Minute}},
+		{"  MAX( proxy_rate_in [1m] )>=10MB/s ",
+			AlertExpression{Aggregation: "max", Metric: "proxy_rate_in", Window: time.Minute, Operator: ">=", Threshold: 10 << 20}},
+		{"min(frps_memory_bytes[1h]) < 1.5GB",
+			AlertExpression{Aggregation: "min", Metric: "frps_memory_bytes", Window: time.Hour, Operator: "<", Threshold: 1.5 * (1 << 30)}},
+		{"frps_client_count == 0 for 5m",
+			AlertExpression{Aggregation: "last", Metric: "frps_client_count", Operator: "==", Threshold: 0, For: 5 * time.Minute}},
+		{"last(proxy_cur_conns) != 3",
+			AlertExpression{Aggregation: "last", Metric: "proxy_cur_conns", Operator: "!=", Threshold: 3}},
+		{"frps_cpu_percent[10m] <= 90%",
+			AlertExpression{Aggregation: "last", Metric: "frps_cpu_percent", Window: 10 * time.Minute, Operator: "<=", Threshold: 90}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := ParseAlertExpression(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *e)
+		})
+	}
+
+	invalid := []string{
+		"",
+		"cpu > 80",                             // 未知指标
+		"sum(frps_cpu_percent[5m]) > 80",       // 未知聚合函数
+		"avg(frps_cpu_percent) > 80",           // 聚合缺少窗口
+		"avg(frps_cpu_percent[30s]) > 80",      // 窗口过短
+		"avg(frps_cpu_percent[48h]) > 80",      // 窗口过长
+		"avg(proxy_cur_conns[5m]) > 10",        // 无历史数据的指标不能聚合
+		"frps_cpu_percent > 80MB",              // 单位不匹配
+		"frps_memory_bytes > 1GB/s",            // 非速率指标不能使用速率单位
+		"proxy_cur_conns > 10%",                // 百分号只适用于百分比指标
+		"frps_cpu_percent >> 80",               // 运算符错误
+		"frps_cpu_percent > 80 for -5m",        // 负持续时间
+		"frps_cpu_percent > 80 for ever",       // 持续时间格式错误
+		"avg(frps_cpu_percent[5m]) > 80 and x", // 多余内容
+	}
+	for _, expr := range invalid {
+		_, err := ParseAlertExpression(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+// TestAlertExpression_Evaluate 测试聚合、比较及告警描述
+func TestAlertExpression_Evaluate(t *testing.T) {
+	samples := []float64{70, 95, 85}
+	tests := []struct {
+		expr    string
+		value   float64
+		matched bool
+	}{
+		{"avg(frps_cpu_percent[5m]) > 80", 250.0 / 3, true},
+		{"max(frps_cpu_percent[5m]) >= 95", 95, true},
+		{"min(frps_cpu_percent[5m]) < 70", 70, false},
+		{"frps_cpu_percent[5m] == 85", 85, true},
+		{"last(frps_cpu_percent[5m]) != 85", 85, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := ParseAlertExpression(tt.expr)
+			require.NoError(t, err)
+			value, ok := e.Aggregate(samples)
+			require.True(t, ok)
+			assert.InDelta(t, tt.value, value, 1e-9)
+			assert.Equal(t, tt.matched, e.Compare(value))
+		})
+	}
+
+	e, err := ParseAlertExpression("avg(proxy_rate_out[5m]) > 1MB for 1h30m")
+	require.NoError(t, err)
+	_, ok := e.Aggregate(nil)
+	assert.False(t, ok, "无采样时不评估")
+	assert.Equal(t, 5*time.Minute, e.LookBack())
+	assert.Equal(t, "5m内平均出站速率 2.00 MB/s，> 1.00 MB/s，已持续 1h30m", e.Describe(2<<20))
+
+	e, err = ParseAlertExpression("proxy_cur_conns > 100")
+	require.NoError(t, err)
+	assert.Equal(t, defaultLastWindow, e.LookBack())
+	assert.False(t, e.Series())
+	assert.Equal(t, "当前连接数 150，> 100", e.Describe(150))
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"time"
+)
+
+// metricTarget 指标规则的一个评估目标
+type metricTarget struct {
+	id       uint
+	name     string
+	serverID uint         // 指标序列所属的 frps
+	proxy    *model.Proxy // 代理目标
+	series   []string     // 代理指标序列名，clientName.proxyName 及兼容旧数据的短名称
+}
+
+// CheckMetricAlerts 评估指标表达式规则，供定时任务调用
+func (s *AlertService) CheckMetricAlerts() {
+	s.checkMetricAlertsAt(s.now())
+}
+
+func (s *AlertService) checkMetricAlertsAt(now time.Time) {
+	now = now.Local()
+	rules, err := s.alertRepo.GetEnabledRulesByRuleType(model.RuleTypeMetric)
+	if err != nil {
+		logger.Errorf("[指标告警] 获取规则失败: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		expr, err := ParseAlertExpression(rule.Expression)
+		if err != nil {
+			logger.Warnf("[指标告警] 规则 %d 表达式无效: %v", rule.ID, err)
+			continue
+		}
+		for _, target := range s.metricTargets(rule, expr) {
+			value, ok := s.sampleMetric(expr, &target, now)
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("metric:%d:%d", rule.ID, target.id)
+			seen[key] = true
+			s.handleMetricState(key, rule, expr, &target, value, now)
+		}
+	}
+
+	// 清理已删除规则或目标的待确认状态
+	s.stateMutex.Lock()
+	for key := range s.metricPending {
+		if !seen[key] {
+			delete(s.metricPending, key)
+		}
+	}
+	s.stateMutex.Unlock()
+}
+
+// metricTargets 获取规则的评估目标，目标ID为0时匹配该类型的全部目标
+func (s *AlertService) metricTargets(rule *model.AlertRule, expr *AlertExpression) []metricTarget {
+	if rule.TargetType != expr.Target() {
+		logger.Warnf("[指标告警] 规则 %d 的目标类型 %s 与指标 %s 不匹配", rule.ID, rule.TargetType, expr.Metric)
+		return nil
+	}
+
+	var targets []metricTarget
+	switch expr.Target() {
+	case model.AlertTargetFrps:
+		if s.frpServerRepo == nil {
+			return nil
+		}
+		var servers []model.FrpServer
+		if rule.TargetID != 0 {
+			if server, err := s.frpServerRepo.GetByID(rule.TargetID); err == nil {
+				servers = append(servers, *server)
+			}
+		} else {
+			servers, _ = s.frpServerRepo.GetEnabled()
+		}
+		for _, server := range servers {
+			targets = append(targets, metricTarget{id: server.ID, name: server.Name, serverID: server.ID})
+		}
+	case model.AlertTargetProxy:
+		var proxies []model.Proxy
+		if id := rule.TargetID; id != 0 || rule.ProxyID != 0 {
+			if id == 0 {
+				id = rule.ProxyID
+			}
+			if proxy, err := s.proxyRepo.FindByID(id); err == nil {
+				proxies = append(proxies, *proxy)
+			}
+		} else {
+			proxies, _ = s.proxyRepo.FindAll()
+		}
+		for i := range proxies {
+			target := metricTarget{id: proxies[i].ID, name: proxies[i].Name, proxy: &proxies[i]}
+			if expr.Series() {
+				if s.clientRepo == nil {
+					continue
+				}
+				client, err := s.clientRepo.FindByID(proxies[i].ClientID)
+				if err != nil || client.FrpServerID == nil {
+					continue
+				}
+				target.serverID = *client.FrpServerID
+				target.series = []string{client.Name + "." + proxies[i].Name, proxies[i].Name}
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// sampleMetric 按表达式取样并聚合，窗口内没有采样时返回 false
+func (s *AlertService) sampleMetric(expr *AlertExpression, target *metricTarget, now time.Time) (float64, bool) {
+	if expr.Metric == "proxy_cur_conns" {
+		return float64(target.proxy.FrpCurConns), true
+	}
+
+	start := now.Add(-expr.LookBack())
+	var samples []float64
+	switch expr.Target() {
+	case model.AlertTargetFrps:
+		records, err := s.serverMetricsRepo.GetHistory(target.serverID, start, now)
+		if err != nil {
+			logger.Errorf("[指标告警] 获取 frps %s 指标失败: %v", target.name, err)
+			return 0, false
+		}
+		for _, r := range records {
+			switch expr.Metric {
+			case "frps_cpu_percent":
+				samples = append(samples, r.CpuPercent)
+			case "frps_memory_bytes":
+				samples = append(samples, float64(r.MemoryBytes))
+			case "frps_client_count":
+				samples = append(samples, float64(r.ClientCount))
+			}
+		}
+	case model.AlertTargetProxy:
+		var records []model.ProxyMetricsHistory
+		for _, name := range target.series {
+			var err error
+			if records, err = s.proxyMetricsRepo.GetHistory(target.serverID, name, start, now); err != nil {
+				logger.Errorf("[指标告警] 获取代理 %s 指标失败: %v", target.name, err)
+				return 0, false
+			}
+			if len(records) > 0 {
+				break
+			}
+		}
+		for _, r := range records {
+			if expr.Metric == "proxy_rate_in" {
+				samples = append(samples, float64(r.RateIn))
+			} else {
+				samples = append(samples, float64(r.RateOut))
+			}
+		}
+	}
+	return expr.Aggregate(samples)
+}
+
+// handleMetricState 条件持续满足 for 指定的时间后触发告警，条件不再满足时结束告警
+func (s *AlertService) handleMetricState(key string, rule *model.AlertRule, expr *AlertExpression, target *metricTarget, value float64, now time.Time) {
+	if !expr.Compare(value) {
+		s.stateMutex.Lock()
+		delete(s.metricPending, key)
+		s.stateMutex.Unlock()
+		s.resolveMetricAlerts(rule, expr, target, value, now)
+		return
+	}
+
+	s.stateMutex.Lock()
+	since, pending := s.metricPending[key]
+	if !pending {
+		since = now
+		s.metricPending[key] = now
+	}
+	s.stateMutex.Unlock()
+	if now.Sub(since) < expr.For {
+		return
+	}
+
+	// 已有未结束的告警时不重复触发，被上级抑制的告警在上级结束后补发通知；恢复后仍需遵守冷却时间
+	ancestors := s.alertAncestors(rule.TargetType, target.id)
+	if open, err := s.alertRepo.GetOpenAlertsByRuleTarget(rule.ID, target.id); err != nil || len(open) > 0 {
+		s.releaseSuppressedMetric(open, ancestors, rule, target, value, now)
+		return
+	}
+	s.stateMutex.RLock()
+	ancestorPending := s.ancestorPendingUnlocked(ancestors)
+	s.stateMutex.RUnlock()
+	if ancestorPending {
+		return
+	}
+	if rule.CooldownMinutes > 0 {
+		since := now.Add(-time.Duration(rule.CooldownMinutes) * time.Minute)
+		if recent, err := s.alertRepo.GetRuleTargetAlertSince(rule.ID, target.id, since); err == nil && recent != nil {
+			return
+		}
+	}
+
+	eventData, _ := json.Marshal(map[string]interface{}{
+		"expression": rule.Expression,
+		"metric":     expr.Metric,
+		"value":      value,
+		"threshold":  expr.Threshold,
+	})
+	alert := &model.AlertLog{
+		RuleID:         rule.ID,
+		TargetType:     rule.TargetType,
+		TargetID:       target.id,
+		AlertType:      model.RuleTypeMetric,
+		CurrentValue:   int64(value),
+		ThresholdValue: int64(expr.Threshold),
+		Message:        fmt.Sprintf("%s %s %s", rule.TargetType, target.name, expr.Describe(value)),
+		EventData:      string(eventData),
+		CreatedAt:      now,
+	}
+	if rule.TargetType == model.AlertTargetProxy {
+		alert.ProxyID = target.id
+	}
+	if s.silenceService != nil {
+		alert.Silenced = s.silenceService.MatchSilence(rule.TargetType, target.id, rule.ID, now) != nil
+	}
+	if parent := s.suppressingAlert(ancestors, metricDependencyGrace, now); parent != nil {
+		alert.SuppressedBy = parent.ID
+	}
+	if err := s.alertRepo.CreateAlert(alert); err != nil {
+		logger.Errorf("[指标告警] 创建告警失败: %v", err)
+		return
+	}
+	publishAlertLog(alert)
+	if alert.SuppressedBy > 0 {
+		logger.Infof("[指标告警] %s 的上级处于告警中，已归并到上级告警 #%d，暂不发送通知", alert.Message, alert.SuppressedBy)
+		return
+	}
+	if alert.Silenced {
+		logger.Infof("[指标告警] %s 处于静默期，已记录告警但不发送通知", alert.Message)
+		return
+	}
+	s.sendMetricNotification(alert, rule, target.name, value)
+	s.startEscalation(alert, rule)
+}
+
+// releaseSuppressedMetric 上级告警结束后条件仍满足时解除被抑制指标告警的抑制并补发通知
+func (s *AlertService) releaseSuppressedMetric(open []model.AlertLog, ancestors []alertTargetRef, rule *model.AlertRule, target *metricTarget, value float64, now time.Time) {
+	for i := range open {
+		alert := &open[i]
+		if alert.SuppressedBy == 0 {
+			continue
+		}
+		if s.suppressingAlert(ancestors, metricDependencyGrace, now) != nil {
+			return
+		}
+		if s.releaseSuppressed(alert) {
+			s.sendMetricNotification(alert, rule, target.name, value)
+			s.startEscalation(alert, rule)
+		}
+	}
+}
+
+// resolveMetricAlerts 结束目标未结束的告警，告警曾发送过通知且规则开启恢复通知时发送恢复通知
+func (s *AlertService) resolveMetricAlerts(rule *model.AlertRule, expr *AlertExpression, target *metricTarget, value float64, now time.Time) {
+	open, err := s.alertRepo.GetOpenAlertsByRuleTarget(rule.ID, target.id)
+	if err != nil || len(open) == 0 {
+		return
+	}
+	if _, err := s.alertRepo.ResolveOpenTargetAlerts(rule.ID, target.id, now); err != nil {
+		logger.Errorf("[指标告警] 结束规则 %d 的告警失败: %v", rule.ID, err)
+		return
+	}
+	recovery := &model.AlertLog{
+		RuleID:     rule.ID,
+		TargetType: rule.TargetType,
+		TargetID:   target.id,
+		AlertType:  "recovery",
+		Status:     model.AlertStatusResolved,
+		Message:    fmt.Sprintf("%s %s 已恢复正常，当前值 %s", rule.TargetType, target.name, expr.FormatValue(value)),
+		CreatedAt:  now,
+	}
+	publishAlertLog(recovery)
+	if !rule.NotifyOnRecovery || allUnnotified(open) {
+		return
+	}
+
+	if rule.NotifyWebhook != "" {
+		go s.enqueueWebhook(rule, recovery, target.name, map[string]interface{}{
+			"target_type": rule.TargetType,
+			"target_name": target.name,
+			"alert_type":  "recovery",
+			"expression":  rule.Expression,
+			"value":       value,
+			"message":     recovery.Message,
+			"timestamp":   now.Unix(),
+		})
+	}
+	data := alertTemplateData(model.MessageEventMetricRecovery, recovery, target.name)
+	data.Expression, data.CurrentValue, data.Threshold = rule.Expression, expr.FormatValue(value), expr.FormatValue(expr.Threshold)
+	s.dispatch(rule, s.digestGroup(rule.TargetType, target.id),
+		s.templates.Compose(model.MessageEventMetricRecovery, now, data))
+}
+
+func (s *AlertService) sendMetricNotification(alert *model.AlertLog, rule *model.AlertRule, targetName string, value float64) {
+	if rule.NotifyWebhook != "" {
+		go s.enqueueWebhook(rule, alert, targetName, map[string]interface{}{
+			"target_type": rule.TargetType,
+			"target_name": targetName,
+			"alert_type":  model.RuleTypeMetric,
+			"expression":  rule.Expression,
+			"value":       value,
+			"message":     alert.Message,
+			"timestamp":   alert.CreatedAt.Unix(),
+		})
+	}
+	data := alertTemplateData(model.MessageEventMetric, alert, targetName)
+	s.dispatch(rule, s.digestGroup(alert.TargetType, alert.TargetID),
+		s.templates.Compose(model.MessageEventMetric, alert.CreatedAt, data))
+	s.markNotified(alert, rule)
+}
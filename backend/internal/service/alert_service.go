@@ -1,9 +1,8 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
+	"frp-web-panel/internal/events"
 	"frp-web-panel/internal/logger"
 	"frp-web-panel/internal/model"
 	"frp-web-panel/internal/repository"
@@ -20,29 +19,55 @@ var httpClient = &http.Client{
 }
 
 type AlertService struct {
-	alertRepo        *repository.AlertRepo
-	trafficRepo      *repository.TrafficRepository
-	proxyRepo        *repository.ProxyRepository
-	clientRepo       *repository.ClientRepository
-	frpServerRepo    *repository.FrpServerRepository
-	emailService     *EmailService
-	recipientService *AlertRecipientService
+	alertRepo           *repository.AlertRepo
+	trafficRepo         *repository.TrafficRepository
+	proxyRepo           *repository.ProxyRepository
+	clientRepo          *repository.ClientRepository
+	frpServerRepo       *repository.FrpServerRepository
+	probeRepo           *repository.ProxyProbeRepository
+	quotaRepo           *repository.TrafficQuotaRepository
+	serverMetricsRepo   *repository.ServerMetricsRepository
+	proxyMetricsRepo    *repository.ProxyMetricsRepository
+	metricsRollup       *MetricsRollupService
+	emailService        *EmailService
+	recipientService    *AlertRecipientService
+	notificationService *NotificationService
+	webhookService      *WebhookDeliveryService
+	silenceService      *AlertSilenceService
+	escalationService   *AlertEscalationService
+	digest              *NotificationDigest
+	templates           *MessageTemplateService
 
 	// 离线状态追踪（内存中，不持久化）
-	pendingOffline map[string]time.Time // key: "frpc:id" 或 "frps:id", value: 首次检测到离线的时间
-	alertingState  map[string]bool      // key: "frpc:id" 或 "frps:id", value: 是否已发送告警
+	pendingOffline map[string]time.Time // key: "frpc:id"、"frps:id" 或 "probe:id", value: 首次检测到离线的时间
+	alertingState  map[string]bool      // key: "frpc:id"、"frps:id" 或 "probe:id", value: 是否已发送告警
+	metricPending  map[string]time.Time // key: "metric:规则ID:目标ID", value: 指标规则条件开始满足的时间
 	stateMutex     sync.RWMutex
+
+	now func() time.Time // 便于测试替换时钟
 }
 
 func NewAlertService(alertRepo *repository.AlertRepo, trafficRepo *repository.TrafficRepository, proxyRepo *repository.ProxyRepository) *AlertService {
 	return &AlertService{
-		alertRepo:        alertRepo,
-		trafficRepo:      trafficRepo,
-		proxyRepo:        proxyRepo,
-		emailService:     NewEmailService(),
-		recipientService: NewAlertRecipientService(),
-		pendingOffline:   make(map[string]time.Time),
-		alertingState:    make(map[string]bool),
+		alertRepo:           alertRepo,
+		trafficRepo:         trafficRepo,
+		proxyRepo:           proxyRepo,
+		quotaRepo:           repository.NewTrafficQuotaRepository(),
+		serverMetricsRepo:   repository.NewServerMetricsRepository(),
+		proxyMetricsRepo:    repository.NewProxyMetricsRepository(),
+		metricsRollup:       NewMetricsRollupService(),
+		emailService:        NewEmailService(),
+		recipientService:    NewAlertRecipientService(),
+		notificationService: NewNotificationService(),
+		webhookService:      NewWebhookDeliveryService(alertRepo),
+		silenceService:      NewAlertSilenceService(),
+		escalationService:   NewAlertEscalationService(alertRepo),
+		digest:              GetNotificationDigest(),
+		templates:           NewMessageTemplateService(),
+		pendingOffline:      make(map[string]time.Time),
+		alertingState:       make(map[string]bool),
+		metricPending:       make(map[string]time.Time),
+		now:                 time.Now,
 	}
 }
 
@@ -56,62 +81,380 @@ func (s *AlertService) SetFrpServerRepo(repo *repository.FrpServerRepository) {
 	s.frpServerRepo = repo
 }
 
+// SetMetricsRollup 设置指标汇总服务（用于按自然日、自然月统计流量）
+func (s *AlertService) SetMetricsRollup(rollup *MetricsRollupService) {
+	s.metricsRollup = rollup
+}
+
+// SetProbeRepo 设置拨测仓库（用于拨测失败告警）
+func (s *AlertService) SetProbeRepo(repo *repository.ProxyProbeRepository) {
+	s.probeRepo = repo
+}
+
+// CheckAlerts 检查代理流量告警规则，供定时任务调用
 func (s *AlertService) CheckAlerts() {
+	s.checkAlertsAt(s.now())
+}
+
+func (s *AlertService) checkAlertsAt(now time.Time) {
+	// 数据库中的时间按服务器时区写入，查询条件统一转换为服务器时区
+	now = now.Local()
 	// 只获取 proxy 类型的流量告警规则，避免查询 ProxyID=0 的无效记录
 	rules, err := s.alertRepo.GetEnabledRulesByTargetType(model.AlertTargetProxy)
 	if err != nil {
 		return
 	}
 
+	usage := make(map[string]map[string]int64)
 	for _, rule := range rules {
-		if s.shouldSkipAlert(rule.ID) {
-			continue
+		if rule.RuleType == model.RuleTypeMetric {
+			continue // 指标规则由 CheckMetricAlerts 评估
 		}
-
 		proxy, err := s.proxyRepo.FindByID(rule.ProxyID)
 		if err != nil {
 			continue
 		}
 
-		var currentValue int64
-		switch rule.RuleType {
-		case "rate":
-			currentValue = proxy.CurrentBytesInRate + proxy.CurrentBytesOutRate
-		case "daily", "monthly":
-			currentValue = proxy.TotalBytesIn + proxy.TotalBytesOut
+		eval, ok := s.evaluateTrafficRule(&rule, proxy, now, usage)
+		if !ok {
+			continue
+		}
+		if !eval.exceeded {
+			s.resolveRuleAlerts(&rule, now)
+			continue
+		}
+		// 已有未结束（含已确认）的告警时不重复触发，避免每个冷却周期都新建告警并重新升级
+		if open, err := s.alertRepo.GetOpenAlertsByRuleTarget(rule.ID, rule.ProxyID); err != nil || len(open) > 0 {
+			continue
+		}
+		if s.inCooldown(&rule, eval.windowStart, now) {
+			continue
 		}
 
-		thresholdBytes := s.convertToBytes(rule.ThresholdValue, rule.ThresholdUnit)
-		if currentValue > thresholdBytes {
-			alert := &model.AlertLog{
-				RuleID:         rule.ID,
-				ProxyID:        rule.ProxyID,
-				AlertType:      rule.RuleType,
-				CurrentValue:   currentValue,
-				ThresholdValue: thresholdBytes,
-				Message:        fmt.Sprintf("代理 %s 的%s流量已超过阈值", proxy.Name, s.getRuleTypeName(rule.RuleType)),
-			}
-			if err := s.alertRepo.CreateAlert(alert); err == nil {
-				s.sendNotification(alert, &rule, proxy.Name)
-			}
+		alert := &model.AlertLog{
+			RuleID:         rule.ID,
+			TargetType:     model.AlertTargetProxy,
+			TargetID:       rule.ProxyID,
+			ProxyID:        rule.ProxyID,
+			AlertType:      rule.RuleType,
+			CurrentValue:   eval.current,
+			ThresholdValue: eval.threshold,
+			Message:        eval.message,
+			Silenced:       s.isSilenced(&rule, now),
+			CreatedAt:      now,
+		}
+		if err := s.alertRepo.CreateAlert(alert); err != nil {
+			continue
+		}
+		publishAlertLog(alert)
+		if !alert.Silenced {
+			s.sendNotification(alert, &rule, proxy.Name)
+			s.startEscalation(alert, &rule)
 		}
 	}
 }
 
-func (s *AlertService) shouldSkipAlert(ruleID uint) bool {
-	recentAlert, err := s.alertRepo.GetRecentAlert(ruleID, 1*time.Hour)
-	return err == nil && recentAlert != nil
+// publishAlertLog 发布告警触发或恢复事件，供日志转发等订阅方使用
+func publishAlertLog(alert *model.AlertLog) {
+	publishAlertTransition(alert, "", alert.CreatedAt)
+}
+
+// publishAlertTransition 发布告警状态变化事件，如确认、取消确认、手动结束、升级和解除抑制；
+// 同步发布，使订阅方按发生顺序收到同一告警的事件
+func publishAlertTransition(alert *model.AlertLog, action string, at time.Time) {
+	events.GetEventBus().PublishSync(events.AlertLogEvent{
+		AlertID:         alert.ID,
+		RuleID:          alert.RuleID,
+		TargetType:      string(alert.TargetType),
+		TargetID:        alert.TargetID,
+		AlertType:       alert.AlertType,
+		Action:          action,
+		Status:          alert.Status,
+		Message:         alert.Message,
+		Silenced:        alert.Silenced,
+		SuppressedBy:    alert.SuppressedBy,
+		EscalationLevel: alert.EscalationLevel,
+		Timestamp:       at,
+	})
 }
 
-func (s *AlertService) sendNotification(alert *model.AlertLog, rule *model.AlertRule, proxyName string) {
-	emails := s.getNotifyEmails(rule)
-	for _, email := range emails {
-		go s.sendEmail(email, alert, proxyName)
+// isSilenced 检查规则目标在指定时间是否处于静默期
+func (s *AlertService) isSilenced(rule *model.AlertRule, at time.Time) bool {
+	if s.silenceService == nil {
+		return false
+	}
+	targetID := rule.TargetID
+	if targetID == 0 && rule.TargetType == model.AlertTargetProxy {
+		targetID = rule.ProxyID
+	}
+	return s.silenceService.MatchSilence(rule.TargetType, targetID, rule.ID, at) != nil
+}
+
+// resolveRuleAlerts 规则恢复正常时结束其未结束的告警
+func (s *AlertService) resolveRuleAlerts(rule *model.AlertRule, now time.Time) {
+	if n, err := s.alertRepo.ResolveOpenAlerts(rule.ID, now); err != nil {
+		logger.Errorf("[告警] 结束规则 %d 的告警失败: %v", rule.ID, err)
+	} else if n > 0 {
+		logger.Infof("[告警] 规则 %d 已恢复正常，结束 %d 条告警", rule.ID, n)
+	}
+}
+
+// trafficEvaluation 流量规则的计算结果
+type trafficEvaluation struct {
+	current     int64
+	threshold   int64
+	exceeded    bool
+	windowStart time.Time // 日/月/配额规则的统计窗口开始时间，速率规则为零值
+	message     string
+}
+
+// evaluateTrafficRule 计算规则当前值和阈值，无法计算时返回 false
+// 日/月规则统计当前自然日、自然月的流量增量，配额规则按关联配额的已用百分比判断
+func (s *AlertService) evaluateTrafficRule(rule *model.AlertRule, proxy *model.Proxy, now time.Time, usage map[string]map[string]int64) (*trafficEvaluation, bool) {
+	switch rule.RuleType {
+	case "rate":
+		eval := &trafficEvaluation{
+			current:   proxy.CurrentBytesInRate + proxy.CurrentBytesOutRate,
+			threshold: s.convertToBytes(rule.ThresholdValue, rule.ThresholdUnit),
+		}
+		eval.exceeded = eval.current > eval.threshold
+		eval.message = fmt.Sprintf("代理 %s 的实时速率流量已超过阈值", proxy.Name)
+		return eval, true
+
+	case "daily", "monthly":
+		loc, err := alertRuleLocation(rule)
+		if err != nil {
+			logger.Warnf("[告警] 规则 %d 时区无效: %s", rule.ID, rule.Timezone)
+			return nil, false
+		}
+		start := trafficWindowStart(rule.RuleType, now, loc).Local()
+		current, err := s.proxyUsage(proxy, start, now, usage)
+		if err != nil {
+			logger.Errorf("[告警] 统计代理 %s 流量失败: %v", proxy.Name, err)
+			return nil, false
+		}
+		eval := &trafficEvaluation{
+			current:     current,
+			threshold:   s.convertToBytes(rule.ThresholdValue, rule.ThresholdUnit),
+			windowStart: start,
+		}
+		eval.exceeded = eval.current > eval.threshold
+		eval.message = fmt.Sprintf("代理 %s 的%s流量已超过阈值", proxy.Name, s.getRuleTypeName(rule.RuleType))
+		return eval, true
+
+	case "quota":
+		quota := s.findProxyQuota(proxy)
+		if quota == nil || quota.LimitBytes <= 0 {
+			return nil, false
+		}
+		eval := &trafficEvaluation{
+			current:     quota.UsedBytes,
+			threshold:   quota.LimitBytes * rule.ThresholdValue / 100,
+			windowStart: quota.PeriodStart,
+		}
+		eval.exceeded = rule.ThresholdValue > 0 && eval.current >= eval.threshold
+		eval.message = fmt.Sprintf("代理 %s 的流量配额已使用 %.1f%%，达到告警阈值 %d%%",
+			proxy.Name, float64(quota.UsedBytes)*100/float64(quota.LimitBytes), rule.ThresholdValue)
+		return eval, true
+	}
+	return nil, false
+}
+
+// alertRuleLocation 获取规则的统计时区，为空时使用服务器时区
+func alertRuleLocation(rule *model.AlertRule) (*time.Location, error) {
+	if rule.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(rule.Timezone)
+}
+
+// trafficWindowStart 计算 now 所在自然日或自然月在指定时区的开始时间
+func trafficWindowStart(ruleType string, now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	if ruleType == "monthly" {
+		return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
+
+// proxyUsage 统计代理在时间范围内的入站+出站流量，同一检查周期内相同时间范围只查询一次
+func (s *AlertService) proxyUsage(proxy *model.Proxy, start, end time.Time, usage map[string]map[string]int64) (int64, error) {
+	if s.clientRepo == nil {
+		return 0, nil
+	}
+	client, err := s.clientRepo.FindByID(proxy.ClientID)
+	if err != nil || client.FrpServerID == nil {
+		return 0, nil
+	}
+
+	rangeKey := fmt.Sprintf("%d-%d", start.Unix(), end.Unix())
+	totals, ok := usage[rangeKey]
+	if !ok {
+		usages, err := s.metricsRollup.ProxyTrafficUsage(start, end)
+		if err != nil {
+			return 0, err
+		}
+		totals = make(map[string]int64, len(usages))
+		for _, u := range usages {
+			totals[fmt.Sprintf("%d:%s", u.ServerID, u.ProxyName)] += u.DeltaIn + u.DeltaOut
+		}
+		usage[rangeKey] = totals
+	}
+
+	// FRP 使用 clientName.proxyName 格式，短名称兼容旧数据
+	return totals[fmt.Sprintf("%d:%s.%s", *client.FrpServerID, client.Name, proxy.Name)] +
+		totals[fmt.Sprintf("%d:%s", *client.FrpServerID, proxy.Name)], nil
+}
+
+// findProxyQuota 获取代理适用的流量配额，代理配额优先于所属客户端的配额
+func (s *AlertService) findProxyQuota(proxy *model.Proxy) *model.TrafficQuota {
+	if quota, err := s.quotaRepo.FindEnabledByTarget(model.QuotaTargetProxy, proxy.ID); err == nil {
+		return quota
+	}
+	if quota, err := s.quotaRepo.FindEnabledByTarget(model.QuotaTargetClient, proxy.ClientID); err == nil {
+		return quota
+	}
+	return nil
+}
+
+// inCooldown 冷却时间内已告警，或在当前统计窗口内已告警过时跳过
+// 日/月/配额规则每个窗口最多告警一次，速率规则只受冷却时间限制
+func (s *AlertService) inCooldown(rule *model.AlertRule, windowStart, now time.Time) bool {
+	since := windowStart
+	if rule.CooldownMinutes > 0 {
+		cooldownStart := now.Add(-time.Duration(rule.CooldownMinutes) * time.Minute)
+		if since.IsZero() || cooldownStart.Before(since) {
+			since = cooldownStart
+		}
 	}
+	if since.IsZero() {
+		return false
+	}
+	recent, err := s.alertRepo.GetRuleAlertSince(rule.ID, since)
+	return err == nil && recent != nil
+}
+
+func (s *AlertService) sendNotification(alert *model.AlertLog, rule *model.AlertRule, proxyName string) {
 	if rule.NotifyWebhook != "" {
-		go s.sendWebhook(rule.NotifyWebhook, alert, proxyName)
+		go s.sendWebhook(rule, alert, proxyName)
+	}
+	data := alertTemplateData(model.MessageEventTraffic, alert, proxyName)
+	s.dispatch(rule, s.digestGroup(model.AlertTargetProxy, alert.ProxyID),
+		s.templates.Compose(model.MessageEventTraffic, alert.CreatedAt, data))
+	s.markNotified(alert, rule)
+}
+
+// markNotified 未配置 Webhook 时立即标记已通知，配置了 Webhook 时由投递成功后标记
+func (s *AlertService) markNotified(alert *model.AlertLog, rule *model.AlertRule) {
+	if rule.NotifyWebhook == "" || s.webhookService == nil {
+		s.alertRepo.MarkAsNotified(alert.ID)
+	}
+}
+
+// startEscalation 规则配置了升级策略时通知第1级并开始升级计时
+func (s *AlertService) startEscalation(alert *model.AlertLog, rule *model.AlertRule) {
+	if s.escalationService != nil {
+		s.escalationService.Start(alert, rule)
+	}
+}
+
+// enqueueWebhook 将 Webhook 加入可靠投递队列
+func (s *AlertService) enqueueWebhook(rule *model.AlertRule, alert *model.AlertLog, targetName string, payload map[string]interface{}) {
+	if s.webhookService == nil {
+		return
+	}
+	if _, err := s.webhookService.Enqueue(rule.NotifyWebhook, rule, alert, targetName, payload); err != nil {
+		logger.Errorf("[告警] Webhook 入队失败: %v", err)
+	}
+}
+
+// dispatch 向规则的接收人邮箱和通知渠道发送按模板渲染的消息，经通知汇总合并及限流后发送
+// Webhook 按规则单独可靠投递，不参与汇总
+func (s *AlertService) dispatch(rule *model.AlertRule, group DigestGroup, msg *ComposedMessage) {
+	if s.digest == nil {
+		return
+	}
+	n := &DigestNotification{Group: group, Message: msg.Message, Emails: s.getNotifyEmails(rule), SendEmail: msg.EmailSender(s.emailService)}
+	if s.notificationService != nil {
+		n.ChannelIDs = s.notificationService.ChannelIDsForRule(rule)
+	}
+	s.digest.Submit(n)
+}
+
+// digestGroup 告警的汇总分组，见 digestGrouper
+func (s *AlertService) digestGroup(targetType model.AlertTargetType, targetID uint) DigestGroup {
+	return s.grouper().group(targetType, targetID)
+}
+
+// clientServerID 获取客户端所属的 frps，未知时返回0
+func (s *AlertService) clientServerID(clientID uint) uint {
+	return s.grouper().clientServerID(clientID)
+}
+
+func (s *AlertService) grouper() digestGrouper {
+	return digestGrouper{clientRepo: s.clientRepo, proxyRepo: s.proxyRepo, probeRepo: s.probeRepo, frpServerRepo: s.frpServerRepo}
+}
+
+// digestGrouper 计算告警的汇总分组：frps 及其下的 frpc、代理、拨测告警按 frps 分组，
+// 使 frps 重启时同一接收方只收到一条汇总消息；无法确定所属 frps 时按目标类型分组
+type digestGrouper struct {
+	clientRepo    *repository.ClientRepository
+	proxyRepo     *repository.ProxyRepository
+	probeRepo     *repository.ProxyProbeRepository
+	frpServerRepo *repository.FrpServerRepository
+}
+
+func (g digestGrouper) group(targetType model.AlertTargetType, targetID uint) DigestGroup {
+	var serverID uint
+	switch targetType {
+	case model.AlertTargetFrps:
+		serverID = targetID
+	case model.AlertTargetFrpc:
+		serverID = g.clientServerID(targetID)
+	case model.AlertTargetProxy:
+		serverID = g.proxyServerID(targetID)
+	case model.AlertTargetProbe:
+		if g.probeRepo != nil {
+			if probe, err := g.probeRepo.FindByID(targetID); err == nil {
+				serverID = g.proxyServerID(probe.ProxyID)
+			}
+		}
+	}
+	if serverID == 0 {
+		return DigestGroup{Key: "type:" + string(targetType), Name: string(targetType)}
+	}
+
+	name := fmt.Sprintf("#%d", serverID)
+	if g.frpServerRepo != nil {
+		if server, err := g.frpServerRepo.GetByID(serverID); err == nil {
+			name = server.Name
+		}
 	}
-	s.alertRepo.MarkAsNotified(alert.ID)
+	return DigestGroup{Key: fmt.Sprintf("server:%d", serverID), Name: "frps " + name}
+}
+
+// clientServerID 获取客户端所属的 frps，未知时返回0
+func (g digestGrouper) clientServerID(clientID uint) uint {
+	if g.clientRepo == nil {
+		return 0
+	}
+	client, err := g.clientRepo.FindByID(clientID)
+	if err != nil || client.FrpServerID == nil {
+		return 0
+	}
+	return *client.FrpServerID
+}
+
+// proxyServerID 获取代理所属的 frps，未知时返回0
+func (g digestGrouper) proxyServerID(proxyID uint) uint {
+	if g.proxyRepo == nil {
+		return 0
+	}
+	proxy, err := g.proxyRepo.FindByID(proxyID)
+	if err != nil {
+		return 0
+	}
+	return g.clientServerID(proxy.ClientID)
 }
 
 // getNotifyEmails 获取规则的所有通知邮箱
@@ -135,38 +478,15 @@ func parseIDList(str string) []uint {
 	return ids
 }
 
-func (s *AlertService) sendEmail(to string, alert *model.AlertLog, proxyName string) error {
-	subject := fmt.Sprintf("FRP流量告警 - %s", proxyName)
-	html, _, err := GenerateTrafficAlertEmail(TrafficAlertData{
-		ProxyName:    proxyName,
-		AlertType:    s.getRuleTypeName(alert.AlertType),
-		CurrentValue: formatBytes(alert.CurrentValue),
-		Threshold:    formatBytes(alert.ThresholdValue),
-		Time:         alert.CreatedAt,
-	})
-	if err != nil {
-		return err
-	}
-	return s.emailService.SendHTMLEmail(to, subject, html, "text/html")
-}
-
-func (s *AlertService) sendWebhook(url string, alert *model.AlertLog, proxyName string) error {
-	payload := map[string]interface{}{
+func (s *AlertService) sendWebhook(rule *model.AlertRule, alert *model.AlertLog, proxyName string) {
+	s.enqueueWebhook(rule, alert, proxyName, map[string]interface{}{
 		"proxy_name":      proxyName,
 		"alert_type":      alert.AlertType,
 		"current_value":   alert.CurrentValue,
 		"threshold_value": alert.ThresholdValue,
 		"message":         alert.Message,
 		"timestamp":       alert.CreatedAt.Unix(),
-	}
-
-	jsonData, _ := json.Marshal(payload)
-	resp, err := httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	})
 }
 
 func formatBytes(bytes int64) string {
@@ -203,12 +523,44 @@ func (s *AlertService) getRuleTypeName(ruleType string) string {
 		return "每月"
 	case "rate":
 		return "实时速率"
+	case "quota":
+		return "配额使用率"
 	default:
 		return ""
 	}
 }
 
+// validateRule 校验时区、配额百分比、指标表达式及 Webhook 模板
+func validateRule(rule *model.AlertRule) error {
+	if _, err := alertRuleLocation(rule); err != nil {
+		return fmt.Errorf("无效的时区: %s", rule.Timezone)
+	}
+	if rule.RuleType == model.RuleTypeMetric {
+		rule.Expression = strings.TrimSpace(rule.Expression)
+		expr, err := ParseAlertExpression(rule.Expression)
+		if err != nil {
+			return err
+		}
+		if rule.TargetType != expr.Target() {
+			return fmt.Errorf("指标 %s 只能用于 %s 类型的目标", expr.Metric, expr.Target())
+		}
+	}
+	if rule.RuleType == "quota" {
+		if rule.ThresholdValue <= 0 || rule.ThresholdValue > 1000 {
+			return fmt.Errorf("配额使用率阈值应为 1-1000 的百分比")
+		}
+		rule.ThresholdUnit = "percent"
+	}
+	return ValidateWebhookTemplate(rule.WebhookTemplate)
+}
+
 func (s *AlertService) CreateRule(rule *model.AlertRule) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+	if err := s.escalationService.ValidatePolicyRef(rule.EscalationPolicyID); err != nil {
+		return err
+	}
 	return s.alertRepo.CreateRule(rule)
 }
 
@@ -217,6 +569,12 @@ func (s *AlertService) GetRulesByProxyID(proxyID uint) ([]model.AlertRule, error
 }
 
 func (s *AlertService) UpdateRule(rule *model.AlertRule) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+	if err := s.escalationService.ValidatePolicyRef(rule.EscalationPolicyID); err != nil {
+		return err
+	}
 	return s.alertRepo.UpdateRule(rule)
 }
 
@@ -232,10 +590,65 @@ func (s *AlertService) GetAlertLogs(limit int) ([]model.AlertLog, error) {
 	return s.alertRepo.GetAlertLogs(limit)
 }
 
-// CheckOfflineAlerts 检查 frpc 和 frps 离线告警
+// GetAlertLogsByStatus 按状态获取告警日志，status 为 open 时返回触发中和已确认的告警
+func (s *AlertService) GetAlertLogsByStatus(status string, limit int) ([]model.AlertLog, error) {
+	switch status {
+	case "open", model.AlertStatusFiring, model.AlertStatusAcknowledged, model.AlertStatusResolved:
+		return s.alertRepo.GetAlertLogsByStatus(status, limit)
+	default:
+		return nil, fmt.Errorf("不支持的告警状态: %s", status)
+	}
+}
+
+// AcknowledgeAlert 确认触发中的告警，记录确认人
+func (s *AlertService) AcknowledgeAlert(id, userID uint, username string) (*model.AlertLog, error) {
+	now := s.now()
+	return s.transitionAlert(id, "acknowledged", now, "只能确认触发中的告警", func() (bool, error) {
+		return s.alertRepo.AcknowledgeAlert(id, userID, username, now)
+	})
+}
+
+// UnacknowledgeAlert 取消确认，告警恢复为触发中
+func (s *AlertService) UnacknowledgeAlert(id uint) (*model.AlertLog, error) {
+	return s.transitionAlert(id, "unacknowledged", s.now(), "只能取消确认已确认的告警", func() (bool, error) {
+		return s.alertRepo.UnacknowledgeAlert(id)
+	})
+}
+
+// ResolveAlert 手动结束告警，用于不会自动恢复的系统告警
+func (s *AlertService) ResolveAlert(id uint) (*model.AlertLog, error) {
+	now := s.now()
+	return s.transitionAlert(id, "resolved", now, "告警已结束", func() (bool, error) {
+		return s.alertRepo.ResolveAlert(id, now)
+	})
+}
+
+// transitionAlert 更新告警状态，成功后发布状态变化事件
+func (s *AlertService) transitionAlert(id uint, action string, at time.Time, invalidMsg string, update func() (bool, error)) (*model.AlertLog, error) {
+	if _, err := s.alertRepo.FindAlertByID(id); err != nil {
+		return nil, fmt.Errorf("告警不存在")
+	}
+	updated, err := update()
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("%s", invalidMsg)
+	}
+	alert, err := s.alertRepo.FindAlertByID(id)
+	if err != nil {
+		return nil, err
+	}
+	publishAlertTransition(alert, action, at)
+	return alert, nil
+}
+
+// CheckOfflineAlerts 检查 frps、frpc 离线告警和拨测告警，按依赖关系从上级到下级检查，
+// 使上级的告警和恢复先于下级处理
 func (s *AlertService) CheckOfflineAlerts() {
-	s.checkFrpcOfflineAlerts()
 	s.checkFrpsOfflineAlerts()
+	s.checkFrpcOfflineAlerts()
+	s.checkProbeAlerts()
 }
 
 // checkFrpcOfflineAlerts 检查 frpc 离线告警（带延迟确认）
@@ -251,6 +664,9 @@ func (s *AlertService) checkFrpcOfflineAlerts() {
 	}
 
 	for _, rule := range rules {
+		if rule.RuleType == model.RuleTypeMetric {
+			continue
+		}
 		client, err := s.clientRepo.FindByID(rule.TargetID)
 		if err != nil {
 			continue
@@ -276,6 +692,9 @@ func (s *AlertService) checkFrpsOfflineAlerts() {
 	}
 
 	for _, rule := range rules {
+		if rule.RuleType == model.RuleTypeMetric {
+			continue
+		}
 		server, err := s.frpServerRepo.GetByID(rule.TargetID)
 		if err != nil {
 			continue
@@ -288,6 +707,33 @@ func (s *AlertService) checkFrpsOfflineAlerts() {
 	}
 }
 
+// checkProbeAlerts 检查拨测失败告警（带延迟确认），以最近一次拨测结果判断
+func (s *AlertService) checkProbeAlerts() {
+	if s.probeRepo == nil {
+		return
+	}
+
+	rules, err := s.alertRepo.GetEnabledRulesByTargetType(model.AlertTargetProbe)
+	if err != nil {
+		logger.Errorf("离线告警 获取拨测告警规则失败: %v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		probe, err := s.probeRepo.FindByID(rule.TargetID)
+		if err != nil || !probe.Enabled || probe.LastCheckedAt == nil {
+			continue
+		}
+
+		proxyName := ""
+		if proxy, err := s.proxyRepo.FindByID(probe.ProxyID); err == nil {
+			proxyName = proxy.Name
+		}
+		targetKey := fmt.Sprintf("probe:%d", rule.TargetID)
+		s.handleOfflineState(targetKey, !probe.LastSuccess, &rule, ProbeDisplayName(probe, proxyName), "probe")
+	}
+}
+
 // handleOfflineState 处理离线状态（延迟确认 + 恢复通知）
 func (s *AlertService) handleOfflineState(targetKey string, isOffline bool, rule *model.AlertRule, targetName, targetType string) {
 	s.stateMutex.Lock()
@@ -314,18 +760,32 @@ func (s *AlertService) handleOfflineState(targetKey string, isOffline bool, rule
 		}
 
 		// 检查是否已发送告警
+		alertTargetType := model.AlertTargetType(targetType)
+		grace := time.Duration(delaySeconds) * time.Second
+		// 被上级抑制的告警从数据库读取，服务重启后仍能在上级结束后补发通知
+		if alert, err := s.alertRepo.GetOpenSuppressedAlert(rule.ID, rule.TargetID); err == nil {
+			s.alertingState[targetKey] = true
+			if s.suppressingAlert(s.alertAncestors(alertTargetType, rule.TargetID), grace, time.Now()) != nil {
+				return
+			}
+			if s.releaseSuppressed(alert) {
+				s.sendOfflineNotification(alert, rule, targetName, targetType)
+				s.startEscalation(alert, rule)
+			}
+			return
+		}
 		if s.alertingState[targetKey] {
 			return // 已发送告警，等待恢复
 		}
 
+		// 上级仍在离线延迟确认期内时等待，避免下级先于上级告警
+		ancestors := s.alertAncestors(alertTargetType, rule.TargetID)
+		if s.ancestorPendingUnlocked(ancestors) {
+			return
+		}
+
 		// 检查冷却时间
 		cooldown := time.Duration(rule.CooldownMinutes) * time.Minute
-		var alertTargetType model.AlertTargetType
-		if targetType == "frpc" {
-			alertTargetType = model.AlertTargetFrpc
-		} else {
-			alertTargetType = model.AlertTargetFrps
-		}
 		if s.shouldSkipAlertByTargetUnlocked(alertTargetType, rule.TargetID, cooldown) {
 			return
 		}
@@ -339,10 +799,28 @@ func (s *AlertService) handleOfflineState(targetKey string, isOffline bool, rule
 			AlertType:  "offline",
 			Message:    fmt.Sprintf("%s %s 已离线（持续 %d 秒）", targetType, targetName, int(time.Since(firstOfflineTime).Seconds())),
 		}
+		if alertTargetType == model.AlertTargetProbe {
+			alert.Message = fmt.Sprintf("拨测 %s 持续失败（持续 %d 秒）", targetName, int(time.Since(firstOfflineTime).Seconds()))
+			if probe, err := s.probeRepo.FindByID(rule.TargetID); err == nil && probe.LastError != "" {
+				alert.Message += ": " + probe.LastError
+			}
+		}
+		alert.Silenced = s.isSilenced(rule, time.Now())
+		if parent := s.suppressingAlert(ancestors, grace, time.Now()); parent != nil {
+			alert.SuppressedBy = parent.ID
+		}
 		if err := s.alertRepo.CreateAlert(alert); err == nil {
-			s.sendOfflineNotification(alert, rule, targetName, targetType)
+			publishAlertLog(alert)
 			s.alertingState[targetKey] = true
-			logger.Infof("离线告警 %s %s 离线告警已发送", targetType, targetName)
+			if alert.SuppressedBy > 0 {
+				logger.Infof("离线告警 %s %s 的上级处于告警中，已归并到上级告警 #%d，暂不发送通知", targetType, targetName, alert.SuppressedBy)
+			} else if alert.Silenced {
+				logger.Infof("离线告警 %s %s 处于静默期，已记录告警但不发送通知", targetType, targetName)
+			} else {
+				s.sendOfflineNotification(alert, rule, targetName, targetType)
+				s.startEscalation(alert, rule)
+				logger.Infof("离线告警 %s %s 离线告警已发送", targetType, targetName)
+			}
 		}
 	} else {
 		// 目标在线
@@ -353,16 +831,50 @@ func (s *AlertService) handleOfflineState(targetKey string, isOffline bool, rule
 		delete(s.pendingOffline, targetKey)
 		delete(s.alertingState, targetKey)
 
-		// 如果之前已发送告警且启用了恢复通知，发送恢复通知
-		if wasAlerting && rule.NotifyOnRecovery {
-			s.sendRecoveryNotification(rule, targetName, targetType)
-			logger.Infof("离线告警 %s %s 已恢复在线，恢复通知已发送", targetType, targetName)
-		} else if wasPending {
-			logger.Infof("离线告警 %s %s 在延迟确认期内恢复，取消告警", targetType, targetName)
+		if !wasAlerting {
+			if wasPending {
+				logger.Infof("离线告警 %s %s 在延迟确认期内恢复，取消告警", targetType, targetName)
+			}
+			return
+		}
+
+		// 结束离线告警；离线告警未发送过通知或当前处于静默期时不发送恢复通知
+		openAlerts, _ := s.alertRepo.GetOpenAlertsByRule(rule.ID)
+		s.resolveRuleAlerts(rule, time.Now())
+		publishAlertLog(&model.AlertLog{
+			RuleID:     rule.ID,
+			TargetType: model.AlertTargetType(targetType),
+			TargetID:   rule.TargetID,
+			AlertType:  "recovery",
+			Status:     model.AlertStatusResolved,
+			Message:    fmt.Sprintf("%s %s 已恢复在线", targetType, targetName),
+			CreatedAt:  time.Now(),
+		})
+		if !rule.NotifyOnRecovery {
+			return
+		}
+		if s.isSilenced(rule, time.Now()) || allUnnotified(openAlerts) {
+			logger.Infof("离线告警 %s %s 已恢复在线，告警处于静默期或被上级抑制，不发送恢复通知", targetType, targetName)
+			return
 		}
+		s.sendRecoveryNotification(rule, targetName, targetType)
+		logger.Infof("离线告警 %s %s 已恢复在线，恢复通知已发送", targetType, targetName)
 	}
 }
 
+// allUnnotified 告警均在静默期内触发或被上级告警抑制，即均未发送过通知
+func allUnnotified(alerts []model.AlertLog) bool {
+	if len(alerts) == 0 {
+		return false
+	}
+	for _, a := range alerts {
+		if !a.Silenced && a.SuppressedBy == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // shouldSkipAlertByTargetUnlocked 检查是否应跳过告警（不加锁版本）
 func (s *AlertService) shouldSkipAlertByTargetUnlocked(targetType model.AlertTargetType, targetID uint, cooldown time.Duration) bool {
 	recentAlert, err := s.alertRepo.GetRecentAlertByTarget(targetType, targetID, cooldown)
@@ -371,87 +883,57 @@ func (s *AlertService) shouldSkipAlertByTargetUnlocked(targetType model.AlertTar
 
 // sendRecoveryNotification 发送恢复通知
 func (s *AlertService) sendRecoveryNotification(rule *model.AlertRule, targetName, targetType string) {
-	emails := s.getNotifyEmails(rule)
-	for _, email := range emails {
-		go s.sendRecoveryEmail(email, targetName, targetType)
-	}
 	if rule.NotifyWebhook != "" {
-		go s.sendRecoveryWebhook(rule.NotifyWebhook, targetName, targetType)
-	}
-}
-
-func (s *AlertService) sendRecoveryEmail(to, targetName, targetType string) error {
-	subject := fmt.Sprintf("FRP恢复通知 - %s %s 已恢复在线", targetType, targetName)
-	html, _, err := GenerateRecoveryAlertEmail(RecoveryAlertData{
-		TargetType: targetType,
-		TargetName: targetName,
-		Time:       time.Now(),
-	})
-	if err != nil {
-		return err
-	}
-	return s.emailService.SendHTMLEmail(to, subject, html, "text/html")
-}
-
-func (s *AlertService) sendRecoveryWebhook(url, targetName, targetType string) error {
-	payload := map[string]interface{}{
+		go s.sendRecoveryWebhook(rule, targetName, targetType)
+	}
+	recovery := &model.AlertLog{
+		TargetType: model.AlertTargetType(targetType),
+		TargetID:   rule.TargetID,
+		AlertType:  "recovery",
+		Message:    fmt.Sprintf("%s %s 已恢复在线", targetType, targetName),
+	}
+	data := alertTemplateData(model.MessageEventRecovery, recovery, targetName)
+	s.dispatch(rule, s.digestGroup(recovery.TargetType, rule.TargetID),
+		s.templates.Compose(model.MessageEventRecovery, time.Now(), data))
+}
+
+// sendRecoveryWebhook 恢复通知没有告警日志，投递记录的告警日志ID为0
+func (s *AlertService) sendRecoveryWebhook(rule *model.AlertRule, targetName, targetType string) {
+	alert := &model.AlertLog{
+		RuleID:     rule.ID,
+		TargetType: model.AlertTargetType(targetType),
+		TargetID:   rule.TargetID,
+		AlertType:  "recovery",
+		Message:    fmt.Sprintf("%s %s 已恢复在线", targetType, targetName),
+		CreatedAt:  time.Now(),
+	}
+	s.enqueueWebhook(rule, alert, targetName, map[string]interface{}{
 		"target_type": targetType,
 		"target_name": targetName,
 		"alert_type":  "recovery",
-		"message":     fmt.Sprintf("%s %s 已恢复在线", targetType, targetName),
-		"timestamp":   time.Now().Unix(),
-	}
-
-	jsonData, _ := json.Marshal(payload)
-	resp, err := httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+		"message":     alert.Message,
+		"timestamp":   alert.CreatedAt.Unix(),
+	})
 }
 
 func (s *AlertService) sendOfflineNotification(alert *model.AlertLog, rule *model.AlertRule, targetName, targetType string) {
-	emails := s.getNotifyEmails(rule)
-	for _, email := range emails {
-		go s.sendOfflineEmail(email, alert, targetName, targetType)
-	}
 	if rule.NotifyWebhook != "" {
-		go s.sendOfflineWebhook(rule.NotifyWebhook, alert, targetName, targetType)
-	}
-	s.alertRepo.MarkAsNotified(alert.ID)
-}
-
-func (s *AlertService) sendOfflineEmail(to string, alert *model.AlertLog, targetName, targetType string) error {
-	subject := fmt.Sprintf("FRP离线告警 - %s %s", targetType, targetName)
-	html, _, err := GenerateOfflineAlertEmail(OfflineAlertData{
-		TargetType: targetType,
-		TargetName: targetName,
-		Message:    alert.Message,
-		Time:       alert.CreatedAt,
-	})
-	if err != nil {
-		return err
+		go s.sendOfflineWebhook(rule, alert, targetName, targetType)
 	}
-	return s.emailService.SendHTMLEmail(to, subject, html, "text/html")
+	data := alertTemplateData(model.MessageEventOffline, alert, targetName)
+	s.dispatch(rule, s.digestGroup(alert.TargetType, alert.TargetID),
+		s.templates.Compose(model.MessageEventOffline, alert.CreatedAt, data))
+	s.markNotified(alert, rule)
 }
 
-func (s *AlertService) sendOfflineWebhook(url string, alert *model.AlertLog, targetName, targetType string) error {
-	payload := map[string]interface{}{
+func (s *AlertService) sendOfflineWebhook(rule *model.AlertRule, alert *model.AlertLog, targetName, targetType string) {
+	s.enqueueWebhook(rule, alert, targetName, map[string]interface{}{
 		"target_type": targetType,
 		"target_name": targetName,
 		"alert_type":  "offline",
 		"message":     alert.Message,
 		"timestamp":   alert.CreatedAt.Unix(),
-	}
-
-	jsonData, _ := json.Marshal(payload)
-	resp, err := httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	})
 }
 
 // GetRulesByTargetType 根据目标类型获取规则
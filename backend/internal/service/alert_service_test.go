@@ -2,10 +2,14 @@ package service
 
 import (
 	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestConvertToBytes 测试阈值单位转换
@@ -183,4 +187,428 @@ func TestAlertService_NilRepos(t *testing.T) {
 		s.checkFrpsOfflineAlerts()
 		assert.True(t, true)
 	})
+
+	t.Run("空probeRepo不panic", func(t *testing.T) {
+		s := &AlertService{
+			probeRepo:      nil,
+			pendingOffline: make(map[string]time.Time),
+			alertingState:  make(map[string]bool),
+		}
+		s.checkProbeAlerts()
+		assert.True(t, true)
+	})
+}
+
+// TestTrafficWindowStart 测试自然日、自然月窗口按时区对齐
+func TestTrafficWindowStart(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	newYork, _ := time.LoadLocation("America/New_York")
+	now := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		ruleType string
+		loc      *time.Location
+		expected time.Time
+	}{
+		{"上海自然日", "daily", shanghai, time.Date(2026, 3, 1, 0, 0, 0, 0, shanghai)},
+		{"纽约自然日为前一天", "daily", newYork, time.Date(2026, 2, 28, 0, 0, 0, 0, newYork)},
+		{"上海自然月", "monthly", shanghai, time.Date(2026, 3, 1, 0, 0, 0, 0, shanghai)},
+		{"纽约自然月为上月", "monthly", newYork, time.Date(2026, 2, 1, 0, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(trafficWindowStart(tt.ruleType, now, tt.loc)))
+		})
+	}
+}
+
+func setupTrafficAlertTestDB(t *testing.T) *model.Proxy {
+	newServiceTestDB(t, &model.Setting{}, &model.Client{}, &model.Proxy{},
+		&model.AlertRule{}, &model.AlertLog{}, &model.ProxyMetricsHistory{}, &model.ProxyMetricsRollup{},
+		&model.TrafficQuota{})
+
+	serverID := uint(1)
+	client := &model.Client{Name: "c1", FrpServerID: &serverID}
+	require.NoError(t, database.DB.Create(client).Error)
+	proxy := &model.Proxy{ClientID: client.ID, Name: "web", Type: "tcp", Enabled: true,
+		TotalBytesIn: 100 << 30, CurrentBytesInRate: 2 << 20}
+	require.NoError(t, database.DB.Create(proxy).Error)
+	return proxy
+}
+
+// TestAlertService_CheckAlerts 测试流量告警按自然窗口统计、冷却时间及配额百分比阈值
+func TestAlertService_CheckAlerts(t *testing.T) {
+	// 2026-03-15 02:00 UTC：上海 3月15日10:00，纽约 3月14日22:00
+	now := time.Date(2026, 3, 15, 2, 0, 0, 0, time.UTC)
+	const gb = int64(1 << 30)
+	history := []struct {
+		at    time.Time
+		bytes int64
+	}{
+		{time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC), 50 * gb}, // 上月
+		{time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), 1 * gb},    // 本月
+		{time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC), 5 * gb},  // 纽约当天，上海前一天
+		{time.Date(2026, 3, 15, 1, 0, 0, 0, time.UTC), 2 * gb},   // 两个时区均为当天
+	}
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		name          string
+		rule          model.AlertRule
+		quota         *model.TrafficQuota
+		lastAlertAt   *time.Time
+		expectAlert   bool
+		expectCurrent int64
+	}{
+		{"上海当日流量未超阈值", model.AlertRule{RuleType: "daily", ThresholdValue: 3, ThresholdUnit: "GB", Timezone: "Asia/Shanghai"},
+			nil, nil, false, 0},
+		{"纽约当日包含更早的流量", model.AlertRule{RuleType: "daily", ThresholdValue: 3, ThresholdUnit: "GB", Timezone: "America/New_York"},
+			nil, nil, true, 7 * gb},
+		{"上海当日超出阈值", model.AlertRule{RuleType: "daily", ThresholdValue: 1, ThresholdUnit: "GB", Timezone: "Asia/Shanghai"},
+			nil, nil, true, 2 * gb},
+		{"自然月不计上月流量", model.AlertRule{RuleType: "monthly", ThresholdValue: 10, ThresholdUnit: "GB", Timezone: "Asia/Shanghai"},
+			nil, nil, false, 0},
+		{"自然月超出阈值", model.AlertRule{RuleType: "monthly", ThresholdValue: 5, ThresholdUnit: "GB", Timezone: "Asia/Shanghai"},
+			nil, nil, true, 8 * gb},
+		{"当日窗口内已告警过不再告警", model.AlertRule{RuleType: "daily", ThresholdValue: 1, ThresholdUnit: "GB", Timezone: "Asia/Shanghai", CooldownMinutes: 60},
+			nil, ago(9*time.Hour + 30*time.Minute), false, 0},
+		{"新窗口且冷却结束后再次告警", model.AlertRule{RuleType: "daily", ThresholdValue: 1, ThresholdUnit: "GB", Timezone: "Asia/Shanghai", CooldownMinutes: 60},
+			nil, ago(10*time.Hour + 30*time.Minute), true, 2 * gb},
+		{"跨窗口但仍在冷却时间内", model.AlertRule{RuleType: "daily", ThresholdValue: 1, ThresholdUnit: "GB", Timezone: "Asia/Shanghai", CooldownMinutes: 12 * 60},
+			nil, ago(10*time.Hour + 30*time.Minute), false, 0},
+		{"速率规则冷却时间内跳过", model.AlertRule{RuleType: "rate", ThresholdValue: 1, ThresholdUnit: "MB", CooldownMinutes: 60},
+			nil, ago(30 * time.Minute), false, 0},
+		{"速率规则使用规则冷却时间", model.AlertRule{RuleType: "rate", ThresholdValue: 1, ThresholdUnit: "MB", CooldownMinutes: 10},
+			nil, ago(30 * time.Minute), true, 2 << 20},
+		{"配额使用率达到80%", model.AlertRule{RuleType: "quota", ThresholdValue: 80},
+			&model.TrafficQuota{TargetType: model.QuotaTargetProxy, LimitBytes: 10 * gb, UsedBytes: 17 * gb / 2}, nil, true, 17 * gb / 2},
+		{"配额使用率未达到100%", model.AlertRule{RuleType: "quota", ThresholdValue: 100},
+			&model.TrafficQuota{TargetType: model.QuotaTargetProxy, LimitBytes: 10 * gb, UsedBytes: 17 * gb / 2}, nil, false, 0},
+		{"客户端配额使用率", model.AlertRule{RuleType: "quota", ThresholdValue: 100},
+			&model.TrafficQuota{TargetType: model.QuotaTargetClient, LimitBytes: 10 * gb, UsedBytes: 10 * gb}, nil, true, 10 * gb},
+		{"配额周期内已告警过", model.AlertRule{RuleType: "quota", ThresholdValue: 80, CooldownMinutes: 10},
+			&model.TrafficQuota{TargetType: model.QuotaTargetProxy, LimitBytes: 10 * gb, UsedBytes: 9 * gb, PeriodStart: now.AddDate(0, 0, -3)},
+			ago(2 * time.Hour), false, 0},
+		{"无配额时不告警", model.AlertRule{RuleType: "quota", ThresholdValue: 80}, nil, nil, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := setupTrafficAlertTestDB(t)
+			for _, h := range history {
+				require.NoError(t, database.DB.Create(&model.ProxyMetricsHistory{ServerID: 1, ProxyName: "c1.web",
+					DeltaIn: h.bytes / 2, DeltaOut: h.bytes - h.bytes/2, RecordTime: h.at.Local()}).Error)
+			}
+			rule := tt.rule
+			rule.TargetType = model.AlertTargetProxy
+			rule.TargetID = proxy.ID
+			rule.ProxyID = proxy.ID
+			rule.Enabled = true
+			require.NoError(t, database.DB.Create(&rule).Error)
+			if rule.CooldownMinutes == 0 {
+				// gorm 默认值会将 0 写为 60，测试无冷却时间的情况需显式更新
+				require.NoError(t, database.DB.Model(&rule).Update("cooldown_minutes", 0).Error)
+			}
+			if tt.quota != nil {
+				quota := *tt.quota
+				quota.TargetID = proxy.ID
+				if quota.TargetType == model.QuotaTargetClient {
+					quota.TargetID = proxy.ClientID
+				}
+				quota.Enabled = true
+				require.NoError(t, database.DB.Create(&quota).Error)
+			}
+			if tt.lastAlertAt != nil {
+				require.NoError(t, database.DB.Create(&model.AlertLog{RuleID: rule.ID, ProxyID: proxy.ID,
+					AlertType: rule.RuleType, CreatedAt: tt.lastAlertAt.Local()}).Error)
+			}
+
+			svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+			svc.SetClientRepo(repository.NewClientRepository())
+			svc.now = func() time.Time { return now }
+			svc.CheckAlerts()
+
+			var alerts []model.AlertLog
+			require.NoError(t, database.DB.Where("rule_id = ? AND created_at = ?", rule.ID, now.Local()).Find(&alerts).Error)
+			if !tt.expectAlert {
+				assert.Empty(t, alerts)
+				return
+			}
+			require.Len(t, alerts, 1)
+			assert.Equal(t, tt.expectCurrent, alerts[0].CurrentValue)
+		})
+	}
+}
+
+func setupLifecycleTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.AlertRule{}, &model.AlertLog{},
+		&model.AlertSilence{}, &model.WebhookDelivery{}, &model.WebhookDeliveryAttempt{})
+}
+
+// TestAlertService_AlertLifecycle 测试告警确认、取消确认及手动结束的状态流转
+func TestAlertService_AlertLifecycle(t *testing.T) {
+	setupLifecycleTestDB(t)
+	svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, nil)
+	stream := newLogStreamService()
+	stream.Start()
+	alert := &model.AlertLog{RuleID: 1, TargetType: model.AlertTargetSystem, AlertType: "login_failed", Message: "登录失败"}
+	require.NoError(t, svc.alertRepo.CreateAlert(alert))
+	assert.Equal(t, model.AlertStatusFiring, alert.Status)
+
+	_, err := svc.UnacknowledgeAlert(alert.ID)
+	assert.Error(t, err, "未确认的告警不能取消确认")
+
+	acked, err := svc.AcknowledgeAlert(alert.ID, 7, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, model.AlertStatusAcknowledged, acked.Status)
+	assert.Equal(t, uint(7), acked.AckedBy)
+	assert.Equal(t, "admin", acked.AckedByName)
+	require.NotNil(t, acked.AckedAt)
+
+	_, err = svc.AcknowledgeAlert(alert.ID, 7, "admin")
+	assert.Error(t, err, "不能重复确认")
+
+	open, err := svc.GetAlertLogsByStatus("open", 10)
+	require.NoError(t, err)
+	assert.Len(t, open, 1)
+
+	unacked, err := svc.UnacknowledgeAlert(alert.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AlertStatusFiring, unacked.Status)
+	assert.Empty(t, unacked.AckedByName)
+	assert.Nil(t, unacked.AckedAt)
+
+	resolved, err := svc.ResolveAlert(alert.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AlertStatusResolved, resolved.Status)
+	assert.NotNil(t, resolved.ResolvedAt)
+
+	_, err = svc.AcknowledgeAlert(alert.ID, 7, "admin")
+	assert.Error(t, err, "已结束的告警不能确认")
+	_, err = svc.ResolveAlert(alert.ID)
+	assert.Error(t, err)
+	_, err = svc.AcknowledgeAlert(999, 7, "admin")
+	assert.Error(t, err)
+	_, err = svc.GetAlertLogsByStatus("unknown", 10)
+	assert.Error(t, err)
+
+	// 每次状态变化都发布事件，失败的操作不发布
+	var actions []string
+	for _, entry := range stream.pending {
+		if entry.Fields["alert_id"] == strconv.FormatUint(uint64(alert.ID), 10) {
+			actions = append(actions, entry.Fields["action"])
+		}
+	}
+	assert.Equal(t, []string{"acknowledged", "unacknowledged", "resolved"}, actions)
+}
+
+// TestAlertService_OfflineSilence 测试静默期内离线告警只记录不通知，恢复时结束告警且不发送恢复通知
+func TestAlertService_OfflineSilence(t *testing.T) {
+	tests := []struct {
+		name           string
+		silence        *model.AlertSilence
+		expectSilenced bool
+		expectWebhooks int // 离线告警及恢复通知的 Webhook 数量
+	}{
+		{"无静默时发送告警和恢复通知", nil, false, 2},
+		{"匹配目标的静默", &model.AlertSilence{TargetType: model.AlertTargetFrpc, TargetID: 5}, true, 0},
+		{"匹配规则的静默", &model.AlertSilence{RuleID: 1}, true, 0},
+		{"其他目标的静默不影响", &model.AlertSilence{TargetType: model.AlertTargetFrpc, TargetID: 6}, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupLifecycleTestDB(t)
+			srv, recv := newWebhookReceiver(t)
+			rule := &model.AlertRule{TargetType: model.AlertTargetFrpc, TargetID: 5, RuleType: "offline",
+				NotifyOnRecovery: true, Enabled: true, NotifyWebhook: srv.URL}
+			require.NoError(t, database.DB.Create(rule).Error)
+			if tt.silence != nil {
+				silence := *tt.silence
+				silence.StartsAt = time.Now().Add(-time.Minute)
+				silence.EndsAt = time.Now().Add(time.Hour)
+				require.NoError(t, database.DB.Create(&silence).Error)
+			}
+
+			svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, nil)
+			svc.pendingOffline["frpc:5"] = time.Now().Add(-10 * time.Minute)
+			svc.handleOfflineState("frpc:5", true, rule, "web", "frpc")
+
+			var alert model.AlertLog
+			require.NoError(t, database.DB.Where("rule_id = ?", rule.ID).First(&alert).Error)
+			assert.Equal(t, model.AlertStatusFiring, alert.Status)
+			assert.Equal(t, tt.expectSilenced, alert.Silenced)
+
+			svc.handleOfflineState("frpc:5", false, rule, "web", "frpc")
+			require.NoError(t, database.DB.First(&alert, alert.ID).Error)
+			assert.Equal(t, model.AlertStatusResolved, alert.Status)
+			assert.NotNil(t, alert.ResolvedAt)
+
+			countDeliveries := func() int64 {
+				var n int64
+				database.DB.Model(&model.WebhookDelivery{}).Count(&n)
+				return n
+			}
+			if tt.expectWebhooks > 0 {
+				// 等待异步投递全部完成（告警标记已通知为投递的最后一步），避免影响后续测试
+				assert.Eventually(t, func() bool {
+					var delivered int64
+					database.DB.Model(&model.WebhookDelivery{}).Where("status = ?", model.WebhookDeliverySuccess).Count(&delivered)
+					var notified model.AlertLog
+					database.DB.First(&notified, alert.ID)
+					return delivered == int64(tt.expectWebhooks) && notified.Notified
+				}, 2*time.Second, 20*time.Millisecond)
+			} else {
+				assert.Never(t, func() bool { return countDeliveries() > 0 }, 300*time.Millisecond, 20*time.Millisecond)
+				recv.mu.Lock()
+				assert.Empty(t, recv.requests)
+				recv.mu.Unlock()
+			}
+		})
+	}
+}
+
+// TestAlertService_TrafficAutoResolve 测试流量恢复到阈值以下时结束未结束的告警
+func TestAlertService_TrafficAutoResolve(t *testing.T) {
+	proxy := setupTrafficAlertTestDB(t)
+	rule := &model.AlertRule{TargetType: model.AlertTargetProxy, TargetID: proxy.ID, ProxyID: proxy.ID,
+		RuleType: "rate", ThresholdValue: 10, ThresholdUnit: "MB", Enabled: true}
+	require.NoError(t, database.DB.Create(rule).Error)
+	require.NoError(t, database.DB.AutoMigrate(&model.AlertSilence{}))
+	open := &model.AlertLog{RuleID: rule.ID, ProxyID: proxy.ID, AlertType: "rate", Status: model.AlertStatusAcknowledged}
+	require.NoError(t, database.DB.Create(open).Error)
+
+	svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+	svc.CheckAlerts()
+
+	var stored model.AlertLog
+	require.NoError(t, database.DB.First(&stored, open.ID).Error)
+	assert.Equal(t, model.AlertStatusResolved, stored.Status)
+}
+
+// TestAlertService_TrafficOpenAlert 测试流量告警未结束（含已确认）时冷却结束也不重复触发
+func TestAlertService_TrafficOpenAlert(t *testing.T) {
+	proxy := setupTrafficAlertTestDB(t)
+	require.NoError(t, database.DB.AutoMigrate(&model.AlertSilence{}))
+	rule := &model.AlertRule{TargetType: model.AlertTargetProxy, TargetID: proxy.ID, ProxyID: proxy.ID,
+		RuleType: "rate", ThresholdValue: 1, ThresholdUnit: "MB", Enabled: true}
+	require.NoError(t, database.DB.Create(rule).Error)
+	require.NoError(t, database.DB.Model(rule).Update("cooldown_minutes", 0).Error)
+	open := &model.AlertLog{RuleID: rule.ID, TargetType: model.AlertTargetProxy, TargetID: proxy.ID, ProxyID: proxy.ID,
+		AlertType: "rate", Status: model.AlertStatusAcknowledged, CreatedAt: time.Now().Add(-2 * time.Hour)}
+	require.NoError(t, database.DB.Create(open).Error)
+
+	svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+	countAlerts := func() int64 {
+		var count int64
+		require.NoError(t, database.DB.Model(&model.AlertLog{}).Where("rule_id = ?", rule.ID).Count(&count).Error)
+		return count
+	}
+	svc.CheckAlerts()
+	assert.Equal(t, int64(1), countAlerts(), "已确认的告警仍未结束，不重复触发")
+
+	_, err := svc.ResolveAlert(open.ID)
+	require.NoError(t, err)
+	svc.CheckAlerts()
+	assert.Equal(t, int64(2), countAlerts(), "告警结束后仍超出阈值时再次触发")
+}
+
+// TestAlertService_CheckMetricAlerts 测试指标表达式规则的持续时间、目标匹配及自动恢复
+func TestAlertService_CheckMetricAlerts(t *testing.T) {
+	proxy := setupTrafficAlertTestDB(t)
+	sqlDB, err := database.DB.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, database.DB.AutoMigrate(&model.FrpServer{}, &model.ServerMetricsHistory{}, &model.AlertSilence{}))
+
+	busy := &model.FrpServer{Name: "busy", Host: "10.0.0.1", Enabled: true}
+	idle := &model.FrpServer{Name: "idle", Host: "10.0.0.2", Enabled: true}
+	require.NoError(t, database.DB.Create(busy).Error)
+	require.NoError(t, database.DB.Create(idle).Error)
+	require.NoError(t, database.DB.Model(proxy).Update("frp_cur_conns", 150).Error)
+
+	t0 := time.Date(2026, 3, 15, 10, 0, 0, 0, time.Local)
+	addCPU := func(server *model.FrpServer, at time.Time, cpu float64) {
+		require.NoError(t, database.DB.Create(&model.ServerMetricsHistory{ServerID: server.ID, CpuPercent: cpu, RecordTime: at}).Error)
+	}
+	for _, m := range []time.Duration{-time.Minute, time.Minute, 2 * time.Minute} {
+		addCPU(busy, t0.Add(m), 90)
+		addCPU(idle, t0.Add(m), 20)
+	}
+	require.NoError(t, database.DB.Create(&model.ProxyMetricsHistory{ServerID: 1, ProxyName: "c1.web",
+		RateIn: 2 << 20, RecordTime: t0.Add(-30 * time.Second)}).Error)
+
+	cpuRule := &model.AlertRule{TargetType: model.AlertTargetFrps, RuleType: model.RuleTypeMetric,
+		Expression: "avg(frps_cpu_percent[5m]) > 80 for 2m", Enabled: true}
+	rateRule := &model.AlertRule{TargetType: model.AlertTargetProxy, TargetID: proxy.ID, RuleType: model.RuleTypeMetric,
+		Expression: "max(proxy_rate_in[1m]) >= 1MB/s", Enabled: true}
+	connRule := &model.AlertRule{TargetType: model.AlertTargetProxy, RuleType: model.RuleTypeMetric,
+		Expression: "proxy_cur_conns > 200", Enabled: true}
+	for _, rule := range []*model.AlertRule{cpuRule, rateRule, connRule} {
+		require.NoError(t, database.DB.Create(rule).Error)
+	}
+
+	svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+	svc.SetClientRepo(repository.NewClientRepository())
+	svc.SetFrpServerRepo(repository.NewFrpServerRepository(database.DB))
+	openAlerts := func(rule *model.AlertRule) []model.AlertLog {
+		var alerts []model.AlertLog
+		require.NoError(t, database.DB.Where("rule_id = ? AND status <> ?", rule.ID, model.AlertStatusResolved).Find(&alerts).Error)
+		return alerts
+	}
+
+	// 条件刚开始满足，未达到持续时间
+	svc.checkMetricAlertsAt(t0)
+	assert.Empty(t, openAlerts(cpuRule))
+	require.Len(t, openAlerts(rateRule), 1, "未设置持续时间时立即触发")
+	assert.Equal(t, proxy.ID, openAlerts(rateRule)[0].TargetID)
+	assert.Empty(t, openAlerts(connRule))
+
+	// 持续满足 2 分钟后只对超过阈值的 frps 触发，之后不重复触发
+	svc.checkMetricAlertsAt(t0.Add(2 * time.Minute))
+	svc.checkMetricAlertsAt(t0.Add(3 * time.Minute))
+	alerts := openAlerts(cpuRule)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, busy.ID, alerts[0].TargetID)
+	assert.Equal(t, model.RuleTypeMetric, alerts[0].AlertType)
+	assert.Contains(t, alerts[0].Message, "busy")
+	assert.Equal(t, int64(80), alerts[0].ThresholdValue)
+
+	// 窗口内只剩低于阈值的采样后自动恢复
+	addCPU(busy, t0.Add(8*time.Minute), 10)
+	svc.checkMetricAlertsAt(t0.Add(9 * time.Minute))
+	assert.Empty(t, openAlerts(cpuRule))
+	assert.Len(t, openAlerts(rateRule), 1, "窗口内没有采样时不评估，不会误判为恢复")
+}
+
+// TestAlertService_ValidateMetricRule 测试指标规则的表达式及目标类型校验
+func TestAlertService_ValidateMetricRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    model.AlertRule
+		wantErr bool
+	}{
+		{"有效规则", model.AlertRule{TargetType: model.AlertTargetFrps, RuleType: model.RuleTypeMetric,
+			Expression: "max(frps_memory_bytes[10m]) > 2GB"}, false},
+		{"缺少表达式", model.AlertRule{TargetType: model.AlertTargetFrps, RuleType: model.RuleTypeMetric}, true},
+		{"表达式无效", model.AlertRule{TargetType: model.AlertTargetFrps, RuleType: model.RuleTypeMetric,
+			Expression: "frps_cpu_percent >"}, true},
+		{"目标类型不匹配", model.AlertRule{TargetType: model.AlertTargetProxy, RuleType: model.RuleTypeMetric,
+			Expression: "frps_cpu_percent > 80"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := tt.rule
+			err := validateRule(&rule)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
 }
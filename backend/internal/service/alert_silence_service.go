@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"strings"
+	"time"
+)
+
+// AlertSilenceService 告警静默及计划维护窗口管理
+type AlertSilenceService struct {
+	silenceRepo *repository.AlertSilenceRepository
+}
+
+func NewAlertSilenceService() *AlertSilenceService {
+	return &AlertSilenceService{
+		silenceRepo: repository.NewAlertSilenceRepository(),
+	}
+}
+
+// GetSilences 获取全部静默并填充当前状态
+func (s *AlertSilenceService) GetSilences() ([]model.AlertSilence, error) {
+	silences, err := s.silenceRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range silences {
+		fillSilenceState(&silences[i], now)
+	}
+	return silences, nil
+}
+
+// CreateSilence 创建静默，开始时间为空时立即生效
+func (s *AlertSilenceService) CreateSilence(silence *model.AlertSilence) error {
+	silence.ID = 0
+	if err := validateSilence(silence, time.Now()); err != nil {
+		return err
+	}
+	if err := s.silenceRepo.Create(silence); err != nil {
+		return err
+	}
+	fillSilenceState(silence, time.Now())
+	return nil
+}
+
+// UpdateSilence 更新静默的匹配条件、时间和备注，创建人不变
+func (s *AlertSilenceService) UpdateSilence(silence *model.AlertSilence) (*model.AlertSilence, error) {
+	existing, err := s.silenceRepo.FindByID(silence.ID)
+	if err != nil {
+		return nil, fmt.Errorf("静默不存在")
+	}
+	if err := validateSilence(silence, time.Now()); err != nil {
+		return nil, err
+	}
+	silence.CreatedBy = existing.CreatedBy
+	silence.CreatedByName = existing.CreatedByName
+	silence.CreatedAt = existing.CreatedAt
+	if err := s.silenceRepo.Update(silence); err != nil {
+		return nil, err
+	}
+	fillSilenceState(silence, time.Now())
+	return silence, nil
+}
+
+func (s *AlertSilenceService) DeleteSilence(id uint) error {
+	if _, err := s.silenceRepo.FindByID(id); err != nil {
+		return fmt.Errorf("静默不存在")
+	}
+	return s.silenceRepo.Delete(id)
+}
+
+// ExpireSilence 立即结束静默，未开始的计划维护一并结束
+func (s *AlertSilenceService) ExpireSilence(id uint) (*model.AlertSilence, error) {
+	silence, err := s.silenceRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("静默不存在")
+	}
+	now := time.Now()
+	if !silence.EndsAt.After(now) {
+		return nil, fmt.Errorf("静默已结束")
+	}
+	silence.EndsAt = now
+	if silence.StartsAt.After(now) {
+		silence.StartsAt = now
+	}
+	if err := s.silenceRepo.Update(silence); err != nil {
+		return nil, err
+	}
+	fillSilenceState(silence, now)
+	return silence, nil
+}
+
+// MatchSilence 获取指定时间匹配目标或规则的生效静默，无匹配返回 nil
+func (s *AlertSilenceService) MatchSilence(targetType model.AlertTargetType, targetID, ruleID uint, at time.Time) *model.AlertSilence {
+	silences, err := s.silenceRepo.FindActive(at.Local())
+	if err != nil {
+		logger.Errorf("[告警静默] 获取生效静默失败: %v", err)
+		return nil
+	}
+	for i := range silences {
+		if silenceMatches(&silences[i], targetType, targetID, ruleID) {
+			return &silences[i]
+		}
+	}
+	return nil
+}
+
+// silenceMatches 未设置的匹配条件视为匹配全部
+func silenceMatches(silence *model.AlertSilence, targetType model.AlertTargetType, targetID, ruleID uint) bool {
+	if silence.TargetType != "" && silence.TargetType != targetType {
+		return false
+	}
+	if silence.TargetID != 0 && silence.TargetID != targetID {
+		return false
+	}
+	return silence.RuleID == 0 || silence.RuleID == ruleID
+}
+
+func validateSilence(silence *model.AlertSilence, now time.Time) error {
+	silence.Comment = strings.TrimSpace(silence.Comment)
+	switch silence.TargetType {
+	case "", model.AlertTargetProxy, model.AlertTargetFrpc, model.AlertTargetFrps, model.AlertTargetSystem, model.AlertTargetProbe:
+	default:
+		return fmt.Errorf("不支持的目标类型: %s", silence.TargetType)
+	}
+	if silence.TargetID != 0 && silence.TargetType == "" {
+		return fmt.Errorf("指定目标ID时必须指定目标类型")
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if silence.EndsAt.IsZero() {
+		return fmt.Errorf("结束时间不能为空")
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return fmt.Errorf("结束时间必须晚于开始时间")
+	}
+	return nil
+}
+
+func fillSilenceState(silence *model.AlertSilence, now time.Time) {
+	switch {
+	case now.Before(silence.StartsAt):
+		silence.State = model.SilenceStatePending
+	case now.Before(silence.EndsAt):
+		silence.State = model.SilenceStateActive
+	default:
+		silence.State = model.SilenceStateExpired
+	}
+}
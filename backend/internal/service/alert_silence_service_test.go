@@ -0,0 +1,78 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSilenceTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.AlertSilence{})
+}
+
+// TestSilenceMatches 测试静默匹配条件，未设置的条件匹配全部
+func TestSilenceMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		silence  model.AlertSilence
+		expected bool
+	}{
+		{"全局静默", model.AlertSilence{}, true},
+		{"目标类型匹配", model.AlertSilence{TargetType: model.AlertTargetFrpc}, true},
+		{"目标类型不匹配", model.AlertSilence{TargetType: model.AlertTargetFrps}, false},
+		{"目标ID匹配", model.AlertSilence{TargetType: model.AlertTargetFrpc, TargetID: 3}, true},
+		{"目标ID不匹配", model.AlertSilence{TargetType: model.AlertTargetFrpc, TargetID: 4}, false},
+		{"规则匹配", model.AlertSilence{RuleID: 9}, true},
+		{"规则不匹配", model.AlertSilence{RuleID: 8}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, silenceMatches(&tt.silence, model.AlertTargetFrpc, 3, 9))
+		})
+	}
+}
+
+// TestAlertSilenceService_Lifecycle 测试静默校验、生效时间判断及立即结束
+func TestAlertSilenceService_Lifecycle(t *testing.T) {
+	setupSilenceTestDB(t)
+	svc := NewAlertSilenceService()
+	now := time.Now()
+
+	invalid := []model.AlertSilence{
+		{TargetType: "unknown", EndsAt: now.Add(time.Hour)},
+		{TargetID: 1, EndsAt: now.Add(time.Hour)},
+		{TargetType: model.AlertTargetFrpc},
+		{StartsAt: now.Add(time.Hour), EndsAt: now},
+	}
+	for _, s := range invalid {
+		assert.Error(t, svc.CreateSilence(&s))
+	}
+
+	active := &model.AlertSilence{TargetType: model.AlertTargetFrpc, TargetID: 1, EndsAt: now.Add(time.Hour), Comment: " 升级 "}
+	require.NoError(t, svc.CreateSilence(active))
+	assert.Equal(t, model.SilenceStateActive, active.State)
+	assert.Equal(t, "升级", active.Comment)
+
+	maintenance := &model.AlertSilence{TargetType: model.AlertTargetFrps, StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(3 * time.Hour)}
+	require.NoError(t, svc.CreateSilence(maintenance))
+	assert.Equal(t, model.SilenceStatePending, maintenance.State)
+
+	assert.NotNil(t, svc.MatchSilence(model.AlertTargetFrpc, 1, 5, now.Add(time.Minute)))
+	assert.Nil(t, svc.MatchSilence(model.AlertTargetFrpc, 2, 5, now.Add(time.Minute)))
+	assert.Nil(t, svc.MatchSilence(model.AlertTargetFrps, 1, 5, now.Add(time.Minute)), "计划维护未开始")
+	assert.NotNil(t, svc.MatchSilence(model.AlertTargetFrps, 1, 5, now.Add(150*time.Minute)), "计划维护期间生效")
+
+	expired, err := svc.ExpireSilence(active.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SilenceStateExpired, expired.State)
+	assert.Nil(t, svc.MatchSilence(model.AlertTargetFrpc, 1, 5, time.Now().Add(time.Second)))
+	_, err = svc.ExpireSilence(active.ID)
+	assert.Error(t, err)
+
+	silences, err := svc.GetSilences()
+	require.NoError(t, err)
+	assert.Len(t, silences, 2)
+}
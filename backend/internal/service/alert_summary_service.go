@@ -0,0 +1,278 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultAlertSummaryHour 默认每天发送汇总邮件的时刻
+const defaultAlertSummaryHour = 8
+
+// AlertSummaryService 每日告警汇总，统计告警数量、离线时长及证书事件并定期发送汇总邮件
+type AlertSummaryService struct {
+	alertRepo        *repository.AlertRepo
+	settingRepo      *repository.SettingRepository
+	emailService     *EmailService
+	recipientService *AlertRecipientService
+
+	now func() time.Time // 便于测试替换时钟
+}
+
+func NewAlertSummaryService(alertRepo *repository.AlertRepo) *AlertSummaryService {
+	return &AlertSummaryService{
+		alertRepo:        alertRepo,
+		settingRepo:      repository.NewSettingRepository(),
+		emailService:     NewEmailService(),
+		recipientService: NewAlertRecipientService(),
+		now:              time.Now,
+	}
+}
+
+// ParseAlertSummaryDate 解析汇总日期，date 格式为 2006-01-02，为空时为昨天；tz 为空时使用服务器时区
+func ParseAlertSummaryDate(date, tz string, now time.Time) (time.Time, time.Time, error) {
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("无效的时区: %s", tz)
+		}
+	}
+	if date == "" {
+		local := now.In(loc)
+		start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -1)
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	start, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("日期格式错误，应为 YYYY-MM-DD")
+	}
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// BuildSummary 统计时间范围内触发的告警，时间范围左闭右开；离线时长同时包含开始前已离线、范围内仍未恢复的告警
+func (s *AlertSummaryService) BuildSummary(start, end time.Time) (*model.AlertDailySummary, error) {
+	alerts, err := s.alertRepo.GetAlertsBetween(start.Local(), end.Local())
+	if err != nil {
+		return nil, fmt.Errorf("获取告警记录失败: %w", err)
+	}
+	carried, err := s.alertRepo.GetOfflineAlertsOpenAt(start.Local())
+	if err != nil {
+		return nil, fmt.Errorf("获取告警记录失败: %w", err)
+	}
+
+	// 统计今天时离线时长只计算到当前时间
+	limit := end
+	if now := s.now(); now.Before(limit) {
+		limit = now
+	}
+
+	summary := &model.AlertDailySummary{
+		Start:      start,
+		End:        end,
+		Timezone:   start.Location().String(),
+		Types:      []model.AlertTypeCount{},
+		Offline:    []model.OfflineSummary{},
+		CertEvents: []model.CertEventSummary{},
+	}
+	typeCounts := make(map[string]int)
+	offline := make(map[string]*model.OfflineSummary)
+	addOffline := func(a *model.AlertLog) {
+		key := fmt.Sprintf("%s:%d", a.TargetType, a.TargetID)
+		o, ok := offline[key]
+		if !ok {
+			o = &model.OfflineSummary{TargetType: a.TargetType, TargetID: a.TargetID, TargetName: s.targetName(a.TargetType, a.TargetID)}
+			offline[key] = o
+		}
+		seconds, ongoing := offlineSeconds(a, start, limit)
+		o.Count++
+		o.TotalSeconds += seconds
+		if seconds > o.LongestSeconds {
+			o.LongestSeconds = seconds
+		}
+		o.Ongoing = o.Ongoing || ongoing
+	}
+	// 开始前已离线的告警只计入离线时长，不计入触发数量
+	for i := range carried {
+		addOffline(&carried[i])
+	}
+	for _, a := range alerts {
+		summary.Total++
+		typeCounts[a.AlertType]++
+		if a.Silenced {
+			summary.Silenced++
+		}
+		if a.Status == model.AlertStatusFiring || a.Status == model.AlertStatusAcknowledged {
+			summary.Open++
+		}
+
+		switch a.AlertType {
+		case "offline":
+			addOffline(&a)
+		case model.RuleTypeCertApplySuccess, model.RuleTypeCertApplyFailed, model.RuleTypeCertExpiring,
+			model.RuleTypeCertExpired, model.RuleTypeCertRenewSuccess, model.RuleTypeCertRenewFailed:
+			var data CertEventData
+			_ = json.Unmarshal([]byte(a.EventData), &data)
+			summary.CertEvents = append(summary.CertEvents, model.CertEventSummary{
+				Time:      a.CreatedAt.In(start.Location()),
+				AlertType: a.AlertType,
+				Domain:    data.Domain,
+				Message:   a.Message,
+			})
+		}
+	}
+
+	for alertType, count := range typeCounts {
+		summary.Types = append(summary.Types, model.AlertTypeCount{AlertType: alertType, Name: alertTypeName(alertType), Count: count})
+	}
+	sort.Slice(summary.Types, func(i, j int) bool {
+		if summary.Types[i].Count != summary.Types[j].Count {
+			return summary.Types[i].Count > summary.Types[j].Count
+		}
+		return summary.Types[i].AlertType < summary.Types[j].AlertType
+	})
+	for _, o := range offline {
+		summary.Offline = append(summary.Offline, *o)
+	}
+	sort.Slice(summary.Offline, func(i, j int) bool {
+		if summary.Offline[i].TotalSeconds != summary.Offline[j].TotalSeconds {
+			return summary.Offline[i].TotalSeconds > summary.Offline[j].TotalSeconds
+		}
+		return summary.Offline[i].TargetName < summary.Offline[j].TargetName
+	})
+	return summary, nil
+}
+
+// offlineSeconds 计算离线告警在统计范围内到恢复或统计结束的时长，开始前触发的从 start 起算，统计结束时仍未恢复返回 true
+func offlineSeconds(a *model.AlertLog, start, limit time.Time) (int64, bool) {
+	from := a.CreatedAt
+	if from.Before(start) {
+		from = start
+	}
+	stop := from
+	ongoing := false
+	switch {
+	case a.ResolvedAt != nil && a.ResolvedAt.Before(limit):
+		stop = *a.ResolvedAt
+	case a.ResolvedAt != nil || a.Status == model.AlertStatusFiring || a.Status == model.AlertStatusAcknowledged:
+		stop, ongoing = limit, true
+	}
+	if stop.Before(from) {
+		return 0, ongoing
+	}
+	return int64(stop.Sub(from).Seconds()), ongoing
+}
+
+// targetName 获取离线告警目标的显示名称，目标已删除时显示ID
+func (s *AlertSummaryService) targetName(targetType model.AlertTargetType, id uint) string {
+	name := lookupAlertTargetName(targetType, id)
+	switch {
+	case name == "":
+		return fmt.Sprintf("%s #%d", targetType, id)
+	case targetType == model.AlertTargetProbe:
+		return name
+	default:
+		return string(targetType) + " " + name
+	}
+}
+
+// alertTypeName 告警类型的中文名称
+func alertTypeName(alertType string) string {
+	switch alertType {
+	case "offline":
+		return "离线"
+	case "recovery":
+		return "恢复"
+	case model.RuleTypeMetric:
+		return "指标"
+	case "daily":
+		return "每日流量"
+	case "monthly":
+		return "每月流量"
+	case "rate":
+		return "实时速率"
+	case "quota":
+		return "配额使用率"
+	default:
+		return getRuleTypeName(alertType)
+	}
+}
+
+// CheckDailySummary 检查是否需要发送前一天的告警汇总，供定时任务调用
+func (s *AlertSummaryService) CheckDailySummary() {
+	s.checkDailySummaryAt(s.now())
+}
+
+func (s *AlertSummaryService) checkDailySummaryAt(now time.Time) {
+	if enabled, _ := s.settingRepo.GetSetting("alert_summary_enabled"); enabled != "true" {
+		return
+	}
+
+	tz, _ := s.settingRepo.GetSetting("alert_summary_timezone")
+	start, end, err := ParseAlertSummaryDate("", tz, now)
+	if err != nil {
+		logger.Warnf("[告警汇总] 时区设置无效: %s", tz)
+		return
+	}
+	hour := defaultAlertSummaryHour
+	if v, _ := s.settingRepo.GetSetting("alert_summary_hour"); v != "" {
+		if h, err := strconv.Atoi(v); err == nil && h >= 0 && h < 24 {
+			hour = h
+		}
+	}
+	if now.In(end.Location()).Hour() < hour {
+		return
+	}
+
+	dateKey := start.Format("2006-01-02")
+	if last, _ := s.settingRepo.GetSetting("alert_summary_last_date"); last == dateKey {
+		return
+	}
+	if err := s.SendSummary(start, end); err != nil {
+		logger.Errorf("[告警汇总] 发送 %s 告警汇总失败: %v", dateKey, err)
+		return
+	}
+	if err := s.settingRepo.UpdateSetting("alert_summary_last_date", dateKey); err != nil {
+		logger.Errorf("[告警汇总] 记录汇总发送日期失败: %v", err)
+	}
+}
+
+// SendSummary 生成时间范围内的告警汇总并发送给配置的接收人
+func (s *AlertSummaryService) SendSummary(start, end time.Time) error {
+	recipientIDs, _ := s.settingRepo.GetSetting("alert_summary_recipient_ids")
+	groupIDs, _ := s.settingRepo.GetSetting("alert_summary_group_ids")
+	emails := s.recipientService.GetEmailsByRecipientAndGroupIDs(parseIDList(recipientIDs), parseIDList(groupIDs))
+	if len(emails) == 0 {
+		return fmt.Errorf("未配置汇总接收人")
+	}
+
+	summary, err := s.BuildSummary(start, end)
+	if err != nil {
+		return err
+	}
+	date := start.Format("2006-01-02")
+	html, _, err := GenerateAlertSummaryEmail(AlertSummaryEmailData{Date: date, Summary: summary})
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("FRP 每日告警汇总 - %s（%d条）", date, summary.Total)
+	sent := 0
+	for _, to := range emails {
+		if err := s.emailService.SendHTMLEmail(to, subject, html, "text/html"); err != nil {
+			logger.Errorf("[告警汇总] 发送汇总到 %s 失败: %v", to, err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return fmt.Errorf("所有接收人发送失败")
+	}
+	logger.Infof("[告警汇总] %s 告警汇总已发送给 %d 个接收人", date, sent)
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAlertSummaryDate 测试汇总日期解析及默认昨天
+func TestParseAlertSummaryDate(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	now := time.Date(2026, 3, 15, 1, 0, 0, 0, time.UTC) // 上海 3月15日9:00
+
+	start, end, err := ParseAlertSummaryDate("", "Asia/Shanghai", now)
+	require.NoError(t, err)
+	assert.True(t, start.Equal(time.Date(2026, 3, 14, 0, 0, 0, 0, shanghai)))
+	assert.True(t, end.Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, shanghai)))
+
+	start, end, err = ParseAlertSummaryDate("2026-03-01", "Asia/Shanghai", now)
+	require.NoError(t, err)
+	assert.True(t, start.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, shanghai)))
+	assert.Equal(t, 24*time.Hour, end.Sub(start))
+
+	_, _, err = ParseAlertSummaryDate("2026/03/01", "", now)
+	assert.Error(t, err)
+	_, _, err = ParseAlertSummaryDate("", "Mars/Base", now)
+	assert.Error(t, err)
+}
+
+// TestAlertSummaryService_BuildSummary 测试按类型计数、离线时长及证书事件统计
+func TestAlertSummaryService_BuildSummary(t *testing.T) {
+	newServiceTestDB(t, &model.AlertLog{}, &model.Client{}, &model.Proxy{}, &model.FrpServer{})
+
+	client := &model.Client{Name: "home"}
+	require.NoError(t, database.DB.Create(client).Error)
+
+	start := time.Date(2026, 3, 14, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+	at := func(h, m int) time.Time { return start.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	alerts := []model.AlertLog{
+		// 前一天触发、开始后恢复的离线只计入范围内的离线时长
+		{TargetType: model.AlertTargetFrpc, TargetID: client.ID, AlertType: "offline", Status: model.AlertStatusResolved,
+			CreatedAt: start.Add(-time.Hour), ResolvedAt: ptr(start.Add(time.Hour))},
+		// 前一天已恢复的告警不统计
+		{TargetType: model.AlertTargetFrpc, TargetID: client.ID, AlertType: "offline", Status: model.AlertStatusResolved,
+			CreatedAt: start.Add(-2 * time.Hour), ResolvedAt: ptr(start.Add(-time.Hour))},
+		// 前一天触发、至今未恢复的离线计算整个统计范围
+		{TargetType: model.AlertTargetProbe, TargetID: 3, AlertType: "offline", Status: model.AlertStatusFiring,
+			CreatedAt: start.Add(-3 * time.Hour)},
+		{TargetType: model.AlertTargetFrpc, TargetID: client.ID, AlertType: "offline", Status: model.AlertStatusResolved,
+			CreatedAt: at(1, 0), ResolvedAt: ptr(at(1, 10))},
+		{TargetType: model.AlertTargetFrpc, TargetID: client.ID, AlertType: "offline", Status: model.AlertStatusFiring,
+			CreatedAt: at(23, 0)},
+		{TargetType: model.AlertTargetFrps, TargetID: 9, AlertType: "offline", Status: model.AlertStatusResolved,
+			CreatedAt: at(2, 0), ResolvedAt: ptr(at(2, 5)), Silenced: true},
+		{TargetType: model.AlertTargetSystem, AlertType: model.RuleTypeCertRenewFailed, Status: model.AlertStatusFiring,
+			Message: "证书续签失败: example.com", EventData: `{"domain":"example.com"}`, CreatedAt: at(3, 0)},
+		{TargetType: model.AlertTargetProxy, TargetID: 1, AlertType: "daily", Status: model.AlertStatusAcknowledged, CreatedAt: at(4, 0)},
+	}
+	for i := range alerts {
+		require.NoError(t, database.DB.Create(&alerts[i]).Error)
+	}
+
+	svc := NewAlertSummaryService(repository.NewAlertRepo(database.DB))
+	svc.now = func() time.Time { return end.Add(time.Hour) }
+	summary, err := svc.BuildSummary(start, end)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, summary.Total)
+	assert.Equal(t, 1, summary.Silenced)
+	assert.Equal(t, 3, summary.Open)
+	assert.Equal(t, []model.AlertTypeCount{
+		{AlertType: "offline", Name: "离线", Count: 3},
+		{AlertType: model.RuleTypeCertRenewFailed, Name: "证书续签失败", Count: 1},
+		{AlertType: "daily", Name: "每日流量", Count: 1},
+	}, summary.Types)
+
+	require.Len(t, summary.Offline, 3)
+	assert.Equal(t, model.OfflineSummary{TargetType: model.AlertTargetProbe, TargetID: 3, TargetName: "probe #3",
+		Count: 1, TotalSeconds: 24 * 3600, LongestSeconds: 24 * 3600, Ongoing: true}, summary.Offline[0], "开始前已离线的从统计开始计算")
+	assert.Equal(t, model.OfflineSummary{TargetType: model.AlertTargetFrpc, TargetID: client.ID, TargetName: "frpc home",
+		Count: 3, TotalSeconds: 130 * 60, LongestSeconds: 60 * 60, Ongoing: true}, summary.Offline[1], "未恢复的离线计算到统计结束")
+	assert.Equal(t, "frps #9", summary.Offline[2].TargetName, "已删除的目标显示ID")
+	assert.Equal(t, int64(5*60), summary.Offline[2].TotalSeconds)
+
+	require.Len(t, summary.CertEvents, 1)
+	assert.Equal(t, "example.com", summary.CertEvents[0].Domain)
+
+	html, _, err := GenerateAlertSummaryEmail(AlertSummaryEmailData{Date: "2026-03-14", Summary: summary})
+	require.NoError(t, err)
+	assert.Contains(t, html, "frpc home")
+	assert.Contains(t, html, "2小时10分（仍离线）")
+}
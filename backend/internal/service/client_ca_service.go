@@ -0,0 +1,405 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"frp-web-panel/internal/config"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/websocket"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// clientCertCNPrefix 客户端证书 CommonName 前缀，后接客户端ID
+	clientCertCNPrefix = "frp-client-"
+	// clientCertRenewBefore 证书到期前多久开始续期（有效期较短时取有效期的三分之一）
+	clientCertRenewBefore = 30 * 24 * time.Hour
+)
+
+var ErrClientCANotEnabled = errors.New("守护进程 mTLS 未启用")
+
+// ClientCAService 面板内置 CA，为守护进程签发、校验、续期和吊销 mTLS 客户端证书
+type ClientCAService struct {
+	cfg         config.DaemonTLSConfig
+	clientRepo  *repository.ClientRepository
+	revokedRepo *repository.RevokedClientCertRepository
+	daemonHub   *websocket.ClientDaemonHub
+
+	mu     sync.RWMutex
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caPEM  []byte
+}
+
+func NewClientCAService(cfg config.DaemonTLSConfig, daemonHub *websocket.ClientDaemonHub) *ClientCAService {
+	return &ClientCAService{
+		cfg:         cfg,
+		clientRepo:  repository.NewClientRepository(),
+		revokedRepo: repository.NewRevokedClientCertRepository(),
+		daemonHub:   daemonHub,
+	}
+}
+
+// Enabled 是否启用了 mTLS 且 CA 已加载
+func (s *ClientCAService) Enabled() bool {
+	if s == nil || !s.cfg.Enabled {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.caCert != nil
+}
+
+// RequireClientCert 是否强制守护进程出示客户端证书
+func (s *ClientCAService) RequireClientCert() bool {
+	return s.Enabled() && s.cfg.RequireClientCert
+}
+
+// Init 加载 CA，不存在时自动生成
+func (s *ClientCAService) Init() error {
+	if !s.cfg.Enabled {
+		return nil
+	}
+
+	certPath := filepath.Join(s.cfg.CADir, "ca.crt")
+	keyPath := filepath.Join(s.cfg.CADir, "ca.key")
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		var err error
+		certPEM, keyPEM, err = generateClientCA()
+		if err != nil {
+			return fmt.Errorf("生成客户端CA失败: %w", err)
+		}
+		if err := os.MkdirAll(s.cfg.CADir, 0700); err != nil {
+			return fmt.Errorf("创建CA目录失败: %w", err)
+		}
+		if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+			return fmt.Errorf("保存CA证书失败: %w", err)
+		}
+		if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+			return fmt.Errorf("保存CA私钥失败: %w", err)
+		}
+		logger.Infof("[客户端CA] 已生成新的客户端CA: %s", certPath)
+	} else if certErr != nil || keyErr != nil {
+		return fmt.Errorf("读取客户端CA失败: cert=%v, key=%v", certErr, keyErr)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("解析客户端CA失败: %w", err)
+	}
+	caCert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("解析客户端CA证书失败: %w", err)
+	}
+	caKey, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("客户端CA私钥必须为 ECDSA")
+	}
+
+	s.mu.Lock()
+	s.caCert = caCert
+	s.caKey = caKey
+	s.caPEM = certPEM
+	s.mu.Unlock()
+
+	logger.Infof("[客户端CA] 客户端CA已加载，有效期至 %s", caCert.NotAfter.Format("2006-01-02"))
+	return nil
+}
+
+// generateClientCA 生成自签名的 ECDSA CA
+func generateClientCA() ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "frp-web-panel client CA", Organization: []string{"frp-web-panel"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// TLSConfig 构建守护进程 TLS 监听器配置: 客户端证书可选出示，出示时必须由内置CA签发且未被吊销
+func (s *ClientCAService) TLSConfig() (*tls.Config, error) {
+	if !s.Enabled() {
+		return nil, ErrClientCANotEnabled
+	}
+
+	serverCert, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载服务端证书失败: %w", err)
+	}
+
+	s.mu.RLock()
+	pool := x509.NewCertPool()
+	pool.AddCert(s.caCert)
+	s.mu.RUnlock()
+
+	clientAuth := tls.VerifyClientCertIfGiven
+	if s.cfg.RequireClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   clientAuth,
+		ClientCAs:    pool,
+		VerifyPeerCertificate: func(_ [][]byte, chains [][]*x509.Certificate) error {
+			if len(chains) == 0 || len(chains[0]) == 0 {
+				return nil
+			}
+			serial := chains[0][0].SerialNumber.Text(16)
+			revoked, err := s.revokedRepo.IsRevoked(serial)
+			if err != nil {
+				return fmt.Errorf("检查证书吊销状态失败: %w", err)
+			}
+			if revoked {
+				return fmt.Errorf("客户端证书已吊销: serial=%s", serial)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// DaemonURL 守护进程连接 mTLS 监听器使用的地址，主机名取自面板公网地址，端口为 daemon_tls.port
+func (s *ClientCAService) DaemonURL(publicURL string) string {
+	if !s.Enabled() {
+		return ""
+	}
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return "wss://" + net.JoinHostPort(u.Hostname(), strconv.Itoa(s.cfg.Port))
+}
+
+// ServerCAPEM mTLS 监听器使用自签名证书时返回该证书，供守护进程固定信任；由公共 CA 签发时返回空字符串
+func (s *ClientCAService) ServerCAPEM() string {
+	data, err := os.ReadFile(s.cfg.CertFile)
+	if err != nil {
+		return ""
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return ""
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || cert.CheckSignatureFrom(cert) != nil {
+		return ""
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+// IssueClientCert 为客户端签发新证书并记录为当前证书，旧证书随之失效
+func (s *ClientCAService) IssueClientCert(clientID uint) (*model.ClientTLSCert, error) {
+	if !s.Enabled() {
+		return nil, ErrClientCANotEnabled
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成客户端私钥失败: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, fmt.Errorf("生成证书序列号失败: %w", err)
+	}
+
+	now := time.Now()
+	expireAt := now.AddDate(0, 0, s.cfg.ClientCertDays)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: fmt.Sprintf("%s%d", clientCertCNPrefix, clientID), Organization: []string{"frp-web-panel"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     expireAt,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	s.mu.RLock()
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, s.caCert, &key.PublicKey, s.caKey)
+	caPEM := string(s.caPEM)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("签发客户端证书失败: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("序列化客户端私钥失败: %w", err)
+	}
+
+	issued := &model.ClientTLSCert{
+		CertPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		KeyPEM:   string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		CAPEM:    caPEM,
+		Serial:   serial.Text(16),
+		ExpireAt: expireAt,
+	}
+
+	if err := s.clientRepo.UpdateTLSCert(clientID, issued.Serial, &expireAt); err != nil {
+		return nil, fmt.Errorf("保存证书信息失败: %w", err)
+	}
+
+	logger.Infof("[客户端CA] 已为客户端 %d 签发证书: serial=%s, 有效期至 %s", clientID, issued.Serial, expireAt.Format("2006-01-02"))
+	return issued, nil
+}
+
+// ClientIDFromCert 从已通过链校验的证书解析客户端ID，并确认其为该客户端当前有效的证书
+func (s *ClientCAService) ClientIDFromCert(cert *x509.Certificate) (uint, error) {
+	if !strings.HasPrefix(cert.Subject.CommonName, clientCertCNPrefix) {
+		return 0, fmt.Errorf("证书 CommonName 无效: %s", cert.Subject.CommonName)
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(cert.Subject.CommonName, clientCertCNPrefix), 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("证书 CommonName 无效: %s", cert.Subject.CommonName)
+	}
+
+	client, err := s.clientRepo.FindByID(uint(id))
+	if err != nil {
+		return 0, fmt.Errorf("证书对应的客户端 %d 不存在", id)
+	}
+	serial := cert.SerialNumber.Text(16)
+	if client.TLSCertSerial != serial {
+		return 0, fmt.Errorf("证书不是客户端 %d 的当前证书: serial=%s", id, serial)
+	}
+	return uint(id), nil
+}
+
+// NeedsRenewal 判断守护进程持有的证书是否需要重新签发
+func (s *ClientCAService) NeedsRenewal(clientID uint, daemonSerial string) bool {
+	if !s.Enabled() {
+		return false
+	}
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil {
+		return false
+	}
+	if client.TLSCertSerial == "" || client.TLSCertExpireAt == nil || client.TLSCertSerial != daemonSerial {
+		return true
+	}
+	return time.Until(*client.TLSCertExpireAt) < s.renewBefore()
+}
+
+// renewBefore 续期提前量
+func (s *ClientCAService) renewBefore() time.Duration {
+	third := time.Duration(s.cfg.ClientCertDays) * 24 * time.Hour / 3
+	if third < clientCertRenewBefore {
+		return third
+	}
+	return clientCertRenewBefore
+}
+
+// RenewClientCert 签发新证书并通过 WebSocket 推送给守护进程
+func (s *ClientCAService) RenewClientCert(clientID uint) error {
+	if s.daemonHub == nil || !s.daemonHub.IsClientOnline(clientID) {
+		return fmt.Errorf("客户端 %d 未连接", clientID)
+	}
+
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil {
+		return fmt.Errorf("客户端不存在")
+	}
+	oldSerial := client.TLSCertSerial
+
+	issued, err := s.IssueClientCert(clientID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.daemonHub.PushClientCert(clientID, issued.CertPEM, issued.KeyPEM, issued.CAPEM); err != nil {
+		// 守护进程未能保存新证书时保留旧证书，避免客户端无法重连
+		if oldSerial != "" {
+			s.clientRepo.UpdateTLSCert(clientID, oldSerial, client.TLSCertExpireAt)
+		}
+		return err
+	}
+
+	if oldSerial != "" {
+		if err := s.revokedRepo.Revoke(clientID, oldSerial, "superseded"); err != nil {
+			logger.Warnf("[客户端CA] 吊销客户端 %d 旧证书失败: %v", clientID, err)
+		}
+	}
+	return nil
+}
+
+// RenewExpiringCerts 为在线且证书即将到期的客户端续期，供定时任务调用
+func (s *ClientCAService) RenewExpiringCerts() {
+	if !s.Enabled() || s.daemonHub == nil {
+		return
+	}
+
+	for _, clientID := range s.daemonHub.GetOnlineClientIDs() {
+		client, err := s.clientRepo.FindByID(clientID)
+		if err != nil || client.TLSCertExpireAt == nil {
+			continue
+		}
+		if time.Until(*client.TLSCertExpireAt) >= s.renewBefore() {
+			continue
+		}
+		if err := s.RenewClientCert(clientID); err != nil {
+			logger.Warnf("[客户端CA] 客户端 %d 证书续期失败: %v", clientID, err)
+		}
+	}
+}
+
+// RevokeClientCert 吊销客户端当前证书
+func (s *ClientCAService) RevokeClientCert(clientID uint, reason string) error {
+	if s == nil || !s.cfg.Enabled {
+		return nil
+	}
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil || client.TLSCertSerial == "" {
+		return nil
+	}
+	if err := s.revokedRepo.Revoke(clientID, client.TLSCertSerial, reason); err != nil {
+		return fmt.Errorf("吊销客户端证书失败: %w", err)
+	}
+	logger.Infof("[客户端CA] 已吊销客户端 %d 的证书: serial=%s, reason=%s", clientID, client.TLSCertSerial, reason)
+	return nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"frp-web-panel/internal/config"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupClientCATest(t *testing.T) (*ClientCAService, *model.Client) {
+	setupServiceTestDB(t)
+	require.NoError(t, database.DB.AutoMigrate(&model.RevokedClientCert{}))
+
+	ca := NewClientCAService(config.DaemonTLSConfig{Enabled: true, CADir: t.TempDir(), ClientCertDays: 30}, nil)
+	require.NoError(t, ca.Init())
+
+	client := &model.Client{Name: "ca-client", ServerAddr: "127.0.0.1", ServerPort: 7000}
+	require.NoError(t, repository.NewClientRepository().Create(client))
+	return ca, client
+}
+
+func parseTestCert(t *testing.T, certPEM string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(certPEM))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+// TestClientCAService_IssueAndVerify 测试签发的证书可由CA校验并映射到客户端
+func TestClientCAService_IssueAndVerify(t *testing.T) {
+	ca, client := setupClientCATest(t)
+
+	issued, err := ca.IssueClientCert(client.ID)
+	require.NoError(t, err)
+
+	cert := parseTestCert(t, issued.CertPEM)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(issued.CAPEM)))
+	_, err = cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.NoError(t, err, "客户端证书应由内置CA签发")
+
+	clientID, err := ca.ClientIDFromCert(cert)
+	assert.NoError(t, err)
+	assert.Equal(t, client.ID, clientID)
+	assert.False(t, ca.NeedsRenewal(client.ID, issued.Serial), "守护进程持有当前证书时无需续期")
+	assert.True(t, ca.NeedsRenewal(client.ID, ""), "守护进程没有证书时需要签发")
+}
+
+// TestClientCAService_ReissueInvalidatesOld 测试重新签发后旧证书不再被接受
+func TestClientCAService_ReissueInvalidatesOld(t *testing.T) {
+	ca, client := setupClientCATest(t)
+
+	first, err := ca.IssueClientCert(client.ID)
+	require.NoError(t, err)
+	_, err = ca.IssueClientCert(client.ID)
+	require.NoError(t, err)
+
+	_, err = ca.ClientIDFromCert(parseTestCert(t, first.CertPEM))
+	assert.Error(t, err)
+}
+
+// TestClientCAService_Revoke 测试吊销记录
+func TestClientCAService_Revoke(t *testing.T) {
+	ca, client := setupClientCATest(t)
+
+	issued, err := ca.IssueClientCert(client.ID)
+	require.NoError(t, err)
+	require.NoError(t, ca.RevokeClientCert(client.ID, "client_deleted"))
+
+	revoked, err := repository.NewRevokedClientCertRepository().IsRevoked(issued.Serial)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+// TestClientCAService_DaemonConnectInfo 测试注册时附带的 mTLS 监听器地址和自签名服务端证书
+func TestClientCAService_DaemonConnectInfo(t *testing.T) {
+	ca, client := setupClientCATest(t)
+	ca.cfg.Port = 8443
+	assert.Equal(t, "wss://panel.example.com:8443", ca.DaemonURL("https://panel.example.com:8080/"))
+
+	selfSigned, _, err := generateClientCA()
+	require.NoError(t, err)
+	ca.cfg.CertFile = filepath.Join(t.TempDir(), "server.crt")
+	require.NoError(t, os.WriteFile(ca.cfg.CertFile, selfSigned, 0644))
+	assert.Equal(t, string(selfSigned), ca.ServerCAPEM(), "自签名服务端证书需要下发给守护进程")
+
+	issued, err := ca.IssueClientCert(client.ID)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(ca.cfg.CertFile, []byte(issued.CertPEM), 0644))
+	assert.Empty(t, ca.ServerCAPEM(), "非自签名证书由系统信任链校验")
+}
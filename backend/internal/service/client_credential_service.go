@@ -0,0 +1,184 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"frp-web-panel/internal/config"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/websocket"
+	"time"
+)
+
+// DaemonTokenGracePeriod 凭证轮换后旧凭证的宽限期
+const DaemonTokenGracePeriod = 24 * time.Hour
+
+var (
+	ErrDaemonCredentialRevoked = errors.New("客户端凭证已被吊销")
+	ErrDaemonCredentialInvalid = errors.New("客户端凭证验证失败")
+	ErrLegacyTokenDisabled     = errors.New("旧版查询参数认证已停用，请升级守护进程")
+)
+
+// DaemonTokenRotateResult 凭证轮换结果
+type DaemonTokenRotateResult struct {
+	Token   string `json:"token"`
+	Pushed  bool   `json:"pushed"`
+	Message string `json:"message"`
+}
+
+// ClientCredentialService 守护进程凭证服务，负责握手校验、凭证轮换与吊销
+type ClientCredentialService struct {
+	clientRepo          *repository.ClientRepository
+	daemonHub           *websocket.ClientDaemonHub
+	legacyTokenDisabled bool
+}
+
+func NewClientCredentialService(daemonHub *websocket.ClientDaemonHub) *ClientCredentialService {
+	return &ClientCredentialService{
+		clientRepo:          repository.NewClientRepository(),
+		daemonHub:           daemonHub,
+		legacyTokenDisabled: config.GlobalConfig != nil && config.GlobalConfig.DaemonAuth.DisableLegacyToken,
+	}
+}
+
+// NewDaemonChallengeNonce 生成握手随机数
+func NewDaemonChallengeNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SignDaemonChallenge 计算握手签名: HMAC-SHA256(token, nonce:clientID)
+func SignDaemonChallenge(token, nonce string, clientID uint) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", nonce, clientID)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// acceptedDaemonTokens 返回当前可用于认证的凭证（当前凭证 + 宽限期内的旧凭证）
+func acceptedDaemonTokens(client *model.Client, now time.Time) []string {
+	tokens := make([]string, 0, 2)
+	if client.DaemonToken != "" {
+		tokens = append(tokens, client.DaemonToken)
+	} else if client.Token != "" {
+		// 未轮换过的客户端沿用安装时写入的 Token
+		tokens = append(tokens, client.Token)
+	}
+	if client.DaemonTokenPrev != "" && client.DaemonTokenPrevExpire != nil && now.Before(*client.DaemonTokenPrevExpire) {
+		tokens = append(tokens, client.DaemonTokenPrev)
+	}
+	return tokens
+}
+
+// VerifyDaemonSignature 校验守护进程对握手随机数的签名
+func (s *ClientCredentialService) VerifyDaemonSignature(clientID uint, nonce, signature string) (*model.Client, error) {
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("客户端不存在")
+	}
+	if client.DaemonTokenRevoked {
+		return nil, ErrDaemonCredentialRevoked
+	}
+
+	for _, token := range acceptedDaemonTokens(client, time.Now()) {
+		expected := SignDaemonChallenge(token, nonce, clientID)
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return client, nil
+		}
+	}
+	return nil, ErrDaemonCredentialInvalid
+}
+
+// VerifyLegacyToken 校验旧版守护进程通过查询参数传递的明文凭证，配置 daemon_auth.disable_legacy_token 后一律拒绝
+func (s *ClientCredentialService) VerifyLegacyToken(clientID uint, token string) (*model.Client, error) {
+	if s.legacyTokenDisabled {
+		return nil, ErrLegacyTokenDisabled
+	}
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("客户端不存在")
+	}
+	if client.DaemonTokenRevoked {
+		return nil, ErrDaemonCredentialRevoked
+	}
+
+	for _, accepted := range acceptedDaemonTokens(client, time.Now()) {
+		if hmac.Equal([]byte(accepted), []byte(token)) {
+			return client, nil
+		}
+	}
+	return nil, ErrDaemonCredentialInvalid
+}
+
+// RotateDaemonToken 生成新凭证并推送给在线的守护进程，旧凭证在宽限期内仍然有效
+func (s *ClientCredentialService) RotateDaemonToken(clientID uint) (*DaemonTokenRotateResult, error) {
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("客户端不存在")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("生成凭证失败: %w", err)
+	}
+	newToken := hex.EncodeToString(raw)
+
+	// 已吊销的凭证不进入宽限期
+	prevToken := ""
+	var prevExpire *time.Time
+	if !client.DaemonTokenRevoked {
+		if client.DaemonToken != "" {
+			prevToken = client.DaemonToken
+		} else {
+			prevToken = client.Token
+		}
+		expire := time.Now().Add(DaemonTokenGracePeriod)
+		prevExpire = &expire
+	}
+
+	if err := s.clientRepo.UpdateDaemonCredential(clientID, newToken, prevToken, prevExpire, false); err != nil {
+		return nil, fmt.Errorf("保存凭证失败: %w", err)
+	}
+	logger.Infof("[客户端凭证] 客户端 %d 凭证已轮换", clientID)
+
+	result := &DaemonTokenRotateResult{Token: newToken}
+	if s.daemonHub == nil || !s.daemonHub.IsClientOnline(clientID) {
+		result.Message = "客户端不在线，请手动更新 daemon.yaml 中的 token"
+		return result, nil
+	}
+
+	if err := s.daemonHub.PushTokenRotate(clientID, newToken); err != nil {
+		logger.Warnf("[客户端凭证] 推送新凭证到客户端 %d 失败: %v", clientID, err)
+		result.Message = fmt.Sprintf("推送失败，旧凭证将在 %s 后失效: %v", DaemonTokenGracePeriod, err)
+		return result, nil
+	}
+
+	result.Pushed = true
+	result.Message = "新凭证已推送到客户端"
+	return result, nil
+}
+
+// RevokeDaemonToken 立即吊销客户端凭证并断开其连接，需重新轮换凭证后才能再次连接
+func (s *ClientCredentialService) RevokeDaemonToken(clientID uint) error {
+	client, err := s.clientRepo.FindByID(clientID)
+	if err != nil {
+		return fmt.Errorf("客户端不存在")
+	}
+
+	if err := s.clientRepo.UpdateDaemonCredential(clientID, client.DaemonToken, "", nil, true); err != nil {
+		return fmt.Errorf("吊销凭证失败: %w", err)
+	}
+	logger.Infof("[客户端凭证] 客户端 %d 凭证已吊销", clientID)
+
+	if s.daemonHub != nil && s.daemonHub.DisconnectClient(clientID) {
+		logger.Infof("[客户端凭证] 已断开客户端 %d 的会话", clientID)
+	}
+	return nil
+}
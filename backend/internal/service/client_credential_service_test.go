@@ -0,0 +1,49 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignDaemonChallenge 测试握手签名
+func TestSignDaemonChallenge(t *testing.T) {
+	sig := SignDaemonChallenge("token", "nonce", 1)
+	assert.Len(t, sig, 64, "HMAC-SHA256 签名应为64位十六进制")
+	assert.Equal(t, sig, SignDaemonChallenge("token", "nonce", 1), "相同输入签名应该一致")
+	assert.NotEqual(t, sig, SignDaemonChallenge("token", "nonce", 2), "客户端ID应参与签名")
+	assert.NotEqual(t, sig, SignDaemonChallenge("other", "nonce", 1), "凭证应参与签名")
+}
+
+// TestAcceptedDaemonTokens 测试可用凭证集合
+func TestAcceptedDaemonTokens(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		client   model.Client
+		expected []string
+	}{
+		{"未轮换沿用Token", model.Client{Token: "frp"}, []string{"frp"}},
+		{"已轮换使用DaemonToken", model.Client{Token: "frp", DaemonToken: "new"}, []string{"new"}},
+		{"宽限期内接受旧凭证", model.Client{DaemonToken: "new", DaemonTokenPrev: "old", DaemonTokenPrevExpire: &future}, []string{"new", "old"}},
+		{"宽限期外拒绝旧凭证", model.Client{DaemonToken: "new", DaemonTokenPrev: "old", DaemonTokenPrevExpire: &past}, []string{"new"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, acceptedDaemonTokens(&tt.client, now))
+		})
+	}
+}
+
+// TestVerifyLegacyToken_Disabled 测试停用旧版查询参数认证后直接拒绝
+func TestVerifyLegacyToken_Disabled(t *testing.T) {
+	svc := &ClientCredentialService{legacyTokenDisabled: true}
+	_, err := svc.VerifyLegacyToken(1, "frp")
+	assert.ErrorIs(t, err, ErrLegacyTokenDisabled)
+}
@@ -18,6 +18,7 @@ type ClientRegisterService struct {
 	githubMirrorRepo *repository.GithubMirrorRepository
 	settingRepo      *repository.SettingRepository
 	frpServerRepo    *repository.FrpServerRepository
+	clientCA         *ClientCAService
 }
 
 func NewClientRegisterService() *ClientRegisterService {
@@ -30,6 +31,11 @@ func NewClientRegisterService() *ClientRegisterService {
 	}
 }
 
+// SetClientCA 设置客户端CA服务，启用 mTLS 时注册即签发客户端证书
+func (s *ClientRegisterService) SetClientCA(clientCA *ClientCAService) {
+	s.clientCA = clientCA
+}
+
 // getPublicURL 获取公网访问地址,优先从设置中读取,否则使用默认值
 func (s *ClientRegisterService) getPublicURL() string {
 	publicURL, err := s.settingRepo.GetOrCreate("public_url", "http://localhost:8080", "公网访问地址(用于生成客户端注册脚本)")
@@ -301,11 +307,39 @@ sudo wget -q -O ${DAEMON_DIR}/frpc-daemon-ws "$DAEMON_URL" || {
 }
 sudo chmod +x ${DAEMON_DIR}/frpc-daemon-ws
 
+# 写入注册时签发的 mTLS 客户端证书，守护程序改为连接 mTLS 监听器
+DAEMON_SERVER_URL="%s"
+TLS_CONFIG=""
+json_value() {
+    echo "$REGISTER_RESPONSE" | grep -o "\"$1\":\"[^\"]*\"" | head -1 | cut -d'"' -f4
+}
+TLS_CERT=$(json_value cert_pem)
+if [ -n "$TLS_CERT" ]; then
+    sudo mkdir -p ${DAEMON_DIR}/tls
+    printf '%%b' "$TLS_CERT" | sudo tee ${DAEMON_DIR}/tls/client.crt > /dev/null
+    printf '%%b' "$(json_value key_pem)" | sudo tee ${DAEMON_DIR}/tls/client.key > /dev/null
+    printf '%%b' "$(json_value ca_pem)" | sudo tee ${DAEMON_DIR}/tls/client-ca.crt > /dev/null
+    sudo chmod 600 ${DAEMON_DIR}/tls/client.key
+    TLS_CONFIG="tls_cert_file: \"${DAEMON_DIR}/tls/client.crt\"
+tls_key_file: \"${DAEMON_DIR}/tls/client.key\""
+    SERVER_CA=$(json_value server_ca_pem)
+    if [ -n "$SERVER_CA" ]; then
+        printf '%%b' "$SERVER_CA" | sudo tee ${DAEMON_DIR}/tls/server-ca.crt > /dev/null
+        TLS_CONFIG="${TLS_CONFIG}
+server_ca_file: \"${DAEMON_DIR}/tls/server-ca.crt\""
+    fi
+    TLS_URL=$(json_value daemon_url)
+    if [ -n "$TLS_URL" ]; then
+        DAEMON_SERVER_URL="$TLS_URL"
+    fi
+    echo "   已写入 mTLS 客户端证书，守护程序将连接 ${DAEMON_SERVER_URL}"
+fi
+
 # 生成守护程序配置
 sudo tee ${DAEMON_DIR}/daemon.yaml > /dev/null << EOF
 client_id: ${CLIENT_ID}
 token: "%s"
-server_url: "%s"
+server_url: "${DAEMON_SERVER_URL}"
 frpc_path: "${INSTALL_DIR}/frpc"
 frpc_config: "${INSTALL_DIR}/frpc.toml"
 frpc_admin_port: 7400
@@ -315,6 +349,7 @@ frpc_service_name: "frpc"
 daemon_service_name: "frpc-daemon"
 log_file: "${DAEMON_DIR}/frpc-daemon.log"
 heartbeat_sec: 30
+${TLS_CONFIG}
 EOF
 
 # 创建守护程序服务
@@ -366,7 +401,7 @@ echo "   查看日志: sudo journalctl -u frpc-daemon -f"
 echo ""
 `, t.ClientName, t.ServerAddr, t.ServerPort, version, version, downloadURL,
 		t.ServerAddr, t.ServerPort, t.ClientName, t.TokenStr, t.AdminPassword,
-		apiURL, t.Token, apiURL, t.TokenStr, wsURL, t.AdminPassword)
+		apiURL, t.Token, apiURL, wsURL, t.TokenStr, t.AdminPassword)
 }
 
 func (s *ClientRegisterService) generatePowerShellScript(t *model.ClientRegisterToken, apiURL, baseURL, version string) string {
@@ -461,6 +496,7 @@ func (s *ClientRegisterService) generatePowerShellScript(t *model.ClientRegister
 		fmt.Sprintf("    $body = @{token=\"%s\"} | ConvertTo-Json\n", t.Token) +
 		fmt.Sprintf("    $response = Invoke-RestMethod -Uri \"%s/api/clients/register\" -Method Post -Body $body -ContentType \"application/json\"\n", apiURL) +
 		"    $CLIENT_ID = $response.data.id\n" +
+		"    $TLS_CERT = $response.data.tls_cert\n" +
 		"    Write-Host \"✅注册成功 (ClientID: $CLIENT_ID)\" -ForegroundColor Green\n" +
 		"} catch {\n" +
 		"    Write-Host \"⚠️  注册失败，跳过守护程序\" -ForegroundColor Yellow\n" +
@@ -484,10 +520,28 @@ func (s *ClientRegisterService) generatePowerShellScript(t *model.ClientRegister
 		"    }\n" +
 		"    Invoke-WebRequest -Uri $DAEMON_URL -OutFile \"$DAEMON_DIR\\frpc-daemon-ws.exe\" -UseBasicParsing\n" +
 		"    \n" +
+		"    # 写入注册时签发的 mTLS 客户端证书，守护程序改为连接 mTLS 监听器\n" +
+		fmt.Sprintf("    $DAEMON_SERVER_URL = \"%s\"\n", wsURL) +
+		"    $TLS_CONFIG = \"\"\n" +
+		"    if ($TLS_CERT -and $TLS_CERT.cert_pem) {\n" +
+		"        $TLS_DIR = \"$DAEMON_DIR\\tls\"\n" +
+		"        New-Item -ItemType Directory -Path $TLS_DIR -Force | Out-Null\n" +
+		"        [IO.File]::WriteAllText(\"$TLS_DIR\\client.crt\", $TLS_CERT.cert_pem)\n" +
+		"        [IO.File]::WriteAllText(\"$TLS_DIR\\client.key\", $TLS_CERT.key_pem)\n" +
+		"        [IO.File]::WriteAllText(\"$TLS_DIR\\client-ca.crt\", $TLS_CERT.ca_pem)\n" +
+		"        $TLS_CONFIG = \"tls_cert_file: '$TLS_DIR\\client.crt'`ntls_key_file: '$TLS_DIR\\client.key'\"\n" +
+		"        if ($TLS_CERT.server_ca_pem) {\n" +
+		"            [IO.File]::WriteAllText(\"$TLS_DIR\\server-ca.crt\", $TLS_CERT.server_ca_pem)\n" +
+		"            $TLS_CONFIG += \"`nserver_ca_file: '$TLS_DIR\\server-ca.crt'\"\n" +
+		"        }\n" +
+		"        if ($TLS_CERT.daemon_url) { $DAEMON_SERVER_URL = $TLS_CERT.daemon_url }\n" +
+		"        Write-Host \"   已写入 mTLS 客户端证书，守护程序将连接 $DAEMON_SERVER_URL\" -ForegroundColor Gray\n" +
+		"    }\n" +
+		"    \n" +
 		"    $daemonConfig = @\"\n" +
 		"client_id: $CLIENT_ID\n" +
 		fmt.Sprintf("token: \"%s\"\n", t.TokenStr) +
-		fmt.Sprintf("server_url: \"%s\"\n", wsURL) +
+		"server_url: \"$DAEMON_SERVER_URL\"\n" +
 		"frpc_path: \"$INSTALL_DIR\\frpc.exe\"\n" +
 		"frpc_config: \"$INSTALL_DIR\\frpc.toml\"\n" +
 		"frpc_admin_port: 7400\n" +
@@ -497,6 +551,7 @@ func (s *ClientRegisterService) generatePowerShellScript(t *model.ClientRegister
 		"daemon_service_name: \"frpc-daemon\"\n" +
 		"log_file: \"$DAEMON_DIR\\frpc-daemon.log\"\n" +
 		"heartbeat_sec: 30\n" +
+		"$TLS_CONFIG\n" +
 		"\"@\n" +
 		"    $daemonConfig | Out-File -FilePath \"$DAEMON_DIR\\daemon.yaml\" -Encoding UTF8\n" +
 		"    \n" +
@@ -584,6 +639,25 @@ func (s *ClientRegisterService) RegisterClient(token string) (*model.Client, err
 		return nil, err
 	}
 
+	if s.clientCA.Enabled() {
+		issued, err := s.clientCA.IssueClientCert(client.ID)
+		switch {
+		case err == nil:
+			// 安装脚本将证书写入守护进程目录，并改为连接 mTLS 监听器
+			issued.DaemonURL = s.clientCA.DaemonURL(s.getPublicURL())
+			issued.ServerCAPEM = s.clientCA.ServerCAPEM()
+			client.TLSCert = issued
+		case s.clientCA.RequireClientCert():
+			// 强制客户端证书时没有证书的守护进程无法连接，注册失败且 Token 保持可用
+			logger.Errorf("[客户端注册] ❌ 签发客户端证书失败: %v", err)
+			s.clientRepo.Delete(client.ID)
+			return nil, fmt.Errorf("签发客户端证书失败: %w", err)
+		default:
+			// 守护进程先通过普通端口连接，认证后由面板签发并推送证书
+			logger.Warnf("[客户端注册] ⚠️ 签发客户端证书失败: %v", err)
+		}
+	}
+
 	if err := s.tokenRepo.MarkAsUsed(t.ID); err != nil {
 		logger.Warnf("[客户端注册] ⚠️ 标记Token失败: %v", err)
 		return nil, err
@@ -25,6 +25,7 @@ import (
 type ClientService struct {
 	clientRepo    *repository.ClientRepository
 	frpServerRepo *repository.FrpServerRepository
+	clientCA      *ClientCAService
 }
 
 func NewClientService() *ClientService {
@@ -34,6 +35,11 @@ func NewClientService() *ClientService {
 	}
 }
 
+// SetClientCA 设置客户端CA服务，用于删除客户端时吊销其证书
+func (s *ClientService) SetClientCA(clientCA *ClientCAService) {
+	s.clientCA = clientCA
+}
+
 func (s *ClientService) GetClients(page, pageSize int, keyword string) ([]model.Client, int64, error) {
 	return s.clientRepo.FindAll(page, pageSize, keyword)
 }
@@ -81,6 +87,11 @@ func (s *ClientService) DeleteClient(id uint) error {
 		logger.Infof("客户端删除 客户端 ID=%d 不在线，跳过发送停止命令", id)
 	}
 
+	// 吊销客户端证书，防止已删除客户端的证书继续用于连接
+	if err := s.clientCA.RevokeClientCert(id, "client_deleted"); err != nil {
+		logger.Warnf("客户端删除 %v", err)
+	}
+
 	// 使用事务确保数据一致性：先删除关联的代理，再删除客户端
 	return database.DB.Transaction(func(tx *gorm.DB) error {
 		// 先删除该客户端关联的所有代理
@@ -90,6 +101,11 @@ func (s *ClientService) DeleteClient(id uint) error {
 		}
 		logger.Infof("客户端删除 已删除客户端 ID=%d 的所有关联代理", id)
 
+		if err := tx.Where("client_id = ?", id).Delete(&model.ClientMetricsHistory{}).Error; err != nil {
+			logger.Errorf("客户端删除 删除客户端 ID=%d 的指标历史失败: %v", id, err)
+			return err
+		}
+
 		// 再删除客户端记录
 		if err := tx.Delete(&model.Client{}, id).Error; err != nil {
 			logger.Errorf("客户端删除 删除客户端 ID=%d 失败: %v", id, err)
@@ -25,7 +25,7 @@ func setupServiceTestDB(t *testing.T) {
 	database.DB, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	err = database.DB.AutoMigrate(&model.Client{}, &model.Proxy{})
+	err = database.DB.AutoMigrate(&model.Client{}, &model.Proxy{}, &model.ClientMetricsHistory{})
 	assert.NoError(t, err)
 }
 
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/websocket"
+	"time"
+)
+
+// DaemonCertState 守护进程本地记录的证书状态
+type DaemonCertState struct {
+	Domain   string     `json:"domain"`
+	ExpireAt *time.Time `json:"expire_at"`
+	SyncedAt *time.Time `json:"synced_at"`
+}
+
+// DaemonUpdateState 守护进程最近一次更新结果
+type DaemonUpdateState struct {
+	UpdateType string    `json:"update_type"`
+	Version    string    `json:"version"`
+	Success    bool      `json:"success"`
+	Message    string    `json:"message"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// DaemonStateReport 守护进程重连后上报的本地状态
+type DaemonStateReport struct {
+	ConfigVersion   int                `json:"config_version"`
+	ConfigHash      string             `json:"config_hash"`
+	ConfigAppliedAt *time.Time         `json:"config_applied_at"`
+	ConfigError     string             `json:"config_error"`
+	Certs           []DaemonCertState  `json:"certs"`
+	LastUpdate      *DaemonUpdateState `json:"last_update"`
+	UpdatedAt       *time.Time         `json:"updated_at"`
+}
+
+// StateReconcileResult 状态对账结果
+type StateReconcileResult struct {
+	ConfigPushed bool
+	CertsPushed  []string
+}
+
+// ParseDaemonStateReport 解析 state_report 消息数据
+func ParseDaemonStateReport(data map[string]interface{}) (*DaemonStateReport, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var report DaemonStateReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, fmt.Errorf("状态上报格式错误: %w", err)
+	}
+	return &report, nil
+}
+
+// ConfigContentHash 计算配置内容哈希，与守护进程本地记录的算法一致
+func ConfigContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClientStateService 守护进程状态对账服务，根据守护进程上报的本地状态补推配置和证书
+type ClientStateService struct {
+	clientService *ClientService
+	proxyService  *ProxyService
+	certRepo      *repository.CertificateRepository
+	clientRepo    *repository.ClientRepository
+	daemonHub     *websocket.ClientDaemonHub
+}
+
+func NewClientStateService(daemonHub *websocket.ClientDaemonHub) *ClientStateService {
+	return &ClientStateService{
+		clientService: NewClientService(),
+		proxyService:  NewProxyService(),
+		certRepo:      repository.NewCertificateRepository(),
+		clientRepo:    repository.NewClientRepository(),
+		daemonHub:     daemonHub,
+	}
+}
+
+// Reconcile 保存守护进程上报的状态，并补推与面板不一致的配置和证书
+func (s *ClientStateService) Reconcile(clientID uint, report *DaemonStateReport) (*StateReconcileResult, error) {
+	client, err := s.clientService.GetClient(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("客户端不存在")
+	}
+
+	if err := s.clientRepo.UpdateDaemonState(clientID, report.ConfigVersion, report.ConfigHash, time.Now()); err != nil {
+		logger.Warnf("[状态对账] 保存客户端 %d 上报状态失败: %v", clientID, err)
+	}
+
+	result := &StateReconcileResult{}
+
+	// 先补推证书，避免新配置引用的证书文件不存在
+	pushed, err := s.reconcileCerts(clientID, report.Certs)
+	if err != nil {
+		logger.Warnf("[状态对账] 客户端 %d 证书对账失败: %v", clientID, err)
+	}
+	result.CertsPushed = pushed
+
+	configPushed, err := s.reconcileConfig(client, report)
+	if err != nil {
+		return result, err
+	}
+	result.ConfigPushed = configPushed
+	return result, nil
+}
+
+// reconcileConfig 比较当前应下发的配置与守护进程已应用的配置，不一致时重新推送
+func (s *ClientStateService) reconcileConfig(client *model.Client, report *DaemonStateReport) (bool, error) {
+	config, err := s.proxyService.ExportClientConfig(client.ID)
+	if err != nil {
+		return false, fmt.Errorf("生成配置失败: %w", err)
+	}
+
+	if report.ConfigHash == ConfigContentHash(config) {
+		logger.Debugf("[状态对账] 客户端 %d 配置一致: version=%d", client.ID, report.ConfigVersion)
+		if client.ConfigSyncStatus != "synced" {
+			s.clientService.UpdateConfigSyncStatus(client.ID, true, "", false)
+		}
+		return false, nil
+	}
+
+	// 版本号取两者较大值递增，避免守护进程把新配置当作旧版本
+	newVersion := client.ConfigVersion
+	if report.ConfigVersion > newVersion {
+		newVersion = report.ConfigVersion
+	}
+	newVersion++
+
+	logger.Infof("[状态对账] 客户端 %d 配置不一致 (已应用 version=%d)，重新推送 version=%d", client.ID, report.ConfigVersion, newVersion)
+	s.clientService.SetConfigSyncPending(client.ID)
+	if err := s.daemonHub.PushConfigUpdate(client.ID, config, newVersion); err != nil {
+		s.clientService.UpdateConfigSyncStatus(client.ID, false, fmt.Sprintf("推送配置失败: %v", err), false)
+		return false, fmt.Errorf("推送配置失败: %w", err)
+	}
+	s.clientService.UpdateConfigSync(client.ID, newVersion, nil)
+	return true, nil
+}
+
+// reconcileCerts 补推守护进程缺失或版本较旧的证书
+func (s *ClientStateService) reconcileCerts(clientID uint, reported []DaemonCertState) ([]string, error) {
+	expected, err := s.expectedCerts(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]DaemonCertState, len(reported))
+	for _, c := range reported {
+		local[c.Domain] = c
+	}
+
+	var pushed []string
+	for _, cert := range expected {
+		if !certOutdated(cert, local[cert.Domain]) {
+			continue
+		}
+		if err := s.daemonHub.PushCertSync(clientID, cert.Domain, cert.CertPEM, cert.KeyPEM); err != nil {
+			logger.Errorf("[状态对账] 补推证书 %s 到客户端 %d 失败: %v", cert.Domain, clientID, err)
+			continue
+		}
+		logger.Infof("[状态对账] 证书 %s 已补推到客户端 %d", cert.Domain, clientID)
+		pushed = append(pushed, cert.Domain)
+	}
+	return pushed, nil
+}
+
+// expectedCerts 返回客户端启用的代理所引用的有效证书（按证书去重）
+func (s *ClientStateService) expectedCerts(clientID uint) ([]model.Certificate, error) {
+	proxies, err := s.proxyService.GetProxiesByClient(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("获取代理列表失败: %w", err)
+	}
+
+	seen := make(map[uint]bool)
+	var certs []model.Certificate
+	for _, proxy := range proxies {
+		if !proxy.Enabled || proxy.CertID == nil || seen[*proxy.CertID] {
+			continue
+		}
+		seen[*proxy.CertID] = true
+
+		cert, err := s.certRepo.FindByID(*proxy.CertID)
+		if err != nil || cert == nil {
+			continue
+		}
+		if cert.Status != model.CertStatusActive || cert.CertPEM == "" || cert.KeyPEM == "" {
+			continue
+		}
+		certs = append(certs, *cert)
+	}
+	return certs, nil
+}
+
+// certOutdated 判断守护进程本地证书是否缺失或早于面板中的证书
+func certOutdated(cert model.Certificate, local DaemonCertState) bool {
+	if local.Domain == "" {
+		return true
+	}
+	if cert.NotAfter == nil || local.ExpireAt == nil {
+		return false
+	}
+	// 证书有效期精确到秒，比较前去掉序列化带来的亚秒误差
+	return local.ExpireAt.Truncate(time.Second).Before(cert.NotAfter.Truncate(time.Second))
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseDaemonStateReport 测试解析守护进程状态上报
+func TestParseDaemonStateReport(t *testing.T) {
+	data := map[string]interface{}{
+		"config_version": float64(7),
+		"config_hash":    "abc",
+		"certs": []interface{}{
+			map[string]interface{}{"domain": "a.com", "expire_at": "2026-01-02T03:04:05Z"},
+		},
+		"last_update": map[string]interface{}{"update_type": "frpc", "version": "0.61.0", "success": true},
+	}
+
+	report, err := ParseDaemonStateReport(data)
+	require.NoError(t, err)
+	assert.Equal(t, 7, report.ConfigVersion)
+	assert.Equal(t, "abc", report.ConfigHash)
+	require.Len(t, report.Certs, 1)
+	assert.Equal(t, "a.com", report.Certs[0].Domain)
+	require.NotNil(t, report.Certs[0].ExpireAt)
+	assert.Equal(t, 2026, report.Certs[0].ExpireAt.Year())
+	require.NotNil(t, report.LastUpdate)
+	assert.True(t, report.LastUpdate.Success)
+
+	_, err = ParseDaemonStateReport(map[string]interface{}{"config_version": "x"})
+	assert.Error(t, err, "字段类型错误应返回错误")
+}
+
+// TestCertOutdated 测试证书对账判断
+func TestCertOutdated(t *testing.T) {
+	notAfter := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	older := notAfter.Add(-30 * 24 * time.Hour)
+	sameWithNanos := notAfter.Add(500 * time.Millisecond)
+	cert := model.Certificate{Domain: "a.com", NotAfter: &notAfter}
+
+	assert.True(t, certOutdated(cert, DaemonCertState{}), "守护进程缺少证书应补推")
+	assert.True(t, certOutdated(cert, DaemonCertState{Domain: "a.com", ExpireAt: &older}), "守护进程证书较旧应补推")
+	assert.False(t, certOutdated(cert, DaemonCertState{Domain: "a.com", ExpireAt: &notAfter}))
+	assert.False(t, certOutdated(cert, DaemonCertState{Domain: "a.com", ExpireAt: &sameWithNanos}))
+	assert.False(t, certOutdated(cert, DaemonCertState{Domain: "a.com"}), "未知过期时间时不重复推送")
+}
+
+// TestConfigContentHash 测试配置哈希
+func TestConfigContentHash(t *testing.T) {
+	assert.Len(t, ConfigContentHash("a"), 64)
+	assert.Equal(t, ConfigContentHash("a"), ConfigContentHash("a"))
+	assert.NotEqual(t, ConfigContentHash("a"), ConfigContentHash("b"))
+}
@@ -1,10 +1,15 @@
 package service
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"frp-web-panel/internal/logger"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"strconv"
 )
 
@@ -25,6 +30,62 @@ func (s *EmailService) SendEmail(to, subject, body string) error {
 
 // SendHTMLEmail 发送HTML邮件
 func (s *EmailService) SendHTMLEmail(to, subject, body, contentType string) error {
+	if contentType == "" {
+		contentType = "text/html"
+	}
+	return s.deliver(to, func(from string) []byte {
+		return []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s; charset=UTF-8\r\n\r\n%s", from, to, subject, contentType, body))
+	})
+}
+
+// EmailAttachment 邮件附件
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// SendEmailWithAttachments 发送带附件的HTML邮件
+func (s *EmailService) SendEmailWithAttachments(to, subject, html string, attachments []EmailAttachment) error {
+	return s.deliver(to, func(from string) []byte {
+		return buildMultipartMessage(from, to, subject, html, attachments)
+	})
+}
+
+// buildMultipartMessage 构建 multipart/mixed 邮件内容
+func buildMultipartMessage(from, to, subject, html string, attachments []EmailAttachment) []byte {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\n", from, to, subject)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	part.Write([]byte(html))
+
+	for _, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	mw.Close()
+	return buf.Bytes()
+}
+
+// deliver 读取 SMTP 配置并发送邮件，build 根据发件人生成完整邮件内容
+func (s *EmailService) deliver(to string, build func(from string) []byte) error {
 	config, err := s.settingService.GetEmailConfig()
 	if err != nil {
 		return fmt.Errorf("获取邮件配置失败: %w", err)
@@ -39,12 +100,7 @@ func (s *EmailService) SendHTMLEmail(to, subject, body, contentType string) erro
 		from = config.Username
 	}
 
-	if contentType == "" {
-		contentType = "text/html"
-	}
-
-	msg := []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s; charset=UTF-8\r\n\r\n%s", from, to, subject, contentType, body))
-
+	msg := build(from)
 	addr := fmt.Sprintf("%s:%s", config.Host, config.Port)
 
 	if config.SSL {
@@ -1,9 +1,17 @@
 package service
 
 import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestGetPort 测试端口解析
@@ -145,3 +153,33 @@ func TestFromAddressFallback(t *testing.T) {
 		assert.Equal(t, "noreply@example.com", from)
 	})
 }
+
+// TestBuildMultipartMessage 测试带附件邮件的 MIME 结构
+func TestBuildMultipartMessage(t *testing.T) {
+	msg := buildMultipartMessage("sender@example.com", "receiver@example.com", "流量报表", "<h1>报表</h1>", []EmailAttachment{
+		{Filename: "report.csv", ContentType: "text/csv", Data: []byte("a,b\n1,2\n")},
+	})
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	html, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Contains(t, html.Header.Get("Content-Type"), "text/html")
+
+	attachment, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "report.csv", attachment.FileName())
+	encoded, err := io.ReadAll(attachment)
+	require.NoError(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(decoded))
+
+	_, err = mr.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"fmt"
+	"frp-web-panel/internal/model"
+	"strings"
 	"time"
 
 	"github.com/matcornic/hermes/v2"
@@ -9,6 +11,11 @@ import (
 
 // getEmailHermes 获取配置好的 hermes 实例
 func getEmailHermes() hermes.Hermes {
+	return getLocalizedEmailHermes(model.LocaleZhCN)
+}
+
+// getLocalizedEmailHermes 获取指定语言的 hermes 实例
+func getLocalizedEmailHermes(locale string) hermes.Hermes {
 	settingService := NewSettingService()
 	panelURL := settingService.GetPanelURL()
 
@@ -17,36 +24,12 @@ func getEmailHermes() hermes.Hermes {
 		Product: hermes.Product{
 			Name:        "FRP Panel",
 			Link:        panelURL,
-			Copyright:   "© FRP Panel - 内网穿透管理平台",
-			TroubleText: "如果按钮无法点击，请复制以下链接到浏览器打开：",
+			Copyright:   translate(locale, "email.copyright"),
+			TroubleText: translate(locale, "email.trouble"),
 		},
 	}
 }
 
-// TrafficAlertData 流量告警数据
-type TrafficAlertData struct {
-	ProxyName    string
-	AlertType    string
-	CurrentValue string
-	Threshold    string
-	Time         time.Time
-}
-
-// OfflineAlertData 离线告警数据
-type OfflineAlertData struct {
-	TargetType string
-	TargetName string
-	Message    string
-	Time       time.Time
-}
-
-// RecoveryAlertData 恢复通知数据
-type RecoveryAlertData struct {
-	TargetType string
-	TargetName string
-	Time       time.Time
-}
-
 // TestEmailData 测试邮件数据
 type TestEmailData struct {
 	Host string
@@ -54,46 +37,58 @@ type TestEmailData struct {
 	SSL  bool
 }
 
-// SystemAlertData 系统告警数据
-type SystemAlertData struct {
-	AlertType string
-	Message   string
-	EventData string
-	Time      time.Time
+// AlertEmailData 按消息模板渲染后的告警邮件数据
+type AlertEmailData struct {
+	Locale  string
+	Subject string // 邮件主题，同时作为正文标题
+	Body    string // 按行拆分为段落
+	Level   string // 决定按钮颜色和结束语
+	Fields  []NotificationField
+	Time    time.Time
 }
 
-// GenerateTrafficAlertEmail 生成流量告警邮件
-func GenerateTrafficAlertEmail(data TrafficAlertData) (html, text string, err error) {
-	h := getEmailHermes()
+// GenerateAlertEmail 生成告警邮件，流量、离线、恢复、指标、升级及系统告警共用
+func GenerateAlertEmail(data AlertEmailData) (html, text string, err error) {
+	h := getLocalizedEmailHermes(data.Locale)
 	settingService := NewSettingService()
 	panelURL := settingService.GetPanelURL()
 
+	var intros []string
+	for _, line := range strings.Split(data.Body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			intros = append(intros, line)
+		}
+	}
+	dictionary := make([]hermes.Entry, 0, len(data.Fields)+1)
+	for _, f := range data.Fields {
+		dictionary = append(dictionary, hermes.Entry{Key: f.Name, Value: f.Value})
+	}
+	dictionary = append(dictionary, hermes.Entry{Key: translate(data.Locale, "email.time"), Value: data.Time.Format("2006-01-02 15:04:05")})
+
+	color, outros := "#3869D4", []string(nil)
+	switch data.Level {
+	case NotifyLevelAlert:
+		color, outros = "#DC4D2F", []string{translate(data.Locale, "email.outro.alert")}
+	case NotifyLevelRecovery:
+		color, outros = "#22BC66", []string{translate(data.Locale, "email.outro.recovery")}
+	}
+
 	email := hermes.Email{
 		Body: hermes.Body{
-			Title: "流量告警通知",
-			Intros: []string{
-				"您的代理流量已超过设定阈值，请及时处理。",
-			},
-			Dictionary: []hermes.Entry{
-				{Key: "代理名称", Value: data.ProxyName},
-				{Key: "告警类型", Value: data.AlertType},
-				{Key: "当前值", Value: data.CurrentValue},
-				{Key: "阈值", Value: data.Threshold},
-				{Key: "时间", Value: data.Time.Format("2006-01-02 15:04:05")},
-			},
+			Title:      data.Subject,
+			Intros:     intros,
+			Dictionary: dictionary,
 			Actions: []hermes.Action{
 				{
-					Instructions: "点击下方按钮查看详情：",
+					Instructions: translate(data.Locale, "email.instructions"),
 					Button: hermes.Button{
-						Color: "#DC4D2F",
-						Text:  "查看详情",
+						Color: color,
+						Text:  translate(data.Locale, "email.button"),
 						Link:  panelURL,
 					},
 				},
 			},
-			Outros: []string{
-				"请及时处理以避免服务中断。",
-			},
+			Outros: outros,
 		},
 	}
 	html, err = h.GenerateHTML(email)
@@ -107,36 +102,39 @@ func GenerateTrafficAlertEmail(data TrafficAlertData) (html, text string, err er
 	return html, text, nil
 }
 
-// GenerateOfflineAlertEmail 生成离线告警邮件
-func GenerateOfflineAlertEmail(data OfflineAlertData) (html, text string, err error) {
+// GenerateTestEmail 生成测试邮件
+func GenerateTestEmail(data TestEmailData) (html, text string, err error) {
 	h := getEmailHermes()
 	settingService := NewSettingService()
 	panelURL := settingService.GetPanelURL()
 
+	sslText := "否"
+	if data.SSL {
+		sslText = "是"
+	}
 	email := hermes.Email{
 		Body: hermes.Body{
-			Title: "离线告警通知",
+			Title: "邮件配置测试",
 			Intros: []string{
-				fmt.Sprintf("您的 %s 已离线，请及时检查。", data.TargetType),
+				"这是一封测试邮件，用于验证 FRP Panel 的邮件配置是否正确。",
 			},
 			Dictionary: []hermes.Entry{
-				{Key: "目标类型", Value: data.TargetType},
-				{Key: "目标名称", Value: data.TargetName},
-				{Key: "告警消息", Value: data.Message},
-				{Key: "时间", Value: data.Time.Format("2006-01-02 15:04:05")},
+				{Key: "SMTP 服务器", Value: data.Host},
+				{Key: "端口", Value: data.Port},
+				{Key: "SSL/TLS", Value: sslText},
 			},
 			Actions: []hermes.Action{
 				{
-					Instructions: "点击下方按钮立即检查：",
+					Instructions: "点击下方按钮打开面板：",
 					Button: hermes.Button{
-						Color: "#DC4D2F",
-						Text:  "立即检查",
+						Color: "#3869D4",
+						Text:  "打开 FRP Panel",
 						Link:  panelURL,
 					},
 				},
 			},
 			Outros: []string{
-				"请及时处理以恢复服务。",
+				"如果您收到此邮件，说明邮件配置正确。",
 			},
 		},
 	}
@@ -151,36 +149,71 @@ func GenerateOfflineAlertEmail(data OfflineAlertData) (html, text string, err er
 	return html, text, nil
 }
 
-// GenerateSystemAlertEmail 生成系统告警邮件
-func GenerateSystemAlertEmail(data SystemAlertData) (html, text string, err error) {
+// TrafficReportEmailData 月度流量报表邮件数据
+type TrafficReportEmailData struct {
+	Period     string
+	Timezone   string
+	Rows       []model.TrafficReportRow // 按客户端汇总
+	TrafficIn  int64
+	TrafficOut int64
+	// Approximate 部分明细数据已超出保留期，合计可能偏小
+	Approximate bool
+}
+
+// GenerateTrafficReportEmail 生成月度流量报表邮件
+func GenerateTrafficReportEmail(data TrafficReportEmailData) (html, text string, err error) {
 	h := getEmailHermes()
 	settingService := NewSettingService()
 	panelURL := settingService.GetPanelURL()
 
+	rows := make([][]hermes.Entry, 0, len(data.Rows))
+	for _, r := range data.Rows {
+		name := r.ClientName
+		if name == "" {
+			name = "未关联客户端"
+		}
+		rows = append(rows, []hermes.Entry{
+			{Key: "客户端", Value: name},
+			{Key: "服务器", Value: r.ServerName},
+			{Key: "入站", Value: formatBytes(r.TrafficIn)},
+			{Key: "出站", Value: formatBytes(r.TrafficOut)},
+			{Key: "合计", Value: formatBytes(r.Total)},
+		})
+	}
+
+	intros := []string{
+		fmt.Sprintf("%s 流量用量统计（时区: %s），按代理明细见附件。", data.Period, data.Timezone),
+	}
+	if data.Approximate {
+		intros = append(intros, "注意：统计周期首尾部分时段的明细数据已超出保留期，合计可能偏小。")
+	}
+
 	email := hermes.Email{
 		Body: hermes.Body{
-			Title: "系统告警通知",
-			Intros: []string{
-				fmt.Sprintf("系统事件: %s", data.AlertType),
-			},
+			Title:  "月度流量报表",
+			Intros: intros,
 			Dictionary: []hermes.Entry{
-				{Key: "告警类型", Value: data.AlertType},
-				{Key: "告警消息", Value: data.Message},
-				{Key: "时间", Value: data.Time.Format("2006-01-02 15:04:05")},
+				{Key: "统计周期", Value: data.Period},
+				{Key: "入站合计", Value: formatBytes(data.TrafficIn)},
+				{Key: "出站合计", Value: formatBytes(data.TrafficOut)},
+				{Key: "总流量", Value: formatBytes(data.TrafficIn + data.TrafficOut)},
+			},
+			Table: hermes.Table{
+				Data: rows,
+				Columns: hermes.Columns{
+					CustomAlignment: map[string]string{"入站": "right", "出站": "right", "合计": "right"},
+				},
 			},
 			Actions: []hermes.Action{
 				{
-					Instructions: "点击下方按钮查看详情：",
+					Instructions: "点击下方按钮查看流量统计：",
 					Button: hermes.Button{
-						Color: "#DC4D2F",
+						Color: "#22BC66",
 						Text:  "查看详情",
 						Link:  panelURL,
 					},
 				},
 			},
-			Outros: []string{
-				"请及时处理相关事项。",
-			},
 		},
 	}
 	html, err = h.GenerateHTML(email)
@@ -194,36 +227,55 @@ func GenerateSystemAlertEmail(data SystemAlertData) (html, text string, err erro
 	return html, text, nil
 }
 
-// GenerateRecoveryAlertEmail 生成恢复通知邮件
-func GenerateRecoveryAlertEmail(data RecoveryAlertData) (html, text string, err error) {
-	h := getEmailHermes()
+// AlertDigestItem 告警汇总邮件中的一条通知
+type AlertDigestItem struct {
+	Time    time.Time
+	Title   string
+	Content string
+}
+
+// AlertDigestEmailData 告警汇总邮件数据
+type AlertDigestEmailData struct {
+	Locale    string
+	GroupName string
+	Items     []AlertDigestItem
+	Dropped   int // 此前因限流未发送的通知数
+}
+
+// GenerateAlertDigestEmail 生成告警汇总邮件，同一原因短时间内的多条告警合并为一封
+func GenerateAlertDigestEmail(data AlertDigestEmailData) (html, text string, err error) {
+	h := getLocalizedEmailHermes(data.Locale)
 	settingService := NewSettingService()
 	panelURL := settingService.GetPanelURL()
 
+	rows := make([][]hermes.Entry, 0, len(data.Items))
+	for _, item := range data.Items {
+		rows = append(rows, []hermes.Entry{
+			{Key: translate(data.Locale, "email.time"), Value: item.Time.Format("2006-01-02 15:04:05")},
+			{Key: translate(data.Locale, "digest.notification"), Value: item.Title},
+			{Key: translate(data.Locale, "digest.content_column"), Value: item.Content},
+		})
+	}
+	intros := []string{fmt.Sprintf(translate(data.Locale, "digest.email_intro"), data.GroupName, len(data.Items))}
+	if data.Dropped > 0 {
+		intros = append(intros, fmt.Sprintf(translate(data.Locale, "digest.email_dropped"), data.Dropped))
+	}
+
 	email := hermes.Email{
 		Body: hermes.Body{
-			Title: "恢复通知",
-			Intros: []string{
-				fmt.Sprintf("您的 %s 已恢复在线。", data.TargetType),
-			},
-			Dictionary: []hermes.Entry{
-				{Key: "目标类型", Value: data.TargetType},
-				{Key: "目标名称", Value: data.TargetName},
-				{Key: "恢复时间", Value: data.Time.Format("2006-01-02 15:04:05")},
-			},
+			Title:  translate(data.Locale, "digest.email_title"),
+			Intros: intros,
+			Table:  hermes.Table{Data: rows},
 			Actions: []hermes.Action{
 				{
-					Instructions: "点击下方按钮查看状态：",
+					Instructions: translate(data.Locale, "email.instructions"),
 					Button: hermes.Button{
-						Color: "#22BC66",
-						Text:  "查看状态",
+						Color: "#DC4D2F",
+						Text:  translate(data.Locale, "email.button"),
 						Link:  panelURL,
 					},
 				},
 			},
-			Outros: []string{
-				"服务已恢复正常运行。",
-			},
 		},
 	}
 	html, err = h.GenerateHTML(email)
@@ -237,40 +289,75 @@ func GenerateRecoveryAlertEmail(data RecoveryAlertData) (html, text string, err
 	return html, text, nil
 }
 
-// GenerateTestEmail 生成测试邮件
-func GenerateTestEmail(data TestEmailData) (html, text string, err error) {
+// AlertSummaryEmailData 每日告警汇总邮件数据
+type AlertSummaryEmailData struct {
+	Date    string
+	Summary *model.AlertDailySummary
+}
+
+// GenerateAlertSummaryEmail 生成每日告警汇总邮件
+func GenerateAlertSummaryEmail(data AlertSummaryEmailData) (html, text string, err error) {
 	h := getEmailHermes()
 	settingService := NewSettingService()
 	panelURL := settingService.GetPanelURL()
+	summary := data.Summary
 
-	sslText := "否"
-	if data.SSL {
-		sslText = "是"
+	dictionary := []hermes.Entry{
+		{Key: "统计日期", Value: data.Date},
+		{Key: "告警总数", Value: fmt.Sprintf("%d", summary.Total)},
+		{Key: "静默告警", Value: fmt.Sprintf("%d", summary.Silenced)},
+		{Key: "未结束告警", Value: fmt.Sprintf("%d", summary.Open)},
+	}
+	for _, c := range summary.Types {
+		dictionary = append(dictionary, hermes.Entry{Key: c.Name, Value: fmt.Sprintf("%d", c.Count)})
+	}
+
+	rows := make([][]hermes.Entry, 0, len(summary.Offline))
+	for _, o := range summary.Offline {
+		total := formatSummaryDuration(o.TotalSeconds)
+		if o.Ongoing {
+			total += "（仍离线）"
+		}
+		rows = append(rows, []hermes.Entry{
+			{Key: "离线目标", Value: o.TargetName},
+			{Key: "次数", Value: fmt.Sprintf("%d", o.Count)},
+			{Key: "累计离线", Value: total},
+			{Key: "最长一次", Value: formatSummaryDuration(o.LongestSeconds)},
+		})
+	}
+
+	var outros []string
+	if len(summary.CertEvents) > 0 {
+		outros = append(outros, fmt.Sprintf("证书事件 %d 条：", len(summary.CertEvents)))
+		for _, e := range summary.CertEvents {
+			outros = append(outros, fmt.Sprintf("%s %s", e.Time.Format("15:04"), e.Message))
+		}
 	}
+
 	email := hermes.Email{
 		Body: hermes.Body{
-			Title: "邮件配置测试",
+			Title: "每日告警汇总",
 			Intros: []string{
-				"这是一封测试邮件，用于验证 FRP Panel 的邮件配置是否正确。",
+				fmt.Sprintf("%s 共产生 %d 条告警（时区: %s）。", data.Date, summary.Total, summary.Timezone),
 			},
-			Dictionary: []hermes.Entry{
-				{Key: "SMTP 服务器", Value: data.Host},
-				{Key: "端口", Value: data.Port},
-				{Key: "SSL/TLS", Value: sslText},
+			Dictionary: dictionary,
+			Table: hermes.Table{
+				Data: rows,
+				Columns: hermes.Columns{
+					CustomAlignment: map[string]string{"次数": "right", "累计离线": "right", "最长一次": "right"},
+				},
 			},
 			Actions: []hermes.Action{
 				{
-					Instructions: "点击下方按钮打开面板：",
+					Instructions: "点击下方按钮查看告警记录：",
 					Button: hermes.Button{
 						Color: "#3869D4",
-						Text:  "打开 FRP Panel",
+						Text:  "查看详情",
 						Link:  panelURL,
 					},
 				},
 			},
-			Outros: []string{
-				"如果您收到此邮件，说明邮件配置正确。",
-			},
+			Outros: outros,
 		},
 	}
 	html, err = h.GenerateHTML(email)
@@ -283,3 +370,16 @@ func GenerateTestEmail(data TestEmailData) (html, text string, err error) {
 	}
 	return html, text, nil
 }
+
+// formatSummaryDuration 将秒数格式化为 1小时5分 形式
+func formatSummaryDuration(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%d小时%d分", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%d分%d秒", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%d秒", seconds)
+	}
+}
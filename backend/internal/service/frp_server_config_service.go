@@ -69,6 +69,7 @@ webServer:
 	 user: "%s"
 	 password: "%s"
 `, server.BindPort, server.DashboardPort, server.DashboardUser, server.DashboardPwd)
+	content += FrpsPluginConfig(server.ID, true)
 
 	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
 		return err
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/config"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// frps HTTP 插件操作
+const (
+	FrpsPluginOpLogin       = "Login"
+	FrpsPluginOpNewProxy    = "NewProxy"
+	FrpsPluginOpNewWorkConn = "NewWorkConn"
+	FrpsPluginOpNewUserConn = "NewUserConn"
+)
+
+// frpsPluginOps 写入 frps 配置的插件操作
+var frpsPluginOps = []string{FrpsPluginOpLogin, FrpsPluginOpNewProxy, FrpsPluginOpNewWorkConn, FrpsPluginOpNewUserConn}
+
+// FrpsPluginService 实现 frps HTTP 插件协议，frps 在相应事件发生时回调面板
+// Login/NewWorkConn 校验 frpc 的 user 对应面板中的有效客户端，NewProxy 校验代理与面板记录一致，NewUserConn 执行来源访问策略
+type FrpsPluginService struct {
+	visitorService *ProxyVisitorService
+	accessService  *ProxyAccessService
+	clientRepo     *repository.ClientRepository
+	proxyRepo      *repository.ProxyRepository
+	logService     *LogService
+}
+
+func NewFrpsPluginService(visitorService *ProxyVisitorService, accessService *ProxyAccessService) *FrpsPluginService {
+	return &FrpsPluginService{
+		visitorService: visitorService,
+		accessService:  accessService,
+		clientRepo:     repository.NewClientRepository(),
+		proxyRepo:      repository.NewProxyRepository(),
+		logService:     NewLogService(),
+	}
+}
+
+// frpsPluginToken 计算服务器专属的插件回调令牌
+// frps 插件不支持自定义请求头，令牌放在回调路径中，由 JWT 密钥派生，无需额外存储
+func frpsPluginToken(serverID uint) string {
+	secret := ""
+	if config.GlobalConfig != nil {
+		secret = config.GlobalConfig.JWT.Secret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "frps-plugin:%d", serverID)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// VerifyToken 校验插件回调令牌
+func (s *FrpsPluginService) VerifyToken(serverID uint, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(frpsPluginToken(serverID))) == 1
+}
+
+// Handle 处理一次插件回调
+func (s *FrpsPluginService) Handle(serverID uint, req *model.FrpsPluginRequest) *model.FrpsPluginResponse {
+	var reason string
+	var err error
+	switch req.Op {
+	case FrpsPluginOpLogin:
+		var content model.FrpsLoginContent
+		if err = json.Unmarshal(req.Content, &content); err == nil {
+			if _, reason = s.checkClient(serverID, content.User); reason != "" {
+				s.logReject(serverID, fmt.Sprintf("拒绝客户端登录: user=%s, 地址=%s, 原因: %s", content.User, content.ClientAddress, reason))
+			}
+		}
+	case FrpsPluginOpNewProxy:
+		var content model.FrpsNewProxyContent
+		if err = json.Unmarshal(req.Content, &content); err == nil {
+			if reason = s.checkProxy(serverID, &content); reason != "" {
+				s.logReject(serverID, fmt.Sprintf("拒绝注册代理: user=%s, 代理=%s, 原因: %s", content.User.User, content.ProxyName, reason))
+			}
+		}
+	case FrpsPluginOpNewWorkConn:
+		var content model.FrpsNewWorkConnContent
+		if err = json.Unmarshal(req.Content, &content); err == nil {
+			_, reason = s.checkClient(serverID, content.User.User)
+		}
+	case FrpsPluginOpNewUserConn:
+		var content model.FrpsNewUserConnContent
+		if err = json.Unmarshal(req.Content, &content); err == nil {
+			now := time.Now()
+			if reason = s.accessService.Authorize(serverID, content.ProxyName, content.RemoteAddr, now); reason == "" {
+				s.visitorService.Record(serverID, content.ProxyName, content.RemoteAddr, now)
+			}
+		}
+	default:
+		logger.Debugf("[frps插件] 服务器 %d 未处理的操作: %s", serverID, req.Op)
+	}
+
+	if err != nil {
+		logger.Warnf("[frps插件] 服务器 %d 的 %s 请求内容解析失败: %v", serverID, req.Op, err)
+		return &model.FrpsPluginResponse{Reject: true, RejectReason: "插件请求内容无效"}
+	}
+	if reason != "" {
+		logger.Warnf("[frps插件] 服务器 %d 拒绝 %s: %s", serverID, req.Op, reason)
+		return &model.FrpsPluginResponse{Reject: true, RejectReason: reason}
+	}
+	return &model.FrpsPluginResponse{Unchange: true}
+}
+
+// checkClient 校验 frpc 的 user 对应面板中属于该服务器且凭证未被吊销的客户端，返回拒绝原因
+func (s *FrpsPluginService) checkClient(serverID uint, user string) (*model.Client, string) {
+	if user == "" {
+		return nil, "frpc 未设置 user"
+	}
+	client, err := s.clientRepo.FindByName(user)
+	if err != nil {
+		return nil, fmt.Sprintf("客户端 %s 未在面板中登记", user)
+	}
+	if client.FrpServerID == nil {
+		return nil, fmt.Sprintf("客户端 %s 未绑定服务器", user)
+	}
+	if *client.FrpServerID != serverID {
+		return nil, fmt.Sprintf("客户端 %s 不属于该服务器", user)
+	}
+	if client.DaemonTokenRevoked {
+		return nil, fmt.Sprintf("客户端 %s 凭证已被吊销", user)
+	}
+	return client, ""
+}
+
+// checkProxy 校验代理已在面板中登记、已启用，且类型、端口和域名与面板记录一致，返回拒绝原因
+func (s *FrpsPluginService) checkProxy(serverID uint, content *model.FrpsNewProxyContent) string {
+	client, reason := s.checkClient(serverID, content.User.User)
+	if reason != "" {
+		return reason
+	}
+
+	// 不同 frp 版本传入的代理名可能带 user 前缀
+	name := strings.TrimPrefix(content.ProxyName, client.Name+".")
+	proxy, err := s.proxyRepo.FindByClientIDAndName(client.ID, name)
+	if err != nil {
+		return fmt.Sprintf("代理 %s 未在面板中登记", name)
+	}
+	if !proxy.Enabled {
+		return fmt.Sprintf("代理 %s 已禁用", name)
+	}
+	if content.ProxyType != proxy.Type {
+		return fmt.Sprintf("代理类型不一致: 请求 %s, 面板 %s", content.ProxyType, proxy.Type)
+	}
+	if proxy.RemotePort > 0 && content.RemotePort != proxy.RemotePort {
+		return fmt.Sprintf("远程端口不一致: 请求 %d, 面板 %d", content.RemotePort, proxy.RemotePort)
+	}
+	if !sameDomains(content.CustomDomains, proxy.CustomDomains) {
+		return fmt.Sprintf("自定义域名不一致: 请求 %s, 面板 %s", strings.Join(content.CustomDomains, ","), proxy.CustomDomains)
+	}
+	if content.Subdomain != proxy.Subdomain {
+		return fmt.Sprintf("子域名不一致: 请求 %s, 面板 %s", content.Subdomain, proxy.Subdomain)
+	}
+	return ""
+}
+
+// sameDomains 比较请求的域名列表与面板中逗号分隔的域名，忽略顺序和大小写
+func sameDomains(requested []string, configured string) bool {
+	want := make(map[string]bool)
+	for _, d := range strings.Split(configured, ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			want[d] = true
+		}
+	}
+	got := make(map[string]bool)
+	for _, d := range requested {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			got[d] = true
+		}
+	}
+	if len(want) != len(got) {
+		return false
+	}
+	for d := range got {
+		if !want[d] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *FrpsPluginService) logReject(serverID uint, desc string) {
+	s.logService.CreateLogAsync(0, "reject", "frps_plugin", serverID, desc, "127.0.0.1")
+}
+
+// FrpsPluginConfig 生成 frps 配置中的 httpPlugins 片段，未启用插件时返回空字符串
+// 本地服务器直接回调本机监听端口，远程服务器使用 frps_plugin_url 或公网访问地址
+func FrpsPluginConfig(serverID uint, local bool) string {
+	settingRepo := repository.NewSettingRepository()
+	if enabled, _ := settingRepo.GetSetting("frps_plugin_enabled"); enabled != "true" {
+		return ""
+	}
+
+	base := ""
+	if local && config.GlobalConfig != nil {
+		base = fmt.Sprintf("http://127.0.0.1:%d", config.GlobalConfig.Server.Port)
+	} else if base, _ = settingRepo.GetSetting("frps_plugin_url"); base == "" {
+		base, _ = settingRepo.GetSetting("public_url")
+	}
+	u, err := url.Parse(strings.TrimSpace(base))
+	if err != nil || u.Host == "" {
+		logger.Warnf("[frps插件] 面板回调地址无效: %q，跳过插件配置", base)
+		return ""
+	}
+
+	addr := u.Host
+	if u.Scheme == "https" {
+		addr = "https://" + u.Host
+	}
+	path := strings.TrimSuffix(u.Path, "/") + fmt.Sprintf("/api/frps-plugin/%d/%s", serverID, frpsPluginToken(serverID))
+
+	return fmt.Sprintf(`httpPlugins:
+  - name: "frp-web-panel"
+    addr: "%s"
+    path: "%s"
+    ops:
+      - %s
+`, addr, path, strings.Join(frpsPluginOps, "\n      - "))
+}
@@ -11,9 +11,11 @@
 package service
 
 import (
+	"frp-web-panel/internal/events"
 	"frp-web-panel/internal/model"
 	"frp-web-panel/internal/repository"
 	"frp-web-panel/internal/util"
+	"time"
 )
 
 type LogService struct {
@@ -34,13 +36,28 @@ func (s *LogService) GetLogs(page, pageSize int, operationType, resourceType str
 func (s *LogService) CreateLog(userID uint, operationType, resourceType string, resourceID uint, description, ipAddress string) error {
 	// 异步查询IP归属地，避免阻塞主流程
 	ipLocation := util.GetIPLocation(ipAddress)
-	return s.logRepo.CreateLog(userID, operationType, resourceType, resourceID, description, ipAddress, ipLocation)
+	if err := s.logRepo.CreateLog(userID, operationType, resourceType, resourceID, description, ipAddress, ipLocation); err != nil {
+		return err
+	}
+	publishOperationLog(userID, operationType, resourceType, resourceID, description, ipAddress, ipLocation)
+	return nil
 }
 
 // CreateLogAsync 异步创建操作日志（不阻塞主流程）
 func (s *LogService) CreateLogAsync(userID uint, operationType, resourceType string, resourceID uint, description, ipAddress string) {
-	go func() {
-		ipLocation := util.GetIPLocation(ipAddress)
-		s.logRepo.CreateLog(userID, operationType, resourceType, resourceID, description, ipAddress, ipLocation)
-	}()
+	go s.CreateLog(userID, operationType, resourceType, resourceID, description, ipAddress)
+}
+
+// publishOperationLog 发布操作日志事件，供日志转发等订阅方使用
+func publishOperationLog(userID uint, operationType, resourceType string, resourceID uint, description, ipAddress, ipLocation string) {
+	events.GetEventBus().PublishSync(events.OperationLogEvent{
+		UserID:        userID,
+		OperationType: operationType,
+		ResourceType:  resourceType,
+		ResourceID:    resourceID,
+		Description:   description,
+		IPAddress:     ipAddress,
+		IPLocation:    ipLocation,
+		Timestamp:     time.Now(),
+	})
 }
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/model"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	logSinkTimeout        = 10 * time.Second
+	defaultSyslogAppName  = "frp-panel"
+	defaultSyslogFacility = 16 // local0
+	// syslogSDID 结构化数据ID，32473 为 RFC5424 文档示例使用的私有企业号
+	syslogSDID = "frp@32473"
+)
+
+// LogStreamEntry 转发到日志平台的一条事件
+type LogStreamEntry struct {
+	Time    time.Time
+	Stream  string // alert, audit, system
+	Event   string // 告警类型、操作类型或系统事件类型
+	Level   string // info, warning
+	Message string
+	Fields  map[string]string // 结构化字段，syslog 写入 STRUCTURED-DATA，Loki 写入日志行 JSON
+}
+
+// logSinkWriter 日志转发目标的推送实现
+type logSinkWriter interface {
+	Validate(sink *model.LogSink) error
+	Write(sink *model.LogSink, entries []*LogStreamEntry) error
+}
+
+// logSinkRejectedError 目标拒绝接收该批事件，如请求格式错误、认证失败，重试不会成功
+type logSinkRejectedError struct {
+	err error
+}
+
+func (e *logSinkRejectedError) Error() string { return e.err.Error() }
+func (e *logSinkRejectedError) Unwrap() error { return e.err }
+
+var logSinkWriters = map[model.LogSinkType]logSinkWriter{
+	model.LogSinkSyslog: syslogWriter{},
+	model.LogSinkLoki:   lokiWriter{client: &http.Client{Timeout: logSinkTimeout}},
+}
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// parseSinkLabels 解析 k=v,k2=v2 格式的附加标签
+func parseSinkLabels(str string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(str, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !labelNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("标签格式错误: %s，应为 name=value，名称只能包含字母、数字和下划线", pair)
+		}
+		labels[name] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// entryLabels 事件的固定标签，附加标签不覆盖固定标签
+func entryLabels(sink *model.LogSink, entry *LogStreamEntry) map[string]string {
+	labels, _ := parseSinkLabels(sink.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels["job"] = defaultSyslogAppName
+	labels["stream"] = entry.Stream
+	labels["level"] = entry.Level
+	return labels
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// syslogWriter 按 RFC5424 格式发送，UDP 每条一个数据报，TCP/TLS 使用 RFC6587 octet-counting 分帧
+type syslogWriter struct{}
+
+func (syslogWriter) Validate(sink *model.LogSink) error {
+	if _, _, err := net.SplitHostPort(sink.Address); err != nil {
+		return fmt.Errorf("syslog 地址格式应为 host:port")
+	}
+	if sink.Protocol == "" {
+		sink.Protocol = "udp"
+	}
+	switch sink.Protocol {
+	case "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("不支持的 syslog 传输协议: %s", sink.Protocol)
+	}
+	if sink.Facility < 0 || sink.Facility > 23 {
+		return fmt.Errorf("syslog facility 应在 0-23 之间")
+	}
+	return nil
+}
+
+func (w syslogWriter) Write(sink *model.LogSink, entries []*LogStreamEntry) error {
+	conn, err := w.dial(sink)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(logSinkTimeout))
+
+	hostname, _ := os.Hostname()
+	for _, entry := range entries {
+		msg := formatSyslogMessage(sink, entry, hostname, os.Getpid())
+		if sink.Protocol != "udp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := io.WriteString(conn, msg); err != nil {
+			return fmt.Errorf("发送 syslog 失败: %w", err)
+		}
+	}
+	return nil
+}
+
+func (syslogWriter) dial(sink *model.LogSink) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: logSinkTimeout}
+	var conn net.Conn
+	var err error
+	switch sink.Protocol {
+	case "tls":
+		host, _, _ := net.SplitHostPort(sink.Address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", sink.Address, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: sink.TLSSkipVerify,
+		})
+	case "tcp":
+		conn, err = dialer.Dial("tcp", sink.Address)
+	default:
+		conn, err = dialer.Dial("udp", sink.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接 syslog 服务器失败: %w", err)
+	}
+	return conn, nil
+}
+
+// syslogSeverity 事件级别对应的 syslog severity
+func syslogSeverity(level string) int {
+	switch level {
+	case "error":
+		return 3
+	case "warning":
+		return 4
+	default:
+		return 6
+	}
+}
+
+// formatSyslogMessage 生成 RFC5424 消息：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func formatSyslogMessage(sink *model.LogSink, entry *LogStreamEntry, hostname string, pid int) string {
+	facility := sink.Facility
+	if facility == 0 {
+		facility = defaultSyslogFacility
+	}
+	appName := sink.AppName
+	if appName == "" {
+		appName = defaultSyslogAppName
+	}
+
+	params := entryLabels(sink, entry)
+	for k, v := range entry.Fields {
+		if _, exists := params[k]; !exists {
+			params[k] = v
+		}
+	}
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, k := range sortedKeys(params) {
+		fmt.Fprintf(&sd, ` %s="%s"`, syslogHeaderValue(k, 32), syslogParamEscaper.Replace(params[k]))
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		facility*8+syslogSeverity(entry.Level),
+		entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderValue(hostname, 255),
+		syslogHeaderValue(appName, 48),
+		pid,
+		syslogHeaderValue(entry.Event, 32),
+		sd.String(),
+		entry.Message)
+}
+
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogHeaderValue 头部字段只允许可见 ASCII 字符，为空时使用 NILVALUE
+func syslogHeaderValue(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// lokiWriter 通过 Loki 推送 API 发送，标签相同的事件合并为一个 stream
+type lokiWriter struct {
+	client *http.Client
+}
+
+func (lokiWriter) Validate(sink *model.LogSink) error {
+	u, err := url.Parse(sink.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Loki 地址必须是 http(s) URL")
+	}
+	return nil
+}
+
+// lokiPushURL 地址未包含路径时使用默认推送路径
+func lokiPushURL(address string) string {
+	u, err := url.Parse(address)
+	if err != nil || strings.Trim(u.Path, "/") != "" {
+		return address
+	}
+	u.Path = "/loki/api/v1/push"
+	return u.String()
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (w lokiWriter) Write(sink *model.LogSink, entries []*LogStreamEntry) error {
+	body, err := lokiPushBody(sink, entries)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, lokiPushURL(sink.Address), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sink.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", sink.TenantID)
+	}
+	if sink.Username != "" {
+		req.SetBasicAuth(sink.Username, sink.Password)
+	} else if sink.Password != "" {
+		req.Header.Set("Authorization", "Bearer "+sink.Password)
+	}
+
+	client := w.client
+	if sink.TLSSkipVerify {
+		client = &http.Client{
+			Timeout:   logSinkTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("推送到 Loki 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		err := fmt.Errorf("Loki 返回状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		// 4xx 为请求本身的问题，429 限流除外
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return &logSinkRejectedError{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// lokiPushBody 生成推送请求体，日志行为包含消息和结构化字段的 JSON
+func lokiPushBody(sink *model.LogSink, entries []*LogStreamEntry) ([]byte, error) {
+	streams := make(map[string]*lokiStream)
+	var order []string
+	for _, entry := range entries {
+		labels := entryLabels(sink, entry)
+		labels["event"] = entry.Event
+		var key strings.Builder
+		for _, k := range sortedKeys(labels) {
+			key.WriteString(k + "=" + labels[k] + ",")
+		}
+		stream, ok := streams[key.String()]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key.String()] = stream
+			order = append(order, key.String())
+		}
+
+		line := make(map[string]string, len(entry.Fields)+1)
+		for k, v := range entry.Fields {
+			line[k] = v
+		}
+		line["msg"] = entry.Message
+		lineJSON, err := json.Marshal(line)
+		if err != nil {
+			return nil, err
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.Time.UnixNano(), 10), string(lineJSON)})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		// Loki 拒绝同一 stream 中时间早于已写入日志的条目，按时间排序
+		values := streams[key].Values
+		sort.SliceStable(values, func(i, j int) bool {
+			ti, _ := strconv.ParseInt(values[i][0], 10, 64)
+			tj, _ := strconv.ParseInt(values[j][0], 10, 64)
+			return ti < tj
+		})
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+	return json.Marshal(payload)
+}
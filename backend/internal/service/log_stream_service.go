@@ -0,0 +1,406 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"frp-web-panel/internal/events"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// logStreamBufferLimit 每个转发目标最多缓存的事件数，目标长时间不可用时丢弃最早的事件
+	logStreamBufferLimit = 5000
+	// logStreamBatchSize 单次推送的最大事件数
+	logStreamBatchSize = 500
+)
+
+// LogStreamService 将告警事件、操作日志和系统事件转发到 syslog、Loki 等日志平台。
+// 事件经事件总线进入内存缓冲，由定时任务批量推送，推送失败的事件保留到下次重试
+type LogStreamService struct {
+	sinkRepo *repository.LogSinkRepository
+	userRepo *repository.UserRepository
+
+	mu             sync.Mutex
+	pending        []*LogStreamEntry          // 尚未分配到转发目标的事件
+	pendingDropped map[string]int64           // key: 事件流，value: 分配前因缓冲区满丢弃的事件数，分配时计入订阅该事件流的目标
+	queues         map[uint][]*LogStreamEntry // key: 转发目标ID，value: 等待推送或推送失败待重试的事件
+	dropped        map[uint]int64             // key: 转发目标ID，value: 因缓冲区满或被目标拒绝丢弃的事件数
+	flushMu        sync.Mutex
+
+	subscribeOnce sync.Once
+}
+
+var (
+	logStreamService     *LogStreamService
+	logStreamServiceOnce sync.Once
+)
+
+// GetLogStreamService 获取全局日志转发服务，缓冲区需在各服务间共享
+func GetLogStreamService() *LogStreamService {
+	logStreamServiceOnce.Do(func() {
+		logStreamService = newLogStreamService()
+	})
+	return logStreamService
+}
+
+func newLogStreamService() *LogStreamService {
+	return &LogStreamService{
+		sinkRepo:       repository.NewLogSinkRepository(),
+		userRepo:       repository.NewUserRepository(),
+		pendingDropped: make(map[string]int64),
+		queues:         make(map[uint][]*LogStreamEntry),
+		dropped:        make(map[uint]int64),
+	}
+}
+
+// Start 订阅告警、操作日志和系统事件
+func (s *LogStreamService) Start() {
+	s.subscribeOnce.Do(func() {
+		bus := events.GetEventBus()
+		bus.Subscribe(events.EventAlertLog, func(e events.Event) { s.Enqueue(alertLogEntry(e.(events.AlertLogEvent))) })
+		bus.Subscribe(events.EventOperationLog, func(e events.Event) { s.Enqueue(s.operationLogEntry(e.(events.OperationLogEvent))) })
+		bus.Subscribe(events.EventSystem, func(e events.Event) { s.Enqueue(systemEventEntry(e.(events.SystemEvent))) })
+	})
+}
+
+// Stop 停止前推送缓冲中的事件
+func (s *LogStreamService) Stop() {
+	s.Flush()
+}
+
+// Enqueue 加入待推送缓冲，缓冲区满时丢弃最早的事件
+func (s *LogStreamService) Enqueue(entry *LogStreamEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) >= logStreamBufferLimit {
+		s.pendingDropped[s.pending[0].Stream]++
+		s.pending = s.pending[1:]
+	}
+	s.pending = append(s.pending, entry)
+}
+
+// Flush 将缓冲的事件分配到启用的转发目标并推送，由定时任务调用
+func (s *LogStreamService) Flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	sinks, err := s.sinkRepo.FindEnabled()
+	if err != nil {
+		logger.Errorf("[日志转发] 获取转发目标失败: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	pendingDropped := s.pendingDropped
+	s.pendingDropped = make(map[string]int64)
+	enabled := make(map[uint]bool, len(sinks))
+	for i := range sinks {
+		sink := &sinks[i]
+		enabled[sink.ID] = true
+		streams := sinkStreams(sink)
+		for stream, n := range pendingDropped {
+			if streams == nil || streams[stream] {
+				s.dropped[sink.ID] += n
+			}
+		}
+		for _, entry := range pending {
+			if streams == nil || streams[entry.Stream] {
+				s.queues[sink.ID] = append(s.queues[sink.ID], entry)
+			}
+		}
+		if over := len(s.queues[sink.ID]) - logStreamBufferLimit; over > 0 {
+			s.queues[sink.ID] = s.queues[sink.ID][over:]
+			s.dropped[sink.ID] += int64(over)
+		}
+	}
+	// 停用或删除的目标不再保留缓冲
+	for id := range s.queues {
+		if !enabled[id] {
+			delete(s.queues, id)
+		}
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := range sinks {
+		sink := &sinks[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.flushSink(sink)
+		}()
+	}
+	wg.Wait()
+}
+
+// flushSink 分批推送目标的缓冲事件，失败时保留未推送的事件等待下次重试，
+// 被目标拒绝（如 Loki 返回 4xx）的批次重试也不会成功，直接丢弃
+func (s *LogStreamService) flushSink(sink *model.LogSink) {
+	writer, ok := logSinkWriters[sink.Type]
+	if !ok {
+		return
+	}
+	for {
+		s.mu.Lock()
+		batch := s.queues[sink.ID]
+		if len(batch) > logStreamBatchSize {
+			batch = batch[:logStreamBatchSize]
+		}
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+
+		err := writer.Write(sink, batch)
+		var rejected *logSinkRejectedError
+		switch {
+		case errors.As(err, &rejected):
+			logger.Warnf("[日志转发] %s(%s) 拒绝了 %d 条事件，已丢弃: %v", sink.Name, sink.Type, len(batch), err)
+			if sink.LastError != truncateError(err) {
+				s.sinkRepo.UpdateStatus(sink.ID, truncateError(err), nil)
+				sink.LastError = truncateError(err)
+			}
+		case err != nil:
+			logger.Warnf("[日志转发] 推送到 %s(%s) 失败，%d 条事件等待重试: %v", sink.Name, sink.Type, len(batch), err)
+			if sink.LastError != truncateError(err) {
+				s.sinkRepo.UpdateStatus(sink.ID, truncateError(err), nil)
+			}
+			return
+		default:
+			now := time.Now()
+			s.sinkRepo.UpdateStatus(sink.ID, "", &now)
+			sink.LastError = ""
+		}
+
+		s.mu.Lock()
+		queue, exists := s.queues[sink.ID]
+		if !exists {
+			s.mu.Unlock()
+			return // 推送期间目标已删除
+		}
+		s.queues[sink.ID] = queue[len(batch):]
+		if rejected != nil {
+			s.dropped[sink.ID] += int64(len(batch))
+		}
+		s.mu.Unlock()
+	}
+}
+
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > 500 {
+		msg = msg[:500]
+	}
+	return msg
+}
+
+// sinkStreams 目标转发的事件流，为空转发全部时返回 nil
+func sinkStreams(sink *model.LogSink) map[string]bool {
+	if strings.TrimSpace(sink.Streams) == "" {
+		return nil
+	}
+	streams := make(map[string]bool)
+	for _, stream := range strings.Split(sink.Streams, ",") {
+		streams[strings.TrimSpace(stream)] = true
+	}
+	return streams
+}
+
+func alertLogEntry(e events.AlertLogEvent) *LogStreamEntry {
+	level := "warning"
+	if e.AlertType == "recovery" || e.Action == "acknowledged" || e.Action == "resolved" {
+		level = "info"
+	}
+	fields := map[string]string{
+		"rule_id":     strconv.FormatUint(uint64(e.RuleID), 10),
+		"target_type": e.TargetType,
+		"target_id":   strconv.FormatUint(uint64(e.TargetID), 10),
+		"status":      e.Status,
+	}
+	if e.AlertID != 0 {
+		fields["alert_id"] = strconv.FormatUint(uint64(e.AlertID), 10)
+	}
+	if e.Silenced {
+		fields["silenced"] = "true"
+	}
+	if e.SuppressedBy != 0 {
+		fields["suppressed_by"] = strconv.FormatUint(uint64(e.SuppressedBy), 10)
+	}
+	if e.Action != "" {
+		fields["action"] = e.Action
+	}
+	if e.EscalationLevel != 0 {
+		fields["escalation_level"] = strconv.Itoa(e.EscalationLevel)
+	}
+	return &LogStreamEntry{Time: e.Timestamp, Stream: model.LogStreamAlert, Event: e.AlertType, Level: level, Message: e.Message, Fields: fields}
+}
+
+func (s *LogStreamService) operationLogEntry(e events.OperationLogEvent) *LogStreamEntry {
+	fields := map[string]string{
+		"user_id":       strconv.FormatUint(uint64(e.UserID), 10),
+		"resource_type": e.ResourceType,
+		"resource_id":   strconv.FormatUint(uint64(e.ResourceID), 10),
+		"ip":            e.IPAddress,
+	}
+	if e.IPLocation != "" {
+		fields["ip_location"] = e.IPLocation
+	}
+	if user, err := s.userRepo.FindByID(e.UserID); err == nil {
+		fields["username"] = user.Username
+	}
+	return &LogStreamEntry{Time: e.Timestamp, Stream: model.LogStreamAudit, Event: e.OperationType, Level: "info", Message: e.Description, Fields: fields}
+}
+
+// systemEventEntry 事件详情中的标量值作为结构化字段
+func systemEventEntry(e events.SystemEvent) *LogStreamEntry {
+	fields := make(map[string]string)
+	var data map[string]interface{}
+	if json.Unmarshal([]byte(e.Data), &data) == nil {
+		for k, v := range data {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}, nil:
+				continue
+			}
+			fields[k] = fmt.Sprint(v)
+		}
+	}
+	return &LogStreamEntry{Time: e.Timestamp, Stream: model.LogStreamSystem, Event: e.EventType, Level: e.Level, Message: e.Message, Fields: fields}
+}
+
+// GetAllSinks 获取全部转发目标及缓冲状态，密码不返回
+func (s *LogStreamService) GetAllSinks() ([]model.LogSink, error) {
+	sinks, err := s.sinkRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range sinks {
+		sinks[i].HasPassword = sinks[i].Password != ""
+		sinks[i].Pending = len(s.queues[sinks[i].ID])
+		sinks[i].Dropped = s.dropped[sinks[i].ID]
+	}
+	return sinks, nil
+}
+
+// CreateSink 创建转发目标
+func (s *LogStreamService) CreateSink(sink *model.LogSink) error {
+	sink.ID = 0
+	if err := validateLogSink(sink); err != nil {
+		return err
+	}
+	if err := s.sinkRepo.Create(sink); err != nil {
+		return fmt.Errorf("创建转发目标失败，名称可能已存在")
+	}
+	sink.HasPassword = sink.Password != ""
+	return nil
+}
+
+// UpdateSink 更新转发目标，密码为空时保留原值，clearPassword 为 true 时清空
+func (s *LogStreamService) UpdateSink(sink *model.LogSink, clearPassword bool) (*model.LogSink, error) {
+	existing, err := s.sinkRepo.FindByID(sink.ID)
+	if err != nil {
+		return nil, fmt.Errorf("转发目标不存在")
+	}
+	if sink.Password == "" && !clearPassword {
+		sink.Password = existing.Password
+	}
+	if err := validateLogSink(sink); err != nil {
+		return nil, err
+	}
+	if err := s.sinkRepo.Update(sink); err != nil {
+		return nil, fmt.Errorf("更新转发目标失败，名称可能已存在")
+	}
+	updated, err := s.sinkRepo.FindByID(sink.ID)
+	if err != nil {
+		return nil, err
+	}
+	updated.HasPassword = updated.Password != ""
+	return updated, nil
+}
+
+// DeleteSink 删除转发目标，未推送的缓冲事件一并丢弃
+func (s *LogStreamService) DeleteSink(id uint) error {
+	if _, err := s.sinkRepo.FindByID(id); err != nil {
+		return fmt.Errorf("转发目标不存在")
+	}
+	if err := s.sinkRepo.Delete(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.queues, id)
+	delete(s.dropped, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// TestSink 向已保存的目标同步发送一条测试事件
+func (s *LogStreamService) TestSink(id uint) error {
+	sink, err := s.sinkRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("转发目标不存在")
+	}
+	return sendTestLogEntry(sink)
+}
+
+// TestSinkConfig 使用未保存的配置发送测试事件，ID 不为空且密码为空时沿用已保存的值
+func (s *LogStreamService) TestSinkConfig(sink *model.LogSink) error {
+	if sink.ID != 0 && sink.Password == "" {
+		if existing, err := s.sinkRepo.FindByID(sink.ID); err == nil {
+			sink.Password = existing.Password
+		}
+	}
+	if err := validateLogSink(sink); err != nil {
+		return err
+	}
+	return sendTestLogEntry(sink)
+}
+
+func sendTestLogEntry(sink *model.LogSink) error {
+	return logSinkWriters[sink.Type].Write(sink, []*LogStreamEntry{{
+		Time:    time.Now(),
+		Stream:  model.LogStreamSystem,
+		Event:   "test",
+		Level:   "info",
+		Message: fmt.Sprintf("这是一条来自 FRP 管理面板的测试事件，收到说明转发目标「%s」配置正确。", sink.Name),
+		Fields:  map[string]string{"sink_type": string(sink.Type)},
+	}})
+}
+
+func validateLogSink(sink *model.LogSink) error {
+	sink.Name = strings.TrimSpace(sink.Name)
+	sink.Address = strings.TrimSpace(sink.Address)
+	if sink.Name == "" {
+		return fmt.Errorf("转发目标名称不能为空")
+	}
+	writer, ok := logSinkWriters[sink.Type]
+	if !ok {
+		return fmt.Errorf("不支持的转发目标类型: %s", sink.Type)
+	}
+	var streams []string
+	for _, stream := range strings.Split(sink.Streams, ",") {
+		stream = strings.TrimSpace(stream)
+		switch stream {
+		case "":
+			continue
+		case model.LogStreamAlert, model.LogStreamAudit, model.LogStreamSystem:
+			streams = append(streams, stream)
+		default:
+			return fmt.Errorf("不支持的事件流: %s", stream)
+		}
+	}
+	sink.Streams = strings.Join(streams, ",")
+	if _, err := parseSinkLabels(sink.Labels); err != nil {
+		return err
+	}
+	return writer.Validate(sink)
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"frp-web-panel/internal/events"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLogStreamTestDB(t *testing.T, sinks ...*model.LogSink) *LogStreamService {
+	newServiceTestDB(t, &model.LogSink{}, &model.User{})
+	svc := newLogStreamService()
+	for _, sink := range sinks {
+		require.NoError(t, svc.CreateSink(sink))
+	}
+	return svc
+}
+
+func testLogEntry(stream, message string) *LogStreamEntry {
+	return &LogStreamEntry{
+		Time:    time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC),
+		Stream:  stream,
+		Event:   "offline",
+		Level:   "warning",
+		Message: message,
+		Fields:  map[string]string{"target_type": "frpc", "note": `a "quoted" ]value`},
+	}
+}
+
+// TestFormatSyslogMessage 测试 RFC5424 消息格式及结构化数据转义
+func TestFormatSyslogMessage(t *testing.T) {
+	sink := &model.LogSink{Labels: "env=prod"}
+	msg := formatSyslogMessage(sink, testLogEntry(model.LogStreamAlert, "frpc home 已离线"), "panel host", 42)
+	assert.Equal(t, `<132>1 2026-03-15T10:00:00.000000Z panel_host frp-panel 42 offline `+
+		`[frp@32473 env="prod" job="frp-panel" level="warning" note="a \"quoted\" \]value" stream="alert" target_type="frpc"] frpc home 已离线`, msg)
+
+	sink = &model.LogSink{Facility: 1, AppName: "panel"}
+	entry := &LogStreamEntry{Time: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Stream: model.LogStreamAudit, Level: "info", Message: "x"}
+	assert.True(t, strings.HasPrefix(formatSyslogMessage(sink, entry, "", 1), "<14>1 2026-03-15T10:00:00.000000Z - panel 1 - "))
+}
+
+// TestValidateLogSink 测试转发目标校验
+func TestValidateLogSink(t *testing.T) {
+	sink := &model.LogSink{Name: " syslog ", Type: model.LogSinkSyslog, Address: "127.0.0.1:514", Streams: "alert, audit"}
+	require.NoError(t, validateLogSink(sink))
+	assert.Equal(t, "syslog", sink.Name)
+	assert.Equal(t, "udp", sink.Protocol, "默认使用 UDP")
+	assert.Equal(t, "alert,audit", sink.Streams)
+
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: "kafka"}), "不支持的转发目标类型")
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: model.LogSinkSyslog, Address: "127.0.0.1"}), "host:port")
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: model.LogSinkSyslog, Address: "h:514", Protocol: "quic"}), "传输协议")
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: model.LogSinkSyslog, Address: "h:514", Facility: 24}), "facility")
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: model.LogSinkLoki, Address: "loki:3100"}), "http(s)")
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: model.LogSinkLoki, Address: "http://loki", Streams: "metrics"}), "不支持的事件流")
+	assert.ErrorContains(t, validateLogSink(&model.LogSink{Name: "x", Type: model.LogSinkLoki, Address: "http://loki", Labels: "bad-name=1"}), "标签格式错误")
+
+	assert.Equal(t, "http://loki:3100/loki/api/v1/push", lokiPushURL("http://loki:3100"))
+	assert.Equal(t, "http://gw/custom/push", lokiPushURL("http://gw/custom/push"))
+}
+
+// TestLogStreamService_FlushSyslog 测试通过 UDP 和 TCP 推送到本地 syslog 监听，并按事件流过滤
+func TestLogStreamService_FlushSyslog(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer udp.Close()
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcp.Close()
+
+	tcpFrames := make(chan string, 10)
+	go func() {
+		conn, err := tcp.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+			tcpFrames <- string(frame)
+		}
+	}()
+
+	svc := setupLogStreamTestDB(t,
+		&model.LogSink{Name: "udp", Type: model.LogSinkSyslog, Address: udp.LocalAddr().String(), Enabled: true},
+		&model.LogSink{Name: "tcp", Type: model.LogSinkSyslog, Address: tcp.Addr().String(), Protocol: "tcp", Streams: "audit", Enabled: true},
+	)
+	svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc home 已离线"))
+	audit := testLogEntry(model.LogStreamAudit, "更新代理 web")
+	audit.Level = "info"
+	svc.Enqueue(audit)
+	svc.Flush()
+
+	buf := make([]byte, 4096)
+	var received []string
+	for i := 0; i < 2; i++ {
+		udp.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := udp.ReadFrom(buf)
+		require.NoError(t, err)
+		received = append(received, string(buf[:n]))
+	}
+	assert.Contains(t, received[0], `stream="alert"`)
+	assert.True(t, strings.HasSuffix(received[0], "frpc home 已离线"))
+	assert.Contains(t, received[1], `stream="audit"`)
+
+	select {
+	case frame := <-tcpFrames:
+		assert.True(t, strings.HasPrefix(frame, "<134>1 "), "审计事件为 info 级别")
+		assert.True(t, strings.HasSuffix(frame, "更新代理 web"))
+	case <-time.After(2 * time.Second):
+		t.Fatal("TCP 未收到事件")
+	}
+	select {
+	case frame := <-tcpFrames:
+		t.Fatalf("只转发 audit 事件流，收到: %s", frame)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	sinks, err := svc.GetAllSinks()
+	require.NoError(t, err)
+	for _, sink := range sinks {
+		assert.NotNil(t, sink.LastSentAt)
+		assert.Zero(t, sink.Pending)
+	}
+}
+
+// TestLogStreamService_FlushLokiRetry 测试 Loki 推送失败时保留事件，下次推送成功后按标签分组发送
+func TestLogStreamService_FlushLokiRetry(t *testing.T) {
+	var mu sync.Mutex
+	fail := true
+	var bodies [][]byte
+	var headers []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		if fail {
+			http.Error(w, "ingester unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		headers = append(headers, r.Header.Clone())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	svc := setupLogStreamTestDB(t, &model.LogSink{
+		Name: "loki", Type: model.LogSinkLoki, Address: srv.URL, Labels: "env=prod",
+		TenantID: "ops", Password: "secret-token", Enabled: true,
+	})
+	svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc home 已离线"))
+	svc.Flush()
+
+	sinks, err := svc.GetAllSinks()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sinks[0].Pending, "推送失败的事件保留等待重试")
+	assert.Contains(t, sinks[0].LastError, "503")
+	assert.True(t, sinks[0].HasPassword)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc office 已离线"))
+	svc.Enqueue(testLogEntry(model.LogStreamSystem, "证书续签失败"))
+	svc.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, "ops", headers[0].Get("X-Scope-OrgID"))
+	assert.Equal(t, "Bearer secret-token", headers[0].Get("Authorization"))
+
+	var payload struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal(bodies[0], &payload))
+	require.Len(t, payload.Streams, 2, "按标签分为 alert 和 system 两个 stream")
+	alert := payload.Streams[0]
+	assert.Equal(t, map[string]string{"env": "prod", "job": "frp-panel", "stream": "alert", "level": "warning", "event": "offline"}, alert.Stream)
+	require.Len(t, alert.Values, 2)
+	assert.Equal(t, strconv.FormatInt(time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC).UnixNano(), 10), alert.Values[0][0])
+	var line map[string]string
+	require.NoError(t, json.Unmarshal([]byte(alert.Values[0][1]), &line))
+	assert.Equal(t, "frpc home 已离线", line["msg"])
+	assert.Equal(t, "frpc", line["target_type"])
+
+	sinks, err = svc.GetAllSinks()
+	require.NoError(t, err)
+	assert.Zero(t, sinks[0].Pending)
+	assert.Empty(t, sinks[0].LastError)
+}
+
+// TestLogStreamService_FlushLokiRejected 测试 Loki 返回 4xx 时丢弃该批事件并计数，429 保留等待重试
+func TestLogStreamService_FlushLokiRejected(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusBadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		http.Error(w, "entry out of order", status)
+	}))
+	defer srv.Close()
+
+	svc := setupLogStreamTestDB(t, &model.LogSink{Name: "loki", Type: model.LogSinkLoki, Address: srv.URL, Enabled: true})
+	svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc home 已离线"))
+	svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc office 已离线"))
+	svc.Flush()
+
+	sinks, err := svc.GetAllSinks()
+	require.NoError(t, err)
+	assert.Zero(t, sinks[0].Pending, "被拒绝的事件不再重试")
+	assert.Equal(t, int64(2), sinks[0].Dropped)
+	assert.Contains(t, sinks[0].LastError, "400")
+
+	mu.Lock()
+	status = http.StatusTooManyRequests
+	mu.Unlock()
+	svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc home 已离线"))
+	svc.Flush()
+	sinks, err = svc.GetAllSinks()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sinks[0].Pending, "限流时保留等待重试")
+	assert.Equal(t, int64(2), sinks[0].Dropped)
+}
+
+// TestLogStreamService_PendingOverflow 测试分配前缓冲区满丢弃的事件计入订阅该事件流的目标
+func TestLogStreamService_PendingOverflow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "ingester unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	svc := setupLogStreamTestDB(t,
+		&model.LogSink{Name: "all", Type: model.LogSinkLoki, Address: srv.URL, Enabled: true},
+		&model.LogSink{Name: "audit", Type: model.LogSinkLoki, Address: srv.URL, Streams: "audit", Enabled: true},
+	)
+	for i := 0; i < logStreamBufferLimit+3; i++ {
+		svc.Enqueue(testLogEntry(model.LogStreamAlert, "frpc home 已离线"))
+	}
+	svc.Flush()
+
+	sinks, err := svc.GetAllSinks()
+	require.NoError(t, err)
+	dropped := map[string]int64{}
+	for _, sink := range sinks {
+		dropped[sink.Name] = sink.Dropped
+	}
+	assert.Equal(t, map[string]int64{"all": 3, "audit": 0}, dropped)
+}
+
+// TestLokiPushBody_Order 测试同一 stream 内的日志按时间排序
+func TestLokiPushBody_Order(t *testing.T) {
+	later := testLogEntry(model.LogStreamAlert, "later")
+	later.Time = later.Time.Add(time.Second)
+	body, err := lokiPushBody(&model.LogSink{}, []*LogStreamEntry{later, testLogEntry(model.LogStreamAlert, "earlier")})
+	require.NoError(t, err)
+
+	var payload struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Len(t, payload.Streams, 1)
+	require.Len(t, payload.Streams[0].Values, 2)
+	assert.Contains(t, payload.Streams[0].Values[0][1], "earlier")
+	assert.Contains(t, payload.Streams[0].Values[1][1], "later")
+}
+
+// TestLogStreamService_Events 测试事件总线事件转换为转发事件
+func TestLogStreamService_Events(t *testing.T) {
+	at := time.Now()
+	entry := alertLogEntry(events.AlertLogEvent{AlertID: 3, RuleID: 1, TargetType: "frpc", TargetID: 2,
+		AlertType: "offline", Status: model.AlertStatusFiring, Message: "frpc home 已离线", SuppressedBy: 9, Timestamp: at})
+	assert.Equal(t, model.LogStreamAlert, entry.Stream)
+	assert.Equal(t, "warning", entry.Level)
+	assert.Equal(t, "9", entry.Fields["suppressed_by"])
+	assert.Equal(t, "3", entry.Fields["alert_id"])
+
+	entry = alertLogEntry(events.AlertLogEvent{AlertType: "recovery", Timestamp: at})
+	assert.Equal(t, "info", entry.Level)
+	assert.NotContains(t, entry.Fields, "alert_id")
+
+	entry = alertLogEntry(events.AlertLogEvent{AlertID: 3, AlertType: "offline", Action: "acknowledged", Timestamp: at})
+	assert.Equal(t, "info", entry.Level)
+	assert.Equal(t, "acknowledged", entry.Fields["action"])
+	entry = alertLogEntry(events.AlertLogEvent{AlertID: 3, AlertType: "offline", Action: "escalated", EscalationLevel: 2, Timestamp: at})
+	assert.Equal(t, "warning", entry.Level)
+	assert.Equal(t, "2", entry.Fields["escalation_level"])
+
+	entry = systemEventEntry(events.SystemEvent{EventType: model.RuleTypeLoginFailed, Level: "warning",
+		Message: "登录失败", Data: `{"username":"admin","ip":"1.2.3.4","ids":[1,2]}`, Timestamp: at})
+	assert.Equal(t, model.LogStreamSystem, entry.Stream)
+	assert.Equal(t, map[string]string{"username": "admin", "ip": "1.2.3.4"}, entry.Fields)
+
+	svc := setupLogStreamTestDB(t)
+	require.NoError(t, database.DB.Create(&model.User{Username: "admin", Password: "x"}).Error)
+	entry = svc.operationLogEntry(events.OperationLogEvent{UserID: 1, OperationType: "update", ResourceType: "proxy",
+		ResourceID: 5, Description: "更新代理 web", IPAddress: "127.0.0.1", Timestamp: at})
+	assert.Equal(t, model.LogStreamAudit, entry.Stream)
+	assert.Equal(t, "update", entry.Event)
+	assert.Equal(t, "admin", entry.Fields["username"])
+}
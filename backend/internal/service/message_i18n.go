@@ -0,0 +1,127 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"strings"
+)
+
+// messageCatalog 告警消息中除模板外的固定文字，如通知字段名称、邮件按钮和汇总消息
+var messageCatalog = map[string]map[string]string{
+	model.LocaleZhCN: {
+		"field.proxy":            "代理",
+		"field.alert_type":       "告警类型",
+		"field.current_value":    "当前值",
+		"field.threshold":        "阈值",
+		"field.target":           "目标",
+		"field.target_type":      "目标类型",
+		"field.target_name":      "目标名称",
+		"field.expression":       "表达式",
+		"field.escalation_level": "升级级别",
+		"field.duration":         "已持续",
+		"email.time":             "时间",
+		"email.instructions":     "点击下方按钮查看详情：",
+		"email.button":           "查看详情",
+		"email.outro.alert":      "请及时处理。",
+		"email.outro.recovery":   "服务已恢复正常运行。",
+		"email.copyright":        "© FRP Panel - 内网穿透管理平台",
+		"email.trouble":          "如果按钮无法点击，请复制以下链接到浏览器打开：",
+		"digest.title":           "FRP告警汇总 - %s（%d条）",
+		"digest.content":         "%s 短时间内产生 %d 条通知，已合并发送",
+		"digest.email_title":     "告警汇总通知",
+		"digest.email_intro":     "%s 短时间内产生 %d 条通知，已合并为一封邮件发送。",
+		"digest.email_dropped":   "此前有 %d 条通知因超过发送上限未发送，请登录面板查看告警记录。",
+		"digest.notification":    "通知",
+		"digest.content_column":  "内容",
+		"digest.dropped":         "限流丢弃",
+		"digest.dropped_value":   "此前 %d 条通知因超过发送上限未发送",
+	},
+	model.LocaleEnUS: {
+		"field.proxy":            "Proxy",
+		"field.alert_type":       "Alert type",
+		"field.current_value":    "Current value",
+		"field.threshold":        "Threshold",
+		"field.target":           "Target",
+		"field.target_type":      "Target type",
+		"field.target_name":      "Target name",
+		"field.expression":       "Expression",
+		"field.escalation_level": "Escalation level",
+		"field.duration":         "Lasting",
+		"email.time":             "Time",
+		"email.instructions":     "Click the button below to view details:",
+		"email.button":           "View details",
+		"email.outro.alert":      "Please take action promptly.",
+		"email.outro.recovery":   "The service is back to normal.",
+		"email.copyright":        "© FRP Panel",
+		"email.trouble":          "If the button does not work, copy the link below into your browser:",
+		"digest.title":           "FRP alert digest - %s (%d)",
+		"digest.content":         "%s produced %d notifications in a short time, merged into one message",
+		"digest.email_title":     "Alert digest",
+		"digest.email_intro":     "%s produced %d notifications in a short time, merged into one email.",
+		"digest.email_dropped":   "%d earlier notifications were not sent because the rate limit was exceeded, see the alert logs in the panel.",
+		"digest.notification":    "Notification",
+		"digest.content_column":  "Details",
+		"digest.dropped":         "Rate limited",
+		"digest.dropped_value":   "%d earlier notifications were not sent because the rate limit was exceeded",
+	},
+}
+
+// enEventNames 事件类型的英文名称，中文名称见 alertTypeName
+var enEventNames = map[string]string{
+	"offline":                      "Offline",
+	"recovery":                     "Recovery",
+	"daily":                        "Daily traffic",
+	"monthly":                      "Monthly traffic",
+	"rate":                         "Traffic rate",
+	"quota":                        "Quota usage",
+	model.RuleTypeMetric:           "Metric",
+	model.RuleTypeCertApplySuccess: "Certificate issued",
+	model.RuleTypeCertApplyFailed:  "Certificate request failed",
+	model.RuleTypeCertExpiring:     "Certificate expiring",
+	model.RuleTypeCertExpired:      "Certificate expired",
+	model.RuleTypeCertRenewSuccess: "Certificate renewed",
+	model.RuleTypeCertRenewFailed:  "Certificate renewal failed",
+	model.RuleTypeDNSSyncSuccess:   "DNS sync succeeded",
+	model.RuleTypeDNSSyncFailed:    "DNS sync failed",
+	model.RuleTypeLoginFailed:      "Login failed",
+	model.RuleTypeConfigChanged:    "Configuration changed",
+	model.RuleTypeQuotaExceeded:    "Traffic quota exceeded",
+	model.RuleTypeQuotaRestored:    "Traffic quota restored",
+	model.RuleTypeAccessBlocked:    "Access blocked",
+}
+
+// normalizeLocale 将语言设置规范为支持的语言，未知语言使用中文
+func normalizeLocale(locale string) string {
+	if strings.HasPrefix(strings.ToLower(locale), "en") {
+		return model.LocaleEnUS
+	}
+	return model.LocaleZhCN
+}
+
+// notifyLocale 读取告警通知语言设置
+func notifyLocale() string {
+	locale, _ := repository.NewSettingRepository().GetSetting("notify_locale")
+	return normalizeLocale(locale)
+}
+
+// translate 获取固定文字的译文，缺失时依次使用中文和键名
+func translate(locale, key string) string {
+	if text, ok := messageCatalog[normalizeLocale(locale)][key]; ok {
+		return text
+	}
+	if text, ok := messageCatalog[model.LocaleZhCN][key]; ok {
+		return text
+	}
+	return key
+}
+
+// eventTypeName 事件类型的本地化名称
+func eventTypeName(locale, eventType string) string {
+	if normalizeLocale(locale) == model.LocaleEnUS {
+		if name, ok := enEventNames[eventType]; ok {
+			return name
+		}
+		return eventType
+	}
+	return alertTypeName(eventType)
+}
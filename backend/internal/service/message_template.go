@@ -0,0 +1,595 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MessageTemplateData 消息模板可用的变量，模板中以 {{.TargetName}} 的形式引用
+type MessageTemplateData struct {
+	EventType       string                 `json:"event_type"`       // 告警类型，如 offline、daily、metric、cert_renew_failed
+	EventName       string                 `json:"event_name"`       // 告警类型的本地化名称
+	Level           string                 `json:"level"`            // alert/recovery/info
+	TargetType      string                 `json:"target_type"`      // proxy/frpc/frps/probe/system
+	TargetName      string                 `json:"target_name"`      // 目标名称，系统事件为空
+	Message         string                 `json:"message"`          // 告警日志中记录的消息
+	CurrentValue    string                 `json:"current_value"`    // 已格式化的当前值
+	Threshold       string                 `json:"threshold"`        // 已格式化的阈值
+	Expression      string                 `json:"expression"`       // 指标规则表达式
+	EscalationLevel int                    `json:"escalation_level"` // 告警升级级别
+	Duration        string                 `json:"duration"`         // 告警升级时已持续的时长
+	Time            string                 `json:"time"`             // 告警时间，格式 2006-01-02 15:04:05
+	EventData       map[string]interface{} `json:"event_data"`       // 系统事件详情，如 {{.EventData.domain}}
+	PanelURL        string                 `json:"panel_url"`        // 面板访问地址
+}
+
+// MessageTemplateVariable 模板变量说明
+type MessageTemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// messageTemplateVariables 模板变量说明，与 MessageTemplateData 的字段对应
+var messageTemplateVariables = []MessageTemplateVariable{
+	{Name: ".EventType", Description: "告警类型，如 offline、daily、metric、cert_renew_failed"},
+	{Name: ".EventName", Description: "告警类型的名称，随通知语言变化"},
+	{Name: ".Level", Description: "消息级别: alert/recovery/info"},
+	{Name: ".TargetType", Description: "目标类型: proxy/frpc/frps/probe/system"},
+	{Name: ".TargetName", Description: "目标名称，系统事件为空"},
+	{Name: ".Message", Description: "告警日志中记录的消息"},
+	{Name: ".CurrentValue", Description: "当前值，流量告警为格式化后的字节数"},
+	{Name: ".Threshold", Description: "阈值"},
+	{Name: ".Expression", Description: "指标规则表达式"},
+	{Name: ".EscalationLevel", Description: "告警升级级别"},
+	{Name: ".Duration", Description: "告警升级时已持续的时长"},
+	{Name: ".Time", Description: "告警时间，格式 2006-01-02 15:04:05"},
+	{Name: ".EventData", Description: "系统事件详情，如 {{.EventData.domain}}、{{.EventData.error}}"},
+	{Name: ".PanelURL", Description: "面板访问地址"},
+}
+
+// builtinMessageTemplate 内置消息模板
+type builtinMessageTemplate struct {
+	Subject string
+	Body    string
+}
+
+// builtinMessageEvents 内置模板的事件类型，按展示顺序排列
+var builtinMessageEvents = []string{
+	model.MessageEventTraffic,
+	model.MessageEventOffline,
+	model.MessageEventRecovery,
+	model.MessageEventMetric,
+	model.MessageEventMetricRecovery,
+	model.MessageEventEscalation,
+	model.MessageEventSystem,
+}
+
+// systemMessageEvents 可单独配置模板的系统告警规则类型
+var systemMessageEvents = []string{
+	model.RuleTypeCertApplySuccess,
+	model.RuleTypeCertApplyFailed,
+	model.RuleTypeCertExpiring,
+	model.RuleTypeCertExpired,
+	model.RuleTypeCertRenewSuccess,
+	model.RuleTypeCertRenewFailed,
+	model.RuleTypeDNSSyncSuccess,
+	model.RuleTypeDNSSyncFailed,
+	model.RuleTypeLoginFailed,
+	model.RuleTypeConfigChanged,
+	model.RuleTypeQuotaExceeded,
+	model.RuleTypeQuotaRestored,
+	model.RuleTypeAccessBlocked,
+}
+
+// builtinMessageTemplates 各语言的内置模板，中文模板沿用原邮件的固定文字，通知渠道与邮件共用标题
+var builtinMessageTemplates = map[string]map[string]builtinMessageTemplate{
+	model.LocaleZhCN: {
+		model.MessageEventTraffic:        {Subject: "FRP流量告警 - {{.TargetName}}", Body: "{{.Message}}"},
+		model.MessageEventOffline:        {Subject: "FRP离线告警 - {{.TargetType}} {{.TargetName}}", Body: "{{.Message}}"},
+		model.MessageEventRecovery:       {Subject: "FRP恢复通知 - {{.TargetType}} {{.TargetName}} 已恢复在线", Body: "{{.TargetType}} {{.TargetName}} 已恢复在线"},
+		model.MessageEventMetric:         {Subject: "FRP指标告警 - {{.TargetName}}", Body: "{{.Message}}"},
+		model.MessageEventMetricRecovery: {Subject: "FRP指标恢复 - {{.TargetName}}", Body: "{{.Message}}"},
+		model.MessageEventEscalation:     {Subject: "FRP告警 - 第{{.EscalationLevel}}级通知", Body: "{{.Message}}"},
+		model.MessageEventSystem:         {Subject: "FRP系统告警 - {{.EventName}}", Body: "{{.Message}}"},
+	},
+	model.LocaleEnUS: {
+		model.MessageEventTraffic: {
+			Subject: "FRP traffic alert - {{.TargetName}}",
+			Body:    "{{.EventName}} of proxy {{.TargetName}} exceeded the threshold: {{.CurrentValue}} / {{.Threshold}}",
+		},
+		model.MessageEventOffline: {
+			Subject: "FRP offline alert - {{.TargetType}} {{.TargetName}}",
+			Body:    "{{.TargetType}} {{.TargetName}} is offline since {{.Time}}",
+		},
+		model.MessageEventRecovery: {
+			Subject: "FRP recovery - {{.TargetType}} {{.TargetName}}",
+			Body:    "{{.TargetType}} {{.TargetName}} is back online",
+		},
+		model.MessageEventMetric: {
+			Subject: "FRP metric alert - {{.TargetName}}",
+			Body:    "{{.TargetType}} {{.TargetName}} matched {{.Expression}}, current value {{.CurrentValue}}",
+		},
+		model.MessageEventMetricRecovery: {
+			Subject: "FRP metric recovered - {{.TargetName}}",
+			Body:    "{{.TargetType}} {{.TargetName}} is back to normal, current value {{.CurrentValue}}",
+		},
+		model.MessageEventEscalation: {
+			Subject: "FRP alert - level {{.EscalationLevel}} escalation",
+			Body:    "{{.EventName}} alert{{if .TargetName}} on {{.TargetName}}{{end}} has not been acknowledged for {{.Duration}}",
+		},
+		model.MessageEventSystem: {
+			Subject: "FRP system alert - {{.EventName}}",
+			Body: "System event: {{.EventName}}" +
+				"{{with .EventData.domain}}, domain {{.}}{{end}}" +
+				"{{with .EventData.target_name}}, target {{.}}{{end}}" +
+				"{{with .EventData.proxy_name}}, proxy {{.}}{{end}}" +
+				"{{with .EventData.username}}, user {{.}}{{end}}" +
+				"{{with .EventData.ip}}, IP {{.}}{{end}}" +
+				"{{with .EventData.key}}, setting {{.}}{{end}}" +
+				"{{with .EventData.error}}\nError: {{.}}{{end}}",
+		},
+	},
+}
+
+// MessageTemplateService 告警消息模板，按事件类型和发送方式渲染标题和正文，
+// 依次使用用户模板、通知语言对应的内置模板
+type MessageTemplateService struct {
+	repo *repository.MessageTemplateRepository
+}
+
+func NewMessageTemplateService() *MessageTemplateService {
+	return &MessageTemplateService{repo: repository.NewMessageTemplateRepository()}
+}
+
+// isMessageEvent 是否为可配置模板的事件类型
+func isMessageEvent(eventType string) bool {
+	if _, ok := builtinMessageTemplates[model.LocaleZhCN][eventType]; ok {
+		return true
+	}
+	for _, t := range systemMessageEvents {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinTemplate 获取内置模板，系统告警规则类型使用 system 模板
+func builtinTemplate(locale, eventType string) builtinMessageTemplate {
+	templates := builtinMessageTemplates[normalizeLocale(locale)]
+	if tpl, ok := templates[eventType]; ok {
+		return tpl
+	}
+	return templates[model.MessageEventSystem]
+}
+
+// executeMessageTemplate 渲染标题和正文，标题中的换行替换为空格
+func executeMessageTemplate(subject, body string, data *MessageTemplateData) (string, string, error) {
+	render := func(name, text string) (string, error) {
+		tpl, err := template.New(name).Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	s, err := render("subject", subject)
+	if err != nil {
+		return "", "", fmt.Errorf("标题模板错误: %w", err)
+	}
+	b, err := render("body", body)
+	if err != nil {
+		return "", "", fmt.Errorf("正文模板错误: %w", err)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(s, "\n", " ")), strings.TrimSpace(b), nil
+}
+
+func (s *MessageTemplateService) GetAll() ([]model.MessageTemplate, error) {
+	return s.repo.FindAll()
+}
+
+// validate 校验事件类型、发送方式，并用示例数据试渲染模板
+func (s *MessageTemplateService) validate(tpl *model.MessageTemplate) error {
+	if !isMessageEvent(tpl.EventType) {
+		return fmt.Errorf("不支持的事件类型: %s", tpl.EventType)
+	}
+	if tpl.Channel != model.MessageChannelEmail && tpl.Channel != model.MessageChannelIM {
+		return fmt.Errorf("发送方式只能为 email 或 im")
+	}
+	if strings.TrimSpace(tpl.Subject) == "" || strings.TrimSpace(tpl.Body) == "" {
+		return fmt.Errorf("标题和正文不能为空")
+	}
+	data := sampleTemplateData(tpl.EventType, model.LocaleZhCN, time.Now())
+	_, _, err := executeMessageTemplate(tpl.Subject, tpl.Body, data)
+	return err
+}
+
+func (s *MessageTemplateService) Create(tpl *model.MessageTemplate) error {
+	if err := s.validate(tpl); err != nil {
+		return err
+	}
+	if _, err := s.repo.FindByEventChannel(tpl.EventType, tpl.Channel); err == nil {
+		return fmt.Errorf("事件 %s 的 %s 模板已存在", tpl.EventType, tpl.Channel)
+	}
+	return s.repo.Create(tpl)
+}
+
+func (s *MessageTemplateService) Update(tpl *model.MessageTemplate) (*model.MessageTemplate, error) {
+	existing, err := s.repo.FindByID(tpl.ID)
+	if err != nil {
+		return nil, fmt.Errorf("模板不存在")
+	}
+	if err := s.validate(tpl); err != nil {
+		return nil, err
+	}
+	if other, err := s.repo.FindByEventChannel(tpl.EventType, tpl.Channel); err == nil && other.ID != tpl.ID {
+		return nil, fmt.Errorf("事件 %s 的 %s 模板已存在", tpl.EventType, tpl.Channel)
+	}
+	tpl.CreatedAt = existing.CreatedAt
+	if err := s.repo.Update(tpl); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+func (s *MessageTemplateService) Delete(id uint) error {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return fmt.Errorf("模板不存在")
+	}
+	return s.repo.Delete(id)
+}
+
+// MessageTemplateDefault 内置模板
+type MessageTemplateDefault struct {
+	EventType string `json:"event_type"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+}
+
+// MessageTemplateDefaults 内置模板及模板变量说明
+type MessageTemplateDefaults struct {
+	Locale       string                    `json:"locale"`
+	Templates    []MessageTemplateDefault  `json:"templates"`
+	SystemEvents []string                  `json:"system_events"` // 可单独配置模板的系统告警规则类型，未配置时使用 system 模板
+	Variables    []MessageTemplateVariable `json:"variables"`
+}
+
+// GetDefaults 获取指定语言的内置模板，locale 为空时使用通知语言设置
+func (s *MessageTemplateService) GetDefaults(locale string) *MessageTemplateDefaults {
+	if locale == "" {
+		locale = notifyLocale()
+	}
+	locale = normalizeLocale(locale)
+	defaults := &MessageTemplateDefaults{Locale: locale, SystemEvents: systemMessageEvents, Variables: messageTemplateVariables}
+	for _, event := range builtinMessageEvents {
+		tpl := builtinMessageTemplates[locale][event]
+		defaults.Templates = append(defaults.Templates, MessageTemplateDefault{EventType: event, Subject: tpl.Subject, Body: tpl.Body})
+	}
+	return defaults
+}
+
+// render 按用户模板或内置模板渲染，用户模板渲染失败时使用内置模板
+func (s *MessageTemplateService) render(eventType, channel, locale string, data *MessageTemplateData) (string, string) {
+	keys := []string{eventType}
+	if _, ok := builtinMessageTemplates[model.LocaleZhCN][eventType]; !ok {
+		keys = append(keys, model.MessageEventSystem)
+	}
+	for _, key := range keys {
+		tpl, err := s.repo.FindByEventChannel(key, channel)
+		if err != nil || !tpl.Enabled {
+			continue
+		}
+		subject, body, err := executeMessageTemplate(tpl.Subject, tpl.Body, data)
+		if err == nil {
+			return subject, body
+		}
+		logger.Warnf("[消息模板] 模板 %s/%s 渲染失败，使用内置模板: %v", key, channel, err)
+	}
+
+	builtin := builtinTemplate(locale, eventType)
+	subject, body, err := executeMessageTemplate(builtin.Subject, builtin.Body, data)
+	if err != nil {
+		logger.Errorf("[消息模板] 内置模板 %s 渲染失败: %v", eventType, err)
+		return data.EventName, data.Message
+	}
+	return subject, body
+}
+
+// ComposedMessage 按模板渲染后的告警消息，同时用于通知渠道和邮件
+type ComposedMessage struct {
+	Message *NotificationMessage
+	Email   AlertEmailData
+}
+
+// EmailSender 返回发送该消息邮件的函数
+func (m *ComposedMessage) EmailSender(emailService *EmailService) func(to string) error {
+	return func(to string) error {
+		html, _, err := GenerateAlertEmail(m.Email)
+		if err != nil {
+			return err
+		}
+		return emailService.SendHTMLEmail(to, m.Email.Subject, html, "text/html")
+	}
+}
+
+// Compose 按通知语言渲染消息，eventType 为模板事件类型
+func (s *MessageTemplateService) Compose(eventType string, at time.Time, data *MessageTemplateData) *ComposedMessage {
+	locale := notifyLocale()
+	fillTemplateData(data, locale, at)
+	title, content := s.render(eventType, model.MessageChannelIM, locale, data)
+	subject, body := s.render(eventType, model.MessageChannelEmail, locale, data)
+	fields := messageFields(eventType, locale, data)
+	return &ComposedMessage{
+		Message: &NotificationMessage{
+			Title:     title,
+			Content:   content,
+			Level:     data.Level,
+			EventType: data.EventType,
+			Fields:    fields,
+			Time:      at,
+		},
+		Email: AlertEmailData{Locale: locale, Subject: subject, Body: body, Level: data.Level, Fields: fields, Time: at},
+	}
+}
+
+// messageFields 通知渠道消息和邮件中附带的结构化字段，系统事件展开事件详情
+func messageFields(eventType, locale string, data *MessageTemplateData) []NotificationField {
+	field := func(key, value string) NotificationField {
+		return NotificationField{Name: translate(locale, key), Value: value}
+	}
+	switch eventType {
+	case model.MessageEventTraffic:
+		return []NotificationField{
+			field("field.proxy", data.TargetName),
+			field("field.alert_type", data.EventName),
+			field("field.current_value", data.CurrentValue),
+			field("field.threshold", data.Threshold),
+		}
+	case model.MessageEventOffline, model.MessageEventRecovery:
+		return []NotificationField{
+			field("field.target_type", data.TargetType),
+			field("field.target_name", data.TargetName),
+		}
+	case model.MessageEventMetric:
+		return []NotificationField{
+			field("field.target", data.TargetName),
+			field("field.expression", data.Expression),
+		}
+	case model.MessageEventMetricRecovery:
+		return []NotificationField{
+			field("field.expression", data.Expression),
+			field("field.current_value", data.CurrentValue),
+		}
+	case model.MessageEventEscalation:
+		return []NotificationField{
+			field("field.alert_type", data.EventName),
+			field("field.escalation_level", strconv.Itoa(data.EscalationLevel)),
+			field("field.duration", data.Duration),
+		}
+	default:
+		if len(data.EventData) == 0 {
+			return nil
+		}
+		eventData, _ := json.Marshal(data.EventData)
+		return eventDataFields(string(eventData))
+	}
+}
+
+// fillTemplateData 填充与语言和时间相关的变量
+func fillTemplateData(data *MessageTemplateData, locale string, at time.Time) {
+	data.EventName = eventTypeName(locale, data.EventType)
+	data.Time = at.Format("2006-01-02 15:04:05")
+	data.PanelURL = NewSettingService().GetPanelURL()
+}
+
+// alertTemplateData 由告警日志生成模板变量，targetName 为告警目标的显示名称
+func alertTemplateData(eventType string, alert *model.AlertLog, targetName string) *MessageTemplateData {
+	data := &MessageTemplateData{
+		EventType:  alert.AlertType,
+		Level:      NotifyLevelAlert,
+		TargetType: string(alert.TargetType),
+		TargetName: targetName,
+		Message:    alert.Message,
+	}
+	if alert.EventData != "" {
+		_ = json.Unmarshal([]byte(alert.EventData), &data.EventData)
+	}
+
+	switch eventType {
+	case model.MessageEventTraffic:
+		data.CurrentValue = formatBytes(alert.CurrentValue)
+		data.Threshold = formatBytes(alert.ThresholdValue)
+	case model.MessageEventRecovery:
+		data.Level = NotifyLevelRecovery
+	case model.MessageEventMetric, model.MessageEventMetricRecovery:
+		if eventType == model.MessageEventMetricRecovery {
+			data.Level = NotifyLevelRecovery
+		}
+		if expression, ok := data.EventData["expression"].(string); ok {
+			data.Expression = expression
+			if expr, err := ParseAlertExpression(expression); err == nil {
+				value, _ := data.EventData["value"].(float64)
+				data.CurrentValue = expr.FormatValue(value)
+				data.Threshold = expr.FormatValue(expr.Threshold)
+			}
+		}
+	case model.MessageEventOffline, model.MessageEventEscalation:
+	default:
+		data.Level = systemNotifyLevel(alert.AlertType)
+	}
+	return data
+}
+
+// lookupAlertTargetName 查询告警目标的名称，拨测返回拨测显示名称，目标不存在或为系统事件时返回空
+func lookupAlertTargetName(targetType model.AlertTargetType, id uint) string {
+	switch targetType {
+	case model.AlertTargetFrpc:
+		if client, err := repository.NewClientRepository().FindByID(id); err == nil {
+			return client.Name
+		}
+	case model.AlertTargetFrps:
+		if server, err := repository.NewFrpServerRepository(database.DB).GetByID(id); err == nil {
+			return server.Name
+		}
+	case model.AlertTargetProxy:
+		if proxy, err := repository.NewProxyRepository().FindByID(id); err == nil {
+			return proxy.Name
+		}
+	case model.AlertTargetProbe:
+		if probe, err := repository.NewProxyProbeRepository().FindByID(id); err == nil {
+			proxyName := ""
+			if proxy, err := repository.NewProxyRepository().FindByID(probe.ProxyID); err == nil {
+				proxyName = proxy.Name
+			}
+			return ProbeDisplayName(probe, proxyName)
+		}
+	}
+	return ""
+}
+
+// sampleAlertLog 按事件类型生成预览用的示例告警日志
+func sampleAlertLog(eventType string, now time.Time) (*model.AlertLog, string) {
+	alert := &model.AlertLog{ID: 1, RuleID: 1, TargetID: 1, CreatedAt: now}
+	switch eventType {
+	case model.MessageEventTraffic:
+		alert.TargetType, alert.ProxyID, alert.AlertType = model.AlertTargetProxy, 1, "daily"
+		alert.CurrentValue, alert.ThresholdValue = 12<<30, 10<<30
+		alert.Message = "代理 web 的每日流量已超过阈值"
+		return alert, "web"
+	case model.MessageEventOffline, model.MessageEventEscalation:
+		alert.TargetType, alert.AlertType = model.AlertTargetFrpc, "offline"
+		alert.Message = "frpc home 已离线（持续 180 秒）"
+		if eventType == model.MessageEventEscalation {
+			alert.CreatedAt = now.Add(-30 * time.Minute)
+		}
+		return alert, "home"
+	case model.MessageEventRecovery:
+		alert.TargetType, alert.AlertType = model.AlertTargetFrpc, "recovery"
+		alert.Message = "frpc home 已恢复在线"
+		return alert, "home"
+	case model.MessageEventMetric, model.MessageEventMetricRecovery:
+		alert.TargetType, alert.ProxyID, alert.AlertType = model.AlertTargetProxy, 1, model.RuleTypeMetric
+		alert.EventData = `{"expression":"avg(proxy_rate_in[5m]) > 1048576","metric":"proxy_rate_in","threshold":1048576,"value":2097152}`
+		alert.Message = "proxy web avg(proxy_rate_in[5m]) = 2.00 MB/s > 1.00 MB/s"
+		if eventType == model.MessageEventMetricRecovery {
+			alert.AlertType = "recovery"
+			alert.Message = "proxy web 已恢复正常，当前值 512.00 KB/s"
+			alert.EventData = `{"expression":"avg(proxy_rate_in[5m]) > 1048576","value":524288}`
+		}
+		return alert, "web"
+	}
+
+	ruleType := eventType
+	if eventType == model.MessageEventSystem {
+		ruleType = model.RuleTypeCertRenewFailed
+	}
+	alert.TargetType, alert.TargetID, alert.AlertType = model.AlertTargetSystem, 0, ruleType
+	alert.Message = getRuleTypeName(ruleType) + ": example.com"
+	alert.EventData = `{"domain":"example.com","error":"acme: rate limited"}`
+	return alert, ""
+}
+
+// sampleTemplateData 示例模板变量，用于校验模板
+func sampleTemplateData(eventType, locale string, now time.Time) *MessageTemplateData {
+	alert, targetName := sampleAlertLog(eventType, now)
+	data := alertTemplateData(eventType, alert, targetName)
+	data.EventName = eventTypeName(locale, data.EventType)
+	data.Time = alert.CreatedAt.Format("2006-01-02 15:04:05")
+	if eventType == model.MessageEventEscalation {
+		data.EscalationLevel, data.Duration = 2, "30m0s"
+	}
+	return data
+}
+
+// MessageTemplatePreviewRequest 模板预览请求，标题和正文都为空时预览当前生效的模板
+type MessageTemplatePreviewRequest struct {
+	EventType  string          `json:"event_type" binding:"required"`
+	Channel    string          `json:"channel"` // email/im，默认 im
+	Locale     string          `json:"locale"`  // 为空时使用通知语言设置
+	Subject    string          `json:"subject"`
+	Body       string          `json:"body"`
+	TargetName string          `json:"target_name"` // 示例目标名称，为空时使用示例告警对应的名称
+	Alert      *model.AlertLog `json:"alert"`       // 示例告警日志，为空时按事件类型生成
+}
+
+// MessageTemplatePreview 模板预览结果
+type MessageTemplatePreview struct {
+	Subject string               `json:"subject"`
+	Body    string               `json:"body"`
+	HTML    string               `json:"html,omitempty"` // 发送方式为邮件时的邮件 HTML
+	Data    *MessageTemplateData `json:"data"`           // 渲染使用的模板变量
+}
+
+// Preview 使用示例告警日志渲染模板
+func (s *MessageTemplateService) Preview(req *MessageTemplatePreviewRequest) (*MessageTemplatePreview, error) {
+	if !isMessageEvent(req.EventType) {
+		return nil, fmt.Errorf("不支持的事件类型: %s", req.EventType)
+	}
+	channel := req.Channel
+	if channel == "" {
+		channel = model.MessageChannelIM
+	}
+	if channel != model.MessageChannelEmail && channel != model.MessageChannelIM {
+		return nil, fmt.Errorf("发送方式只能为 email 或 im")
+	}
+	locale := req.Locale
+	if locale == "" {
+		locale = notifyLocale()
+	}
+	locale = normalizeLocale(locale)
+
+	now := time.Now()
+	alert, targetName := sampleAlertLog(req.EventType, now)
+	if req.Alert != nil {
+		alert = req.Alert
+		if alert.CreatedAt.IsZero() {
+			alert.CreatedAt = now
+		}
+	}
+	if req.TargetName != "" {
+		targetName = req.TargetName
+	}
+	data := alertTemplateData(req.EventType, alert, targetName)
+	fillTemplateData(data, locale, alert.CreatedAt)
+	if req.EventType == model.MessageEventEscalation {
+		data.EscalationLevel = 2
+		data.Duration = now.Sub(alert.CreatedAt).Truncate(time.Minute).String()
+	}
+
+	preview := &MessageTemplatePreview{Data: data}
+	if req.Subject == "" && req.Body == "" {
+		preview.Subject, preview.Body = s.render(req.EventType, channel, locale, data)
+	} else {
+		var err error
+		if preview.Subject, preview.Body, err = executeMessageTemplate(req.Subject, req.Body, data); err != nil {
+			return nil, err
+		}
+	}
+	if channel == model.MessageChannelEmail {
+		html, _, err := GenerateAlertEmail(AlertEmailData{
+			Locale:  locale,
+			Subject: preview.Subject,
+			Body:    preview.Body,
+			Level:   data.Level,
+			Fields:  messageFields(req.EventType, locale, data),
+			Time:    alert.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		preview.HTML = html
+	}
+	return preview, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMessageTemplateTestDB(t *testing.T, locale string) *MessageTemplateService {
+	newServiceTestDB(t, &model.Setting{}, &model.MessageTemplate{})
+	require.NoError(t, repository.NewSettingRepository().UpdateSetting("notify_locale", locale))
+	return NewMessageTemplateService()
+}
+
+func offlineTestAlert() *model.AlertLog {
+	return &model.AlertLog{
+		TargetType: model.AlertTargetFrpc,
+		TargetID:   1,
+		AlertType:  "offline",
+		Message:    "frpc home 已离线（持续 180 秒）",
+		CreatedAt:  time.Date(2026, 3, 15, 10, 0, 0, 0, time.Local),
+	}
+}
+
+// TestMessageTemplateService_Compose 测试按通知语言选择内置模板及用户模板覆盖
+func TestMessageTemplateService_Compose(t *testing.T) {
+	t.Run("中文内置模板与原消息一致", func(t *testing.T) {
+		svc := setupMessageTemplateTestDB(t, "zh-CN")
+		alert := offlineTestAlert()
+		msg := svc.Compose(model.MessageEventOffline, alert.CreatedAt, alertTemplateData(model.MessageEventOffline, alert, "home"))
+
+		assert.Equal(t, "FRP离线告警 - frpc home", msg.Message.Title)
+		assert.Equal(t, alert.Message, msg.Message.Content)
+		assert.Equal(t, NotifyLevelAlert, msg.Message.Level)
+		assert.Equal(t, []NotificationField{{Name: "目标类型", Value: "frpc"}, {Name: "目标名称", Value: "home"}}, msg.Message.Fields)
+		assert.Equal(t, msg.Message.Title, msg.Email.Subject)
+	})
+
+	t.Run("英文内置模板", func(t *testing.T) {
+		svc := setupMessageTemplateTestDB(t, "en-US")
+		alert := &model.AlertLog{
+			TargetType: model.AlertTargetProxy, ProxyID: 1, AlertType: "daily",
+			CurrentValue: 12 << 30, ThresholdValue: 10 << 30, CreatedAt: time.Now(),
+		}
+		msg := svc.Compose(model.MessageEventTraffic, alert.CreatedAt, alertTemplateData(model.MessageEventTraffic, alert, "web"))
+
+		assert.Equal(t, "FRP traffic alert - web", msg.Message.Title)
+		assert.Equal(t, "Daily traffic of proxy web exceeded the threshold: 12.00 GB / 10.00 GB", msg.Message.Content)
+		assert.Equal(t, NotificationField{Name: "Alert type", Value: "Daily traffic"}, msg.Message.Fields[1])
+		assert.Equal(t, model.LocaleEnUS, msg.Email.Locale)
+
+		alert = &model.AlertLog{
+			TargetType: model.AlertTargetSystem, AlertType: model.RuleTypeCertRenewFailed,
+			EventData: `{"domain":"example.com","error":"rate limited"}`, CreatedAt: time.Now(),
+		}
+		msg = svc.Compose(model.RuleTypeCertRenewFailed, alert.CreatedAt, alertTemplateData(model.RuleTypeCertRenewFailed, alert, ""))
+		assert.Equal(t, "System event: Certificate renewal failed, domain example.com\nError: rate limited", msg.Message.Content)
+	})
+
+	t.Run("中文恢复通知标题", func(t *testing.T) {
+		svc := setupMessageTemplateTestDB(t, "zh-CN")
+		alert := offlineTestAlert()
+		msg := svc.Compose(model.MessageEventRecovery, alert.CreatedAt, alertTemplateData(model.MessageEventRecovery, alert, "home"))
+		assert.Equal(t, "FRP恢复通知 - frpc home 已恢复在线", msg.Email.Subject)
+		assert.Equal(t, "frpc home 已恢复在线", msg.Email.Body)
+	})
+
+	t.Run("用户模板按发送方式覆盖", func(t *testing.T) {
+		svc := setupMessageTemplateTestDB(t, "zh-CN")
+		require.NoError(t, svc.Create(&model.MessageTemplate{
+			EventType: model.MessageEventOffline, Channel: model.MessageChannelIM, Enabled: true,
+			Subject: "[{{.Level}}] {{.TargetName}} down", Body: "{{.TargetType}}/{{.TargetName}} @ {{.Time}}",
+		}))
+		alert := offlineTestAlert()
+		msg := svc.Compose(model.MessageEventOffline, alert.CreatedAt, alertTemplateData(model.MessageEventOffline, alert, "home"))
+
+		assert.Equal(t, "[alert] home down", msg.Message.Title)
+		assert.Equal(t, "frpc/home @ 2026-03-15 10:00:00", msg.Message.Content)
+		assert.Equal(t, "FRP离线告警 - frpc home", msg.Email.Subject, "邮件未配置模板时使用内置模板")
+	})
+
+	t.Run("系统事件使用 system 模板及事件详情", func(t *testing.T) {
+		svc := setupMessageTemplateTestDB(t, "zh-CN")
+		require.NoError(t, svc.Create(&model.MessageTemplate{
+			EventType: model.MessageEventSystem, Channel: model.MessageChannelEmail, Enabled: true,
+			Subject: "{{.EventName}}: {{.EventData.domain}}", Body: "{{.Message}}",
+		}))
+		require.NoError(t, svc.Create(&model.MessageTemplate{
+			EventType: model.RuleTypeCertRenewFailed, Channel: model.MessageChannelEmail, Enabled: false,
+			Subject: "停用的模板", Body: "停用的模板",
+		}))
+		alert := &model.AlertLog{
+			TargetType: model.AlertTargetSystem, AlertType: model.RuleTypeCertRenewFailed,
+			Message: "证书续签失败: example.com", EventData: `{"domain":"example.com"}`, CreatedAt: time.Now(),
+		}
+		msg := svc.Compose(model.RuleTypeCertRenewFailed, alert.CreatedAt, alertTemplateData(model.RuleTypeCertRenewFailed, alert, ""))
+
+		assert.Equal(t, "证书续签失败: example.com", msg.Email.Subject)
+		assert.Equal(t, "FRP系统告警 - 证书续签失败", msg.Message.Title)
+		assert.Equal(t, []NotificationField{{Name: "domain", Value: "example.com"}}, msg.Message.Fields)
+	})
+
+	t.Run("用户模板渲染失败时使用内置模板", func(t *testing.T) {
+		svc := setupMessageTemplateTestDB(t, "zh-CN")
+		require.NoError(t, database.DB.Create(&model.MessageTemplate{
+			EventType: model.MessageEventOffline, Channel: model.MessageChannelIM, Enabled: true,
+			Subject: "{{.Unknown}}", Body: "x",
+		}).Error)
+		alert := offlineTestAlert()
+		msg := svc.Compose(model.MessageEventOffline, alert.CreatedAt, alertTemplateData(model.MessageEventOffline, alert, "home"))
+		assert.Equal(t, "FRP离线告警 - frpc home", msg.Message.Title)
+	})
+}
+
+// TestMessageTemplateService_Validate 测试模板校验
+func TestMessageTemplateService_Validate(t *testing.T) {
+	svc := setupMessageTemplateTestDB(t, "zh-CN")
+	tpl := func(event, channel, subject string) *model.MessageTemplate {
+		return &model.MessageTemplate{EventType: event, Channel: channel, Subject: subject, Body: "{{.Message}}", Enabled: true}
+	}
+
+	assert.ErrorContains(t, svc.Create(tpl("unknown", model.MessageChannelIM, "x")), "不支持的事件类型")
+	assert.ErrorContains(t, svc.Create(tpl(model.MessageEventOffline, "sms", "x")), "发送方式")
+	assert.ErrorContains(t, svc.Create(tpl(model.MessageEventOffline, model.MessageChannelIM, "{{.TargetName")), "标题模板错误")
+	assert.ErrorContains(t, svc.Create(tpl(model.MessageEventOffline, model.MessageChannelIM, "{{.Unknown}}")), "标题模板错误")
+
+	require.NoError(t, svc.Create(tpl(model.MessageEventOffline, model.MessageChannelIM, "{{.TargetName}}")))
+	assert.ErrorContains(t, svc.Create(tpl(model.MessageEventOffline, model.MessageChannelIM, "x")), "已存在")
+	require.NoError(t, svc.Create(tpl(model.RuleTypeLoginFailed, model.MessageChannelEmail, "{{.EventData.ip}}")))
+}
+
+// TestMessageTemplateService_Preview 测试使用示例告警日志预览模板
+func TestMessageTemplateService_Preview(t *testing.T) {
+	svc := setupMessageTemplateTestDB(t, "zh-CN")
+
+	preview, err := svc.Preview(&MessageTemplatePreviewRequest{EventType: model.MessageEventMetric, Locale: "en"})
+	require.NoError(t, err)
+	assert.Equal(t, "FRP metric alert - web", preview.Subject, "未提供模板时预览生效的内置模板")
+	assert.Equal(t, "proxy web matched avg(proxy_rate_in[5m]) > 1048576, current value "+preview.Data.CurrentValue, preview.Body)
+	assert.NotEmpty(t, preview.Data.CurrentValue)
+	assert.Empty(t, preview.HTML)
+
+	preview, err = svc.Preview(&MessageTemplatePreviewRequest{
+		EventType: model.MessageEventOffline,
+		Channel:   model.MessageChannelEmail,
+		Subject:   "{{.TargetName}} 离线",
+		Body:      "{{.Message}}\n请检查 {{.TargetName}}",
+		Alert: &model.AlertLog{
+			TargetType: model.AlertTargetFrps, AlertType: "offline", Message: "frps edge 已离线（持续 60 秒）",
+		},
+		TargetName: "edge",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "edge 离线", preview.Subject)
+	assert.Equal(t, "frps edge 已离线（持续 60 秒）\n请检查 edge", preview.Body)
+	assert.Contains(t, preview.HTML, "请检查 edge")
+	assert.Contains(t, preview.HTML, "目标名称")
+
+	_, err = svc.Preview(&MessageTemplatePreviewRequest{EventType: model.MessageEventOffline, Subject: "{{.Nope}}", Body: "x"})
+	assert.ErrorContains(t, err, "标题模板错误")
+}
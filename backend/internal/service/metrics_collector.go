@@ -24,18 +24,22 @@ type MetricsCollector struct {
 	serverRepo       *repository.FrpServerRepository
 	metricsRepo      *repository.ServerMetricsRepository
 	proxyMetricsRepo *repository.ProxyMetricsRepository
+	clientMetrics    *repository.ClientMetricsRepository
+	rollup           *MetricsRollupService
 	settingRepo      *repository.SettingRepository
 	stopChan         chan struct{}
 	interval         time.Duration
 	ticker           *time.Ticker
 	mutex            sync.RWMutex
 
-	// 内存缓存：存储上一次采样值，用于计算速率
+	// 内存缓存：存储上一次采样值，用于计算增量和速率
 	// key: "serverID:proxyName"
 	trafficCache sync.Map
+	// 已从数据库恢复过上次采样值的服务器，面板重启后增量可以接续
+	seededServers sync.Map
 }
 
-func NewMetricsCollector(serverRepo *repository.FrpServerRepository) *MetricsCollector {
+func NewMetricsCollector(serverRepo *repository.FrpServerRepository, rollup *MetricsRollupService) *MetricsCollector {
 	settingRepo := repository.NewSettingRepository()
 	// 从数据库读取采集间隔，默认30秒
 	interval := 30 * time.Second
@@ -49,6 +53,8 @@ func NewMetricsCollector(serverRepo *repository.FrpServerRepository) *MetricsCol
 		serverRepo:       serverRepo,
 		metricsRepo:      repository.NewServerMetricsRepository(),
 		proxyMetricsRepo: repository.NewProxyMetricsRepository(),
+		clientMetrics:    repository.NewClientMetricsRepository(),
+		rollup:           rollup,
 		settingRepo:      settingRepo,
 		stopChan:         make(chan struct{}),
 		interval:         interval,
@@ -144,6 +150,7 @@ func (c *MetricsCollector) collectOne(server *model.FrpServer) {
 		MemoryBytes: metrics.MemoryBytes,
 		TrafficIn:   metrics.TrafficIn,
 		TrafficOut:  metrics.TrafficOut,
+		ClientCount: metrics.ClientCounts,
 		RecordTime:  now,
 	}
 
@@ -152,7 +159,11 @@ func (c *MetricsCollector) collectOne(server *model.FrpServer) {
 	}
 
 	// 处理每个隧道的流量数据
-	c.processProxyTraffics(server.ID, metrics.ProxyTraffics, now)
+	var frpsStartTime time.Time
+	if metrics.StartTime > 0 {
+		frpsStartTime = time.Unix(int64(metrics.StartTime), 0)
+	}
+	c.processProxyTraffics(server.ID, metrics.ProxyTraffics, frpsStartTime, now)
 }
 
 func (c *MetricsCollector) cleanupLoop() {
@@ -178,22 +189,16 @@ func (c *MetricsCollector) cleanupLoop() {
 }
 
 func (c *MetricsCollector) cleanup() {
-	before := time.Now().AddDate(0, 0, -7)
-
-	// 清理服务器指标历史
-	deleted, err := c.metricsRepo.DeleteOlderThan(before)
-	if err != nil {
-		logger.Errorf("MetricsCollector 清理服务器历史数据失败: %v", err)
-	} else {
-		logger.Infof("MetricsCollector 已清理 %d 条服务器过期指标数据", deleted)
-	}
+	// 服务器与隧道指标按层级保留天数清理
+	c.rollup.Cleanup()
 
-	// 清理隧道指标历史
-	deletedProxy, err := c.proxyMetricsRepo.DeleteOlderThan(before)
+	// 清理客户端指标历史
+	before := time.Now().AddDate(0, 0, -c.rollup.ClientRetentionDays())
+	deletedClient, err := c.clientMetrics.DeleteOlderThan(before)
 	if err != nil {
-		logger.Errorf("MetricsCollector 清理隧道历史数据失败: %v", err)
+		logger.Errorf("MetricsCollector 清理客户端历史数据失败: %v", err)
 	} else {
-		logger.Infof("MetricsCollector 已清理 %d 条隧道过期指标数据", deletedProxy)
+		logger.Infof("MetricsCollector 已清理 %d 条客户端过期指标数据", deletedClient)
 	}
 }
 
@@ -204,16 +209,40 @@ type proxyRateUpdate struct {
 	RateOut int64
 }
 
-// processProxyTraffics 处理每个隧道的流量数据，计算速率并保存
-func (c *MetricsCollector) processProxyTraffics(serverID uint, traffics []frp.ProxyTrafficData, now time.Time) {
-	if len(traffics) == 0 {
+// seedTrafficCache 首次处理某服务器时从数据库恢复各隧道最近一次采样值
+func (c *MetricsCollector) seedTrafficCache(serverID uint) {
+	if _, loaded := c.seededServers.LoadOrStore(serverID, true); loaded {
 		return
 	}
+	records, err := c.proxyMetricsRepo.GetLatestByServer(serverID)
+	if err != nil {
+		logger.Warnf("MetricsCollector 恢复服务器 %d 隧道采样基准失败: %v", serverID, err)
+		return
+	}
+	for _, r := range records {
+		c.trafficCache.LoadOrStore(fmt.Sprintf("%d:%s", serverID, r.ProxyName), &proxyTrafficSample{
+			TrafficIn:  r.TrafficIn,
+			TrafficOut: r.TrafficOut,
+			Timestamp:  r.RecordTime,
+		})
+	}
+}
+
+// trafficDelta 计算累计计数器的增量，计数器被重置时增量即为重置后的当前值
+func trafficDelta(current, last int64, reset bool) int64 {
+	if reset || current < last {
+		return current
+	}
+	return current - last
+}
 
-	intervalSecs := int64(c.interval.Seconds())
-	if intervalSecs <= 0 {
-		intervalSecs = 30
+// processProxyTraffics 处理每个隧道的流量数据，计算增量与速率并保存
+// frpsStartTime 为 frps 进程启动时间，晚于上次采样说明 frps 已重启、计数器已归零
+func (c *MetricsCollector) processProxyTraffics(serverID uint, traffics []frp.ProxyTrafficData, frpsStartTime, now time.Time) {
+	if len(traffics) == 0 {
+		return
 	}
+	c.seedTrafficCache(serverID)
 
 	var proxyMetrics []model.ProxyMetricsHistory
 	var rateUpdates []proxyRateUpdate
@@ -221,22 +250,27 @@ func (c *MetricsCollector) processProxyTraffics(serverID uint, traffics []frp.Pr
 	for _, pt := range traffics {
 		cacheKey := fmt.Sprintf("%d:%s", serverID, pt.Name)
 
-		var rateIn, rateOut int64
+		var deltaIn, deltaOut, rateIn, rateOut int64
 
-		// 从缓存获取上次采样值
+		// 从缓存获取上次采样值，首次采集只记录基准值，避免把历史累计值当作增量
 		if lastVal, ok := c.trafficCache.Load(cacheKey); ok {
 			last := lastVal.(*proxyTrafficSample)
+			reset := !frpsStartTime.IsZero() && frpsStartTime.After(last.Timestamp)
+			if reset || pt.TrafficIn < last.TrafficIn || pt.TrafficOut < last.TrafficOut {
+				logger.Infof("MetricsCollector 检测到隧道 %s 流量计数器重置 (服务器 %d): in %d -> %d, out %d -> %d",
+					pt.Name, serverID, last.TrafficIn, pt.TrafficIn, last.TrafficOut, pt.TrafficOut)
+			}
+			deltaIn = trafficDelta(pt.TrafficIn, last.TrafficIn, reset)
+			deltaOut = trafficDelta(pt.TrafficOut, last.TrafficOut, reset)
+
 			elapsed := now.Sub(last.Timestamp).Seconds()
+			if reset {
+				// 重置后的流量产生于 frps 启动之后
+				elapsed = now.Sub(frpsStartTime).Seconds()
+			}
 			if elapsed > 0 {
-				rateIn = int64(float64(pt.TrafficIn-last.TrafficIn) / elapsed)
-				rateOut = int64(float64(pt.TrafficOut-last.TrafficOut) / elapsed)
-				// 防止负值（服务器重启等情况）
-				if rateIn < 0 {
-					rateIn = 0
-				}
-				if rateOut < 0 {
-					rateOut = 0
-				}
+				rateIn = int64(float64(deltaIn) / elapsed)
+				rateOut = int64(float64(deltaOut) / elapsed)
 			}
 		}
 
@@ -254,6 +288,8 @@ func (c *MetricsCollector) processProxyTraffics(serverID uint, traffics []frp.Pr
 			ProxyType:  pt.Type,
 			TrafficIn:  pt.TrafficIn,
 			TrafficOut: pt.TrafficOut,
+			DeltaIn:    deltaIn,
+			DeltaOut:   deltaOut,
 			RateIn:     rateIn,
 			RateOut:    rateOut,
 			RecordTime: now,
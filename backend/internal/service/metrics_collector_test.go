@@ -0,0 +1,76 @@
+package service
+
+import (
+	"frp-web-panel/internal/frp"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCollectorTestDB(t *testing.T) *MetricsCollector {
+	newServiceTestDB(t, &model.Proxy{}, &model.ProxyMetricsHistory{})
+
+	return &MetricsCollector{
+		proxyMetricsRepo: repository.NewProxyMetricsRepository(),
+		interval:         30 * time.Second,
+	}
+}
+
+func latestProxySample(t *testing.T, proxyName string) model.ProxyMetricsHistory {
+	var record model.ProxyMetricsHistory
+	require.NoError(t, database.DB.Where("proxy_name = ?", proxyName).Order("id DESC").First(&record).Error)
+	return record
+}
+
+func TestTrafficDelta(t *testing.T) {
+	assert.Equal(t, int64(50), trafficDelta(150, 100, false))
+	assert.Equal(t, int64(30), trafficDelta(30, 100, false), "计数器变小视为重置")
+	assert.Equal(t, int64(150), trafficDelta(150, 100, true), "frps 重启后当前值即为增量")
+}
+
+// TestMetricsCollector_ProcessProxyTraffics 测试增量计算与计数器重置检测
+func TestMetricsCollector_ProcessProxyTraffics(t *testing.T) {
+	c := setupCollectorTestDB(t)
+	frpsStart := time.Now().Add(-time.Hour)
+	t0 := time.Now()
+
+	sample := func(in, out int64, start, at time.Time) model.ProxyMetricsHistory {
+		c.processProxyTraffics(1, []frp.ProxyTrafficData{{Name: "c.web", Type: "http", TrafficIn: in, TrafficOut: out}}, start, at)
+		return latestProxySample(t, "c.web")
+	}
+
+	first := sample(10000, 5000, frpsStart, t0)
+	assert.Equal(t, int64(0), first.DeltaIn, "首次采集只记录基准值")
+
+	second := sample(13000, 5600, frpsStart, t0.Add(30*time.Second))
+	assert.Equal(t, int64(3000), second.DeltaIn)
+	assert.Equal(t, int64(600), second.DeltaOut)
+	assert.Equal(t, int64(100), second.RateIn)
+
+	// 计数器变小
+	third := sample(900, 300, frpsStart, t0.Add(60*time.Second))
+	assert.Equal(t, int64(900), third.DeltaIn)
+	assert.Equal(t, int64(300), third.DeltaOut)
+
+	// frps 在两次采样之间重启，重启后流量已超过上次的值
+	restart := t0.Add(80 * time.Second)
+	fourth := sample(2000, 400, restart, t0.Add(90*time.Second))
+	assert.Equal(t, int64(2000), fourth.DeltaIn)
+	assert.Equal(t, int64(200), fourth.RateIn, "速率按 frps 启动后的时长计算")
+
+	// 面板重启后从数据库恢复基准值，增量可以接续
+	c2 := &MetricsCollector{proxyMetricsRepo: repository.NewProxyMetricsRepository(), interval: 30 * time.Second}
+	c2.processProxyTraffics(1, []frp.ProxyTrafficData{{Name: "c.web", Type: "http", TrafficIn: 2500, TrafficOut: 450}}, restart, t0.Add(120*time.Second))
+	fifth := latestProxySample(t, "c.web")
+	assert.Equal(t, int64(500), fifth.DeltaIn)
+	assert.Equal(t, int64(50), fifth.DeltaOut)
+
+	var total int64
+	require.NoError(t, database.DB.Model(&model.ProxyMetricsHistory{}).Select("SUM(delta_in)").Scan(&total).Error)
+	assert.Equal(t, int64(3000+900+2000+500), total)
+}
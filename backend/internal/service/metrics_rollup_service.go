@@ -0,0 +1,601 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// metricsTier 指标存储层级
+type metricsTier struct {
+	resolution       string
+	step             time.Duration // 时间桶宽度，原始数据为 0
+	maxSpan          time.Duration // 查询范围不超过该值时优先使用此粒度，0 表示不限
+	retentionKey     string        // 保留天数设置项
+	defaultRetention int           // 默认保留天数
+}
+
+// metricsTiers 由细到粗排列，每一层由上一层汇总而来
+var metricsTiers = []metricsTier{
+	{model.MetricsResolutionRaw, 0, 24 * time.Hour, "metrics_retention_raw_days", 7},
+	{model.MetricsResolution5m, 5 * time.Minute, 7 * 24 * time.Hour, "metrics_retention_5m_days", 30},
+	{model.MetricsResolution1h, time.Hour, 90 * 24 * time.Hour, "metrics_retention_1h_days", 180},
+	{model.MetricsResolution1d, 24 * time.Hour, 0, "metrics_retention_1d_days", 730},
+}
+
+const (
+	// rollupDelay 汇总时跳过最近一段时间，等待采集写入完成
+	rollupDelay = time.Minute
+	// rollupMaxBuckets 单次最多汇总的时间桶数量，积压时分多次追赶
+	rollupMaxBuckets = 288
+	// clientRetentionKey 客户端指标保留天数设置项
+	clientRetentionKey     = "metrics_retention_client_days"
+	defaultClientRetention = 7
+)
+
+// MetricsRollupService 将原始指标逐级降采样为 5 分钟、小时、天粒度，并按层级清理过期数据
+type MetricsRollupService struct {
+	serverMetricsRepo *repository.ServerMetricsRepository
+	proxyMetricsRepo  *repository.ProxyMetricsRepository
+	rollupRepo        *repository.MetricsRollupRepository
+	settingRepo       *repository.SettingRepository
+}
+
+func NewMetricsRollupService() *MetricsRollupService {
+	return &MetricsRollupService{
+		serverMetricsRepo: repository.NewServerMetricsRepository(),
+		proxyMetricsRepo:  repository.NewProxyMetricsRepository(),
+		rollupRepo:        repository.NewMetricsRollupRepository(),
+		settingRepo:       repository.NewSettingRepository(),
+	}
+}
+
+// bucketStart 计算时间所在时间桶的起始时间，天粒度按本地时区零点对齐
+func bucketStart(t time.Time, step time.Duration) time.Time {
+	if step >= 24*time.Hour {
+		y, m, d := t.In(time.Local).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	return t.Truncate(step)
+}
+
+// nextBucket 计算下一个时间桶的起始时间
+func nextBucket(t time.Time, step time.Duration) time.Time {
+	if step >= 24*time.Hour {
+		return bucketStart(t, step).AddDate(0, 0, 1)
+	}
+	return bucketStart(t, step).Add(step)
+}
+
+func (s *MetricsRollupService) retentionDays(tier metricsTier) int {
+	if val, err := s.settingRepo.GetSetting(tier.retentionKey); err == nil {
+		if days, err := strconv.Atoi(val); err == nil && days > 0 {
+			return days
+		}
+	}
+	return tier.defaultRetention
+}
+
+// ClientRetentionDays 客户端守护进程上报指标的保留天数，客户端指标不做降采样
+func (s *MetricsRollupService) ClientRetentionDays() int {
+	if val, err := s.settingRepo.GetSetting(clientRetentionKey); err == nil {
+		if days, err := strconv.Atoi(val); err == nil && days > 0 {
+			return days
+		}
+	}
+	return defaultClientRetention
+}
+
+// Rollup 执行一次降采样，供定时任务调用
+func (s *MetricsRollupService) Rollup() {
+	s.rollupAt(time.Now())
+}
+
+func (s *MetricsRollupService) rollupAt(now time.Time) {
+	for i := 1; i < len(metricsTiers); i++ {
+		tier, source := metricsTiers[i], metricsTiers[i-1]
+		if err := s.rollupProxies(tier, source, now); err != nil {
+			logger.Errorf("[指标汇总] 汇总 %s 隧道指标失败: %v", tier.resolution, err)
+		}
+		if err := s.rollupServers(tier, source, now); err != nil {
+			logger.Errorf("[指标汇总] 汇总 %s 服务器指标失败: %v", tier.resolution, err)
+		}
+	}
+}
+
+// rollupWindow 计算本次需要汇总的时间范围 [from, to)，只汇总已经结束的时间桶。
+// sourceEnd 为来源层已汇总到的时间，来源层仍在补汇积压数据时不汇总其尚未覆盖的时间桶，
+// 否则粗粒度的时间桶只包含部分数据，且之后不会再重新汇总；来源为原始数据时为 nil
+func rollupWindow(tier metricsTier, first, sourceEnd *time.Time, now time.Time) (time.Time, time.Time, bool) {
+	if first == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	from := bucketStart(*first, tier.step)
+	to := bucketStart(now.Add(-rollupDelay), tier.step)
+	if sourceEnd != nil {
+		if end := bucketStart(*sourceEnd, tier.step); end.Before(to) {
+			to = end
+		}
+	}
+	limit := from
+	for i := 0; i < rollupMaxBuckets && limit.Before(to); i++ {
+		limit = nextBucket(limit, tier.step)
+	}
+	if limit.Before(to) {
+		to = limit
+	}
+	return from, to, from.Before(to)
+}
+
+// latestProxyBucketEnd 来源层最新隧道时间桶的结束时间，来源层没有数据时返回 nil
+func (s *MetricsRollupService) latestProxyBucketEnd(source metricsTier) (*time.Time, error) {
+	latest, err := s.rollupRepo.LatestProxyBucket(source.resolution)
+	if err != nil || latest == nil {
+		return nil, err
+	}
+	end := nextBucket(*latest, source.step)
+	return &end, nil
+}
+
+// latestServerBucketEnd 来源层最新服务器时间桶的结束时间，来源层没有数据时返回 nil
+func (s *MetricsRollupService) latestServerBucketEnd(source metricsTier) (*time.Time, error) {
+	latest, err := s.rollupRepo.LatestServerBucket(source.resolution)
+	if err != nil || latest == nil {
+		return nil, err
+	}
+	end := nextBucket(*latest, source.step)
+	return &end, nil
+}
+
+func (s *MetricsRollupService) rollupProxies(tier, source metricsTier, now time.Time) error {
+	// 从已汇总的最新时间桶之后、来源数据中最早的一条开始，跳过采集中断造成的空档
+	var since time.Time
+	latest, err := s.rollupRepo.LatestProxyBucket(tier.resolution)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		since = nextBucket(*latest, tier.step)
+	}
+
+	var first, sourceEnd *time.Time
+	if source.resolution == model.MetricsResolutionRaw {
+		first, err = s.proxyMetricsRepo.FirstRecordTimeSince(since)
+	} else {
+		first, err = s.rollupRepo.FirstProxyBucketSince(source.resolution, since)
+		if err == nil {
+			sourceEnd, err = s.latestProxyBucketEnd(source)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	from, to, ok := rollupWindow(tier, first, sourceEnd, now)
+	if !ok {
+		return nil
+	}
+
+	var samples []model.ProxyMetricsRollup
+	if source.resolution == model.MetricsResolutionRaw {
+		records, err := s.proxyMetricsRepo.FindInRange(from, to)
+		if err != nil {
+			return err
+		}
+		samples = make([]model.ProxyMetricsRollup, 0, len(records))
+		for _, r := range records {
+			samples = append(samples, model.ProxyMetricsRollup{
+				ServerID:   r.ServerID,
+				ProxyName:  r.ProxyName,
+				ProxyType:  r.ProxyType,
+				TrafficIn:  r.TrafficIn,
+				TrafficOut: r.TrafficOut,
+				DeltaIn:    r.DeltaIn,
+				DeltaOut:   r.DeltaOut,
+				RateIn:     r.RateIn,
+				RateOut:    r.RateOut,
+				MaxRateIn:  r.RateIn,
+				MaxRateOut: r.RateOut,
+				Samples:    1,
+				BucketTime: r.RecordTime,
+			})
+		}
+	} else {
+		samples, err = s.rollupRepo.FindProxyRollupsInRange(source.resolution, from, to)
+		if err != nil {
+			return err
+		}
+	}
+
+	rollups := aggregateProxyRollups(samples, tier)
+	if err := s.rollupRepo.SaveProxyRollups(rollups); err != nil {
+		return err
+	}
+	logger.Debugf("[指标汇总] %s 隧道指标: %s ~ %s，%d 条 -> %d 条", tier.resolution, from.Format(time.RFC3339), to.Format(time.RFC3339), len(samples), len(rollups))
+	return nil
+}
+
+func (s *MetricsRollupService) rollupServers(tier, source metricsTier, now time.Time) error {
+	var since time.Time
+	latest, err := s.rollupRepo.LatestServerBucket(tier.resolution)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		since = nextBucket(*latest, tier.step)
+	}
+
+	var first, sourceEnd *time.Time
+	if source.resolution == model.MetricsResolutionRaw {
+		first, err = s.serverMetricsRepo.FirstRecordTimeSince(since)
+	} else {
+		first, err = s.rollupRepo.FirstServerBucketSince(source.resolution, since)
+		if err == nil {
+			sourceEnd, err = s.latestServerBucketEnd(source)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	from, to, ok := rollupWindow(tier, first, sourceEnd, now)
+	if !ok {
+		return nil
+	}
+
+	var samples []model.ServerMetricsRollup
+	if source.resolution == model.MetricsResolutionRaw {
+		records, err := s.serverMetricsRepo.FindInRange(from, to)
+		if err != nil {
+			return err
+		}
+		samples = make([]model.ServerMetricsRollup, 0, len(records))
+		for _, r := range records {
+			samples = append(samples, model.ServerMetricsRollup{
+				ServerID:       r.ServerID,
+				CpuPercent:     r.CpuPercent,
+				MaxCpuPercent:  r.CpuPercent,
+				MemoryBytes:    r.MemoryBytes,
+				MaxMemoryBytes: r.MemoryBytes,
+				TrafficIn:      r.TrafficIn,
+				TrafficOut:     r.TrafficOut,
+				Samples:        1,
+				BucketTime:     r.RecordTime,
+			})
+		}
+	} else {
+		samples, err = s.rollupRepo.FindServerRollupsInRange(source.resolution, from, to)
+		if err != nil {
+			return err
+		}
+	}
+
+	rollups := aggregateServerRollups(samples, tier)
+	if err := s.rollupRepo.SaveServerRollups(rollups); err != nil {
+		return err
+	}
+	logger.Debugf("[指标汇总] %s 服务器指标: %s ~ %s，%d 条 -> %d 条", tier.resolution, from.Format(time.RFC3339), to.Format(time.RFC3339), len(samples), len(rollups))
+	return nil
+}
+
+// aggregateProxyRollups 将按时间升序排列的样本合并到目标粒度的时间桶中
+// 速率按样本数加权平均，流量增量求和，累计流量取时间桶内最后一个样本
+func aggregateProxyRollups(samples []model.ProxyMetricsRollup, tier metricsTier) []model.ProxyMetricsRollup {
+	type key struct {
+		serverID  uint
+		proxyName string
+		bucket    int64
+	}
+	index := make(map[key]int)
+	var result []model.ProxyMetricsRollup
+	for _, m := range samples {
+		bucket := bucketStart(m.BucketTime, tier.step)
+		k := key{m.ServerID, m.ProxyName, bucket.Unix()}
+		i, ok := index[k]
+		if !ok {
+			i = len(result)
+			index[k] = i
+			result = append(result, model.ProxyMetricsRollup{
+				Resolution: tier.resolution,
+				ServerID:   m.ServerID,
+				ProxyName:  m.ProxyName,
+				BucketTime: bucket,
+			})
+		}
+		r := &result[i]
+		r.ProxyType = m.ProxyType
+		r.TrafficIn, r.TrafficOut = m.TrafficIn, m.TrafficOut
+		r.DeltaIn += m.DeltaIn
+		r.DeltaOut += m.DeltaOut
+		// 先累加加权和，最后统一除以样本数
+		r.RateIn += m.RateIn * int64(m.Samples)
+		r.RateOut += m.RateOut * int64(m.Samples)
+		r.MaxRateIn = max(r.MaxRateIn, m.MaxRateIn)
+		r.MaxRateOut = max(r.MaxRateOut, m.MaxRateOut)
+		r.Samples += m.Samples
+	}
+	for i := range result {
+		if result[i].Samples > 0 {
+			result[i].RateIn /= int64(result[i].Samples)
+			result[i].RateOut /= int64(result[i].Samples)
+		}
+	}
+	return result
+}
+
+// aggregateServerRollups 将按时间升序排列的服务器样本合并到目标粒度的时间桶中
+func aggregateServerRollups(samples []model.ServerMetricsRollup, tier metricsTier) []model.ServerMetricsRollup {
+	type key struct {
+		serverID uint
+		bucket   int64
+	}
+	index := make(map[key]int)
+	var result []model.ServerMetricsRollup
+	for _, m := range samples {
+		bucket := bucketStart(m.BucketTime, tier.step)
+		k := key{m.ServerID, bucket.Unix()}
+		i, ok := index[k]
+		if !ok {
+			i = len(result)
+			index[k] = i
+			result = append(result, model.ServerMetricsRollup{
+				Resolution: tier.resolution,
+				ServerID:   m.ServerID,
+				BucketTime: bucket,
+			})
+		}
+		r := &result[i]
+		r.TrafficIn, r.TrafficOut = m.TrafficIn, m.TrafficOut
+		r.CpuPercent += m.CpuPercent * float64(m.Samples)
+		r.MemoryBytes += m.MemoryBytes * int64(m.Samples)
+		r.MaxCpuPercent = max(r.MaxCpuPercent, m.MaxCpuPercent)
+		r.MaxMemoryBytes = max(r.MaxMemoryBytes, m.MaxMemoryBytes)
+		r.Samples += m.Samples
+	}
+	for i := range result {
+		if result[i].Samples > 0 {
+			result[i].CpuPercent /= float64(result[i].Samples)
+			result[i].MemoryBytes /= int64(result[i].Samples)
+		}
+	}
+	return result
+}
+
+// Cleanup 按各层级保留天数清理过期数据，尚未汇总到下一层级的数据会被保留
+func (s *MetricsRollupService) Cleanup() {
+	s.cleanupAt(time.Now())
+}
+
+func (s *MetricsRollupService) cleanupAt(now time.Time) {
+	for i, tier := range metricsTiers {
+		before := now.AddDate(0, 0, -s.retentionDays(tier))
+
+		proxyBefore, serverBefore := before, before
+		if i+1 < len(metricsTiers) {
+			next := metricsTiers[i+1]
+			proxyBefore = s.coveredBefore(before, next, s.rollupRepo.LatestProxyBucket)
+			serverBefore = s.coveredBefore(before, next, s.rollupRepo.LatestServerBucket)
+		}
+
+		var deletedProxy, deletedServer int64
+		var err error
+		if tier.resolution == model.MetricsResolutionRaw {
+			deletedProxy, err = s.proxyMetricsRepo.DeleteOlderThan(proxyBefore)
+		} else {
+			deletedProxy, err = s.rollupRepo.DeleteProxyRollupsOlderThan(tier.resolution, proxyBefore)
+		}
+		if err != nil {
+			logger.Errorf("[指标汇总] 清理 %s 隧道指标失败: %v", tier.resolution, err)
+		}
+
+		if tier.resolution == model.MetricsResolutionRaw {
+			deletedServer, err = s.serverMetricsRepo.DeleteOlderThan(serverBefore)
+		} else {
+			deletedServer, err = s.rollupRepo.DeleteServerRollupsOlderThan(tier.resolution, serverBefore)
+		}
+		if err != nil {
+			logger.Errorf("[指标汇总] 清理 %s 服务器指标失败: %v", tier.resolution, err)
+		}
+
+		logger.Infof("[指标汇总] 已清理 %s 粒度过期数据: 隧道 %d 条，服务器 %d 条", tier.resolution, deletedProxy, deletedServer)
+	}
+}
+
+// coveredBefore 将清理截止时间限制在下一层级已汇总的范围内，避免汇总落后时丢失数据
+func (s *MetricsRollupService) coveredBefore(before time.Time, next metricsTier, latest func(string) (*time.Time, error)) time.Time {
+	bucket, err := latest(next.resolution)
+	if err != nil || bucket == nil {
+		return time.Time{}
+	}
+	if covered := nextBucket(*bucket, next.step); covered.Before(before) {
+		return covered
+	}
+	return before
+}
+
+// chooseTier 根据查询范围选择粒度：在范围跨度允许且保留期覆盖起始时间的层级中取最细的
+func (s *MetricsRollupService) chooseTier(start, end, now time.Time) int {
+	span := end.Sub(start)
+	for i, tier := range metricsTiers {
+		if tier.maxSpan > 0 && span > tier.maxSpan {
+			continue
+		}
+		if start.Before(now.AddDate(0, 0, -s.retentionDays(tier))) {
+			continue
+		}
+		return i
+	}
+	return len(metricsTiers) - 1
+}
+
+// GetProxyHistory 获取隧道指标历史，根据时间范围自动选择粒度，返回实际使用的粒度
+func (s *MetricsRollupService) GetProxyHistory(serverID uint, proxyName string, start, end time.Time) ([]model.ProxyMetricsHistory, string, error) {
+	// 汇总数据尚未生成时（如刚升级或汇总任务落后）退回更细的粒度
+	for i := s.chooseTier(start, end, time.Now()); i >= 0; i-- {
+		tier := metricsTiers[i]
+		if tier.resolution == model.MetricsResolutionRaw {
+			records, err := s.proxyMetricsRepo.GetHistory(serverID, proxyName, start, end)
+			return records, tier.resolution, err
+		}
+
+		rollups, err := s.rollupRepo.GetProxyRollups(tier.resolution, serverID, proxyName, start, end)
+		if err != nil {
+			return nil, tier.resolution, err
+		}
+		if len(rollups) == 0 {
+			continue
+		}
+		records := make([]model.ProxyMetricsHistory, 0, len(rollups))
+		for _, r := range rollups {
+			records = append(records, model.ProxyMetricsHistory{
+				ID:         r.ID,
+				ServerID:   r.ServerID,
+				ProxyName:  r.ProxyName,
+				ProxyType:  r.ProxyType,
+				TrafficIn:  r.TrafficIn,
+				TrafficOut: r.TrafficOut,
+				DeltaIn:    r.DeltaIn,
+				DeltaOut:   r.DeltaOut,
+				RateIn:     r.RateIn,
+				RateOut:    r.RateOut,
+				RecordTime: r.BucketTime,
+				CreatedAt:  r.BucketTime,
+			})
+		}
+		return records, tier.resolution, nil
+	}
+	return nil, model.MetricsResolutionRaw, nil
+}
+
+// GetServerHistory 获取服务器指标历史，根据时间范围自动选择粒度，返回实际使用的粒度
+func (s *MetricsRollupService) GetServerHistory(serverID uint, start, end time.Time) ([]model.ServerMetricsHistory, string, error) {
+	for i := s.chooseTier(start, end, time.Now()); i >= 0; i-- {
+		tier := metricsTiers[i]
+		if tier.resolution == model.MetricsResolutionRaw {
+			records, err := s.serverMetricsRepo.GetHistory(serverID, start, end)
+			return records, tier.resolution, err
+		}
+
+		rollups, err := s.rollupRepo.GetServerRollups(tier.resolution, serverID, start, end)
+		if err != nil {
+			return nil, tier.resolution, err
+		}
+		if len(rollups) == 0 {
+			continue
+		}
+		records := make([]model.ServerMetricsHistory, 0, len(rollups))
+		for _, r := range rollups {
+			records = append(records, model.ServerMetricsHistory{
+				ID:          r.ID,
+				ServerID:    r.ServerID,
+				CpuPercent:  r.CpuPercent,
+				MemoryBytes: r.MemoryBytes,
+				TrafficIn:   r.TrafficIn,
+				TrafficOut:  r.TrafficOut,
+				RecordTime:  r.BucketTime,
+				CreatedAt:   r.BucketTime,
+			})
+		}
+		return records, tier.resolution, nil
+	}
+	return nil, model.MetricsResolutionRaw, nil
+}
+
+// ProxyTrafficUsage 统计时间范围内各隧道的流量增量合计
+// 优先使用粗粒度汇总覆盖完整的时间桶，首尾不足一个时间桶的部分及尚未汇总的最近数据逐级由更细粒度补齐
+func (s *MetricsRollupService) ProxyTrafficUsage(start, end time.Time) ([]model.ProxyTrafficUsage, error) {
+	usages, _, err := s.ProxyTrafficUsageDetail(start, end)
+	return usages, err
+}
+
+// ProxyTrafficUsageDetail 同 ProxyTrafficUsage，另返回结果是否精确
+// 用到的某一粒度数据早于该粒度的保留期时（如天粒度按服务器时区对齐，其他时区的首尾需要小时粒度补齐，而小时数据已清理），
+// 这部分流量无法统计，结果偏小
+func (s *MetricsRollupService) ProxyTrafficUsageDetail(start, end time.Time) ([]model.ProxyTrafficUsage, bool, error) {
+	totals := make(map[string]*model.ProxyTrafficUsage)
+	exact := true
+	if err := s.sumProxyUsage(len(metricsTiers)-1, start, end, time.Now(), totals, &exact); err != nil {
+		return nil, false, err
+	}
+
+	usages := make([]model.ProxyTrafficUsage, 0, len(totals))
+	for _, u := range totals {
+		usages = append(usages, *u)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].ServerID != usages[j].ServerID {
+			return usages[i].ServerID < usages[j].ServerID
+		}
+		return usages[i].ProxyName < usages[j].ProxyName
+	})
+	return usages, exact, nil
+}
+
+func (s *MetricsRollupService) sumProxyUsage(tierIndex int, start, end, now time.Time, totals map[string]*model.ProxyTrafficUsage, exact *bool) error {
+	if !start.Before(end) {
+		return nil
+	}
+	tier := metricsTiers[tierIndex]
+	if tier.resolution == model.MetricsResolutionRaw {
+		s.checkRetention(tier, start, now, exact)
+		usages, err := s.proxyMetricsRepo.SumDeltaByProxy(start, end)
+		if err != nil {
+			return err
+		}
+		mergeProxyUsage(totals, usages)
+		return nil
+	}
+
+	// 只使用完全落在范围内且已汇总的时间桶
+	alignedStart := bucketStart(start, tier.step)
+	if alignedStart.Before(start) {
+		alignedStart = nextBucket(start, tier.step)
+	}
+	alignedEnd := bucketStart(end, tier.step)
+	latest, err := s.rollupRepo.LatestProxyBucket(tier.resolution)
+	if err != nil {
+		return err
+	}
+	if latest == nil {
+		return s.sumProxyUsage(tierIndex-1, start, end, now, totals, exact)
+	}
+	if watermark := nextBucket(*latest, tier.step); watermark.Before(alignedEnd) {
+		alignedEnd = watermark
+	}
+	if !alignedStart.Before(alignedEnd) {
+		return s.sumProxyUsage(tierIndex-1, start, end, now, totals, exact)
+	}
+
+	s.checkRetention(tier, alignedStart, now, exact)
+	usages, err := s.rollupRepo.SumProxyDeltaByProxy(tier.resolution, alignedStart, alignedEnd)
+	if err != nil {
+		return err
+	}
+	mergeProxyUsage(totals, usages)
+	if err := s.sumProxyUsage(tierIndex-1, start, alignedStart, now, totals, exact); err != nil {
+		return err
+	}
+	return s.sumProxyUsage(tierIndex-1, alignedEnd, end, now, totals, exact)
+}
+
+// checkRetention 查询起点早于该粒度保留期时数据已被清理，标记结果不精确
+func (s *MetricsRollupService) checkRetention(tier metricsTier, start, now time.Time, exact *bool) {
+	if start.Before(now.AddDate(0, 0, -s.retentionDays(tier))) {
+		*exact = false
+	}
+}
+
+func mergeProxyUsage(totals map[string]*model.ProxyTrafficUsage, usages []model.ProxyTrafficUsage) {
+	for _, u := range usages {
+		key := fmt.Sprintf("%d:%s", u.ServerID, u.ProxyName)
+		if existing, ok := totals[key]; ok {
+			existing.DeltaIn += u.DeltaIn
+			existing.DeltaOut += u.DeltaOut
+			continue
+		}
+		usage := u
+		totals[key] = &usage
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRollupTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.ProxyMetricsHistory{}, &model.ServerMetricsHistory{},
+		&model.ProxyMetricsRollup{}, &model.ServerMetricsRollup{})
+}
+
+// insertRawMetrics 每 30 秒写入一条隧道和服务器原始指标
+func insertRawMetrics(t *testing.T, start, end time.Time) {
+	var traffic int64
+	for ts := start; ts.Before(end); ts = ts.Add(30 * time.Second) {
+		traffic += 3000
+		require.NoError(t, database.DB.Create(&model.ProxyMetricsHistory{
+			ServerID: 1, ProxyName: "c.web", ProxyType: "http",
+			TrafficIn: traffic, TrafficOut: traffic * 2, RateIn: 100, RateOut: 200, RecordTime: ts,
+		}).Error)
+		require.NoError(t, database.DB.Create(&model.ServerMetricsHistory{
+			ServerID: 1, CpuPercent: 10, MemoryBytes: 1024, TrafficIn: traffic, TrafficOut: traffic, RecordTime: ts,
+		}).Error)
+	}
+}
+
+func countRollups(t *testing.T, m interface{}, resolution string) int64 {
+	var count int64
+	require.NoError(t, database.DB.Model(m).Where("resolution = ?", resolution).Count(&count).Error)
+	return count
+}
+
+// TestAggregateProxyRollups 测试隧道样本聚合
+func TestAggregateProxyRollups(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	samples := []model.ProxyMetricsRollup{
+		{ServerID: 1, ProxyName: "a", TrafficIn: 10, DeltaIn: 10, RateIn: 100, MaxRateIn: 150, Samples: 1, BucketTime: base},
+		{ServerID: 1, ProxyName: "a", TrafficIn: 20, DeltaIn: 10, RateIn: 400, MaxRateIn: 500, Samples: 3, BucketTime: base.Add(time.Minute)},
+		{ServerID: 1, ProxyName: "b", TrafficIn: 5, RateIn: 50, MaxRateIn: 50, Samples: 1, BucketTime: base.Add(2 * time.Minute)},
+		{ServerID: 1, ProxyName: "a", TrafficIn: 30, RateIn: 10, MaxRateIn: 10, Samples: 1, BucketTime: base.Add(6 * time.Minute)},
+	}
+
+	result := aggregateProxyRollups(samples, metricsTiers[1])
+	require.Len(t, result, 3)
+
+	first := result[0]
+	assert.Equal(t, model.MetricsResolution5m, first.Resolution)
+	assert.Equal(t, "a", first.ProxyName)
+	assert.True(t, first.BucketTime.Equal(base))
+	assert.Equal(t, int64(20), first.TrafficIn, "累计流量取时间桶内最后一个样本")
+	assert.Equal(t, int64(20), first.DeltaIn, "流量增量求和")
+	assert.Equal(t, int64(325), first.RateIn, "速率按样本数加权平均")
+	assert.Equal(t, int64(500), first.MaxRateIn)
+	assert.Equal(t, 4, first.Samples)
+
+	assert.Equal(t, "b", result[1].ProxyName)
+	assert.True(t, result[2].BucketTime.Equal(base.Add(5*time.Minute)))
+}
+
+// TestMetricsRollupService_Rollup 测试逐级汇总及重复执行的幂等性
+func TestMetricsRollupService_Rollup(t *testing.T) {
+	setupRollupTestDB(t)
+	svc := NewMetricsRollupService()
+
+	now := time.Now().Truncate(time.Hour)
+	insertRawMetrics(t, now.Add(-3*time.Hour), now.Add(-1*time.Hour))
+
+	svc.rollupAt(now)
+	assert.Equal(t, int64(24), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution5m))
+	assert.Equal(t, int64(2), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution1h))
+	assert.Equal(t, int64(24), countRollups(t, &model.ServerMetricsRollup{}, model.MetricsResolution5m))
+	assert.Equal(t, int64(2), countRollups(t, &model.ServerMetricsRollup{}, model.MetricsResolution1h))
+
+	var hourly []model.ProxyMetricsRollup
+	require.NoError(t, database.DB.Where("resolution = ?", model.MetricsResolution1h).Order("bucket_time ASC").Find(&hourly).Error)
+	assert.Equal(t, 120, hourly[0].Samples)
+	assert.Equal(t, int64(100), hourly[0].RateIn)
+	assert.Equal(t, int64(120*3000), hourly[0].TrafficIn)
+
+	svc.rollupAt(now)
+	assert.Equal(t, int64(24), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution5m), "重复执行不应产生重复记录")
+	assert.Equal(t, int64(2), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution1h))
+}
+
+// TestMetricsRollupService_RollupLaggingSource 测试 5 分钟层分批补汇积压数据时，小时层不汇总其尚未覆盖的时间桶
+func TestMetricsRollupService_RollupLaggingSource(t *testing.T) {
+	setupRollupTestDB(t)
+	svc := NewMetricsRollupService()
+
+	now := time.Now().Truncate(time.Hour)
+	insertRawMetrics(t, now.Add(-30*time.Hour+30*time.Minute), now.Add(-1*time.Hour))
+	partial := now.Add(-6 * time.Hour)
+
+	// 单次最多汇总 288 个 5 分钟时间桶，第一次只覆盖到 partial+30m
+	svc.rollupAt(now)
+	assert.Equal(t, int64(288), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution5m))
+	assert.Equal(t, int64(24), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution1h))
+	assert.Equal(t, int64(24), countRollups(t, &model.ServerMetricsRollup{}, model.MetricsResolution1h))
+	var count int64
+	require.NoError(t, database.DB.Model(&model.ProxyMetricsRollup{}).
+		Where("resolution = ? AND bucket_time = ?", model.MetricsResolution1h, partial).Count(&count).Error)
+	assert.Zero(t, count, "5 分钟层只覆盖一半的小时不汇总")
+
+	svc.rollupAt(now)
+	var hourly model.ProxyMetricsRollup
+	require.NoError(t, database.DB.Where("resolution = ? AND bucket_time = ?", model.MetricsResolution1h, partial).First(&hourly).Error)
+	assert.Equal(t, 120, hourly.Samples, "补汇完成后按完整的一小时汇总")
+	assert.Equal(t, int64(29), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution1h))
+}
+
+// TestMetricsRollupService_Cleanup 测试清理时保留尚未汇总的原始数据
+func TestMetricsRollupService_Cleanup(t *testing.T) {
+	setupRollupTestDB(t)
+	svc := NewMetricsRollupService()
+
+	now := time.Now().Truncate(time.Hour)
+	insertRawMetrics(t, now.AddDate(0, 0, -10), now.AddDate(0, 0, -10).Add(time.Hour))
+	insertRawMetrics(t, now.AddDate(0, 0, -8), now.AddDate(0, 0, -8).Add(time.Hour))
+
+	// 单次最多汇总一天，-8 天的数据尚未汇总
+	svc.rollupAt(now)
+	svc.cleanupAt(now)
+
+	var remaining []model.ProxyMetricsHistory
+	require.NoError(t, database.DB.Order("record_time ASC").Find(&remaining).Error)
+	require.Len(t, remaining, 120)
+	assert.False(t, remaining[0].RecordTime.Before(now.AddDate(0, 0, -8)))
+
+	// 追赶汇总后再次清理
+	svc.rollupAt(now)
+	svc.cleanupAt(now)
+	var count int64
+	database.DB.Model(&model.ProxyMetricsHistory{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, int64(24), countRollups(t, &model.ProxyMetricsRollup{}, model.MetricsResolution5m))
+}
+
+// TestMetricsRollupService_ClientRetentionDays 测试客户端指标保留天数设置
+func TestMetricsRollupService_ClientRetentionDays(t *testing.T) {
+	setupRollupTestDB(t)
+	svc := NewMetricsRollupService()
+
+	assert.Equal(t, defaultClientRetention, svc.ClientRetentionDays(), "未设置时使用默认值")
+
+	require.NoError(t, svc.settingRepo.UpdateSetting(clientRetentionKey, "30"))
+	assert.Equal(t, 30, svc.ClientRetentionDays())
+
+	require.NoError(t, svc.settingRepo.UpdateSetting(clientRetentionKey, "0"))
+	assert.Equal(t, defaultClientRetention, svc.ClientRetentionDays(), "非法值使用默认值")
+}
+
+// TestMetricsRollupService_ChooseTier 测试根据查询范围选择粒度
+func TestMetricsRollupService_ChooseTier(t *testing.T) {
+	setupRollupTestDB(t)
+	svc := NewMetricsRollupService()
+	now := time.Now()
+
+	resolution := func(start, end time.Time) string {
+		return metricsTiers[svc.chooseTier(start, end, now)].resolution
+	}
+	assert.Equal(t, model.MetricsResolutionRaw, resolution(now.Add(-6*time.Hour), now))
+	assert.Equal(t, model.MetricsResolution5m, resolution(now.AddDate(0, 0, -3), now))
+	assert.Equal(t, model.MetricsResolution5m, resolution(now.AddDate(0, 0, -10), now.AddDate(0, 0, -10).Add(time.Hour)), "超出原始数据保留期")
+	assert.Equal(t, model.MetricsResolution1h, resolution(now.AddDate(0, 0, -30), now))
+	assert.Equal(t, model.MetricsResolution1d, resolution(now.AddDate(-1, 0, 0), now))
+}
+
+// TestMetricsRollupService_GetProxyHistory 测试查询时自动选择粒度并在汇总缺失时退回
+func TestMetricsRollupService_GetProxyHistory(t *testing.T) {
+	setupRollupTestDB(t)
+	svc := NewMetricsRollupService()
+
+	now := time.Now()
+	insertRawMetrics(t, now.Add(-2*time.Hour), now)
+
+	// 尚未汇总时退回原始数据
+	records, resolution, err := svc.GetProxyHistory(1, "c.web", now.AddDate(0, 0, -3), now)
+	require.NoError(t, err)
+	assert.Equal(t, model.MetricsResolutionRaw, resolution)
+	assert.NotEmpty(t, records)
+
+	svc.rollupAt(now)
+	records, resolution, err = svc.GetProxyHistory(1, "c.web", now.AddDate(0, 0, -3), now)
+	require.NoError(t, err)
+	assert.Equal(t, model.MetricsResolution5m, resolution)
+	require.NotEmpty(t, records)
+	assert.Equal(t, int64(100), records[0].RateIn)
+	assert.Equal(t, "c.web", records[0].ProxyName)
+
+	records, resolution, err = svc.GetProxyHistory(1, "c.web", now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, model.MetricsResolutionRaw, resolution)
+	assert.Len(t, records, 120)
+}
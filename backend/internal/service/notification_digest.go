@@ -0,0 +1,287 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDigestWindowSeconds = 60 // 默认汇总窗口
+	defaultNotifyRateLimit     = 30 // 默认每个接收方每小时最多发送的消息数
+	notifyRateLimitPeriod      = time.Hour
+)
+
+// DigestGroup 通知汇总分组，同一接收方同一分组的告警在汇总窗口内合并发送
+type DigestGroup struct {
+	Key  string // 分组键，如 server:1、system:cert_expiring
+	Name string // 分组名称，用于汇总消息标题
+}
+
+// DigestNotification 一条待发送的告警通知
+type DigestNotification struct {
+	Group      DigestGroup
+	Message    *NotificationMessage
+	Emails     []string
+	ChannelIDs []uint
+	SendEmail  func(to string) error // 汇总窗口内只有这一条时使用原邮件模板发送，为空时使用汇总邮件模板
+}
+
+// digestBatch 某接收方某分组待合并发送的通知
+type digestBatch struct {
+	email     string // 邮件接收方
+	channelID uint   // 通知渠道接收方
+	group     DigestGroup
+	items     []*DigestNotification
+	deadline  time.Time
+}
+
+// dest 接收方标识，用于限流计数
+func (b *digestBatch) dest() string {
+	if b.channelID != 0 {
+		return fmt.Sprintf("channel:%d", b.channelID)
+	}
+	return "email:" + b.email
+}
+
+// NotificationDigest 告警通知汇总与限流
+// 同一接收方（邮箱或通知渠道）同一原因的告警在汇总窗口内合并为一条消息发送，避免 frps 重启等场景下的告警风暴；
+// 每个接收方每小时的发送数量受限，超出的消息丢弃并在下一条消息中注明丢弃数量
+type NotificationDigest struct {
+	settingRepo         *repository.SettingRepository
+	channelRepo         *repository.NotificationChannelRepository
+	emailService        *EmailService
+	notificationService *NotificationService
+
+	mu      sync.Mutex
+	batches map[string]*digestBatch // key: 接收方|分组键
+	sent    map[string][]time.Time  // key: 接收方，限流周期内的发送时间
+	dropped map[string]int          // key: 接收方，因限流丢弃的消息数
+
+	now func() time.Time // 便于测试替换时钟
+}
+
+var notificationDigestInstance *NotificationDigest
+var notificationDigestOnce sync.Once
+
+// GetNotificationDigest 获取通知汇总单例，告警服务和系统事件通知器共用以便跨来源合并
+func GetNotificationDigest() *NotificationDigest {
+	notificationDigestOnce.Do(func() {
+		notificationDigestInstance = newNotificationDigest()
+	})
+	return notificationDigestInstance
+}
+
+func newNotificationDigest() *NotificationDigest {
+	return &NotificationDigest{
+		settingRepo:         repository.NewSettingRepository(),
+		channelRepo:         repository.NewNotificationChannelRepository(),
+		emailService:        NewEmailService(),
+		notificationService: NewNotificationService(),
+		batches:             make(map[string]*digestBatch),
+		sent:                make(map[string][]time.Time),
+		dropped:             make(map[string]int),
+		now:                 time.Now,
+	}
+}
+
+// Submit 提交告警通知，汇总窗口为0时立即发送
+func (d *NotificationDigest) Submit(n *DigestNotification) {
+	window := time.Duration(d.intSetting("alert_digest_window_seconds", defaultDigestWindowSeconds)) * time.Second
+	now := d.now()
+
+	var ready []*digestBatch
+	d.mu.Lock()
+	add := func(b *digestBatch) {
+		if window <= 0 {
+			b.items = []*DigestNotification{n}
+			ready = append(ready, b)
+			return
+		}
+		key := b.dest() + "|" + n.Group.Key
+		batch, ok := d.batches[key]
+		if !ok {
+			b.deadline = now.Add(window)
+			d.batches[key] = b
+			batch = b
+		}
+		batch.items = append(batch.items, n)
+	}
+	for _, email := range n.Emails {
+		add(&digestBatch{email: email, group: n.Group})
+	}
+	for _, id := range n.ChannelIDs {
+		add(&digestBatch{channelID: id, group: n.Group})
+	}
+	d.mu.Unlock()
+
+	for _, b := range ready {
+		go d.deliver(b)
+	}
+}
+
+// FlushDue 发送汇总窗口已结束的通知，供定时任务调用
+func (d *NotificationDigest) FlushDue() {
+	now := d.now()
+	var due []*digestBatch
+	d.mu.Lock()
+	for key, b := range d.batches {
+		if !now.Before(b.deadline) {
+			due = append(due, b)
+			delete(d.batches, key)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, b := range due {
+		d.deliver(b)
+	}
+}
+
+// deliver 发送一批通知，只有一条时按原格式发送，多条时合并为汇总消息
+func (d *NotificationDigest) deliver(b *digestBatch) {
+	limit := d.intSetting("notify_rate_limit_per_hour", defaultNotifyRateLimit)
+	var channel *model.NotificationChannel
+	if b.channelID != 0 {
+		var err error
+		if channel, err = d.channelRepo.FindByID(b.channelID); err != nil || !channel.Enabled {
+			return
+		}
+		if channel.RateLimit > 0 {
+			limit = channel.RateLimit
+		}
+	}
+
+	dropped, ok := d.acquire(b.dest(), limit, len(b.items))
+	if !ok {
+		logger.Warnf("[通知汇总] %s 已达到每小时 %d 条的发送上限，丢弃 %d 条通知", b.dest(), limit, len(b.items))
+		return
+	}
+
+	var err error
+	if channel != nil {
+		err = d.notificationService.sendTo(channel, digestMessage(b, dropped, notifyLocale()))
+	} else {
+		err = d.sendEmail(b, dropped, notifyLocale())
+	}
+	if err != nil {
+		logger.Errorf("[通知汇总] 发送到 %s 失败: %v", b.dest(), err)
+	}
+}
+
+// acquire 检查接收方是否超过发送上限，超过时将本批 count 条消息计入丢弃数，未超过时记录本次发送并返回此前被丢弃的消息数
+func (d *NotificationDigest) acquire(dest string, limit, count int) (int, bool) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sent := d.sent[dest]
+	cutoff := now.Add(-notifyRateLimitPeriod)
+	i := 0
+	for i < len(sent) && !sent[i].After(cutoff) {
+		i++
+	}
+	sent = sent[i:]
+
+	if limit > 0 && len(sent) >= limit {
+		d.sent[dest] = sent
+		d.dropped[dest] += count
+		return 0, false
+	}
+	d.sent[dest] = append(sent, now)
+	dropped := d.dropped[dest]
+	delete(d.dropped, dest)
+	return dropped, true
+}
+
+// digestMessage 生成发送到通知渠道的消息
+func digestMessage(b *digestBatch, dropped int, locale string) *NotificationMessage {
+	droppedField := NotificationField{
+		Name:  translate(locale, "digest.dropped"),
+		Value: fmt.Sprintf(translate(locale, "digest.dropped_value"), dropped),
+	}
+	if len(b.items) == 1 {
+		if dropped == 0 {
+			return b.items[0].Message
+		}
+		msg := *b.items[0].Message
+		msg.Fields = append(append([]NotificationField{}, msg.Fields...), droppedField)
+		return &msg
+	}
+
+	msg := &NotificationMessage{
+		Title:     fmt.Sprintf(translate(locale, "digest.title"), b.group.Name, len(b.items)),
+		Content:   fmt.Sprintf(translate(locale, "digest.content"), b.group.Name, len(b.items)),
+		Level:     digestLevel(b.items),
+		EventType: "digest",
+	}
+	for _, item := range b.items {
+		msg.Fields = append(msg.Fields, NotificationField{
+			Name:  item.Message.Time.Format("15:04:05") + " " + item.Message.Title,
+			Value: item.Message.Content,
+		})
+		if item.Message.Time.After(msg.Time) {
+			msg.Time = item.Message.Time
+		}
+	}
+	if dropped > 0 {
+		msg.Fields = append(msg.Fields, droppedField)
+	}
+	return msg
+}
+
+// digestLevel 含告警时按告警发送，全部为恢复时按恢复发送，其余按通知发送
+func digestLevel(items []*DigestNotification) string {
+	level := NotifyLevelRecovery
+	for _, item := range items {
+		switch item.Message.Level {
+		case NotifyLevelAlert:
+			return NotifyLevelAlert
+		case NotifyLevelInfo:
+			level = NotifyLevelInfo
+		}
+	}
+	return level
+}
+
+// sendEmail 发送邮件，只有一条且未丢弃消息时使用原邮件模板
+func (d *NotificationDigest) sendEmail(b *digestBatch, dropped int, locale string) error {
+	if len(b.items) == 1 && dropped == 0 && b.items[0].SendEmail != nil {
+		return b.items[0].SendEmail(b.email)
+	}
+
+	data := AlertDigestEmailData{Locale: locale, GroupName: b.group.Name, Dropped: dropped}
+	for _, item := range b.items {
+		data.Items = append(data.Items, AlertDigestItem{
+			Time:    item.Message.Time,
+			Title:   item.Message.Title,
+			Content: item.Message.Content,
+		})
+	}
+	html, _, err := GenerateAlertDigestEmail(data)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf(translate(locale, "digest.title"), b.group.Name, len(b.items))
+	if len(b.items) == 1 {
+		subject = b.items[0].Message.Title
+	}
+	return d.emailService.SendHTMLEmail(b.email, subject, html, "text/html")
+}
+
+// intSetting 读取非负整数设置，未设置或无效时使用默认值
+func (d *NotificationDigest) intSetting(key string, def int) int {
+	value, err := d.settingRepo.GetSetting(key)
+	if err != nil || value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// digestPayload 通用 Webhook 渠道收到的消息
+type digestPayload struct {
+	Title     string            `json:"title"`
+	Level     string            `json:"level"`
+	EventType string            `json:"event_type"`
+	Fields    map[string]string `json:"fields"`
+}
+
+func setupDigestTestDB(t *testing.T, window, limit string) {
+	newServiceTestDB(t, &model.Setting{}, &model.NotificationChannel{},
+		&model.WebhookDelivery{}, &model.WebhookDeliveryAttempt{})
+
+	settingRepo := repository.NewSettingRepository()
+	require.NoError(t, settingRepo.UpdateSetting("alert_digest_window_seconds", window))
+	require.NoError(t, settingRepo.UpdateSetting("notify_rate_limit_per_hour", limit))
+}
+
+func receivedPayloads(t *testing.T, recv *webhookReceiver) []digestPayload {
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	payloads := make([]digestPayload, len(recv.bodies))
+	for i, body := range recv.bodies {
+		require.NoError(t, json.Unmarshal([]byte(body), &payloads[i]))
+	}
+	return payloads
+}
+
+func digestTestNotification(group string, channelID uint, level, title string, at time.Time) *DigestNotification {
+	return &DigestNotification{
+		Group:      DigestGroup{Key: group, Name: group},
+		Message:    &NotificationMessage{Title: title, Content: title + " 详情", Level: level, Time: at},
+		ChannelIDs: []uint{channelID},
+	}
+}
+
+// TestNotificationDigest_Group 测试汇总窗口内同一分组的通知合并发送，单条通知按原格式发送
+func TestNotificationDigest_Group(t *testing.T) {
+	setupDigestTestDB(t, "60", "0")
+	srv, recv := newWebhookReceiver(t)
+	channel := &model.NotificationChannel{Name: "hook", Type: model.ChannelTypeWebhook, URL: srv.URL, Enabled: true}
+	require.NoError(t, database.DB.Create(channel).Error)
+
+	t0 := time.Date(2026, 3, 15, 10, 0, 0, 0, time.Local)
+	now := t0
+	d := newNotificationDigest()
+	d.now = func() time.Time { return now }
+
+	for i, level := range []string{NotifyLevelAlert, NotifyLevelAlert, NotifyLevelRecovery} {
+		d.Submit(digestTestNotification("server:1", channel.ID, level, fmt.Sprintf("frpc c%d 离线", i), t0.Add(time.Duration(i)*time.Second)))
+	}
+	d.Submit(digestTestNotification("server:2", channel.ID, NotifyLevelAlert, "frps edge 离线", t0))
+
+	now = t0.Add(30 * time.Second)
+	d.FlushDue()
+	assert.Empty(t, receivedPayloads(t, recv), "汇总窗口未结束时不发送")
+
+	now = t0.Add(time.Minute)
+	d.FlushDue()
+	payloads := receivedPayloads(t, recv)
+	require.Len(t, payloads, 2)
+	byTitle := map[string]digestPayload{}
+	for _, p := range payloads {
+		byTitle[p.Title] = p
+	}
+
+	digest, ok := byTitle["FRP告警汇总 - server:1（3条）"]
+	require.True(t, ok, "同一分组合并为一条汇总消息")
+	assert.Equal(t, "digest", digest.EventType)
+	assert.Equal(t, NotifyLevelAlert, digest.Level)
+	assert.Len(t, digest.Fields, 3)
+	assert.Equal(t, "frpc c2 离线 详情", digest.Fields["10:00:02 frpc c2 离线"])
+
+	single, ok := byTitle["frps edge 离线"]
+	require.True(t, ok, "只有一条时按原格式发送")
+	assert.Empty(t, single.Fields)
+
+	d.FlushDue()
+	assert.Len(t, receivedPayloads(t, recv), 2, "已发送的批次不重复发送")
+}
+
+// TestNotificationDigest_RateLimit 测试渠道每小时发送上限，超出的消息丢弃并在限流解除后的第一条消息中注明
+func TestNotificationDigest_RateLimit(t *testing.T) {
+	setupDigestTestDB(t, "60", "100")
+	srv, recv := newWebhookReceiver(t)
+	channel := &model.NotificationChannel{Name: "hook", Type: model.ChannelTypeWebhook, URL: srv.URL, Enabled: true, RateLimit: 2}
+	require.NoError(t, database.DB.Create(channel).Error)
+
+	t0 := time.Date(2026, 3, 15, 10, 0, 0, 0, time.Local)
+	now := t0
+	d := newNotificationDigest()
+	d.now = func() time.Time { return now }
+	flushGroups := func(prefix string, n int) {
+		for i := 0; i < n; i++ {
+			d.Submit(digestTestNotification(fmt.Sprintf("%s:%d", prefix, i), channel.ID, NotifyLevelAlert, fmt.Sprintf("%s %d", prefix, i), now))
+		}
+		now = now.Add(time.Minute)
+		d.FlushDue()
+	}
+
+	flushGroups("a", 3)
+	assert.Len(t, receivedPayloads(t, recv), 2, "渠道上限优先于全局设置")
+
+	// 限流周期内仍然丢弃，汇总批次按其中的通知条数计入丢弃数
+	now = t0.Add(30 * time.Minute)
+	for i := 0; i < 3; i++ {
+		d.Submit(digestTestNotification("b", channel.ID, NotifyLevelAlert, fmt.Sprintf("b %d", i), now))
+	}
+	now = now.Add(time.Minute)
+	d.FlushDue()
+	assert.Len(t, receivedPayloads(t, recv), 2)
+
+	// 最早的发送超过一小时后恢复发送，并注明此前丢弃的数量
+	now = t0.Add(time.Hour + 2*time.Minute)
+	flushGroups("c", 1)
+	payloads := receivedPayloads(t, recv)
+	require.Len(t, payloads, 3)
+	assert.Equal(t, "c 0", payloads[2].Title)
+	assert.Equal(t, "此前 4 条通知因超过发送上限未发送", payloads[2].Fields["限流丢弃"])
+}
+
+// TestAlertService_DigestGroup 测试告警按所属 frps 分组
+func TestAlertService_DigestGroup(t *testing.T) {
+	proxy := setupTrafficAlertTestDB(t)
+	require.NoError(t, database.DB.AutoMigrate(&model.FrpServer{}))
+	svc := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+	svc.SetClientRepo(repository.NewClientRepository())
+
+	assert.Equal(t, DigestGroup{Key: "server:1", Name: "frps #1"}, svc.digestGroup(model.AlertTargetProxy, proxy.ID))
+	assert.Equal(t, DigestGroup{Key: "server:1", Name: "frps #1"}, svc.digestGroup(model.AlertTargetFrpc, proxy.ClientID))
+	assert.Equal(t, DigestGroup{Key: "type:frpc", Name: "frpc"}, svc.digestGroup(model.AlertTargetFrpc, 999), "未知所属 frps 时按目标类型分组")
+
+	server := &model.FrpServer{ID: 1, Name: "main", Host: "127.0.0.1"}
+	require.NoError(t, database.DB.Create(server).Error)
+	svc.SetFrpServerRepo(repository.NewFrpServerRepository(database.DB))
+	assert.Equal(t, DigestGroup{Key: "server:1", Name: "frps main"}, svc.digestGroup(model.AlertTargetFrps, 1))
+}
@@ -0,0 +1,497 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/model"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通知级别
+const (
+	NotifyLevelAlert    = "alert"    // 告警
+	NotifyLevelRecovery = "recovery" // 恢复
+	NotifyLevelInfo     = "info"     // 通知/测试
+)
+
+// NotificationField 通知中的键值字段
+type NotificationField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NotificationMessage 与渠道无关的通知内容，由各渠道格式化为对应的消息格式
+type NotificationMessage struct {
+	Title     string              `json:"title"`
+	Content   string              `json:"content"`
+	Level     string              `json:"level"`
+	EventType string              `json:"event_type"`
+	Fields    []NotificationField `json:"fields"`
+	Time      time.Time           `json:"time"`
+}
+
+// NotificationSender 通知渠道发送器，新增渠道类型时实现该接口并注册到 notificationSenders
+type NotificationSender interface {
+	// Validate 校验渠道配置
+	Validate(channel *model.NotificationChannel) error
+	// Send 格式化并发送消息
+	Send(channel *model.NotificationChannel, msg *NotificationMessage) error
+}
+
+// notificationSenders 已注册的渠道发送器
+var notificationSenders = map[model.NotificationChannelType]NotificationSender{
+	model.ChannelTypeDingTalk: dingTalkSender{},
+	model.ChannelTypeWeCom:    weComSender{},
+	model.ChannelTypeFeishu:   feishuSender{},
+	model.ChannelTypeTelegram: telegramSender{},
+	model.ChannelTypeSlack:    slackSender{},
+	model.ChannelTypeNtfy:     ntfySender{},
+	model.ChannelTypeGotify:   gotifySender{},
+	model.ChannelTypeWebhook:  webhookSender{},
+}
+
+const notifyTimeLayout = "2006-01-02 15:04:05"
+
+// markdown 生成通用 Markdown 正文（钉钉、企业微信、飞书、Gotify 使用）
+func (m *NotificationMessage) markdown() string {
+	var b strings.Builder
+	if m.Content != "" {
+		b.WriteString(m.Content)
+		b.WriteString("\n\n")
+	}
+	for _, f := range m.Fields {
+		fmt.Fprintf(&b, "- **%s**: %s\n", f.Name, f.Value)
+	}
+	fmt.Fprintf(&b, "\n> %s", m.Time.Format(notifyTimeLayout))
+	return b.String()
+}
+
+// plainText 生成纯文本正文（ntfy 使用）
+func (m *NotificationMessage) plainText() string {
+	var b strings.Builder
+	if m.Content != "" {
+		b.WriteString(m.Content)
+		b.WriteString("\n")
+	}
+	for _, f := range m.Fields {
+		fmt.Fprintf(&b, "%s: %s\n", f.Name, f.Value)
+	}
+	b.WriteString(m.Time.Format(notifyTimeLayout))
+	return b.String()
+}
+
+// levelEmoji 标题前缀，便于在群消息中区分告警和恢复
+func (m *NotificationMessage) levelEmoji() string {
+	switch m.Level {
+	case NotifyLevelAlert:
+		return "🔴"
+	case NotifyLevelRecovery:
+		return "🟢"
+	default:
+		return "🔔"
+	}
+}
+
+// postJSON 发送 JSON 请求，返回响应体；非 2xx 状态码视为失败
+func postJSON(rawURL string, payload interface{}, headers map[string]string) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncateString(string(body), 200))
+	}
+	return body, nil
+}
+
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+func requireURL(rawURL, name string) error {
+	if rawURL == "" {
+		return fmt.Errorf("%s不能为空", name)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s格式无效", name)
+	}
+	return nil
+}
+
+// checkErrCode 校验钉钉/企业微信返回的 errcode
+func checkErrCode(body []byte) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("响应解析失败: %s", truncateString(string(body), 200))
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("errcode=%d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// dingTalkSender 钉钉群机器人，Markdown 消息，可选加签
+type dingTalkSender struct{}
+
+func (dingTalkSender) Validate(channel *model.NotificationChannel) error {
+	return requireURL(channel.URL, "Webhook 地址")
+}
+
+func (dingTalkSender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	target := channel.URL
+	if channel.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(channel.Secret))
+		mac.Write([]byte(timestamp + "\n" + channel.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		target += urlQuerySeparator(target) + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+	title := msg.levelEmoji() + " " + msg.Title
+	body, err := postJSON(target, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  "#### " + title + "\n\n" + msg.markdown(),
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return checkErrCode(body)
+}
+
+func urlQuerySeparator(rawURL string) string {
+	if strings.Contains(rawURL, "?") {
+		return "&"
+	}
+	return "?"
+}
+
+// weComSender 企业微信群机器人，Markdown 消息
+type weComSender struct{}
+
+func (weComSender) Validate(channel *model.NotificationChannel) error {
+	return requireURL(channel.URL, "Webhook 地址")
+}
+
+func (weComSender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	color := "info"
+	if msg.Level == NotifyLevelAlert {
+		color = "warning"
+	}
+	content := fmt.Sprintf("### <font color=\"%s\">%s</font>\n%s", color, msg.Title, msg.markdown())
+	body, err := postJSON(channel.URL, map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": content},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return checkErrCode(body)
+}
+
+// feishuSender 飞书群机器人，消息卡片，可选加签
+type feishuSender struct{}
+
+func (feishuSender) Validate(channel *model.NotificationChannel) error {
+	return requireURL(channel.URL, "Webhook 地址")
+}
+
+func (feishuSender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	template := "blue"
+	switch msg.Level {
+	case NotifyLevelAlert:
+		template = "red"
+	case NotifyLevelRecovery:
+		template = "green"
+	}
+	payload := map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"header": map[string]interface{}{
+				"title":    map[string]string{"tag": "plain_text", "content": msg.Title},
+				"template": template,
+			},
+			"elements": []interface{}{
+				map[string]interface{}{
+					"tag":  "div",
+					"text": map[string]string{"tag": "lark_md", "content": msg.markdown()},
+				},
+			},
+		},
+	}
+	if channel.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+channel.Secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	body, err := postJSON(channel.URL, payload, nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("响应解析失败: %s", truncateString(string(body), 200))
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("code=%d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// telegramSender Telegram Bot API sendMessage，HTML 格式
+type telegramSender struct{}
+
+const defaultTelegramAPI = "https://api.telegram.org"
+
+func (telegramSender) Validate(channel *model.NotificationChannel) error {
+	if channel.Token == "" {
+		return fmt.Errorf("Bot Token 不能为空")
+	}
+	if channel.ChatID == "" {
+		return fmt.Errorf("Chat ID 不能为空")
+	}
+	if channel.URL != "" {
+		return requireURL(channel.URL, "API 地址")
+	}
+	return nil
+}
+
+func (telegramSender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s <b>%s</b>\n", msg.levelEmoji(), html.EscapeString(msg.Title))
+	if msg.Content != "" {
+		fmt.Fprintf(&b, "\n%s\n", html.EscapeString(msg.Content))
+	}
+	if len(msg.Fields) > 0 {
+		b.WriteString("\n")
+		for _, f := range msg.Fields {
+			fmt.Fprintf(&b, "<b>%s</b>: %s\n", html.EscapeString(f.Name), html.EscapeString(f.Value))
+		}
+	}
+	fmt.Fprintf(&b, "\n<i>%s</i>", msg.Time.Format(notifyTimeLayout))
+
+	apiBase := strings.TrimRight(channel.URL, "/")
+	if apiBase == "" {
+		apiBase = defaultTelegramAPI
+	}
+	body, err := postJSON(apiBase+"/bot"+channel.Token+"/sendMessage", map[string]interface{}{
+		"chat_id":                  channel.ChatID,
+		"text":                     b.String(),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}, nil)
+	if err != nil {
+		// 错误信息中的请求地址包含 Bot Token，避免写入日志
+		return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), channel.Token, "***"))
+	}
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("响应解析失败: %s", truncateString(string(body), 200))
+	}
+	if !result.OK {
+		return fmt.Errorf("发送失败: %s", result.Description)
+	}
+	return nil
+}
+
+// slackSender Slack Incoming Webhook，Block Kit 消息
+type slackSender struct{}
+
+func (slackSender) Validate(channel *model.NotificationChannel) error {
+	return requireURL(channel.URL, "Webhook 地址")
+}
+
+// slackEscape 转义 Slack mrkdwn 中的控制字符
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func (slackSender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	title := msg.levelEmoji() + " " + msg.Title
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]string{"type": "plain_text", "text": title},
+		},
+	}
+	if msg.Content != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": slackEscape(msg.Content)},
+		})
+	}
+	// Slack 每个 section 最多 10 个字段
+	for i := 0; i < len(msg.Fields); i += 10 {
+		end := i + 10
+		if end > len(msg.Fields) {
+			end = len(msg.Fields)
+		}
+		fields := make([]interface{}, 0, end-i)
+		for _, f := range msg.Fields[i:end] {
+			fields = append(fields, map[string]string{
+				"type": "mrkdwn",
+				"text": "*" + slackEscape(f.Name) + "*\n" + slackEscape(f.Value),
+			})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type":     "context",
+		"elements": []interface{}{map[string]string{"type": "mrkdwn", "text": msg.Time.Format(notifyTimeLayout)}},
+	})
+
+	// Slack 成功时返回纯文本 ok，失败时返回非 2xx 状态码
+	_, err := postJSON(channel.URL, map[string]interface{}{
+		"text":   title + "\n" + msg.Content,
+		"blocks": blocks,
+	}, nil)
+	return err
+}
+
+// ntfySender ntfy JSON 发布接口
+type ntfySender struct{}
+
+const defaultNtfyServer = "https://ntfy.sh"
+
+func (ntfySender) Validate(channel *model.NotificationChannel) error {
+	if channel.Topic == "" {
+		return fmt.Errorf("ntfy 主题不能为空")
+	}
+	if channel.Priority < 0 || channel.Priority > 5 {
+		return fmt.Errorf("ntfy 优先级需在 1-5 之间")
+	}
+	if channel.URL != "" {
+		return requireURL(channel.URL, "服务地址")
+	}
+	return nil
+}
+
+func (ntfySender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	server := strings.TrimRight(channel.URL, "/")
+	if server == "" {
+		server = defaultNtfyServer
+	}
+	tag := "bell"
+	switch msg.Level {
+	case NotifyLevelAlert:
+		tag = "rotating_light"
+	case NotifyLevelRecovery:
+		tag = "white_check_mark"
+	}
+	payload := map[string]interface{}{
+		"topic":   channel.Topic,
+		"title":   msg.Title,
+		"message": msg.plainText(),
+		"tags":    []string{tag},
+	}
+	if channel.Priority > 0 {
+		payload["priority"] = channel.Priority
+	}
+	var headers map[string]string
+	if channel.Token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + channel.Token}
+	}
+	_, err := postJSON(server, payload, headers)
+	return err
+}
+
+// gotifySender Gotify 消息接口，Markdown 渲染
+type gotifySender struct{}
+
+func (gotifySender) Validate(channel *model.NotificationChannel) error {
+	if err := requireURL(channel.URL, "服务地址"); err != nil {
+		return err
+	}
+	if channel.Token == "" {
+		return fmt.Errorf("应用令牌不能为空")
+	}
+	if channel.Priority < 0 || channel.Priority > 10 {
+		return fmt.Errorf("Gotify 优先级需在 0-10 之间")
+	}
+	return nil
+}
+
+func (gotifySender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	priority := channel.Priority
+	if priority == 0 {
+		priority = 5
+	}
+	_, err := postJSON(strings.TrimRight(channel.URL, "/")+"/message", map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.markdown(),
+		"priority": priority,
+		"extras": map[string]interface{}{
+			"client::display": map[string]string{"contentType": "text/markdown"},
+		},
+	}, map[string]string{"X-Gotify-Key": channel.Token})
+	return err
+}
+
+// webhookSender 通用 JSON Webhook
+type webhookSender struct{}
+
+func (webhookSender) Validate(channel *model.NotificationChannel) error {
+	return requireURL(channel.URL, "Webhook 地址")
+}
+
+// Send 直接发送，仅用于测试消息；告警和系统通知经 NotificationService 进入 Webhook 投递队列
+func (webhookSender) Send(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	_, err := postJSON(channel.URL, webhookMessagePayload(msg), nil)
+	return err
+}
+
+// webhookMessagePayload 通用 Webhook 请求体
+func webhookMessagePayload(msg *NotificationMessage) map[string]interface{} {
+	fields := make(map[string]string, len(msg.Fields))
+	for _, f := range msg.Fields {
+		fields[f.Name] = f.Value
+	}
+	return map[string]interface{}{
+		"title":      msg.Title,
+		"message":    msg.Content,
+		"level":      msg.Level,
+		"event_type": msg.EventType,
+		"fields":     fields,
+		"timestamp":  msg.Time.Unix(),
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"strings"
+	"time"
+)
+
+// NotificationService 通知渠道管理及消息分发
+type NotificationService struct {
+	channelRepo    *repository.NotificationChannelRepository
+	recipientRepo  *repository.AlertRecipientRepo
+	webhookService *WebhookDeliveryService
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		channelRepo:    repository.NewNotificationChannelRepository(),
+		recipientRepo:  repository.NewAlertRecipientRepo(),
+		webhookService: NewWebhookDeliveryService(nil),
+	}
+}
+
+// GetAllChannels 获取全部通知渠道，令牌和密钥不返回
+func (s *NotificationService) GetAllChannels() ([]model.NotificationChannel, error) {
+	channels, err := s.channelRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range channels {
+		fillChannelFlags(&channels[i])
+	}
+	return channels, nil
+}
+
+// CreateChannel 创建通知渠道
+func (s *NotificationService) CreateChannel(channel *model.NotificationChannel) error {
+	channel.ID = 0
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+	if err := s.channelRepo.Create(channel); err != nil {
+		return fmt.Errorf("创建通知渠道失败，名称可能已存在")
+	}
+	fillChannelFlags(channel)
+	return nil
+}
+
+// UpdateChannel 更新通知渠道，令牌和密钥为空时保留原值，clearToken/clearSecret 为 true 时清空
+func (s *NotificationService) UpdateChannel(channel *model.NotificationChannel, clearToken, clearSecret bool) (*model.NotificationChannel, error) {
+	existing, err := s.channelRepo.FindByID(channel.ID)
+	if err != nil {
+		return nil, fmt.Errorf("通知渠道不存在")
+	}
+	mergeChannelSecrets(channel, existing)
+	if clearToken {
+		channel.Token = ""
+	}
+	if clearSecret {
+		channel.Secret = ""
+	}
+	channel.CreatedAt = existing.CreatedAt
+	if err := validateChannel(channel); err != nil {
+		return nil, err
+	}
+	if err := s.channelRepo.Update(channel); err != nil {
+		return nil, fmt.Errorf("更新通知渠道失败，名称可能已存在")
+	}
+	fillChannelFlags(channel)
+	return channel, nil
+}
+
+// DeleteChannel 删除通知渠道，规则和分组中引用的失效ID在发送时忽略
+func (s *NotificationService) DeleteChannel(id uint) error {
+	if _, err := s.channelRepo.FindByID(id); err != nil {
+		return fmt.Errorf("通知渠道不存在")
+	}
+	return s.channelRepo.Delete(id)
+}
+
+// TestChannel 向已保存的渠道发送测试消息
+func (s *NotificationService) TestChannel(id uint) error {
+	channel, err := s.channelRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("通知渠道不存在")
+	}
+	return s.sendTest(channel)
+}
+
+// TestChannelConfig 使用未保存的配置发送测试消息
+// ID 不为空且渠道类型和地址未改动时，令牌和密钥为空则沿用已保存的值，避免把已保存的凭据发往新地址
+func (s *NotificationService) TestChannelConfig(channel *model.NotificationChannel) error {
+	if channel.ID != 0 {
+		if existing, err := s.channelRepo.FindByID(channel.ID); err == nil && sameChannelTarget(channel, existing) {
+			mergeChannelSecrets(channel, existing)
+		}
+	}
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+	return s.sendTest(channel)
+}
+
+// sendTest 测试消息直接发送以便返回结果，不进入 Webhook 投递队列
+func (s *NotificationService) sendTest(channel *model.NotificationChannel) error {
+	sender, ok := notificationSenders[channel.Type]
+	if !ok {
+		return fmt.Errorf("不支持的通知渠道类型: %s", channel.Type)
+	}
+	return sender.Send(channel, &NotificationMessage{
+		Title:     "FRP 通知渠道测试",
+		Content:   fmt.Sprintf("这是一条来自 FRP 管理面板的测试消息，收到说明通知渠道「%s」配置正确。", channel.Name),
+		Level:     NotifyLevelInfo,
+		EventType: "test",
+		Fields:    []NotificationField{{Name: "渠道类型", Value: string(channel.Type)}},
+		Time:      time.Now(),
+	})
+}
+
+// ChannelIDsForRule 获取规则需通知的渠道：规则直接引用的渠道及所选分组关联的渠道
+func (s *NotificationService) ChannelIDsForRule(rule *model.AlertRule) []uint {
+	ids := parseIDList(rule.NotifyChannelIDs)
+	if groupIDs := parseIDList(rule.NotifyGroupIDs); len(groupIDs) > 0 {
+		groups, err := s.recipientRepo.GetEnabledGroupsByIDs(groupIDs)
+		if err != nil {
+			logger.Errorf("[通知渠道] 获取分组失败: %v", err)
+		}
+		for _, g := range groups {
+			ids = append(ids, parseIDList(g.ChannelIDs)...)
+		}
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// NotifyRule 向规则关联的全部渠道异步发送消息
+func (s *NotificationService) NotifyRule(rule *model.AlertRule, msg *NotificationMessage) {
+	s.SendToChannels(s.ChannelIDsForRule(rule), msg)
+}
+
+// SendToChannels 向指定渠道异步发送消息，失败仅记录日志
+func (s *NotificationService) SendToChannels(ids []uint, msg *NotificationMessage) {
+	if len(ids) == 0 {
+		return
+	}
+	channels, err := s.channelRepo.FindEnabledByIDs(ids)
+	if err != nil {
+		logger.Errorf("[通知渠道] 获取通知渠道失败: %v", err)
+		return
+	}
+	for i := range channels {
+		channel := &channels[i]
+		go func() {
+			if err := s.sendTo(channel, msg); err != nil {
+				logger.Errorf("[通知渠道] 发送到 %s(%s) 失败: %v", channel.Name, channel.Type, err)
+			}
+		}()
+	}
+}
+
+// sendTo 发送消息到渠道，通用 Webhook 进入投递队列，失败按退避重试并可在投递记录中查看和重新投递
+func (s *NotificationService) sendTo(channel *model.NotificationChannel, msg *NotificationMessage) error {
+	if channel.Type == model.ChannelTypeWebhook && s.webhookService != nil {
+		_, err := s.webhookService.EnqueueJSON(channel.URL, msg.EventType, webhookMessagePayload(msg))
+		return err
+	}
+	sender, ok := notificationSenders[channel.Type]
+	if !ok {
+		return fmt.Errorf("不支持的通知渠道类型: %s", channel.Type)
+	}
+	return sender.Send(channel, msg)
+}
+
+func validateChannel(channel *model.NotificationChannel) error {
+	channel.Name = strings.TrimSpace(channel.Name)
+	channel.URL = strings.TrimSpace(channel.URL)
+	if channel.Name == "" {
+		return fmt.Errorf("渠道名称不能为空")
+	}
+	if channel.RateLimit < 0 {
+		return fmt.Errorf("每小时发送上限不能为负数")
+	}
+	sender, ok := notificationSenders[channel.Type]
+	if !ok {
+		return fmt.Errorf("不支持的通知渠道类型: %s", channel.Type)
+	}
+	return sender.Validate(channel)
+}
+
+func mergeChannelSecrets(channel, existing *model.NotificationChannel) {
+	if channel.Token == "" {
+		channel.Token = existing.Token
+	}
+	if channel.Secret == "" {
+		channel.Secret = existing.Secret
+	}
+}
+
+// sameChannelTarget 渠道类型和地址是否与已保存的一致
+func sameChannelTarget(channel, existing *model.NotificationChannel) bool {
+	return channel.Type == existing.Type && strings.TrimSpace(channel.URL) == strings.TrimSpace(existing.URL)
+}
+
+func fillChannelFlags(channel *model.NotificationChannel) {
+	channel.HasToken = channel.Token != ""
+	channel.HasSecret = channel.Secret != ""
+}
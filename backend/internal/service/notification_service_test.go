@@ -0,0 +1,294 @@
+package service
+
+import (
+	"encoding/json"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedRequest 测试服务器收到的请求
+type capturedRequest struct {
+	Path   string
+	Query  string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// newCaptureServer 创建记录请求并返回固定响应的测试服务器
+func newCaptureServer(t *testing.T, response string) (*httptest.Server, *capturedRequest) {
+	captured := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.Path = r.URL.Path
+		captured.Query = r.URL.RawQuery
+		captured.Header = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		captured.Body = nil
+		_ = json.Unmarshal(data, &captured.Body)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, captured
+}
+
+func testNotificationMessage() *NotificationMessage {
+	return &NotificationMessage{
+		Title:     "FRP离线告警 - frpc <web>",
+		Content:   "frpc web 已离线",
+		Level:     NotifyLevelAlert,
+		EventType: "offline",
+		Fields:    []NotificationField{{Name: "目标名称", Value: "web"}},
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local),
+	}
+}
+
+// TestNotificationSenders 测试各渠道消息格式、签名及响应校验
+func TestNotificationSenders(t *testing.T) {
+	msg := testNotificationMessage()
+
+	t.Run("钉钉Markdown及加签", func(t *testing.T) {
+		srv, req := newCaptureServer(t, `{"errcode":0,"errmsg":"ok"}`)
+		ch := &model.NotificationChannel{Type: model.ChannelTypeDingTalk, URL: srv.URL + "/robot/send?access_token=abc", Secret: "SEC"}
+		require.NoError(t, dingTalkSender{}.Send(ch, msg))
+		assert.Contains(t, req.Query, "access_token=abc&timestamp=")
+		assert.Contains(t, req.Query, "&sign=")
+		assert.Equal(t, "markdown", req.Body["msgtype"])
+		text := req.Body["markdown"].(map[string]interface{})["text"].(string)
+		assert.Contains(t, text, "- **目标名称**: web")
+		assert.Contains(t, text, "2026-01-02 03:04:05")
+
+		srv2, _ := newCaptureServer(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+		err := dingTalkSender{}.Send(&model.NotificationChannel{URL: srv2.URL}, msg)
+		assert.ErrorContains(t, err, "sign not match")
+	})
+
+	t.Run("企业微信Markdown", func(t *testing.T) {
+		srv, req := newCaptureServer(t, `{"errcode":0}`)
+		require.NoError(t, weComSender{}.Send(&model.NotificationChannel{URL: srv.URL}, msg))
+		content := req.Body["markdown"].(map[string]interface{})["content"].(string)
+		assert.True(t, strings.HasPrefix(content, `### <font color="warning">`))
+	})
+
+	t.Run("飞书卡片及加签", func(t *testing.T) {
+		srv, req := newCaptureServer(t, `{"code":0,"msg":"success"}`)
+		require.NoError(t, feishuSender{}.Send(&model.NotificationChannel{URL: srv.URL, Secret: "SEC"}, msg))
+		assert.Equal(t, "interactive", req.Body["msg_type"])
+		assert.NotEmpty(t, req.Body["sign"])
+		assert.NotEmpty(t, req.Body["timestamp"])
+		header := req.Body["card"].(map[string]interface{})["header"].(map[string]interface{})
+		assert.Equal(t, "red", header["template"])
+
+		srv2, _ := newCaptureServer(t, `{"code":19021,"msg":"sign match fail"}`)
+		assert.ErrorContains(t, feishuSender{}.Send(&model.NotificationChannel{URL: srv2.URL}, msg), "sign match fail")
+	})
+
+	t.Run("Telegram HTML转义", func(t *testing.T) {
+		srv, req := newCaptureServer(t, `{"ok":true}`)
+		ch := &model.NotificationChannel{URL: srv.URL, Token: "123:ABC", ChatID: "-100"}
+		require.NoError(t, telegramSender{}.Send(ch, msg))
+		assert.Equal(t, "/bot123:ABC/sendMessage", req.Path)
+		assert.Equal(t, "-100", req.Body["chat_id"])
+		assert.Equal(t, "HTML", req.Body["parse_mode"])
+		assert.Contains(t, req.Body["text"], "frpc &lt;web&gt;")
+
+		fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"ok":false,"description":"Unauthorized"}`)
+		}))
+		defer fail.Close()
+		err := telegramSender{}.Send(&model.NotificationChannel{URL: fail.URL, Token: "123:ABC", ChatID: "1"}, msg)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "123:ABC", "错误信息不包含 Bot Token")
+	})
+
+	t.Run("Slack Blocks", func(t *testing.T) {
+		srv, req := newCaptureServer(t, "ok")
+		require.NoError(t, slackSender{}.Send(&model.NotificationChannel{URL: srv.URL}, msg))
+		blocks := req.Body["blocks"].([]interface{})
+		require.Len(t, blocks, 4)
+		assert.Equal(t, "header", blocks[0].(map[string]interface{})["type"])
+		fields := blocks[2].(map[string]interface{})["fields"].([]interface{})
+		assert.Equal(t, "*目标名称*\nweb", fields[0].(map[string]interface{})["text"])
+	})
+
+	t.Run("ntfy JSON发布", func(t *testing.T) {
+		srv, req := newCaptureServer(t, `{"id":"x"}`)
+		require.NoError(t, ntfySender{}.Send(&model.NotificationChannel{URL: srv.URL, Topic: "frp", Token: "tk", Priority: 4}, msg))
+		assert.Equal(t, "Bearer tk", req.Header.Get("Authorization"))
+		assert.Equal(t, "frp", req.Body["topic"])
+		assert.Equal(t, float64(4), req.Body["priority"])
+		assert.Contains(t, req.Body["message"], "目标名称: web")
+	})
+
+	t.Run("Gotify", func(t *testing.T) {
+		srv, req := newCaptureServer(t, `{"id":1}`)
+		require.NoError(t, gotifySender{}.Send(&model.NotificationChannel{URL: srv.URL + "/", Token: "app"}, msg))
+		assert.Equal(t, "/message", req.Path)
+		assert.Equal(t, "app", req.Header.Get("X-Gotify-Key"))
+		assert.Equal(t, float64(5), req.Body["priority"], "未设置优先级时使用默认值")
+	})
+
+	t.Run("通用Webhook", func(t *testing.T) {
+		srv, req := newCaptureServer(t, "")
+		require.NoError(t, webhookSender{}.Send(&model.NotificationChannel{URL: srv.URL}, msg))
+		assert.Equal(t, "offline", req.Body["event_type"])
+		assert.Equal(t, map[string]interface{}{"目标名称": "web"}, req.Body["fields"])
+	})
+
+	t.Run("非2xx状态码视为失败", func(t *testing.T) {
+		fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer fail.Close()
+		assert.ErrorContains(t, webhookSender{}.Send(&model.NotificationChannel{URL: fail.URL}, msg), "HTTP 404")
+	})
+}
+
+func setupNotificationTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.NotificationChannel{}, &model.AlertRecipientGroup{},
+		&model.WebhookDelivery{}, &model.WebhookDeliveryAttempt{})
+}
+
+// TestNotificationService_Channels 测试渠道校验、密钥保留及清空
+func TestNotificationService_Channels(t *testing.T) {
+	setupNotificationTestDB(t)
+	svc := NewNotificationService()
+
+	invalid := []model.NotificationChannel{
+		{Name: "", Type: model.ChannelTypeSlack, URL: "https://hooks.slack.com/x"},
+		{Name: "a", Type: "sms"},
+		{Name: "a", Type: model.ChannelTypeDingTalk, URL: "ftp://x"},
+		{Name: "a", Type: model.ChannelTypeTelegram, ChatID: "1"},
+		{Name: "a", Type: model.ChannelTypeNtfy},
+		{Name: "a", Type: model.ChannelTypeGotify, URL: "https://gotify.example.com"},
+	}
+	for _, ch := range invalid {
+		assert.Error(t, svc.CreateChannel(&ch), "%+v", ch)
+	}
+
+	ch := &model.NotificationChannel{Name: "tg", Type: model.ChannelTypeTelegram, Token: "123:ABC", ChatID: "1", Enabled: true}
+	require.NoError(t, svc.CreateChannel(ch))
+	assert.True(t, ch.HasToken)
+	data, _ := json.Marshal(ch)
+	assert.NotContains(t, string(data), "123:ABC", "令牌不序列化")
+
+	assert.Error(t, svc.CreateChannel(&model.NotificationChannel{Name: "tg", Type: model.ChannelTypeTelegram, Token: "x", ChatID: "1"}), "名称重复")
+
+	updated, err := svc.UpdateChannel(&model.NotificationChannel{ID: ch.ID, Name: "tg", Type: model.ChannelTypeTelegram, ChatID: "2", Enabled: true}, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, "123:ABC", updated.Token, "令牌留空时保留原值")
+	assert.Equal(t, "2", updated.ChatID)
+
+	_, err = svc.UpdateChannel(&model.NotificationChannel{ID: ch.ID, Name: "tg", Type: model.ChannelTypeTelegram, ChatID: "2"}, true, false)
+	assert.Error(t, err, "清空必填令牌后校验失败")
+
+	ntfy := &model.NotificationChannel{Name: "ntfy", Type: model.ChannelTypeNtfy, Topic: "frp", Token: "tk", Enabled: true}
+	require.NoError(t, svc.CreateChannel(ntfy))
+	cleared, err := svc.UpdateChannel(&model.NotificationChannel{ID: ntfy.ID, Name: "ntfy", Type: model.ChannelTypeNtfy, Topic: "frp", Enabled: true}, true, false)
+	require.NoError(t, err)
+	assert.False(t, cleared.HasToken)
+
+	channels, err := svc.GetAllChannels()
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+	assert.True(t, channels[0].HasToken)
+
+	require.NoError(t, svc.DeleteChannel(ntfy.ID))
+	assert.Error(t, svc.DeleteChannel(ntfy.ID))
+}
+
+// TestNotificationService_ChannelIDsForRule 测试规则渠道与分组渠道合并去重
+func TestNotificationService_ChannelIDsForRule(t *testing.T) {
+	setupNotificationTestDB(t)
+	groups := []model.AlertRecipientGroup{
+		{Name: "ops", Enabled: true, ChannelIDs: "2,3"},
+		{Name: "off", Enabled: true, ChannelIDs: "9"},
+	}
+	require.NoError(t, database.DB.Create(&groups).Error)
+	require.NoError(t, database.DB.Model(&groups[1]).Update("enabled", false).Error)
+
+	svc := NewNotificationService()
+	rule := &model.AlertRule{NotifyChannelIDs: "1,2", NotifyGroupIDs: "1,2"}
+	assert.Equal(t, []uint{1, 2, 3}, svc.ChannelIDsForRule(rule), "禁用分组的渠道不通知")
+	assert.Empty(t, svc.ChannelIDsForRule(&model.AlertRule{}))
+}
+
+// TestNotificationService_TestChannel 测试发送测试消息
+func TestNotificationService_TestChannel(t *testing.T) {
+	setupNotificationTestDB(t)
+	srv, req := newCaptureServer(t, "")
+	svc := NewNotificationService()
+	ch := &model.NotificationChannel{Name: "hook", Type: model.ChannelTypeWebhook, URL: srv.URL, Enabled: true}
+	require.NoError(t, svc.CreateChannel(ch))
+
+	require.NoError(t, svc.TestChannel(ch.ID))
+	assert.Equal(t, "test", req.Body["event_type"])
+	assert.Error(t, svc.TestChannel(999))
+
+	require.NoError(t, svc.TestChannelConfig(&model.NotificationChannel{Name: "new", Type: model.ChannelTypeWebhook, URL: srv.URL}))
+	assert.Error(t, svc.TestChannelConfig(&model.NotificationChannel{Name: "new", Type: model.ChannelTypeWebhook}))
+}
+
+// TestNotificationService_WebhookChannelDelivery 测试通用 Webhook 渠道经投递队列发送，失败后等待重试
+func TestNotificationService_WebhookChannelDelivery(t *testing.T) {
+	setupNotificationTestDB(t)
+	srv, req := newCaptureServer(t, "")
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer fail.Close()
+	svc := NewNotificationService()
+	msg := testNotificationMessage()
+
+	require.NoError(t, svc.sendTo(&model.NotificationChannel{Type: model.ChannelTypeWebhook, URL: srv.URL}, msg))
+	assert.Equal(t, "offline", req.Body["event_type"])
+	assert.Equal(t, "offline", req.Header.Get(WebhookHeaderEvent))
+
+	require.NoError(t, svc.sendTo(&model.NotificationChannel{Type: model.ChannelTypeWebhook, URL: fail.URL}, msg), "投递失败由重试处理")
+
+	var deliveries []model.WebhookDelivery
+	require.NoError(t, database.DB.Order("id").Find(&deliveries).Error)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, model.WebhookDeliverySuccess, deliveries[0].Status)
+	assert.Equal(t, model.WebhookDeliveryPending, deliveries[1].Status)
+	assert.Equal(t, 1, deliveries[1].Attempts)
+	assert.Equal(t, http.StatusBadGateway, deliveries[1].LastStatusCode)
+}
+
+// TestNotificationService_TestChannelConfigSecrets 测试地址改动后不沿用已保存的令牌
+func TestNotificationService_TestChannelConfigSecrets(t *testing.T) {
+	setupNotificationTestDB(t)
+	saved, savedReq := newCaptureServer(t, "")
+	other, otherReq := newCaptureServer(t, "")
+	svc := NewNotificationService()
+	ch := &model.NotificationChannel{Name: "ntfy", Type: model.ChannelTypeNtfy, URL: saved.URL, Topic: "frp", Token: "tk_saved", Enabled: true}
+	require.NoError(t, svc.CreateChannel(ch))
+
+	require.NoError(t, svc.TestChannelConfig(&model.NotificationChannel{ID: ch.ID, Name: "ntfy", Type: model.ChannelTypeNtfy, URL: saved.URL, Topic: "frp"}))
+	assert.Equal(t, "Bearer tk_saved", savedReq.Header.Get("Authorization"), "地址未改动时沿用已保存的令牌")
+
+	require.NoError(t, svc.TestChannelConfig(&model.NotificationChannel{ID: ch.ID, Name: "ntfy", Type: model.ChannelTypeNtfy, URL: other.URL, Topic: "frp"}))
+	assert.Empty(t, otherReq.Header.Get("Authorization"), "地址改动后不发送已保存的令牌")
+
+	require.NoError(t, svc.TestChannelConfig(&model.NotificationChannel{ID: ch.ID, Name: "ntfy", Type: model.ChannelTypeNtfy, URL: other.URL, Topic: "frp", Token: "tk_new"}))
+	assert.Equal(t, "Bearer tk_new", otherReq.Header.Get("Authorization"))
+}
+
+// TestEventDataFields 测试系统事件详情展开为通知字段
+func TestEventDataFields(t *testing.T) {
+	fields := eventDataFields(`{"domain":"a.example.com","cert_id":3,"error":"","proxy_ids":[1,2],"x":null}`)
+	assert.Equal(t, []NotificationField{
+		{Name: "cert_id", Value: "3"},
+		{Name: "domain", Value: "a.example.com"},
+		{Name: "proxy_ids", Value: "[1,2]"},
+	}, fields)
+	assert.Nil(t, eventDataFields("not json"))
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/websocket"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// metricsNamespace 面板导出指标的名称前缀
+const metricsNamespace = "frp_panel_"
+
+// PrometheusExporter 将面板自身的数据库状态与运行状态导出为 Prometheus 指标
+type PrometheusExporter struct {
+	clientRepo  *repository.ClientRepository
+	proxyRepo   *repository.ProxyRepository
+	serverRepo  *repository.FrpServerRepository
+	certRepo    *repository.CertificateRepository
+	dnsRepo     *repository.DNSRecordRepository
+	alertRepo   *repository.AlertRepo
+	daemonHub   *websocket.ClientDaemonHub
+	hub         *websocket.Hub
+	taskManager *TaskManager
+}
+
+func NewPrometheusExporter(serverRepo *repository.FrpServerRepository, alertRepo *repository.AlertRepo, daemonHub *websocket.ClientDaemonHub, hub *websocket.Hub, taskManager *TaskManager) *PrometheusExporter {
+	return &PrometheusExporter{
+		clientRepo:  repository.NewClientRepository(),
+		proxyRepo:   repository.NewProxyRepository(),
+		serverRepo:  serverRepo,
+		certRepo:    repository.NewCertificateRepository(),
+		dnsRepo:     repository.NewDNSRecordRepository(),
+		alertRepo:   alertRepo,
+		daemonHub:   daemonHub,
+		hub:         hub,
+		taskManager: taskManager,
+	}
+}
+
+// metricFamilies 按名称收集指标，输出时按名称排序
+type metricFamilies map[string]*dto.MetricFamily
+
+func (f metricFamilies) add(name, help string, typ dto.MetricType, value float64, labels ...string) {
+	name = metricsNamespace + name
+	family, ok := f[name]
+	if !ok {
+		family = &dto.MetricFamily{Name: proto.String(name), Help: proto.String(help), Type: typ.Enum()}
+		f[name] = family
+	}
+
+	metric := &dto.Metric{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(labels[i]), Value: proto.String(labels[i+1])})
+	}
+	switch typ {
+	case dto.MetricType_COUNTER:
+		metric.Counter = &dto.Counter{Value: proto.Float64(value)}
+	default:
+		metric.Gauge = &dto.Gauge{Value: proto.Float64(value)}
+	}
+	family.Metric = append(family.Metric, metric)
+}
+
+func (f metricFamilies) gauge(name, help string, value float64, labels ...string) {
+	f.add(name, help, dto.MetricType_GAUGE, value, labels...)
+}
+
+func (f metricFamilies) counter(name, help string, value float64, labels ...string) {
+	f.add(name, help, dto.MetricType_COUNTER, value, labels...)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Gather 采集所有指标，单个数据源失败只记录日志，不影响其他指标
+func (e *PrometheusExporter) Gather() []*dto.MetricFamily {
+	f := metricFamilies{}
+	now := time.Now()
+
+	serverNames := e.collectServers(f)
+	clientLabels := e.collectClients(f, serverNames)
+	e.collectProxies(f, clientLabels)
+	e.collectCertificates(f, now)
+	e.collectDNSRecords(f)
+	e.collectAlerts(f)
+	e.collectRuntime(f)
+
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		families = append(families, f[name])
+	}
+	return families
+}
+
+// Write 以指定格式输出指标
+func (e *PrometheusExporter) Write(w io.Writer, format expfmt.Format) error {
+	encoder := expfmt.NewEncoder(w, format)
+	for _, family := range e.Gather() {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (e *PrometheusExporter) collectServers(f metricFamilies) map[uint]string {
+	names := make(map[uint]string)
+	servers, err := e.serverRepo.GetAll()
+	if err != nil {
+		logger.Warnf("[Prometheus] 获取服务器列表失败: %v", err)
+		return names
+	}
+	for _, server := range servers {
+		names[server.ID] = server.Name
+		f.gauge("frp_server_up", "frps 服务器是否处于运行状态", boolValue(server.Status == model.StatusRunning), "server", server.Name)
+	}
+	return names
+}
+
+// clientLabel 客户端指标的公共标签
+type clientLabel struct {
+	server string
+	client string
+}
+
+func (e *PrometheusExporter) collectClients(f metricFamilies, serverNames map[uint]string) map[uint]clientLabel {
+	labels := make(map[uint]clientLabel)
+	clients, err := e.clientRepo.GetAllForStatusCheck()
+	if err != nil {
+		logger.Warnf("[Prometheus] 获取客户端列表失败: %v", err)
+		return labels
+	}
+	for _, client := range clients {
+		label := clientLabel{client: client.Name}
+		if client.FrpServerID != nil {
+			label.server = serverNames[*client.FrpServerID]
+		}
+		labels[client.ID] = label
+
+		f.gauge("client_online", "客户端 frpc 是否在线", boolValue(client.OnlineStatus == "online"), "server", label.server, "client", label.client)
+		f.gauge("client_ws_connected", "客户端守护进程 WebSocket 是否已连接", boolValue(client.WsConnected), "server", label.server, "client", label.client)
+		f.gauge("client_config_version", "客户端当前下发的配置版本", float64(client.ConfigVersion), "server", label.server, "client", label.client)
+		f.gauge("client_config_sync_status", "客户端配置同步状态（当前状态为1）", 1, "server", label.server, "client", label.client, "status", client.ConfigSyncStatus)
+	}
+	return labels
+}
+
+func (e *PrometheusExporter) collectProxies(f metricFamilies, clientLabels map[uint]clientLabel) {
+	proxies, err := e.proxyRepo.FindAll()
+	if err != nil {
+		logger.Warnf("[Prometheus] 获取代理列表失败: %v", err)
+		return
+	}
+	for _, proxy := range proxies {
+		cl := clientLabels[proxy.ClientID]
+		labels := []string{"server", cl.server, "client", cl.client, "proxy", proxy.Name, "type", proxy.Type}
+
+		f.counter("proxy_traffic_in_bytes_total", "代理累计入站流量（字节）", float64(proxy.TotalBytesIn), labels...)
+		f.counter("proxy_traffic_out_bytes_total", "代理累计出站流量（字节）", float64(proxy.TotalBytesOut), labels...)
+		f.gauge("proxy_traffic_in_rate_bytes", "代理当前入站速率（字节/秒）", float64(proxy.CurrentBytesInRate), labels...)
+		f.gauge("proxy_traffic_out_rate_bytes", "代理当前出站速率（字节/秒）", float64(proxy.CurrentBytesOutRate), labels...)
+		f.gauge("proxy_connections", "代理当前连接数", float64(proxy.FrpCurConns), labels...)
+		f.gauge("proxy_online", "代理在 frps 上是否在线", boolValue(proxy.FrpStatus == "online"), labels...)
+		f.gauge("proxy_enabled", "代理是否启用", boolValue(proxy.Enabled), labels...)
+	}
+}
+
+func (e *PrometheusExporter) collectCertificates(f metricFamilies, now time.Time) {
+	certs, err := e.certRepo.FindAll()
+	if err != nil {
+		logger.Warnf("[Prometheus] 获取证书列表失败: %v", err)
+		return
+	}
+	for _, cert := range certs {
+		if cert.NotAfter == nil {
+			continue
+		}
+		days := cert.NotAfter.Sub(now).Hours() / 24
+		// 同一域名可能有多张证书（如续期前后），以证书ID区分
+		f.gauge("certificate_expiry_days", "证书剩余有效天数", days,
+			"cert_id", strconv.FormatUint(uint64(cert.ID), 10), "domain", cert.Domain, "status", cert.Status)
+	}
+}
+
+func (e *PrometheusExporter) collectDNSRecords(f metricFamilies) {
+	records, err := e.dnsRepo.FindAll()
+	if err != nil {
+		logger.Warnf("[Prometheus] 获取DNS记录失败: %v", err)
+		return
+	}
+	for _, record := range records {
+		f.gauge("dns_record_status", "DNS 记录同步状态（当前状态为1）", 1, "domain", record.Domain,
+			"record_type", record.RecordType, "record_name", dnsRecordName(record), "status", string(record.Status))
+	}
+}
+
+// dnsRecordName 记录相对根域名的主机记录，根域名本身为 @
+func dnsRecordName(record model.DNSRecord) string {
+	if record.RootDomain == "" {
+		_, rr := ParseDomain(record.Domain)
+		return rr
+	}
+	if record.Domain == record.RootDomain {
+		return "@"
+	}
+	return strings.TrimSuffix(record.Domain, "."+record.RootDomain)
+}
+
+func (e *PrometheusExporter) collectAlerts(f metricFamilies) {
+	rules, err := e.alertRepo.GetAllRules()
+	if err != nil {
+		logger.Warnf("[Prometheus] 获取告警规则失败: %v", err)
+	} else {
+		counts := make(map[[2]string]int)
+		for _, rule := range rules {
+			counts[[2]string{string(rule.TargetType), strconv.FormatBool(rule.Enabled)}]++
+		}
+		for key, count := range counts {
+			f.gauge("alert_rules", "告警规则数量", float64(count), "target_type", key[0], "enabled", key[1])
+		}
+	}
+
+	logs, err := e.alertRepo.CountAlertLogsByType()
+	if err != nil {
+		logger.Warnf("[Prometheus] 统计告警记录失败: %v", err)
+		return
+	}
+	for _, c := range logs {
+		f.counter("alerts_total", "已触发的告警总数", float64(c.Count), "target_type", c.TargetType, "alert_type", c.AlertType)
+	}
+}
+
+func (e *PrometheusExporter) collectRuntime(f metricFamilies) {
+	if e.daemonHub != nil {
+		f.gauge("daemon_connections", "已连接的客户端守护进程数量", float64(e.daemonHub.GetOnlineCount()))
+	}
+	if e.hub != nil {
+		f.gauge("websocket_connections", "已连接的前端 WebSocket 数量", float64(e.hub.ClientCount()))
+	}
+	if e.taskManager == nil {
+		return
+	}
+	for _, status := range e.taskManager.TaskStatuses() {
+		f.counter("task_runs_total", "后台任务执行次数", float64(status.Runs), "task", status.Name)
+		f.counter("task_failures_total", "后台任务异常次数", float64(status.Failures), "task", status.Name)
+		f.gauge("task_running", "后台任务是否正在执行", boolValue(status.Running), "task", status.Name)
+		if !status.LastRun.IsZero() {
+			f.gauge("task_last_run_timestamp_seconds", "后台任务最近一次开始执行的时间", float64(status.LastRun.Unix()), "task", status.Name)
+			f.gauge("task_last_duration_seconds", "后台任务最近一次执行耗时（秒）", status.LastDuration.Seconds(), "task", status.Name)
+		}
+		if status.Periodic && !status.LastRun.IsZero() {
+			// 超过两个周期未执行视为任务卡死
+			stale := time.Since(status.LastRun) > 2*status.Interval+status.LastDuration
+			f.gauge("task_healthy", "后台任务是否按周期正常执行", boolValue(!stale && status.LastError == ""), "task", status.Name)
+		}
+	}
+}
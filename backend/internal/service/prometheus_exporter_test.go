@@ -0,0 +1,89 @@
+package service
+
+import (
+	"bytes"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetricFamilies 测试指标按名称归组并输出为文本格式
+func TestMetricFamilies(t *testing.T) {
+	f := metricFamilies{}
+	f.counter("proxy_traffic_in_bytes_total", "入站流量", 100, "client", "c1", "proxy", "web")
+	f.counter("proxy_traffic_in_bytes_total", "入站流量", 200, "client", "c2", "proxy", "ssh")
+	f.gauge("daemon_connections", "连接数", 3)
+
+	require.Len(t, f, 2)
+	assert.Len(t, f["frp_panel_proxy_traffic_in_bytes_total"].Metric, 2)
+
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range f {
+		require.NoError(t, encoder.Encode(family))
+	}
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE frp_panel_proxy_traffic_in_bytes_total counter")
+	assert.Contains(t, out, `frp_panel_proxy_traffic_in_bytes_total{client="c1",proxy="web"} 100`)
+	assert.Contains(t, out, "frp_panel_daemon_connections 3")
+}
+
+// TestCollectCertificatesAndDNSRecords 测试同一域名的多张证书和多条记录输出不同的标签组合
+func TestCollectCertificatesAndDNSRecords(t *testing.T) {
+	newServiceTestDB(t, &model.Certificate{}, &model.DNSRecord{})
+
+	now := time.Now()
+	expire := now.Add(30 * 24 * time.Hour)
+	renewed := now.Add(90 * 24 * time.Hour)
+	require.NoError(t, database.DB.Create(&model.Certificate{Domain: "a.example.com", Status: "active", NotAfter: &expire}).Error)
+	require.NoError(t, database.DB.Create(&model.Certificate{Domain: "a.example.com", Status: "active", NotAfter: &renewed}).Error)
+	require.NoError(t, database.DB.Create(&model.DNSRecord{Domain: "a.example.com", RootDomain: "example.com", RecordType: "A", Status: "synced"}).Error)
+	require.NoError(t, database.DB.Create(&model.DNSRecord{Domain: "a.example.com", RootDomain: "example.com", RecordType: "AAAA", Status: "synced"}).Error)
+	require.NoError(t, database.DB.Create(&model.DNSRecord{Domain: "example.com", RootDomain: "example.com", RecordType: "A", Status: "synced"}).Error)
+
+	e := NewPrometheusExporter(nil, nil, nil, nil, nil)
+	f := metricFamilies{}
+	e.collectCertificates(f, now)
+	e.collectDNSRecords(f)
+
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range f {
+		require.NoError(t, encoder.Encode(family))
+	}
+	out := buf.String()
+	assert.Contains(t, out, `frp_panel_certificate_expiry_days{cert_id="1",domain="a.example.com",status="active"}`)
+	assert.Contains(t, out, `frp_panel_certificate_expiry_days{cert_id="2",domain="a.example.com",status="active"}`)
+	assert.Contains(t, out, `frp_panel_dns_record_status{domain="a.example.com",record_type="A",record_name="a",status="synced"} 1`)
+	assert.Contains(t, out, `frp_panel_dns_record_status{domain="a.example.com",record_type="AAAA",record_name="a",status="synced"} 1`)
+	assert.Contains(t, out, `frp_panel_dns_record_status{domain="example.com",record_type="A",record_name="@",status="synced"} 1`)
+}
+
+// TestTaskStatuses 测试后台任务执行状态记录
+func TestTaskStatuses(t *testing.T) {
+	m := NewTaskManager()
+	ok := NewPeriodicTask("ok", 0, func() {})
+	failing := NewPeriodicTask("failing", 0, func() { panic("boom") })
+	m.RegisterTask(ok)
+	m.RegisterTask(failing)
+
+	ok.track(ok.fn)
+	failing.track(failing.fn)
+
+	statuses := m.TaskStatuses()
+	require.Len(t, statuses, 2)
+
+	assert.Equal(t, "ok", statuses[0].Name)
+	assert.EqualValues(t, 1, statuses[0].Runs)
+	assert.EqualValues(t, 0, statuses[0].Failures)
+	assert.False(t, statuses[0].LastRun.IsZero())
+
+	assert.EqualValues(t, 1, statuses[1].Runs)
+	assert.EqualValues(t, 1, statuses[1].Failures, "panic 应记为失败而不是终止进程")
+	assert.Equal(t, "boom", statuses[1].LastError)
+}
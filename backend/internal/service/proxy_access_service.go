@@ -0,0 +1,442 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/util"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAccessBlockThreshold 告警规则未设置阈值时，窗口内触发告警的拦截次数
+	defaultAccessBlockThreshold = 10
+	accessBlockWindow           = 10 * time.Minute
+	accessPolicyCacheTTL        = time.Minute
+)
+
+// compiledAccessPolicy 解析后的访问策略
+type compiledAccessPolicy struct {
+	allowNets    []*net.IPNet
+	denyNets     []*net.IPNet
+	allowRegions []string
+	denyRegions  []string
+}
+
+type accessBlockStat struct {
+	count     int64
+	firstSeen time.Time
+	lastSeen  time.Time
+	location  string
+	reason    string
+}
+
+type accessProxyKey struct {
+	serverID  uint
+	proxyName string
+}
+
+// accessBlockCounter 单个代理的拦截计数窗口，用于触发频繁拦截告警
+type accessBlockCounter struct {
+	start      time.Time
+	count      int64
+	lastIP     string
+	lastReason string
+	dirty      bool
+}
+
+// ProxyAccessService 代理来源访问控制，按 IP/CIDR 黑白名单和地区规则拦截 frps 用户连接
+type ProxyAccessService struct {
+	accessRepo  *repository.ProxyAccessRepository
+	proxyRepo   *repository.ProxyRepository
+	clientRepo  *repository.ClientRepository
+	settingRepo *repository.SettingRepository
+	notifier    *SystemEventNotifier
+
+	policyMu       sync.RWMutex
+	policies       map[string]*compiledAccessPolicy // key: clientName.proxyName
+	policyLoadedAt time.Time
+
+	mu       sync.Mutex
+	blocks   map[visitorKey]*accessBlockStat
+	dropped  int64
+	counters map[accessProxyKey]*accessBlockCounter
+	flushMu  sync.Mutex
+}
+
+func NewProxyAccessService() *ProxyAccessService {
+	return &ProxyAccessService{
+		accessRepo:  repository.NewProxyAccessRepository(),
+		proxyRepo:   repository.NewProxyRepository(),
+		clientRepo:  repository.NewClientRepository(),
+		settingRepo: repository.NewSettingRepository(),
+		blocks:      make(map[visitorKey]*accessBlockStat),
+		counters:    make(map[accessProxyKey]*accessBlockCounter),
+	}
+}
+
+// SetEventNotifier 设置系统事件通知器，用于频繁拦截告警
+func (s *ProxyAccessService) SetEventNotifier(notifier *SystemEventNotifier) {
+	s.notifier = notifier
+}
+
+// GetPolicy 获取代理的访问策略，未配置时返回 nil
+func (s *ProxyAccessService) GetPolicy(proxyID uint) (*model.ProxyAccessPolicy, error) {
+	policy, err := s.accessRepo.FindPolicyByProxyID(proxyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy.ProxyName = s.proxyFullName(proxyID)
+	return policy, nil
+}
+
+// SavePolicy 创建或更新代理的访问策略
+func (s *ProxyAccessService) SavePolicy(policy *model.ProxyAccessPolicy) (*model.ProxyAccessPolicy, error) {
+	if _, err := s.proxyRepo.FindByID(policy.ProxyID); err != nil {
+		return nil, fmt.Errorf("代理不存在")
+	}
+	if _, err := compileAccessPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.accessRepo.FindPolicyByProxyID(policy.ProxyID); err == nil {
+		policy.ID = existing.ID
+		policy.CreatedAt = existing.CreatedAt
+	}
+	if err := s.accessRepo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+	s.invalidatePolicies()
+	policy.ProxyName = s.proxyFullName(policy.ProxyID)
+	return policy, nil
+}
+
+// DeletePolicy 删除代理的访问策略
+func (s *ProxyAccessService) DeletePolicy(proxyID uint) error {
+	if err := s.accessRepo.DeletePolicyByProxyID(proxyID); err != nil {
+		return err
+	}
+	s.invalidatePolicies()
+	return nil
+}
+
+func (s *ProxyAccessService) proxyFullName(proxyID uint) string {
+	proxy, err := s.proxyRepo.FindByID(proxyID)
+	if err != nil {
+		return ""
+	}
+	client, err := s.clientRepo.FindByID(proxy.ClientID)
+	if err != nil {
+		return proxy.Name
+	}
+	return client.Name + "." + proxy.Name
+}
+
+// compileAccessPolicy 解析策略中的 CIDR 和地区列表
+func compileAccessPolicy(policy *model.ProxyAccessPolicy) (*compiledAccessPolicy, error) {
+	compiled := &compiledAccessPolicy{
+		allowRegions: splitAccessList(policy.AllowRegions),
+		denyRegions:  splitAccessList(policy.DenyRegions),
+	}
+	var err error
+	if compiled.allowNets, err = parseCIDRList(policy.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if compiled.denyNets, err = parseCIDRList(policy.DenyCIDRs); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// splitAccessList 按逗号或换行拆分列表
+func splitAccessList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseCIDRList 解析 IP/CIDR 列表，单个 IP 视为主机地址
+func parseCIDRList(value string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range splitAccessList(value) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 CIDR: %s", item)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// evaluate 判断来源是否被拦截，返回拦截原因；先匹配黑名单，再在配置了白名单时要求命中白名单
+func (p *compiledAccessPolicy) evaluate(ip net.IP, location func() string) string {
+	for _, n := range p.denyNets {
+		if n.Contains(ip) {
+			return fmt.Sprintf("命中IP黑名单 %s", n.String())
+		}
+	}
+	if len(p.denyRegions) > 0 {
+		if region := matchRegion(location(), p.denyRegions); region != "" {
+			return fmt.Sprintf("命中地区黑名单 %s", region)
+		}
+	}
+
+	if len(p.allowNets) == 0 && len(p.allowRegions) == 0 {
+		return ""
+	}
+	for _, n := range p.allowNets {
+		if n.Contains(ip) {
+			return ""
+		}
+	}
+	if len(p.allowRegions) > 0 && matchRegion(location(), p.allowRegions) != "" {
+		return ""
+	}
+	return "不在白名单中"
+}
+
+// matchRegion 判断归属地(国家 省份 城市 ISP)是否包含规则中的任一地区，返回命中的地区
+func matchRegion(location string, regions []string) string {
+	fields := strings.Fields(location)
+	for _, region := range regions {
+		if location == region {
+			return region
+		}
+		for _, field := range fields {
+			if field == region {
+				return region
+			}
+		}
+	}
+	return ""
+}
+
+func (s *ProxyAccessService) invalidatePolicies() {
+	s.policyMu.Lock()
+	s.policyLoadedAt = time.Time{}
+	s.policyMu.Unlock()
+}
+
+// policyFor 获取代理的访问策略，策略缓存在内存中定期刷新
+func (s *ProxyAccessService) policyFor(proxyName string) *compiledAccessPolicy {
+	s.policyMu.RLock()
+	if time.Since(s.policyLoadedAt) < accessPolicyCacheTTL {
+		policy := s.policies[proxyName]
+		s.policyMu.RUnlock()
+		return policy
+	}
+	s.policyMu.RUnlock()
+
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+	if time.Since(s.policyLoadedAt) >= accessPolicyCacheTTL {
+		s.loadPolicies()
+	}
+	return s.policies[proxyName]
+}
+
+func (s *ProxyAccessService) loadPolicies() {
+	policies, err := s.accessRepo.FindEnabledPolicies()
+	if err != nil {
+		// 加载失败时沿用旧策略，避免数据库抖动导致放行
+		logger.Errorf("[访问控制] 加载访问策略失败: %v", err)
+		return
+	}
+
+	compiled := make(map[string]*compiledAccessPolicy, len(policies))
+	for i := range policies {
+		name := s.proxyFullName(policies[i].ProxyID)
+		if name == "" {
+			continue
+		}
+		p, err := compileAccessPolicy(&policies[i])
+		if err != nil {
+			logger.Warnf("[访问控制] 代理 %s 的访问策略无效: %v", name, err)
+			continue
+		}
+		compiled[name] = p
+	}
+	s.policies = compiled
+	s.policyLoadedAt = time.Now()
+}
+
+// Authorize 按访问策略检查一次用户连接，被拦截时记录并返回拦截原因
+func (s *ProxyAccessService) Authorize(serverID uint, proxyName, remoteAddr string, at time.Time) string {
+	policy := s.policyFor(proxyName)
+	if policy == nil {
+		return ""
+	}
+	ipStr := remoteIP(remoteAddr)
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return ""
+	}
+
+	location := ""
+	reason := policy.evaluate(ip, func() string {
+		if location == "" {
+			location = util.GetIPLocation(ipStr)
+		}
+		return location
+	})
+	if reason != "" {
+		s.recordBlock(serverID, proxyName, ipStr, location, reason, at)
+	}
+	return reason
+}
+
+func (s *ProxyAccessService) recordBlock(serverID uint, proxyName, ip, location, reason string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counterKey := accessProxyKey{serverID: serverID, proxyName: proxyName}
+	counter, ok := s.counters[counterKey]
+	if !ok || at.Sub(counter.start) >= accessBlockWindow {
+		counter = &accessBlockCounter{start: at}
+		s.counters[counterKey] = counter
+	}
+	counter.count++
+	counter.lastIP = ip
+	counter.lastReason = reason
+	counter.dirty = true
+
+	key := visitorKey{serverID: serverID, proxyName: proxyName, ip: ip}
+	if stat, ok := s.blocks[key]; ok {
+		stat.count++
+		stat.lastSeen = at
+		stat.reason = reason
+		return
+	}
+	if len(s.blocks) >= visitorBufferLimit {
+		s.dropped++
+		return
+	}
+	s.blocks[key] = &accessBlockStat{count: 1, firstSeen: at, lastSeen: at, location: location, reason: reason}
+}
+
+// Flush 写入拦截记录并检查频繁拦截告警，供定时任务调用
+func (s *ProxyAccessService) Flush() {
+	s.flushAt(time.Now())
+}
+
+func (s *ProxyAccessService) flushAt(now time.Time) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	blocks, dropped := s.blocks, s.dropped
+	s.blocks = make(map[visitorKey]*accessBlockStat)
+	s.dropped = 0
+	var events []AccessBlockEventData
+	for key, counter := range s.counters {
+		if now.Sub(counter.start) >= accessBlockWindow && !counter.dirty {
+			delete(s.counters, key)
+			continue
+		}
+		if !counter.dirty {
+			continue
+		}
+		counter.dirty = false
+		events = append(events, AccessBlockEventData{
+			ServerID:      key.serverID,
+			ProxyName:     key.proxyName,
+			BlockCount:    counter.count,
+			WindowMinutes: int(accessBlockWindow / time.Minute),
+			LastIP:        counter.lastIP,
+			LastReason:    counter.lastReason,
+		})
+	}
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		logger.Warnf("[访问控制] 缓存已满，丢弃 %d 次拦截记录", dropped)
+	}
+	if len(blocks) > 0 {
+		records := make([]model.ProxyAccessBlock, 0, len(blocks))
+		for key, stat := range blocks {
+			location := stat.location
+			if location == "" {
+				location = util.GetIPLocation(key.ip)
+			}
+			records = append(records, model.ProxyAccessBlock{
+				ServerID:     key.serverID,
+				ProxyName:    key.proxyName,
+				IP:           key.ip,
+				Location:     location,
+				Reason:       stat.reason,
+				BlockCount:   stat.count,
+				FirstBlocked: stat.firstSeen,
+				LastBlocked:  stat.lastSeen,
+			})
+		}
+		if err := s.accessRepo.UpsertBlocks(records); err != nil {
+			logger.Errorf("[访问控制] 写入 %d 条拦截记录失败: %v", len(records), err)
+		}
+	}
+
+	if s.notifier != nil {
+		for _, event := range events {
+			s.notifier.NotifyAccessBlocked(event)
+		}
+	}
+}
+
+// Cleanup 清理超过访客保留天数的拦截记录，供定时任务调用
+func (s *ProxyAccessService) Cleanup() {
+	retention := defaultVisitorRetention
+	if val, err := s.settingRepo.GetSetting("proxy_visitor_retention_days"); err == nil {
+		if days, err := strconv.Atoi(val); err == nil && days > 0 {
+			retention = days
+		}
+	}
+	if deleted, err := s.accessRepo.DeleteBlocksBefore(time.Now().AddDate(0, 0, -retention)); err != nil {
+		logger.Errorf("[访问控制] 清理过期拦截记录失败: %v", err)
+	} else if deleted > 0 {
+		logger.Infof("[访问控制] 清理 %d 条超过 %d 天的拦截记录", deleted, retention)
+	}
+}
+
+// GetRecentBlocks 获取代理最近的拦截记录
+func (s *ProxyAccessService) GetRecentBlocks(proxyID uint, limit int) ([]model.ProxyAccessBlock, error) {
+	proxy, err := s.proxyRepo.FindByID(proxyID)
+	if err != nil {
+		return nil, fmt.Errorf("代理不存在")
+	}
+	client, err := s.clientRepo.FindByID(proxy.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("客户端不存在")
+	}
+	if client.FrpServerID == nil {
+		return []model.ProxyAccessBlock{}, nil
+	}
+
+	s.Flush()
+	return s.accessRepo.FindRecentBlocks(*client.FrpServerID, []string{client.Name + "." + proxy.Name}, limit)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompiledAccessPolicy_Evaluate 测试黑白名单及地区规则判定
+func TestCompiledAccessPolicy_Evaluate(t *testing.T) {
+	policy, err := compileAccessPolicy(&model.ProxyAccessPolicy{
+		AllowCIDRs:   "203.0.113.0/24\n2001:db8::1",
+		DenyCIDRs:    "203.0.113.66",
+		AllowRegions: "广东省",
+		DenyRegions:  "电信",
+	})
+	require.NoError(t, err)
+
+	location := func(loc string) func() string { return func() string { return loc } }
+	assert.Empty(t, policy.evaluate(net.ParseIP("203.0.113.5"), location("美国")))
+	assert.Empty(t, policy.evaluate(net.ParseIP("2001:db8::1"), location("")))
+	assert.Contains(t, policy.evaluate(net.ParseIP("203.0.113.66"), location("")), "IP黑名单", "黑名单优先于白名单")
+	assert.Empty(t, policy.evaluate(net.ParseIP("198.51.100.1"), location("中国 广东省 深圳市 联通")))
+	assert.Contains(t, policy.evaluate(net.ParseIP("198.51.100.1"), location("中国 广东省 深圳市 电信")), "地区黑名单")
+	assert.Equal(t, "不在白名单中", policy.evaluate(net.ParseIP("198.51.100.1"), location("中国 北京市 联通")))
+
+	open, err := compileAccessPolicy(&model.ProxyAccessPolicy{DenyCIDRs: "10.0.0.0/8"})
+	require.NoError(t, err)
+	assert.Empty(t, open.evaluate(net.ParseIP("198.51.100.1"), location("")), "未配置白名单时默认放行")
+
+	_, err = compileAccessPolicy(&model.ProxyAccessPolicy{AllowCIDRs: "10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = compileAccessPolicy(&model.ProxyAccessPolicy{DenyCIDRs: "not-an-ip"})
+	assert.Error(t, err)
+}
+
+// TestProxyAccessService_AuthorizeAndAlert 测试插件拦截、拦截记录聚合及频繁拦截告警
+func TestProxyAccessService_AuthorizeAndAlert(t *testing.T) {
+	setupVisitorTestDB(t)
+	require.NoError(t, database.DB.AutoMigrate(&model.AlertRule{}, &model.AlertLog{}, &model.OperationLog{}))
+	serverID := uint(1)
+	client := &model.Client{Name: "c", FrpServerID: &serverID}
+	require.NoError(t, database.DB.Create(client).Error)
+	proxy := &model.Proxy{ClientID: client.ID, Name: "rdp", Type: "tcp", LocalPort: 3389, RemotePort: 6000, Enabled: true}
+	require.NoError(t, database.DB.Create(proxy).Error)
+	require.NoError(t, database.DB.Create(&model.AlertRule{
+		TargetType: model.AlertTargetSystem, RuleType: model.RuleTypeAccessBlocked, ThresholdValue: 3, Enabled: true,
+	}).Error)
+
+	accessService := NewProxyAccessService()
+	accessService.SetEventNotifier(NewSystemEventNotifier(repository.NewAlertRepo(database.DB)))
+	visitorService := NewProxyVisitorService()
+	plugin := NewFrpsPluginService(visitorService, accessService)
+
+	saved, err := accessService.SavePolicy(&model.ProxyAccessPolicy{ProxyID: proxy.ID, AllowCIDRs: "10.0.0.0/8", Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, "c.rdp", saved.ProxyName)
+	_, err = accessService.SavePolicy(&model.ProxyAccessPolicy{ProxyID: proxy.ID, AllowCIDRs: "10.0.0.0/8, 192.168.0.0/16", Enabled: true})
+	require.NoError(t, err, "重复保存更新已有策略")
+	_, err = accessService.SavePolicy(&model.ProxyAccessPolicy{ProxyID: 999, Enabled: true})
+	assert.Error(t, err)
+
+	userConn := func(addr string) *model.FrpsPluginResponse {
+		return plugin.Handle(1, pluginRequest(t, FrpsPluginOpNewUserConn, map[string]interface{}{
+			"user": map[string]string{"user": "c"}, "proxy_name": "c.rdp", "proxy_type": "tcp", "remote_addr": addr,
+		}))
+	}
+	assert.False(t, userConn("10.1.2.3:5000").Reject)
+	assert.False(t, userConn("192.168.1.1:5000").Reject)
+	resp := userConn("198.51.100.7:5000")
+	assert.True(t, resp.Reject)
+	assert.Equal(t, "不在白名单中", resp.RejectReason)
+
+	accessService.Flush()
+	var alerts int64
+	database.DB.Model(&model.AlertLog{}).Count(&alerts)
+	assert.Zero(t, alerts, "未达到阈值不告警")
+
+	userConn("198.51.100.7:5001")
+	userConn("198.51.100.8:5000")
+	blocks, err := accessService.GetRecentBlocks(proxy.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	counts := map[string]int64{}
+	for _, b := range blocks {
+		counts[b.IP] = b.BlockCount
+	}
+	assert.Equal(t, map[string]int64{"198.51.100.7": 2, "198.51.100.8": 1}, counts)
+
+	database.DB.Model(&model.AlertLog{}).Where("alert_type = ?", model.RuleTypeAccessBlocked).Count(&alerts)
+	assert.Equal(t, int64(1), alerts, "窗口内拦截次数达到阈值后告警")
+
+	visitors, err := visitorService.GetRecentVisitors(proxy.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, visitors, 2, "被拦截的连接不计入访客")
+
+	require.NoError(t, accessService.DeletePolicy(proxy.ID))
+	assert.False(t, userConn("198.51.100.7:5002").Reject, "删除策略后放行")
+	policy, err := accessService.GetPolicy(proxy.ID)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+}
+
+// TestProxyAccessService_CounterWindow 测试拦截计数窗口过期后重新计数
+func TestProxyAccessService_CounterWindow(t *testing.T) {
+	setupVisitorTestDB(t)
+	svc := NewProxyAccessService()
+	now := time.Now()
+	svc.recordBlock(1, "c.rdp", "198.51.100.1", "", "不在白名单中", now)
+	svc.recordBlock(1, "c.rdp", "198.51.100.1", "", "不在白名单中", now.Add(time.Minute))
+	assert.Equal(t, int64(2), svc.counters[accessProxyKey{1, "c.rdp"}].count)
+
+	svc.recordBlock(1, "c.rdp", "198.51.100.1", "", "不在白名单中", now.Add(accessBlockWindow))
+	assert.Equal(t, int64(1), svc.counters[accessProxyKey{1, "c.rdp"}].count)
+
+	svc.flushAt(now.Add(accessBlockWindow))
+	svc.flushAt(now.Add(3 * accessBlockWindow))
+	assert.Empty(t, svc.counters, "过期且无新拦截的窗口被清理")
+}
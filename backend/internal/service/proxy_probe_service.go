@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProbeInterval   = 60 // 秒
+	minProbeInterval       = 10 // 秒
+	defaultProbeTimeout    = 10 // 秒
+	maxProbeTimeout        = 60 // 秒
+	defaultProbeRetention  = 30 // 天
+	probeConcurrency       = 10
+	probeMaxBodyBytes      = 1 << 20
+	defaultProbeResultSize = 1000
+)
+
+// probeUptimeWindows 可用率统计的时间范围（小时）：24小时、7天、30天
+var probeUptimeWindows = []int{24, 24 * 7, 24 * 30}
+
+// ProxyProbeService 代理外部拨测服务
+// 面板按配置的间隔从外部连接代理暴露的端口或域名，记录耗时和成功率，
+// 最近一次结果供 probe 类型告警规则判断是否离线
+type ProxyProbeService struct {
+	probeRepo     *repository.ProxyProbeRepository
+	proxyRepo     *repository.ProxyRepository
+	clientRepo    *repository.ClientRepository
+	frpServerRepo *repository.FrpServerRepository
+	settingRepo   *repository.SettingRepository
+	rootCAs       *x509.CertPool // 为空时使用系统根证书
+}
+
+func NewProxyProbeService() *ProxyProbeService {
+	return &ProxyProbeService{
+		probeRepo:     repository.NewProxyProbeRepository(),
+		proxyRepo:     repository.NewProxyRepository(),
+		clientRepo:    repository.NewClientRepository(),
+		frpServerRepo: repository.NewFrpServerRepository(database.DB),
+		settingRepo:   repository.NewSettingRepository(),
+	}
+}
+
+// GetProbes 获取代理的全部拨测配置
+func (s *ProxyProbeService) GetProbes(proxyID uint) ([]model.ProxyProbe, error) {
+	probes, err := s.probeRepo.FindByProxyID(proxyID)
+	if err != nil {
+		return nil, err
+	}
+	name := s.proxyFullName(proxyID)
+	for i := range probes {
+		probes[i].ProxyName = name
+	}
+	return probes, nil
+}
+
+// GetProbe 获取单个拨测配置
+func (s *ProxyProbeService) GetProbe(id uint) (*model.ProxyProbe, error) {
+	probe, err := s.probeRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("拨测不存在")
+	}
+	probe.ProxyName = s.proxyFullName(probe.ProxyID)
+	return probe, nil
+}
+
+// CreateProbe 创建拨测配置
+func (s *ProxyProbeService) CreateProbe(probe *model.ProxyProbe) error {
+	if err := s.validateProbe(probe); err != nil {
+		return err
+	}
+	probe.ID = 0
+	probe.LastCheckedAt = nil
+	probe.LastSuccess = false
+	probe.LastLatencyMs = 0
+	probe.LastError = ""
+	probe.CertExpiresAt = nil
+	if err := s.probeRepo.Create(probe); err != nil {
+		return err
+	}
+	probe.ProxyName = s.proxyFullName(probe.ProxyID)
+	return nil
+}
+
+// UpdateProbe 更新拨测配置，所属代理和最近拨测状态不可修改
+func (s *ProxyProbeService) UpdateProbe(probe *model.ProxyProbe) (*model.ProxyProbe, error) {
+	existing, err := s.probeRepo.FindByID(probe.ID)
+	if err != nil {
+		return nil, fmt.Errorf("拨测不存在")
+	}
+	probe.ProxyID = existing.ProxyID
+	if err := s.validateProbe(probe); err != nil {
+		return nil, err
+	}
+
+	existing.Type = probe.Type
+	existing.Target = probe.Target
+	existing.ExpectedStatus = probe.ExpectedStatus
+	existing.Keyword = probe.Keyword
+	existing.CertExpiryDays = probe.CertExpiryDays
+	existing.IntervalSeconds = probe.IntervalSeconds
+	existing.TimeoutSeconds = probe.TimeoutSeconds
+	existing.Enabled = probe.Enabled
+	if err := s.probeRepo.Update(existing); err != nil {
+		return nil, err
+	}
+	existing.ProxyName = s.proxyFullName(existing.ProxyID)
+	return existing, nil
+}
+
+// DeleteProbe 删除拨测配置及历史结果
+func (s *ProxyProbeService) DeleteProbe(id uint) error {
+	if _, err := s.probeRepo.FindByID(id); err != nil {
+		return fmt.Errorf("拨测不存在")
+	}
+	return s.probeRepo.Delete(id)
+}
+
+// validateProbe 校验拨测参数并填充默认值，同时确认能推导出拨测目标
+func (s *ProxyProbeService) validateProbe(probe *model.ProxyProbe) error {
+	switch probe.Type {
+	case model.ProbeTypeTCP, model.ProbeTypeHTTP, model.ProbeTypeTLS:
+	default:
+		return fmt.Errorf("不支持的拨测类型: %s", probe.Type)
+	}
+	if probe.IntervalSeconds == 0 {
+		probe.IntervalSeconds = defaultProbeInterval
+	}
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = defaultProbeTimeout
+	}
+	if probe.IntervalSeconds < minProbeInterval {
+		return fmt.Errorf("拨测间隔不能小于 %d 秒", minProbeInterval)
+	}
+	if probe.TimeoutSeconds < 1 || probe.TimeoutSeconds > maxProbeTimeout {
+		return fmt.Errorf("拨测超时需在 1-%d 秒之间", maxProbeTimeout)
+	}
+	if probe.TimeoutSeconds >= probe.IntervalSeconds {
+		return fmt.Errorf("拨测超时需小于拨测间隔")
+	}
+	if probe.ExpectedStatus != 0 && (probe.ExpectedStatus < 100 || probe.ExpectedStatus > 599) {
+		return fmt.Errorf("期望状态码无效: %d", probe.ExpectedStatus)
+	}
+	if probe.CertExpiryDays < 0 {
+		return fmt.Errorf("证书剩余天数不能为负数")
+	}
+	probe.Target = strings.TrimSpace(probe.Target)
+
+	proxy, err := s.proxyRepo.FindByID(probe.ProxyID)
+	if err != nil {
+		return fmt.Errorf("代理不存在")
+	}
+	_, err = s.resolveTarget(probe, proxy)
+	return err
+}
+
+// resolveTarget 推导拨测目标：TCP 为 host:port，HTTP 为 URL，TLS 为 host:port
+func (s *ProxyProbeService) resolveTarget(probe *model.ProxyProbe, proxy *model.Proxy) (string, error) {
+	switch probe.Type {
+	case model.ProbeTypeTCP:
+		if probe.Target != "" {
+			if _, _, err := net.SplitHostPort(probe.Target); err != nil {
+				return "", fmt.Errorf("TCP 拨测目标需为 host:port 格式")
+			}
+			return probe.Target, nil
+		}
+		if proxy.RemotePort <= 0 {
+			return "", fmt.Errorf("代理未配置远程端口，请填写拨测目标")
+		}
+		host := s.serverHost(proxy)
+		if host == "" {
+			return "", fmt.Errorf("无法确定服务器地址，请填写拨测目标")
+		}
+		return net.JoinHostPort(host, strconv.Itoa(proxy.RemotePort)), nil
+
+	case model.ProbeTypeHTTP:
+		if probe.Target != "" {
+			if !strings.HasPrefix(probe.Target, "http://") && !strings.HasPrefix(probe.Target, "https://") {
+				return "", fmt.Errorf("HTTP 拨测目标需以 http:// 或 https:// 开头")
+			}
+			return probe.Target, nil
+		}
+		domain := firstDomain(proxy.CustomDomains)
+		if domain == "" {
+			return "", fmt.Errorf("代理未配置自定义域名，请填写拨测目标")
+		}
+		scheme := "http"
+		if proxy.Type == "https" {
+			scheme = "https"
+		}
+		return scheme + "://" + domain + "/", nil
+
+	default: // model.ProbeTypeTLS
+		target := probe.Target
+		if target == "" {
+			target = firstDomain(proxy.CustomDomains)
+			if target == "" {
+				return "", fmt.Errorf("代理未配置自定义域名，请填写拨测目标")
+			}
+		}
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			target = net.JoinHostPort(target, "443")
+		}
+		return target, nil
+	}
+}
+
+// serverHost 获取代理所属客户端连接的 frps 地址
+func (s *ProxyProbeService) serverHost(proxy *model.Proxy) string {
+	client, err := s.clientRepo.FindByID(proxy.ClientID)
+	if err != nil {
+		return ""
+	}
+	if client.FrpServerID != nil {
+		if server, err := s.frpServerRepo.GetByID(*client.FrpServerID); err == nil && server.Host != "" {
+			return server.Host
+		}
+	}
+	return client.ServerAddr
+}
+
+func firstDomain(domains string) string {
+	for _, d := range strings.Split(domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			return d
+		}
+	}
+	return ""
+}
+
+// RunDue 执行到期的拨测，供定时任务调用
+func (s *ProxyProbeService) RunDue() {
+	s.runDueAt(time.Now())
+}
+
+func (s *ProxyProbeService) runDueAt(now time.Time) {
+	probes, err := s.probeRepo.FindEnabled()
+	if err != nil {
+		logger.Errorf("[拨测] 获取拨测配置失败: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for i := range probes {
+		probe := &probes[i]
+		if probe.LastCheckedAt != nil && now.Sub(*probe.LastCheckedAt) < time.Duration(probe.IntervalSeconds)*time.Second {
+			continue
+		}
+		proxy, err := s.proxyRepo.FindByID(probe.ProxyID)
+		if err != nil || !proxy.Enabled {
+			continue // 代理已删除或已禁用时不拨测
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.execute(probe, proxy)
+		}()
+	}
+	wg.Wait()
+}
+
+// RunNow 立即执行一次拨测并返回结果
+func (s *ProxyProbeService) RunNow(id uint) (*model.ProxyProbeResult, error) {
+	probe, err := s.probeRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("拨测不存在")
+	}
+	proxy, err := s.proxyRepo.FindByID(probe.ProxyID)
+	if err != nil {
+		return nil, fmt.Errorf("代理不存在")
+	}
+	return s.execute(probe, proxy), nil
+}
+
+// execute 执行一次拨测并保存结果
+func (s *ProxyProbeService) execute(probe *model.ProxyProbe, proxy *model.Proxy) *model.ProxyProbeResult {
+	start := time.Now()
+	result := &model.ProxyProbeResult{ProbeID: probe.ID, CheckedAt: start}
+
+	var certExpiresAt *time.Time
+	target, err := s.resolveTarget(probe, proxy)
+	if err == nil {
+		timeout := time.Duration(probe.TimeoutSeconds) * time.Second
+		switch probe.Type {
+		case model.ProbeTypeTCP:
+			err = probeTCP(target, timeout)
+		case model.ProbeTypeHTTP:
+			result.StatusCode, err = s.probeHTTP(probe, target, timeout)
+		case model.ProbeTypeTLS:
+			certExpiresAt, err = s.probeTLS(probe, target, timeout, start)
+		}
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	probe.LastCheckedAt = &start
+	probe.LastSuccess = result.Success
+	probe.LastLatencyMs = result.LatencyMs
+	probe.LastError = result.Error
+	if certExpiresAt != nil {
+		probe.CertExpiresAt = certExpiresAt
+	}
+	if err := s.probeRepo.SaveResult(probe, result); err != nil {
+		logger.Errorf("[拨测] 保存拨测结果失败: probe=%d, %v", probe.ID, err)
+	}
+	if !result.Success {
+		logger.Debugf("[拨测] 拨测失败: probe=%d, target=%s, %s", probe.ID, target, result.Error)
+	}
+	return result
+}
+
+func probeTCP(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("连接失败: %v", err)
+	}
+	return conn.Close()
+}
+
+// probeHTTP 发起 GET 请求并校验状态码和关键字，不跟随重定向
+func (s *ProxyProbeService) probeHTTP(probe *model.ProxyProbe, url string, timeout time.Duration) (int, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: s.rootCAs},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("请求地址无效: %v", err)
+	}
+	req.Header.Set("User-Agent", "frp-web-panel-probe")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if probe.ExpectedStatus != 0 {
+		if resp.StatusCode != probe.ExpectedStatus {
+			return resp.StatusCode, fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, probe.ExpectedStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+
+	if probe.Keyword != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, probeMaxBodyBytes))
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("读取响应失败: %v", err)
+		}
+		if !strings.Contains(string(body), probe.Keyword) {
+			return resp.StatusCode, fmt.Errorf("响应中未找到关键字 %q", probe.Keyword)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// probeTLS 完成 TLS 握手并检查证书有效期，返回证书过期时间
+func (s *ProxyProbeService) probeTLS(probe *model.ProxyProbe, addr string, timeout time.Duration, now time.Time) (*time.Time, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	dialer := &net.Dialer{Timeout: timeout}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, RootCAs: s.rootCAs}}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return nil, fmt.Errorf("证书校验失败: %v", certErr.Err)
+		}
+		return nil, fmt.Errorf("TLS 握手失败: %v", err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("服务端未返回证书")
+	}
+	expiresAt := certs[0].NotAfter
+	if !expiresAt.After(now) {
+		return &expiresAt, fmt.Errorf("证书已于 %s 过期", expiresAt.Format("2006-01-02 15:04:05"))
+	}
+	if probe.CertExpiryDays > 0 && expiresAt.Before(now.AddDate(0, 0, probe.CertExpiryDays)) {
+		return &expiresAt, fmt.Errorf("证书将于 %s 过期，剩余不足 %d 天", expiresAt.Format("2006-01-02 15:04:05"), probe.CertExpiryDays)
+	}
+	return &expiresAt, nil
+}
+
+// GetResults 获取最近 hours 小时的拨测结果
+func (s *ProxyProbeService) GetResults(id uint, hours int) ([]model.ProxyProbeResult, error) {
+	if _, err := s.probeRepo.FindByID(id); err != nil {
+		return nil, fmt.Errorf("拨测不存在")
+	}
+	return s.probeRepo.FindResults(id, time.Now().Add(-time.Duration(hours)*time.Hour), defaultProbeResultSize)
+}
+
+// GetUptime 获取拨测最近 24 小时、7 天、30 天的可用率
+func (s *ProxyProbeService) GetUptime(id uint) ([]model.ProxyProbeUptime, error) {
+	if _, err := s.probeRepo.FindByID(id); err != nil {
+		return nil, fmt.Errorf("拨测不存在")
+	}
+	return s.uptimeAt(id, time.Now())
+}
+
+func (s *ProxyProbeService) uptimeAt(id uint, now time.Time) ([]model.ProxyProbeUptime, error) {
+	uptimes := make([]model.ProxyProbeUptime, 0, len(probeUptimeWindows))
+	for _, hours := range probeUptimeWindows {
+		total, success, avgLatency, err := s.probeRepo.GetUptime(id, now.Add(-time.Duration(hours)*time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		uptime := model.ProxyProbeUptime{Hours: hours, Total: total, Success: success, UptimePct: -1, AvgLatencyMs: avgLatency}
+		if total > 0 {
+			uptime.UptimePct = float64(success) * 100 / float64(total)
+		}
+		uptimes = append(uptimes, uptime)
+	}
+	return uptimes, nil
+}
+
+// Cleanup 清理超过保留天数的拨测结果，供定时任务调用
+func (s *ProxyProbeService) Cleanup() {
+	retention := defaultProbeRetention
+	if val, err := s.settingRepo.GetSetting("proxy_probe_retention_days"); err == nil {
+		if days, err := strconv.Atoi(val); err == nil && days > 0 {
+			retention = days
+		}
+	}
+	if deleted, err := s.probeRepo.DeleteResultsBefore(time.Now().AddDate(0, 0, -retention)); err != nil {
+		logger.Errorf("[拨测] 清理过期拨测结果失败: %v", err)
+	} else if deleted > 0 {
+		logger.Infof("[拨测] 清理 %d 条超过 %d 天的拨测结果", deleted, retention)
+	}
+}
+
+// ProbeDisplayName 告警中使用的拨测名称
+func ProbeDisplayName(probe *model.ProxyProbe, proxyName string) string {
+	return fmt.Sprintf("%s(%s拨测)", proxyName, strings.ToUpper(probe.Type))
+}
+
+func (s *ProxyProbeService) proxyFullName(proxyID uint) string {
+	proxy, err := s.proxyRepo.FindByID(proxyID)
+	if err != nil {
+		return ""
+	}
+	client, err := s.clientRepo.FindByID(proxy.ClientID)
+	if err != nil {
+		return proxy.Name
+	}
+	return client.Name + "." + proxy.Name
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"crypto/x509"
+	"fmt"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupProbeTestDB(t *testing.T) *model.Proxy {
+	newServiceTestDB(t, &model.Setting{}, &model.Client{}, &model.Proxy{}, &model.FrpServer{},
+		&model.ProxyProbe{}, &model.ProxyProbeResult{})
+
+	server := &model.FrpServer{Name: "s1", Host: "127.0.0.1"}
+	require.NoError(t, database.DB.Create(server).Error)
+	client := &model.Client{Name: "c", ServerAddr: "frps.example.com", FrpServerID: &server.ID}
+	require.NoError(t, database.DB.Create(client).Error)
+	proxy := &model.Proxy{ClientID: client.ID, Name: "web", Type: "http", LocalPort: 80, RemotePort: 6000,
+		CustomDomains: "a.example.com, b.example.com", Enabled: true}
+	require.NoError(t, database.DB.Create(proxy).Error)
+	return proxy
+}
+
+// TestProxyProbeService_ResolveAndValidate 测试拨测目标推导及参数校验
+func TestProxyProbeService_ResolveAndValidate(t *testing.T) {
+	proxy := setupProbeTestDB(t)
+	svc := NewProxyProbeService()
+
+	tests := []struct {
+		name     string
+		probe    model.ProxyProbe
+		expected string
+	}{
+		{"TCP使用服务器地址和远程端口", model.ProxyProbe{Type: model.ProbeTypeTCP}, "127.0.0.1:6000"},
+		{"TCP自定义目标", model.ProxyProbe{Type: model.ProbeTypeTCP, Target: "1.2.3.4:22"}, "1.2.3.4:22"},
+		{"HTTP使用首个自定义域名", model.ProxyProbe{Type: model.ProbeTypeHTTP}, "http://a.example.com/"},
+		{"HTTP自定义URL", model.ProxyProbe{Type: model.ProbeTypeHTTP, Target: "https://x.example.com/health"}, "https://x.example.com/health"},
+		{"TLS默认443端口", model.ProxyProbe{Type: model.ProbeTypeTLS}, "a.example.com:443"},
+		{"TLS自定义端口", model.ProxyProbe{Type: model.ProbeTypeTLS, Target: "x.example.com:8443"}, "x.example.com:8443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := svc.resolveTarget(&tt.probe, proxy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+
+	probe := &model.ProxyProbe{ProxyID: proxy.ID, Type: model.ProbeTypeHTTP, Enabled: true}
+	require.NoError(t, svc.CreateProbe(probe))
+	assert.Equal(t, defaultProbeInterval, probe.IntervalSeconds, "未设置间隔时使用默认值")
+	assert.Equal(t, "c.web", probe.ProxyName)
+
+	invalid := []model.ProxyProbe{
+		{ProxyID: proxy.ID, Type: "icmp"},
+		{ProxyID: proxy.ID, Type: model.ProbeTypeTCP, IntervalSeconds: 5},
+		{ProxyID: proxy.ID, Type: model.ProbeTypeTCP, IntervalSeconds: 30, TimeoutSeconds: 30},
+		{ProxyID: proxy.ID, Type: model.ProbeTypeHTTP, ExpectedStatus: 42},
+		{ProxyID: proxy.ID, Type: model.ProbeTypeHTTP, Target: "a.example.com"},
+		{ProxyID: proxy.ID, Type: model.ProbeTypeTCP, Target: "no-port"},
+		{ProxyID: 999, Type: model.ProbeTypeTCP},
+	}
+	for _, p := range invalid {
+		assert.Error(t, svc.CreateProbe(&p), "%+v", p)
+	}
+}
+
+// TestProxyProbeService_Execute 测试 TCP/HTTP/TLS 拨测执行及结果记录
+func TestProxyProbeService_Execute(t *testing.T) {
+	proxy := setupProbeTestDB(t)
+	svc := NewProxyProbeService()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "status: ok")
+	}))
+	defer httpServer.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer tlsServer.Close()
+	svc.rootCAs = x509.NewCertPool()
+	svc.rootCAs.AddCert(tlsServer.Certificate())
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	run := func(probe model.ProxyProbe) *model.ProxyProbeResult {
+		probe.ProxyID = proxy.ID
+		probe.Enabled = true
+		require.NoError(t, svc.CreateProbe(&probe))
+		result, err := svc.RunNow(probe.ID)
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.True(t, run(model.ProxyProbe{Type: model.ProbeTypeTCP, Target: httpServer.Listener.Addr().String()}).Success)
+	assert.False(t, run(model.ProxyProbe{Type: model.ProbeTypeTCP, Target: closedAddr, TimeoutSeconds: 1}).Success)
+
+	ok := run(model.ProxyProbe{Type: model.ProbeTypeHTTP, Target: httpServer.URL, Keyword: "ok"})
+	assert.True(t, ok.Success)
+	assert.Equal(t, http.StatusOK, ok.StatusCode)
+	down := run(model.ProxyProbe{Type: model.ProbeTypeHTTP, Target: httpServer.URL + "/down"})
+	assert.False(t, down.Success)
+	assert.Equal(t, http.StatusBadGateway, down.StatusCode)
+	assert.True(t, run(model.ProxyProbe{Type: model.ProbeTypeHTTP, Target: httpServer.URL + "/down", ExpectedStatus: 502}).Success)
+	keyword := run(model.ProxyProbe{Type: model.ProbeTypeHTTP, Target: httpServer.URL, Keyword: "healthy"})
+	assert.False(t, keyword.Success)
+	assert.Contains(t, keyword.Error, "关键字")
+
+	tlsAddr := tlsServer.Listener.Addr().String()
+	assert.True(t, run(model.ProxyProbe{Type: model.ProbeTypeTLS, Target: tlsAddr}).Success)
+	expiring := run(model.ProxyProbe{Type: model.ProbeTypeTLS, Target: tlsAddr, CertExpiryDays: 365 * 1000})
+	assert.False(t, expiring.Success)
+	assert.Contains(t, expiring.Error, "剩余不足")
+
+	stored, err := svc.GetProbe(expiring.ProbeID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastCheckedAt)
+	require.NotNil(t, stored.CertExpiresAt)
+	assert.Equal(t, tlsServer.Certificate().NotAfter.Unix(), stored.CertExpiresAt.Unix())
+	assert.False(t, stored.LastSuccess)
+	assert.Equal(t, expiring.Error, stored.LastError)
+}
+
+// TestProxyProbeService_RunDueAndUptime 测试按间隔执行拨测及可用率统计
+func TestProxyProbeService_RunDueAndUptime(t *testing.T) {
+	proxy := setupProbeTestDB(t)
+	svc := NewProxyProbeService()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	probe := &model.ProxyProbe{ProxyID: proxy.ID, Type: model.ProbeTypeTCP, Target: listener.Addr().String(), IntervalSeconds: 60, Enabled: true}
+	require.NoError(t, svc.CreateProbe(probe))
+
+	now := time.Now()
+	svc.runDueAt(now)
+	svc.runDueAt(now.Add(30 * time.Second))
+	var count int64
+	database.DB.Model(&model.ProxyProbeResult{}).Count(&count)
+	assert.Equal(t, int64(1), count, "未到间隔不重复拨测")
+
+	svc.runDueAt(time.Now().Add(time.Minute))
+	database.DB.Model(&model.ProxyProbeResult{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	require.NoError(t, database.DB.Model(proxy).Update("enabled", false).Error)
+	svc.runDueAt(time.Now().Add(2 * time.Minute))
+	database.DB.Model(&model.ProxyProbeResult{}).Count(&count)
+	assert.Equal(t, int64(2), count, "代理禁用时不拨测")
+
+	require.NoError(t, database.DB.Create(&[]model.ProxyProbeResult{
+		{ProbeID: probe.ID, Success: false, CheckedAt: now.Add(-time.Hour)},
+		{ProbeID: probe.ID, Success: true, LatencyMs: 40, CheckedAt: now.Add(-48 * time.Hour)},
+		{ProbeID: probe.ID, Success: false, CheckedAt: now.Add(-40 * 24 * time.Hour)},
+	}).Error)
+
+	uptimes, err := svc.uptimeAt(probe.ID, now.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, uptimes, 3)
+	assert.Equal(t, int64(3), uptimes[0].Total)
+	assert.InDelta(t, 66.67, uptimes[0].UptimePct, 0.01)
+	assert.Equal(t, int64(4), uptimes[1].Total)
+	assert.Equal(t, int64(3), uptimes[1].Success)
+	assert.Equal(t, int64(4), uptimes[2].Total, "超出30天的结果不计入")
+
+	results, err := svc.GetResults(probe.ID, 24)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].CheckedAt.Before(results[2].CheckedAt), "按时间升序返回")
+
+	empty, err := svc.uptimeAt(probe.ID+1, now)
+	require.NoError(t, err)
+	assert.Equal(t, float64(-1), empty[0].UptimePct, "无数据时可用率为 -1")
+
+	require.NoError(t, svc.DeleteProbe(probe.ID))
+	database.DB.Model(&model.ProxyProbeResult{}).Count(&count)
+	assert.Zero(t, count, "删除拨测时清理历史结果")
+}
+
+// TestAlertService_CheckProbeAlerts 测试拨测持续失败告警及恢复
+func TestAlertService_CheckProbeAlerts(t *testing.T) {
+	proxy := setupProbeTestDB(t)
+	require.NoError(t, database.DB.AutoMigrate(&model.AlertRule{}, &model.AlertLog{},
+		&model.AlertRecipient{}, &model.AlertRecipientGroup{}, &model.AlertGroupRecipient{}))
+
+	checkedAt := time.Now()
+	probe := &model.ProxyProbe{ProxyID: proxy.ID, Type: model.ProbeTypeHTTP, IntervalSeconds: 60, TimeoutSeconds: 10,
+		Enabled: true, LastCheckedAt: &checkedAt, LastError: "状态码 502"}
+	require.NoError(t, database.DB.Create(probe).Error)
+	rule := &model.AlertRule{TargetType: model.AlertTargetProbe, TargetID: probe.ID, RuleType: "offline", OfflineDelaySeconds: 60, Enabled: true}
+	require.NoError(t, database.DB.Create(rule).Error)
+
+	alertService := NewAlertService(repository.NewAlertRepo(database.DB), nil, repository.NewProxyRepository())
+	alertService.SetProbeRepo(repository.NewProxyProbeRepository())
+
+	alertService.checkProbeAlerts()
+	_, pending := alertService.pendingOffline[fmt.Sprintf("probe:%d", probe.ID)]
+	assert.True(t, pending, "首次失败进入延迟确认")
+
+	alertService.pendingOffline[fmt.Sprintf("probe:%d", probe.ID)] = time.Now().Add(-2 * time.Minute)
+	alertService.checkProbeAlerts()
+	var logs []model.AlertLog
+	require.NoError(t, database.DB.Find(&logs).Error)
+	require.Len(t, logs, 1)
+	assert.Equal(t, model.AlertTargetProbe, logs[0].TargetType)
+	assert.Contains(t, logs[0].Message, "web(HTTP拨测)")
+	assert.Contains(t, logs[0].Message, "状态码 502")
+
+	require.NoError(t, database.DB.Model(probe).Update("last_success", true).Error)
+	alertService.checkProbeAlerts()
+	assert.False(t, alertService.alertingState[fmt.Sprintf("probe:%d", probe.ID)], "拨测成功后清除告警状态")
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/util"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// visitorBufferLimit 内存中最多缓存的访客条目，超出后丢弃新来源，避免被扫描流量撑爆内存
+	visitorBufferLimit        = 10000
+	defaultVisitorRetention   = 30
+	defaultVisitorMaxPerProxy = 500
+)
+
+type visitorKey struct {
+	serverID  uint
+	proxyName string
+	ip        string
+}
+
+type visitorStat struct {
+	count     int64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// ProxyVisitorService 记录代理来源 IP，连接事件先在内存中聚合，由定时任务批量写入数据库
+type ProxyVisitorService struct {
+	visitorRepo *repository.ProxyVisitorRepository
+	proxyRepo   *repository.ProxyRepository
+	clientRepo  *repository.ClientRepository
+	settingRepo *repository.SettingRepository
+
+	mu        sync.Mutex
+	buffer    map[visitorKey]*visitorStat
+	dropped   int64
+	flushMu   sync.Mutex // 串行化写库，同时保护 locations
+	locations map[string]string
+}
+
+func NewProxyVisitorService() *ProxyVisitorService {
+	return &ProxyVisitorService{
+		visitorRepo: repository.NewProxyVisitorRepository(),
+		proxyRepo:   repository.NewProxyRepository(),
+		clientRepo:  repository.NewClientRepository(),
+		settingRepo: repository.NewSettingRepository(),
+		buffer:      make(map[visitorKey]*visitorStat),
+		locations:   make(map[string]string),
+	}
+}
+
+// Record 记录一次用户连接，remoteAddr 为 host:port 格式
+func (s *ProxyVisitorService) Record(serverID uint, proxyName, remoteAddr string, at time.Time) {
+	ip := remoteIP(remoteAddr)
+	if ip == "" || proxyName == "" {
+		return
+	}
+
+	key := visitorKey{serverID: serverID, proxyName: proxyName, ip: ip}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stat, ok := s.buffer[key]; ok {
+		stat.count++
+		stat.lastSeen = at
+		return
+	}
+	if len(s.buffer) >= visitorBufferLimit {
+		s.dropped++
+		return
+	}
+	s.buffer[key] = &visitorStat{count: 1, firstSeen: at, lastSeen: at}
+}
+
+// remoteIP 从 host:port 格式的地址中取出 IP
+func remoteIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// Flush 将内存中聚合的访客写入数据库，供定时任务调用
+func (s *ProxyVisitorService) Flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	buffer, dropped := s.buffer, s.dropped
+	s.buffer = make(map[visitorKey]*visitorStat)
+	s.dropped = 0
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		logger.Warnf("[访客记录] 缓存已满，丢弃 %d 次连接记录", dropped)
+	}
+	if len(buffer) == 0 {
+		return
+	}
+
+	visitors := make([]model.ProxyVisitor, 0, len(buffer))
+	for key, stat := range buffer {
+		visitors = append(visitors, model.ProxyVisitor{
+			ServerID:  key.serverID,
+			ProxyName: key.proxyName,
+			IP:        key.ip,
+			Location:  s.lookupLocation(key.ip),
+			ConnCount: stat.count,
+			FirstSeen: stat.firstSeen,
+			LastSeen:  stat.lastSeen,
+		})
+	}
+	if err := s.visitorRepo.Upsert(visitors); err != nil {
+		logger.Errorf("[访客记录] 写入 %d 条访客记录失败: %v", len(visitors), err)
+	}
+}
+
+// lookupLocation 查询 IP 归属地，结果在进程内缓存
+func (s *ProxyVisitorService) lookupLocation(ip string) string {
+	if location, ok := s.locations[ip]; ok {
+		return location
+	}
+	location := util.GetIPLocation(ip)
+	if len(s.locations) >= visitorBufferLimit {
+		s.locations = make(map[string]string)
+	}
+	s.locations[ip] = location
+	return location
+}
+
+// Cleanup 按保留天数和单代理数量上限清理访客记录，供定时任务调用
+func (s *ProxyVisitorService) Cleanup() {
+	s.cleanupAt(time.Now())
+}
+
+func (s *ProxyVisitorService) cleanupAt(now time.Time) {
+	retention := s.intSetting("proxy_visitor_retention_days", defaultVisitorRetention)
+	if deleted, err := s.visitorRepo.DeleteBefore(now.AddDate(0, 0, -retention)); err != nil {
+		logger.Errorf("[访客记录] 清理过期记录失败: %v", err)
+	} else if deleted > 0 {
+		logger.Infof("[访客记录] 清理 %d 条超过 %d 天的记录", deleted, retention)
+	}
+
+	maxPerProxy := s.intSetting("proxy_visitor_max_per_proxy", defaultVisitorMaxPerProxy)
+	if deleted, err := s.visitorRepo.TrimPerProxy(maxPerProxy); err != nil {
+		logger.Errorf("[访客记录] 清理超出数量上限的记录失败: %v", err)
+	} else if deleted > 0 {
+		logger.Infof("[访客记录] 清理 %d 条超出单代理上限的记录", deleted)
+	}
+}
+
+func (s *ProxyVisitorService) intSetting(key string, defaultValue int) int {
+	if val, err := s.settingRepo.GetSetting(key); err == nil {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// GetRecentVisitors 获取代理最近 hours 小时内的访客
+func (s *ProxyVisitorService) GetRecentVisitors(proxyID uint, hours, limit int) ([]model.ProxyVisitor, error) {
+	proxy, err := s.proxyRepo.FindByID(proxyID)
+	if err != nil {
+		return nil, fmt.Errorf("代理不存在")
+	}
+	client, err := s.clientRepo.FindByID(proxy.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("客户端不存在")
+	}
+	if client.FrpServerID == nil {
+		return []model.ProxyVisitor{}, nil
+	}
+
+	// 未刷新的缓存也一并写入，保证查询到最新访客
+	s.Flush()
+
+	// 兼容未设置 user 前缀的旧版客户端
+	names := []string{client.Name + "." + proxy.Name, proxy.Name}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	return s.visitorRepo.FindRecent(*client.FrpServerID, names, since, limit)
+}
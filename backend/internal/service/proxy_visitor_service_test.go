@@ -0,0 +1,166 @@
+package service
+
+import (
+	"encoding/json"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupVisitorTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.Client{}, &model.Proxy{}, &model.ProxyVisitor{},
+		&model.ProxyAccessPolicy{}, &model.ProxyAccessBlock{})
+}
+
+// TestProxyVisitorService_RecordAndFlush 测试访客聚合写入及重复刷新累加
+func TestProxyVisitorService_RecordAndFlush(t *testing.T) {
+	setupVisitorTestDB(t)
+	serverID := uint(1)
+	client := &model.Client{Name: "c", FrpServerID: &serverID}
+	require.NoError(t, database.DB.Create(client).Error)
+	proxy := &model.Proxy{ClientID: client.ID, Name: "ssh", Type: "tcp", LocalPort: 22}
+	require.NoError(t, database.DB.Create(proxy).Error)
+
+	svc := NewProxyVisitorService()
+	now := time.Now()
+	svc.Record(1, "c.ssh", "10.0.0.1:5000", now.Add(-time.Minute))
+	svc.Record(1, "c.ssh", "10.0.0.1:5001", now)
+	svc.Record(1, "c.ssh", "[fd00::1]:22", now)
+	svc.Record(1, "c.web", "10.0.0.2:80", now)
+	svc.Record(1, "c.ssh", "", now)
+	svc.Flush()
+
+	svc.Record(1, "c.ssh", "10.0.0.1:5002", now.Add(time.Second))
+	visitors, err := svc.GetRecentVisitors(proxy.ID, 24, 100)
+	require.NoError(t, err)
+	require.Len(t, visitors, 2)
+	assert.Equal(t, "10.0.0.1", visitors[0].IP)
+	assert.Equal(t, int64(3), visitors[0].ConnCount, "重复刷新累加连接次数")
+	assert.WithinDuration(t, now.Add(-time.Minute), visitors[0].FirstSeen, time.Millisecond)
+	assert.WithinDuration(t, now.Add(time.Second), visitors[0].LastSeen, time.Millisecond)
+	assert.Equal(t, "内网IP", visitors[0].Location)
+	assert.Equal(t, "fd00::1", visitors[1].IP)
+}
+
+// TestProxyVisitorService_Cleanup 测试按保留天数和单代理上限清理
+func TestProxyVisitorService_Cleanup(t *testing.T) {
+	setupVisitorTestDB(t)
+	require.NoError(t, database.DB.Create(&model.Setting{Key: "proxy_visitor_max_per_proxy", Value: "2"}).Error)
+
+	now := time.Now()
+	visitors := []model.ProxyVisitor{
+		{ServerID: 1, ProxyName: "c.ssh", IP: "1.1.1.1", ConnCount: 1, LastSeen: now.AddDate(0, 0, -40)},
+		{ServerID: 1, ProxyName: "c.ssh", IP: "1.1.1.2", ConnCount: 1, LastSeen: now.Add(-3 * time.Hour)},
+		{ServerID: 1, ProxyName: "c.ssh", IP: "1.1.1.3", ConnCount: 1, LastSeen: now.Add(-2 * time.Hour)},
+		{ServerID: 1, ProxyName: "c.ssh", IP: "1.1.1.4", ConnCount: 1, LastSeen: now.Add(-1 * time.Hour)},
+		{ServerID: 1, ProxyName: "c.web", IP: "1.1.1.5", ConnCount: 1, LastSeen: now},
+	}
+	require.NoError(t, database.DB.Create(&visitors).Error)
+
+	NewProxyVisitorService().cleanupAt(now)
+
+	var remaining []model.ProxyVisitor
+	require.NoError(t, database.DB.Order("ip").Find(&remaining).Error)
+	ips := make([]string, 0, len(remaining))
+	for _, v := range remaining {
+		ips = append(ips, v.IP)
+	}
+	assert.Equal(t, []string{"1.1.1.3", "1.1.1.4", "1.1.1.5"}, ips)
+}
+
+// TestFrpsPluginService 测试插件令牌校验、NewUserConn 处理及配置生成
+func TestFrpsPluginService(t *testing.T) {
+	setupVisitorTestDB(t)
+	visitorService := NewProxyVisitorService()
+	svc := NewFrpsPluginService(visitorService, NewProxyAccessService())
+
+	token := frpsPluginToken(1)
+	assert.True(t, svc.VerifyToken(1, token))
+	assert.False(t, svc.VerifyToken(2, token), "令牌与服务器绑定")
+
+	content, _ := json.Marshal(map[string]interface{}{
+		"user":        map[string]string{"user": "c"},
+		"proxy_name":  "c.ssh",
+		"proxy_type":  "tcp",
+		"remote_addr": "10.0.0.9:1234",
+	})
+	resp := svc.Handle(1, &model.FrpsPluginRequest{Version: "0.1.0", Op: FrpsPluginOpNewUserConn, Content: content})
+	assert.False(t, resp.Reject)
+	assert.True(t, resp.Unchange)
+	visitorService.Flush()
+	var count int64
+	database.DB.Model(&model.ProxyVisitor{}).Where("proxy_name = ? AND ip = ?", "c.ssh", "10.0.0.9").Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	assert.Empty(t, FrpsPluginConfig(1, false), "未启用时不生成插件配置")
+	require.NoError(t, database.DB.Create(&model.Setting{Key: "frps_plugin_enabled", Value: "true"}).Error)
+	require.NoError(t, database.DB.Create(&model.Setting{Key: "frps_plugin_url", Value: "https://panel.example.com/frp/"}).Error)
+	cfg := FrpsPluginConfig(1, false)
+	assert.Contains(t, cfg, `addr: "https://panel.example.com"`)
+	assert.Contains(t, cfg, `path: "/frp/api/frps-plugin/1/`+token+`"`)
+	assert.Contains(t, cfg, "      - Login\n      - NewProxy\n")
+	assert.True(t, strings.HasSuffix(cfg, "- NewUserConn\n"))
+}
+
+func pluginRequest(t *testing.T, op string, content interface{}) *model.FrpsPluginRequest {
+	data, err := json.Marshal(content)
+	require.NoError(t, err)
+	return &model.FrpsPluginRequest{Version: "0.1.0", Op: op, Content: data}
+}
+
+// TestFrpsPluginService_Authorize 测试 Login/NewProxy/NewWorkConn 授权校验
+func TestFrpsPluginService_Authorize(t *testing.T) {
+	setupVisitorTestDB(t)
+	require.NoError(t, database.DB.AutoMigrate(&model.OperationLog{}))
+	serverID, otherServerID := uint(1), uint(2)
+	client := &model.Client{Name: "c", FrpServerID: &serverID}
+	require.NoError(t, database.DB.Create(client).Error)
+	require.NoError(t, database.DB.Create(&model.Client{Name: "other", FrpServerID: &otherServerID}).Error)
+	require.NoError(t, database.DB.Create(&model.Client{Name: "revoked", FrpServerID: &serverID, DaemonTokenRevoked: true}).Error)
+	require.NoError(t, database.DB.Create(&model.Client{Name: "unbound"}).Error)
+	proxies := []model.Proxy{
+		{ClientID: client.ID, Name: "ssh", Type: "tcp", LocalPort: 22, RemotePort: 6000, Enabled: true},
+		{ClientID: client.ID, Name: "web", Type: "http", LocalPort: 80, CustomDomains: "a.example.com", Enabled: true},
+		{ClientID: client.ID, Name: "off", Type: "tcp", LocalPort: 23, RemotePort: 6001, Enabled: true},
+	}
+	require.NoError(t, database.DB.Create(&proxies).Error)
+	require.NoError(t, database.DB.Model(&proxies[2]).Update("enabled", false).Error)
+
+	svc := NewFrpsPluginService(NewProxyVisitorService(), NewProxyAccessService())
+
+	login := func(user string) bool {
+		return svc.Handle(1, pluginRequest(t, FrpsPluginOpLogin, map[string]interface{}{"user": user})).Reject
+	}
+	assert.False(t, login("c"))
+	assert.True(t, login(""), "未设置 user")
+	assert.True(t, login("unknown"), "未登记客户端")
+	assert.True(t, login("other"), "属于其他服务器")
+	assert.True(t, login("revoked"), "凭证已吊销")
+	assert.True(t, login("unbound"), "未绑定服务器")
+
+	newProxy := func(content map[string]interface{}) *model.FrpsPluginResponse {
+		content["user"] = map[string]string{"user": "c"}
+		return svc.Handle(1, pluginRequest(t, FrpsPluginOpNewProxy, content))
+	}
+	assert.False(t, newProxy(map[string]interface{}{"proxy_name": "ssh", "proxy_type": "tcp", "remote_port": 6000}).Reject)
+	assert.False(t, newProxy(map[string]interface{}{"proxy_name": "c.ssh", "proxy_type": "tcp", "remote_port": 6000}).Reject, "兼容带 user 前缀的代理名")
+	assert.True(t, newProxy(map[string]interface{}{"proxy_name": "ssh", "proxy_type": "tcp", "remote_port": 7000}).Reject, "端口不一致")
+	assert.True(t, newProxy(map[string]interface{}{"proxy_name": "ssh", "proxy_type": "udp", "remote_port": 6000}).Reject, "类型不一致")
+	assert.True(t, newProxy(map[string]interface{}{"proxy_name": "off", "proxy_type": "tcp", "remote_port": 6001}).Reject, "已禁用")
+	assert.True(t, newProxy(map[string]interface{}{"proxy_name": "rogue", "proxy_type": "tcp", "remote_port": 6002}).Reject, "未登记代理")
+	assert.False(t, newProxy(map[string]interface{}{"proxy_name": "web", "proxy_type": "http", "custom_domains": []string{"A.example.com"}}).Reject)
+	resp := newProxy(map[string]interface{}{"proxy_name": "web", "proxy_type": "http", "custom_domains": []string{"a.example.com", "evil.example.com"}})
+	assert.True(t, resp.Reject, "域名不一致")
+	assert.Contains(t, resp.RejectReason, "自定义域名不一致")
+
+	workConn := svc.Handle(1, pluginRequest(t, FrpsPluginOpNewWorkConn, map[string]interface{}{"user": map[string]string{"user": "unknown"}}))
+	assert.True(t, workConn.Reject)
+
+	invalid := svc.Handle(1, &model.FrpsPluginRequest{Op: FrpsPluginOpLogin, Content: []byte("not json")})
+	assert.True(t, invalid.Reject)
+}
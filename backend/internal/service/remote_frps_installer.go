@@ -250,6 +250,7 @@ log:
   to: "%s/frps.log"
   level: "info"
 `, server.BindPort, server.Token, server.DashboardPort, server.DashboardUser, server.DashboardPwd, installPath)
+	config += FrpsPluginConfig(server.ID, false)
 
 	configPath := fmt.Sprintf("%s/frps.yaml", installPath)
 	cmd := fmt.Sprintf("sudo tee %s > /dev/null << 'EOF'\n%s\nEOF", configPath, config)
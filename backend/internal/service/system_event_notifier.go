@@ -1,29 +1,41 @@
 package service
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
+	"frp-web-panel/internal/events"
 	"frp-web-panel/internal/logger"
 	"frp-web-panel/internal/model"
 	"frp-web-panel/internal/repository"
-	"net/http"
+	"sort"
 	"time"
 )
 
 // SystemEventNotifier 系统事件通知器
 type SystemEventNotifier struct {
-	alertRepo        *repository.AlertRepo
-	emailService     *EmailService
-	recipientService *AlertRecipientService
+	alertRepo           *repository.AlertRepo
+	emailService        *EmailService
+	recipientService    *AlertRecipientService
+	notificationService *NotificationService
+	webhookService      *WebhookDeliveryService
+	silenceService      *AlertSilenceService
+	escalationService   *AlertEscalationService
+	digest              *NotificationDigest
+	templates           *MessageTemplateService
 }
 
 // NewSystemEventNotifier 创建系统事件通知器
 func NewSystemEventNotifier(alertRepo *repository.AlertRepo) *SystemEventNotifier {
 	return &SystemEventNotifier{
-		alertRepo:        alertRepo,
-		emailService:     NewEmailService(),
-		recipientService: NewAlertRecipientService(),
+		alertRepo:           alertRepo,
+		emailService:        NewEmailService(),
+		recipientService:    NewAlertRecipientService(),
+		notificationService: NewNotificationService(),
+		webhookService:      NewWebhookDeliveryService(alertRepo),
+		silenceService:      NewAlertSilenceService(),
+		escalationService:   NewAlertEscalationService(alertRepo),
+		digest:              GetNotificationDigest(),
+		templates:           NewMessageTemplateService(),
 	}
 }
 
@@ -59,6 +71,29 @@ type ConfigEventData struct {
 	Operator string `json:"operator,omitempty"`
 }
 
+// QuotaEventData 流量配额事件数据
+type QuotaEventData struct {
+	QuotaID    uint   `json:"quota_id"`
+	TargetType string `json:"target_type"`
+	TargetID   uint   `json:"target_id"`
+	TargetName string `json:"target_name"`
+	Period     string `json:"period"`
+	Action     string `json:"action"`
+	UsedBytes  int64  `json:"used_bytes"`
+	LimitBytes int64  `json:"limit_bytes"`
+	ProxyIDs   []uint `json:"proxy_ids,omitempty"`
+}
+
+// AccessBlockEventData 访问拦截事件数据
+type AccessBlockEventData struct {
+	ServerID      uint   `json:"server_id"`
+	ProxyName     string `json:"proxy_name"`
+	BlockCount    int64  `json:"block_count"`
+	WindowMinutes int    `json:"window_minutes"`
+	LastIP        string `json:"last_ip"`
+	LastReason    string `json:"last_reason"`
+}
+
 // NotifyCertApply 证书申请事件通知
 func (n *SystemEventNotifier) NotifyCertApply(domain string, certID uint, success bool, errMsg string) {
 	ruleType := model.RuleTypeCertApplySuccess
@@ -140,6 +175,36 @@ func (n *SystemEventNotifier) NotifyConfigChanged(key string, oldValue string, n
 	n.notifySystemEvent(model.RuleTypeConfigChanged, message, eventData)
 }
 
+// NotifyQuotaExceeded 流量配额超出通知
+func (n *SystemEventNotifier) NotifyQuotaExceeded(data QuotaEventData) {
+	action := "已禁用相关代理"
+	if data.Action == model.QuotaActionThrottle {
+		action = "已限制相关代理带宽"
+	}
+	message := fmt.Sprintf("流量配额超出: %s, 已用 %s / 配额 %s, %s",
+		data.TargetName, formatBytes(data.UsedBytes), formatBytes(data.LimitBytes), action)
+	n.notifySystemEvent(model.RuleTypeQuotaExceeded, message, data)
+}
+
+// NotifyQuotaRestored 流量配额恢复通知
+func (n *SystemEventNotifier) NotifyQuotaRestored(data QuotaEventData) {
+	message := fmt.Sprintf("流量配额已恢复: %s, 相关代理已恢复原状态", data.TargetName)
+	n.notifySystemEvent(model.RuleTypeQuotaRestored, message, data)
+}
+
+// NotifyAccessBlocked 代理来源访问被频繁拦截通知，仅触发阈值不高于拦截次数的规则
+func (n *SystemEventNotifier) NotifyAccessBlocked(data AccessBlockEventData) {
+	message := fmt.Sprintf("代理 %s 在 %d 分钟内拦截 %d 次来源访问，最近来源: %s (%s)",
+		data.ProxyName, data.WindowMinutes, data.BlockCount, data.LastIP, data.LastReason)
+	n.notifySystemEventWhere(model.RuleTypeAccessBlocked, message, data, func(rule *model.AlertRule) bool {
+		threshold := rule.ThresholdValue
+		if threshold <= 0 {
+			threshold = defaultAccessBlockThreshold
+		}
+		return data.BlockCount >= threshold
+	})
+}
+
 func isSensitiveKey(key string) bool {
 	sensitiveKeys := []string{"password", "secret", "token", "key"}
 	for _, sk := range sensitiveKeys {
@@ -152,14 +217,36 @@ func isSensitiveKey(key string) bool {
 
 // notifySystemEvent 通用系统事件通知
 func (n *SystemEventNotifier) notifySystemEvent(ruleType string, message string, eventData interface{}) {
+	n.notifySystemEventWhere(ruleType, message, eventData, nil)
+}
+
+// notifySystemEventWhere 系统事件通知，match 不为空时只触发匹配的规则
+// 只有告警级别的事件记为触发中；成功、变更、恢复类事件本身就是结果，记为已恢复，不进入升级流程
+func (n *SystemEventNotifier) notifySystemEventWhere(ruleType string, message string, eventData interface{}, match func(rule *model.AlertRule) bool) {
+	eventDataJSON, _ := json.Marshal(eventData)
+	isAlert := systemNotifyLevel(ruleType) == NotifyLevelAlert
+	level := "info"
+	if isAlert {
+		level = "warning"
+	}
+	events.GetEventBus().PublishSync(events.SystemEvent{
+		EventType: ruleType,
+		Level:     level,
+		Message:   message,
+		Data:      string(eventDataJSON),
+		Timestamp: time.Now(),
+	})
+
 	rules, err := n.alertRepo.GetSystemAlertRulesByRuleType(ruleType)
 	if err != nil || len(rules) == 0 {
 		return
 	}
 
-	eventDataJSON, _ := json.Marshal(eventData)
-
 	for _, rule := range rules {
+		if match != nil && !match(&rule) {
+			continue
+		}
+
 		// 检查冷却时间
 		cooldown := time.Duration(rule.CooldownMinutes) * time.Minute
 		if cooldown > 0 {
@@ -177,27 +264,95 @@ func (n *SystemEventNotifier) notifySystemEvent(ruleType string, message string,
 			AlertType:  ruleType,
 			Message:    message,
 			EventData:  string(eventDataJSON),
+			Silenced:   n.silenceService.MatchSilence(model.AlertTargetSystem, 0, rule.ID, time.Now()) != nil,
+		}
+		if !isAlert {
+			now := time.Now()
+			alert.Status = model.AlertStatusResolved
+			alert.ResolvedAt = &now
 		}
 
 		if err := n.alertRepo.CreateAlert(alert); err != nil {
 			logger.Errorf("系统告警 创建告警日志失败: %v", err)
 			continue
 		}
+		publishAlertLog(alert)
 
-		// 发送通知
-		n.sendNotification(alert, &rule, ruleType)
+		// 静默期内只记录告警，不发送通知也不升级
+		if !alert.Silenced {
+			n.sendNotification(alert, &rule, ruleType)
+			if isAlert {
+				n.escalationService.Start(alert, &rule)
+			}
+		}
 	}
 }
 
+// sendNotification 发送系统告警，邮件和通知渠道按事件类型汇总，如证书批量续签失败时合并为一条消息
 func (n *SystemEventNotifier) sendNotification(alert *model.AlertLog, rule *model.AlertRule, ruleType string) {
-	emails := n.getNotifyEmails(rule)
-	for _, email := range emails {
-		go n.sendSystemAlertEmail(email, alert, ruleType)
-	}
 	if rule.NotifyWebhook != "" {
-		go n.sendSystemAlertWebhook(rule.NotifyWebhook, alert, ruleType)
+		go n.sendSystemAlertWebhook(rule, alert, ruleType)
+	}
+	data := alertTemplateData(ruleType, alert, "")
+	msg := n.templates.Compose(ruleType, alert.CreatedAt, data)
+	notification := &DigestNotification{
+		Group:     DigestGroup{Key: "system:" + ruleType, Name: data.EventName},
+		Message:   msg.Message,
+		Emails:    n.getNotifyEmails(rule),
+		SendEmail: msg.EmailSender(n.emailService),
+	}
+	if n.notificationService != nil {
+		notification.ChannelIDs = n.notificationService.ChannelIDsForRule(rule)
+	}
+	n.digest.Submit(notification)
+	// 配置了 Webhook 时由投递成功后标记已通知
+	if rule.NotifyWebhook == "" {
+		n.alertRepo.MarkAsNotified(alert.ID)
+	}
+}
+
+// systemNotifyLevel 成功类事件按通知发送，配额恢复按恢复发送，其余按告警发送
+func systemNotifyLevel(ruleType string) string {
+	switch ruleType {
+	case model.RuleTypeCertApplySuccess, model.RuleTypeCertRenewSuccess, model.RuleTypeDNSSyncSuccess, model.RuleTypeConfigChanged:
+		return NotifyLevelInfo
+	case model.RuleTypeQuotaRestored:
+		return NotifyLevelRecovery
+	default:
+		return NotifyLevelAlert
+	}
+}
+
+// eventDataFields 将事件详情 JSON 展开为按键名排序的通知字段，忽略空值
+func eventDataFields(eventData string) []NotificationField {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(eventData), &data); err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]NotificationField, 0, len(keys))
+	for _, k := range keys {
+		var value string
+		switch v := data[k].(type) {
+		case nil:
+			continue
+		case string:
+			value = v
+		default:
+			b, _ := json.Marshal(v)
+			value = string(b)
+		}
+		if value == "" {
+			continue
+		}
+		fields = append(fields, NotificationField{Name: k, Value: value})
 	}
-	n.alertRepo.MarkAsNotified(alert.ID)
+	return fields
 }
 
 func (n *SystemEventNotifier) getNotifyEmails(rule *model.AlertRule) []string {
@@ -206,34 +361,16 @@ func (n *SystemEventNotifier) getNotifyEmails(rule *model.AlertRule) []string {
 	return n.recipientService.GetEmailsByRecipientAndGroupIDs(recipientIDs, groupIDs)
 }
 
-func (n *SystemEventNotifier) sendSystemAlertEmail(to string, alert *model.AlertLog, ruleType string) error {
-	subject := fmt.Sprintf("FRP系统告警 - %s", getRuleTypeName(ruleType))
-	html, _, err := GenerateSystemAlertEmail(SystemAlertData{
-		AlertType: getRuleTypeName(ruleType),
-		Message:   alert.Message,
-		EventData: alert.EventData,
-		Time:      alert.CreatedAt,
-	})
-	if err != nil {
-		return err
-	}
-	return n.emailService.SendHTMLEmail(to, subject, html, "text/html")
-}
-
-func (n *SystemEventNotifier) sendSystemAlertWebhook(url string, alert *model.AlertLog, ruleType string) error {
+func (n *SystemEventNotifier) sendSystemAlertWebhook(rule *model.AlertRule, alert *model.AlertLog, ruleType string) {
 	payload := map[string]interface{}{
 		"alert_type": ruleType,
 		"message":    alert.Message,
 		"event_data": alert.EventData,
 		"timestamp":  alert.CreatedAt.Unix(),
 	}
-	jsonData, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
+	if _, err := n.webhookService.Enqueue(rule.NotifyWebhook, rule, alert, ruleType, payload); err != nil {
+		logger.Errorf("系统告警 Webhook 入队失败: %v", err)
 	}
-	defer resp.Body.Close()
-	return nil
 }
 
 func getRuleTypeName(ruleType string) string {
@@ -248,6 +385,9 @@ func getRuleTypeName(ruleType string) string {
 		model.RuleTypeDNSSyncFailed:    "DNS同步失败",
 		model.RuleTypeLoginFailed:      "登录失败",
 		model.RuleTypeConfigChanged:    "配置变更",
+		model.RuleTypeQuotaExceeded:    "流量配额超出",
+		model.RuleTypeQuotaRestored:    "流量配额恢复",
+		model.RuleTypeAccessBlocked:    "访问频繁被拦截",
 	}
 	if name, ok := names[ruleType]; ok {
 		return name
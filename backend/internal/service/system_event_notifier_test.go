@@ -2,9 +2,12 @@ package service
 
 import (
 	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestIsSensitiveKey 测试敏感字段检测
@@ -49,6 +52,7 @@ func TestGetRuleTypeName_SystemEvents(t *testing.T) {
 		{"DNS同步失败", model.RuleTypeDNSSyncFailed, "DNS同步失败"},
 		{"登录失败", model.RuleTypeLoginFailed, "登录失败"},
 		{"配置变更", model.RuleTypeConfigChanged, "配置变更"},
+		{"访问频繁被拦截", model.RuleTypeAccessBlocked, "访问频繁被拦截"},
 		{"未知类型", "unknown_type", "unknown_type"},
 	}
 
@@ -141,3 +145,32 @@ func TestRuleTypeConstants(t *testing.T) {
 	assert.Equal(t, "login_failed", model.RuleTypeLoginFailed)
 	assert.Equal(t, "config_changed", model.RuleTypeConfigChanged)
 }
+
+// TestSystemEventNotifier_EventStatus 测试只有告警级别的系统事件记为触发中，成功和恢复类事件直接记为已恢复
+func TestSystemEventNotifier_EventStatus(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.AlertRule{}, &model.AlertLog{}, &model.AlertSilence{},
+		&model.AlertRecipient{}, &model.AlertRecipientGroup{}, &model.AlertGroupRecipient{}, &model.NotificationChannel{},
+		&model.AlertEscalationPolicy{}, &model.AlertEscalationStep{}, &model.MessageTemplate{})
+	for _, ruleType := range []string{model.RuleTypeCertRenewSuccess, model.RuleTypeCertRenewFailed, model.RuleTypeQuotaRestored} {
+		require.NoError(t, database.DB.Create(&model.AlertRule{TargetType: model.AlertTargetSystem, RuleType: ruleType, Enabled: true}).Error)
+	}
+
+	notifier := NewSystemEventNotifier(repository.NewAlertRepo(database.DB))
+	notifier.NotifyCertRenew("a.example.com", 1, true, "")
+	notifier.NotifyCertRenew("a.example.com", 1, false, "timeout")
+	notifier.NotifyQuotaRestored(QuotaEventData{TargetName: "c"})
+
+	statuses := map[string]model.AlertLog{}
+	var logs []model.AlertLog
+	require.NoError(t, database.DB.Find(&logs).Error)
+	for _, l := range logs {
+		statuses[l.AlertType] = l
+	}
+	require.Len(t, statuses, 3)
+	assert.Equal(t, model.AlertStatusFiring, statuses[model.RuleTypeCertRenewFailed].Status)
+	assert.Nil(t, statuses[model.RuleTypeCertRenewFailed].ResolvedAt)
+	for _, ruleType := range []string{model.RuleTypeCertRenewSuccess, model.RuleTypeQuotaRestored} {
+		assert.Equal(t, model.AlertStatusResolved, statuses[ruleType].Status, ruleType)
+		assert.NotNil(t, statuses[ruleType].ResolvedAt, ruleType)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"frp-web-panel/internal/logger"
 	"sync"
 	"time"
@@ -13,15 +14,74 @@ type Task interface {
 	Name() string
 }
 
+// TaskStatus 任务运行状态，用于健康监控
+type TaskStatus struct {
+	Name         string
+	Periodic     bool
+	Interval     time.Duration
+	Running      bool
+	Runs         int64
+	Failures     int64
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    string
+}
+
+// taskTracker 记录任务的执行情况
+type taskTracker struct {
+	mu     sync.Mutex
+	status TaskStatus
+}
+
+// track 执行一次任务并记录耗时，任务 panic 时记录失败而不影响后续执行
+func (tr *taskTracker) track(fn func()) {
+	start := time.Now()
+	tr.mu.Lock()
+	tr.status.Running = true
+	tr.mu.Unlock()
+
+	var panicErr string
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr = fmt.Sprint(r)
+				logger.Errorf("TaskManager 任务 %s 执行异常: %v", tr.status.Name, r)
+			}
+		}()
+		fn()
+	}()
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.status.Running = false
+	tr.status.Runs++
+	tr.status.LastRun = start
+	tr.status.LastDuration = time.Since(start)
+	tr.status.LastError = panicErr
+	if panicErr != "" {
+		tr.status.Failures++
+	}
+}
+
+// Status 返回任务状态快照
+func (tr *taskTracker) Status() TaskStatus {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.status
+}
+
 // PeriodicTask 定时任务
 type PeriodicTask struct {
+	taskTracker
 	name     string
 	interval time.Duration
 	fn       func()
 }
 
 func NewPeriodicTask(name string, interval time.Duration, fn func()) *PeriodicTask {
-	return &PeriodicTask{name: name, interval: interval, fn: fn}
+	t := &PeriodicTask{name: name, interval: interval, fn: fn}
+	t.status = TaskStatus{Name: name, Periodic: true, Interval: interval}
+	return t
 }
 
 func (t *PeriodicTask) Name() string { return t.name }
@@ -31,12 +91,12 @@ func (t *PeriodicTask) Start(ctx context.Context) {
 	defer ticker.Stop()
 
 	// 立即执行一次
-	t.fn()
+	t.track(t.fn)
 
 	for {
 		select {
 		case <-ticker.C:
-			t.fn()
+			t.track(t.fn)
 		case <-ctx.Done():
 			logger.Infof("TaskManager 定时任务 %s 已停止", t.name)
 			return
@@ -46,18 +106,21 @@ func (t *PeriodicTask) Start(ctx context.Context) {
 
 // OneShotTask 一次性后台任务
 type OneShotTask struct {
+	taskTracker
 	name string
 	fn   func(ctx context.Context)
 }
 
 func NewOneShotTask(name string, fn func(ctx context.Context)) *OneShotTask {
-	return &OneShotTask{name: name, fn: fn}
+	t := &OneShotTask{name: name, fn: fn}
+	t.status = TaskStatus{Name: name}
+	return t
 }
 
 func (t *OneShotTask) Name() string { return t.name }
 
 func (t *OneShotTask) Start(ctx context.Context) {
-	t.fn(ctx)
+	t.track(func() { t.fn(ctx) })
 	logger.Infof("TaskManager 一次性任务 %s 已完成", t.name)
 }
 
@@ -134,6 +197,22 @@ func (m *TaskManager) Shutdown(timeout time.Duration) error {
 	}
 }
 
+// TaskStatuses 返回所有已注册任务的运行状态
+func (m *TaskManager) TaskStatuses() []TaskStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]TaskStatus, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		if reporter, ok := task.(interface{ Status() TaskStatus }); ok {
+			statuses = append(statuses, reporter.Status())
+		} else {
+			statuses = append(statuses, TaskStatus{Name: task.Name()})
+		}
+	}
+	return statuses
+}
+
 // Context 返回任务管理器的 context，供外部服务使用
 func (m *TaskManager) Context() context.Context {
 	return m.ctx
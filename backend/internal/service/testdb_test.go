@@ -0,0 +1,24 @@
+package service
+
+import (
+	"frp-web-panel/pkg/database"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newServiceTestDB 创建内存数据库、迁移指定模型并设置为全局数据库
+// 限制为单个连接，否则每个连接各自打开一个独立的内存数据库，后台 goroutine 会看不到测试写入的数据
+func newServiceTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	database.DB = db
+	return db
+}
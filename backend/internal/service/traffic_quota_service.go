@@ -0,0 +1,616 @@
+package service
+
+import (
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/websocket"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quotaUsageDelay 统计用量时留出的延迟，避免漏掉正在写入的采样
+const quotaUsageDelay = 30 * time.Second
+
+// bandwidthLimitPattern frpc transport.bandwidthLimit 支持的格式
+var bandwidthLimitPattern = regexp.MustCompile(`^[1-9][0-9]*(KB|MB)$`)
+
+// TrafficQuotaService 流量配额服务，按周期累计代理流量，超出配额时禁用或限速代理并在周期重置时恢复
+type TrafficQuotaService struct {
+	mu            sync.Mutex
+	pendingPush   map[uint]bool // 持锁期间需要推送配置的客户端，释放锁后推送
+	quotaRepo     *repository.TrafficQuotaRepository
+	proxyRepo     *repository.ProxyRepository
+	clientRepo    *repository.ClientRepository
+	metricsRepo   *repository.ProxyMetricsRepository
+	proxyService  *ProxyService
+	clientService *ClientService
+	logService    *LogService
+	daemonHub     *websocket.ClientDaemonHub
+	eventNotifier *SystemEventNotifier
+}
+
+func NewTrafficQuotaService(daemonHub *websocket.ClientDaemonHub) *TrafficQuotaService {
+	return &TrafficQuotaService{
+		quotaRepo:     repository.NewTrafficQuotaRepository(),
+		proxyRepo:     repository.NewProxyRepository(),
+		clientRepo:    repository.NewClientRepository(),
+		metricsRepo:   repository.NewProxyMetricsRepository(),
+		proxyService:  NewProxyService(),
+		clientService: NewClientService(),
+		logService:    NewLogService(),
+		daemonHub:     daemonHub,
+		pendingPush:   make(map[uint]bool),
+	}
+}
+
+// SetEventNotifier 设置系统事件通知器
+func (s *TrafficQuotaService) SetEventNotifier(notifier *SystemEventNotifier) {
+	s.eventNotifier = notifier
+}
+
+// quotaPeriodStart 计算 now 所在统计周期的开始时间，按本地时区对齐
+func quotaPeriodStart(quota *model.TrafficQuota, now time.Time) time.Time {
+	if quota.Period == model.QuotaPeriodDaily {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	day := quota.ResetDay
+	if day < 1 || day > 28 {
+		day = 1
+	}
+	start := time.Date(now.Year(), now.Month(), day, 0, 0, 0, 0, now.Location())
+	if now.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+func (s *TrafficQuotaService) validateQuota(quota *model.TrafficQuota) error {
+	switch quota.TargetType {
+	case model.QuotaTargetProxy:
+		if _, err := s.proxyRepo.FindByID(quota.TargetID); err != nil {
+			return fmt.Errorf("代理不存在")
+		}
+	case model.QuotaTargetClient:
+		if _, err := s.clientRepo.FindByID(quota.TargetID); err != nil {
+			return fmt.Errorf("客户端不存在")
+		}
+	default:
+		return fmt.Errorf("不支持的配额目标类型: %s", quota.TargetType)
+	}
+
+	switch quota.Period {
+	case model.QuotaPeriodDaily:
+	case model.QuotaPeriodMonthly:
+		if quota.ResetDay == 0 {
+			quota.ResetDay = 1
+		}
+		if quota.ResetDay < 1 || quota.ResetDay > 28 {
+			return fmt.Errorf("重置日必须在 1-28 之间")
+		}
+	default:
+		return fmt.Errorf("不支持的配额周期: %s", quota.Period)
+	}
+
+	if quota.LimitBytes <= 0 {
+		return fmt.Errorf("配额必须大于 0")
+	}
+
+	switch quota.Action {
+	case "":
+		quota.Action = model.QuotaActionSuspend
+	case model.QuotaActionSuspend:
+	case model.QuotaActionThrottle:
+		if !bandwidthLimitPattern.MatchString(quota.ThrottleLimit) {
+			return fmt.Errorf("限速带宽格式错误，应为如 512KB 或 1MB")
+		}
+	default:
+		return fmt.Errorf("不支持的处理方式: %s", quota.Action)
+	}
+	return nil
+}
+
+// GetAllQuotas 获取所有流量配额
+func (s *TrafficQuotaService) GetAllQuotas() ([]model.TrafficQuota, error) {
+	quotas, err := s.quotaRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range quotas {
+		quotas[i].TargetName = s.targetName(&quotas[i])
+	}
+	return quotas, nil
+}
+
+// CreateQuota 创建流量配额，用量从当前周期开始统计
+func (s *TrafficQuotaService) CreateQuota(quota *model.TrafficQuota) error {
+	if err := s.validateQuota(quota); err != nil {
+		return err
+	}
+	quota.ID = 0
+	quota.PeriodStart = time.Time{}
+	quota.UsedBytes = 0
+	quota.LastCheckedAt = nil
+	quota.Exceeded = false
+	quota.ExceededAt = nil
+
+	s.mu.Lock()
+	defer s.unlockAndPush()
+	if err := s.quotaRepo.Create(quota); err != nil {
+		return err
+	}
+	quota.TargetName = s.targetName(quota)
+	return nil
+}
+
+// UpdateQuota 更新配额设置，目标不可修改；已超出的配额先恢复代理，下次检查时按新设置重新判断
+func (s *TrafficQuotaService) UpdateQuota(quota *model.TrafficQuota) (*model.TrafficQuota, error) {
+	s.mu.Lock()
+	defer s.unlockAndPush()
+
+	existing, err := s.quotaRepo.FindByID(quota.ID)
+	if err != nil {
+		return nil, fmt.Errorf("配额不存在")
+	}
+	quota.TargetType = existing.TargetType
+	quota.TargetID = existing.TargetID
+	if err := s.validateQuota(quota); err != nil {
+		return nil, err
+	}
+
+	if existing.Exceeded {
+		s.restore(existing, time.Now(), "配额设置已修改")
+	}
+	existing.Period = quota.Period
+	existing.LimitBytes = quota.LimitBytes
+	existing.ResetDay = quota.ResetDay
+	existing.Action = quota.Action
+	existing.ThrottleLimit = quota.ThrottleLimit
+	existing.Enabled = quota.Enabled
+	if err := s.quotaRepo.Update(existing); err != nil {
+		return nil, err
+	}
+	existing.TargetName = s.targetName(existing)
+	return existing, nil
+}
+
+// DeleteQuota 删除配额，并恢复被该配额处置的代理
+func (s *TrafficQuotaService) DeleteQuota(id uint) error {
+	s.mu.Lock()
+	defer s.unlockAndPush()
+
+	quota, err := s.quotaRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("配额不存在")
+	}
+	if quota.Exceeded {
+		s.restore(quota, time.Now(), "配额已删除")
+	}
+	return s.quotaRepo.Delete(id)
+}
+
+// ResetQuota 手动清零当前周期用量，并恢复被处置的代理
+func (s *TrafficQuotaService) ResetQuota(id uint) (*model.TrafficQuota, error) {
+	s.mu.Lock()
+	defer s.unlockAndPush()
+
+	quota, err := s.quotaRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("配额不存在")
+	}
+	now := time.Now()
+	if quota.Exceeded {
+		s.restore(quota, now, "用量已手动清零")
+	}
+	checkedAt := now.Add(-quotaUsageDelay)
+	quota.UsedBytes = 0
+	quota.LastCheckedAt = &checkedAt
+	if err := s.quotaRepo.Update(quota); err != nil {
+		return nil, err
+	}
+	quota.TargetName = s.targetName(quota)
+	return quota, nil
+}
+
+// Check 累计各配额用量，处理超出配额和周期重置
+func (s *TrafficQuotaService) Check() {
+	s.checkAt(time.Now())
+}
+
+func (s *TrafficQuotaService) checkAt(now time.Time) {
+	s.mu.Lock()
+	defer s.unlockAndPush()
+
+	quotas, err := s.quotaRepo.FindAll()
+	if err != nil {
+		logger.Errorf("[流量配额] 获取配额列表失败: %v", err)
+		return
+	}
+	for i := range quotas {
+		s.checkQuota(&quotas[i], now)
+	}
+}
+
+func (s *TrafficQuotaService) checkQuota(quota *model.TrafficQuota, now time.Time) {
+	if !quota.Enabled {
+		if quota.Exceeded {
+			s.restore(quota, now, "配额已停用")
+			s.saveQuota(quota)
+		}
+		return
+	}
+
+	periodStart := quotaPeriodStart(quota, now)
+	if !quota.PeriodStart.Equal(periodStart) {
+		if quota.Exceeded {
+			s.restore(quota, now, "统计周期已重置")
+		}
+		quota.PeriodStart = periodStart
+		quota.UsedBytes = 0
+		quota.LastCheckedAt = nil
+	}
+
+	// 增量累计，原始指标过期清理后月度用量仍然准确
+	from := quota.PeriodStart
+	if quota.LastCheckedAt != nil && quota.LastCheckedAt.After(from) {
+		from = *quota.LastCheckedAt
+	}
+	to := now.Add(-quotaUsageDelay)
+	if to.After(from) {
+		used, err := s.measureUsage(quota, from, to)
+		if err != nil {
+			logger.Warnf("[流量配额] 配额 %d 统计用量失败: %v", quota.ID, err)
+			return
+		}
+		quota.UsedBytes += used
+		quota.LastCheckedAt = &to
+	}
+
+	switch {
+	case !quota.Exceeded && quota.UsedBytes >= quota.LimitBytes:
+		s.enforce(quota, now)
+	case quota.Exceeded && quota.UsedBytes < quota.LimitBytes:
+		s.restore(quota, now, "配额已调高")
+	}
+	s.saveQuota(quota)
+}
+
+func (s *TrafficQuotaService) saveQuota(quota *model.TrafficQuota) {
+	if err := s.quotaRepo.Update(quota); err != nil {
+		logger.Errorf("[流量配额] 保存配额 %d 状态失败: %v", quota.ID, err)
+	}
+}
+
+// quotaProxies 获取配额覆盖的代理及其所属客户端
+func (s *TrafficQuotaService) quotaProxies(quota *model.TrafficQuota) ([]model.Proxy, *model.Client, error) {
+	if quota.TargetType == model.QuotaTargetProxy {
+		proxy, err := s.proxyRepo.FindByID(quota.TargetID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("代理不存在: %w", err)
+		}
+		client, err := s.clientRepo.FindByID(proxy.ClientID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("客户端不存在: %w", err)
+		}
+		return []model.Proxy{*proxy}, client, nil
+	}
+
+	client, err := s.clientRepo.FindByID(quota.TargetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("客户端不存在: %w", err)
+	}
+	proxies, err := s.proxyRepo.FindByClientID(client.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proxies, client, nil
+}
+
+// measureUsage 统计配额覆盖的代理在时间范围内的入站+出站流量
+func (s *TrafficQuotaService) measureUsage(quota *model.TrafficQuota, start, end time.Time) (int64, error) {
+	proxies, client, err := s.quotaProxies(quota)
+	if err != nil {
+		return 0, err
+	}
+	if client.FrpServerID == nil {
+		return 0, nil
+	}
+
+	// FRP 使用 clientName.proxyName 格式，短名称兼容旧数据
+	names := make([]string, 0, len(proxies)*2)
+	for _, p := range proxies {
+		names = append(names, client.Name+"."+p.Name, p.Name)
+	}
+	return s.metricsRepo.SumTrafficDelta(*client.FrpServerID, names, start, end)
+}
+
+// enforce 超出配额时禁用或限速代理，并推送配置
+func (s *TrafficQuotaService) enforce(quota *model.TrafficQuota, now time.Time) {
+	proxies, client, err := s.quotaProxies(quota)
+	if err != nil {
+		logger.Warnf("[流量配额] 配额 %d 获取代理失败: %v", quota.ID, err)
+		return
+	}
+
+	var affected []uint
+	for i := range proxies {
+		changed, err := s.applyToProxy(quota, &proxies[i])
+		if err != nil {
+			logger.Errorf("[流量配额] 处置代理 %s 失败: %v", proxies[i].Name, err)
+			continue
+		}
+		if changed {
+			affected = append(affected, proxies[i].ID)
+		}
+	}
+	if len(affected) > 0 {
+		s.pendingPush[client.ID] = true
+	}
+
+	quota.Exceeded = true
+	quota.ExceededAt = &now
+
+	name := s.targetName(quota)
+	logger.Warnf("[流量配额] %s 超出配额: 已用 %d / 配额 %d，处置代理 %d 个", name, quota.UsedBytes, quota.LimitBytes, len(affected))
+	s.logService.CreateLogAsync(0, "quota_exceeded", "traffic_quota", quota.ID,
+		fmt.Sprintf("流量配额超出: %s, 已用 %s / 配额 %s, 处置代理 %d 个",
+			name, formatBytes(quota.UsedBytes), formatBytes(quota.LimitBytes), len(affected)), "127.0.0.1")
+	if s.eventNotifier != nil {
+		s.eventNotifier.NotifyQuotaExceeded(quotaEventData(quota, name, affected))
+	}
+}
+
+// applyToProxy 按配额处理方式处置单个代理，返回代理配置是否发生变化
+func (s *TrafficQuotaService) applyToProxy(quota *model.TrafficQuota, proxy *model.Proxy) (bool, error) {
+	existing, err := s.quotaRepo.FindSuspensionsByProxy(proxy.ID)
+	if err != nil {
+		return false, err
+	}
+	suspension := &model.TrafficQuotaSuspension{
+		QuotaID:                quota.ID,
+		ProxyID:                proxy.ID,
+		Action:                 quota.Action,
+		PrevBandwidthLimit:     proxy.BandwidthLimit,
+		PrevBandwidthLimitMode: proxy.BandwidthLimitMode,
+	}
+
+	if quota.Action == model.QuotaActionThrottle {
+		// 已被其他配额限速时沿用最初的带宽设置
+		for _, e := range existing {
+			if e.Action == model.QuotaActionThrottle {
+				suspension.PrevBandwidthLimit = e.PrevBandwidthLimit
+				suspension.PrevBandwidthLimitMode = e.PrevBandwidthLimitMode
+				break
+			}
+		}
+		// 取现有限制与配额限速中更严格的一个，管理员设置的更低带宽保持不变
+		suspension.AppliedBandwidthLimit = quota.ThrottleLimit
+		if stricterBandwidthLimit(proxy.BandwidthLimit, quota.ThrottleLimit) {
+			suspension.AppliedBandwidthLimit = proxy.BandwidthLimit
+		}
+		if err := s.quotaRepo.CreateSuspension(suspension); err != nil {
+			return false, err
+		}
+		if proxy.BandwidthLimit == suspension.AppliedBandwidthLimit {
+			return false, nil
+		}
+		mode := proxy.BandwidthLimitMode
+		if mode == "" {
+			mode = "client"
+		}
+		if err := s.proxyRepo.UpdateBandwidthLimit(proxy.ID, suspension.AppliedBandwidthLimit, mode); err != nil {
+			s.quotaRepo.DeleteSuspension(suspension.ID)
+			return false, err
+		}
+		return true, nil
+	}
+
+	if !proxy.Enabled {
+		// 已被其他配额禁用时共同持有，手动禁用的代理不接管
+		if !hasQuotaAction(existing, model.QuotaActionSuspend) {
+			return false, nil
+		}
+		return false, s.quotaRepo.CreateSuspension(suspension)
+	}
+	if err := s.quotaRepo.CreateSuspension(suspension); err != nil {
+		return false, err
+	}
+	if _, err := s.proxyService.ToggleProxy(proxy.ID); err != nil {
+		s.quotaRepo.DeleteSuspension(suspension.ID)
+		return false, err
+	}
+	return true, nil
+}
+
+// restore 恢复配额处置过的代理并推送配置
+func (s *TrafficQuotaService) restore(quota *model.TrafficQuota, now time.Time, reason string) {
+	suspensions, err := s.quotaRepo.FindSuspensionsByQuota(quota.ID)
+	if err != nil {
+		logger.Errorf("[流量配额] 配额 %d 获取处置记录失败: %v", quota.ID, err)
+		return
+	}
+
+	var restored []uint
+	clientIDs := make(map[uint]bool)
+	for _, suspension := range suspensions {
+		if err := s.quotaRepo.DeleteSuspension(suspension.ID); err != nil {
+			logger.Errorf("[流量配额] 删除处置记录 %d 失败: %v", suspension.ID, err)
+			continue
+		}
+		clientID, changed, err := s.releaseProxy(&suspension)
+		if err != nil {
+			logger.Errorf("[流量配额] 恢复代理 %d 失败: %v", suspension.ProxyID, err)
+			continue
+		}
+		if changed {
+			restored = append(restored, suspension.ProxyID)
+			clientIDs[clientID] = true
+		}
+	}
+	for clientID := range clientIDs {
+		s.pendingPush[clientID] = true
+	}
+
+	quota.Exceeded = false
+	quota.ExceededAt = nil
+
+	name := s.targetName(quota)
+	logger.Infof("[流量配额] %s %s，恢复代理 %d 个", name, reason, len(restored))
+	s.logService.CreateLogAsync(0, "quota_restored", "traffic_quota", quota.ID,
+		fmt.Sprintf("流量配额恢复: %s, %s, 恢复代理 %d 个", name, reason, len(restored)), "127.0.0.1")
+	if s.eventNotifier != nil {
+		s.eventNotifier.NotifyQuotaRestored(quotaEventData(quota, name, restored))
+	}
+}
+
+// releaseProxy 撤销单条处置记录，代理仍被其他配额同类处置或已被手动修改时保持现状
+func (s *TrafficQuotaService) releaseProxy(suspension *model.TrafficQuotaSuspension) (uint, bool, error) {
+	proxy, err := s.proxyRepo.FindByID(suspension.ProxyID)
+	if err != nil {
+		// 代理已删除
+		return 0, false, nil
+	}
+	others, err := s.quotaRepo.FindSuspensionsByProxy(proxy.ID)
+	if err != nil {
+		return 0, false, err
+	}
+	if hasQuotaAction(others, suspension.Action) {
+		return 0, false, nil
+	}
+
+	if suspension.Action == model.QuotaActionThrottle {
+		if proxy.BandwidthLimit != suspension.AppliedBandwidthLimit {
+			return 0, false, nil
+		}
+		// 限速时未改动带宽（原有限制更严格）
+		if proxy.BandwidthLimit == suspension.PrevBandwidthLimit && proxy.BandwidthLimitMode == suspension.PrevBandwidthLimitMode {
+			return 0, false, nil
+		}
+		if err := s.proxyRepo.UpdateBandwidthLimit(proxy.ID, suspension.PrevBandwidthLimit, suspension.PrevBandwidthLimitMode); err != nil {
+			return 0, false, err
+		}
+		return proxy.ClientID, true, nil
+	}
+
+	if proxy.Enabled {
+		return 0, false, nil
+	}
+	if _, err := s.proxyService.ToggleProxy(proxy.ID); err != nil {
+		return 0, false, err
+	}
+	return proxy.ClientID, true, nil
+}
+
+// bandwidthLimitBytes 解析 frpc 带宽限制为每秒字节数，未设置或无法解析时视为不限速
+func bandwidthLimitBytes(limit string) (int64, bool) {
+	if !bandwidthLimitPattern.MatchString(limit) {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(limit[:len(limit)-2], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if strings.HasSuffix(limit, "MB") {
+		return value * 1024 * 1024, true
+	}
+	return value * 1024, true
+}
+
+// stricterBandwidthLimit 判断现有带宽限制是否不高于配额限速
+func stricterBandwidthLimit(current, throttle string) bool {
+	currentBytes, ok := bandwidthLimitBytes(current)
+	if !ok {
+		return false
+	}
+	throttleBytes, ok := bandwidthLimitBytes(throttle)
+	return ok && currentBytes <= throttleBytes
+}
+
+func hasQuotaAction(suspensions []model.TrafficQuotaSuspension, action string) bool {
+	for _, s := range suspensions {
+		if s.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// unlockAndPush 释放锁后推送持锁期间处置或恢复代理的客户端配置。推送需要等待守护进程确认，
+// 持锁推送会使一个无响应的守护进程阻塞配额检查和配额管理
+func (s *TrafficQuotaService) unlockAndPush() {
+	clientIDs := make([]uint, 0, len(s.pendingPush))
+	for clientID := range s.pendingPush {
+		clientIDs = append(clientIDs, clientID)
+	}
+	s.pendingPush = make(map[uint]bool)
+	s.mu.Unlock()
+
+	sort.Slice(clientIDs, func(i, j int) bool { return clientIDs[i] < clientIDs[j] })
+	for _, clientID := range clientIDs {
+		s.pushClientConfig(clientID)
+	}
+}
+
+// pushClientConfig 推送客户端配置，离线客户端在守护进程重连对账时补推
+func (s *TrafficQuotaService) pushClientConfig(clientID uint) {
+	if s.daemonHub == nil || !s.daemonHub.IsClientOnline(clientID) {
+		logger.Infof("[流量配额] 客户端 %d 不在线，配置将在守护进程重连后对账下发", clientID)
+		return
+	}
+
+	client, err := s.clientService.GetClient(clientID)
+	if err != nil {
+		logger.Errorf("[流量配额] 获取客户端 %d 失败: %v", clientID, err)
+		return
+	}
+	config, err := s.proxyService.ExportClientConfig(clientID)
+	if err != nil {
+		s.clientService.UpdateConfigSyncStatus(clientID, false, fmt.Sprintf("生成配置失败: %v", err), false)
+		return
+	}
+
+	newVersion := client.ConfigVersion + 1
+	s.clientService.SetConfigSyncPending(clientID)
+	if err := s.daemonHub.PushConfigUpdate(clientID, config, newVersion); err != nil {
+		logger.Errorf("[流量配额] 推送客户端 %d 配置失败: %v", clientID, err)
+		s.clientService.UpdateConfigSyncStatus(clientID, false, fmt.Sprintf("推送配置失败: %v", err), false)
+		return
+	}
+	s.clientService.UpdateConfigSync(clientID, newVersion, nil)
+}
+
+// targetName 获取配额目标的显示名称
+func (s *TrafficQuotaService) targetName(quota *model.TrafficQuota) string {
+	if quota.TargetType == model.QuotaTargetProxy {
+		if proxy, err := s.proxyRepo.FindByID(quota.TargetID); err == nil {
+			if client, err := s.clientRepo.FindByID(proxy.ClientID); err == nil {
+				return client.Name + "." + proxy.Name
+			}
+			return proxy.Name
+		}
+		return fmt.Sprintf("代理#%d", quota.TargetID)
+	}
+	if client, err := s.clientRepo.FindByID(quota.TargetID); err == nil {
+		return client.Name
+	}
+	return fmt.Sprintf("客户端#%d", quota.TargetID)
+}
+
+func quotaEventData(quota *model.TrafficQuota, name string, proxyIDs []uint) QuotaEventData {
+	return QuotaEventData{
+		QuotaID:    quota.ID,
+		TargetType: quota.TargetType,
+		TargetID:   quota.TargetID,
+		TargetName: name,
+		Period:     quota.Period,
+		Action:     quota.Action,
+		UsedBytes:  quota.UsedBytes,
+		LimitBytes: quota.LimitBytes,
+		ProxyIDs:   proxyIDs,
+	}
+}
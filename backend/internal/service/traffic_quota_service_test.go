@@ -0,0 +1,211 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupQuotaTestDB(t *testing.T) (*model.Client, []model.Proxy) {
+	newServiceTestDB(t, &model.Client{}, &model.Proxy{}, &model.ProxyMetricsHistory{},
+		&model.TrafficQuota{}, &model.TrafficQuotaSuspension{}, &model.OperationLog{})
+
+	serverID := uint(1)
+	client := &model.Client{Name: "c", FrpServerID: &serverID}
+	require.NoError(t, database.DB.Create(client).Error)
+	proxies := []model.Proxy{
+		{ClientID: client.ID, Name: "web", Type: "http", LocalPort: 80, Enabled: true},
+		{ClientID: client.ID, Name: "ssh", Type: "tcp", LocalPort: 22, Enabled: true, BandwidthLimit: "10MB", BandwidthLimitMode: "server"},
+	}
+	require.NoError(t, database.DB.Create(&proxies).Error)
+	return client, proxies
+}
+
+func insertQuotaTraffic(t *testing.T, proxyName string, delta int64, at time.Time) {
+	require.NoError(t, database.DB.Create(&model.ProxyMetricsHistory{
+		ServerID: 1, ProxyName: proxyName, DeltaIn: delta / 2, DeltaOut: delta - delta/2, RecordTime: at,
+	}).Error)
+}
+
+func reloadProxy(t *testing.T, id uint) model.Proxy {
+	var proxy model.Proxy
+	require.NoError(t, database.DB.First(&proxy, id).Error)
+	return proxy
+}
+
+// TestQuotaPeriodStart 测试统计周期计算
+func TestQuotaPeriodStart(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, loc)
+
+	daily := &model.TrafficQuota{Period: model.QuotaPeriodDaily}
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, loc), quotaPeriodStart(daily, now))
+
+	monthly := &model.TrafficQuota{Period: model.QuotaPeriodMonthly, ResetDay: 5}
+	assert.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, loc), quotaPeriodStart(monthly, now))
+
+	monthly.ResetDay = 15
+	assert.Equal(t, time.Date(2026, 2, 15, 0, 0, 0, 0, loc), quotaPeriodStart(monthly, now), "未到重置日时属于上一周期")
+
+	assert.Equal(t, time.Date(2025, 12, 15, 0, 0, 0, 0, loc), quotaPeriodStart(monthly, time.Date(2026, 1, 3, 0, 0, 0, 0, loc)), "跨年")
+}
+
+// TestTrafficQuotaService_SuspendAndRestore 测试超出配额禁用代理并在周期重置后恢复
+func TestTrafficQuotaService_SuspendAndRestore(t *testing.T) {
+	client, proxies := setupQuotaTestDB(t)
+	svc := NewTrafficQuotaService(nil)
+
+	// ssh 已被手动禁用，不应被配额接管
+	require.NoError(t, database.DB.Model(&proxies[1]).Update("enabled", false).Error)
+
+	quota := &model.TrafficQuota{TargetType: model.QuotaTargetClient, TargetID: client.ID, Period: model.QuotaPeriodDaily, LimitBytes: 1000, Enabled: true}
+	require.NoError(t, svc.CreateQuota(quota))
+
+	periodStart := quotaPeriodStart(quota, time.Now())
+	now := periodStart.Add(12 * time.Hour)
+	insertQuotaTraffic(t, "c.web", 600, periodStart.Add(-time.Minute)) // 上一周期
+	insertQuotaTraffic(t, "c.web", 600, now.Add(-2*time.Minute))
+
+	svc.checkAt(now)
+	reloaded, err := svc.quotaRepo.FindByID(quota.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), reloaded.UsedBytes)
+	assert.False(t, reloaded.Exceeded)
+
+	insertQuotaTraffic(t, "c.ssh", 500, now.Add(10*time.Second))
+	insertQuotaTraffic(t, "c.web", 999, now.Add(50*time.Second)) // 晚于统计截止时间，下次计入
+	svc.checkAt(now.Add(time.Minute))
+
+	reloaded, _ = svc.quotaRepo.FindByID(quota.ID)
+	assert.Equal(t, int64(1100), reloaded.UsedBytes, "用量增量累计，不重复统计")
+	assert.True(t, reloaded.Exceeded)
+	assert.False(t, reloadProxy(t, proxies[0].ID).Enabled)
+	suspensions, _ := svc.quotaRepo.FindSuspensionsByQuota(quota.ID)
+	assert.Len(t, suspensions, 1, "手动禁用的代理不记录")
+	assert.Empty(t, svc.pendingPush, "释放锁后推送受影响客户端的配置")
+	require.True(t, svc.mu.TryLock(), "推送配置时不持有锁")
+	svc.mu.Unlock()
+
+	// 下一周期恢复
+	svc.checkAt(periodStart.AddDate(0, 0, 1).Add(time.Minute))
+	reloaded, _ = svc.quotaRepo.FindByID(quota.ID)
+	assert.False(t, reloaded.Exceeded)
+	assert.Equal(t, int64(0), reloaded.UsedBytes)
+	assert.True(t, reloadProxy(t, proxies[0].ID).Enabled)
+	assert.False(t, reloadProxy(t, proxies[1].ID).Enabled, "手动禁用的代理保持禁用")
+	suspensions, _ = svc.quotaRepo.FindSuspensionsByQuota(quota.ID)
+	assert.Empty(t, suspensions)
+}
+
+// TestTrafficQuotaService_CreateDisabled 测试创建时保留显式关闭的启用状态，关闭的配额不统计也不禁用代理
+func TestTrafficQuotaService_CreateDisabled(t *testing.T) {
+	client, proxies := setupQuotaTestDB(t)
+	svc := NewTrafficQuotaService(nil)
+
+	quota := &model.TrafficQuota{TargetType: model.QuotaTargetClient, TargetID: client.ID, Period: model.QuotaPeriodDaily, LimitBytes: 1000}
+	require.NoError(t, svc.CreateQuota(quota))
+	reloaded, err := svc.quotaRepo.FindByID(quota.ID)
+	require.NoError(t, err)
+	assert.False(t, reloaded.Enabled)
+
+	now := quotaPeriodStart(quota, time.Now()).Add(12 * time.Hour)
+	insertQuotaTraffic(t, "c.web", 2000, now.Add(-time.Minute))
+	svc.checkAt(now)
+	reloaded, _ = svc.quotaRepo.FindByID(quota.ID)
+	assert.False(t, reloaded.Exceeded)
+	assert.True(t, reloadProxy(t, proxies[0].ID).Enabled)
+}
+
+// TestTrafficQuotaService_Throttle 测试超出配额限速及调高配额后恢复原带宽设置
+func TestTrafficQuotaService_Throttle(t *testing.T) {
+	_, proxies := setupQuotaTestDB(t)
+	svc := NewTrafficQuotaService(nil)
+
+	quota := &model.TrafficQuota{TargetType: model.QuotaTargetProxy, TargetID: proxies[1].ID, Period: model.QuotaPeriodMonthly,
+		LimitBytes: 100, Action: model.QuotaActionThrottle, ThrottleLimit: "1MB", Enabled: true}
+	require.NoError(t, svc.CreateQuota(quota))
+	assert.Equal(t, 1, quota.ResetDay)
+	assert.Equal(t, "c.ssh", quota.TargetName)
+
+	now := quotaPeriodStart(quota, time.Now()).Add(12 * time.Hour)
+	insertQuotaTraffic(t, "ssh", 200, now.Add(-time.Minute)) // 兼容短名称
+	svc.checkAt(now)
+
+	proxy := reloadProxy(t, proxies[1].ID)
+	assert.True(t, proxy.Enabled)
+	assert.Equal(t, "1MB", proxy.BandwidthLimit)
+
+	quota.LimitBytes = 1000
+	updated, err := svc.UpdateQuota(quota)
+	require.NoError(t, err)
+	assert.False(t, updated.Exceeded)
+
+	proxy = reloadProxy(t, proxies[1].ID)
+	assert.Equal(t, "10MB", proxy.BandwidthLimit)
+	assert.Equal(t, "server", proxy.BandwidthLimitMode)
+
+	svc.checkAt(now)
+	updated, _ = svc.quotaRepo.FindByID(quota.ID)
+	assert.False(t, updated.Exceeded, "调高配额后不再超出")
+}
+
+// TestTrafficQuotaService_ThrottleKeepsStricterLimit 测试现有带宽限制低于限速值时保持不变
+func TestTrafficQuotaService_ThrottleKeepsStricterLimit(t *testing.T) {
+	_, proxies := setupQuotaTestDB(t)
+	svc := NewTrafficQuotaService(nil)
+
+	// ssh 已手动限速 10MB，配额限速 20MB 不应放宽
+	quota := &model.TrafficQuota{TargetType: model.QuotaTargetProxy, TargetID: proxies[1].ID, Period: model.QuotaPeriodMonthly,
+		LimitBytes: 100, Action: model.QuotaActionThrottle, ThrottleLimit: "20MB", Enabled: true}
+	require.NoError(t, svc.CreateQuota(quota))
+
+	now := quotaPeriodStart(quota, time.Now()).Add(12 * time.Hour)
+	insertQuotaTraffic(t, "c.ssh", 200, now.Add(-time.Minute))
+	svc.checkAt(now)
+
+	reloaded, _ := svc.quotaRepo.FindByID(quota.ID)
+	assert.True(t, reloaded.Exceeded)
+	proxy := reloadProxy(t, proxies[1].ID)
+	assert.Equal(t, "10MB", proxy.BandwidthLimit)
+	assert.Equal(t, "server", proxy.BandwidthLimitMode)
+	suspensions, _ := svc.quotaRepo.FindSuspensionsByQuota(quota.ID)
+	require.Len(t, suspensions, 1)
+	assert.Equal(t, "10MB", suspensions[0].AppliedBandwidthLimit)
+
+	quota.LimitBytes = 1000
+	_, err := svc.UpdateQuota(quota)
+	require.NoError(t, err)
+	proxy = reloadProxy(t, proxies[1].ID)
+	assert.Equal(t, "10MB", proxy.BandwidthLimit)
+	assert.Equal(t, "server", proxy.BandwidthLimitMode)
+}
+
+// TestStricterBandwidthLimit 测试带宽限制比较
+func TestStricterBandwidthLimit(t *testing.T) {
+	assert.True(t, stricterBandwidthLimit("512KB", "1MB"))
+	assert.True(t, stricterBandwidthLimit("1024KB", "1MB"), "相等时保留现有设置")
+	assert.False(t, stricterBandwidthLimit("2MB", "1MB"))
+	assert.False(t, stricterBandwidthLimit("", "1MB"), "未限速")
+}
+
+// TestTrafficQuotaService_Validate 测试配额参数校验
+func TestTrafficQuotaService_Validate(t *testing.T) {
+	client, proxies := setupQuotaTestDB(t)
+	svc := NewTrafficQuotaService(nil)
+
+	cases := []model.TrafficQuota{
+		{TargetType: "server", TargetID: 1, Period: model.QuotaPeriodDaily, LimitBytes: 1},
+		{TargetType: model.QuotaTargetProxy, TargetID: 999, Period: model.QuotaPeriodDaily, LimitBytes: 1},
+		{TargetType: model.QuotaTargetClient, TargetID: client.ID, Period: "weekly", LimitBytes: 1},
+		{TargetType: model.QuotaTargetClient, TargetID: client.ID, Period: model.QuotaPeriodMonthly, ResetDay: 31, LimitBytes: 1},
+		{TargetType: model.QuotaTargetClient, TargetID: client.ID, Period: model.QuotaPeriodDaily, LimitBytes: 0},
+		{TargetType: model.QuotaTargetProxy, TargetID: proxies[0].ID, Period: model.QuotaPeriodDaily, LimitBytes: 1, Action: model.QuotaActionThrottle, ThrottleLimit: "1GB"},
+	}
+	for i := range cases {
+		assert.Error(t, svc.CreateQuota(&cases[i]), "case %d", i)
+	}
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/internal/util"
+	"frp-web-panel/pkg/database"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trafficReportMaxDays 单次报表允许的最大天数，超出各粒度保留期的部分在报表中标记为不精确
+const trafficReportMaxDays = 731
+
+// TrafficReportQuery 流量报表查询条件，时间范围左闭右开
+type TrafficReportQuery struct {
+	Start    time.Time
+	End      time.Time
+	Location *time.Location
+	GroupBy  string
+	ClientID uint
+	ServerID uint
+}
+
+// TrafficReportService 流量用量报表服务，按客户端/代理/服务器汇总任意时间范围的流量并导出，定期发送月度报表邮件
+type TrafficReportService struct {
+	metricsRollup    *MetricsRollupService
+	clientRepo       *repository.ClientRepository
+	proxyRepo        *repository.ProxyRepository
+	frpServerRepo    *repository.FrpServerRepository
+	settingRepo      *repository.SettingRepository
+	emailService     *EmailService
+	recipientService *AlertRecipientService
+}
+
+func NewTrafficReportService(metricsRollup *MetricsRollupService) *TrafficReportService {
+	return &TrafficReportService{
+		metricsRollup:    metricsRollup,
+		clientRepo:       repository.NewClientRepository(),
+		proxyRepo:        repository.NewProxyRepository(),
+		frpServerRepo:    repository.NewFrpServerRepository(database.DB),
+		settingRepo:      repository.NewSettingRepository(),
+		emailService:     NewEmailService(),
+		recipientService: NewAlertRecipientService(),
+	}
+}
+
+// ParseTrafficReportRange 解析报表时间范围
+// month 格式为 2006-01，优先于 startDate/endDate；日期格式为 2006-01-02，结束日期包含当天；tz 为空时使用服务器时区
+func ParseTrafficReportRange(startDate, endDate, month, tz string) (time.Time, time.Time, *time.Location, error) {
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return time.Time{}, time.Time{}, nil, fmt.Errorf("无效的时区: %s", tz)
+		}
+	}
+
+	if month != "" {
+		start, err := time.ParseInLocation("2006-01", month, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, nil, fmt.Errorf("月份格式错误，应为 YYYY-MM")
+		}
+		return start, start.AddDate(0, 1, 0), loc, nil
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", startDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("开始日期格式错误，应为 YYYY-MM-DD")
+	}
+	end, err := time.ParseInLocation("2006-01-02", endDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("结束日期格式错误，应为 YYYY-MM-DD")
+	}
+	end = end.AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("结束日期不能早于开始日期")
+	}
+	if end.Sub(start) > trafficReportMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("时间范围不能超过 %d 天", trafficReportMaxDays)
+	}
+	return start, end, loc, nil
+}
+
+// BuildReport 生成流量报表
+func (s *TrafficReportService) BuildReport(query TrafficReportQuery) (*model.TrafficReport, error) {
+	switch query.GroupBy {
+	case "":
+		query.GroupBy = model.TrafficReportByClient
+	case model.TrafficReportByClient, model.TrafficReportByProxy, model.TrafficReportByServer:
+	default:
+		return nil, fmt.Errorf("不支持的分组方式: %s", query.GroupBy)
+	}
+	if query.Location == nil {
+		query.Location = time.Local
+	}
+
+	usages, exact, err := s.metricsRollup.ProxyTrafficUsageDetail(query.Start, query.End)
+	if err != nil {
+		return nil, fmt.Errorf("统计流量失败: %w", err)
+	}
+
+	clients, err := s.clientRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := s.proxyRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	servers, err := s.frpServerRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	clientByID := make(map[uint]*model.Client, len(clients))
+	for i := range clients {
+		clientByID[clients[i].ID] = &clients[i]
+	}
+	// FRP 使用 clientName.proxyName 格式命名隧道，客户端名称本身可能含点，按面板中的完整名称匹配
+	proxyByName := make(map[string]*model.Proxy, len(proxies))
+	for i, p := range proxies {
+		if client, ok := clientByID[p.ClientID]; ok {
+			proxyByName[client.Name+"."+p.Name] = &proxies[i]
+		}
+	}
+	serverNames := make(map[uint]string, len(servers))
+	for _, srv := range servers {
+		serverNames[srv.ID] = srv.Name
+	}
+
+	report := &model.TrafficReport{
+		GroupBy:     query.GroupBy,
+		Timezone:    query.Location.String(),
+		Start:       query.Start.In(query.Location),
+		End:         query.End.In(query.Location),
+		Rows:        []model.TrafficReportRow{},
+		Approximate: !exact,
+	}
+	rows := make(map[string]*model.TrafficReportRow)
+	var order []string
+
+	for _, u := range usages {
+		if query.ServerID != 0 && u.ServerID != query.ServerID {
+			continue
+		}
+
+		row := model.TrafficReportRow{ServerID: u.ServerID, ServerName: serverNames[u.ServerID], ProxyName: u.ProxyName}
+		if proxy, ok := proxyByName[u.ProxyName]; ok {
+			client := clientByID[proxy.ClientID]
+			row.ClientID, row.ClientName = client.ID, client.Name
+			row.ProxyID, row.ProxyName = proxy.ID, proxy.Name
+		} else if client := matchClientPrefix(clients, u.ProxyName); client != nil {
+			// 面板中未登记的代理按最长的客户端名称前缀归属
+			row.ClientID, row.ClientName = client.ID, client.Name
+			row.ProxyName = strings.TrimPrefix(u.ProxyName, client.Name+".")
+		}
+		if query.ClientID != 0 && row.ClientID != query.ClientID {
+			continue
+		}
+
+		var key string
+		switch query.GroupBy {
+		case model.TrafficReportByProxy:
+			key = fmt.Sprintf("%d:%s", u.ServerID, u.ProxyName)
+		case model.TrafficReportByClient:
+			key = fmt.Sprintf("%d:%d", u.ServerID, row.ClientID)
+			row.ProxyID, row.ProxyName = 0, ""
+		case model.TrafficReportByServer:
+			key = fmt.Sprintf("%d", u.ServerID)
+			row.ClientID, row.ClientName, row.ProxyID, row.ProxyName = 0, "", 0, ""
+		}
+
+		existing, ok := rows[key]
+		if !ok {
+			existing = &row
+			rows[key] = existing
+			order = append(order, key)
+		}
+		existing.TrafficIn += u.DeltaIn
+		existing.TrafficOut += u.DeltaOut
+		existing.Total += u.DeltaIn + u.DeltaOut
+		report.TrafficIn += u.DeltaIn
+		report.TrafficOut += u.DeltaOut
+	}
+	report.Total = report.TrafficIn + report.TrafficOut
+
+	for _, key := range order {
+		report.Rows = append(report.Rows, *rows[key])
+	}
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Total > report.Rows[j].Total
+	})
+	return report, nil
+}
+
+// matchClientPrefix 返回名称为隧道名前缀（clientName.）的客户端，多个匹配时取名称最长的
+func matchClientPrefix(clients []model.Client, proxyName string) *model.Client {
+	var matched *model.Client
+	for i := range clients {
+		if strings.HasPrefix(proxyName, clients[i].Name+".") && (matched == nil || len(clients[i].Name) > len(matched.Name)) {
+			matched = &clients[i]
+		}
+	}
+	return matched
+}
+
+// reportTable 将报表转换为表格，首行为表头，末行为合计
+func reportTable(report *model.TrafficReport) [][]interface{} {
+	var header []interface{}
+	switch report.GroupBy {
+	case model.TrafficReportByProxy:
+		header = []interface{}{"服务器", "客户端", "代理"}
+	case model.TrafficReportByClient:
+		header = []interface{}{"服务器", "客户端"}
+	default:
+		header = []interface{}{"服务器"}
+	}
+	nameColumns := len(header)
+	header = append(header, "入站(字节)", "出站(字节)", "合计(字节)")
+
+	table := [][]interface{}{header}
+	for _, r := range report.Rows {
+		row := []interface{}{r.ServerName, r.ClientName, r.ProxyName}[:nameColumns]
+		table = append(table, append(row, r.TrafficIn, r.TrafficOut, r.Total))
+	}
+
+	total := make([]interface{}, nameColumns)
+	total[0] = "合计"
+	for i := 1; i < nameColumns; i++ {
+		total[i] = ""
+	}
+	table = append(table, append(total, report.TrafficIn, report.TrafficOut, report.Total))
+	return table
+}
+
+// WriteCSV 导出 CSV，带 UTF-8 BOM 以便 Excel 正确识别中文
+func (s *TrafficReportService) WriteCSV(w io.Writer, report *model.TrafficReport) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	for _, row := range reportTable(report) {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = fmt.Sprint(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteXLSX 导出 XLSX
+func (s *TrafficReportService) WriteXLSX(w io.Writer, report *model.TrafficReport) error {
+	return util.WriteXLSX(w, "流量报表", reportTable(report))
+}
+
+// ReportFilename 生成导出文件名
+func ReportFilename(report *model.TrafficReport, ext string) string {
+	return fmt.Sprintf("traffic-report-%s-%s_%s.%s", report.GroupBy,
+		report.Start.Format("20060102"), report.End.AddDate(0, 0, -1).Format("20060102"), ext)
+}
+
+// CheckMonthlyReport 检查是否需要发送上月流量报表，供定时任务调用
+func (s *TrafficReportService) CheckMonthlyReport() {
+	s.checkMonthlyReportAt(time.Now())
+}
+
+func (s *TrafficReportService) checkMonthlyReportAt(now time.Time) {
+	if enabled, _ := s.settingRepo.GetSetting("traffic_report_enabled"); enabled != "true" {
+		return
+	}
+
+	loc := time.Local
+	if tz, _ := s.settingRepo.GetSetting("traffic_report_timezone"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			logger.Warnf("[流量报表] 时区设置无效: %s", tz)
+			return
+		}
+	}
+
+	local := now.In(loc)
+	monthStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+	// 等待上月最后的采样写入后再统计
+	if local.Sub(monthStart) < time.Hour {
+		return
+	}
+	prevMonth := monthStart.AddDate(0, -1, 0)
+	monthKey := prevMonth.Format("2006-01")
+	if last, _ := s.settingRepo.GetSetting("traffic_report_last_month"); last == monthKey {
+		return
+	}
+
+	if err := s.SendMonthlyReport(prevMonth, monthStart); err != nil {
+		logger.Errorf("[流量报表] 发送 %s 月度报表失败: %v", monthKey, err)
+		return
+	}
+	if err := s.settingRepo.UpdateSetting("traffic_report_last_month", monthKey); err != nil {
+		logger.Errorf("[流量报表] 记录报表发送月份失败: %v", err)
+	}
+}
+
+// SendMonthlyReport 生成指定月份报表并发送给配置的接收人，附件为按代理明细的 XLSX 和 CSV
+func (s *TrafficReportService) SendMonthlyReport(start, end time.Time) error {
+	recipientIDs, _ := s.settingRepo.GetSetting("traffic_report_recipient_ids")
+	groupIDs, _ := s.settingRepo.GetSetting("traffic_report_group_ids")
+	emails := s.recipientService.GetEmailsByRecipientAndGroupIDs(parseIDList(recipientIDs), parseIDList(groupIDs))
+	if len(emails) == 0 {
+		return fmt.Errorf("未配置报表接收人")
+	}
+
+	query := TrafficReportQuery{Start: start, End: end, Location: start.Location()}
+	query.GroupBy = model.TrafficReportByClient
+	summary, err := s.BuildReport(query)
+	if err != nil {
+		return err
+	}
+	query.GroupBy = model.TrafficReportByProxy
+	detail, err := s.BuildReport(query)
+	if err != nil {
+		return err
+	}
+
+	var xlsxBuf, csvBuf bytes.Buffer
+	if err := s.WriteXLSX(&xlsxBuf, detail); err != nil {
+		return err
+	}
+	if err := s.WriteCSV(&csvBuf, detail); err != nil {
+		return err
+	}
+
+	period := start.Format("2006-01")
+	html, _, err := GenerateTrafficReportEmail(TrafficReportEmailData{
+		Period:      period,
+		Timezone:    summary.Timezone,
+		Rows:        summary.Rows,
+		TrafficIn:   summary.TrafficIn,
+		TrafficOut:  summary.TrafficOut,
+		Approximate: summary.Approximate,
+	})
+	if err != nil {
+		return err
+	}
+	attachments := []EmailAttachment{
+		{Filename: ReportFilename(detail, "xlsx"), ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Data: xlsxBuf.Bytes()},
+		{Filename: ReportFilename(detail, "csv"), ContentType: "text/csv", Data: csvBuf.Bytes()},
+	}
+
+	subject := fmt.Sprintf("FRP 月度流量报表 - %s", period)
+	sent := 0
+	for _, to := range emails {
+		if err := s.emailService.SendEmailWithAttachments(to, subject, html, attachments); err != nil {
+			logger.Errorf("[流量报表] 发送报表到 %s 失败: %v", to, err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return fmt.Errorf("所有接收人发送失败")
+	}
+	logger.Infof("[流量报表] %s 月度报表已发送给 %d 个接收人", period, sent)
+	return nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/pkg/database"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReportTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.Client{}, &model.Proxy{}, &model.FrpServer{},
+		&model.ProxyMetricsHistory{}, &model.ProxyMetricsRollup{}, &model.ServerMetricsHistory{}, &model.ServerMetricsRollup{})
+}
+
+func insertReportTraffic(t *testing.T, serverID uint, proxyName string, deltaIn, deltaOut int64, at time.Time) {
+	require.NoError(t, database.DB.Create(&model.ProxyMetricsHistory{
+		ServerID: serverID, ProxyName: proxyName, DeltaIn: deltaIn, DeltaOut: deltaOut, RecordTime: at,
+	}).Error)
+}
+
+// TestParseTrafficReportRange 测试报表时间范围解析
+func TestParseTrafficReportRange(t *testing.T) {
+	start, end, loc, err := ParseTrafficReportRange("", "", "2026-02", "Asia/Shanghai")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Shanghai", loc.String())
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, loc), start)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, loc), end)
+
+	start, end, _, err = ParseTrafficReportRange("2026-01-10", "2026-01-10", "", "UTC")
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, end.Sub(start), "结束日期包含当天")
+
+	_, _, _, err = ParseTrafficReportRange("2026-01-10", "2026-01-09", "", "")
+	assert.Error(t, err)
+	_, _, _, err = ParseTrafficReportRange("2020-01-01", "2026-01-01", "", "")
+	assert.Error(t, err)
+	_, _, _, err = ParseTrafficReportRange("", "", "2026-01", "Mars/Base")
+	assert.Error(t, err)
+}
+
+// TestMetricsRollupService_ProxyTrafficUsage 测试跨汇总粒度统计流量不重复计算
+func TestMetricsRollupService_ProxyTrafficUsage(t *testing.T) {
+	setupReportTestDB(t)
+	svc := NewMetricsRollupService()
+
+	now := time.Now().Truncate(time.Hour)
+	start := now.Add(-3 * time.Hour)
+	var expected int64
+	for ts := start; ts.Before(now); ts = ts.Add(30 * time.Second) {
+		insertReportTraffic(t, 1, "c.web", 100, 200, ts)
+		expected += 300
+	}
+	svc.rollupAt(now)
+
+	// 非整点范围需要拼接小时、5 分钟和原始数据
+	rangeStart, rangeEnd := start.Add(7*time.Minute+15*time.Second), now.Add(-22*time.Minute)
+	var want int64
+	for ts := start; ts.Before(now); ts = ts.Add(30 * time.Second) {
+		if !ts.Before(rangeStart) && ts.Before(rangeEnd) {
+			want += 300
+		}
+	}
+
+	usages, err := svc.ProxyTrafficUsage(rangeStart, rangeEnd)
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, want, usages[0].DeltaIn+usages[0].DeltaOut)
+
+	usages, err = svc.ProxyTrafficUsage(start, now)
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, expected, usages[0].DeltaIn+usages[0].DeltaOut)
+}
+
+// TestMetricsRollupService_ProxyTrafficUsageApproximate 测试首尾需要的细粒度数据超出保留期时标记为不精确
+func TestMetricsRollupService_ProxyTrafficUsageApproximate(t *testing.T) {
+	setupReportTestDB(t)
+	svc := NewMetricsRollupService()
+
+	day := bucketStart(time.Now().AddDate(0, 0, -400), 24*time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, database.DB.Create(&model.ProxyMetricsRollup{
+			Resolution: model.MetricsResolution1d, ServerID: 1, ProxyName: "c.web",
+			DeltaIn: 100, DeltaOut: 100, Samples: 1, BucketTime: day.AddDate(0, 0, i),
+		}).Error)
+	}
+
+	// 与天粒度对齐的范围可以完整统计
+	usages, exact, err := svc.ProxyTrafficUsageDetail(day, day.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	assert.True(t, exact)
+	require.Len(t, usages, 1)
+	assert.Equal(t, int64(600), usages[0].DeltaIn+usages[0].DeltaOut)
+
+	// 首尾偏移几小时（如其他时区的零点）需要小时数据，已超出保留期
+	usages, exact, err = svc.ProxyTrafficUsageDetail(day.Add(5*time.Hour), day.AddDate(0, 0, 2).Add(5*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, exact)
+	require.Len(t, usages, 1)
+	assert.Equal(t, int64(200), usages[0].DeltaIn+usages[0].DeltaOut, "只统计完整的天")
+
+	// 近期数据的首尾仍在细粒度保留期内
+	now := time.Now()
+	_, exact, err = svc.ProxyTrafficUsageDetail(now.Add(-5*time.Hour-7*time.Minute), now)
+	require.NoError(t, err)
+	assert.True(t, exact)
+}
+
+// TestTrafficReportService_BuildReport 测试按客户端/代理/服务器分组及导出
+func TestTrafficReportService_BuildReport(t *testing.T) {
+	setupReportTestDB(t)
+	require.NoError(t, database.DB.Create(&model.FrpServer{ID: 1, Name: "srv-a", Host: "a"}).Error)
+	require.NoError(t, database.DB.Create(&model.FrpServer{ID: 2, Name: "srv-b", Host: "b"}).Error)
+	client := &model.Client{Name: "c"}
+	require.NoError(t, database.DB.Create(client).Error)
+	proxy := &model.Proxy{ClientID: client.ID, Name: "web", Type: "http", LocalPort: 80}
+	require.NoError(t, database.DB.Create(proxy).Error)
+
+	svc := NewTrafficReportService(NewMetricsRollupService())
+	now := time.Now()
+	insertReportTraffic(t, 1, "c.web", 100, 200, now.Add(-time.Hour))
+	insertReportTraffic(t, 1, "c.ssh", 10, 20, now.Add(-time.Hour))
+	insertReportTraffic(t, 2, "c.web", 1000, 0, now.Add(-time.Hour))
+	insertReportTraffic(t, 2, "orphan", 5, 5, now.Add(-time.Hour))
+	insertReportTraffic(t, 1, "c.web", 9999, 0, now.Add(-48*time.Hour)) // 范围外
+
+	query := TrafficReportQuery{Start: now.Add(-2 * time.Hour), End: now, GroupBy: model.TrafficReportByProxy}
+	report, err := svc.BuildReport(query)
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 4)
+	assert.Equal(t, int64(1340), report.Total)
+	assert.False(t, report.Approximate)
+	assert.Equal(t, "srv-b", report.Rows[0].ServerName)
+	assert.Equal(t, proxy.ID, report.Rows[1].ProxyID)
+	assert.Equal(t, "web", report.Rows[1].ProxyName)
+	assert.Equal(t, "orphan", report.Rows[3].ProxyName, "无法归属客户端的代理保留原名")
+	assert.Zero(t, report.Rows[3].ClientID)
+
+	query.GroupBy = model.TrafficReportByClient
+	query.ClientID = client.ID
+	report, err = svc.BuildReport(query)
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 2)
+	assert.Equal(t, int64(1000), report.Rows[0].Total)
+	assert.Equal(t, int64(330), report.Rows[1].Total)
+	assert.Equal(t, int64(1330), report.Total)
+
+	query.GroupBy = model.TrafficReportByServer
+	query.ClientID = 0
+	query.ServerID = 1
+	report, err = svc.BuildReport(query)
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 1)
+	assert.Equal(t, int64(330), report.Rows[0].Total)
+
+	var buf bytes.Buffer
+	require.NoError(t, svc.WriteCSV(&buf, report))
+	lines := strings.Split(strings.TrimSpace(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF")), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "服务器,入站(字节),出站(字节),合计(字节)", lines[0])
+	assert.Equal(t, "srv-a,110,220,330", lines[1])
+	assert.Equal(t, "合计,110,220,330", lines[2])
+
+	query.GroupBy = "unknown"
+	_, err = svc.BuildReport(query)
+	assert.Error(t, err)
+}
+
+// TestTrafficReportService_DottedClientName 测试客户端名称含点时按完整名称归属客户端和代理
+func TestTrafficReportService_DottedClientName(t *testing.T) {
+	setupReportTestDB(t)
+	require.NoError(t, database.DB.Create(&model.FrpServer{ID: 1, Name: "srv-a", Host: "a"}).Error)
+	short := &model.Client{Name: "web01"}
+	require.NoError(t, database.DB.Create(short).Error)
+	dotted := &model.Client{Name: "web01.prod"}
+	require.NoError(t, database.DB.Create(dotted).Error)
+	proxy := &model.Proxy{ClientID: dotted.ID, Name: "api", Type: "http", LocalPort: 80}
+	require.NoError(t, database.DB.Create(proxy).Error)
+
+	now := time.Now()
+	insertReportTraffic(t, 1, "web01.prod.api", 100, 0, now.Add(-time.Hour))
+	insertReportTraffic(t, 1, "web01.prod.ssh", 10, 0, now.Add(-time.Hour))
+	insertReportTraffic(t, 1, "web01.ssh", 1, 0, now.Add(-time.Hour))
+
+	svc := NewTrafficReportService(NewMetricsRollupService())
+	report, err := svc.BuildReport(TrafficReportQuery{Start: now.Add(-2 * time.Hour), End: now, GroupBy: model.TrafficReportByProxy})
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 3)
+	assert.Equal(t, dotted.ID, report.Rows[0].ClientID)
+	assert.Equal(t, proxy.ID, report.Rows[0].ProxyID)
+	assert.Equal(t, "api", report.Rows[0].ProxyName)
+	assert.Equal(t, dotted.ID, report.Rows[1].ClientID, "未登记的代理按最长的客户端名称前缀归属")
+	assert.Equal(t, "ssh", report.Rows[1].ProxyName)
+	assert.Equal(t, short.ID, report.Rows[2].ClientID)
+	assert.Equal(t, "ssh", report.Rows[2].ProxyName)
+}
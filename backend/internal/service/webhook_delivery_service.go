@@ -0,0 +1,356 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultWebhookMaxAttempts = 6
+	maxWebhookMaxAttempts     = 20
+	defaultWebhookRetention   = 30 // 天
+	webhookBackoffBase        = 30 * time.Second
+	webhookBackoffMax         = 1 * time.Hour
+	webhookClaimLease         = 2 * time.Minute // 单次投递的最长占用时间，需大于 HTTP 超时
+	webhookDueBatchSize       = 50
+	webhookConcurrency        = 5
+	webhookMaxResponseBytes   = 1024
+	defaultWebhookListSize    = 100
+)
+
+// Webhook 请求头
+const (
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookTemplateData 请求体模板可用的数据，内嵌告警日志的全部字段
+type WebhookTemplateData struct {
+	model.AlertLog
+	TargetName string                 // 代理、客户端、服务器名称，系统告警为规则类型
+	Timestamp  int64                  // 告警时间的 Unix 秒
+	Payload    map[string]interface{} // 默认请求体，可通过 {{json .Payload}} 原样输出
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"formatBytes": formatBytes,
+	"formatTime": func(t time.Time, layout string) string {
+		return t.Format(layout)
+	},
+}
+
+// ValidateWebhookTemplate 校验请求体模板语法，空模板表示使用默认 JSON
+func ValidateWebhookTemplate(tpl string) error {
+	if strings.TrimSpace(tpl) == "" {
+		return nil
+	}
+	if _, err := template.New("webhook").Funcs(webhookTemplateFuncs).Parse(tpl); err != nil {
+		return fmt.Errorf("Webhook 模板语法错误: %v", err)
+	}
+	return nil
+}
+
+// RenderWebhookPayload 渲染请求体并返回对应的 Content-Type
+// 未配置模板时输出默认 JSON；模板输出为合法 JSON 时按 JSON 发送，否则按纯文本发送
+func RenderWebhookPayload(tpl string, data *WebhookTemplateData) (string, string, error) {
+	if strings.TrimSpace(tpl) == "" {
+		body, err := json.Marshal(data.Payload)
+		return string(body), "application/json", err
+	}
+	t, err := template.New("webhook").Funcs(webhookTemplateFuncs).Option("missingkey=zero").Parse(tpl)
+	if err != nil {
+		return "", "", fmt.Errorf("Webhook 模板语法错误: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("渲染 Webhook 模板失败: %v", err)
+	}
+	if json.Valid(buf.Bytes()) {
+		return buf.String(), "application/json", nil
+	}
+	return buf.String(), "text/plain; charset=utf-8", nil
+}
+
+// SignWebhookPayload 计算签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制，带 sha256= 前缀
+func SignWebhookPayload(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff 第 n 次失败后的重试间隔：30秒起按 2 的幂增长，最长 1 小时
+func webhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := webhookBackoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookBackoffMax {
+			return webhookBackoffMax
+		}
+	}
+	return delay
+}
+
+// WebhookDeliveryService 告警 Webhook 可靠投递
+// 投递记录入队后立即尝试一次，失败按指数退避由定时任务重试，
+// 投递成功后才将关联告警日志标记为已通知
+type WebhookDeliveryService struct {
+	deliveryRepo *repository.WebhookDeliveryRepository
+	alertRepo    *repository.AlertRepo
+	settingRepo  *repository.SettingRepository
+	client       *http.Client
+}
+
+func NewWebhookDeliveryService(alertRepo *repository.AlertRepo) *WebhookDeliveryService {
+	return &WebhookDeliveryService{
+		deliveryRepo: repository.NewWebhookDeliveryRepository(),
+		alertRepo:    alertRepo,
+		settingRepo:  repository.NewSettingRepository(),
+		client:       httpClient,
+	}
+}
+
+// Enqueue 渲染请求体并持久化投递记录，随后立即尝试投递一次
+// 模板渲染失败时回退到默认 JSON，保证告警不会因模板错误丢失
+func (s *WebhookDeliveryService) Enqueue(url string, rule *model.AlertRule, alert *model.AlertLog, targetName string, payload map[string]interface{}) (*model.WebhookDelivery, error) {
+	data := &WebhookTemplateData{
+		AlertLog:   *alert,
+		TargetName: targetName,
+		Timestamp:  alert.CreatedAt.Unix(),
+		Payload:    payload,
+	}
+	body, contentType, err := RenderWebhookPayload(rule.WebhookTemplate, data)
+	if err != nil {
+		logger.Warnf("[Webhook] 规则 %d %v，使用默认请求体", rule.ID, err)
+		body, contentType, err = RenderWebhookPayload("", data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.enqueue(&model.WebhookDelivery{
+		AlertLogID:  alert.ID,
+		RuleID:      rule.ID,
+		EventType:   alert.AlertType,
+		URL:         url,
+		ContentType: contentType,
+		Payload:     body,
+	})
+}
+
+// EnqueueJSON 持久化通知渠道的 JSON 投递记录并立即尝试投递一次，与告警 Webhook 共用重试和投递记录
+func (s *WebhookDeliveryService) EnqueueJSON(url, eventType string, payload interface{}) (*model.WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueue(&model.WebhookDelivery{
+		EventType:   eventType,
+		URL:         url,
+		ContentType: "application/json",
+		Payload:     string(body),
+	})
+}
+
+func (s *WebhookDeliveryService) enqueue(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.MaxAttempts = s.maxAttempts()
+	delivery.NextAttemptAt = time.Now()
+	if err := s.deliveryRepo.Create(delivery); err != nil {
+		return nil, fmt.Errorf("保存 Webhook 投递记录失败: %v", err)
+	}
+	s.claimAndAttempt(delivery, time.Now())
+	return delivery, nil
+}
+
+// ProcessDue 投递全部到期的记录，供定时任务调用
+func (s *WebhookDeliveryService) ProcessDue() {
+	now := time.Now()
+	deliveries, err := s.deliveryRepo.FindDue(now, webhookDueBatchSize)
+	if err != nil {
+		logger.Errorf("[Webhook] 获取待投递记录失败: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, webhookConcurrency)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.claimAndAttempt(delivery, now)
+		}()
+	}
+	wg.Wait()
+}
+
+// Redeliver 手动重新投递，重置重试次数并立即发送
+func (s *WebhookDeliveryService) Redeliver(id uint) (*model.WebhookDelivery, error) {
+	delivery, err := s.deliveryRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("投递记录不存在")
+	}
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.MaxAttempts = s.maxAttempts()
+	delivery.NextAttemptAt = time.Now().Add(webhookClaimLease)
+	if err := s.deliveryRepo.Update(delivery); err != nil {
+		return nil, err
+	}
+	s.attempt(delivery, true)
+	return delivery, nil
+}
+
+// GetDeliveries 获取投递记录
+func (s *WebhookDeliveryService) GetDeliveries(status string, alertLogID uint, limit int) ([]model.WebhookDelivery, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = defaultWebhookListSize
+	}
+	return s.deliveryRepo.FindAll(status, alertLogID, limit)
+}
+
+// GetAttempts 获取投递记录的尝试历史
+func (s *WebhookDeliveryService) GetAttempts(id uint) ([]model.WebhookDeliveryAttempt, error) {
+	if _, err := s.deliveryRepo.FindByID(id); err != nil {
+		return nil, fmt.Errorf("投递记录不存在")
+	}
+	return s.deliveryRepo.FindAttempts(id)
+}
+
+// Cleanup 清理超过保留天数且已结束的投递记录，供定时任务调用
+func (s *WebhookDeliveryService) Cleanup() {
+	retention := s.intSetting("webhook_delivery_retention_days", defaultWebhookRetention)
+	if deleted, err := s.deliveryRepo.DeleteFinishedBefore(time.Now().AddDate(0, 0, -retention)); err != nil {
+		logger.Errorf("[Webhook] 清理过期投递记录失败: %v", err)
+	} else if deleted > 0 {
+		logger.Infof("[Webhook] 清理 %d 条超过 %d 天的投递记录", deleted, retention)
+	}
+}
+
+func (s *WebhookDeliveryService) claimAndAttempt(delivery *model.WebhookDelivery, now time.Time) {
+	claimed, err := s.deliveryRepo.Claim(delivery.ID, now, now.Add(webhookClaimLease))
+	if err != nil {
+		logger.Errorf("[Webhook] 锁定投递记录 %d 失败: %v", delivery.ID, err)
+		return
+	}
+	if claimed {
+		s.attempt(delivery, false)
+	}
+}
+
+// attempt 发送一次请求，记录尝试结果并更新投递状态
+func (s *WebhookDeliveryService) attempt(delivery *model.WebhookDelivery, manual bool) {
+	start := time.Now()
+	statusCode, respBody, sendErr := s.send(delivery)
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	record := &model.WebhookDeliveryAttempt{
+		DeliveryID:   delivery.ID,
+		Attempt:      delivery.Attempts,
+		Manual:       manual,
+		Success:      sendErr == nil,
+		StatusCode:   statusCode,
+		ResponseBody: respBody,
+		DurationMs:   time.Since(start).Milliseconds(),
+	}
+
+	if sendErr == nil {
+		now := time.Now()
+		delivery.Status = model.WebhookDeliverySuccess
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	} else {
+		record.Error = truncateString(sendErr.Error(), 480)
+		delivery.LastError = record.Error
+		if delivery.Attempts >= delivery.MaxAttempts {
+			delivery.Status = model.WebhookDeliveryFailed
+			logger.Warnf("[Webhook] 投递 %d 到 %s 失败 %d 次，停止重试: %v", delivery.ID, delivery.URL, delivery.Attempts, sendErr)
+		} else {
+			delivery.Status = model.WebhookDeliveryPending
+			delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+		}
+	}
+
+	if err := s.deliveryRepo.CreateAttempt(record); err != nil {
+		logger.Errorf("[Webhook] 保存投递尝试记录失败: %v", err)
+	}
+	if err := s.deliveryRepo.Update(delivery); err != nil {
+		logger.Errorf("[Webhook] 更新投递记录 %d 失败: %v", delivery.ID, err)
+	}
+	if delivery.Status == model.WebhookDeliverySuccess && delivery.AlertLogID > 0 && s.alertRepo != nil {
+		if err := s.alertRepo.MarkAsNotified(delivery.AlertLogID); err != nil {
+			logger.Errorf("[Webhook] 标记告警 %d 已通知失败: %v", delivery.AlertLogID, err)
+		}
+	}
+}
+
+// send 发送请求，非 2xx 响应视为失败
+func (s *WebhookDeliveryService) send(delivery *model.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", delivery.ContentType)
+	req.Header.Set("User-Agent", "frp-web-panel-webhook")
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	if secret, _ := s.settingRepo.GetSetting("webhook_signing_secret"); secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookHeaderTimestamp, timestamp)
+		req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(secret, timestamp, delivery.Payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+func (s *WebhookDeliveryService) maxAttempts() int {
+	n := s.intSetting("webhook_max_attempts", defaultWebhookMaxAttempts)
+	if n > maxWebhookMaxAttempts {
+		return maxWebhookMaxAttempts
+	}
+	return n
+}
+
+func (s *WebhookDeliveryService) intSetting(key string, def int) int {
+	if val, err := s.settingRepo.GetSetting(key); err == nil {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
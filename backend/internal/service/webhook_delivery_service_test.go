@@ -0,0 +1,217 @@
+package service
+
+import (
+	"frp-web-panel/internal/model"
+	"frp-web-panel/internal/repository"
+	"frp-web-panel/pkg/database"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupWebhookTestDB(t *testing.T) {
+	newServiceTestDB(t, &model.Setting{}, &model.AlertRule{}, &model.AlertLog{},
+		&model.WebhookDelivery{}, &model.WebhookDeliveryAttempt{})
+}
+
+// webhookReceiver 按顺序返回预设状态码的 Webhook 接收端，记录收到的请求
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) (*httptest.Server, *webhookReceiver) {
+	recv := &webhookReceiver{statuses: statuses}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		recv.requests = append(recv.requests, r)
+		recv.bodies = append(recv.bodies, string(body))
+		status := http.StatusOK
+		if len(recv.statuses) > 0 {
+			status = recv.statuses[0]
+			if len(recv.statuses) > 1 {
+				recv.statuses = recv.statuses[1:]
+			}
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, recv
+}
+
+func createWebhookTestAlert(t *testing.T) (*model.AlertRule, *model.AlertLog) {
+	rule := &model.AlertRule{TargetType: model.AlertTargetFrpc, TargetID: 1, RuleType: "offline", Enabled: true}
+	require.NoError(t, database.DB.Create(rule).Error)
+	alert := &model.AlertLog{RuleID: rule.ID, TargetType: model.AlertTargetFrpc, TargetID: 1, AlertType: "offline",
+		Message: "frpc web 已离线"}
+	require.NoError(t, database.DB.Create(alert).Error)
+	return rule, alert
+}
+
+// TestRenderWebhookPayload 测试默认请求体及模板渲染
+func TestRenderWebhookPayload(t *testing.T) {
+	data := &WebhookTemplateData{
+		AlertLog:   model.AlertLog{ID: 7, AlertType: "offline", Message: `frpc "web" 已离线`, CurrentValue: 2048},
+		TargetName: "web",
+		Timestamp:  1700000000,
+		Payload:    map[string]interface{}{"alert_type": "offline"},
+	}
+
+	tests := []struct {
+		name        string
+		tpl         string
+		body        string
+		contentType string
+	}{
+		{"空模板使用默认JSON", "", `{"alert_type":"offline"}`, "application/json"},
+		{"JSON模板", `{"text":{{json .Message}},"id":{{.ID}},"ts":{{.Timestamp}}}`,
+			`{"text":"frpc \"web\" 已离线","id":7,"ts":1700000000}`, "application/json"},
+		{"文本模板", `{{.TargetName}}: {{formatBytes .CurrentValue}}`, "web: 2.00 KB", "text/plain; charset=utf-8"},
+		{"输出默认请求体", `{{json .Payload}}`, `{"alert_type":"offline"}`, "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType, err := RenderWebhookPayload(tt.tpl, data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, body)
+			assert.Equal(t, tt.contentType, contentType)
+		})
+	}
+
+	assert.NoError(t, ValidateWebhookTemplate(""))
+	assert.Error(t, ValidateWebhookTemplate("{{.Message"))
+	assert.Error(t, ValidateWebhookTemplate("{{unknownFunc .Message}}"))
+}
+
+// TestWebhookBackoff 测试指数退避间隔
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, 60*time.Second, webhookBackoff(2))
+	assert.Equal(t, 4*time.Minute, webhookBackoff(4))
+	assert.Equal(t, time.Hour, webhookBackoff(8))
+	assert.Equal(t, time.Hour, webhookBackoff(100))
+}
+
+// TestWebhookDeliveryService_SignAndRetry 测试签名头、失败重试及投递成功后标记已通知
+func TestWebhookDeliveryService_SignAndRetry(t *testing.T) {
+	setupWebhookTestDB(t)
+	settingRepo := repository.NewSettingRepository()
+	require.NoError(t, settingRepo.UpdateSetting("webhook_signing_secret", "s3cret"))
+	require.NoError(t, settingRepo.UpdateSetting("webhook_max_attempts", "6"))
+	srv, recv := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	rule, alert := createWebhookTestAlert(t)
+	alertRepo := repository.NewAlertRepo(database.DB)
+	svc := NewWebhookDeliveryService(alertRepo)
+
+	delivery, err := svc.Enqueue(srv.URL, rule, alert, "web", map[string]interface{}{"message": alert.Message})
+	require.NoError(t, err)
+	assert.Equal(t, model.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+	assert.WithinDuration(t, time.Now().Add(webhookBackoffBase), delivery.NextAttemptAt, 5*time.Second)
+
+	req := recv.requests[0]
+	assert.Equal(t, "offline", req.Header.Get(WebhookHeaderEvent))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, SignWebhookPayload("s3cret", req.Header.Get(WebhookHeaderTimestamp), recv.bodies[0]),
+		req.Header.Get(WebhookHeaderSignature))
+
+	var stored model.AlertLog
+	require.NoError(t, database.DB.First(&stored, alert.ID).Error)
+	assert.False(t, stored.Notified, "投递成功前不应标记已通知")
+
+	// 未到重试时间不投递
+	svc.ProcessDue()
+	assert.Len(t, recv.requests, 1)
+
+	require.NoError(t, database.DB.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	svc.ProcessDue()
+	assert.Len(t, recv.requests, 2)
+	assert.Equal(t, recv.bodies[0], recv.bodies[1], "重试使用相同请求体")
+
+	updated, err := repository.NewWebhookDeliveryRepository().FindByID(delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WebhookDeliverySuccess, updated.Status)
+	assert.Equal(t, 2, updated.Attempts)
+	assert.NotNil(t, updated.DeliveredAt)
+
+	require.NoError(t, database.DB.First(&stored, alert.ID).Error)
+	assert.True(t, stored.Notified)
+
+	attempts, err := svc.GetAttempts(delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.False(t, attempts[0].Success)
+	assert.Equal(t, "HTTP 500", attempts[0].Error)
+	assert.True(t, attempts[1].Success)
+	assert.Equal(t, "ok", attempts[1].ResponseBody)
+}
+
+// TestWebhookDeliveryService_ExhaustAndRedeliver 测试重试次数用尽及手动重新投递
+func TestWebhookDeliveryService_ExhaustAndRedeliver(t *testing.T) {
+	setupWebhookTestDB(t)
+	settingRepo := repository.NewSettingRepository()
+	require.NoError(t, settingRepo.UpdateSetting("webhook_signing_secret", ""))
+	require.NoError(t, settingRepo.UpdateSetting("webhook_max_attempts", "1"))
+	srv, recv := newWebhookReceiver(t, http.StatusBadGateway, http.StatusNoContent)
+	rule, alert := createWebhookTestAlert(t)
+	rule.WebhookTemplate = "{{.TargetName}} {{.Message}}"
+	svc := NewWebhookDeliveryService(repository.NewAlertRepo(database.DB))
+
+	delivery, err := svc.Enqueue(srv.URL, rule, alert, "web", nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.WebhookDeliveryFailed, delivery.Status)
+	assert.Empty(t, recv.requests[0].Header.Get(WebhookHeaderSignature), "未配置密钥时不签名")
+	assert.Equal(t, "web frpc web 已离线", recv.bodies[0])
+
+	failed, err := svc.GetDeliveries(model.WebhookDeliveryFailed, alert.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+
+	redelivered, err := svc.Redeliver(delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WebhookDeliverySuccess, redelivered.Status)
+	assert.Equal(t, 1, redelivered.Attempts)
+
+	attempts, err := svc.GetAttempts(delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.True(t, attempts[1].Manual)
+
+	_, err = svc.Redeliver(9999)
+	assert.Error(t, err)
+}
+
+// TestWebhookDeliveryService_Cleanup 测试只清理过期且已结束的投递记录
+func TestWebhookDeliveryService_Cleanup(t *testing.T) {
+	setupWebhookTestDB(t)
+	old := time.Now().AddDate(0, 0, -40)
+	rows := []model.WebhookDelivery{
+		{URL: "http://a", Status: model.WebhookDeliverySuccess, CreatedAt: old},
+		{URL: "http://b", Status: model.WebhookDeliveryPending, CreatedAt: old},
+		{URL: "http://c", Status: model.WebhookDeliveryFailed},
+	}
+	require.NoError(t, database.DB.Create(&rows).Error)
+	require.NoError(t, database.DB.Create(&model.WebhookDeliveryAttempt{DeliveryID: rows[0].ID}).Error)
+
+	NewWebhookDeliveryService(nil).Cleanup()
+
+	var urls []string
+	database.DB.Model(&model.WebhookDelivery{}).Order("id").Pluck("url", &urls)
+	assert.Equal(t, []string{"http://b", "http://c"}, urls)
+	var attempts int64
+	database.DB.Model(&model.WebhookDeliveryAttempt{}).Count(&attempts)
+	assert.Zero(t, attempts)
+}
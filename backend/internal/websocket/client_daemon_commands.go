@@ -1,181 +1,120 @@
 package websocket
 
 import (
-	"encoding/json"
 	"fmt"
 	"frp-web-panel/internal/logger"
 	"time"
 )
 
-// PushConfigUpdate 推送配置更新到指定客户端
-func (h *ClientDaemonHub) PushConfigUpdate(clientID uint, config string, version int) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
+// ConfigApplyTimeout 等待守护进程应用配置的超时时间，包含 frpc 热重载或重启
+const ConfigApplyTimeout = 60 * time.Second
 
-	if !exists {
-		return fmt.Errorf("客户端 %d 未连接", clientID)
-	}
-
-	msg := Message{
-		Type: "config_update",
-		Data: map[string]interface{}{
-			"config":  config,
-			"version": version,
-		},
-		Timestamp: time.Now().Unix(),
+// PushConfigUpdate 推送配置更新到指定客户端，等待守护进程应用配置的结果
+// 旧版守护进程不回复确认，结果仍通过 config_sync_result 消息异步返回
+func (h *ClientDaemonHub) PushConfigUpdate(clientID uint, config string, version int) error {
+	result, err := h.SendCommand(clientID, "config_update", map[string]interface{}{
+		"config":  config,
+		"version": version,
+	}, ConfigApplyTimeout)
+	if err != nil {
+		return err
 	}
-
-	data, _ := json.Marshal(msg)
-	select {
-	case conn.Send <- data:
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满")
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("客户端应用配置失败: %v", err)
 	}
+	logger.Debugf("[ClientDaemonHub] 已向客户端 %d 推送配置: version=%d, request_id=%s", clientID, version, result.RequestID)
+	return nil
 }
 
-// SendUpdateCommand 向指定客户端发送更新命令
+// SendUpdateCommand 向指定客户端发送更新命令，支持确认的守护进程等待其确认接收
 func (h *ClientDaemonHub) SendUpdateCommand(clientID uint, updateType string, version string, downloadURL string, mirrorID uint) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
-
-	if !exists {
+	if !h.IsClientOnline(clientID) {
 		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法发送更新命令", clientID)
 		return fmt.Errorf("客户端 %d 未连接", clientID)
 	}
 
-	msg := Message{
-		Type:     "update",
-		ClientID: clientID,
-		Data: map[string]interface{}{
-			"update_type":  updateType,
-			"version":      version,
-			"download_url": downloadURL,
-			"mirror_id":    mirrorID,
-		},
-		Timestamp: time.Now().Unix(),
-	}
-
-	data, err := json.Marshal(msg)
+	result, err := h.SendCommand(clientID, "update", map[string]interface{}{
+		"update_type":  updateType,
+		"version":      version,
+		"download_url": downloadURL,
+		"mirror_id":    mirrorID,
+	}, DefaultCommandTimeout)
 	if err != nil {
-		return fmt.Errorf("序列化更新命令失败: %v", err)
+		return fmt.Errorf("发送更新命令失败: %v", err)
 	}
-
-	select {
-	case conn.Send <- data:
-		logger.Infof("[ClientDaemonHub] 已向客户端 %d 发送更新命令: type=%s, version=%s", clientID, updateType, version)
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满，无法发送更新命令")
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("客户端拒绝更新命令: %v", err)
 	}
+
+	logger.Infof("[ClientDaemonHub] 客户端 %d 已接收更新命令: type=%s, version=%s", clientID, updateType, version)
+	return nil
 }
 
-// PushCertSync 推送证书同步到指定客户端
+// PushCertSync 推送证书同步到指定客户端，支持确认的守护进程等待其保存结果
 func (h *ClientDaemonHub) PushCertSync(clientID uint, domain string, certPEM string, keyPEM string) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
-
-	if !exists {
+	if !h.IsClientOnline(clientID) {
 		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法推送证书", clientID)
 		return fmt.Errorf("客户端 %d 未连接", clientID)
 	}
 
-	msg := Message{
-		Type:     "cert_sync",
-		ClientID: clientID,
-		Data: map[string]interface{}{
-			"domain":   domain,
-			"cert_pem": certPEM,
-			"key_pem":  keyPEM,
-		},
-		Timestamp: time.Now().Unix(),
-	}
-
-	data, err := json.Marshal(msg)
+	result, err := h.SendCommand(clientID, "cert_sync", map[string]interface{}{
+		"domain":   domain,
+		"cert_pem": certPEM,
+		"key_pem":  keyPEM,
+	}, DefaultCommandTimeout)
 	if err != nil {
-		return fmt.Errorf("序列化证书同步消息失败: %v", err)
+		return fmt.Errorf("推送证书失败: %v", err)
 	}
-
-	select {
-	case conn.Send <- data:
-		logger.Infof("[ClientDaemonHub] 已向客户端 %d 推送证书: domain=%s", clientID, domain)
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满，无法推送证书")
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("客户端保存证书失败: %v", err)
 	}
+
+	logger.Infof("[ClientDaemonHub] 客户端 %d 已保存证书: domain=%s", clientID, domain)
+	return nil
 }
 
-// PushCertDelete 推送证书删除命令到指定客户端
+// PushCertDelete 推送证书删除命令到指定客户端，支持确认的守护进程等待其删除结果
 func (h *ClientDaemonHub) PushCertDelete(clientID uint, domain string) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
-
-	if !exists {
+	if !h.IsClientOnline(clientID) {
 		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法推送证书删除", clientID)
 		return fmt.Errorf("客户端 %d 未连接", clientID)
 	}
 
-	msg := Message{
-		Type:     "cert_delete",
-		ClientID: clientID,
-		Data: map[string]interface{}{
-			"domain": domain,
-		},
-		Timestamp: time.Now().Unix(),
-	}
-
-	data, err := json.Marshal(msg)
+	result, err := h.SendCommand(clientID, "cert_delete", map[string]interface{}{
+		"domain": domain,
+	}, DefaultCommandTimeout)
 	if err != nil {
-		return fmt.Errorf("序列化证书删除消息失败: %v", err)
+		return fmt.Errorf("推送证书删除失败: %v", err)
 	}
-
-	select {
-	case conn.Send <- data:
-		logger.Infof("[ClientDaemonHub] 已向客户端 %d 推送证书删除: domain=%s", clientID, domain)
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满，无法推送证书删除")
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("客户端删除证书失败: %v", err)
 	}
+
+	logger.Infof("[ClientDaemonHub] 客户端 %d 已删除证书: domain=%s", clientID, domain)
+	return nil
 }
 
-// SendLogStreamCommand 向指定客户端发送日志流命令
+// SendLogStreamCommand 向指定客户端发送日志流命令，支持确认的守护进程等待其执行结果
 func (h *ClientDaemonHub) SendLogStreamCommand(clientID uint, logType string, action string, lines int) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
-
-	if !exists {
+	if !h.IsClientOnline(clientID) {
 		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法发送日志流命令", clientID)
 		return fmt.Errorf("客户端 %d 未连接", clientID)
 	}
 
-	msg := Message{
-		Type:     "log_stream",
-		ClientID: clientID,
-		Data: map[string]interface{}{
-			"log_type": logType,
-			"action":   action,
-			"lines":    lines,
-		},
-		Timestamp: time.Now().Unix(),
-	}
-
-	data, err := json.Marshal(msg)
+	result, err := h.SendCommand(clientID, "log_stream", map[string]interface{}{
+		"log_type": logType,
+		"action":   action,
+		"lines":    lines,
+	}, DefaultCommandTimeout)
 	if err != nil {
-		return fmt.Errorf("序列化日志流命令失败: %v", err)
+		return err
 	}
-
-	select {
-	case conn.Send <- data:
-		logger.Infof("[ClientDaemonHub] 已向客户端 %d 发送日志流命令: type=%s, action=%s", clientID, logType, action)
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满，无法发送日志流命令")
+	if err := commandError(result); err != nil {
+		return err
 	}
+
+	logger.Infof("[ClientDaemonHub] 客户端 %d 已执行日志流命令: type=%s, action=%s", clientID, logType, action)
+	return nil
 }
 
 // FrpcControlResult frpc控制结果
@@ -184,121 +123,100 @@ type FrpcControlResult struct {
 	Message string
 }
 
-// SendFrpcControlCommand 向指定客户端发送frpc控制命令
+// SendFrpcControlCommand 向指定客户端发送frpc控制命令，不等待结果
 func (h *ClientDaemonHub) SendFrpcControlCommand(clientID uint, action string) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
-
-	if !exists {
-		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法发送frpc控制命令", clientID)
-		return fmt.Errorf("客户端 %d 未连接", clientID)
-	}
-
-	msg := Message{
+	msg := &Message{
 		Type:     "frpc_control",
 		ClientID: clientID,
 		Data: map[string]interface{}{
 			"action": action,
 		},
-		Timestamp: time.Now().Unix(),
 	}
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("序列化frpc控制命令失败: %v", err)
-	}
-
-	select {
-	case conn.Send <- data:
-		logger.Infof("[ClientDaemonHub] 已向客户端 %d 发送frpc控制命令: action=%s", clientID, action)
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满，无法发送frpc控制命令")
+	if _, err := h.sendMessage(clientID, msg); err != nil {
+		logger.Warnf("[ClientDaemonHub] 向客户端 %d 发送frpc控制命令失败: %v", clientID, err)
+		return err
 	}
+	logger.Infof("[ClientDaemonHub] 已向客户端 %d 发送frpc控制命令: action=%s", clientID, action)
+	return nil
 }
 
 // SendFrpcControlCommandAndWait 发送frpc控制命令并等待结果
 func (h *ClientDaemonHub) SendFrpcControlCommandAndWait(clientID uint, action string, timeout time.Duration) (*FrpcControlResult, error) {
-	// 创建结果通道
-	resultChan := make(chan *FrpcControlResult, 1)
-	key := fmt.Sprintf("%d_%s_%d", clientID, action, time.Now().UnixNano())
-
-	// 注册等待
-	h.mu.Lock()
-	if h.frpcControlWaiters == nil {
-		h.frpcControlWaiters = make(map[uint]chan *FrpcControlResult)
+	if !h.IsClientOnline(clientID) {
+		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法发送frpc控制命令", clientID)
+		return nil, fmt.Errorf("客户端 %d 未连接", clientID)
 	}
-	h.frpcControlWaiters[clientID] = resultChan
-	h.mu.Unlock()
-
-	// 清理函数
-	defer func() {
-		h.mu.Lock()
-		delete(h.frpcControlWaiters, clientID)
-		h.mu.Unlock()
-	}()
 
-	// 发送命令
-	if err := h.SendFrpcControlCommand(clientID, action); err != nil {
+	// 旧版守护进程不回复 ack，等待其 frpc_control_result 消息
+	data := map[string]interface{}{"action": action}
+	result, err := h.sendAndWait(clientID, "frpc_control", data, timeout, !h.supportsAck(clientID))
+	if err != nil {
 		return nil, err
 	}
 
-	logger.Debugf("[ClientDaemonHub] 等待客户端 %d 的frpc控制结果: key=%s", clientID, key)
+	return &FrpcControlResult{Success: result.Success, Message: result.Message}, nil
+}
 
-	// 等待结果或超时
-	select {
-	case result := <-resultChan:
-		logger.Debugf("[ClientDaemonHub] 收到客户端 %d 的frpc控制结果: success=%v", clientID, result.Success)
-		return result, nil
-	case <-time.After(timeout):
-		return nil, fmt.Errorf("等待frpc控制结果超时")
+// SendShutdownCommand 向指定客户端发送停止命令
+func (h *ClientDaemonHub) SendShutdownCommand(clientID uint) error {
+	msg := &Message{
+		Type:     "shutdown",
+		ClientID: clientID,
+	}
+
+	if _, err := h.sendMessage(clientID, msg); err != nil {
+		logger.Warnf("[ClientDaemonHub] 向客户端 %d 发送停止命令失败: %v", clientID, err)
+		return err
 	}
+	logger.Infof("[ClientDaemonHub] 已向客户端 %d 发送停止命令", clientID)
+	return nil
 }
 
-// NotifyFrpcControlResult 通知frpc控制结果
-func (h *ClientDaemonHub) NotifyFrpcControlResult(clientID uint, success bool, message string) {
-	h.mu.RLock()
-	resultChan, exists := h.frpcControlWaiters[clientID]
-	h.mu.RUnlock()
+// PushTokenRotate 推送新的守护进程凭证，等待守护进程持久化结果
+func (h *ClientDaemonHub) PushTokenRotate(clientID uint, token string) error {
+	if !h.IsClientOnline(clientID) {
+		return fmt.Errorf("客户端 %d 未连接", clientID)
+	}
+	if !h.supportsAck(clientID) {
+		return fmt.Errorf("客户端 %d 的守护进程版本过旧，请先升级守护进程", clientID)
+	}
 
-	if exists {
-		select {
-		case resultChan <- &FrpcControlResult{Success: success, Message: message}:
-			logger.Debugf("[ClientDaemonHub] 已通知frpc控制结果: clientID=%d, success=%v", clientID, success)
-		default:
-			logger.Warnf("[ClientDaemonHub] 通知frpc控制结果失败，通道已满: clientID=%d", clientID)
-		}
+	result, err := h.SendAndWait(clientID, "token_rotate", map[string]interface{}{
+		"token": token,
+	}, DefaultCommandTimeout)
+	if err != nil {
+		return fmt.Errorf("推送凭证失败: %v", err)
+	}
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("客户端保存凭证失败: %v", err)
 	}
-}
 
-// SendShutdownCommand 向指定客户端发送停止命令
-func (h *ClientDaemonHub) SendShutdownCommand(clientID uint) error {
-	h.mu.RLock()
-	conn, exists := h.clients[clientID]
-	h.mu.RUnlock()
+	logger.Infof("[ClientDaemonHub] 客户端 %d 已更新守护进程凭证", clientID)
+	return nil
+}
 
-	if !exists {
-		logger.Warnf("[ClientDaemonHub] 客户端 %d 未连接，无法发送停止命令", clientID)
+// PushClientCert 推送新的 mTLS 客户端证书，等待守护进程保存结果
+func (h *ClientDaemonHub) PushClientCert(clientID uint, certPEM string, keyPEM string, caPEM string) error {
+	if !h.IsClientOnline(clientID) {
 		return fmt.Errorf("客户端 %d 未连接", clientID)
 	}
-
-	msg := Message{
-		Type:      "shutdown",
-		ClientID:  clientID,
-		Timestamp: time.Now().Unix(),
+	if !h.supportsAck(clientID) {
+		return fmt.Errorf("客户端 %d 的守护进程版本过旧，请先升级守护进程", clientID)
 	}
 
-	data, err := json.Marshal(msg)
+	result, err := h.SendAndWait(clientID, "client_cert", map[string]interface{}{
+		"cert_pem": certPEM,
+		"key_pem":  keyPEM,
+		"ca_pem":   caPEM,
+	}, DefaultCommandTimeout)
 	if err != nil {
-		return fmt.Errorf("序列化停止命令失败: %v", err)
+		return fmt.Errorf("推送客户端证书失败: %v", err)
 	}
-
-	select {
-	case conn.Send <- data:
-		logger.Infof("[ClientDaemonHub] 已向客户端 %d 发送停止命令", clientID)
-		return nil
-	default:
-		return fmt.Errorf("发送队列已满，无法发送停止命令")
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("客户端保存证书失败: %v", err)
 	}
+
+	logger.Infof("[ClientDaemonHub] 客户端 %d 已更新 mTLS 证书", clientID)
+	return nil
 }
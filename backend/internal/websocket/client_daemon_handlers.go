@@ -50,6 +50,10 @@ func (h *ClientDaemonHub) HandleUpdateResult(clientID uint, data map[string]inte
 
 // HandleVersionReport 处理版本上报消息
 func (h *ClientDaemonHub) HandleVersionReport(clientID uint, data map[string]interface{}) {
+	if protocol, ok := data["protocol"].(float64); ok {
+		h.SetDaemonProtocol(clientID, int(protocol))
+	}
+
 	h.mu.RLock()
 	callback := h.versionReportCallback
 	h.mu.RUnlock()
@@ -98,11 +102,8 @@ func (h *ClientDaemonHub) HandleFrpcControlResult(clientID uint, data map[string
 	message, _ := data["message"].(string)
 
 	logger.Debugf("[ClientDaemonHub] 处理frpc控制结果: clientID=%d, action=%s, success=%v", clientID, action, success)
+	h.resolveLegacyRequest(clientID, "frpc_control", success, message)
 
-	// 通知等待的请求
-	h.NotifyFrpcControlResult(clientID, success, message)
-
-	// 同时调用回调（如果设置了）
 	h.mu.RLock()
 	callback := h.frpcControlResultCallback
 	h.mu.RUnlock()
@@ -21,7 +21,8 @@ type ClientDaemonHub struct {
 	logDataCallback           LogDataCallback
 	frpcControlResultCallback FrpcControlResultCallback
 	configSyncResultCallback  ConfigSyncResultCallback
-	frpcControlWaiters        map[uint]chan *FrpcControlResult
+	pendingMu                 sync.Mutex
+	pendingRequests           map[string]*pendingRequest
 }
 
 // DaemonConnection 客户端守护程序连接
@@ -30,8 +31,12 @@ type DaemonConnection struct {
 	Conn     *websocket.Conn
 	Send     chan []byte
 	Hub      *ClientDaemonHub
+	Protocol int // 守护进程上报的协议版本，旧版守护进程为 0
 }
 
+// DaemonProtocolAck 支持命令确认的守护进程协议版本，低于该版本的守护进程不回复 ack
+const DaemonProtocolAck = 1
+
 var ClientDaemonHubInstance *ClientDaemonHub
 
 func init() {
@@ -41,9 +46,10 @@ func init() {
 
 func NewClientDaemonHub() *ClientDaemonHub {
 	return &ClientDaemonHub{
-		clients:    make(map[uint]*DaemonConnection),
-		Register:   make(chan *DaemonConnection),
-		Unregister: make(chan *DaemonConnection),
+		clients:         make(map[uint]*DaemonConnection),
+		Register:        make(chan *DaemonConnection),
+		Unregister:      make(chan *DaemonConnection),
+		pendingRequests: make(map[string]*pendingRequest),
 	}
 }
 
@@ -125,17 +131,27 @@ func (h *ClientDaemonHub) Run() {
 
 		case conn := <-h.Unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[conn.ClientID]; ok {
-				delete(h.clients, conn.ClientID)
-				close(conn.Send)
-				logger.Infof("[ClientDaemonHub] 客户端 %d 已断开", conn.ClientID)
-				callback := h.statusCallback
+			current, ok := h.clients[conn.ClientID]
+			if !ok {
 				h.mu.Unlock()
-				if callback != nil {
-					go callback(conn.ClientID, false)
-				}
-			} else {
+				h.failPendingRequests(conn, "客户端连接已断开")
+				continue
+			}
+			close(conn.Send)
+			if current != conn {
+				// 守护进程已重连，旧连接注销时不影响新连接
 				h.mu.Unlock()
+				h.failPendingRequests(conn, "客户端连接已断开")
+				logger.Debugf("[ClientDaemonHub] 客户端 %d 的旧连接已关闭", conn.ClientID)
+				continue
+			}
+			delete(h.clients, conn.ClientID)
+			logger.Infof("[ClientDaemonHub] 客户端 %d 已断开", conn.ClientID)
+			callback := h.statusCallback
+			h.mu.Unlock()
+			h.failPendingRequests(conn, "客户端连接已断开")
+			if callback != nil {
+				go callback(conn.ClientID, false)
 			}
 		}
 	}
@@ -149,6 +165,23 @@ func (h *ClientDaemonHub) IsClientOnline(clientID uint) bool {
 	return exists
 }
 
+// SetDaemonProtocol 记录客户端当前连接的守护进程协议版本
+func (h *ClientDaemonHub) SetDaemonProtocol(clientID uint, protocol int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if conn, exists := h.clients[clientID]; exists {
+		conn.Protocol = protocol
+	}
+}
+
+// supportsAck 客户端当前连接的守护进程是否会回复命令确认
+func (h *ClientDaemonHub) supportsAck(clientID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	conn, exists := h.clients[clientID]
+	return exists && conn.Protocol >= DaemonProtocolAck
+}
+
 // GetOnlineCount 获取在线客户端数量
 func (h *ClientDaemonHub) GetOnlineCount() int {
 	h.mu.RLock()
@@ -167,6 +200,22 @@ func (h *ClientDaemonHub) GetOnlineClientIDs() []uint {
 	return ids
 }
 
+// DisconnectClient 主动断开指定客户端的连接，返回客户端此前是否在线
+func (h *ClientDaemonHub) DisconnectClient(clientID uint) bool {
+	h.mu.RLock()
+	conn, exists := h.clients[clientID]
+	h.mu.RUnlock()
+
+	if !exists {
+		return false
+	}
+
+	// 关闭底层连接后 ReadPump 会退出并完成注销
+	conn.Conn.Close()
+	logger.Infof("[ClientDaemonHub] 已主动断开客户端 %d 的连接", clientID)
+	return true
+}
+
 // WritePump 处理发送消息
 func (dc *DaemonConnection) WritePump() {
 	ticker := time.NewTicker(30 * time.Second)
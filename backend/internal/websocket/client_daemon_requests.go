@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"frp-web-panel/internal/logger"
+	"time"
+)
+
+// DefaultCommandTimeout 等待守护进程确认的默认超时时间
+const DefaultCommandTimeout = 15 * time.Second
+
+// MessageTypeAck 守护进程对命令的通用确认消息类型
+const MessageTypeAck = "ack"
+
+// CommandResult 守护进程返回的命令执行结果
+type CommandResult struct {
+	RequestID string
+	Type      string
+	Success   bool
+	Message   string
+	Data      map[string]interface{}
+}
+
+// pendingRequest 等待确认的请求
+type pendingRequest struct {
+	clientID uint
+	conn     *DaemonConnection // 发送命令的连接，守护进程重连后旧连接的断开不影响新连接上的请求
+	msgType  string
+	legacy   bool // 旧版守护进程不回复 ack，由对应的结果消息按客户端和类型匹配
+	result   chan *CommandResult
+}
+
+// newRequestID 生成请求ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// connection 获取客户端当前的连接
+func (h *ClientDaemonHub) connection(clientID uint) (*DaemonConnection, error) {
+	h.mu.RLock()
+	conn, exists := h.clients[clientID]
+	h.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("客户端 %d 未连接", clientID)
+	}
+	return conn, nil
+}
+
+// sendMessage 为消息分配请求ID并写入发送队列，返回请求ID
+func (h *ClientDaemonHub) sendMessage(clientID uint, msg *Message) (string, error) {
+	conn, err := h.connection(clientID)
+	if err != nil {
+		return "", err
+	}
+	return sendToConnection(conn, msg)
+}
+
+// sendToConnection 将消息写入指定连接的发送队列
+func sendToConnection(conn *DaemonConnection, msg *Message) (string, error) {
+	if msg.RequestID == "" {
+		msg.RequestID = newRequestID()
+	}
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().Unix()
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("序列化消息失败: %v", err)
+	}
+
+	select {
+	case conn.Send <- data:
+		return msg.RequestID, nil
+	default:
+		return "", fmt.Errorf("发送队列已满")
+	}
+}
+
+// SendCommand 发送命令，守护进程支持确认时等待执行结果，旧版守护进程只投递不等待
+func (h *ClientDaemonHub) SendCommand(clientID uint, msgType string, data map[string]interface{}, timeout time.Duration) (*CommandResult, error) {
+	if h.supportsAck(clientID) {
+		return h.SendAndWait(clientID, msgType, data, timeout)
+	}
+
+	requestID, err := h.sendMessage(clientID, &Message{Type: msgType, ClientID: clientID, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("[ClientDaemonHub] 客户端 %d 的守护进程不支持确认，命令已投递: type=%s, request_id=%s", clientID, msgType, requestID)
+	return &CommandResult{RequestID: requestID, Type: msgType, Success: true}, nil
+}
+
+// SendAndWait 发送命令并同步等待守护进程的确认结果
+func (h *ClientDaemonHub) SendAndWait(clientID uint, msgType string, data map[string]interface{}, timeout time.Duration) (*CommandResult, error) {
+	return h.sendAndWait(clientID, msgType, data, timeout, false)
+}
+
+// sendAndWait 注册等待者后发送命令，legacy 为 true 时等待旧版守护进程的结果消息而非 ack
+func (h *ClientDaemonHub) sendAndWait(clientID uint, msgType string, data map[string]interface{}, timeout time.Duration, legacy bool) (*CommandResult, error) {
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	conn, err := h.connection(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	requestID := newRequestID()
+	pending := &pendingRequest{
+		clientID: clientID,
+		conn:     conn,
+		msgType:  msgType,
+		legacy:   legacy,
+		result:   make(chan *CommandResult, 1),
+	}
+
+	// 先注册再发送，避免确认消息先于注册到达
+	h.pendingMu.Lock()
+	h.pendingRequests[requestID] = pending
+	h.pendingMu.Unlock()
+
+	defer func() {
+		h.pendingMu.Lock()
+		delete(h.pendingRequests, requestID)
+		h.pendingMu.Unlock()
+	}()
+
+	msg := &Message{
+		Type:      msgType,
+		RequestID: requestID,
+		ClientID:  clientID,
+		Data:      data,
+	}
+	if _, err := sendToConnection(conn, msg); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("[ClientDaemonHub] 等待客户端 %d 的确认: type=%s, request_id=%s", clientID, msgType, requestID)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-pending.result:
+		logger.Debugf("[ClientDaemonHub] 收到客户端 %d 的确认: type=%s, request_id=%s, success=%v", clientID, msgType, requestID, result.Success)
+		return result, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("等待客户端 %d 响应超时: type=%s", clientID, msgType)
+	}
+}
+
+// HandleAck 处理守护进程返回的确认消息
+func (h *ClientDaemonHub) HandleAck(clientID uint, msg *Message) {
+	if msg.RequestID == "" {
+		logger.Warnf("[ClientDaemonHub] 客户端 %d 的确认消息缺少 request_id，忽略", clientID)
+		return
+	}
+
+	result := &CommandResult{RequestID: msg.RequestID, Data: msg.Data}
+	if msg.Data != nil {
+		result.Type, _ = msg.Data["type"].(string)
+		result.Success, _ = msg.Data["success"].(bool)
+		result.Message, _ = msg.Data["message"].(string)
+	}
+
+	h.resolvePendingRequest(clientID, result)
+}
+
+// resolvePendingRequest 将结果交给等待中的请求
+func (h *ClientDaemonHub) resolvePendingRequest(clientID uint, result *CommandResult) {
+	h.pendingMu.Lock()
+	pending, exists := h.pendingRequests[result.RequestID]
+	if exists && pending.clientID == clientID {
+		delete(h.pendingRequests, result.RequestID)
+	}
+	h.pendingMu.Unlock()
+
+	if !exists {
+		logger.Debugf("[ClientDaemonHub] 客户端 %d 的确认没有等待者: request_id=%s", clientID, result.RequestID)
+		return
+	}
+	if pending.clientID != clientID {
+		logger.Warnf("[ClientDaemonHub] 确认消息来源不匹配: request_id=%s, 期望客户端=%d, 实际客户端=%d", result.RequestID, pending.clientID, clientID)
+		return
+	}
+
+	if result.Type == "" {
+		result.Type = pending.msgType
+	}
+	pending.result <- result
+}
+
+// resolveLegacyRequest 将旧版守护进程不带 request_id 的结果消息交给该客户端同类型的等待者
+func (h *ClientDaemonHub) resolveLegacyRequest(clientID uint, msgType string, success bool, message string) {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+
+	for requestID, pending := range h.pendingRequests {
+		if !pending.legacy || pending.clientID != clientID || pending.msgType != msgType {
+			continue
+		}
+		delete(h.pendingRequests, requestID)
+		pending.result <- &CommandResult{RequestID: requestID, Type: msgType, Success: success, Message: message}
+		return
+	}
+}
+
+// failPendingRequests 连接断开时让通过该连接发送的等待中请求立即失败
+func (h *ClientDaemonHub) failPendingRequests(conn *DaemonConnection, reason string) {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+
+	for requestID, pending := range h.pendingRequests {
+		if pending.conn != conn {
+			continue
+		}
+		delete(h.pendingRequests, requestID)
+		pending.result <- &CommandResult{
+			RequestID: requestID,
+			Type:      pending.msgType,
+			Success:   false,
+			Message:   reason,
+		}
+	}
+}
+
+// commandError 将失败的命令结果转换为错误
+func commandError(result *CommandResult) error {
+	if result.Success {
+		return nil
+	}
+	if result.Message == "" {
+		return fmt.Errorf("客户端执行 %s 失败", result.Type)
+	}
+	return fmt.Errorf("%s", result.Message)
+}
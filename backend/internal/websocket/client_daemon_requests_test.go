@@ -0,0 +1,168 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHub 创建带有一个支持确认的模拟连接的 Hub，返回连接的发送队列
+func newTestHub(clientID uint) (*ClientDaemonHub, chan []byte) {
+	hub := NewClientDaemonHub()
+	send := make(chan []byte, 8)
+	hub.clients[clientID] = &DaemonConnection{ClientID: clientID, Send: send, Hub: hub, Protocol: DaemonProtocolAck}
+	return hub, send
+}
+
+// ackFromSendQueue 读取发送队列中的消息并模拟守护进程回复确认
+func ackFromSendQueue(t *testing.T, hub *ClientDaemonHub, send chan []byte, clientID uint, success bool, message string) {
+	raw := <-send
+	var msg Message
+	if !assert.NoError(t, json.Unmarshal(raw, &msg)) {
+		return
+	}
+	assert.NotEmpty(t, msg.RequestID, "命令消息应该携带 request_id")
+
+	hub.HandleAck(clientID, &Message{
+		Type:      MessageTypeAck,
+		RequestID: msg.RequestID,
+		Data: map[string]interface{}{
+			"type":    msg.Type,
+			"success": success,
+			"message": message,
+		},
+	})
+}
+
+// TestSendAndWait_Success 测试收到成功确认
+func TestSendAndWait_Success(t *testing.T) {
+	hub, send := newTestHub(1)
+	go ackFromSendQueue(t, hub, send, 1, true, "ok")
+
+	result, err := hub.SendAndWait(1, "cert_sync", map[string]interface{}{"domain": "a.com"}, time.Second)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "ok", result.Message)
+	assert.Equal(t, "cert_sync", result.Type)
+	assert.Empty(t, hub.pendingRequests, "完成后应该清理等待队列")
+}
+
+// TestSendAndWait_Failure 测试收到失败确认
+func TestSendAndWait_Failure(t *testing.T) {
+	hub, send := newTestHub(1)
+	go ackFromSendQueue(t, hub, send, 1, false, "磁盘已满")
+
+	err := hub.PushCertSync(1, "a.com", "cert", "key")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "磁盘已满")
+}
+
+// TestSendAndWait_Timeout 测试等待超时
+func TestSendAndWait_Timeout(t *testing.T) {
+	hub, _ := newTestHub(1)
+
+	_, err := hub.SendAndWait(1, "log_stream", nil, 50*time.Millisecond)
+	require.Error(t, err)
+	assert.Empty(t, hub.pendingRequests, "超时后应该清理等待队列")
+}
+
+// TestSendAndWait_Offline 测试客户端未连接
+func TestSendAndWait_Offline(t *testing.T) {
+	hub := NewClientDaemonHub()
+
+	_, err := hub.SendAndWait(2, "log_stream", nil, time.Second)
+	require.Error(t, err)
+	assert.Empty(t, hub.pendingRequests)
+}
+
+// TestHandleAck_WrongClient 测试其他客户端的确认不会完成请求
+func TestHandleAck_WrongClient(t *testing.T) {
+	hub, send := newTestHub(1)
+	go ackFromSendQueue(t, hub, send, 2, true, "")
+
+	_, err := hub.SendAndWait(1, "cert_delete", nil, 100*time.Millisecond)
+	assert.Error(t, err, "来自其他客户端的确认应该被忽略")
+}
+
+// TestFailPendingRequests 测试断开连接时等待中的请求立即失败
+func TestFailPendingRequests(t *testing.T) {
+	hub, send := newTestHub(1)
+	conn := hub.clients[1]
+	go func() {
+		<-send
+		hub.failPendingRequests(conn, "客户端连接已断开")
+	}()
+
+	result, err := hub.SendAndWait(1, "frpc_control", nil, time.Second)
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "客户端连接已断开", result.Message)
+}
+
+// TestSendCommand_LegacyDaemon 测试旧版守护进程不等待确认
+func TestSendCommand_LegacyDaemon(t *testing.T) {
+	hub, send := newTestHub(1)
+	hub.clients[1].Protocol = 0
+
+	start := time.Now()
+	require.NoError(t, hub.PushCertSync(1, "a.com", "cert", "key"))
+	require.NoError(t, hub.PushConfigUpdate(1, "[common]", 3))
+	assert.Less(t, time.Since(start), time.Second, "旧版守护进程不回复 ack，不应等待超时")
+	assert.Len(t, send, 2)
+	assert.Empty(t, hub.pendingRequests)
+
+	hub.SetDaemonProtocol(1, DaemonProtocolAck)
+	go ackFromSendQueue(t, hub, send, 1, true, "")
+	<-send
+	<-send
+	require.NoError(t, hub.PushConfigUpdate(1, "[common]", 4), "版本上报后等待确认")
+}
+
+// TestPushConfigUpdate_Failure 测试守护进程应用配置失败
+func TestPushConfigUpdate_Failure(t *testing.T) {
+	hub, send := newTestHub(1)
+	go ackFromSendQueue(t, hub, send, 1, false, "配置校验失败")
+
+	err := hub.PushConfigUpdate(1, "[common]", 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "配置校验失败")
+}
+
+// TestSendFrpcControl_LegacyDaemon 测试旧版守护进程通过 frpc_control_result 返回结果
+func TestSendFrpcControl_LegacyDaemon(t *testing.T) {
+	hub, send := newTestHub(1)
+	hub.clients[1].Protocol = 0
+	go func() {
+		<-send
+		hub.HandleFrpcControlResult(1, map[string]interface{}{"action": "restart", "success": true, "message": "已重启"})
+	}()
+
+	result, err := hub.SendFrpcControlCommandAndWait(1, "restart", time.Second)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "已重启", result.Message)
+}
+
+// TestUnregister_StaleConnection 测试守护进程重连后旧连接注销不影响新连接
+func TestUnregister_StaleConnection(t *testing.T) {
+	hub, send := newTestHub(1)
+	stale := &DaemonConnection{ClientID: 1, Send: make(chan []byte, 1), Hub: hub}
+	go hub.Run()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := hub.SendAndWait(1, "cert_sync", nil, time.Second)
+		done <- err
+	}()
+	raw := <-send
+	hub.Unregister <- stale
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(raw, &msg))
+	hub.HandleAck(1, &Message{Type: MessageTypeAck, RequestID: msg.RequestID, Data: map[string]interface{}{"success": true}})
+	require.NoError(t, <-done)
+	assert.True(t, hub.IsClientOnline(1), "旧连接注销不应移除新连接")
+}
@@ -33,6 +33,7 @@ type ConfigSyncResultCallback func(clientID uint, success bool, errorMsg string,
 // Message WebSocket消息结构
 type Message struct {
 	Type      string                 `json:"type"`
+	RequestID string                 `json:"request_id,omitempty"`
 	ClientID  uint                   `json:"client_id,omitempty"`
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Timestamp int64                  `json:"timestamp"`
@@ -42,3 +43,16 @@ type Message struct {
 type MessageHandler interface {
 	HandleMessage(clientID uint, msg *Message)
 }
+
+// 守护进程认证握手消息类型
+const (
+	MessageTypeAuthChallenge = "auth_challenge"
+	MessageTypeAuthResponse  = "auth_response"
+	MessageTypeAuthResult    = "auth_result"
+)
+
+// MessageTypeStateReport 守护进程重连后上报本地状态缓存
+const MessageTypeStateReport = "state_report"
+
+// MessageTypeClientMetrics 守护进程周期上报的主机与 frpc 进程指标
+const MessageTypeClientMetrics = "client_metrics"
@@ -77,6 +77,13 @@ func (h *Hub) RunWithContext(ctx context.Context) {
 	}
 }
 
+// ClientCount 返回当前连接的前端 WebSocket 数量
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
 func (h *Hub) BroadcastMessage(message []byte) {
 	h.broadcast <- message
 }
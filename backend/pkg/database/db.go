@@ -116,12 +116,32 @@ func autoMigrate() error {
 		&model.ClientRegisterToken{},
 		&model.ServerMetricsHistory{},
 		&model.ProxyMetricsHistory{},
+		&model.ClientMetricsHistory{},
+		&model.ServerMetricsRollup{},
+		&model.ProxyMetricsRollup{},
 		&model.AlertRecipient{},
 		&model.AlertRecipientGroup{},
 		&model.AlertGroupRecipient{},
 		&model.DNSProvider{},
 		&model.DNSRecord{},
 		&model.Certificate{},
+		&model.RevokedClientCert{},
+		&model.TrafficQuota{},
+		&model.TrafficQuotaSuspension{},
+		&model.ProxyVisitor{},
+		&model.ProxyAccessPolicy{},
+		&model.ProxyAccessBlock{},
+		&model.ProxyProbe{},
+		&model.ProxyProbeResult{},
+		&model.NotificationChannel{},
+		&model.MessageTemplate{},
+		&model.LogSink{},
+		&model.WebhookDelivery{},
+		&model.WebhookDeliveryAttempt{},
+		&model.AlertSilence{},
+		&model.AlertEscalationPolicy{},
+		&model.AlertEscalationStep{},
+		&model.OnCallSchedule{},
 	)
 }
 
@@ -202,6 +222,141 @@ func createDefaultSettings() error {
 			Value:       "30",
 			Description: "流量采集间隔(秒)",
 		},
+		{
+			Key:         "metrics_retention_raw_days",
+			Value:       "7",
+			Description: "原始指标保留天数",
+		},
+		{
+			Key:         "metrics_retention_5m_days",
+			Value:       "30",
+			Description: "5分钟汇总指标保留天数",
+		},
+		{
+			Key:         "metrics_retention_1h_days",
+			Value:       "180",
+			Description: "小时汇总指标保留天数",
+		},
+		{
+			Key:         "metrics_retention_1d_days",
+			Value:       "730",
+			Description: "天汇总指标保留天数",
+		},
+		{
+			Key:         "metrics_retention_client_days",
+			Value:       "7",
+			Description: "客户端指标保留天数",
+		},
+		{
+			Key:         "traffic_report_enabled",
+			Value:       "false",
+			Description: "是否每月发送流量报表邮件",
+		},
+		{
+			Key:         "traffic_report_recipient_ids",
+			Value:       "",
+			Description: "月度流量报表接收人ID列表(逗号分隔)",
+		},
+		{
+			Key:         "traffic_report_group_ids",
+			Value:       "",
+			Description: "月度流量报表接收分组ID列表(逗号分隔)",
+		},
+		{
+			Key:         "traffic_report_timezone",
+			Value:       "",
+			Description: "月度流量报表时区(IANA 名称，留空使用服务器时区)",
+		},
+		{
+			Key:         "traffic_report_last_month",
+			Value:       "",
+			Description: "最近一次已发送流量报表的月份",
+		},
+		{
+			Key:         "frps_plugin_enabled",
+			Value:       "false",
+			Description: "是否在托管的 frps 配置中启用面板 HTTP 插件，启用后拒绝未在面板登记的客户端和代理(需 frps 能访问面板)",
+		},
+		{
+			Key:         "frps_plugin_url",
+			Value:       "",
+			Description: "frps 插件回调面板的地址(留空使用公网访问地址)",
+		},
+		{
+			Key:         "proxy_visitor_retention_days",
+			Value:       "30",
+			Description: "代理访客记录保留天数",
+		},
+		{
+			Key:         "proxy_visitor_max_per_proxy",
+			Value:       "500",
+			Description: "每个代理最多保留的访客IP数量",
+		},
+		{
+			Key:         "proxy_probe_retention_days",
+			Value:       "30",
+			Description: "代理拨测结果保留天数",
+		},
+		{
+			Key:         "webhook_signing_secret",
+			Value:       "",
+			Description: "告警 Webhook 签名密钥，设置后请求携带 X-Webhook-Timestamp 和 X-Webhook-Signature(HMAC-SHA256) 头",
+		},
+		{
+			Key:         "webhook_max_attempts",
+			Value:       "6",
+			Description: "告警 Webhook 最大投递次数(含首次)，失败后按指数退避重试",
+		},
+		{
+			Key:         "webhook_delivery_retention_days",
+			Value:       "30",
+			Description: "Webhook 投递记录保留天数",
+		},
+		{
+			Key:         "alert_digest_window_seconds",
+			Value:       "60",
+			Description: "告警通知汇总窗口(秒)，同一接收方同一原因(所属 frps 或系统事件类型)的告警在窗口内合并为一条消息，0 表示立即发送",
+		},
+		{
+			Key:         "notify_rate_limit_per_hour",
+			Value:       "30",
+			Description: "每个邮箱或通知渠道每小时最多发送的告警消息数，超出的消息丢弃(渠道可单独设置)，0 表示不限制",
+		},
+		{
+			Key:         "notify_locale",
+			Value:       "zh-CN",
+			Description: "告警通知语言(zh-CN/en-US)，决定内置消息模板及字段名称",
+		},
+		{
+			Key:         "alert_summary_enabled",
+			Value:       "false",
+			Description: "是否每天发送前一天的告警汇总邮件",
+		},
+		{
+			Key:         "alert_summary_hour",
+			Value:       "8",
+			Description: "每日告警汇总邮件的发送时刻(0-23)",
+		},
+		{
+			Key:         "alert_summary_recipient_ids",
+			Value:       "",
+			Description: "每日告警汇总接收人ID列表(逗号分隔)",
+		},
+		{
+			Key:         "alert_summary_group_ids",
+			Value:       "",
+			Description: "每日告警汇总接收分组ID列表(逗号分隔)",
+		},
+		{
+			Key:         "alert_summary_timezone",
+			Value:       "",
+			Description: "每日告警汇总时区(IANA 名称，留空使用服务器时区)",
+		},
+		{
+			Key:         "alert_summary_last_date",
+			Value:       "",
+			Description: "最近一次已发送告警汇总的日期",
+		},
 		{
 			Key:         "server_info_interval",
 			Value:       "5",
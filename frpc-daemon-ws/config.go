@@ -41,6 +41,21 @@ type Config struct {
 	// daemon 自身的 systemctl 服务名称（Linux 系统使用 systemctl 管理 daemon 服务）
 	// 如果配置了此项，daemon 自更新时将使用 systemctl restart 而不是直接启动进程
 	DaemonServiceName string `yaml:"daemon_service_name"`
+
+	// mTLS 客户端证书（由面板签发并通过 WebSocket 下发），默认保存在配置文件目录的 tls 子目录
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// 面板服务端证书的 CA，配置后只信任该 CA 签发的服务端证书
+	ServerCAFile string `yaml:"server_ca_file"`
+
+	// 主机与 frpc 进程指标上报间隔（秒），默认 60，设置为负数时关闭
+	MetricsIntervalSec int `yaml:"metrics_interval_sec"`
+
+	// 本地状态缓存文件，记录已应用的配置版本、证书和最近一次更新结果，默认保存在配置文件目录
+	StateFile string `yaml:"state_file"`
+
+	// 配置文件路径，用于持久化服务端下发的新凭证
+	path string
 }
 
 // ValidateConfig 验证配置必填字段
@@ -72,6 +87,7 @@ func LoadConfig(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	cfg.path = path
 	// 验证必填字段
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("配置验证失败: %w", err)
@@ -96,5 +112,70 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.FrpcAdminPassword == "" {
 		cfg.FrpcAdminPassword = "admin"
 	}
+	// mTLS 证书默认路径
+	tlsDir := filepath.Join(filepath.Dir(path), "tls")
+	if cfg.TLSCertFile == "" {
+		cfg.TLSCertFile = filepath.Join(tlsDir, "client.crt")
+	}
+	if cfg.TLSKeyFile == "" {
+		cfg.TLSKeyFile = filepath.Join(tlsDir, "client.key")
+	}
+	if cfg.MetricsIntervalSec == 0 {
+		cfg.MetricsIntervalSec = 60
+	} else if cfg.MetricsIntervalSec > 0 && cfg.MetricsIntervalSec < 10 {
+		cfg.MetricsIntervalSec = 10
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join(filepath.Dir(path), "daemon-state.json")
+	}
 	return &cfg, nil
 }
+
+// SaveToken 更新凭证并写回配置文件，保留文件中的其他字段
+func (c *Config) SaveToken(token string) error {
+	if c.path == "" {
+		return fmt.Errorf("配置文件路径未知")
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("配置文件格式错误")
+	}
+
+	root := doc.Content[0]
+	updated := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "token" {
+			root.Content[i+1].Value = token
+			root.Content[i+1].Style = yaml.DoubleQuotedStyle
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "token"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: token, Style: yaml.DoubleQuotedStyle},
+		)
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("序列化配置文件失败: %w", err)
+	}
+
+	if err := writeFileAtomic(c.path, out, 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+
+	c.Token = token
+	return nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks /proc 中 CPU 时间的单位（USER_HZ），主流 Linux 发行版均为 100
+const clockTicks = 100
+
+// HostMetrics 主机与 frpc 进程指标
+type HostMetrics struct {
+	CpuPercent       float64
+	MemTotalBytes    int64
+	MemUsedBytes     int64
+	NetRxBytes       int64
+	NetTxBytes       int64
+	NetRxRate        int64
+	NetTxRate        int64
+	FrpcRunning      bool
+	FrpcPID          int
+	FrpcCpuPercent   float64
+	FrpcRssBytes     int64
+	FrpcConnections  int
+	FrpcUptime       int64
+	FrpcRestartCount int
+}
+
+// MetricsSampler 周期采样主机与 frpc 进程指标，CPU 与网络速率根据两次采样的差值计算
+type MetricsSampler struct {
+	frpcMgr *FrpcManager
+
+	lastSample   time.Time
+	lastCPUTotal uint64
+	lastCPUIdle  uint64
+	lastNetRx    int64
+	lastNetTx    int64
+	lastProcCPU  uint64
+	lastPID      int
+	restartCount int
+}
+
+// NewMetricsSampler 创建指标采样器
+func NewMetricsSampler(frpcMgr *FrpcManager) *MetricsSampler {
+	return &MetricsSampler{frpcMgr: frpcMgr}
+}
+
+// Sample 采集一次指标，非 Linux 系统只上报 frpc 运行状态
+func (s *MetricsSampler) Sample() HostMetrics {
+	now := time.Now()
+	elapsed := now.Sub(s.lastSample).Seconds()
+	first := s.lastSample.IsZero()
+	s.lastSample = now
+
+	var m HostMetrics
+	pid := s.frpcMgr.FrpcPID()
+	m.FrpcPID = pid
+	m.FrpcRunning = pid > 0 || s.frpcMgr.IsFrpcAlive()
+
+	// PID 变化说明 frpc 被重启（包括崩溃后由 systemd 拉起）
+	if pid > 0 && s.lastPID > 0 && pid != s.lastPID {
+		s.restartCount++
+	}
+	pidChanged := pid != s.lastPID
+	if pid > 0 {
+		s.lastPID = pid
+	}
+	m.FrpcRestartCount = s.restartCount
+
+	if runtime.GOOS != "linux" {
+		return m
+	}
+
+	if total, idle, err := readProcStat(); err == nil {
+		if !first && total > s.lastCPUTotal {
+			busy := float64((total - s.lastCPUTotal) - (idle - s.lastCPUIdle))
+			m.CpuPercent = busy / float64(total-s.lastCPUTotal) * 100
+		}
+		s.lastCPUTotal, s.lastCPUIdle = total, idle
+	}
+
+	if total, available, err := readMemInfo(); err == nil {
+		m.MemTotalBytes = total
+		m.MemUsedBytes = total - available
+	}
+
+	if rx, tx, err := readNetDev(); err == nil {
+		m.NetRxBytes, m.NetTxBytes = rx, tx
+		if !first && elapsed > 0 && rx >= s.lastNetRx && tx >= s.lastNetTx {
+			m.NetRxRate = int64(float64(rx-s.lastNetRx) / elapsed)
+			m.NetTxRate = int64(float64(tx-s.lastNetTx) / elapsed)
+		}
+		s.lastNetRx, s.lastNetTx = rx, tx
+	}
+
+	if pid > 0 {
+		if cpuTicks, startTicks, err := readProcPIDStat(pid); err == nil {
+			if !first && !pidChanged && elapsed > 0 && cpuTicks >= s.lastProcCPU {
+				m.FrpcCpuPercent = float64(cpuTicks-s.lastProcCPU) / clockTicks / elapsed * 100
+			}
+			s.lastProcCPU = cpuTicks
+			if uptime, err := readSystemUptime(); err == nil {
+				m.FrpcUptime = int64(uptime - float64(startTicks)/clockTicks)
+			}
+		}
+		m.FrpcRssBytes = readProcRSS(pid)
+		m.FrpcConnections = countProcSockets(pid)
+	}
+	return m
+}
+
+// ToMap 转换为消息数据
+func (m HostMetrics) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"cpu_percent":        m.CpuPercent,
+		"mem_total_bytes":    m.MemTotalBytes,
+		"mem_used_bytes":     m.MemUsedBytes,
+		"net_rx_bytes":       m.NetRxBytes,
+		"net_tx_bytes":       m.NetTxBytes,
+		"net_rx_rate":        m.NetRxRate,
+		"net_tx_rate":        m.NetTxRate,
+		"frpc_running":       m.FrpcRunning,
+		"frpc_pid":           m.FrpcPID,
+		"frpc_cpu_percent":   m.FrpcCpuPercent,
+		"frpc_rss_bytes":     m.FrpcRssBytes,
+		"frpc_connections":   m.FrpcConnections,
+		"frpc_uptime":        m.FrpcUptime,
+		"frpc_restart_count": m.FrpcRestartCount,
+	}
+}
+
+// startMetricsReporter 启动指标采样上报协程
+func startMetricsReporter(wsClient *WSClient, frpcMgr *FrpcManager, cfg *Config) {
+	interval := time.Duration(cfg.MetricsIntervalSec) * time.Second
+	sampler := NewMetricsSampler(frpcMgr)
+
+	// 先采样一次作为速率计算的基准
+	sampler.Sample()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("[指标采集] 启动主机指标采集，间隔: %v", interval)
+	for range ticker.C {
+		wsClient.SendClientMetrics(sampler.Sample())
+	}
+}
+
+// readProcStat 读取 /proc/stat 中的 CPU 总时间和空闲时间
+func readProcStat() (total, idle uint64, err error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return 0, 0, fmt.Errorf("/proc/stat 为空")
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("/proc/stat 格式错误")
+	}
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		total += v
+		// idle 与 iowait
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+	return total, idle, nil
+}
+
+// readMemInfo 读取内存总量与可用量（字节）
+func readMemInfo() (total, available int64, err error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseInt(fields[1], 10, 64)
+		switch fields[0] {
+		case "MemTotal:":
+			total = v * 1024
+		case "MemAvailable:":
+			available = v * 1024
+		}
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("/proc/meminfo 缺少 MemTotal")
+	}
+	return total, available, nil
+}
+
+// readNetDev 汇总除回环外所有网卡的累计收发字节数
+func readNetDev() (rx, tx int64, err error) {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		if strings.TrimSpace(line[:idx]) == "lo" {
+			continue
+		}
+		fields := strings.Fields(line[idx+1:])
+		if len(fields) < 9 {
+			continue
+		}
+		r, _ := strconv.ParseInt(fields[0], 10, 64)
+		t, _ := strconv.ParseInt(fields[8], 10, 64)
+		rx += r
+		tx += t
+	}
+	return rx, tx, nil
+}
+
+// readProcPIDStat 读取进程累计 CPU 时间与启动时间（单位 clock ticks）
+func readProcPIDStat(pid int) (cpuTicks, startTicks uint64, err error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// 进程名可能包含空格，从最后一个右括号之后开始解析
+	content := string(data)
+	idx := strings.LastIndex(content, ")")
+	if idx < 0 {
+		return 0, 0, fmt.Errorf("/proc/%d/stat 格式错误", pid)
+	}
+	fields := strings.Fields(content[idx+1:])
+	// fields[0] 为 state（总第3列），utime/stime/starttime 分别为第14/15/22列
+	if len(fields) < 20 {
+		return 0, 0, fmt.Errorf("/proc/%d/stat 字段不足", pid)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	startTicks, _ = strconv.ParseUint(fields[19], 10, 64)
+	return utime + stime, startTicks, nil
+}
+
+// readSystemUptime 读取系统运行时间（秒）
+func readSystemUptime() (float64, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("/proc/uptime 为空")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// readProcRSS 读取进程常驻内存（字节）
+func readProcRSS(pid int) int64 {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "VmRSS:" {
+			v, _ := strconv.ParseInt(fields[1], 10, 64)
+			return v * 1024
+		}
+	}
+	return 0
+}
+
+// countProcSockets 统计进程打开的 socket 数量，作为连接数的近似值
+func countProcSockets(pid int) int {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+		if err == nil && strings.HasPrefix(target, "socket:") {
+			count++
+		}
+	}
+	return count
+}
+
+// FrpcPID 获取 frpc 进程 PID，获取不到返回 0
+func (m *FrpcManager) FrpcPID() int {
+	if runtime.GOOS != "windows" && m.cfg.FrpcServiceName != "" {
+		out, err := exec.Command("systemctl", "show", "-p", "MainPID", "--value", m.cfg.FrpcServiceName).Output()
+		if err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(out))); err == nil && pid > 0 {
+				return pid
+			}
+		}
+		return 0
+	}
+
+	pid, err := m.pidManager.ReadPID()
+	if err != nil || !m.pidManager.IsProcessRunning(pid) {
+		return 0
+	}
+	return pid
+}
@@ -28,6 +28,11 @@ import (
 var BuildTime = "dev"
 
 func main() {
+	// 子命令: frpc-daemon-ws status [-c daemon.yaml] [-json]
+	if len(os.Args) > 1 && os.Args[1] == "status" {
+		os.Exit(runStatusCommand(os.Args[2:]))
+	}
+
 	configPath := flag.String("c", "daemon.yaml", "配置文件路径")
 	flag.Parse()
 
@@ -69,6 +74,12 @@ func main() {
 	// 创建frpc管理器
 	frpcMgr := NewFrpcManager(cfg)
 
+	// 加载本地状态缓存
+	stateStore := LoadStateStore(cfg.StateFile)
+	if state := stateStore.Snapshot(); state.ConfigVersion > 0 {
+		log.Printf("[主程序] 本地状态: 配置版本=%d, 证书数=%d", state.ConfigVersion, len(state.Certs))
+	}
+
 	// 检查 Admin API 是否可用
 	if frpcMgr.IsAdminAPIAvailable() {
 		log.Println("[主程序] ✅ frpc Admin API 可用，将使用热重载方式更新配置")
@@ -81,11 +92,12 @@ func main() {
 
 	// 创建WebSocket客户端
 	var wsClient *WSClient
-	wsClient = NewWSClient(cfg, func(config string, version int) {
+	wsClient = NewWSClient(cfg, func(config string, version int) error {
 		log.Printf("[主程序] 收到配置更新: version=%d", version)
 
 		// 应用配置并获取详细结果
 		result := frpcMgr.ApplyConfigWithResult(config, version)
+		stateStore.RecordConfig(version, config, result)
 
 		// 发送详细的配置同步结果
 		wsClient.SendConfigSyncResult(result)
@@ -94,10 +106,11 @@ func main() {
 		if result.Success {
 			wsClient.SendSyncResult(true, version, "配置已成功应用")
 			log.Printf("[主程序] 配置同步成功: version=%d", version)
-		} else {
-			wsClient.SendSyncResult(false, version, result.Error)
-			log.Printf("[主程序] 应用配置失败: %v", result.Error)
+			return nil
 		}
+		wsClient.SendSyncResult(false, version, result.Error)
+		log.Printf("[主程序] 应用配置失败: %v", result.Error)
+		return fmt.Errorf("%s", result.Error)
 	})
 
 	// 设置停止命令回调
@@ -118,33 +131,37 @@ func main() {
 	})
 
 	// 创建更新器
-	updater := NewUpdater(cfg, frpcMgr, wsClient)
+	updater := NewUpdater(cfg, frpcMgr, wsClient, stateStore)
 	updater.Start()
 
 	// 设置更新命令回调
-	wsClient.SetUpdateCallback(func(updateType string, version string, downloadURL string, mirrorID uint) {
+	wsClient.SetUpdateCallback(func(updateType string, version string, downloadURL string, mirrorID uint) error {
 		log.Printf("[主程序] 收到更新命令: type=%s, version=%s", updateType, version)
-		updater.HandleUpdate(updateType, version, downloadURL, mirrorID)
+		return updater.HandleUpdate(updateType, version, downloadURL, mirrorID)
 	})
 
 	// 设置证书同步回调
-	wsClient.SetCertSyncCallback(func(domain string, certPEM string, keyPEM string) {
+	wsClient.SetCertSyncCallback(func(domain string, certPEM string, keyPEM string) error {
 		log.Printf("[主程序] 收到证书同步: domain=%s", domain)
 		if err := saveCertificate(cfg, domain, certPEM, keyPEM); err != nil {
 			log.Printf("[主程序] ❌ 保存证书失败: %v", err)
-		} else {
-			log.Printf("[主程序] ✅ 证书保存成功: domain=%s", domain)
+			return err
 		}
+		stateStore.RecordCertSynced(domain, certPEM)
+		log.Printf("[主程序] ✅ 证书保存成功: domain=%s", domain)
+		return nil
 	})
 
 	// 设置证书删除回调
-	wsClient.SetCertDeleteCallback(func(domain string) {
+	wsClient.SetCertDeleteCallback(func(domain string) error {
 		log.Printf("[主程序] 收到证书删除: domain=%s", domain)
 		if err := deleteCertificate(cfg, domain); err != nil {
 			log.Printf("[主程序] ❌ 删除证书失败: %v", err)
-		} else {
-			log.Printf("[主程序] ✅ 证书删除成功: domain=%s", domain)
+			return err
 		}
+		stateStore.RecordCertDeleted(domain)
+		log.Printf("[主程序] ✅ 证书删除成功: domain=%s", domain)
+		return nil
 	})
 
 	// 创建日志流管理器
@@ -153,23 +170,26 @@ func main() {
 	})
 
 	// 设置日志流命令回调
-	wsClient.SetLogStreamCallback(func(logType string, action string, lines int) {
+	wsClient.SetLogStreamCallback(func(logType string, action string, lines int) error {
 		log.Printf("[主程序] 收到日志流命令: type=%s, action=%s, lines=%d", logType, action, lines)
 		switch action {
 		case "start":
 			if err := logStreamMgr.StartStream(LogType(logType), lines); err != nil {
 				log.Printf("[主程序] ❌ 启动日志流失败: %v", err)
-			} else {
-				log.Printf("[主程序] ✅ 日志流已启动: type=%s", logType)
+				return err
 			}
+			log.Printf("[主程序] ✅ 日志流已启动: type=%s", logType)
 		case "stop":
 			logStreamMgr.StopStream(LogType(logType))
 			log.Printf("[主程序] ✅ 日志流已停止: type=%s", logType)
+		default:
+			return fmt.Errorf("未知的日志流操作: %s", action)
 		}
+		return nil
 	})
 
 	// 设置frpc控制命令回调
-	wsClient.SetFrpcControlCallback(func(action string) {
+	wsClient.SetFrpcControlCallback(func(action string) (string, error) {
 		log.Printf("[主程序] 收到frpc控制命令: action=%s", action)
 		var err error
 		var message string
@@ -189,10 +209,16 @@ func main() {
 		if err != nil {
 			log.Printf("[主程序] ❌ frpc控制失败: %v", err)
 			wsClient.SendFrpcControlResult(action, false, err.Error())
-		} else {
-			log.Printf("[主程序] ✅ %s", message)
-			wsClient.SendFrpcControlResult(action, true, message)
+			return "", err
 		}
+		log.Printf("[主程序] ✅ %s", message)
+		wsClient.SendFrpcControlResult(action, true, message)
+		return message, nil
+	})
+
+	// 每次重连后上报本地状态，由面板对账补推配置和证书
+	wsClient.SetConnectedCallback(func() {
+		wsClient.SendStateReport(stateStore.Snapshot())
 	})
 
 	// 启动WebSocket客户端
@@ -208,6 +234,11 @@ func main() {
 	// 启动 frpc 健康检查协程
 	go startFrpcHealthChecker(wsClient, frpcMgr, cfg)
 
+	// 启动主机指标采集协程
+	if cfg.MetricsIntervalSec > 0 {
+		go startMetricsReporter(wsClient, frpcMgr, cfg)
+	}
+
 	// 等待中断信号或停止命令
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
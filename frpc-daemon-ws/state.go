@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CertState 本地证书状态
+type CertState struct {
+	Domain   string     `json:"domain"`
+	ExpireAt *time.Time `json:"expire_at"`
+	SyncedAt *time.Time `json:"synced_at"`
+}
+
+// UpdateState 最近一次更新结果
+type UpdateState struct {
+	UpdateType string    `json:"update_type"`
+	Version    string    `json:"version"`
+	Success    bool      `json:"success"`
+	Message    string    `json:"message"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// DaemonState 守护进程本地状态，面板不可用时也能知道当前运行的是哪份配置
+type DaemonState struct {
+	ConfigVersion   int          `json:"config_version"`
+	ConfigHash      string       `json:"config_hash"`
+	ConfigAppliedAt *time.Time   `json:"config_applied_at"`
+	ConfigError     string       `json:"config_error,omitempty"`
+	Certs           []CertState  `json:"certs"`
+	LastUpdate      *UpdateState `json:"last_update,omitempty"`
+	UpdatedAt       *time.Time   `json:"updated_at"`
+}
+
+// StateStore 本地状态缓存，每次变更后立即写入磁盘
+type StateStore struct {
+	path  string
+	mu    sync.Mutex
+	state DaemonState
+}
+
+// LoadStateStore 加载本地状态文件，文件不存在或损坏时从空状态开始
+func LoadStateStore(path string) *StateStore {
+	s := &StateStore{path: path}
+	state, err := ReadStateFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[状态缓存] ⚠️ 读取状态文件失败，将重新记录: %v", err)
+		}
+		return s
+	}
+	s.state = *state
+	return s
+}
+
+// ReadStateFile 读取状态文件
+func ReadStateFile(path string) (*DaemonState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state DaemonState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	return &state, nil
+}
+
+// Snapshot 返回当前状态的副本
+func (s *StateStore) Snapshot() DaemonState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.state
+	state.Certs = append([]CertState(nil), s.state.Certs...)
+	if s.state.LastUpdate != nil {
+		update := *s.state.LastUpdate
+		state.LastUpdate = &update
+	}
+	return state
+}
+
+// RecordConfig 记录配置应用结果，失败时保留上一次成功应用的版本
+func (s *StateStore) RecordConfig(version int, content string, result ConfigSyncResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if result.Success {
+		now := time.Now()
+		s.state.ConfigVersion = version
+		s.state.ConfigHash = configHash(content)
+		s.state.ConfigAppliedAt = &now
+		s.state.ConfigError = ""
+	} else {
+		s.state.ConfigError = fmt.Sprintf("version=%d: %s", version, result.Error)
+	}
+	s.saveLocked()
+}
+
+// RecordCertSynced 记录已保存的证书及其过期时间
+func (s *StateStore) RecordCertSynced(domain string, certPEM string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cert := CertState{Domain: domain, ExpireAt: certExpireAt(certPEM), SyncedAt: &now}
+	replaced := false
+	for i := range s.state.Certs {
+		if s.state.Certs[i].Domain == domain {
+			s.state.Certs[i] = cert
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.state.Certs = append(s.state.Certs, cert)
+		sort.Slice(s.state.Certs, func(i, j int) bool { return s.state.Certs[i].Domain < s.state.Certs[j].Domain })
+	}
+	s.saveLocked()
+}
+
+// RecordCertDeleted 移除已删除的证书
+func (s *StateStore) RecordCertDeleted(domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	certs := s.state.Certs[:0]
+	for _, c := range s.state.Certs {
+		if c.Domain != domain {
+			certs = append(certs, c)
+		}
+	}
+	s.state.Certs = certs
+	s.saveLocked()
+}
+
+// RecordUpdateResult 记录更新结果
+func (s *StateStore) RecordUpdateResult(result UpdateResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.LastUpdate = &UpdateState{
+		UpdateType: string(result.UpdateType),
+		Version:    result.Version,
+		Success:    result.Success,
+		Message:    result.Message,
+		FinishedAt: time.Now(),
+	}
+	s.saveLocked()
+}
+
+// saveLocked 写入状态文件，调用方需持有锁
+func (s *StateStore) saveLocked() {
+	now := time.Now()
+	s.state.UpdatedAt = &now
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		log.Printf("[状态缓存] ❌ 序列化状态失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		log.Printf("[状态缓存] ❌ 创建状态目录失败: %v", err)
+		return
+	}
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		log.Printf("[状态缓存] ❌ 保存状态文件失败: %v", err)
+	}
+}
+
+// ReportData 转换为 state_report 消息数据
+func (st DaemonState) ReportData() map[string]interface{} {
+	data := map[string]interface{}{}
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return data
+	}
+	json.Unmarshal(raw, &data)
+	return data
+}
+
+// configHash 计算配置内容哈希，与面板端算法一致
+func configHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// certExpireAt 解析证书过期时间，解析失败返回 nil
+func certExpireAt(certPEM string) *time.Time {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	expireAt := cert.NotAfter
+	return &expireAt
+}
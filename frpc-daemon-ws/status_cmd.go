@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runStatusCommand 输出本地状态缓存，面板不可用时用于排查客户端当前运行状态
+func runStatusCommand(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	configPath := fs.String("c", "daemon.yaml", "配置文件路径")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 1
+	}
+
+	state, err := ReadStateFile(cfg.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "状态文件不存在: %s（守护进程尚未收到过面板下发的配置）\n", cfg.StateFile)
+		} else {
+			fmt.Fprintf(os.Stderr, "读取状态文件失败: %v\n", err)
+		}
+		return 1
+	}
+
+	// 检查磁盘上的 frpc 配置是否仍是最后一次应用的版本
+	drift := ""
+	if content, err := os.ReadFile(cfg.FrpcConfig); err != nil {
+		drift = fmt.Sprintf("无法读取: %v", err)
+	} else if state.ConfigHash != "" && configHash(string(content)) != state.ConfigHash {
+		drift = "配置文件已在本地被修改"
+	}
+
+	if *asJSON {
+		out := struct {
+			*DaemonState
+			StateFile   string `json:"state_file"`
+			ConfigDrift string `json:"config_drift,omitempty"`
+		}{state, cfg.StateFile, drift}
+		data, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Printf("客户端ID:     %d\n", cfg.ClientID)
+	fmt.Printf("状态文件:     %s\n", cfg.StateFile)
+	fmt.Printf("状态更新时间: %s\n", formatStateTime(state.UpdatedAt))
+	fmt.Println()
+	fmt.Printf("配置版本:     %d\n", state.ConfigVersion)
+	fmt.Printf("配置哈希:     %s\n", state.ConfigHash)
+	fmt.Printf("应用时间:     %s\n", formatStateTime(state.ConfigAppliedAt))
+	if state.ConfigError != "" {
+		fmt.Printf("最近失败:     %s\n", state.ConfigError)
+	}
+	if drift != "" {
+		fmt.Printf("配置文件:     ⚠️ %s (%s)\n", drift, cfg.FrpcConfig)
+	}
+	fmt.Println()
+
+	fmt.Printf("证书 (%d):\n", len(state.Certs))
+	now := time.Now()
+	for _, c := range state.Certs {
+		mark := ""
+		if c.ExpireAt != nil && now.After(*c.ExpireAt) {
+			mark = " ❌ 已过期"
+		}
+		fmt.Printf("  - %s  过期时间: %s%s\n", c.Domain, formatStateTime(c.ExpireAt), mark)
+	}
+	fmt.Println()
+
+	if u := state.LastUpdate; u != nil {
+		result := "成功"
+		if !u.Success {
+			result = "失败"
+		}
+		fmt.Printf("最近更新:     %s %s %s (%s)\n", u.UpdateType, u.Version, result, u.FinishedAt.Local().Format("2006-01-02 15:04:05"))
+		if u.Message != "" {
+			fmt.Printf("更新信息:     %s\n", u.Message)
+		}
+	} else {
+		fmt.Println("最近更新:     无")
+	}
+	return 0
+}
+
+// formatStateTime 格式化可为空的时间
+func formatStateTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
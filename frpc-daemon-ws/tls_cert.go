@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// buildTLSConfig 构建连接面板使用的 TLS 配置: 存在客户端证书时出示，配置了 server_ca_file 时固定信任的服务端 CA
+func buildTLSConfig(cfg *Config) *tls.Config {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err == nil {
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if !os.IsNotExist(err) {
+		log.Printf("[mTLS] ⚠️ 加载客户端证书失败: %v", err)
+	}
+
+	if cfg.ServerCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.ServerCAFile)
+		if err != nil {
+			log.Printf("[mTLS] ⚠️ 读取服务端CA失败: %v", err)
+		} else {
+			pool := x509.NewCertPool()
+			if pool.AppendCertsFromPEM(caPEM) {
+				tlsConfig.RootCAs = pool
+			} else {
+				log.Printf("[mTLS] ⚠️ 服务端CA文件中没有有效证书: %s", cfg.ServerCAFile)
+			}
+		}
+	}
+
+	return tlsConfig
+}
+
+// clientCertSerial 返回本地客户端证书的序列号（十六进制），不存在时返回空字符串
+func clientCertSerial(cfg *Config) string {
+	data, err := os.ReadFile(cfg.TLSCertFile)
+	if err != nil {
+		return ""
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return ""
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return ""
+	}
+	return cert.SerialNumber.Text(16)
+}
+
+// saveClientCert 保存面板下发的客户端证书，下次连接时生效
+func saveClientCert(cfg *Config, certPEM, keyPEM, caPEM string) error {
+	if _, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM)); err != nil {
+		return fmt.Errorf("证书与私钥不匹配: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.TLSCertFile), 0700); err != nil {
+		return fmt.Errorf("创建证书目录失败: %w", err)
+	}
+	if err := writeFileAtomic(cfg.TLSKeyFile, []byte(keyPEM), 0600); err != nil {
+		return fmt.Errorf("保存私钥失败: %w", err)
+	}
+	if err := writeFileAtomic(cfg.TLSCertFile, []byte(certPEM), 0644); err != nil {
+		return fmt.Errorf("保存证书失败: %w", err)
+	}
+	if caPEM != "" {
+		caPath := filepath.Join(filepath.Dir(cfg.TLSCertFile), "client-ca.crt")
+		if err := writeFileAtomic(caPath, []byte(caPEM), 0644); err != nil {
+			log.Printf("[mTLS] ⚠️ 保存客户端CA失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再替换，避免写入中断导致文件损坏
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -59,6 +59,7 @@ type Updater struct {
 	cfg          *Config
 	frpcMgr      *FrpcManager
 	wsClient     *WSClient
+	state        *StateStore
 	progressChan chan UpdateProgress
 	resultChan   chan UpdateResult
 	done         chan struct{} // 用于退出 progressReporter 协程
@@ -67,11 +68,12 @@ type Updater struct {
 }
 
 // NewUpdater 创建更新器
-func NewUpdater(cfg *Config, frpcMgr *FrpcManager, wsClient *WSClient) *Updater {
+func NewUpdater(cfg *Config, frpcMgr *FrpcManager, wsClient *WSClient, state *StateStore) *Updater {
 	return &Updater{
 		cfg:          cfg,
 		frpcMgr:      frpcMgr,
 		wsClient:     wsClient,
+		state:        state,
 		progressChan: make(chan UpdateProgress, 100),
 		resultChan:   make(chan UpdateResult, 10),
 		done:         make(chan struct{}),
@@ -103,7 +105,7 @@ func (u *Updater) Stop() {
 }
 
 // HandleUpdate 处理更新命令
-func (u *Updater) HandleUpdate(updateType string, version string, downloadURL string, mirrorID uint) {
+func (u *Updater) HandleUpdate(updateType string, version string, downloadURL string, mirrorID uint) error {
 	log.Printf("[Updater] ========== 收到更新命令 ==========")
 	log.Printf("[Updater] 更新类型: %s", updateType)
 	log.Printf("[Updater] 目标版本: %s", version)
@@ -118,7 +120,9 @@ func (u *Updater) HandleUpdate(updateType string, version string, downloadURL st
 	default:
 		log.Printf("[Updater] ❌ 不支持的更新类型: %s", updateType)
 		u.reportResult(UpdateType(updateType), false, version, "不支持的更新类型")
+		return fmt.Errorf("不支持的更新类型: %s", updateType)
 	}
+	return nil
 }
 
 // reportProgress 上报进度
@@ -135,12 +139,17 @@ func (u *Updater) reportProgress(updateType UpdateType, stage UpdateStage, progr
 
 // reportResult 上报结果
 func (u *Updater) reportResult(updateType UpdateType, success bool, version, message string) {
-	u.resultChan <- UpdateResult{
+	result := UpdateResult{
 		UpdateType: updateType,
 		Success:    success,
 		Version:    version,
 		Message:    message,
 	}
+	// 先写入本地状态，daemon 自更新重启或面板不可用时结果也不会丢失
+	if u.state != nil {
+		u.state.RecordUpdateResult(result)
+	}
+	u.resultChan <- result
 }
 
 // writeUpdateLog 写入更新日志到文件
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -11,26 +15,34 @@ import (
 )
 
 type Message struct {
-	Type string                 `json:"type"`
-	Data map[string]interface{} `json:"data"`
+	Type      string                 `json:"type"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Data      map[string]interface{} `json:"data"`
 }
 
+// protocolVersion 守护进程通信协议版本，1 起对带 request_id 的命令回复 ack，服务端据此决定是否等待确认
+const protocolVersion = 1
+
+// errCallbackNotSet 命令对应的回调未设置
+var errCallbackNotSet = errors.New("守护进程未注册该命令的处理器")
+
 type WSClient struct {
 	cfg           *Config
 	conn          *websocket.Conn
 	done          chan struct{}
 	reconnect     chan struct{}
 	writeMu       sync.Mutex // 保护 WebSocket 写操作的互斥锁
-	onConfig      func(config string, version int)
-	onShutdown    func()                                                                     // 收到停止命令时的回调
-	onUpdate      func(updateType string, version string, downloadURL string, mirrorID uint) // 收到更新命令时的回调
-	onCertSync    func(domain string, certPEM string, keyPEM string)                         // 收到证书同步时的回调
-	onCertDelete  func(domain string)                                                        // 收到证书删除时的回调
-	onLogStream   func(logType string, action string, lines int)                             // 收到日志流命令时的回调
-	onFrpcControl func(action string)                                                        // 收到frpc控制命令时的回调
+	onConfig      func(config string, version int) error
+	onShutdown    func()                                                                           // 收到停止命令时的回调
+	onUpdate      func(updateType string, version string, downloadURL string, mirrorID uint) error // 收到更新命令时的回调
+	onCertSync    func(domain string, certPEM string, keyPEM string) error                         // 收到证书同步时的回调
+	onCertDelete  func(domain string) error                                                        // 收到证书删除时的回调
+	onLogStream   func(logType string, action string, lines int) error                             // 收到日志流命令时的回调
+	onFrpcControl func(action string) (string, error)                                              // 收到frpc控制命令时的回调
+	onConnected   func()                                                                           // 每次连接（含重连）认证成功后的回调
 }
 
-func NewWSClient(cfg *Config, onConfig func(string, int)) *WSClient {
+func NewWSClient(cfg *Config, onConfig func(string, int) error) *WSClient {
 	return &WSClient{
 		cfg:       cfg,
 		done:      make(chan struct{}),
@@ -40,17 +52,17 @@ func NewWSClient(cfg *Config, onConfig func(string, int)) *WSClient {
 }
 
 // SetUpdateCallback 设置更新命令回调函数
-func (c *WSClient) SetUpdateCallback(callback func(updateType string, version string, downloadURL string, mirrorID uint)) {
+func (c *WSClient) SetUpdateCallback(callback func(updateType string, version string, downloadURL string, mirrorID uint) error) {
 	c.onUpdate = callback
 }
 
 // SetCertSyncCallback 设置证书同步回调函数
-func (c *WSClient) SetCertSyncCallback(callback func(domain string, certPEM string, keyPEM string)) {
+func (c *WSClient) SetCertSyncCallback(callback func(domain string, certPEM string, keyPEM string) error) {
 	c.onCertSync = callback
 }
 
 // SetCertDeleteCallback 设置证书删除回调函数
-func (c *WSClient) SetCertDeleteCallback(callback func(domain string)) {
+func (c *WSClient) SetCertDeleteCallback(callback func(domain string) error) {
 	c.onCertDelete = callback
 }
 
@@ -60,35 +72,37 @@ func (c *WSClient) SetShutdownCallback(callback func()) {
 }
 
 // SetLogStreamCallback 设置日志流命令回调函数
-func (c *WSClient) SetLogStreamCallback(callback func(logType string, action string, lines int)) {
+func (c *WSClient) SetLogStreamCallback(callback func(logType string, action string, lines int) error) {
 	c.onLogStream = callback
 }
 
 // SetFrpcControlCallback 设置frpc控制命令回调函数
-func (c *WSClient) SetFrpcControlCallback(callback func(action string)) {
+func (c *WSClient) SetFrpcControlCallback(callback func(action string) (string, error)) {
 	c.onFrpcControl = callback
 }
 
+// SetConnectedCallback 设置连接成功回调函数
+func (c *WSClient) SetConnectedCallback(callback func()) {
+	c.onConnected = callback
+}
+
+// authTimeout 认证握手超时时间
+const authTimeout = 10 * time.Second
+
 func (c *WSClient) Connect() error {
-	u, _ := url.Parse(c.cfg.ServerURL)
+	u, err := url.Parse(c.cfg.ServerURL)
+	if err != nil {
+		return fmt.Errorf("解析服务器地址失败: %w", err)
+	}
 	u.Path = "/api/clients/daemon/ws"
-	q := u.Query()
-	q.Set("client_id", fmt.Sprintf("%d", c.cfg.ClientID))
-	q.Set("token", c.cfg.Token)
-	u.RawQuery = q.Encode()
 
 	log.Printf("[WS客户端] 准备连接到服务器")
-	log.Printf("[WS客户端] ServerURL: %s", c.cfg.ServerURL)
 	log.Printf("[WS客户端] 完整URL: %s", u.String())
 	log.Printf("[WS客户端] ClientID: %d", c.cfg.ClientID)
-	// Token 脱敏处理，只显示前4个字符
-	maskedToken := c.cfg.Token
-	if len(maskedToken) > 4 {
-		maskedToken = maskedToken[:4] + "****"
-	}
-	log.Printf("[WS客户端] Token: %s", maskedToken)
 
-	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = buildTLSConfig(c.cfg)
+	conn, resp, err := dialer.Dial(u.String(), nil)
 	if err != nil {
 		if resp != nil {
 			log.Printf("[WS客户端] ❌ 连接失败, HTTP状态码: %d", resp.StatusCode)
@@ -97,11 +111,66 @@ func (c *WSClient) Connect() error {
 		log.Printf("[WS客户端] ❌ 连接错误: %v", err)
 		return err
 	}
+
+	if err := c.authenticate(conn); err != nil {
+		log.Printf("[WS客户端] ❌ 认证失败: %v", err)
+		conn.Close()
+		return err
+	}
+
 	c.conn = conn
 	log.Println("[WS客户端] ✅ 连接成功")
 	return nil
 }
 
+// authenticate 完成挑战-响应认证: 使用 token 对服务端随机数做 HMAC 签名，token 本身不经过网络
+func (c *WSClient) authenticate(conn *websocket.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var challenge Message
+	if err := conn.ReadJSON(&challenge); err != nil {
+		return fmt.Errorf("读取认证挑战失败: %w", err)
+	}
+	if challenge.Type != "auth_challenge" {
+		return fmt.Errorf("期望 auth_challenge 消息，收到 %s", challenge.Type)
+	}
+	nonce, _ := challenge.Data["nonce"].(string)
+	if nonce == "" {
+		return fmt.Errorf("认证挑战缺少 nonce")
+	}
+
+	resp := Message{
+		Type: "auth_response",
+		Data: map[string]interface{}{
+			"client_id":   c.cfg.ClientID,
+			"signature":   signChallenge(c.cfg.Token, nonce, c.cfg.ClientID),
+			"cert_serial": clientCertSerial(c.cfg),
+			"protocol":    protocolVersion,
+		},
+	}
+	if err := conn.WriteJSON(resp); err != nil {
+		return fmt.Errorf("发送认证响应失败: %w", err)
+	}
+
+	var result Message
+	if err := conn.ReadJSON(&result); err != nil {
+		return fmt.Errorf("读取认证结果失败: %w", err)
+	}
+	if success, _ := result.Data["success"].(bool); result.Type != "auth_result" || !success {
+		message, _ := result.Data["message"].(string)
+		return fmt.Errorf("服务端拒绝认证: %s", message)
+	}
+	return nil
+}
+
+// signChallenge 计算握手签名: HMAC-SHA256(token, nonce:clientID)
+func signChallenge(token, nonce string, clientID int) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", nonce, clientID)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func (c *WSClient) Run() {
 	backoff := 5
 	for {
@@ -121,6 +190,9 @@ func (c *WSClient) Run() {
 		}
 		backoff = 5
 
+		if c.onConnected != nil {
+			go c.onConnected()
+		}
 		go c.heartbeat()
 		c.readLoop()
 
@@ -158,33 +230,38 @@ func (c *WSClient) readLoop() {
 			configRaw, ok := msg.Data["config"]
 			if !ok {
 				log.Printf("[WS] ❌ 配置更新消息中缺少 config 字段")
+				c.SendAck(msg, "", fmt.Errorf("缺少 config 字段"))
 				continue
 			}
 			config, ok := configRaw.(string)
 			if !ok {
 				log.Printf("[WS] ❌ config 字段类型错误: %T", configRaw)
+				c.SendAck(msg, "", fmt.Errorf("config 字段类型错误"))
 				continue
 			}
 
-			versionRaw, ok := msg.Data["version"]
+			versionRaw, ok := msg.Data["version"].(float64)
 			if !ok {
 				log.Printf("[WS] ❌ 配置更新消息中缺少 version 字段")
+				c.SendAck(msg, "", fmt.Errorf("缺少 version 字段"))
 				continue
 			}
-			version := int(versionRaw.(float64))
+			version := int(versionRaw)
 
 			log.Printf("[WS] ✅ 收到配置更新: version=%d", version)
 			log.Printf("[WS] 配置内容长度: %d 字节", len(config))
 
 			if c.onConfig != nil {
 				log.Printf("[WS] 调用配置处理回调...")
-				c.onConfig(config, version)
+				c.SendAck(msg, "配置已应用", c.onConfig(config, version))
 			} else {
 				log.Printf("[WS] ⚠️ 配置处理回调未设置!")
+				c.SendAck(msg, "", errCallbackNotSet)
 			}
 		case "shutdown":
 			log.Printf("[WS] ========== 收到停止命令 ==========")
 			log.Printf("[WS] 服务器请求停止 daemon 和 frpc")
+			c.SendAck(msg, "正在停止", nil)
 			if c.onShutdown != nil {
 				log.Printf("[WS] 调用停止回调...")
 				c.onShutdown()
@@ -204,9 +281,10 @@ func (c *WSClient) readLoop() {
 			log.Printf("[WS] 更新类型: %s, 版本: %s, 下载地址: %s", updateTypeRaw, versionRaw, downloadURLRaw)
 			if c.onUpdate != nil {
 				log.Printf("[WS] 调用更新回调...")
-				c.onUpdate(updateTypeRaw, versionRaw, downloadURLRaw, mirrorIDRaw)
+				c.SendAck(msg, "更新任务已开始", c.onUpdate(updateTypeRaw, versionRaw, downloadURLRaw, mirrorIDRaw))
 			} else {
 				log.Printf("[WS] ⚠️ 更新回调未设置!")
+				c.SendAck(msg, "", errCallbackNotSet)
 			}
 		case "cert_sync":
 			log.Printf("[WS] ========== 收到证书同步命令 ==========")
@@ -216,9 +294,10 @@ func (c *WSClient) readLoop() {
 			log.Printf("[WS] 证书域名: %s, 证书长度: %d, 私钥长度: %d", domain, len(certPEM), len(keyPEM))
 			if c.onCertSync != nil {
 				log.Printf("[WS] 调用证书同步回调...")
-				c.onCertSync(domain, certPEM, keyPEM)
+				c.SendAck(msg, "证书已保存", c.onCertSync(domain, certPEM, keyPEM))
 			} else {
 				log.Printf("[WS] ⚠️ 证书同步回调未设置!")
+				c.SendAck(msg, "", errCallbackNotSet)
 			}
 		case "cert_delete":
 			log.Printf("[WS] ========== 收到证书删除命令 ==========")
@@ -226,9 +305,10 @@ func (c *WSClient) readLoop() {
 			log.Printf("[WS] 要删除的证书域名: %s", domain)
 			if c.onCertDelete != nil {
 				log.Printf("[WS] 调用证书删除回调...")
-				c.onCertDelete(domain)
+				c.SendAck(msg, "证书已删除", c.onCertDelete(domain))
 			} else {
 				log.Printf("[WS] ⚠️ 证书删除回调未设置!")
+				c.SendAck(msg, "", errCallbackNotSet)
 			}
 		case "log_stream":
 			log.Printf("[WS] ========== 收到日志流命令 ==========")
@@ -241,22 +321,52 @@ func (c *WSClient) readLoop() {
 			log.Printf("[WS] 日志类型: %s, 操作: %s, 行数: %d", logType, action, lines)
 			if c.onLogStream != nil {
 				log.Printf("[WS] 调用日志流回调...")
-				c.onLogStream(logType, action, lines)
+				c.SendAck(msg, "", c.onLogStream(logType, action, lines))
 			} else {
 				log.Printf("[WS] ⚠️ 日志流回调未设置!")
+				c.SendAck(msg, "", errCallbackNotSet)
+			}
+		case "token_rotate":
+			log.Printf("[WS] ========== 收到凭证轮换命令 ==========")
+			token, _ := msg.Data["token"].(string)
+			if token == "" {
+				c.SendAck(msg, "", fmt.Errorf("缺少 token 字段"))
+				continue
 			}
+			if err := c.cfg.SaveToken(token); err != nil {
+				log.Printf("[WS] ❌ 保存新凭证失败: %v", err)
+				c.SendAck(msg, "", err)
+				continue
+			}
+			log.Printf("[WS] ✅ 新凭证已保存，下次连接时生效")
+			c.SendAck(msg, "凭证已更新", nil)
+		case "client_cert":
+			log.Printf("[WS] ========== 收到客户端证书 ==========")
+			certPEM, _ := msg.Data["cert_pem"].(string)
+			keyPEM, _ := msg.Data["key_pem"].(string)
+			caPEM, _ := msg.Data["ca_pem"].(string)
+			if err := saveClientCert(c.cfg, certPEM, keyPEM, caPEM); err != nil {
+				log.Printf("[WS] ❌ 保存客户端证书失败: %v", err)
+				c.SendAck(msg, "", err)
+				continue
+			}
+			log.Printf("[WS] ✅ 客户端证书已保存，下次连接时生效")
+			c.SendAck(msg, "证书已保存", nil)
 		case "frpc_control":
 			log.Printf("[WS] ========== 收到frpc控制命令 ==========")
 			action, _ := msg.Data["action"].(string)
 			log.Printf("[WS] 控制操作: %s", action)
 			if c.onFrpcControl != nil {
 				log.Printf("[WS] 调用frpc控制回调...")
-				c.onFrpcControl(action)
+				message, err := c.onFrpcControl(action)
+				c.SendAck(msg, message, err)
 			} else {
 				log.Printf("[WS] ⚠️ frpc控制回调未设置!")
+				c.SendAck(msg, "", errCallbackNotSet)
 			}
 		default:
 			log.Printf("[WS] 未知消息类型: %s", msg.Type)
+			c.SendAck(msg, "", fmt.Errorf("未知消息类型: %s", msg.Type))
 		}
 	}
 }
@@ -291,6 +401,30 @@ func (c *WSClient) writeJSON(msg interface{}) error {
 	return c.conn.WriteJSON(msg)
 }
 
+// SendAck 发送命令确认，携带原消息的 request_id 供服务端匹配
+// 没有 request_id 的消息来自旧版服务端，无需确认
+func (c *WSClient) SendAck(req Message, message string, err error) {
+	if req.RequestID == "" {
+		return
+	}
+	success := err == nil
+	if err != nil {
+		message = err.Error()
+	}
+	msg := Message{
+		Type:      "ack",
+		RequestID: req.RequestID,
+		Data: map[string]interface{}{
+			"type":    req.Type,
+			"success": success,
+			"message": message,
+		},
+	}
+	if err := c.writeJSON(msg); err != nil {
+		log.Printf("[WS] 发送确认失败: type=%s, request_id=%s, err=%v", req.Type, req.RequestID, err)
+	}
+}
+
 func (c *WSClient) SendSyncResult(success bool, version int, message string) {
 	msg := Message{
 		Type: "sync_result",
@@ -355,6 +489,7 @@ func (c *WSClient) SendVersionReport(frpcVersion, daemonVersion, os, arch string
 			"daemon_version": daemonVersion,
 			"os":             os,
 			"arch":           arch,
+			"protocol":       protocolVersion,
 		},
 	}
 	if err := c.writeJSON(msg); err != nil {
@@ -422,3 +557,27 @@ func (c *WSClient) SendConfigSyncResult(result ConfigSyncResult) {
 		log.Printf("[WS] ✅ 配置同步结果已发送: success=%v, rolled_back=%v", result.Success, result.RolledBack)
 	}
 }
+
+// SendStateReport 上报本地状态缓存，供面板对账
+func (c *WSClient) SendStateReport(state DaemonState) {
+	msg := Message{
+		Type: "state_report",
+		Data: state.ReportData(),
+	}
+	if err := c.writeJSON(msg); err != nil {
+		log.Printf("[WS] 发送状态上报失败: %v", err)
+	} else {
+		log.Printf("[WS] ✅ 状态已上报: config_version=%d, certs=%d", state.ConfigVersion, len(state.Certs))
+	}
+}
+
+// SendClientMetrics 发送主机与 frpc 进程指标
+func (c *WSClient) SendClientMetrics(metrics HostMetrics) {
+	msg := Message{
+		Type: "client_metrics",
+		Data: metrics.ToMap(),
+	}
+	if err := c.writeJSON(msg); err != nil {
+		log.Printf("[WS] 发送主机指标失败: %v", err)
+	}
+}
@@ -1,6 +1,6 @@
 import request from './request';
 
-export type AlertTargetType = 'proxy' | 'frpc' | 'frps' | 'system';
+export type AlertTargetType = 'proxy' | 'frpc' | 'frps' | 'system' | 'probe';
 
 // 系统级告警规则类型
 export type SystemRuleType =
@@ -8,23 +8,30 @@ export type SystemRuleType =
   | 'cert_expiring' | 'cert_expired'
   | 'cert_renew_success' | 'cert_renew_failed'
   | 'dns_sync_success' | 'dns_sync_failed'
-  | 'login_failed' | 'config_changed';
+  | 'login_failed' | 'config_changed'
+  | 'quota_exceeded' | 'quota_restored'
+  | 'access_blocked';
 
 export interface AlertRule {
   id?: number;
   target_type: AlertTargetType;
   target_id: number;
   proxy_id: number; // 保留兼容
-  rule_type: string; // daily, monthly, rate, offline, 或系统级规则类型
-  threshold_value: number;
-  threshold_unit: string;
+  rule_type: string; // daily, monthly, rate, quota, offline, metric, 或系统级规则类型
+  threshold_value: number; // quota 规则为配额使用百分比
+  threshold_unit: string; // bytes, MB, GB, TB, percent
+  expression?: string; // metric 规则的指标表达式，如 avg(frps_cpu_percent[5m]) > 80 for 10m
+  timezone?: string; // 日/月流量统计时区（IANA 名称），为空使用服务器时区
   cooldown_minutes: number;
   offline_delay_seconds: number; // 离线延迟确认时间（秒）
   notify_on_recovery: boolean; // 恢复时是否通知
   enabled: boolean;
   notify_recipient_ids?: string; // 接收人ID列表，逗号分隔
   notify_group_ids?: string; // 分组ID列表，逗号分隔
-  notify_webhook?: string;
+  notify_channel_ids?: string; // 通知渠道ID列表，逗号分隔
+  notify_webhook?: string; // 兼容旧配置，建议改用通知渠道
+  webhook_template?: string; // Webhook 请求体模板（Go template），为空时使用默认 JSON
+  escalation_policy_id?: number; // 告警升级策略ID，0 表示不升级
   created_at?: string;
   updated_at?: string;
 }
@@ -41,9 +48,65 @@ export interface AlertLog {
   message: string;
   event_data?: string; // 系统告警事件详情JSON
   notified: boolean;
+  status: AlertStatus | ''; // 历史记录为空，视为已结束
+  silenced: boolean; // 触发时处于静默期，未发送通知
+  suppressed_by: number; // 被上级 frps/frpc 离线告警抑制时为上级告警ID，未发送通知
+  acked_by?: number;
+  acked_by_name?: string;
+  acked_at?: string;
+  resolved_at?: string;
+  escalation_level: number; // 已通知的升级级别，0 表示未使用升级策略
+  next_escalation_at?: string; // 下一级升级时间，为空表示已到最高级
   created_at: string;
 }
 
+export type AlertStatus = 'firing' | 'acknowledged' | 'resolved';
+
+export const alertStatusNames: Record<AlertStatus, string> = {
+  firing: '触发中',
+  acknowledged: '已确认',
+  resolved: '已恢复',
+};
+
+export type SilenceState = 'pending' | 'active' | 'expired';
+
+// 告警静默，未设置的匹配条件匹配全部；开始时间设为将来即为计划维护窗口
+export interface AlertSilence {
+  id?: number;
+  target_type?: AlertTargetType | '';
+  target_id?: number;
+  rule_id?: number;
+  starts_at?: string; // 为空时立即生效
+  ends_at: string;
+  comment?: string;
+  created_by?: number;
+  created_by_name?: string;
+  state?: SilenceState;
+  created_at?: string;
+  updated_at?: string;
+}
+
+// 每日告警汇总，时间范围左闭右开
+export interface AlertDailySummary {
+  start: string;
+  end: string;
+  timezone: string;
+  total: number;
+  silenced: number;
+  open: number; // 统计范围内触发且仍未结束的告警
+  types: { alert_type: string; name: string; count: number }[];
+  offline: {
+    target_type: AlertTargetType;
+    target_id: number;
+    target_name: string;
+    count: number;
+    total_seconds: number;
+    longest_seconds: number;
+    ongoing: boolean; // 统计结束时仍未恢复
+  }[];
+  cert_events: { time: string; alert_type: string; domain: string; message: string }[];
+}
+
 // 系统规则类型名称映射
 export const systemRuleTypeNames: Record<SystemRuleType, string> = {
   cert_apply_success: '证书申请成功',
@@ -56,6 +119,9 @@ export const systemRuleTypeNames: Record<SystemRuleType, string> = {
   dns_sync_failed: 'DNS同步失败',
   login_failed: '登录失败',
   config_changed: '配置变更',
+  quota_exceeded: '流量配额超出',
+  quota_restored: '流量配额恢复',
+  access_blocked: '访问频繁被拦截',
 };
 
 export const alertApi = {
@@ -64,5 +130,18 @@ export const alertApi = {
   getRulesByProxyID: (proxyId: number) => request.get(`/alerts/rules/proxy/${proxyId}`),
   updateRule: (data: AlertRule) => request.put('/alerts/rules', data),
   deleteRule: (id: number) => request.delete(`/alerts/rules/${id}`),
-  getAlertLogs: (limit?: number) => request.get('/alerts/logs', { params: { limit } }),
+  getAlertLogs: (limit?: number, status?: AlertStatus | 'open') =>
+    request.get('/alerts/logs', { params: { limit, status } }),
+  acknowledgeAlert: (id: number) => request.post(`/alerts/logs/${id}/ack`),
+  unacknowledgeAlert: (id: number) => request.post(`/alerts/logs/${id}/unack`),
+  resolveAlert: (id: number) => request.post(`/alerts/logs/${id}/resolve`),
+  getSuppressedAlerts: (id: number) => request.get<AlertLog[]>(`/alerts/logs/${id}/suppressed`),
+  getSilences: () => request.get<AlertSilence[]>('/alerts/silences'),
+  createSilence: (data: AlertSilence) => request.post('/alerts/silences', data),
+  updateSilence: (id: number, data: AlertSilence) => request.put(`/alerts/silences/${id}`, data),
+  deleteSilence: (id: number) => request.delete(`/alerts/silences/${id}`),
+  expireSilence: (id: number) => request.post(`/alerts/silences/${id}/expire`),
+  getSummary: (date?: string, tz?: string) =>
+    request.get<AlertDailySummary>('/alerts/summary', { params: { date, tz } }),
+  sendSummary: (date?: string, tz?: string) => request.post('/alerts/summary/send', null, { params: { date, tz } }),
 };
\ No newline at end of file
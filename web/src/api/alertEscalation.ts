@@ -0,0 +1,57 @@
+import request from './request';
+import type { AlertRecipient } from './alertRecipient';
+
+export interface AlertEscalationStep {
+  id?: number;
+  policy_id?: number;
+  level?: number; // 按提交顺序自动编号，从1开始
+  group_ids: string; // 接收人分组ID列表，逗号分隔
+  schedule_ids: string; // 值班表ID列表，逗号分隔，通知当前值班人
+  escalate_after_minutes: number; // 本级通知后多少分钟未确认升级到下一级，最后一级忽略
+}
+
+export interface AlertEscalationPolicy {
+  id?: number;
+  name: string;
+  description?: string;
+  enabled: boolean;
+  steps: AlertEscalationStep[];
+  created_at?: string;
+  updated_at?: string;
+}
+
+export interface OnCallSchedule {
+  id?: number;
+  name: string;
+  description?: string;
+  timezone?: string; // IANA 时区名称，为空使用服务器时区
+  handoff_at: string; // 首个轮班开始时间，之后在相同星期和时刻交接
+  rotation_weeks: number;
+  recipient_ids: string; // 按轮换顺序排列的接收人ID，逗号分隔
+  enabled: boolean;
+  current?: AlertRecipient; // 当前值班人
+  created_at?: string;
+  updated_at?: string;
+}
+
+export interface OnCallShift {
+  recipient_id: number;
+  recipient_name: string;
+  starts_at: string;
+  ends_at: string;
+}
+
+export const alertEscalationApi = {
+  getPolicies: () => request.get<AlertEscalationPolicy[]>('/alerts/escalation-policies'),
+  createPolicy: (data: AlertEscalationPolicy) => request.post<AlertEscalationPolicy>('/alerts/escalation-policies', data),
+  updatePolicy: (id: number, data: AlertEscalationPolicy) =>
+    request.put<AlertEscalationPolicy>(`/alerts/escalation-policies/${id}`, data),
+  deletePolicy: (id: number) => request.delete(`/alerts/escalation-policies/${id}`),
+
+  getSchedules: () => request.get<OnCallSchedule[]>('/alerts/oncall-schedules'),
+  createSchedule: (data: OnCallSchedule) => request.post<OnCallSchedule>('/alerts/oncall-schedules', data),
+  updateSchedule: (id: number, data: OnCallSchedule) => request.put<OnCallSchedule>(`/alerts/oncall-schedules/${id}`, data),
+  deleteSchedule: (id: number) => request.delete(`/alerts/oncall-schedules/${id}`),
+  getShifts: (id: number, count?: number) =>
+    request.get<OnCallShift[]>(`/alerts/oncall-schedules/${id}/shifts`, { params: { count } }),
+};
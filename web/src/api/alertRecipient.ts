@@ -14,6 +14,7 @@ export interface AlertRecipientGroup {
   name: string;
   description: string;
   enabled: boolean;
+  channel_ids?: string; // 关联的通知渠道ID列表，逗号分隔
   recipients?: AlertRecipient[];
   created_at?: string;
   updated_at?: string;
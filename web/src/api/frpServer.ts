@@ -20,6 +20,7 @@ export interface ServerMetricsHistory {
   memory_bytes: number;
   traffic_in: number;
   traffic_out: number;
+  client_count: number; // frps 在线客户端数
   record_time: string;
 }
 
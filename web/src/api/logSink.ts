@@ -0,0 +1,45 @@
+import request from './request';
+
+export type LogSinkType = 'syslog' | 'loki';
+export type LogStream = 'alert' | 'audit' | 'system';
+
+// 日志转发目标，告警事件、操作日志和系统事件批量推送到 syslog 或 Loki，失败时保留到下次重试
+export interface LogSink {
+  id?: number;
+  name: string;
+  type: LogSinkType;
+  address: string; // syslog 为 host:port，Loki 为推送地址
+  protocol?: 'udp' | 'tcp' | 'tls'; // syslog 传输协议，默认 udp
+  facility?: number; // syslog facility(0-23)，0 使用 local0
+  app_name?: string;
+  tls_skip_verify?: boolean;
+  streams?: string; // 逗号分隔的事件流，为空转发全部
+  labels?: string; // 附加标签，如 env=prod,region=cn
+  username?: string; // Loki Basic 认证用户名
+  tenant_id?: string; // Loki X-Scope-OrgID
+  enabled: boolean;
+  last_error?: string; // 只读
+  last_sent_at?: string; // 只读
+  has_password?: boolean; // 是否已配置密码（只读）
+  pending?: number; // 等待推送的事件数（只读）
+  dropped?: number; // 因缓冲区满丢弃的事件数（只读）
+  password?: string; // 只写，Loki 密码或 Bearer 令牌，更新时留空保留原值
+  clear_password?: boolean;
+  created_at?: string;
+  updated_at?: string;
+}
+
+export const logStreamNames: Record<LogStream, string> = {
+  alert: '告警事件',
+  audit: '操作日志',
+  system: '系统事件',
+};
+
+export const logSinkApi = {
+  getSinks: () => request.get<LogSink[]>('/log-sinks'),
+  createSink: (data: LogSink) => request.post('/log-sinks', data),
+  updateSink: (id: number, data: LogSink) => request.put(`/log-sinks/${id}`, data),
+  deleteSink: (id: number) => request.delete(`/log-sinks/${id}`),
+  testSink: (id: number) => request.post(`/log-sinks/${id}/test`),
+  testSinkConfig: (data: LogSink) => request.post('/log-sinks/test', data),
+};
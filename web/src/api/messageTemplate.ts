@@ -0,0 +1,68 @@
+import request from './request';
+import type { AlertLog } from './alert';
+
+export type MessageChannel = 'email' | 'im';
+export type NotifyLocale = 'zh-CN' | 'en-US';
+
+// 模板事件类型，系统告警还可以按具体规则类型(如 cert_renew_failed)单独配置
+export type MessageEventType =
+  | 'traffic' | 'offline' | 'recovery' | 'metric'
+  | 'metric_recovery' | 'escalation' | 'system';
+
+// 告警消息模板，标题和正文使用 Go text/template 语法，如 {{.TargetName}}
+export interface MessageTemplate {
+  id?: number;
+  event_type: MessageEventType | string;
+  channel: MessageChannel;
+  subject: string;
+  body: string;
+  enabled: boolean;
+  created_at?: string;
+  updated_at?: string;
+}
+
+export interface MessageTemplateDefaults {
+  locale: NotifyLocale;
+  templates: { event_type: MessageEventType; subject: string; body: string }[];
+  system_events: string[];
+  variables: { name: string; description: string }[];
+}
+
+// 标题和正文都为空时预览当前生效的模板
+export interface MessageTemplatePreviewRequest {
+  event_type: string;
+  channel?: MessageChannel;
+  locale?: NotifyLocale;
+  subject?: string;
+  body?: string;
+  target_name?: string;
+  alert?: Partial<AlertLog>; // 示例告警日志，为空时按事件类型生成
+}
+
+export interface MessageTemplatePreview {
+  subject: string;
+  body: string;
+  html?: string; // 发送方式为邮件时的邮件 HTML
+  data: Record<string, unknown>; // 渲染使用的模板变量
+}
+
+export const messageEventTypeNames: Record<MessageEventType, string> = {
+  traffic: '流量告警',
+  offline: '离线告警',
+  recovery: '恢复通知',
+  metric: '指标告警',
+  metric_recovery: '指标恢复',
+  escalation: '告警升级',
+  system: '系统告警',
+};
+
+export const messageTemplateApi = {
+  getTemplates: () => request.get<MessageTemplate[]>('/alerts/message-templates'),
+  getDefaults: (locale?: NotifyLocale) =>
+    request.get<MessageTemplateDefaults>('/alerts/message-templates/defaults', { params: { locale } }),
+  createTemplate: (data: MessageTemplate) => request.post('/alerts/message-templates', data),
+  updateTemplate: (id: number, data: MessageTemplate) => request.put(`/alerts/message-templates/${id}`, data),
+  deleteTemplate: (id: number) => request.delete(`/alerts/message-templates/${id}`),
+  previewTemplate: (data: MessageTemplatePreviewRequest) =>
+    request.post<MessageTemplatePreview>('/alerts/message-templates/preview', data),
+};
@@ -0,0 +1,46 @@
+import request from './request';
+
+export type NotificationChannelType =
+  | 'dingtalk' | 'wecom' | 'feishu' | 'telegram'
+  | 'slack' | 'ntfy' | 'gotify' | 'webhook';
+
+export interface NotificationChannel {
+  id?: number;
+  name: string;
+  type: NotificationChannelType;
+  url: string; // 机器人 Webhook 地址，ntfy/Gotify 服务地址，Telegram API 地址（可选）
+  chat_id?: string; // Telegram 会话ID
+  topic?: string; // ntfy 主题
+  priority?: number;
+  enabled: boolean;
+  rate_limit?: number; // 每小时发送上限，0 使用全局设置
+  has_token?: boolean; // 是否已配置令牌（只读）
+  has_secret?: boolean; // 是否已配置加签密钥（只读）
+  token?: string; // 只写，更新时留空保留原值
+  secret?: string; // 只写，更新时留空保留原值
+  clear_token?: boolean;
+  clear_secret?: boolean;
+  created_at?: string;
+  updated_at?: string;
+}
+
+// 通知渠道类型名称映射
+export const notificationChannelTypeNames: Record<NotificationChannelType, string> = {
+  dingtalk: '钉钉',
+  wecom: '企业微信',
+  feishu: '飞书',
+  telegram: 'Telegram',
+  slack: 'Slack',
+  ntfy: 'ntfy',
+  gotify: 'Gotify',
+  webhook: '通用 Webhook',
+};
+
+export const notificationChannelApi = {
+  getChannels: () => request.get<NotificationChannel[]>('/alerts/channels'),
+  createChannel: (data: NotificationChannel) => request.post('/alerts/channels', data),
+  updateChannel: (id: number, data: NotificationChannel) => request.put(`/alerts/channels/${id}`, data),
+  deleteChannel: (id: number) => request.delete(`/alerts/channels/${id}`),
+  testChannel: (id: number) => request.post(`/alerts/channels/${id}/test`),
+  testChannelConfig: (data: NotificationChannel) => request.post('/alerts/channels/test', data),
+};
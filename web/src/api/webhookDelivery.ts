@@ -0,0 +1,48 @@
+import request from './request';
+
+export type WebhookDeliveryStatus = 'pending' | 'success' | 'failed';
+
+export interface WebhookDelivery {
+  id: number;
+  alert_log_id: number; // 恢复通知为0
+  rule_id: number;
+  event_type: string;
+  url: string;
+  content_type: string;
+  payload: string;
+  status: WebhookDeliveryStatus;
+  attempts: number;
+  max_attempts: number;
+  next_attempt_at: string;
+  last_status_code: number;
+  last_error: string;
+  delivered_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDeliveryAttempt {
+  id: number;
+  delivery_id: number;
+  attempt: number;
+  manual: boolean;
+  success: boolean;
+  status_code: number;
+  error: string;
+  response_body: string;
+  duration_ms: number;
+  created_at: string;
+}
+
+export const webhookDeliveryStatusNames: Record<WebhookDeliveryStatus, string> = {
+  pending: '等待重试',
+  success: '成功',
+  failed: '失败',
+};
+
+export const webhookDeliveryApi = {
+  getDeliveries: (params?: { status?: WebhookDeliveryStatus; alert_log_id?: number; limit?: number }) =>
+    request.get<WebhookDelivery[]>('/alerts/webhook-deliveries', { params }),
+  getAttempts: (id: number) => request.get<WebhookDeliveryAttempt[]>(`/alerts/webhook-deliveries/${id}/attempts`),
+  redeliver: (id: number) => request.post<WebhookDelivery>(`/alerts/webhook-deliveries/${id}/redeliver`),
+};